@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/decoy"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+)
+
+const decoyUsage = `用法: prism decoy serve
+
+在本機 (127.0.0.1:18780) 提供數據目錄下的內置偽裝站點，供 Trojan 回落使用。
+通常由 prism-decoy 服務運行，無需手動執行。
+`
+
+// runDecoyCommand 執行 decoy 子命令，返回退出碼
+func runDecoyCommand(paths *appctx.Paths, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "serve" {
+		fmt.Fprint(stderr, decoyUsage)
+		return 2
+	}
+
+	dir := paths.DecoySiteDir()
+	if _, err := decoy.EnsureSite(dir); err != nil {
+		fmt.Fprintf(stderr, "生成偽裝站點失敗: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(stdout, "偽裝站點服務監聽 %s:%d (%s)\n", domainConfig.DecoySiteHost, domainConfig.DecoySitePort, dir)
+	if err := decoy.Serve(ctx, domainConfig.DecoySiteHost, domainConfig.DecoySitePort, dir); err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Yat-Muk/prism-v2/internal/infra/diag"
+	"github.com/Yat-Muk/prism-v2/internal/infra/firewall"
+	"github.com/Yat-Muk/prism-v2/internal/infra/singbox"
+	infraSystem "github.com/Yat-Muk/prism-v2/internal/infra/system"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+	"go.uber.org/zap"
+)
+
+// runDiagCommand 生成脫敏診斷包，返回退出碼
+func runDiagCommand(paths *appctx.Paths, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diag", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	outDir := fs.String("o", "", "診斷包輸出目錄 (默認: 系統臨時目錄)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// 子命令不寫入 prism.log，避免診斷過程污染被收集的日誌
+	nop := zap.NewNop()
+	path, err := diag.Build(ctx, diag.Options{
+		Paths:       paths,
+		Firewall:    firewall.NewManager(nop),
+		SysInfo:     infraSystem.NewSystemInfo(nop),
+		SingboxUnit: singbox.ServiceName,
+	}, *outDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "生成診斷包失敗: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "診斷包已生成: %s\n", path)
+	fmt.Fprintln(stdout, "密碼、密鑰、UUID、郵箱與公網 IP 已脫敏，發送前仍建議自行檢查")
+	return 0
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Yat-Muk/prism-v2/internal/application"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	domainSingbox "github.com/Yat-Muk/prism-v2/internal/domain/singbox"
+	infraConfig "github.com/Yat-Muk/prism-v2/internal/infra/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/firewall"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const firewallUsage = `用法: prism firewall <命令>
+
+命令:
+  status                  顯示防火牆後端、SSH 保護信息與當前 Prism 規則
+  apply [-confirm 秒數]   按當前配置同步防火牆規則與源地址策略
+                          指定 -confirm 時需在限時內輸入 yes 確認，否則自動回滾
+                          (回滾由 systemd 計時器執行，SSH 斷開後仍會生效)
+  rollback                恢復 apply -confirm 之前的規則 (通常由計時器自動調用)
+
+SSH 端口始終保持開放，當前會話的客戶端 IP 不受源地址策略限制
+`
+
+// runFirewallCommand 執行 firewall 子命令，返回退出碼
+func runFirewallCommand(paths *appctx.Paths, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, firewallUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "status":
+		return firewallStatus(stdout, stderr)
+	case "apply":
+		return firewallApply(paths, args[1:], stdin, stdout, stderr)
+	case "rollback":
+		return firewallRollback(paths, stdout, stderr)
+	default:
+		fmt.Fprint(stderr, firewallUsage)
+		return 2
+	}
+}
+
+func firewallStatus(stdout, stderr io.Writer) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	guard := firewall.NewGuard(firewall.Detect(zap.NewNop()), zap.NewNop())
+	access := guard.Access(ctx)
+
+	fmt.Fprintf(stdout, "後端: %s\n", guard.Type())
+	fmt.Fprintf(stdout, "SSH 端口: %v\n", access.Ports)
+	if access.ClientIP != "" {
+		fmt.Fprintf(stdout, "當前客戶端: %s\n", access.ClientIP)
+	}
+
+	rules, err := guard.CurrentRules(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "讀取規則失敗: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Prism 規則 (%d):\n", len(rules))
+	for _, r := range rules {
+		fmt.Fprintf(stdout, "  %s\n", r)
+	}
+	return 0
+}
+
+func firewallApply(paths *appctx.Paths, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("firewall apply", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	confirm := fs.Int("confirm", 0, "需在指定秒數內確認，否則回滾 (0 為直接應用)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *confirm < 0 {
+		fmt.Fprintln(stderr, "確認時限不能為負數")
+		return 2
+	}
+
+	logConfig := logger.DefaultConfig()
+	logConfig.OutputPath = filepath.Join(paths.LogDir, "prism.log")
+	log, err := logger.New(logConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "初始化日誌失敗: %v\n", err)
+		return 1
+	}
+	defer log.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// SSH 斷開時不隨終端退出，確保進程內的回滾計時仍能執行
+	signal.Ignore(syscall.SIGHUP)
+
+	cfg, err := application.NewConfigService(infraConfig.NewFileRepository(paths.ConfigFile, nil, log), log).LoadWithMigration(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "加載配置失敗: %v\n", err)
+		return 1
+	}
+
+	guard := firewall.NewGuard(firewall.Detect(log), log)
+	generator := domainSingbox.NewGenerator("unknown", protocol.NewFactory(paths))
+	svc := application.NewSingboxService(generator, nil, guard, paths, log)
+	apply := func(ctx context.Context) error {
+		return svc.SyncFirewall(ctx, cfg)
+	}
+
+	if *confirm == 0 {
+		if err := apply(ctx); err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "防火牆規則已同步")
+		return 0
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		guard.SetExternalRollback(firewall.ExternalRollback{
+			SnapshotPath: rollbackSnapshotPath(paths),
+			Command:      rollbackCommand(exe, paths),
+		})
+	}
+
+	pending, err := guard.ApplyWithConfirm(ctx, time.Duration(*confirm)*time.Second, apply)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "防火牆規則已應用，請在 %d 秒內 (%s 前) 輸入 yes 確認，否則自動回滾: ",
+		*confirm, pending.Deadline().Format("15:04:05"))
+
+	if waitForConfirm(ctx, pending, stdin) && pending.Confirm() {
+		fmt.Fprintln(stdout, "已確認，變更保留")
+		return 0
+	}
+
+	// 未確認: 等待回滾完成
+	if reverted, err := pending.Reverted(); reverted {
+		if err != nil {
+			fmt.Fprintf(stderr, "\n%v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "\n未確認，已回滾到變更前的規則")
+		return 1
+	}
+	fmt.Fprintln(stdout, "已確認，變更保留")
+	return 0
+}
+
+// firewallRollback 從 apply -confirm 保存的快照恢復規則
+func firewallRollback(paths *appctx.Paths, stdout, stderr io.Writer) int {
+	logConfig := logger.DefaultConfig()
+	logConfig.OutputPath = filepath.Join(paths.LogDir, "prism.log")
+	log, err := logger.New(logConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "初始化日誌失敗: %v\n", err)
+		return 1
+	}
+	defer log.Sync()
+
+	path := rollbackSnapshotPath(paths)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Fprintln(stdout, "沒有等待回滾的防火牆變更")
+		return 0
+	}
+
+	log.Warn("防火牆變更未確認，執行進程外回滾")
+	guard := firewall.NewGuard(firewall.Detect(log), log)
+	if err := guard.RestoreSnapshot(path); err != nil {
+		log.Error("進程外回滾失敗", zap.Error(err))
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, "已回滾到變更前的規則")
+	return 0
+}
+
+// rollbackSnapshotPath 進程外回滾的規則快照文件
+func rollbackSnapshotPath(paths *appctx.Paths) string {
+	return filepath.Join(paths.DataDir, "firewall-rollback.json")
+}
+
+// rollbackCommand 返回計時器執行的回滾命令
+// 計時器在獨立的 systemd 單元中運行，需帶上 -dir 才能找到同一數據目錄下的快照
+func rollbackCommand(exe string, paths *appctx.Paths) []string {
+	return []string{exe, "-dir", paths.BaseDir, "firewall", "rollback"}
+}
+
+// waitForConfirm 讀取用戶輸入直到輸入 yes，超時回滾或輸入結束時返回 false
+func waitForConfirm(ctx context.Context, pending *firewall.Pending, stdin io.Reader) bool {
+	answers := make(chan bool, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "yes", "y":
+				answers <- true
+				return
+			}
+		}
+		answers <- false
+	}()
+
+	select {
+	case ok := <-answers:
+		return ok
+	case <-pending.Done():
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
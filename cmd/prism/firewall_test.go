@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunFirewallCommandUsage(t *testing.T) {
+	paths, err := appctx.NewPaths(t.TempDir())
+	require.NoError(t, err)
+
+	for _, args := range [][]string{nil, {"unknown"}} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runFirewallCommand(paths, args, nil, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "用法")
+	}
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, runFirewallCommand(paths, []string{"apply", "-confirm", "-5"}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "確認時限不能為負數")
+}
+
+func TestRollbackCommandKeepsDataDir(t *testing.T) {
+	base := t.TempDir()
+	paths, err := appctx.NewPaths(base)
+	require.NoError(t, err)
+
+	cmd := rollbackCommand("/usr/local/bin/prism", paths)
+	assert.Equal(t, []string{"/usr/local/bin/prism", "-dir", base, "firewall", "rollback"}, cmd)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
+	"github.com/Yat-Muk/prism-v2/internal/infra/singbox"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+)
+
+const logsUsage = `用法: prism logs query [表達式]
+
+表達式由空格分隔的條件組成:
+  level=warn            最低級別 (debug/info/warn/error)
+  since=2h until=30m    時間範圍 (相對時長 30m/2h/7d 或 2024-01-02T15:00)
+  inbound=hysteria2-in  入站標籤
+  source=sing-box       來源 (prism/sing-box/all)
+  limit=100             最多輸出最近 N 條
+  domain=google         按字段匹配 (子串，不區分大小寫)
+  timeout               不帶 = 的詞按消息內容搜索
+`
+
+// runLogsCommand 執行 logs 子命令，返回退出碼
+func runLogsCommand(paths *appctx.Paths, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "query" {
+		fmt.Fprint(stderr, logsUsage)
+		return 2
+	}
+
+	expr := strings.Join(args[1:], " ")
+	q, err := logs.ParseQuery(expr, time.Now())
+	if err != nil {
+		fmt.Fprintf(stderr, "查詢語法錯誤: %v\n", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	searcher := &logs.Searcher{
+		PrismLog:    filepath.Join(paths.LogDir, "prism.log"),
+		SingboxUnit: singbox.ServiceName,
+		SingboxLog:  filepath.Join(paths.LogDir, "sing-box.log"),
+	}
+	entries, err := searcher.Search(ctx, q)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	for _, e := range entries {
+		fmt.Fprintln(stdout, e.Detail())
+	}
+	fmt.Fprintf(stderr, "共 %d 條記錄\n", len(entries))
+	return 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLogsCommand(t *testing.T) {
+	paths, err := appctx.NewPaths(t.TempDir())
+	require.NoError(t, err)
+
+	content := `{"level":"info","ts":"2024-01-02T10:00:00.000Z","msg":"服務已啟動"}
+{"level":"error","ts":"2024-01-02T10:05:00.000Z","msg":"證書續期失敗","domain":"example.com"}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(paths.LogDir, "prism.log"), []byte(content), 0644))
+
+	t.Run("缺少子命令", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runLogsCommand(paths, nil, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "用法")
+	})
+
+	t.Run("語法錯誤", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runLogsCommand(paths, []string{"query", "level=loud"}, &stdout, &stderr))
+	})
+
+	t.Run("按級別與字段查詢", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runLogsCommand(paths, []string{"query", "source=prism", "level=error", "domain=example"}, &stdout, &stderr)
+		assert.Equal(t, 0, code)
+		assert.Contains(t, stdout.String(), "證書續期失敗")
+		assert.NotContains(t, stdout.String(), "服務已啟動")
+		assert.Contains(t, stderr.String(), "共 1 條記錄")
+	})
+}
@@ -38,6 +38,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 子命令 (在重定向 stderr 之前處理，保證錯誤輸出可見)
+	switch flag.Arg(0) {
+	case "logs":
+		os.Exit(runLogsCommand(paths, flag.Args()[1:], os.Stdout, os.Stderr))
+	case "diag":
+		os.Exit(runDiagCommand(paths, flag.Args()[1:], os.Stdout, os.Stderr))
+	case "firewall":
+		os.Exit(runFirewallCommand(paths, flag.Args()[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "decoy":
+		os.Exit(runDecoyCommand(paths, flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
 	stdErrFile := filepath.Join(paths.LogDir, "stderr.log")
 	redirectStdErr(stdErrFile)
 
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/Yat-Muk/prism-v2/internal/application"
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
@@ -13,9 +15,11 @@ import (
 	"github.com/Yat-Muk/prism-v2/internal/infra/backup"
 	"github.com/Yat-Muk/prism-v2/internal/infra/certinfo"
 	infraConfig "github.com/Yat-Muk/prism-v2/internal/infra/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/decoy"
 	"github.com/Yat-Muk/prism-v2/internal/infra/firewall"
 	infraSingbox "github.com/Yat-Muk/prism-v2/internal/infra/singbox"
 	infraSystem "github.com/Yat-Muk/prism-v2/internal/infra/system"
+	"github.com/Yat-Muk/prism-v2/internal/infra/warp"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/cert"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/version"
@@ -27,8 +31,11 @@ import (
 type AppDependencies struct {
 	Log            *zap.Logger
 	Paths          *appctx.Paths
+	ConfigService  *application.ConfigService
 	CertService    *application.CertService
 	SingboxService *application.SingboxService
+	WARPService    *application.WARPService
+	RotationSvc    *application.PortRotationService
 	HandlerConfig  *handlers.Config
 }
 
@@ -66,24 +73,26 @@ func initializeDependencies(log *zap.Logger, paths *appctx.Paths) (*AppDependenc
 	certRepo := certinfo.NewRepository(paths.CertDir)
 
 	// ==========================================
-	// 2. 加載初始配置
+	// 2. 加載初始配置 (舊版本配置自動遷移)
 	// ==========================================
-	initialConfig, err := configRepo.Load(context.Background())
+	configSvc := application.NewConfigService(configRepo, log)
+
+	// 配置文件不存在時倉庫已返回默認配置；其餘錯誤 (解析 / 解密 / 遷移失敗) 必須中止，
+	// 否則後續保存會用默認配置覆蓋用戶文件
+	initialConfig, err := configSvc.LoadWithMigration(context.Background())
 	if err != nil {
-		log.Warn("加載配置失敗，使用默認值", zap.Error(err))
-		initialConfig = domainConfig.DefaultConfig()
+		return nil, fmt.Errorf("加載配置 %s 失敗 (請修復或移走該文件後重試): %w", paths.ConfigFile, err)
 	}
 
 	// ==========================================
 	// 3. 應用服務層 (Application Layer)
 	// ==========================================
 
-	configSvc := application.NewConfigService(configRepo, log)
 	if err := configSvc.SaveWithDefaults(context.Background(), initialConfig); err != nil {
 		log.Warn("初始化保存配置失敗", zap.Error(err))
 	}
 
-	portSvc := application.NewPortService(log)
+	portSvc := application.NewPortService(log, infraSystem.NewPortScanner())
 	protocolSvc := application.NewProtocolService(log)
 	selfSignedGen := cert.NewSelfSignedGenerator(log)
 
@@ -94,6 +103,25 @@ func initializeDependencies(log *zap.Logger, paths *appctx.Paths) (*AppDependenc
 	sbGenerator := domainSingbox.NewGenerator("unknown", protoFactory)
 	sbInfraService := infraSingbox.NewService(systemdMgr, log, firewallMgr, paths)
 	singboxSvc := application.NewSingboxService(sbGenerator, sbInfraService, firewallMgr, paths, log)
+	if exe, err := os.Executable(); err == nil {
+		singboxSvc.SetDecoyServer(decoy.NewService(systemdMgr, paths.DecoyServicePath(),
+			[]string{exe, "-dir", paths.BaseDir, "decoy", "serve"}, log))
+	}
+
+	// WARP Service
+	warpSvc := application.NewWARPService(warp.NewClient("", log), log)
+
+	// 端口輪換：輪換後重新發佈訂閱文件
+	serverAddress := func(cfg *domainConfig.Config) string {
+		if cfg.Server.Host != "" && cfg.Server.Host != "0.0.0.0" {
+			return cfg.Server.Host
+		}
+		return sysInfo.PublicIPv4()
+	}
+	subPublisher := application.NewSubscriptionPublisher(
+		filepath.Join(paths.DataDir, application.SubscriptionFileName), protoFactory, serverAddress)
+	rotationSvc := application.NewPortRotationService(
+		configSvc, portSvc, singboxSvc, infraSystem.NewReachabilityProber(0), subPublisher, log)
 
 	// ==========================================
 	// 4. 狀態管理 (State Management)
@@ -130,13 +158,18 @@ func initializeDependencies(log *zap.Logger, paths *appctx.Paths) (*AppDependenc
 		Executor:        executor,
 		FirewallMgr:     firewallMgr,
 		ProtoFactory:    protoFactory,
+		WARPService:     warpSvc,
+		TrafficService:  application.NewTrafficService(log),
 	}
 
 	return &AppDependencies{
 		Log:            log,
 		Paths:          paths,
+		ConfigService:  configSvc,
 		CertService:    certSvc,
 		SingboxService: singboxSvc,
+		WARPService:    warpSvc,
+		RotationSvc:    rotationSvc,
 		HandlerConfig:  handlerCfg,
 	}, nil
 }
@@ -148,7 +181,17 @@ func runCronTask(ctx context.Context, log *zap.Logger, deps *AppDependencies) er
 		log.Error("證書檢查部分失敗", zap.Error(err))
 	}
 
-	if renewed {
+	warpApplied, err := optimizeWARPEndpoint(ctx, log, deps)
+	if err != nil {
+		log.Warn("WARP 接入點優選失敗", zap.Error(err))
+	}
+
+	rotated, err := rotatePorts(ctx, log, deps)
+	if err != nil {
+		log.Error("端口輪換失敗", zap.Error(err))
+	}
+
+	if renewed && !warpApplied && !rotated {
 		log.Info("證書已更新，重啟核心服務...")
 		if err := deps.SingboxService.Restart(ctx); err != nil {
 			return err
@@ -159,3 +202,69 @@ func runCronTask(ctx context.Context, log *zap.Logger, deps *AppDependencies) er
 
 	return nil
 }
+
+// optimizeWARPEndpoint 啟用自動優選時重新掃描 WARP 接入點
+// 接入點變化時保存配置並重新應用，返回是否已重載核心
+func optimizeWARPEndpoint(ctx context.Context, log *zap.Logger, deps *AppDependencies) (bool, error) {
+	if deps.ConfigService == nil || deps.WARPService == nil {
+		return false, nil
+	}
+
+	cfg, err := deps.ConfigService.GetConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	warpCfg := cfg.Routing.WARP
+	if !warpCfg.Enabled || !warpCfg.AutoOptimize {
+		return false, nil
+	}
+
+	log.Info("執行 WARP 接入點優選...")
+	previous := warpCfg.Endpoint
+	preferIPv6 := cfg.Routing.DomainStrategy == "ipv6_only" || cfg.Routing.DomainStrategy == "prefer_ipv6"
+
+	scanCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	best, _, err := deps.WARPService.OptimizeEndpoint(scanCtx, cfg, preferIPv6)
+	if err != nil {
+		return false, err
+	}
+	if best.Endpoint == previous {
+		log.Debug("WARP 接入點無需更換", zap.String("endpoint", previous))
+		return false, nil
+	}
+
+	if err := deps.ConfigService.UpdateConfig(ctx, func(c *domainConfig.Config) error {
+		c.Routing.WARP.Endpoint = best.Endpoint
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	log.Info("WARP 接入點已更換，重新應用配置",
+		zap.String("from", previous),
+		zap.String("to", best.Endpoint))
+	if err := deps.SingboxService.ApplyConfig(ctx, cfg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rotatePorts 按輪換策略更換端口，返回是否已重載核心
+func rotatePorts(ctx context.Context, log *zap.Logger, deps *AppDependencies) (bool, error) {
+	if deps.RotationSvc == nil {
+		return false, nil
+	}
+
+	result, err := deps.RotationSvc.Run(ctx)
+	if result == nil {
+		return false, err
+	}
+
+	log.Info("端口已輪換",
+		zap.String("reason", result.Reason),
+		zap.Int("count", len(result.Changes)))
+	return err == nil, err
+}
@@ -18,6 +18,7 @@ import (
 	"go.uber.org/zap"
 
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
 	"github.com/Yat-Muk/prism-v2/internal/infra/acme"
 	infraCert "github.com/Yat-Muk/prism-v2/internal/infra/certinfo"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
@@ -491,23 +492,13 @@ func (s *CertService) updateCertMode(ctx context.Context, domain, mode string) e
 	return s.configService.UpdateConfig(ctx, func(cfg *domainConfig.Config) error {
 		updated := false
 
-		// 檢查並更新各個協議的證書配置
-		if cfg.Protocols.Hysteria2.Enabled && cfg.Protocols.Hysteria2.SNI == domain {
-			cfg.Protocols.Hysteria2.CertMode = mode
-			cfg.Protocols.Hysteria2.CertDomain = domain
-			updated = true
-		}
-
-		if cfg.Protocols.TUIC.Enabled && cfg.Protocols.TUIC.SNI == domain {
-			cfg.Protocols.TUIC.CertMode = mode
-			cfg.Protocols.TUIC.CertDomain = domain
-			updated = true
-		}
-
-		if cfg.Protocols.AnyTLS.Enabled && cfg.Protocols.AnyTLS.SNI == domain {
-			cfg.Protocols.AnyTLS.CertMode = mode
-			cfg.Protocols.AnyTLS.CertDomain = domain
-			updated = true
+		// 檢查並更新使用本地證書的協議實例
+		for _, sec := range cfg.Protocols.Sections() {
+			if sec.NeedsCert() && *sec.Enabled && *sec.SNI == domain {
+				*sec.CertMode = mode
+				*sec.CertDomain = domain
+				updated = true
+			}
 		}
 
 		if updated {
@@ -644,57 +635,30 @@ func (s *CertService) EnsureSelfSigned(certPath, keyPath, domain string) error {
 }
 
 // ToggleProtocolCertMode 切換協議證書模式 (業務邏輯核心)
-func (s *CertService) ToggleProtocolCertMode(ctx context.Context, protocol string) (string, error) {
+// protoType 為協議類型名 (如 "hysteria2")，僅支持使用本地證書的協議
+func (s *CertService) ToggleProtocolCertMode(ctx context.Context, protoType string) (string, error) {
+	desc, ok := protocol.LookupType(protocol.Type(protoType))
+	if !ok || !desc.NeedsCert {
+		return "", fmt.Errorf("未知協議: %s", protoType)
+	}
+
 	var resultMsg string
 
 	// 使用 UpdateConfig 閉包，持有鎖
 	err := s.configService.UpdateConfig(ctx, func(cfg *domainConfig.Config) error {
-		var currentMode, sni string
-		var setConfig func(m, d, s string)
-
-		// 1. 根據協議類型定位配置字段
-		switch protocol {
-		case "hysteria2":
-			if !cfg.Protocols.Hysteria2.Enabled {
-				return fmt.Errorf("Hysteria 2 未啟用，無法修改")
-			}
-			currentMode = cfg.Protocols.Hysteria2.CertMode
-			sni = cfg.Protocols.Hysteria2.SNI
-			setConfig = func(m, d, s string) {
-				cfg.Protocols.Hysteria2.CertMode = m
-				cfg.Protocols.Hysteria2.CertDomain = d
-				if s != "" {
-					cfg.Protocols.Hysteria2.SNI = s
-				}
-			}
-		case "tuic":
-			if !cfg.Protocols.TUIC.Enabled {
-				return fmt.Errorf("TUIC 未啟用，無法修改")
-			}
-			currentMode = cfg.Protocols.TUIC.CertMode
-			sni = cfg.Protocols.TUIC.SNI
-			setConfig = func(m, d, s string) {
-				cfg.Protocols.TUIC.CertMode = m
-				cfg.Protocols.TUIC.CertDomain = d
-				if s != "" {
-					cfg.Protocols.TUIC.SNI = s
-				}
-			}
-		case "anytls":
-			if !cfg.Protocols.AnyTLS.Enabled {
-				return fmt.Errorf("AnyTLS 未啟用，無法修改")
-			}
-			currentMode = cfg.Protocols.AnyTLS.CertMode
-			sni = cfg.Protocols.AnyTLS.SNI
-			setConfig = func(m, d, s string) {
-				cfg.Protocols.AnyTLS.CertMode = m
-				cfg.Protocols.AnyTLS.CertDomain = d
-				if s != "" {
-					cfg.Protocols.AnyTLS.SNI = s
-				}
+		// 1. 定位協議配置字段
+		sec, _ := desc.Section(cfg)
+		if !*sec.Enabled {
+			return fmt.Errorf("%s 未啟用，無法修改", desc.Name)
+		}
+		currentMode := *sec.CertMode
+		sni := *sec.SNI
+		setConfig := func(m, dom, sn string) {
+			*sec.CertMode = m
+			*sec.CertDomain = dom
+			if sn != "" {
+				*sec.SNI = sn
 			}
-		default:
-			return fmt.Errorf("未知協議: %s", protocol)
 		}
 
 		// 2. 執行切換邏輯
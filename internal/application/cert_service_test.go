@@ -51,8 +51,8 @@ func TestCertService_SaveAndDelete(t *testing.T) {
 func TestCertService_ToggleProtocolCertMode(t *testing.T) {
 	// 1. 初始化環境
 	mockRepo := &MockRepo{cfg: domainConfig.DefaultConfig()}
-	mockRepo.cfg.Protocols.Hysteria2.Enabled = true
-	mockRepo.cfg.Protocols.Hysteria2.CertMode = "self_signed"
+	mockRepo.cfg.Protocols.Hysteria2().Enabled = true
+	mockRepo.cfg.Protocols.Hysteria2().CertMode = "self_signed"
 
 	logger := zap.NewNop()
 	cfgSvc := NewConfigService(mockRepo, logger)
@@ -99,7 +99,7 @@ func TestCertService_ToggleProtocolCertMode(t *testing.T) {
 	}
 
 	// 確保 SNI 一致
-	mockRepo.cfg.Protocols.Hysteria2.SNI = dummyDomain
+	mockRepo.cfg.Protocols.Hysteria2().SNI = dummyDomain
 
 	// 3. 執行切換 (self_signed -> acme)
 	msg, err := svc.ToggleProtocolCertMode(ctx, "hysteria2")
@@ -114,8 +114,8 @@ func TestCertService_ToggleProtocolCertMode(t *testing.T) {
 		t.Fatalf("獲取配置失敗: %v", err)
 	}
 
-	if updatedCfg.Protocols.Hysteria2.CertMode != "acme" {
-		t.Errorf("預期模式為 acme, 實際為 %s", updatedCfg.Protocols.Hysteria2.CertMode)
+	if updatedCfg.Protocols.Hysteria2().CertMode != "acme" {
+		t.Errorf("預期模式為 acme, 實際為 %s", updatedCfg.Protocols.Hysteria2().CertMode)
 	}
 }
 
@@ -38,7 +38,30 @@ func NewConfigService(
 
 // GetConfig 獲取當前配置
 func (s *ConfigService) GetConfig(ctx context.Context) (*config.Config, error) {
-	return s.repo.Load(ctx)
+	return s.load(ctx)
+}
+
+// load 加載配置並補齊協議主實例
+// 舊版配置 (如恢復的舊備份) 在內存中先遷移再填充默認值，寫回磁盤由 LoadWithMigration 負責
+func (s *ConfigService) load(ctx context.Context) (*config.Config, error) {
+	cfg, err := s.repo.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.migrator.NeedsMigration(cfg) {
+		newCfg, err := s.migrator.MigrateToLatest(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("遷移失敗: %w", err)
+		}
+		if err := newCfg.FillDefaults(); err != nil {
+			return nil, err
+		}
+		return newCfg, nil
+	}
+	if err := cfg.Protocols.EnsurePrimaries(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
 // UpdateConfig 原子更新配置
@@ -48,7 +71,7 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, modifier func(*config.
 	defer s.mu.Unlock()
 
 	// 1. 加載當前配置
-	currentCfg, err := s.repo.Load(ctx)
+	currentCfg, err := s.load(ctx)
 	if err != nil {
 		return fmt.Errorf("加載配置失敗: %w", err)
 	}
@@ -92,10 +115,12 @@ func (s *ConfigService) LoadWithMigration(ctx context.Context) (*config.Config,
 		return nil, fmt.Errorf("加載配置失敗: %w", err)
 	}
 
-	cfg.FillDefaults()
-
 	// 2. 檢查是否需要遷移
+	// 遷移必須先於默認值填充，否則補齊的空白主實例會覆蓋舊版協議配置
 	if !s.migrator.NeedsMigration(cfg) {
+		if err := cfg.FillDefaults(); err != nil {
+			return nil, err
+		}
 		s.logger.Info("配置已是最新版本", zap.Int("version", cfg.Version))
 		return cfg, nil
 	}
@@ -113,6 +138,9 @@ func (s *ConfigService) LoadWithMigration(ctx context.Context) (*config.Config,
 	if err != nil {
 		return nil, fmt.Errorf("遷移失敗: %w", err)
 	}
+	if err := newCfg.FillDefaults(); err != nil {
+		return nil, err
+	}
 
 	// 4. 保存遷移後的配置
 	if err := s.repo.Save(ctx, newCfg); err != nil {
@@ -142,24 +170,18 @@ func (s *ConfigService) RegenerateRealityKeys(ctx context.Context, cfg *config.C
 		return fmt.Errorf("生成 Short ID 失敗: %w", err)
 	}
 
-	// 更新所有 Reality 協議的密鑰
-	if cfg.Protocols.RealityVision.Enabled {
-		cfg.Protocols.RealityVision.PublicKey = keypair.PublicKey
-		cfg.Protocols.RealityVision.ShortID = shortID
-	}
-
-	if cfg.Protocols.RealityGRPC.Enabled {
-		cfg.Protocols.RealityGRPC.PublicKey = keypair.PublicKey
-		cfg.Protocols.RealityGRPC.ShortID = shortID
+	// 更新所有已啟用 Reality 協議的密鑰
+	for _, sec := range cfg.Protocols.Sections() {
+		if sec.NeedsReality() && *sec.Enabled {
+			*sec.PublicKey = keypair.PublicKey
+			*sec.ShortID = shortID
+		}
 	}
 
-	if cfg.Protocols.AnyTLSReality.Enabled {
-		cfg.Protocols.AnyTLSReality.PublicKey = keypair.PublicKey
-		cfg.Protocols.AnyTLSReality.ShortID = shortID
+	if err := cfg.FillDefaults(); err != nil {
+		return err
 	}
 
-	cfg.FillDefaults()
-
 	// 保存配置
 	if err := s.repo.Save(ctx, cfg); err != nil {
 		return fmt.Errorf("保存配置失敗: %w", err)
@@ -174,6 +196,8 @@ func (s *ConfigService) SaveWithDefaults(ctx context.Context, cfg *config.Config
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	cfg.FillDefaults()
+	if err := cfg.FillDefaults(); err != nil {
+		return err
+	}
 	return s.repo.Save(ctx, cfg)
 }
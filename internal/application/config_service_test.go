@@ -2,10 +2,13 @@ package application
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
 	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	infraConfig "github.com/Yat-Muk/prism-v2/internal/infra/config"
 	"go.uber.org/zap"
 )
 
@@ -94,7 +97,7 @@ func TestConfigService_SaveWithDefaults(t *testing.T) {
 	// 創建一個缺省配置 (Hysteria2 密碼為空)
 	cfg := config.DefaultConfig()
 	cfg.Password = "root-password"
-	cfg.Protocols.Hysteria2.Password = ""
+	cfg.Protocols.Hysteria2().Password = ""
 
 	// 保存並觸發 FillDefaults
 	if err := svc.SaveWithDefaults(ctx, cfg); err != nil {
@@ -103,8 +106,8 @@ func TestConfigService_SaveWithDefaults(t *testing.T) {
 
 	// 驗證是否自動填充
 	savedCfg, _ := svc.GetConfig(ctx)
-	if savedCfg.Protocols.Hysteria2.Password != "root-password" {
-		t.Errorf("FillDefaults failed to inherit password. Got: '%s'", savedCfg.Protocols.Hysteria2.Password)
+	if savedCfg.Protocols.Hysteria2().Password != "root-password" {
+		t.Errorf("FillDefaults failed to inherit password. Got: '%s'", savedCfg.Protocols.Hysteria2().Password)
 	}
 }
 
@@ -143,3 +146,81 @@ func TestConfigService_Concurrency(t *testing.T) {
 	finalCfg, _ := svc.GetConfig(ctx)
 	t.Logf("Final Port after concurrent updates: %d", finalCfg.Server.Port)
 }
+
+// TestConfigService_LoadWithMigrationV3 舊版配置文件遷移後應保留協議配置
+func TestConfigService_LoadWithMigrationV3(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte(`
+version: 3
+uuid: global-uuid
+password: global-password
+protocols:
+  hysteria2:
+    enabled: true
+    port: 45678
+    up_mbps: 77
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewConfigService(infraConfig.NewFileRepository(path, nil, zap.NewNop()), zap.NewNop())
+	cfg, err := svc.LoadWithMigration(context.Background())
+	if err != nil {
+		t.Fatalf("LoadWithMigration failed: %v", err)
+	}
+	if cfg.Version != config.ConfigVersionLatest {
+		t.Errorf("版本未更新: %d", cfg.Version)
+	}
+	hy2 := cfg.Protocols.Hysteria2()
+	if hy2 == nil || !hy2.Enabled || hy2.Port != 45678 || hy2.UpMbps != 77 {
+		t.Fatalf("Hysteria2 舊配置丟失: %+v", hy2)
+	}
+	if hy2.Password != "global-password" {
+		t.Errorf("遷移後應填充默認密碼，得到 %q", hy2.Password)
+	}
+	if cfg.Protocols.Instance(config.DefaultProtocolTag(config.ProtocolTypeTUIC)) == nil {
+		t.Error("遷移後應補齊缺失的主實例")
+	}
+
+	// 遷移結果應已寫回磁盤
+	reloaded, err := svc.GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if reloaded.Version != config.ConfigVersionLatest || reloaded.Protocols.Hysteria2().Port != 45678 {
+		t.Errorf("遷移結果未保存: v%d %+v", reloaded.Version, reloaded.Protocols.Hysteria2())
+	}
+}
+
+// TestConfigService_GetConfigV3 恢復的舊版配置經 GetConfig 讀取時應在內存中完成遷移
+func TestConfigService_GetConfigV3(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte(`
+version: 3
+uuid: global-uuid
+password: global-password
+protocols:
+  hysteria2:
+    enabled: true
+    port: 45678
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewConfigService(infraConfig.NewFileRepository(path, nil, zap.NewNop()), zap.NewNop())
+	cfg, err := svc.GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if cfg.Version != config.ConfigVersionLatest {
+		t.Errorf("版本未更新: %d", cfg.Version)
+	}
+	if hy2 := cfg.Protocols.Hysteria2(); !hy2.Enabled || hy2.Port != 45678 {
+		t.Errorf("Hysteria2 舊配置丟失: %+v", hy2)
+	}
+	if cfg.Protocols.AnyTLS() == nil {
+		t.Error("遷移後應補齊缺失的主實例")
+	}
+}
@@ -0,0 +1,233 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	infraSystem "github.com/Yat-Muk/prism-v2/internal/infra/system"
+	"go.uber.org/zap"
+)
+
+// 輪換原因
+const (
+	RotationReasonScheduled = "scheduled" // 定時輪換到期
+	RotationReasonProbe     = "probe"     // 可達性探測失敗
+)
+
+// RotationConfigStore 輪換流程使用的配置讀寫接口 (由 ConfigService 實現)
+type RotationConfigStore interface {
+	GetConfig(ctx context.Context) (*domainConfig.Config, error)
+	UpdateConfig(ctx context.Context, modifier func(*domainConfig.Config) error) error
+}
+
+// ConfigApplier 將配置應用到核心 (由 SingboxService 實現)
+type ConfigApplier interface {
+	ApplyConfig(ctx context.Context, cfg *domainConfig.Config) error
+}
+
+// ReachabilityProber 端口可達性探測
+type ReachabilityProber interface {
+	Probe(ctx context.Context, target infraSystem.ProbeTarget) error
+}
+
+// PortChange 單個協議的端口變化
+type PortChange struct {
+	Protocol protocol.ID
+	From     int
+	To       int
+}
+
+// RotationResult 一次輪換的結果
+type RotationResult struct {
+	Reason  string
+	Changes []PortChange
+}
+
+// PortRotationService 按策略自動輪換協議端口
+// 流程：判斷到期 / 探測失敗 -> PortService 分配新端口 -> ApplyConfig -> 保存 -> 發佈訂閱
+type PortRotationService struct {
+	store     RotationConfigStore
+	ports     PortService
+	applier   ConfigApplier
+	prober    ReachabilityProber
+	publisher *SubscriptionPublisher
+	log       *zap.Logger
+	now       func() time.Time
+}
+
+// NewPortRotationService 創建端口輪換服務
+// prober 為 nil 時忽略探測策略，publisher 為 nil 時不更新訂閱文件
+func NewPortRotationService(
+	store RotationConfigStore,
+	ports PortService,
+	applier ConfigApplier,
+	prober ReachabilityProber,
+	publisher *SubscriptionPublisher,
+	log *zap.Logger,
+) *PortRotationService {
+	return &PortRotationService{
+		store:     store,
+		ports:     ports,
+		applier:   applier,
+		prober:    prober,
+		publisher: publisher,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// Run 執行一次輪換檢查，未發生輪換時返回 nil 結果
+func (s *PortRotationService) Run(ctx context.Context) (*RotationResult, error) {
+	cfg, err := s.store.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := cfg.Rotation
+	if !policy.Enabled {
+		return nil, nil
+	}
+
+	targets := rotationTargets(cfg)
+	if len(targets) == 0 {
+		s.log.Debug("沒有參與輪換的已啟用協議")
+		return nil, nil
+	}
+
+	now := s.now()
+
+	// 首次執行只記錄起點，避免啟用策略後立即更換端口
+	if policy.IntervalDuration() > 0 && policy.LastRotated.IsZero() {
+		if err := s.store.UpdateConfig(ctx, func(c *domainConfig.Config) error {
+			c.Rotation.LastRotated = now
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		reason string
+		rotate []protocol.ID
+	)
+	switch {
+	case policy.Due(now):
+		reason, rotate = RotationReasonScheduled, targets
+	case policy.Probe:
+		reason, rotate = RotationReasonProbe, s.unreachable(ctx, cfg, targets)
+	}
+	if len(rotate) == 0 {
+		return nil, nil
+	}
+
+	result := &RotationResult{Reason: reason}
+	newCfg := cfg
+	for _, id := range rotate {
+		from := protocol.PortOf(newCfg, id)
+		newCfg, err = s.ports.UpdateSinglePort(ctx, newCfg, int(id), "random")
+		if err != nil {
+			return nil, fmt.Errorf("輪換 %s 端口失敗: %w", id, err)
+		}
+		result.Changes = append(result.Changes, PortChange{Protocol: id, From: from, To: protocol.PortOf(newCfg, id)})
+	}
+	newCfg.Rotation.LastRotated = now
+
+	// 先應用再保存：應用失敗時配置文件保持原端口，下次執行重試
+	if err := s.applier.ApplyConfig(ctx, newCfg); err != nil {
+		return nil, fmt.Errorf("應用輪換後的配置失敗: %w", err)
+	}
+
+	if err := s.store.UpdateConfig(ctx, func(c *domainConfig.Config) error {
+		for _, change := range result.Changes {
+			protocol.SetPort(c, change.Protocol, change.To)
+		}
+		c.Rotation.LastRotated = now
+		return nil
+	}); err != nil {
+		// 保存失敗時恢復原配置，避免核心與配置文件端口不一致
+		if rollbackErr := s.applier.ApplyConfig(ctx, cfg); rollbackErr != nil {
+			s.log.Error("恢復輪換前的配置失敗", zap.Error(rollbackErr))
+		}
+		return nil, fmt.Errorf("保存輪換後的配置失敗: %w", err)
+	}
+
+	for _, change := range result.Changes {
+		s.log.Info("已輪換協議端口",
+			zap.String("protocol", change.Protocol.String()),
+			zap.Int("from", change.From),
+			zap.Int("to", change.To),
+			zap.String("reason", reason))
+	}
+
+	if s.publisher != nil {
+		if err := s.publisher.Publish(newCfg); err != nil {
+			return result, fmt.Errorf("發佈訂閱失敗: %w", err)
+		}
+		s.log.Info("訂閱已更新", zap.String("path", s.publisher.Path()))
+	}
+
+	return result, nil
+}
+
+// unreachable 返回探測失敗的協議
+// 未配置探測服務與探測過程中的取消均不觸發輪換
+func (s *PortRotationService) unreachable(ctx context.Context, cfg *domainConfig.Config, targets []protocol.ID) []protocol.ID {
+	if s.prober == nil {
+		return nil
+	}
+
+	host := cfg.Server.Host
+	if s.publisher != nil {
+		host = s.publisher.Address(cfg)
+	}
+
+	var failed []protocol.ID
+	for _, id := range targets {
+		target := infraSystem.ProbeTarget{
+			Network:  id.Network(),
+			Host:     host,
+			Port:     protocol.PortOf(cfg, id),
+			CheckURL: cfg.Rotation.ProbeURL,
+		}
+
+		err := s.prober.Probe(ctx, target)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, infraSystem.ErrProbeUnsupported):
+			s.log.Debug("無法探測協議端口，跳過",
+				zap.String("protocol", id.String()),
+				zap.Int("port", target.Port),
+				zap.String("network", target.Network))
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(err, infraSystem.ErrPortUnreachable):
+			s.log.Warn("協議端口不可達",
+				zap.String("protocol", id.String()),
+				zap.Error(err))
+			failed = append(failed, id)
+		default:
+			// 探測服務自身故障時無法判斷端口狀態，不能據此輪換，否則會斷開所有客戶端
+			s.log.Warn("探測服務不可用，跳過本次探測",
+				zap.String("protocol", id.String()),
+				zap.Error(err))
+		}
+	}
+	return failed
+}
+
+// rotationTargets 返回參與輪換的已啟用協議
+func rotationTargets(cfg *domainConfig.Config) []protocol.ID {
+	var ids []protocol.ID
+	for _, id := range protocol.AllIDs() {
+		if !protocol.IsEnabled(cfg, id) || !cfg.Rotation.Includes(int(id)) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
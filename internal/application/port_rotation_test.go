@@ -0,0 +1,262 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	infraSystem "github.com/Yat-Muk/prism-v2/internal/infra/system"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+	"go.uber.org/zap"
+)
+
+// memoryConfigStore 內存配置倉庫
+type memoryConfigStore struct {
+	cfg *domainConfig.Config
+}
+
+func (m *memoryConfigStore) GetConfig(ctx context.Context) (*domainConfig.Config, error) {
+	return m.cfg.DeepCopy(), nil
+}
+
+func (m *memoryConfigStore) UpdateConfig(ctx context.Context, modifier func(*domainConfig.Config) error) error {
+	next := m.cfg.DeepCopy()
+	if err := modifier(next); err != nil {
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	m.cfg = next
+	return nil
+}
+
+type recordingApplier struct {
+	applied []*domainConfig.Config
+	err     error
+}
+
+func (r *recordingApplier) ApplyConfig(ctx context.Context, cfg *domainConfig.Config) error {
+	r.applied = append(r.applied, cfg)
+	return r.err
+}
+
+// fakeProber 按端口返回探測結果
+type fakeProber struct {
+	down  map[int]bool
+	calls []infraSystem.ProbeTarget
+}
+
+func (f *fakeProber) Probe(ctx context.Context, target infraSystem.ProbeTarget) error {
+	f.calls = append(f.calls, target)
+	if target.CheckURL == "" {
+		return infraSystem.ErrProbeUnsupported
+	}
+	if f.down[target.Port] {
+		return fmt.Errorf("%w: timeout", infraSystem.ErrPortUnreachable)
+	}
+	return nil
+}
+
+func rotationFixture(t *testing.T) (*domainConfig.Config, *SubscriptionPublisher) {
+	t.Helper()
+	cfg := domainConfig.DefaultConfig()
+	cfg.Server.Host = "203.0.113.5"
+	cfg.Protocols.RealityVision().Enabled = true
+	cfg.Protocols.RealityVision().Port = 21001
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.Hysteria2().Port = 21002
+	cfg.Protocols.TUIC().Enabled = false
+	cfg.Protocols.AnyTLS().Enabled = false
+	cfg.Protocols.AnyTLSReality().Enabled = false
+	cfg.Protocols.RealityGRPC().Enabled = false
+	cfg.Protocols.ShadowTLS().Enabled = false
+
+	paths, err := appctx.NewPaths(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(paths.DataDir, SubscriptionFileName)
+	publisher := NewSubscriptionPublisher(path, protocol.NewFactory(paths), nil)
+	return cfg, publisher
+}
+
+func TestPortRotationScheduled(t *testing.T) {
+	cfg, publisher := rotationFixture(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg.Rotation = domainConfig.PortRotationConfig{
+		Enabled:     true,
+		Protocols:   []int{int(protocol.IDRealityVision)},
+		Interval:    "72h",
+		LastRotated: now.Add(-73 * time.Hour),
+	}
+
+	store := &memoryConfigStore{cfg: cfg}
+	applier := &recordingApplier{}
+	svc := NewPortRotationService(store, NewPortService(zap.NewNop(), nil), applier, nil, publisher, zap.NewNop())
+	svc.now = func() time.Time { return now }
+
+	result, err := svc.Run(context.Background())
+	if err != nil {
+		t.Fatalf("輪換失敗: %v", err)
+	}
+	if result == nil || result.Reason != RotationReasonScheduled || len(result.Changes) != 1 {
+		t.Fatalf("應定時輪換 Reality Vision, got %+v", result)
+	}
+
+	newPort := store.cfg.Protocols.RealityVision().Port
+	if newPort == 21001 || newPort != result.Changes[0].To {
+		t.Errorf("保存的端口未更新: %d", newPort)
+	}
+	if store.cfg.Protocols.Hysteria2().Port != 21002 {
+		t.Error("未參與輪換的協議端口不應變化")
+	}
+	if !store.cfg.Rotation.LastRotated.Equal(now) {
+		t.Errorf("應記錄輪換時間, got %v", store.cfg.Rotation.LastRotated)
+	}
+	if len(applier.applied) != 1 || applier.applied[0].Protocols.RealityVision().Port != newPort {
+		t.Error("輪換後應以新端口應用配置")
+	}
+
+	data, err := os.ReadFile(publisher.Path())
+	if err != nil {
+		t.Fatalf("訂閱文件未生成: %v", err)
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(string(data))
+	if !strings.Contains(string(decoded), "@203.0.113.5:"+strconv.Itoa(newPort)) {
+		t.Errorf("訂閱應包含新端口: %s", decoded)
+	}
+
+	// 未到期時不再輪換
+	result, err = svc.Run(context.Background())
+	if err != nil || result != nil {
+		t.Errorf("未到期不應輪換, got %+v, %v", result, err)
+	}
+}
+
+func TestPortRotationApplyFailureKeepsPorts(t *testing.T) {
+	cfg, publisher := rotationFixture(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg.Rotation = domainConfig.PortRotationConfig{
+		Enabled:     true,
+		Interval:    "72h",
+		LastRotated: now.Add(-73 * time.Hour),
+	}
+
+	store := &memoryConfigStore{cfg: cfg}
+	applier := &recordingApplier{err: errors.New("sing-box check failed")}
+	svc := NewPortRotationService(store, NewPortService(zap.NewNop(), nil), applier, nil, publisher, zap.NewNop())
+	svc.now = func() time.Time { return now }
+
+	if _, err := svc.Run(context.Background()); err == nil {
+		t.Fatal("應用失敗時應返回錯誤")
+	}
+	if store.cfg.Protocols.RealityVision().Port != 21001 || !store.cfg.Rotation.LastRotated.Equal(now.Add(-73*time.Hour)) {
+		t.Error("應用失敗時不應保存新端口")
+	}
+	if _, err := os.Stat(publisher.Path()); err == nil {
+		t.Error("應用失敗時不應發佈訂閱")
+	}
+}
+
+func TestPortRotationFirstRunRecordsStart(t *testing.T) {
+	cfg, publisher := rotationFixture(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg.Rotation = domainConfig.PortRotationConfig{Enabled: true, Interval: "24h"}
+
+	store := &memoryConfigStore{cfg: cfg}
+	applier := &recordingApplier{}
+	svc := NewPortRotationService(store, NewPortService(zap.NewNop(), nil), applier, nil, publisher, zap.NewNop())
+	svc.now = func() time.Time { return now }
+
+	result, err := svc.Run(context.Background())
+	if err != nil || result != nil {
+		t.Fatalf("首次執行不應輪換, got %+v, %v", result, err)
+	}
+	if !store.cfg.Rotation.LastRotated.Equal(now) {
+		t.Error("首次執行應記錄起始時間")
+	}
+	if len(applier.applied) != 0 {
+		t.Error("首次執行不應重載核心")
+	}
+}
+
+func TestPortRotationProbeFailure(t *testing.T) {
+	cfg, publisher := rotationFixture(t)
+	cfg.Rotation = domainConfig.PortRotationConfig{Enabled: true, Probe: true, ProbeURL: "https://probe.example.com/?p={port}"}
+
+	store := &memoryConfigStore{cfg: cfg}
+	applier := &recordingApplier{}
+	prober := &fakeProber{down: map[int]bool{21001: true}}
+	svc := NewPortRotationService(store, NewPortService(zap.NewNop(), nil), applier, prober, publisher, zap.NewNop())
+
+	result, err := svc.Run(context.Background())
+	if err != nil {
+		t.Fatalf("輪換失敗: %v", err)
+	}
+	if result == nil || result.Reason != RotationReasonProbe {
+		t.Fatalf("探測失敗應觸發輪換, got %+v", result)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Protocol != protocol.IDRealityVision {
+		t.Errorf("只應輪換不可達的協議, got %+v", result.Changes)
+	}
+	if store.cfg.Protocols.Hysteria2().Port != 21002 {
+		t.Error("可達的協議不應輪換")
+	}
+	if len(prober.calls) != 2 || prober.calls[0].Host != "203.0.113.5" {
+		t.Errorf("探測目標錯誤: %+v", prober.calls)
+	}
+
+	// 全部可達時不輪換
+	prober.down = nil
+	applier.applied = nil
+	result, err = svc.Run(context.Background())
+	if err != nil || result != nil || len(applier.applied) != 0 {
+		t.Errorf("端口可達時不應輪換, got %+v, %v", result, err)
+	}
+}
+
+func TestPortRotationProbeServiceDown(t *testing.T) {
+	cfg, publisher := rotationFixture(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	srv.Close()
+	cfg.Rotation = domainConfig.PortRotationConfig{Enabled: true, Probe: true, ProbeURL: srv.URL + "/?p={port}"}
+
+	store := &memoryConfigStore{cfg: cfg}
+	applier := &recordingApplier{}
+	prober := infraSystem.NewReachabilityProber(time.Second)
+	svc := NewPortRotationService(store, NewPortService(zap.NewNop(), nil), applier, prober, publisher, zap.NewNop())
+
+	result, err := svc.Run(context.Background())
+	if err != nil || result != nil || len(applier.applied) != 0 {
+		t.Fatalf("探測服務不可用時不應輪換, got %+v, %v", result, err)
+	}
+	if store.cfg.Protocols.RealityVision().Port != 21001 || store.cfg.Protocols.Hysteria2().Port != 21002 {
+		t.Error("端口不應變化")
+	}
+}
+
+func TestPortRotationDisabled(t *testing.T) {
+	cfg, publisher := rotationFixture(t)
+	store := &memoryConfigStore{cfg: cfg}
+	prober := &fakeProber{down: map[int]bool{21001: true}}
+	svc := NewPortRotationService(store, NewPortService(zap.NewNop(), nil), &recordingApplier{}, prober, publisher, zap.NewNop())
+
+	result, err := svc.Run(context.Background())
+	if err != nil || result != nil || len(prober.calls) != 0 {
+		t.Errorf("未啟用策略時不應探測或輪換, got %+v, %v", result, err)
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"strings"
 
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	infraSystem "github.com/Yat-Muk/prism-v2/internal/infra/system"
 	"go.uber.org/zap"
 )
 
@@ -19,8 +21,11 @@ type PortService interface {
 	// UpdateSinglePort 更新單個協議端口，支持數字輸入或 "random"
 	UpdateSinglePort(ctx context.Context, cfg *domainConfig.Config, protoID int, portInput string) (*domainConfig.Config, error)
 
-	// UpdateHy2Hopping 更新 Hysteria2 跳躍端口範圍
-	UpdateHy2Hopping(ctx context.Context, cfg *domainConfig.Config, startPort, endPort int) (*domainConfig.Config, error)
+	// UpdateHy2Hopping 更新 Hysteria2 跳躍端口，支持逗號分隔的多個範圍 (如 "20000-30000,40000-45000")
+	UpdateHy2Hopping(ctx context.Context, cfg *domainConfig.Config, spec string) (*domainConfig.Config, error)
+
+	// UpdateHy2HopInterval 更新客戶端跳躍間隔 (如 "30s" 或秒數)，0 或空字符串恢復客戶端默認值
+	UpdateHy2HopInterval(ctx context.Context, cfg *domainConfig.Config, interval string) (*domainConfig.Config, error)
 
 	// ClearHy2Hopping 清除 Hysteria2 跳躍端口
 	ClearHy2Hopping(ctx context.Context, cfg *domainConfig.Config) (*domainConfig.Config, error)
@@ -29,32 +34,34 @@ type PortService interface {
 	GetPort(cfg *domainConfig.Config, protoID int) int
 }
 
+// PortChecker 查詢系統當前監聽的端口
+type PortChecker interface {
+	Listeners() ([]infraSystem.Listener, error)
+}
+
 // portService 端口管理服務實現
 type portService struct {
-	log *zap.Logger
+	log     *zap.Logger
+	checker PortChecker
 }
 
 // NewPortService 創建端口管理服務
-func NewPortService(log *zap.Logger) PortService {
+// checker 為 nil 時只檢查 Prism 配置內部的端口衝突
+func NewPortService(log *zap.Logger, checker PortChecker) PortService {
 	return &portService{
-		log: log,
+		log:     log,
+		checker: checker,
 	}
 }
 
-// 定義端口設置函數類型
-type portSetter func(cfg *domainConfig.Config, port int)
+const (
+	randomPortMin      = 10000
+	randomPortSpan     = 50000
+	randomPortAttempts = 200
 
-// 核心映射表：將協議 ID 映射到具體的 Config 修改邏輯
-// 這消除了巨大的 switch 語句，符合開閉原則
-var portStrategy = map[protocol.ID]portSetter{
-	protocol.IDRealityVision: func(c *domainConfig.Config, p int) { c.Protocols.RealityVision.Port = p },
-	protocol.IDRealityGRPC:   func(c *domainConfig.Config, p int) { c.Protocols.RealityGRPC.Port = p },
-	protocol.IDHysteria2:     func(c *domainConfig.Config, p int) { c.Protocols.Hysteria2.Port = p },
-	protocol.IDTUIC:          func(c *domainConfig.Config, p int) { c.Protocols.TUIC.Port = p },
-	protocol.IDAnyTLS:        func(c *domainConfig.Config, p int) { c.Protocols.AnyTLS.Port = p },
-	protocol.IDAnyTLSReality: func(c *domainConfig.Config, p int) { c.Protocols.AnyTLSReality.Port = p },
-	protocol.IDShadowTLS:     func(c *domainConfig.Config, p int) { c.Protocols.ShadowTLS.Port = p },
-}
+	// 自身進程持有的監聽端口不視為衝突
+	singboxProcess = "sing-box"
+)
 
 // ResetAllPorts 為所有協議隨機生成不衝突的端口
 func (s *portService) ResetAllPorts(ctx context.Context, cfg *domainConfig.Config) (*domainConfig.Config, error) {
@@ -69,11 +76,19 @@ func (s *portService) ResetAllPorts(ctx context.Context, cfg *domainConfig.Confi
 	usedPorts[443] = true
 	usedPorts[22] = true
 
+	// 避開系統中其他程序已佔用的端口
+	for _, l := range s.systemListeners() {
+		if l.Process != singboxProcess {
+			usedPorts[l.Port] = true
+		}
+	}
+	reserved := len(usedPorts)
+
 	// 定義隨機端口生成函數
 	generateUniquePort := func() int {
 		for {
 			// 生成 10000 - 60000 之間的端口
-			p := rand.Intn(50000) + 10000
+			p := rand.Intn(randomPortSpan) + randomPortMin
 			if !usedPorts[p] {
 				usedPorts[p] = true
 				return p
@@ -81,48 +96,39 @@ func (s *portService) ResetAllPorts(ctx context.Context, cfg *domainConfig.Confi
 		}
 	}
 
-	// 1. Reality Vision
-	newCfg.Protocols.RealityVision.Port = generateUniquePort()
-
-	// 2. Reality gRPC
-	newCfg.Protocols.RealityGRPC.Port = generateUniquePort()
-
-	// 3. Hysteria 2
-	newCfg.Protocols.Hysteria2.Port = generateUniquePort()
-	newCfg.Protocols.Hysteria2.PortHopping = ""
-
-	// 4. TUIC
-	newCfg.Protocols.TUIC.Port = generateUniquePort()
-
-	// 5. AnyTLS
-	newCfg.Protocols.AnyTLS.Port = generateUniquePort()
-
-	// 6. AnyTLS Reality
-	newCfg.Protocols.AnyTLSReality.Port = generateUniquePort()
-
-	// 7. ShadowTLS (需要兩個端口: 監聽端口 + Handshake端口)
-	newCfg.Protocols.ShadowTLS.Port = generateUniquePort()
-	// ShadowTLS Detour 端口也需要唯一
-	// 注意：這裡假設 ShadowTLS 實現中 DetourPort 是導出的並需要配置
-	// newCfg.Protocols.ShadowTLS.DetourPort = generateUniquePort()
+	if err := newCfg.Protocols.EnsurePrimaries(); err != nil {
+		return nil, err
+	}
+	for _, sec := range newCfg.Protocols.Sections() {
+		*sec.Port = generateUniquePort()
+	}
+	// 跳躍範圍可能與新端口重疊，需重新設置
+	for _, inst := range newCfg.Protocols.InstancesOf(domainConfig.ProtocolTypeHysteria2) {
+		inst.Hysteria2.PortHopping = ""
+	}
 
-	s.log.Info("端口重置完成", zap.Int("count", len(usedPorts)-3))
+	s.log.Info("端口重置完成", zap.Int("count", len(usedPorts)-reserved))
 	return newCfg, nil
 }
 
-// UpdateSinglePort 重構版：使用 Map 查找策略
+// UpdateSinglePort 更新單個協議的端口
 func (s *portService) UpdateSinglePort(ctx context.Context, cfg *domainConfig.Config, protoID int, portInput string) (*domainConfig.Config, error) {
 	// 1. 驗證 ID
 	pID := protocol.ID(protoID)
-	setter, exists := portStrategy[pID]
-	if !exists {
+	if !pID.IsValid() {
 		return nil, fmt.Errorf("不支持的協議 ID: %d", protoID)
 	}
 
-	// 2. 解析端口 (邏輯保持不變，但代碼更乾淨)
+	listeners := s.systemListeners()
+
+	// 2. 解析端口並檢查衝突
 	var p int
 	if portInput == "random" {
-		p = rand.Intn(50000) + 10000
+		var err error
+		p, err = randomFreePort(cfg, pID.Tag(), pID.Network(), listeners)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		var err error
 		p, err = strconv.Atoi(portInput)
@@ -132,11 +138,14 @@ func (s *portService) UpdateSinglePort(ctx context.Context, cfg *domainConfig.Co
 		if p < 1024 || p > 65535 {
 			return nil, fmt.Errorf("端口範圍必須在 1024-65535 之間")
 		}
+		if err := checkPortConflict(cfg, pID.Tag(), pID.Network(), p, listeners); err != nil {
+			return nil, err
+		}
 	}
 
 	// 3. 應用修改
 	newCfg := cfg.DeepCopy()
-	setter(newCfg, p)
+	protocol.SetPort(newCfg, pID, p)
 
 	s.log.Info("已更新協議端口",
 		zap.String("protocol", pID.String()), // 使用 String() 獲取可讀名稱
@@ -147,27 +156,58 @@ func (s *portService) UpdateSinglePort(ctx context.Context, cfg *domainConfig.Co
 }
 
 // UpdateHy2Hopping 設置 Hysteria2 端口跳躍範圍
-func (s *portService) UpdateHy2Hopping(ctx context.Context, cfg *domainConfig.Config, startPort, endPort int) (*domainConfig.Config, error) {
+func (s *portService) UpdateHy2Hopping(ctx context.Context, cfg *domainConfig.Config, spec string) (*domainConfig.Config, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("配置不能為空")
 	}
 
-	if startPort < 1024 || endPort > 65535 || endPort <= startPort {
-		return nil, fmt.Errorf("跳躍端口範圍必須在 1024-65535 且 start < end")
+	ranges, err := domainConfig.ParsePortRanges(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkHoppingConflict(cfg, protocol.IDHysteria2.Tag(), ranges, s.systemListeners()); err != nil {
+		return nil, err
 	}
 
 	// 使用深拷貝
 	newCfg := cfg.DeepCopy()
-	newCfg.Protocols.Hysteria2.PortHopping = fmt.Sprintf("%d-%d", startPort, endPort)
+	newCfg.Protocols.Hysteria2().PortHopping = domainConfig.FormatPortRanges(ranges)
 
 	s.log.Info("已設置 Hysteria2 端口跳躍",
-		zap.Int("start_port", startPort),
-		zap.Int("end_port", endPort),
+		zap.String("ranges", newCfg.Protocols.Hysteria2().PortHopping),
 	)
 
 	return newCfg, nil
 }
 
+// UpdateHy2HopInterval 設置客戶端跳躍間隔
+func (s *portService) UpdateHy2HopInterval(ctx context.Context, cfg *domainConfig.Config, interval string) (*domainConfig.Config, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("配置不能為空")
+	}
+
+	interval = strings.TrimSpace(interval)
+	// 允許直接輸入秒數，0 表示恢復客戶端默認值
+	if n, err := strconv.Atoi(interval); err == nil {
+		interval = ""
+		if n != 0 {
+			interval = strconv.Itoa(n) + "s"
+		}
+	}
+
+	check := domainConfig.Hysteria2Config{HopInterval: interval}
+	if err := check.ValidatePortHopping(); err != nil {
+		return nil, err
+	}
+
+	newCfg := cfg.DeepCopy()
+	newCfg.Protocols.Hysteria2().HopInterval = interval
+
+	s.log.Info("已設置 Hysteria2 跳躍間隔", zap.String("interval", interval))
+	return newCfg, nil
+}
+
 // ClearHy2Hopping 清除 Hysteria2 端口跳躍設置
 func (s *portService) ClearHy2Hopping(ctx context.Context, cfg *domainConfig.Config) (*domainConfig.Config, error) {
 	if cfg == nil {
@@ -176,7 +216,7 @@ func (s *portService) ClearHy2Hopping(ctx context.Context, cfg *domainConfig.Con
 
 	// 使用深拷貝
 	newCfg := cfg.DeepCopy()
-	newCfg.Protocols.Hysteria2.PortHopping = ""
+	newCfg.Protocols.Hysteria2().PortHopping = ""
 
 	s.log.Info("已清除 Hysteria2 端口跳躍")
 
@@ -185,26 +225,138 @@ func (s *portService) ClearHy2Hopping(ctx context.Context, cfg *domainConfig.Con
 
 // GetPort 實現
 func (s *portService) GetPort(cfg *domainConfig.Config, protoID int) int {
-	if cfg == nil {
-		return 0
-	}
-	// 使用 switch 進行映射，清晰直觀
-	switch protocol.ID(protoID) {
-	case protocol.IDRealityVision:
-		return cfg.Protocols.RealityVision.Port
-	case protocol.IDRealityGRPC:
-		return cfg.Protocols.RealityGRPC.Port
-	case protocol.IDHysteria2:
-		return cfg.Protocols.Hysteria2.Port
-	case protocol.IDTUIC:
-		return cfg.Protocols.TUIC.Port
-	case protocol.IDAnyTLS:
-		return cfg.Protocols.AnyTLS.Port
-	case protocol.IDAnyTLSReality:
-		return cfg.Protocols.AnyTLSReality.Port
-	case protocol.IDShadowTLS:
-		return cfg.Protocols.ShadowTLS.Port
-	default:
-		return 0
+	return protocol.PortOf(cfg, protocol.ID(protoID))
+}
+
+// systemListeners 讀取系統監聽端口，失敗時只記錄警告
+func (s *portService) systemListeners() []infraSystem.Listener {
+	if s.checker == nil {
+		return nil
+	}
+	listeners, err := s.checker.Listeners()
+	if err != nil {
+		s.log.Warn("無法讀取系統端口佔用，跳過檢查", zap.Error(err))
+		return nil
+	}
+	return listeners
+}
+
+// randomFreePort 為協議實例隨機選擇一個不衝突的端口
+func randomFreePort(cfg *domainConfig.Config, tag, network string, listeners []infraSystem.Listener) (int, error) {
+	for i := 0; i < randomPortAttempts; i++ {
+		p := rand.Intn(randomPortSpan) + randomPortMin
+		if checkPortConflict(cfg, tag, network, p, listeners) == nil {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("無法找到可用的隨機端口，請手動指定")
+}
+
+// checkPortConflict 檢查協議實例使用指定端口是否與其他入站、跳躍範圍或系統程序衝突
+// tag 對應的實例可以尚未加入配置 (如新增實例時)
+func checkPortConflict(cfg *domainConfig.Config, tag, network string, port int, listeners []infraSystem.Listener) error {
+	for i := range cfg.Protocols.Instances {
+		inst := &cfg.Protocols.Instances[i]
+		sec, ok := inst.Section()
+		if !ok || inst.Tag == tag || sec.Network != network || !*sec.Enabled {
+			continue
+		}
+		if *sec.Port == port {
+			return fmt.Errorf("端口 %d 已被 %s 使用", port, protocol.InstanceName(inst))
+		}
+	}
+
+	// Clash API 由 sing-box 自身監聽，系統端口檢查無法識別
+	if network == "tcp" && cfg.ClashAPI.IsEnabled() && cfg.ClashAPI.Port() == port {
+		return fmt.Errorf("端口 %d 已被 Clash API 使用", port)
+	}
+
+	// 跳躍範圍內的 UDP 流量會被重定向到對應的 Hysteria2 實例
+	if network == "udp" {
+		for _, h := range hy2Hopping(cfg) {
+			if h.tag == tag {
+				continue
+			}
+			for _, r := range h.ranges {
+				if r.Contains(port) {
+					return fmt.Errorf("端口 %d 位於 %s 跳躍範圍 %s 內", port, h.name, r)
+				}
+			}
+		}
+	}
+
+	// 協議當前端口由自身佔用時不算衝突
+	if inst := cfg.Protocols.Instance(tag); inst != nil {
+		if sec, ok := inst.Section(); ok && *sec.Port == port {
+			return nil
+		}
+	}
+	for _, l := range listeners {
+		if l.Network == network && l.Port == port && l.Process != singboxProcess {
+			return fmt.Errorf("端口 %d/%s 已被 %s 佔用", port, network, listenerOwner(l))
+		}
+	}
+	return nil
+}
+
+// checkHoppingConflict 檢查 Hysteria2 實例的跳躍範圍是否覆蓋其他 UDP 入站、其他跳躍範圍或系統程序的端口
+func checkHoppingConflict(cfg *domainConfig.Config, tag string, ranges []domainConfig.PortRange, listeners []infraSystem.Listener) error {
+	for _, r := range ranges {
+		for i := range cfg.Protocols.Instances {
+			inst := &cfg.Protocols.Instances[i]
+			sec, ok := inst.Section()
+			if !ok || inst.Tag == tag || sec.Network != "udp" || !*sec.Enabled {
+				continue
+			}
+			if r.Contains(*sec.Port) {
+				return fmt.Errorf("跳躍範圍 %s 與 %s 端口 %d 重疊", r, protocol.InstanceName(inst), *sec.Port)
+			}
+		}
+
+		for _, h := range hy2Hopping(cfg) {
+			if h.tag == tag {
+				continue
+			}
+			for _, other := range h.ranges {
+				if r.Start <= other.End && other.Start <= r.End {
+					return fmt.Errorf("跳躍範圍 %s 與 %s 跳躍範圍 %s 重疊", r, h.name, other)
+				}
+			}
+		}
+
+		for _, l := range listeners {
+			if l.Network == "udp" && r.Contains(l.Port) && l.Process != singboxProcess {
+				return fmt.Errorf("跳躍範圍 %s 與 %s 佔用的端口 %d/udp 重疊", r, listenerOwner(l), l.Port)
+			}
+		}
+	}
+	return nil
+}
+
+// hopping 單個 Hysteria2 實例的跳躍範圍
+type hopping struct {
+	tag    string
+	name   string
+	ranges []domainConfig.PortRange
+}
+
+// hy2Hopping 返回所有已啟用 Hysteria2 實例的跳躍範圍
+func hy2Hopping(cfg *domainConfig.Config) []hopping {
+	var result []hopping
+	for _, inst := range cfg.Protocols.InstancesOf(domainConfig.ProtocolTypeHysteria2) {
+		if !inst.Hysteria2.Enabled {
+			continue
+		}
+		if ranges := inst.Hysteria2.HoppingRanges(); len(ranges) > 0 {
+			result = append(result, hopping{tag: inst.Tag, name: protocol.InstanceName(inst), ranges: ranges})
+		}
+	}
+	return result
+}
+
+func listenerOwner(l infraSystem.Listener) string {
+	if l.Process == "" {
+		return "其他程序"
 	}
+	return l.Process
 }
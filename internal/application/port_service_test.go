@@ -2,17 +2,20 @@ package application
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	infraSystem "github.com/Yat-Muk/prism-v2/internal/infra/system"
 	"go.uber.org/zap"
 )
 
 // TestResetAllPorts 測試全局端口重置
 func TestResetAllPorts(t *testing.T) {
 	logger := zap.NewNop()
-	svc := NewPortService(logger)
+	svc := NewPortService(logger, nil)
 	cfg := domainConfig.DefaultConfig()
 	ctx := context.Background()
 
@@ -34,18 +37,18 @@ func TestResetAllPorts(t *testing.T) {
 		ports[p] = true
 	}
 
-	checkPort(newCfg.Protocols.RealityVision.Port, "RealityVision")
-	checkPort(newCfg.Protocols.RealityGRPC.Port, "RealityGRPC")
-	checkPort(newCfg.Protocols.Hysteria2.Port, "Hysteria2")
-	checkPort(newCfg.Protocols.TUIC.Port, "TUIC")
-	checkPort(newCfg.Protocols.AnyTLS.Port, "AnyTLS")
-	checkPort(newCfg.Protocols.AnyTLSReality.Port, "AnyTLSReality")
-	checkPort(newCfg.Protocols.ShadowTLS.Port, "ShadowTLS")
+	checkPort(newCfg.Protocols.RealityVision().Port, "RealityVision")
+	checkPort(newCfg.Protocols.RealityGRPC().Port, "RealityGRPC")
+	checkPort(newCfg.Protocols.Hysteria2().Port, "Hysteria2")
+	checkPort(newCfg.Protocols.TUIC().Port, "TUIC")
+	checkPort(newCfg.Protocols.AnyTLS().Port, "AnyTLS")
+	checkPort(newCfg.Protocols.AnyTLSReality().Port, "AnyTLSReality")
+	checkPort(newCfg.Protocols.ShadowTLS().Port, "ShadowTLS")
 
 	// 2. 驗證深拷貝：原配置不應被修改
-	if cfg.Protocols.RealityVision.Port == newCfg.Protocols.RealityVision.Port {
+	if cfg.Protocols.RealityVision().Port == newCfg.Protocols.RealityVision().Port {
 		// 雖然隨機可能相同，但概率極低，通常 Default 是 443 左右，Reset 是 10000+
-		if cfg.Protocols.RealityVision.Port < 10000 {
+		if cfg.Protocols.RealityVision().Port < 10000 {
 			t.Log("Pass: Original config remained unchanged")
 		}
 	}
@@ -53,7 +56,7 @@ func TestResetAllPorts(t *testing.T) {
 
 // TestUpdateSinglePort 測試單個端口更新
 func TestUpdateSinglePort(t *testing.T) {
-	svc := NewPortService(zap.NewNop())
+	svc := NewPortService(zap.NewNop(), nil)
 	cfg := domainConfig.DefaultConfig()
 	ctx := context.Background()
 
@@ -64,8 +67,8 @@ func TestUpdateSinglePort(t *testing.T) {
 	if err != nil {
 		t.Fatalf("UpdateSinglePort 失敗: %v", err)
 	}
-	if updatedCfg.Protocols.Hysteria2.Port != 23456 {
-		t.Errorf("預期 23456, 實際 %d", updatedCfg.Protocols.Hysteria2.Port)
+	if updatedCfg.Protocols.Hysteria2().Port != 23456 {
+		t.Errorf("預期 23456, 實際 %d", updatedCfg.Protocols.Hysteria2().Port)
 	}
 
 	// 2. 測試：隨機端口
@@ -73,8 +76,8 @@ func TestUpdateSinglePort(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if randomCfg.Protocols.Hysteria2.Port < 10000 || randomCfg.Protocols.Hysteria2.Port > 65535 {
-		t.Errorf("隨機端口範圍錯誤: %d", randomCfg.Protocols.Hysteria2.Port)
+	if randomCfg.Protocols.Hysteria2().Port < 10000 || randomCfg.Protocols.Hysteria2().Port > 65535 {
+		t.Errorf("隨機端口範圍錯誤: %d", randomCfg.Protocols.Hysteria2().Port)
 	}
 
 	// 3. 測試：無效輸入
@@ -89,29 +92,51 @@ func TestUpdateSinglePort(t *testing.T) {
 
 // TestHy2Hopping 測試 Hysteria2 端口跳躍邏輯
 func TestHy2Hopping(t *testing.T) {
-	svc := NewPortService(zap.NewNop())
+	svc := NewPortService(zap.NewNop(), nil)
 	cfg := domainConfig.DefaultConfig()
+	// 默認端口隨機生成，固定 TUIC 端口避免落入跳躍範圍
+	cfg.Protocols.TUIC().Port = 40000
 	ctx := context.Background()
 
 	// 1. 設置跳躍
-	start, end := 20000, 30000
-	updatedCfg, err := svc.UpdateHy2Hopping(ctx, cfg, start, end)
+	updatedCfg, err := svc.UpdateHy2Hopping(ctx, cfg, "20000-30000")
 	if err != nil {
 		t.Fatal(err)
 	}
 	expected := "20000-30000"
-	if updatedCfg.Protocols.Hysteria2.PortHopping != expected {
-		t.Errorf("跳躍格式錯誤: 預期 %s, 得到 %s", expected, updatedCfg.Protocols.Hysteria2.PortHopping)
+	if updatedCfg.Protocols.Hysteria2().PortHopping != expected {
+		t.Errorf("跳躍格式錯誤: 預期 %s, 得到 %s", expected, updatedCfg.Protocols.Hysteria2().PortHopping)
+	}
+
+	// 多個範圍按順序規範化保存
+	multiCfg, err := svc.UpdateHy2Hopping(ctx, cfg, "46000-47000, 20000-30000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := multiCfg.Protocols.Hysteria2().PortHopping; got != "20000-30000,46000-47000" {
+		t.Errorf("多範圍跳躍格式錯誤: %s", got)
+	}
+
+	// 跳躍間隔: 純數字按秒處理，過小的間隔報錯
+	intervalCfg, err := svc.UpdateHy2HopInterval(ctx, cfg, "45")
+	if err != nil || intervalCfg.Protocols.Hysteria2().HopInterval != "45s" {
+		t.Errorf("跳躍間隔設置錯誤: %v, %v", err, intervalCfg)
+	}
+	if _, err := svc.UpdateHy2HopInterval(ctx, cfg, "1s"); err == nil {
+		t.Error("預期過小的跳躍間隔會報錯")
+	}
+	if resetCfg, err := svc.UpdateHy2HopInterval(ctx, intervalCfg, "0"); err != nil || resetCfg.Protocols.Hysteria2().HopInterval != "" {
+		t.Errorf("輸入 0 應恢復默認間隔: %v", err)
 	}
 
 	// 2. 清除跳躍
 	clearedCfg, _ := svc.ClearHy2Hopping(ctx, updatedCfg)
-	if clearedCfg.Protocols.Hysteria2.PortHopping != "" {
+	if clearedCfg.Protocols.Hysteria2().PortHopping != "" {
 		t.Error("清除跳躍失敗")
 	}
 
 	// 3. 無效範圍測試
-	_, err = svc.UpdateHy2Hopping(ctx, cfg, 40000, 30000) // start > end
+	_, err = svc.UpdateHy2Hopping(ctx, cfg, "40000-30000") // start > end
 	if err == nil {
 		t.Error("預期 start > end 會報錯")
 	}
@@ -119,9 +144,9 @@ func TestHy2Hopping(t *testing.T) {
 
 // TestGetPort 測試獲取端口
 func TestGetPort(t *testing.T) {
-	svc := NewPortService(zap.NewNop())
+	svc := NewPortService(zap.NewNop(), nil)
 	cfg := domainConfig.DefaultConfig()
-	cfg.Protocols.TUIC.Port = 8888
+	cfg.Protocols.TUIC().Port = 8888
 
 	port := svc.GetPort(cfg, int(protocol.IDTUIC))
 	if port != 8888 {
@@ -133,3 +158,124 @@ func TestGetPort(t *testing.T) {
 		t.Errorf("未知協議應返回 0, 得到 %d", p)
 	}
 }
+
+// fakePortChecker 模擬系統端口佔用
+type fakePortChecker struct {
+	listeners []infraSystem.Listener
+	err       error
+}
+
+func (f *fakePortChecker) Listeners() ([]infraSystem.Listener, error) {
+	return f.listeners, f.err
+}
+
+// TestPortConflicts 測試端口與其他入站、跳躍範圍及系統程序的衝突檢查
+func TestPortConflicts(t *testing.T) {
+	checker := &fakePortChecker{listeners: []infraSystem.Listener{
+		{Network: "tcp", Port: 8080, Process: "nginx"},
+		{Network: "udp", Port: 5353},
+		{Network: "tcp", Port: 9443, Process: "sing-box"},
+	}}
+	svc := NewPortService(zap.NewNop(), checker)
+	ctx := context.Background()
+
+	cfg := domainConfig.DefaultConfig()
+	cfg.Protocols.RealityVision().Enabled = true
+	cfg.Protocols.RealityVision().Port = 20443
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.Hysteria2().Port = 21443
+	cfg.Protocols.Hysteria2().PortHopping = "30000-31000"
+	cfg.Protocols.TUIC().Enabled = true
+	cfg.Protocols.TUIC().Port = 25000
+	cfg.Protocols.AnyTLS().Enabled = false
+	cfg.Protocols.AnyTLS().Port = 26000
+
+	cases := []struct {
+		name    string
+		id      protocol.ID
+		port    string
+		wantErr string
+	}{
+		{"同端口其他入站", protocol.IDAnyTLS, "20443", "已被 VLESS Reality Vision 使用"},
+		{"不同傳輸層可共用", protocol.IDTUIC, "20443", ""},
+		{"系統程序佔用", protocol.IDRealityGRPC, "8080", "已被 nginx 佔用"},
+		{"未知程序佔用", protocol.IDTUIC, "5353", "已被 其他程序 佔用"},
+		{"sing-box 自身佔用不算衝突", protocol.IDAnyTLS, "9443", ""},
+		{"位於跳躍範圍內", protocol.IDTUIC, "30500", "跳躍範圍"},
+		{"未啟用的協議不算衝突", protocol.IDRealityGRPC, "26000", ""},
+	}
+	for _, tc := range cases {
+		_, err := svc.UpdateSinglePort(ctx, cfg, int(tc.id), tc.port)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: 預期成功，實際 %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: 預期包含 %q 的錯誤，實際 %v", tc.name, tc.wantErr, err)
+		}
+	}
+
+	// 隨機端口不應落在衝突位置
+	for i := 0; i < 20; i++ {
+		newCfg, err := svc.UpdateSinglePort(ctx, cfg, int(protocol.IDTUIC), "random")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := newCfg.Protocols.TUIC().Port; p >= 30000 && p <= 31000 || p == 21443 {
+			t.Fatalf("隨機端口 %d 與現有配置衝突", p)
+		}
+	}
+
+	// 系統端口讀取失敗時不阻止修改
+	failing := NewPortService(zap.NewNop(), &fakePortChecker{err: errors.New("permission denied")})
+	if _, err := failing.UpdateSinglePort(ctx, cfg, int(protocol.IDRealityGRPC), "8080"); err != nil {
+		t.Errorf("讀取失敗時應跳過系統檢查: %v", err)
+	}
+}
+
+// TestHy2HoppingConflicts 測試跳躍範圍與其他入站及系統端口重疊
+func TestHy2HoppingConflicts(t *testing.T) {
+	checker := &fakePortChecker{listeners: []infraSystem.Listener{
+		{Network: "udp", Port: 41000, Process: "coturn"},
+		{Network: "tcp", Port: 42000, Process: "nginx"},
+	}}
+	svc := NewPortService(zap.NewNop(), checker)
+	ctx := context.Background()
+
+	cfg := domainConfig.DefaultConfig()
+	cfg.Protocols.TUIC().Enabled = true
+	cfg.Protocols.TUIC().Port = 35000
+
+	if _, err := svc.UpdateHy2Hopping(ctx, cfg, "34000-36000"); err == nil || !strings.Contains(err.Error(), "TUIC") {
+		t.Errorf("預期與 TUIC 端口重疊報錯，實際 %v", err)
+	}
+	if _, err := svc.UpdateHy2Hopping(ctx, cfg, "38000-39000,40000-41500"); err == nil || !strings.Contains(err.Error(), "coturn") {
+		t.Errorf("預期與系統 UDP 端口重疊報錯，實際 %v", err)
+	}
+	// TCP 監聽不影響 UDP 跳躍
+	if _, err := svc.UpdateHy2Hopping(ctx, cfg, "41600-43000"); err != nil {
+		t.Errorf("預期成功，實際 %v", err)
+	}
+}
+
+// TestResetAllPortsAvoidsSystem 測試重置端口避開系統佔用
+func TestResetAllPortsAvoidsSystem(t *testing.T) {
+	// 佔用隨機區間內所有偶數端口
+	var listeners []infraSystem.Listener
+	for p := 10000; p < 60000; p += 2 {
+		listeners = append(listeners, infraSystem.Listener{Network: "tcp", Port: p, Process: "nginx"})
+	}
+	svc := NewPortService(zap.NewNop(), &fakePortChecker{listeners: listeners})
+
+	newCfg, err := svc.ResetAllPorts(context.Background(), domainConfig.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range protocol.AllIDs() {
+		if p := svc.GetPort(newCfg, int(id)); p%2 == 0 {
+			t.Errorf("%s 分配到已佔用端口 %d", id, p)
+		}
+	}
+}
@@ -17,8 +17,19 @@ type ProtocolService interface {
 	ToggleProtocolsFromInput(ctx context.Context, currentEnabled []int, input string) ([]int, error)
 	// UpdateConfigWithEnabledProtocols 僅更新配置結構體，不負責保存到磁盤
 	UpdateConfigWithEnabledProtocols(cfg *domainConfig.Config, enabledProtocols []int) error
-	// 批量更新 SNI
+	// 批量更新 SNI (僅主實例，其餘實例保留各自的 SNI)
 	UpdateAllSNI(cfg *domainConfig.Config, sni string) error
+
+	// AddInstance 以協議主實例為模板添加新實例
+	// port 為 0 時隨機分配不衝突的端口，sni 為空時沿用主實例的 SNI
+	AddInstance(cfg *domainConfig.Config, protoID int, tag string, port int, sni string) error
+	// RemoveInstance 刪除協議實例 (主實例不可刪除)
+	RemoveInstance(cfg *domainConfig.Config, tag string) error
+	// ToggleInstance 切換協議實例的開關
+	ToggleInstance(cfg *domainConfig.Config, tag string) error
+	// SetInstanceOption 修改協議實例的高級字段
+	// target 為協議編號 (主實例) 或實例標籤，value 為空時恢復默認
+	SetInstanceOption(cfg *domainConfig.Config, target, key, value string) error
 }
 
 // protocolService 協議管理服務實現
@@ -32,37 +43,6 @@ func NewProtocolService(log *zap.Logger) ProtocolService {
 	}
 }
 
-// ---------------------------------------------------------
-// 協議開關策略映射表
-// 如果未來增加新協議，只需在這裡添加一行映射，無需修改業務邏輯
-// ---------------------------------------------------------
-type protocolEnabler func(cfg *domainConfig.Config, enabled bool)
-
-var enableStrategy = map[protocol.ID]protocolEnabler{
-	protocol.IDRealityVision: func(c *domainConfig.Config, e bool) { c.Protocols.RealityVision.Enabled = e },
-	protocol.IDRealityGRPC:   func(c *domainConfig.Config, e bool) { c.Protocols.RealityGRPC.Enabled = e },
-	protocol.IDHysteria2:     func(c *domainConfig.Config, e bool) { c.Protocols.Hysteria2.Enabled = e },
-	protocol.IDTUIC:          func(c *domainConfig.Config, e bool) { c.Protocols.TUIC.Enabled = e },
-	protocol.IDAnyTLS:        func(c *domainConfig.Config, e bool) { c.Protocols.AnyTLS.Enabled = e },
-	protocol.IDAnyTLSReality: func(c *domainConfig.Config, e bool) { c.Protocols.AnyTLSReality.Enabled = e },
-	protocol.IDShadowTLS:     func(c *domainConfig.Config, e bool) { c.Protocols.ShadowTLS.Enabled = e },
-}
-
-// ---------------------------------------------------------
-// SNI 更新策略映射表
-// ---------------------------------------------------------
-type sniSetter func(cfg *domainConfig.Config, sni string)
-
-var sniStrategy = map[protocol.ID]sniSetter{
-	protocol.IDRealityVision: func(c *domainConfig.Config, s string) { c.Protocols.RealityVision.SNI = s },
-	protocol.IDRealityGRPC:   func(c *domainConfig.Config, s string) { c.Protocols.RealityGRPC.SNI = s },
-	protocol.IDHysteria2:     func(c *domainConfig.Config, s string) { c.Protocols.Hysteria2.SNI = s },
-	protocol.IDTUIC:          func(c *domainConfig.Config, s string) { c.Protocols.TUIC.SNI = s },
-	protocol.IDAnyTLS:        func(c *domainConfig.Config, s string) { c.Protocols.AnyTLS.SNI = s },
-	protocol.IDAnyTLSReality: func(c *domainConfig.Config, s string) { c.Protocols.AnyTLSReality.SNI = s },
-	protocol.IDShadowTLS:     func(c *domainConfig.Config, s string) { c.Protocols.ShadowTLS.SNI = s },
-}
-
 // UpdateConfigWithEnabledProtocols 更新配置對象中的協議開關狀態
 func (s *protocolService) UpdateConfigWithEnabledProtocols(cfg *domainConfig.Config, enabledProtocols []int) error {
 	if cfg == nil {
@@ -70,19 +50,15 @@ func (s *protocolService) UpdateConfigWithEnabledProtocols(cfg *domainConfig.Con
 	}
 
 	// 1. 先將所有已知協議設置為 False (重置狀態)
-	// 使用 ids.go 中的 AllIDs() 進行遍歷，確保不漏掉任何一個
+	// 遍歷註冊表中的所有協議，確保不漏掉任何一個
 	for _, id := range protocol.AllIDs() {
-		if setter, ok := enableStrategy[id]; ok {
-			setter(cfg, false)
-		}
+		protocol.SetEnabled(cfg, id, false)
 	}
 
 	// 2. 根據傳入的列表開啟協議
 	for _, idInt := range enabledProtocols {
 		id := protocol.ID(idInt)
-		if setter, ok := enableStrategy[id]; ok {
-			setter(cfg, true)
-		} else {
+		if !protocol.SetEnabled(cfg, id, true) {
 			s.log.Warn("嘗試啟用未知的協議 ID", zap.Int("id", idInt))
 		}
 	}
@@ -136,11 +112,143 @@ func (s *protocolService) UpdateAllSNI(cfg *domainConfig.Config, sni string) err
 	}
 
 	for _, id := range protocol.AllIDs() {
-		if setter, ok := sniStrategy[id]; ok {
-			setter(cfg, sni)
-		}
+		protocol.SetSNI(cfg, id, sni)
 	}
 
 	s.log.Info("已批量更新 SNI", zap.String("new_sni", sni))
 	return nil
 }
+
+// AddInstance 添加協議實例
+func (s *protocolService) AddInstance(cfg *domainConfig.Config, protoID int, tag string, port int, sni string) error {
+	if cfg == nil {
+		return fmt.Errorf("配置不能為空")
+	}
+	d, ok := protocol.Lookup(protocol.ID(protoID))
+	if !ok {
+		return fmt.Errorf("無效的協議編號: %d", protoID)
+	}
+	if cfg.Protocols.Instance(tag) != nil {
+		return fmt.Errorf("協議實例標籤已存在: %s", tag)
+	}
+
+	// 複製主實例的密鑰、證書與策略配置
+	primary := cfg.Protocols.Instance(d.Tag)
+	if primary == nil {
+		return fmt.Errorf("協議主實例不存在: %s", d.Tag)
+	}
+	inst := primary.Clone(tag)
+	sec, _ := inst.Section()
+	*sec.Enabled = true
+	if sni != "" {
+		*sec.SNI = sni
+	}
+
+	if port == 0 {
+		p, err := randomFreePort(cfg, tag, sec.Network, nil)
+		if err != nil {
+			return err
+		}
+		port = p
+	} else {
+		if port < 1024 || port > 65535 {
+			return fmt.Errorf("端口範圍必須在 1024-65535 之間")
+		}
+		if err := checkPortConflict(cfg, tag, sec.Network, port, nil); err != nil {
+			return err
+		}
+	}
+	*sec.Port = port
+
+	// 跳躍範圍只屬於主實例，新實例的本地轉交端口不能與其他實例共用
+	if inst.Hysteria2 != nil {
+		inst.Hysteria2.PortHopping = ""
+	}
+	if inst.ShadowTLS != nil {
+		inst.ShadowTLS.DetourPort = freeDetourPort(cfg, port)
+	}
+
+	if err := cfg.Protocols.AddInstance(inst); err != nil {
+		return err
+	}
+
+	s.log.Info("已添加協議實例", zap.String("protocol", d.Name), zap.String("tag", tag), zap.Int("port", port))
+	return nil
+}
+
+// RemoveInstance 刪除協議實例
+func (s *protocolService) RemoveInstance(cfg *domainConfig.Config, tag string) error {
+	if cfg == nil {
+		return fmt.Errorf("配置不能為空")
+	}
+	if err := cfg.Protocols.RemoveInstance(tag); err != nil {
+		return err
+	}
+	s.log.Info("已刪除協議實例", zap.String("tag", tag))
+	return nil
+}
+
+// ToggleInstance 切換協議實例開關
+func (s *protocolService) ToggleInstance(cfg *domainConfig.Config, tag string) error {
+	if cfg == nil {
+		return fmt.Errorf("配置不能為空")
+	}
+	inst := cfg.Protocols.Instance(tag)
+	if inst == nil {
+		return fmt.Errorf("協議實例不存在: %s", tag)
+	}
+	sec, ok := inst.Section()
+	if !ok {
+		return fmt.Errorf("協議實例 %s 配置無效", tag)
+	}
+	*sec.Enabled = !*sec.Enabled
+	return nil
+}
+
+// freeDetourPort 為 ShadowTLS 實例選擇未被其他實例使用的本地轉交端口
+func freeDetourPort(cfg *domainConfig.Config, listenPort int) int {
+	used := map[int]bool{listenPort: true}
+	for _, sec := range cfg.Protocols.Sections() {
+		used[*sec.Port] = true
+	}
+	for _, inst := range cfg.Protocols.InstancesOf(domainConfig.ProtocolTypeShadowTLS) {
+		used[inst.ShadowTLS.GetDetourPort()] = true
+	}
+	port := 10001
+	for used[port] {
+		port++
+	}
+	return port
+}
+
+// SetInstanceOption 修改協議實例的高級字段，驗證失敗時保持原配置
+func (s *protocolService) SetInstanceOption(cfg *domainConfig.Config, target, key, value string) error {
+	if cfg == nil {
+		return fmt.Errorf("配置不能為空")
+	}
+
+	tag := target
+	if n, err := strconv.Atoi(target); err == nil {
+		d, ok := protocol.Lookup(protocol.ID(n))
+		if !ok {
+			return fmt.Errorf("無效的協議編號: %d", n)
+		}
+		tag = d.Tag
+	}
+	inst := cfg.Protocols.Instance(tag)
+	if inst == nil {
+		return fmt.Errorf("協議實例不存在: %s", target)
+	}
+
+	backup := inst.Clone(inst.Tag)
+	if err := inst.SetOption(key, value); err != nil {
+		return err
+	}
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		*inst = backup
+		return err
+	}
+
+	s.log.Info("已更新協議字段", zap.String("tag", tag), zap.String("field", key))
+	return nil
+}
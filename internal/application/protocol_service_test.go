@@ -122,8 +122,8 @@ func TestUpdateConfigWithEnabledProtocols(t *testing.T) {
 
 	// 1. 準備一個初始配置：RealityVision 開啟，其他關閉
 	cfg := config.DefaultConfig()
-	cfg.Protocols.RealityVision.Enabled = true
-	cfg.Protocols.Hysteria2.Enabled = false
+	cfg.Protocols.RealityVision().Enabled = true
+	cfg.Protocols.Hysteria2().Enabled = false
 
 	// 2. 目標：僅開啟 Hysteria2 (這意味著 RealityVision 應該被自動關閉)
 	targetProtocols := []int{int(protocol.IDHysteria2)}
@@ -136,15 +136,15 @@ func TestUpdateConfigWithEnabledProtocols(t *testing.T) {
 
 	// 4. 驗證
 	// RealityVision 應該被重置為 false
-	if cfg.Protocols.RealityVision.Enabled {
+	if cfg.Protocols.RealityVision().Enabled {
 		t.Error("RealityVision should be disabled after update")
 	}
 	// Hysteria2 應該被設置為 true
-	if !cfg.Protocols.Hysteria2.Enabled {
+	if !cfg.Protocols.Hysteria2().Enabled {
 		t.Error("Hysteria2 should be enabled after update")
 	}
 	// 未涉及的協議 (如 TUIC) 應該保持 false
-	if cfg.Protocols.TUIC.Enabled {
+	if cfg.Protocols.TUIC().Enabled {
 		t.Error("TUIC should remain disabled")
 	}
 }
@@ -156,8 +156,8 @@ func TestUpdateAllSNI(t *testing.T) {
 
 	cfg := config.DefaultConfig()
 	// 設置一些舊值
-	cfg.Protocols.RealityVision.SNI = "old.com"
-	cfg.Protocols.Hysteria2.SNI = "old.com"
+	cfg.Protocols.RealityVision().SNI = "old.com"
+	cfg.Protocols.Hysteria2().SNI = "old.com"
 
 	newSNI := "new-domain.com"
 
@@ -168,14 +168,85 @@ func TestUpdateAllSNI(t *testing.T) {
 	}
 
 	// 驗證所有支持 SNI 的協議是否都已更新
-	if cfg.Protocols.RealityVision.SNI != newSNI {
-		t.Errorf("RealityVision SNI mismatch. Want %s, Got %s", newSNI, cfg.Protocols.RealityVision.SNI)
+	if cfg.Protocols.RealityVision().SNI != newSNI {
+		t.Errorf("RealityVision SNI mismatch. Want %s, Got %s", newSNI, cfg.Protocols.RealityVision().SNI)
 	}
-	if cfg.Protocols.Hysteria2.SNI != newSNI {
-		t.Errorf("Hysteria2 SNI mismatch. Want %s, Got %s", newSNI, cfg.Protocols.Hysteria2.SNI)
+	if cfg.Protocols.Hysteria2().SNI != newSNI {
+		t.Errorf("Hysteria2 SNI mismatch. Want %s, Got %s", newSNI, cfg.Protocols.Hysteria2().SNI)
 	}
-	if cfg.Protocols.TUIC.SNI != newSNI {
-		t.Errorf("TUIC SNI mismatch. Want %s, Got %s", newSNI, cfg.Protocols.TUIC.SNI)
+	if cfg.Protocols.TUIC().SNI != newSNI {
+		t.Errorf("TUIC SNI mismatch. Want %s, Got %s", newSNI, cfg.Protocols.TUIC().SNI)
+	}
+}
+
+// TestAddRemoveInstance 測試協議實例的添加與刪除
+func TestAddRemoveInstance(t *testing.T) {
+	svc := NewProtocolService(zap.NewNop())
+	cfg := config.DefaultConfig()
+
+	if err := svc.AddInstance(cfg, int(protocol.IDRealityVision), "vision-2", 23456, "cdn.example.com"); err != nil {
+		t.Fatalf("添加實例失敗: %v", err)
+	}
+	inst := cfg.Protocols.Instance("vision-2")
+	if inst == nil || inst.RealityVision.Port != 23456 || inst.RealityVision.SNI != "cdn.example.com" || !inst.RealityVision.Enabled {
+		t.Fatalf("實例配置錯誤: %+v", inst)
+	}
+
+	// 端口與現有實例衝突
+	if err := svc.AddInstance(cfg, int(protocol.IDRealityVision), "vision-3", 23456, ""); err == nil {
+		t.Error("端口衝突時應返回錯誤")
+	}
+
+	// ShadowTLS 實例應分配獨立的轉交端口
+	if err := svc.AddInstance(cfg, int(protocol.IDShadowTLS), "stls-2", 0, ""); err != nil {
+		t.Fatalf("添加 ShadowTLS 實例失敗: %v", err)
+	}
+	if d := cfg.Protocols.Instance("stls-2").ShadowTLS.DetourPort; d <= 0 || d == 10000 {
+		t.Errorf("ShadowTLS 轉交端口錯誤: %d", d)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("添加實例後配置應有效: %v", err)
+	}
+
+	if err := svc.RemoveInstance(cfg, "vision-2"); err != nil {
+		t.Errorf("刪除實例失敗: %v", err)
+	}
+	if err := svc.RemoveInstance(cfg, "reality-vision-in"); err == nil {
+		t.Error("主實例不應可刪除")
+	}
+}
+
+// TestSetInstanceOption 測試協議高級參數的修改與回滾
+func TestSetInstanceOption(t *testing.T) {
+	svc := NewProtocolService(zap.NewNop())
+	cfg := config.DefaultConfig()
+
+	if err := svc.SetInstanceOption(cfg, "4", config.OptionCongestionControl, "cubic"); err != nil {
+		t.Fatalf("修改擁塞控制失敗: %v", err)
+	}
+	if got := cfg.Protocols.TUIC().CongestionControl; got != "cubic" {
+		t.Errorf("擁塞控制未更新: %s", got)
+	}
+
+	// 非法取值應回滾
+	if err := svc.SetInstanceOption(cfg, "tuic-in", config.OptionCongestionControl, "reno2"); err == nil {
+		t.Error("非法擁塞控制算法應返回錯誤")
+	}
+	if got := cfg.Protocols.TUIC().CongestionControl; got != "cubic" {
+		t.Errorf("驗證失敗後應保持原值，實際 %s", got)
+	}
+
+	// 字段不適用於該協議
+	if err := svc.SetInstanceOption(cfg, "1", config.OptionSSMethod, "aes-128-gcm"); err == nil {
+		t.Error("Reality Vision 不應支持 SS 加密方式")
+	}
+
+	// 只輸入目標恢復默認
+	if err := svc.SetInstanceOption(cfg, "shadowtls-in", config.OptionSSMethod, ""); err != nil {
+		t.Fatalf("恢復默認失敗: %v", err)
+	}
+	if got := cfg.Protocols.ShadowTLS().GetSSMethod(); got != config.DefaultShadowTLSSSMethod {
+		t.Errorf("應恢復默認加密方式，實際 %s", got)
 	}
 }
 
@@ -3,25 +3,37 @@ package application
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 
 	"go.uber.org/zap"
 
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/domain/singbox"
+	"github.com/Yat-Muk/prism-v2/internal/infra/decoy"
 	infraFirewall "github.com/Yat-Muk/prism-v2/internal/infra/firewall"
 	infraSingbox "github.com/Yat-Muk/prism-v2/internal/infra/singbox"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
 )
 
+// DecoyServer 本機偽裝站點服務，為 Trojan 回落提供內置站點
+type DecoyServer interface {
+	Ensure(ctx context.Context) error
+	Remove(ctx context.Context) error
+}
+
 type SingboxService struct {
 	generator       singbox.Generator
 	service         *infraSingbox.Service
 	firewallManager infraFirewall.Manager
+	decoyServer     DecoyServer
 	paths           *appctx.Paths
 	log             *zap.Logger
+
+	hasPolicies bool // 上次同步是否應用了源地址策略，清除時也需要保存規則
 }
 
 func NewSingboxService(
@@ -40,9 +52,32 @@ func NewSingboxService(
 	}
 }
 
+// SetDecoyServer 設置本機偽裝站點服務，未設置時不管理該服務
+func (s *SingboxService) SetDecoyServer(d DecoyServer) {
+	s.decoyServer = d
+}
+
 func (s *SingboxService) ApplyConfig(ctx context.Context, cfg *domainConfig.Config) error {
 	s.log.Info("開始應用配置到 Sing-box")
 
+	// 0. 使用內置偽裝站點時確保站點已生成 (不覆蓋用戶修改)
+	if s.paths != nil && cfg.Protocols.UsesBundledDecoy() {
+		created, err := decoy.EnsureSite(s.paths.DecoySiteDir())
+		if err != nil {
+			return fmt.Errorf("生成偽裝站點失敗: %w", err)
+		}
+		if created {
+			s.log.Info("✅ 已生成內置偽裝站點", zap.String("dir", s.paths.DecoySiteDir()))
+		}
+	}
+
+	// 國家屏蔽缺少 GeoIP 數據時拒絕應用，避免屏蔽靜默失效
+	if s.firewallManager != nil {
+		if _, err := s.firewallPolicies(cfg); err != nil {
+			return err
+		}
+	}
+
 	// 1. 生成 Sing-box 配置
 	singboxCfg, err := s.generator.Generate(ctx, cfg)
 	if err != nil {
@@ -85,7 +120,18 @@ func (s *SingboxService) ApplyConfig(ctx context.Context, cfg *domainConfig.Conf
 		s.log.Warn("更新防火牆規則失敗", zap.Error(err))
 	}
 
-	// 7. 重載服務
+	// 7. Trojan 回落到內置站點時確保本機偽裝站點服務運行，不再使用時移除
+	if s.decoyServer != nil {
+		if cfg.Protocols.NeedsDecoyServer() {
+			if err := s.decoyServer.Ensure(ctx); err != nil {
+				return fmt.Errorf("啟動本機偽裝站點服務失敗: %w", err)
+			}
+		} else if err := s.decoyServer.Remove(ctx); err != nil {
+			s.log.Warn("移除本機偽裝站點服務失敗", zap.Error(err))
+		}
+	}
+
+	// 8. 重載服務
 	s.log.Info("重載 Sing-box 服務...")
 	if err := s.service.Reload(ctx); err != nil {
 		s.log.Warn("熱重載失敗，嘗試重啟服務", zap.Error(err))
@@ -99,6 +145,15 @@ func (s *SingboxService) ApplyConfig(ctx context.Context, cfg *domainConfig.Conf
 	return nil
 }
 
+// SyncFirewall 僅按配置同步防火牆規則與源地址策略，不改動 sing-box
+func (s *SingboxService) SyncFirewall(ctx context.Context, cfg *domainConfig.Config) error {
+	singboxCfg, err := s.generator.Generate(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("生成配置失敗: %w", err)
+	}
+	return s.updateFirewallRules(ctx, singboxCfg, cfg)
+}
+
 func (s *SingboxService) UpdateConfig(ctx context.Context, sbCfg *singbox.Config) error {
 	return s.service.UpdateConfig(ctx, sbCfg)
 }
@@ -111,36 +166,98 @@ func (s *SingboxService) updateFirewallRules(ctx context.Context, sbCfg *singbox
 
 	s.log.Info("🔄 正在同步防火牆規則...")
 
-	// 1. Flush 舊規則
-	if err := s.firewallManager.FlushRules(ctx); err != nil {
-		s.log.Warn("清理舊規則失敗", zap.Error(err))
+	// 1. 根據配置生成期望的規則集合 (直接使用內存對象)
+	var desired []infraFirewall.Rule
+	for _, portInfo := range s.extractPorts(sbCfg) {
+		desired = append(desired, infraFirewall.PortRule(portInfo.Port, portInfo.Protocol))
 	}
 
-	// 2. 提取並開放端口 (直接使用內存對象)
-	ports := s.extractPorts(sbCfg)
-	for _, portInfo := range ports {
-		if err := s.firewallManager.OpenPort(ctx, portInfo.Port, portInfo.Protocol); err != nil {
-			s.log.Error("開放端口失敗", zap.Int("port", portInfo.Port), zap.Error(err))
+	// 2. 處理 Hysteria 2 跳躍端口 (依賴 domain config)，每個實例的每個範圍各自重定向到其監聽端口
+	if domCfg != nil {
+		for _, inst := range domCfg.Protocols.InstancesOf(domainConfig.ProtocolTypeHysteria2) {
+			hy2 := inst.Hysteria2
+			ranges := hy2.HoppingRanges()
+			if !hy2.Enabled || len(ranges) == 0 {
+				continue
+			}
+			if !s.firewallManager.Capabilities().SupportPortHopping {
+				s.log.Warn("當前防火牆不支持端口跳躍", zap.String("type", s.firewallManager.Type()))
+				break
+			}
+			s.log.Info("配置 Hy2 跳躍端口防火牆", zap.String("tag", inst.Tag), zap.String("ranges", domainConfig.FormatPortRanges(ranges)))
+			for _, r := range ranges {
+				desired = append(desired, infraFirewall.HoppingRules(hy2.Port, r.Start, r.End)...)
+			}
 		}
 	}
 
-	// 3. 處理 Hysteria 2 跳躍端口 (依賴 domain config)
-	if domCfg != nil && domCfg.Protocols.Hysteria2.Enabled && domCfg.Protocols.Hysteria2.PortHopping != "" {
-		hopping := domCfg.Protocols.Hysteria2.PortHopping
-		var start, end int
-		if _, err := fmt.Sscanf(hopping, "%d-%d", &start, &end); err == nil {
-			mainPort := domCfg.Protocols.Hysteria2.Port
-			s.log.Info("配置 Hy2 跳躍端口防火牆", zap.String("range", hopping))
-			if err := s.firewallManager.OpenHysteria2PortHopping(ctx, mainPort, start, end); err != nil {
-				s.log.Error("跳躍端口設置失敗", zap.Error(err))
+	// 3. 與系統實際規則對比，只增刪差異部分
+	plan, err := s.firewallManager.Reconcile(ctx, desired)
+	if err != nil {
+		s.log.Error("同步防火牆規則失敗", zap.Error(err))
+	}
+
+	// 4. 應用各協議的源地址策略 (未配置時清除舊策略)
+	// GeoIP 數據缺失時其餘策略照常應用，錯誤返回給調用方
+	policies, policyErr := s.firewallPolicies(domCfg)
+	if err := s.firewallManager.ApplyPolicies(ctx, policies); err != nil {
+		s.log.Warn("應用源地址策略失敗", zap.Error(err))
+	}
+	policiesChanged := len(policies) > 0 || s.hasPolicies
+	s.hasPolicies = len(policies) > 0
+
+	// 5. 有變更時保存規則
+	if !plan.IsEmpty() || policiesChanged {
+		_ = s.firewallManager.SaveRules(ctx)
+	}
+
+	return policyErr
+}
+
+// firewallPolicies 將協議配置中的防火牆策略轉換為防火牆後端的策略
+// 國家屏蔽從數據目錄下的 geoip/<cc>.zone 讀取網段，數據缺失時該協議不含國家屏蔽並返回錯誤
+func (s *SingboxService) firewallPolicies(domCfg *domainConfig.Config) ([]infraFirewall.Policy, error) {
+	if domCfg == nil {
+		return nil, nil
+	}
+
+	var policies []infraFirewall.Policy
+	var errs []error
+	for _, target := range domCfg.Protocols.FirewallTargets() {
+		fw := target.Firewall
+		policy := infraFirewall.Policy{
+			Name:      target.Name,
+			Protocol:  target.Network,
+			Port:      target.Port,
+			Allow:     normalizeCIDRs(fw.Allow),
+			Deny:      normalizeCIDRs(fw.Deny),
+			RateLimit: fw.RateLimit,
+			RateBurst: fw.Burst(),
+		}
+
+		if len(fw.BlockCountries) > 0 {
+			if s.paths == nil {
+				errs = append(errs, fmt.Errorf("%s 國家屏蔽無法生效: 未配置數據目錄", target.Name))
+			} else if cidrs, err := infraFirewall.LoadCountryCIDRs(filepath.Join(s.paths.DataDir, "geoip"), fw.BlockCountries); err != nil {
+				errs = append(errs, fmt.Errorf("%s 國家屏蔽無法生效: %w", target.Name, err))
+			} else {
+				policy.Countries = cidrs
 			}
 		}
+		policies = append(policies, policy)
 	}
+	return policies, errors.Join(errs...)
+}
 
-	// 4. 保存規則
-	_ = s.firewallManager.SaveRules(ctx)
-
-	return nil
+func normalizeCIDRs(list []string) []string {
+	var result []string
+	for _, s := range list {
+		// 配置已經過校驗，這裡忽略無效值
+		if cidr, err := domainConfig.NormalizeCIDR(s); err == nil {
+			result = append(result, cidr)
+		}
+	}
+	return result
 }
 
 // portInfo 內部輔助結構
@@ -178,13 +295,18 @@ func (s *SingboxService) extractPorts(sbCfg *singbox.Config) []portInfo {
 			continue
 		}
 
+		// 僅本機監聽的入站 (如 ShadowTLS 轉交的 Shadowsocks) 無需開放
+		if listen, _ := inbound["listen"].(string); listen == "127.0.0.1" || listen == "::1" {
+			continue
+		}
+
 		// 獲取協議類型
 		typeVal, _ := inbound["type"].(string)
 		protocol := "tcp"
 
 		switch typeVal {
 		// UDP 為主的協議，以及需要 UDP 轉發的協議，都建議開啟 both
-		case "hysteria2", "tuic", "shadowtls", "naive", "trojan":
+		case "hysteria2", "tuic", "shadowtls", "shadowsocks", "naive", "trojan":
 			protocol = "both"
 		case "vless", "vmess":
 			// 檢查是否開啟了 quic 或 grpc 傳輸，這些通常也需要 UDP
@@ -208,6 +330,14 @@ func (s *SingboxService) Restart(ctx context.Context) error {
 	return s.service.Restart(ctx)
 }
 
+// RemoveDecoyServer 移除本機偽裝站點服務 (卸載時使用)
+func (s *SingboxService) RemoveDecoyServer(ctx context.Context) error {
+	if s.decoyServer == nil {
+		return nil
+	}
+	return s.decoyServer.Remove(ctx)
+}
+
 func (s *SingboxService) Stop(ctx context.Context) error {
 	return s.service.Stop(ctx)
 }
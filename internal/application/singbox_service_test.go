@@ -18,6 +18,8 @@ type MockFirewall struct {
 	}
 	flushed     bool
 	saved       bool
+	desired     []infraFirewall.Rule
+	policies    []infraFirewall.Policy
 	hoppingRule *struct {
 		main  int
 		start int
@@ -74,6 +76,21 @@ func (m *MockFirewall) FlushRules(ctx context.Context) error {
 	return nil
 }
 
+func (m *MockFirewall) CurrentRules(ctx context.Context) ([]infraFirewall.Rule, error) {
+	return m.desired, nil
+}
+
+func (m *MockFirewall) Reconcile(ctx context.Context, desired []infraFirewall.Rule) (infraFirewall.Plan, error) {
+	plan := infraFirewall.Diff(m.desired, desired)
+	m.desired = infraFirewall.Normalize(desired)
+	return plan, nil
+}
+
+func (m *MockFirewall) ApplyPolicies(ctx context.Context, policies []infraFirewall.Policy) error {
+	m.policies = policies
+	return nil
+}
+
 func (m *MockFirewall) Capabilities() infraFirewall.Capabilities {
 	return infraFirewall.Capabilities{
 		SupportPortHopping: true,
@@ -103,6 +120,11 @@ func TestExtractPorts(t *testing.T) {
 				"type":        "socks",
 				"listen_port": 1080.0,
 			},
+			{
+				"type":        "shadowsocks",
+				"listen":      "127.0.0.1",
+				"listen_port": 10000,
+			},
 		},
 	}
 
@@ -130,19 +152,94 @@ func TestUpdateFirewallRules(t *testing.T) {
 	}
 
 	domCfg := domainConfig.DefaultConfig()
-	domCfg.Protocols.Hysteria2.Enabled = true
-	domCfg.Protocols.Hysteria2.Port = 8443
-	domCfg.Protocols.Hysteria2.PortHopping = "20000-30000"
+	domCfg.Protocols.Hysteria2().Enabled = true
+	domCfg.Protocols.Hysteria2().Port = 8443
+	domCfg.Protocols.Hysteria2().PortHopping = "20000-30000,40000-41000"
 
 	err := svc.updateFirewallRules(ctx, sbCfg, domCfg)
 	if err != nil {
 		t.Fatalf("updateFirewallRules 失敗: %v", err)
 	}
 
-	if !mockFW.flushed {
-		t.Error("應先清理舊規則 (Flush)")
+	if mockFW.flushed {
+		t.Error("不應清空規則，應只同步差異")
+	}
+	if !mockFW.saved {
+		t.Error("規則有變更時應保存")
+	}
+
+	var hasPort, hasRedirect, hasSecondRedirect bool
+	for _, r := range mockFW.desired {
+		if r.Start == 443 && !r.IsRedirect() {
+			hasPort = true
+		}
+		if r.IsRedirect() && r.RedirectTo == 8443 && r.Start == 20000 && r.End == 30000 {
+			hasRedirect = true
+		}
+		if r.IsRedirect() && r.RedirectTo == 8443 && r.Start == 40000 && r.End == 41000 {
+			hasSecondRedirect = true
+		}
+	}
+	if !hasPort {
+		t.Error("未開放入站端口")
+	}
+	if !hasRedirect || !hasSecondRedirect {
+		t.Error("未為每個跳躍範圍應用端口跳躍規則")
+	}
+
+	// 配置未變更時再次同步不應產生變更
+	mockFW.saved = false
+	if err := svc.updateFirewallRules(ctx, sbCfg, domCfg); err != nil {
+		t.Fatalf("updateFirewallRules 失敗: %v", err)
+	}
+	if mockFW.saved {
+		t.Error("規則無變更時不應重新保存")
+	}
+}
+
+func TestFirewallPolicies(t *testing.T) {
+	mockFW := &MockFirewall{}
+	svc := NewSingboxService(nil, nil, mockFW, nil, zap.NewNop())
+	ctx := context.Background()
+
+	domCfg := domainConfig.DefaultConfig()
+	domCfg.Protocols.TUIC().Enabled = true
+	domCfg.Protocols.TUIC().Port = 9443
+	domCfg.Protocols.TUIC().Firewall = domainConfig.InboundFirewall{
+		Allow:          []string{"203.0.113.7"},
+		RateLimit:      30,
+		BlockCountries: []string{"RU"},
+	}
+
+	// 缺少 GeoIP 數據時返回錯誤，其餘策略照常應用
+	if err := svc.updateFirewallRules(ctx, &singbox.Config{}, domCfg); err == nil {
+		t.Fatal("缺少 GeoIP 數據時應返回錯誤")
+	}
+	if len(mockFW.policies) != 1 {
+		t.Fatalf("預期 1 條策略，實際 %d", len(mockFW.policies))
+	}
+	p := mockFW.policies[0]
+	if p.Protocol != "udp" || p.Port != 9443 || p.RateLimit != 30 || p.RateBurst != domainConfig.DefaultRateBurst {
+		t.Errorf("策略轉換錯誤: %+v", p)
+	}
+	if len(p.Allow) != 1 || p.Allow[0] != "203.0.113.7/32" {
+		t.Errorf("白名單應規範化為 CIDR: %v", p.Allow)
+	}
+	if len(p.Countries) != 0 {
+		t.Errorf("缺少 GeoIP 數據時不應有國家網段: %v", p.Countries)
+	}
+	// 應用配置時在改動 sing-box 之前拒絕
+	if err := svc.ApplyConfig(ctx, domCfg); err == nil {
+		t.Error("國家屏蔽無法生效時應拒絕應用配置")
+	}
+
+	// 清除策略後仍需保存一次規則
+	mockFW.saved = false
+	domCfg.Protocols.TUIC().Firewall = domainConfig.InboundFirewall{}
+	if err := svc.updateFirewallRules(ctx, &singbox.Config{}, domCfg); err != nil {
+		t.Fatalf("updateFirewallRules 失敗: %v", err)
 	}
-	if mockFW.hoppingRule == nil {
-		t.Error("未應用端口跳躍規則")
+	if len(mockFW.policies) != 0 || !mockFW.saved {
+		t.Error("清除策略後應應用空策略並保存規則")
 	}
 }
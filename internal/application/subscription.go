@@ -0,0 +1,77 @@
+package application
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+)
+
+// SubscriptionFileName 離線訂閱文件名 (位於數據目錄，內容為 Base64 編碼的鏈接列表)
+const SubscriptionFileName = "subscription.txt"
+
+// ServerAddressFunc 解析客戶端連接使用的服務器地址
+type ServerAddressFunc func(cfg *domainConfig.Config) string
+
+// SubscriptionPublisher 將當前配置的分享鏈接寫入訂閱文件
+// 訂閱文件可由任意靜態文件服務對外發佈，端口變化後客戶端更新訂閱即可
+type SubscriptionPublisher struct {
+	path    string
+	factory protocol.Factory
+	address ServerAddressFunc
+}
+
+// NewSubscriptionPublisher 創建訂閱發佈器
+func NewSubscriptionPublisher(path string, factory protocol.Factory, address ServerAddressFunc) *SubscriptionPublisher {
+	return &SubscriptionPublisher{
+		path:    path,
+		factory: factory,
+		address: address,
+	}
+}
+
+// Path 返回訂閱文件路徑
+func (p *SubscriptionPublisher) Path() string {
+	return p.path
+}
+
+// Address 返回配置對應的服務器地址
+func (p *SubscriptionPublisher) Address(cfg *domainConfig.Config) string {
+	if p.address == nil {
+		return cfg.Server.Host
+	}
+	return p.address(cfg)
+}
+
+// Publish 生成分享鏈接並原子寫入訂閱文件
+func (p *SubscriptionPublisher) Publish(cfg *domainConfig.Config) error {
+	addr := p.Address(cfg)
+	if addr == "" || addr == "0.0.0.0" {
+		return fmt.Errorf("無法確定服務器地址，訂閱未更新")
+	}
+
+	links := protocol.ShareLinks(p.factory.FromConfig(cfg), addr)
+	if len(links) == 0 {
+		return fmt.Errorf("沒有已啟用的協議，訂閱未更新")
+	}
+
+	content := base64.StdEncoding.EncodeToString([]byte(strings.Join(links, "\n") + "\n"))
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return fmt.Errorf("創建訂閱目錄失敗: %w", err)
+	}
+	// 鏈接包含密碼，僅允許屬主讀取
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0600); err != nil {
+		return fmt.Errorf("寫入訂閱文件失敗: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("替換訂閱文件失敗: %w", err)
+	}
+	return nil
+}
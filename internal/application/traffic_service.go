@@ -0,0 +1,253 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/clashapi"
+	"go.uber.org/zap"
+)
+
+// 面板展示上限
+const (
+	maxDashboardConnections  = 50
+	maxDashboardDestinations = 10
+)
+
+// TrafficDashboard 流量面板數據
+type TrafficDashboard struct {
+	UpRate        int64 // 總上傳速率 (字節/秒)
+	DownRate      int64
+	UploadTotal   int64 // 核心啟動以來累計
+	DownloadTotal int64
+	MemoryInUse   uint64
+
+	Inbounds        []InboundTraffic
+	Connections     []ConnectionStat // 按當前速率與累計流量排序
+	TotalConns      int
+	TopDestinations []DestinationTraffic
+	SampledAt       time.Time
+}
+
+// InboundTraffic 單個入站的流量
+// 速率由兩次採樣間活動連接的流量差計算，首次採樣時為 0
+type InboundTraffic struct {
+	Tag         string
+	Connections int
+	UpRate      int64
+	DownRate    int64
+}
+
+// ConnectionStat 單個活動連接
+type ConnectionStat struct {
+	ID          string
+	Inbound     string
+	Network     string
+	Source      string
+	Destination string
+	Outbound    string
+	Upload      int64
+	Download    int64
+	UpRate      int64
+	DownRate    int64
+	Duration    time.Duration
+}
+
+// DestinationTraffic 按目標聚合的流量
+type DestinationTraffic struct {
+	Host        string
+	Connections int
+	Upload      int64
+	Download    int64
+}
+
+type connCounter struct {
+	up, down int64
+}
+
+// TrafficService 通過 sing-box Clash API 讀取流量與連接統計
+type TrafficService struct {
+	log *zap.Logger
+	now func() time.Time
+
+	mu     sync.Mutex
+	prev   map[string]connCounter
+	prevAt time.Time
+}
+
+// NewTrafficService 創建流量統計服務
+func NewTrafficService(log *zap.Logger) *TrafficService {
+	return &TrafficService{
+		log: log,
+		now: time.Now,
+	}
+}
+
+func clashClient(cfg *domainConfig.Config) (*clashapi.Client, error) {
+	if cfg == nil || !cfg.ClashAPI.IsEnabled() {
+		return nil, fmt.Errorf("Clash API 未啟用")
+	}
+	return clashapi.NewClient(cfg.ClashAPI.Listen, cfg.ClashAPI.Secret), nil
+}
+
+// Dashboard 採樣一次面板數據
+func (s *TrafficService) Dashboard(ctx context.Context, cfg *domainConfig.Config) (*TrafficDashboard, error) {
+	client, err := clashClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := client.Connections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	traffic, err := client.Traffic(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	d := &TrafficDashboard{
+		UpRate:        traffic.Up,
+		DownRate:      traffic.Down,
+		UploadTotal:   snap.UploadTotal,
+		DownloadTotal: snap.DownloadTotal,
+		TotalConns:    len(snap.Connections),
+		SampledAt:     now,
+	}
+	// 內存統計只用於展示，讀取失敗不影響面板
+	if mem, err := client.Memory(ctx); err == nil {
+		d.MemoryInUse = mem.InUse
+	} else {
+		s.log.Debug("讀取核心內存失敗", zap.Error(err))
+	}
+
+	s.mu.Lock()
+	prev, prevAt := s.prev, s.prevAt
+	s.prev = make(map[string]connCounter, len(snap.Connections))
+	for _, c := range snap.Connections {
+		s.prev[c.ID] = connCounter{up: c.Upload, down: c.Download}
+	}
+	s.prevAt = now
+	s.mu.Unlock()
+
+	elapsed := now.Sub(prevAt).Seconds()
+	if prev == nil || elapsed <= 0 {
+		elapsed = 0
+	}
+
+	inbounds := make(map[string]*InboundTraffic)
+	dests := make(map[string]*DestinationTraffic)
+	for _, c := range snap.Connections {
+		stat := ConnectionStat{
+			ID:          c.ID,
+			Inbound:     c.Inbound(),
+			Network:     c.Metadata.Network,
+			Source:      joinHostPort(c.Metadata.SourceIP, c.Metadata.SourcePort),
+			Destination: joinHostPort(c.Destination(), c.Metadata.DestinationPort),
+			Outbound:    c.Outbound(),
+			Upload:      c.Upload,
+			Download:    c.Download,
+		}
+		if !c.Start.IsZero() {
+			stat.Duration = now.Sub(c.Start)
+		}
+		if elapsed > 0 {
+			// 上次採樣後新建的連接，全部流量都發生在本次間隔內
+			last := prev[c.ID]
+			stat.UpRate = int64(float64(c.Upload-last.up) / elapsed)
+			stat.DownRate = int64(float64(c.Download-last.down) / elapsed)
+		}
+		d.Connections = append(d.Connections, stat)
+
+		in := inbounds[stat.Inbound]
+		if in == nil {
+			in = &InboundTraffic{Tag: stat.Inbound}
+			inbounds[stat.Inbound] = in
+		}
+		in.Connections++
+		in.UpRate += stat.UpRate
+		in.DownRate += stat.DownRate
+
+		host := c.Destination()
+		dest := dests[host]
+		if dest == nil {
+			dest = &DestinationTraffic{Host: host}
+			dests[host] = dest
+		}
+		dest.Connections++
+		dest.Upload += c.Upload
+		dest.Download += c.Download
+	}
+
+	for _, in := range inbounds {
+		d.Inbounds = append(d.Inbounds, *in)
+	}
+	sort.Slice(d.Inbounds, func(i, j int) bool {
+		a, b := d.Inbounds[i], d.Inbounds[j]
+		if a.UpRate+a.DownRate != b.UpRate+b.DownRate {
+			return a.UpRate+a.DownRate > b.UpRate+b.DownRate
+		}
+		return a.Tag < b.Tag
+	})
+
+	sort.SliceStable(d.Connections, func(i, j int) bool {
+		a, b := d.Connections[i], d.Connections[j]
+		if a.UpRate+a.DownRate != b.UpRate+b.DownRate {
+			return a.UpRate+a.DownRate > b.UpRate+b.DownRate
+		}
+		return a.Upload+a.Download > b.Upload+b.Download
+	})
+	if len(d.Connections) > maxDashboardConnections {
+		d.Connections = d.Connections[:maxDashboardConnections]
+	}
+
+	for _, dest := range dests {
+		d.TopDestinations = append(d.TopDestinations, *dest)
+	}
+	sort.Slice(d.TopDestinations, func(i, j int) bool {
+		a, b := d.TopDestinations[i], d.TopDestinations[j]
+		if a.Upload+a.Download != b.Upload+b.Download {
+			return a.Upload+a.Download > b.Upload+b.Download
+		}
+		return a.Host < b.Host
+	})
+	if len(d.TopDestinations) > maxDashboardDestinations {
+		d.TopDestinations = d.TopDestinations[:maxDashboardDestinations]
+	}
+
+	return d, nil
+}
+
+// CloseConnection 關閉指定連接
+func (s *TrafficService) CloseConnection(ctx context.Context, cfg *domainConfig.Config, id string) error {
+	client, err := clashClient(cfg)
+	if err != nil {
+		return err
+	}
+	if err := client.CloseConnection(ctx, id); err != nil {
+		return err
+	}
+	s.log.Info("已關閉連接", zap.String("id", id))
+	return nil
+}
+
+// Reset 清除速率計算的歷史採樣 (重新進入面板時調用)
+func (s *TrafficService) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prev = nil
+	s.prevAt = time.Time{}
+}
+
+func joinHostPort(host, port string) string {
+	if port == "" || port == "0" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
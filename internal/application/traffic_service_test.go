@@ -0,0 +1,145 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/clashapi"
+	"go.uber.org/zap"
+)
+
+// fakeClashAPI 本地模擬的 Clash API，連接列表可在採樣之間修改
+type fakeClashAPI struct {
+	mu     sync.Mutex
+	conns  []clashapi.Connection
+	closed []string
+}
+
+func (f *fakeClashAPI) setConns(conns ...clashapi.Connection) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.conns = conns
+}
+
+func (f *fakeClashAPI) serve(t *testing.T, secret string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/traffic":
+			w.Write([]byte(`{"up":300,"down":900}` + "\n"))
+		case r.URL.Path == "/memory":
+			w.Write([]byte(`{"inuse":1048576,"oslimit":0}` + "\n"))
+		case r.URL.Path == "/connections":
+			json.NewEncoder(w).Encode(clashapi.Snapshot{Connections: f.conns})
+		case strings.HasPrefix(r.URL.Path, "/connections/") && r.Method == http.MethodDelete:
+			f.closed = append(f.closed, strings.TrimPrefix(r.URL.Path, "/connections/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func fakeConn(id, inbound, host string, up, down int64) clashapi.Connection {
+	return clashapi.Connection{
+		ID: id,
+		Metadata: clashapi.Metadata{
+			Network:         "tcp",
+			Type:            "vless/" + inbound,
+			SourceIP:        "198.51.100.7",
+			SourcePort:      "50000",
+			DestinationPort: "443",
+			Host:            host,
+		},
+		Upload:   up,
+		Download: down,
+		Chains:   []string{"direct"},
+	}
+}
+
+func TestTrafficDashboard(t *testing.T) {
+	api := &fakeClashAPI{}
+	srv := api.serve(t, "secret")
+
+	cfg := domainConfig.DefaultConfig()
+	cfg.ClashAPI.Listen = strings.TrimPrefix(srv.URL, "http://")
+	cfg.ClashAPI.Secret = "secret"
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewTrafficService(zap.NewNop())
+	svc.now = func() time.Time { return now }
+
+	api.setConns(
+		fakeConn("a", "reality-vision-in", "www.google.com", 100, 1000),
+		fakeConn("b", "hysteria2-in", "www.youtube.com", 50, 500),
+	)
+	d, err := svc.Dashboard(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("採樣失敗: %v", err)
+	}
+	if d.UpRate != 300 || d.DownRate != 900 || d.MemoryInUse != 1048576 {
+		t.Errorf("總速率或內存錯誤: %+v", d)
+	}
+	if len(d.Inbounds) != 2 || d.Inbounds[0].UpRate != 0 {
+		t.Errorf("首次採樣入站速率應為 0: %+v", d.Inbounds)
+	}
+
+	// 2 秒後：a 增加 2000 下載，b 已關閉，c 為新連接
+	now = now.Add(2 * time.Second)
+	api.setConns(
+		fakeConn("a", "reality-vision-in", "www.google.com", 100, 3000),
+		fakeConn("c", "reality-vision-in", "www.google.com", 0, 400),
+	)
+	d, err = svc.Dashboard(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("採樣失敗: %v", err)
+	}
+
+	if len(d.Inbounds) != 1 {
+		t.Fatalf("應只剩一個活動入站: %+v", d.Inbounds)
+	}
+	in := d.Inbounds[0]
+	if in.Tag != "reality-vision-in" || in.Connections != 2 || in.DownRate != 1200 {
+		t.Errorf("入站速率錯誤 (期望 (2000+400)/2=1200): %+v", in)
+	}
+	if d.Connections[0].ID != "a" || d.Connections[0].DownRate != 1000 {
+		t.Errorf("連接應按速率排序: %+v", d.Connections)
+	}
+	if d.Connections[0].Destination != "www.google.com:443" || d.Connections[0].Source != "198.51.100.7:50000" {
+		t.Errorf("連接地址錯誤: %+v", d.Connections[0])
+	}
+	if len(d.TopDestinations) != 1 || d.TopDestinations[0].Download != 3400 || d.TopDestinations[0].Connections != 2 {
+		t.Errorf("目標聚合錯誤: %+v", d.TopDestinations)
+	}
+
+	if err := svc.CloseConnection(context.Background(), cfg, "a"); err != nil {
+		t.Fatalf("關閉連接失敗: %v", err)
+	}
+	if len(api.closed) != 1 || api.closed[0] != "a" {
+		t.Errorf("未請求關閉連接: %v", api.closed)
+	}
+}
+
+func TestTrafficDashboardDisabled(t *testing.T) {
+	cfg := domainConfig.DefaultConfig()
+	cfg.ClashAPI.Disabled = true
+
+	if _, err := NewTrafficService(zap.NewNop()).Dashboard(context.Background(), cfg); err == nil {
+		t.Error("Clash API 未啟用時應返回錯誤")
+	}
+}
@@ -0,0 +1,194 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/warp"
+)
+
+// WARPService WARP 賬戶管理服務
+// 負責設備註冊、WARP+ 許可證綁定與接入點選擇，結果寫回 WARPConfig
+type WARPService struct {
+	client *warp.Client
+	log    *zap.Logger
+}
+
+// NewWARPService 創建 WARP 服務
+func NewWARPService(client *warp.Client, log *zap.Logger) *WARPService {
+	return &WARPService{
+		client: client,
+		log:    log,
+	}
+}
+
+// Register 註冊新設備並寫入配置
+// 若配置中已有許可證密鑰，註冊完成後自動綁定
+func (s *WARPService) Register(ctx context.Context, cfg *domainConfig.Config, preferIPv6 bool) error {
+	if cfg == nil {
+		return fmt.Errorf("配置未加載")
+	}
+
+	acct, err := s.client.Register(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := &cfg.Routing.WARP
+	w.DeviceID = acct.DeviceID
+	w.AccessToken = acct.AccessToken
+	w.PrivateKey = acct.PrivateKey
+	w.PeerPublicKey = acct.PeerPublicKey
+	w.IPv4 = acct.IPv4
+	w.IPv6 = acct.IPv6
+	w.AccountType = acct.AccountType
+	w.SetReserved(acct.Reserved)
+
+	if endpoint := acct.SelectEndpoint(preferIPv6); endpoint != "" {
+		w.Endpoint = endpoint
+	}
+
+	if w.LicenseKey != "" {
+		if err := s.BindLicense(ctx, cfg, w.LicenseKey); err != nil {
+			// 許可證無效不影響免費賬戶使用
+			s.log.Warn("註冊成功但綁定許可證失敗", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// BindLicense 綁定 WARP+ 許可證，license 為空時僅清除本地記錄
+func (s *WARPService) BindLicense(ctx context.Context, cfg *domainConfig.Config, license string) error {
+	if cfg == nil {
+		return fmt.Errorf("配置未加載")
+	}
+
+	w := &cfg.Routing.WARP
+	license = strings.TrimSpace(license)
+	w.LicenseKey = license
+
+	if license == "" || !w.IsRegistered() {
+		return nil
+	}
+
+	info, err := s.client.BindLicense(ctx, w.DeviceID, w.AccessToken, license)
+	if err != nil {
+		return err
+	}
+
+	w.AccountType = info.AccountType
+	if !info.WARPPlus && info.AccountType != warp.AccountTypePlus {
+		return fmt.Errorf("許可證已提交，但賬戶仍為 %s", info.AccountType)
+	}
+
+	s.log.Info("WARP+ 許可證綁定成功", zap.String("account_type", info.AccountType))
+	return nil
+}
+
+// SetEndpoint 手動指定接入點，格式 host:port，留空恢復默認
+func (s *WARPService) SetEndpoint(cfg *domainConfig.Config, endpoint string) error {
+	if cfg == nil {
+		return fmt.Errorf("配置未加載")
+	}
+
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		cfg.Routing.WARP.Endpoint = ""
+		return nil
+	}
+
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil || host == "" {
+		return fmt.Errorf("接入點格式錯誤，應為 IP:端口 或 [IPv6]:端口")
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("接入點端口無效: %s", portStr)
+	}
+
+	cfg.Routing.WARP.Endpoint = net.JoinHostPort(host, strconv.Itoa(port))
+	return nil
+}
+
+// OptimizeEndpoint 掃描候選接入點並將最優結果寫入配置
+// includeIPv6 為 true 時同時探測內置 IPv6 網段；返回最優結果與完整排序列表
+func (s *WARPService) OptimizeEndpoint(ctx context.Context, cfg *domainConfig.Config, includeIPv6 bool) (*warp.ScanResult, []warp.ScanResult, error) {
+	if cfg == nil {
+		return nil, nil, fmt.Errorf("配置未加載")
+	}
+
+	w := &cfg.Routing.WARP
+	scanner, err := s.newScanner(w)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targets := w.ScanTargets
+	if len(targets) == 0 {
+		targets = append([]string{}, warp.DefaultScanTargetsV4...)
+		if includeIPv6 {
+			targets = append(targets, warp.DefaultScanTargetsV6...)
+		}
+	}
+	// 當前接入點一併參與比較，避免「優選」後反而變差
+	if w.Endpoint != "" {
+		targets = append([]string{w.Endpoint}, targets...)
+	}
+
+	candidates := warp.BuildCandidates(targets, w.ScanPorts, 2)
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("沒有可用的候選接入點")
+	}
+
+	s.log.Info("開始優選 WARP 接入點", zap.Int("candidates", len(candidates)))
+	results := scanner.Scan(ctx, candidates)
+	if ctx.Err() != nil {
+		return nil, results, fmt.Errorf("接入點掃描被中斷: %w", ctx.Err())
+	}
+
+	best := results[0]
+	if !best.Reachable() {
+		return nil, results, fmt.Errorf("所有 %d 個候選接入點均無握手響應", len(candidates))
+	}
+
+	w.Endpoint = best.Endpoint
+	s.log.Info("WARP 接入點優選完成",
+		zap.String("endpoint", best.Endpoint),
+		zap.Duration("rtt", best.AvgRTT),
+		zap.Float64("loss", best.Loss()))
+
+	return &best, results, nil
+}
+
+// TestEndpoint 對當前接入點執行握手探測
+func (s *WARPService) TestEndpoint(ctx context.Context, cfg *domainConfig.Config) (*warp.ScanResult, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("配置未加載")
+	}
+
+	w := &cfg.Routing.WARP
+	scanner, err := s.newScanner(w)
+	if err != nil {
+		return nil, err
+	}
+
+	host, port := w.GetEndpoint()
+	results := scanner.Scan(ctx, []string{net.JoinHostPort(host, strconv.Itoa(port))})
+	return &results[0], nil
+}
+
+func (s *WARPService) newScanner(w *domainConfig.WARPConfig) (*warp.Scanner, error) {
+	return warp.NewScanner(warp.ScanOptions{
+		PrivateKey:    w.PrivateKey,
+		PeerPublicKey: w.GetPeerPublicKey(),
+		Reserved:      w.ReservedBytes(),
+	}, s.log)
+}
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/warp"
+)
+
+func newWARPTestService(t *testing.T) *WARPService {
+	t.Helper()
+
+	clientID := base64.StdEncoding.EncodeToString([]byte{7, 8, 9})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reg", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"id": "dev", "token": "tok",
+			"account": {"account_type": "free"},
+			"config": {
+				"client_id": "` + clientID + `",
+				"interface": {"addresses": {"v4": "172.16.0.2", "v6": "2606:4700::2"}},
+				"peers": [{"public_key": "peer", "endpoint": {"v4": "162.159.192.5:0", "v6": "[2606:4700:d0::1]:0"}}]
+			}
+		}`))
+	})
+	mux.HandleFunc("/reg/dev/account", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"account_type": "unlimited", "warp_plus": true}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return NewWARPService(warp.NewClient(srv.URL, zap.NewNop()), zap.NewNop())
+}
+
+// TestWARPRegister 測試註冊結果寫入配置
+func TestWARPRegister(t *testing.T) {
+	svc := newWARPTestService(t)
+	cfg := domainConfig.DefaultConfig()
+	cfg.Routing.WARP.LicenseKey = "license"
+
+	if err := svc.Register(context.Background(), cfg, false); err != nil {
+		t.Fatalf("Register 失敗: %v", err)
+	}
+
+	w := cfg.Routing.WARP
+	if !w.IsRegistered() {
+		t.Fatal("註冊後應標記為已註冊")
+	}
+	if w.Reserved != "7,8,9" {
+		t.Errorf("Reserved 錯誤: %s", w.Reserved)
+	}
+	if w.Endpoint != "162.159.192.5:2408" {
+		t.Errorf("Endpoint 錯誤: %s", w.Endpoint)
+	}
+	if w.AccountType != warp.AccountTypePlus {
+		t.Errorf("已有許可證時應自動綁定 WARP+，實際: %s", w.AccountType)
+	}
+}
+
+// TestWARPSetEndpoint 測試接入點校驗
+func TestWARPSetEndpoint(t *testing.T) {
+	svc := NewWARPService(nil, zap.NewNop())
+	cfg := domainConfig.DefaultConfig()
+
+	if err := svc.SetEndpoint(cfg, "[2606:4700:d0::a29f:c001]:500"); err != nil {
+		t.Fatalf("合法 IPv6 接入點被拒絕: %v", err)
+	}
+	host, port := cfg.Routing.WARP.GetEndpoint()
+	if host != "2606:4700:d0::a29f:c001" || port != 500 {
+		t.Errorf("解析接入點錯誤: %s %d", host, port)
+	}
+
+	for _, bad := range []string{"162.159.192.1", "1.1.1.1:99999", ":2408"} {
+		if err := svc.SetEndpoint(cfg, bad); err == nil {
+			t.Errorf("非法接入點 %q 應被拒絕", bad)
+		}
+	}
+
+	if err := svc.SetEndpoint(cfg, ""); err != nil || cfg.Routing.WARP.Endpoint != "" {
+		t.Error("留空應恢復默認接入點")
+	}
+}
+
+// TestWARPOptimizeEndpoint 測試優選結果寫入配置
+func TestWARPOptimizeEndpoint(t *testing.T) {
+	// 模擬 WARP 對端：對任何握手發起包返回響應
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("監聽失敗: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n == 148 && buf[0] == 1 {
+				resp := make([]byte, 92)
+				resp[0] = 2
+				_, _ = conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	svc := NewWARPService(nil, zap.NewNop())
+	cfg := domainConfig.DefaultConfig()
+	cfg.Routing.WARP.ScanTargets = []string{conn.LocalAddr().String()}
+
+	best, results, err := svc.OptimizeEndpoint(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("OptimizeEndpoint 失敗: %v", err)
+	}
+	if len(results) != 1 || best.Endpoint != conn.LocalAddr().String() {
+		t.Fatalf("優選結果錯誤: %+v", results)
+	}
+	if cfg.Routing.WARP.Endpoint != best.Endpoint {
+		t.Errorf("最優接入點未寫入配置: %s", cfg.Routing.WARP.Endpoint)
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// DefaultClashAPIListen Clash API 默認監聽地址 (僅本機可訪問)
+const DefaultClashAPIListen = "127.0.0.1:9090"
+
+// ClashAPIConfig sing-box Clash API 配置，供流量面板讀取實時統計
+type ClashAPIConfig struct {
+	Disabled bool   `yaml:"disabled,omitempty"`
+	Listen   string `yaml:"listen,omitempty"` // 必須為回環地址
+	Secret   string `yaml:"secret,omitempty"` // 訪問密鑰，留空時自動生成
+}
+
+// IsEnabled 是否生成 Clash API 配置
+func (c *ClashAPIConfig) IsEnabled() bool {
+	return !c.Disabled && c.Listen != "" && c.Secret != ""
+}
+
+// Port 返回監聽端口，格式錯誤時返回 0
+func (c *ClashAPIConfig) Port() int {
+	_, portStr, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		return 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return port
+}
+
+// FillDefaults 補全監聽地址與訪問密鑰
+func (c *ClashAPIConfig) FillDefaults() {
+	if c.Listen == "" {
+		c.Listen = DefaultClashAPIListen
+	}
+	if c.Secret == "" {
+		c.Secret = generateClashAPISecret()
+	}
+}
+
+// Validate 驗證 Clash API 配置，只允許綁定回環地址
+func (c *ClashAPIConfig) Validate() error {
+	if c.Listen == "" {
+		return nil
+	}
+
+	host, portStr, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		return fmt.Errorf("Clash API 監聽地址格式錯誤: %s (應為 127.0.0.1:9090 形式)", c.Listen)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("Clash API 只能監聽本機回環地址: %s", c.Listen)
+	}
+	if port, err := strconv.Atoi(portStr); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("Clash API 端口無效: %s", portStr)
+	}
+	return nil
+}
+
+func generateClashAPISecret() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		panic("failed to generate clash api secret: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
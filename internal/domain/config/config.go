@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"math/big"
 	mrand "math/rand"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,16 +31,18 @@ type Repository interface {
 
 // Config 主配置結構
 type Config struct {
-	Version     int               `yaml:"version" validate:"required,min=2"`
-	Server      ServerConfig      `yaml:"server"`
-	Log         LogConfig         `yaml:"log"`
-	DNS         *DNSConfig        `yaml:"dns,omitempty"`
-	UUID        string            `yaml:"uuid"` // 全局用戶標識符（所有協議共用）
-	Password    string            `yaml:"password"`
-	Protocols   ProtocolsConfig   `yaml:"protocols"` // 所有入站協議相關
-	Routing     RoutingConfig     `yaml:"routing"`   // 路由與分流相關
-	Backup      BackupConfig      `yaml:"backup"`
-	Certificate CertificateConfig `yaml:"certificate"` // 證書配置
+	Version     int                `yaml:"version" validate:"required,min=2"`
+	Server      ServerConfig       `yaml:"server"`
+	Log         LogConfig          `yaml:"log"`
+	DNS         *DNSConfig         `yaml:"dns,omitempty"`
+	UUID        string             `yaml:"uuid"` // 全局用戶標識符（所有協議共用）
+	Password    string             `yaml:"password"`
+	Protocols   ProtocolsConfig    `yaml:"protocols"`               // 所有入站協議相關
+	Rotation    PortRotationConfig `yaml:"port_rotation,omitempty"` // 端口自動輪換
+	ClashAPI    ClashAPIConfig     `yaml:"clash_api,omitempty"`     // 流量統計接口
+	Routing     RoutingConfig      `yaml:"routing"`                 // 路由與分流相關
+	Backup      BackupConfig       `yaml:"backup"`
+	Certificate CertificateConfig  `yaml:"certificate"` // 證書配置
 }
 
 // ServerConfig 服務器配置
@@ -58,20 +63,20 @@ type LogConfig struct {
 
 // ProtocolsConfig 協議配置
 type ProtocolsConfig struct {
-	// 1: VLESS Reality Vision
-	RealityVision RealityVisionConfig `yaml:"reality_vision"`
-	// 2: VLESS Reality gRPC
-	RealityGRPC RealityGRPCConfig `yaml:"reality_grpc"`
-	// 3: Hysteria2
-	Hysteria2 Hysteria2Config `yaml:"hysteria2"`
-	// 4: TUIC v5
-	TUIC TUICConfig `yaml:"tuic"`
-	// 5: AnyTLS
-	AnyTLS AnyTLSConfig `yaml:"anytls"`
-	// 6: AnyTLS Reality
-	AnyTLSReality AnyTLSRealityConfig `yaml:"anytls_reality"`
-	// 7: ShadowTLS v3
-	ShadowTLS ShadowTLSConfig `yaml:"shadowtls"`
+	// 協議實例列表，以標籤唯一區分；每種協議的主實例使用默認標籤 (如 reality-vision-in)
+	Instances []ProtocolInstance `yaml:"instances,omitempty"`
+
+	// AnyTLS 命名自定義填充方案，供 TUI 快速切換
+	PaddingProfiles []PaddingProfile `yaml:"padding_profiles,omitempty"`
+
+	// V3 及更早版本的固定佈局 (每種協議一個配置)，僅用於讀取舊配置，遷移後清空
+	LegacyRealityVision *RealityVisionConfig `yaml:"reality_vision,omitempty"`
+	LegacyRealityGRPC   *RealityGRPCConfig   `yaml:"reality_grpc,omitempty"`
+	LegacyHysteria2     *Hysteria2Config     `yaml:"hysteria2,omitempty"`
+	LegacyTUIC          *TUICConfig          `yaml:"tuic,omitempty"`
+	LegacyAnyTLS        *AnyTLSConfig        `yaml:"anytls,omitempty"`
+	LegacyAnyTLSReality *AnyTLSRealityConfig `yaml:"anytls_reality,omitempty"`
+	LegacyShadowTLS     *ShadowTLSConfig     `yaml:"shadowtls,omitempty"`
 }
 
 // RealityVisionConfig Reality Vision 配置
@@ -81,7 +86,13 @@ type RealityVisionConfig struct {
 	SNI        string `yaml:"sni" validate:"required_if=Enabled true,omitempty,fqdn"`
 	PublicKey  string `yaml:"public_key"` // 由安裝/更新核心時寫入
 	PrivateKey string `yaml:"private_key"`
-	ShortID    string `yaml:"short_id"` // 可選，留空則由 sing-box 自行處理
+	ShortID    string `yaml:"short_id"`       // 可選，留空則由 sing-box 自行處理
+	UUID       string `yaml:"uuid,omitempty"` // 非主實例可單獨設置，留空則使用全局 UUID
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
 }
 
 // RealityGRPCConfig Reality gRPC 配置
@@ -92,6 +103,12 @@ type RealityGRPCConfig struct {
 	PublicKey  string `yaml:"public_key"`
 	PrivateKey string `yaml:"private_key"`
 	ShortID    string `yaml:"short_id"`
+	UUID       string `yaml:"uuid,omitempty"` // 非主實例可單獨設置，留空則使用全局 UUID
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
 }
 
 // Hysteria2Config Hysteria2 配置（需要證書）
@@ -99,16 +116,27 @@ type Hysteria2Config struct {
 	Enabled     bool   `yaml:"enabled"`
 	Port        int    `yaml:"port" validate:"required_if=Enabled true,omitempty,min=1024,max=65535"`
 	Password    string `yaml:"password" validate:"required_if=Enabled true,omitempty"`
-	PortHopping string `yaml:"port_hopping,omitempty"`
+	PortHopping string `yaml:"port_hopping,omitempty"` // 跳躍端口，逗號分隔多個範圍，如 20000-30000,40000-45000
+	HopInterval string `yaml:"hop_interval,omitempty"` // 客戶端跳躍間隔，如 30s
 	Obfs        string `yaml:"obfs,omitempty"`
 	UpMbps      int    `yaml:"up_mbps,omitempty" validate:"omitempty,min=1"`
 	DownMbps    int    `yaml:"down_mbps,omitempty" validate:"omitempty,min=1"`
-	ALPN        string `yaml:"alpn,omitempty"`
-	SNI         string `yaml:"sni,omitempty" validate:"omitempty,fqdn"`
+	// 忽略客戶端帶寬 (關閉 Brutal)，客戶端改用 BBR 擁塞控制，此時 up_mbps/down_mbps 不生效
+	IgnoreClientBandwidth bool   `yaml:"ignore_client_bandwidth,omitempty"`
+	ALPN                  string `yaml:"alpn,omitempty"`
+	SNI                   string `yaml:"sni,omitempty" validate:"omitempty,fqdn"`
 
 	// 證書模式配置
 	CertMode   string `yaml:"cert_mode" validate:"omitempty,oneof=acme self_signed"`
 	CertDomain string `yaml:"cert_domain,omitempty"`
+
+	// 偽裝網站 (masquerade)，非 Hysteria2 客戶端訪問時返回
+	Decoy DecoyConfig `yaml:"decoy,omitempty"`
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
 }
 
 // TUICConfig TUIC 配置（需要證書）
@@ -121,10 +149,18 @@ type TUICConfig struct {
 	ALPN              []string `yaml:"alpn,omitempty"`
 	CongestionControl string   `yaml:"congestion_control,omitempty"`
 	ZeroRTTHandshake  bool     `yaml:"zero_rtt_handshake,omitempty"`
+	UDPRelayMode      string   `yaml:"udp_relay_mode,omitempty"` // 客戶端 UDP 轉發模式 native / quic
+	AuthTimeout       string   `yaml:"auth_timeout,omitempty"`   // 服務端等待認證的超時，如 3s
+	Heartbeat         string   `yaml:"heartbeat,omitempty"`      // 心跳間隔，如 10s
 
 	// 證書模式配置
 	CertMode   string `yaml:"cert_mode" validate:"omitempty,oneof=acme self_signed"`
 	CertDomain string `yaml:"cert_domain,omitempty"`
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
 }
 
 // AnyTLSConfig AnyTLS 配置（需要證書）
@@ -141,6 +177,11 @@ type AnyTLSConfig struct {
 	// 書模式配置
 	CertMode   string `yaml:"cert_mode" validate:"omitempty,oneof=acme self_signed"`
 	CertDomain string `yaml:"cert_domain,omitempty"`
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
 }
 
 // AnyTLSRealityConfig AnyTLS Reality 配置
@@ -155,6 +196,11 @@ type AnyTLSRealityConfig struct {
 	ShortID       string   `yaml:"short_id,omitempty"`
 	PaddingMode   string   `yaml:"padding_mode,omitempty"`
 	PaddingScheme []string `yaml:"padding_scheme,omitempty"`
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
 }
 
 // ShadowTLSConfig ShadowTLS v3 配置（✅ 需要證書）
@@ -166,7 +212,92 @@ type ShadowTLSConfig struct {
 	SSMethod   string `yaml:"ss_method,omitempty"`
 	SNI        string `yaml:"sni,omitempty" validate:"omitempty,fqdn"`
 	DetourPort int    `yaml:"detour_port,omitempty" validate:"omitempty,min=1,max=65535"`
-	StrictMode bool   `yaml:"strict_mode,omitempty"`
+	StrictMode *bool  `yaml:"strict_mode,omitempty"` // 留空默認開啟
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
+}
+
+// CDNConfig 經 CDN 中轉的 TLS 入站配置（需要證書）
+// VLESS / VMess / Trojan 承載於 WebSocket / HTTPUpgrade / gRPC 之上，
+// 服務端監聽 Port，客戶端連接 CDN 域名 (CDNHost:CDNPort) 由 CDN 回源
+type CDNConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Port        int    `yaml:"port" validate:"required_if=Enabled true,omitempty,min=1024,max=65535"`
+	Proxy       string `yaml:"proxy,omitempty"`        // vless / vmess / trojan
+	Transport   string `yaml:"transport,omitempty"`    // ws / httpupgrade / grpc
+	Path        string `yaml:"path,omitempty"`         // WebSocket / HTTPUpgrade 路徑
+	Host        string `yaml:"host,omitempty"`         // Host 頭，留空使用 CDN 域名
+	ServiceName string `yaml:"service_name,omitempty"` // gRPC 服務名
+	UUID        string `yaml:"uuid,omitempty"`         // VLESS / VMess，主實例使用全局 UUID
+	Password    string `yaml:"password,omitempty"`     // Trojan，主實例使用全局密碼
+	SNI         string `yaml:"sni,omitempty" validate:"omitempty,fqdn"`
+	CDNHost     string `yaml:"cdn_host,omitempty"` // 客戶端連接地址 (CDN 域名或優選 IP)
+	CDNPort     int    `yaml:"cdn_port,omitempty"` // 客戶端連接端口，留空與監聽端口相同
+
+	// 證書模式配置
+	CertMode   string `yaml:"cert_mode" validate:"omitempty,oneof=acme self_signed"`
+	CertDomain string `yaml:"cert_domain,omitempty"`
+
+	// 偽裝網站 (僅 Trojan，認證失敗的連接回落到 http:// 上游)
+	Decoy DecoyConfig `yaml:"decoy,omitempty"`
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
+}
+
+// NaiveConfig NaiveProxy (HTTP/2 CONNECT over TLS) 入站配置（需要證書，建議使用 ACME）
+// sing-box 的 naive 入站不支持回落，未認證的請求會被直接斷開，因此不提供偽裝網站設置
+type NaiveConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Port     int    `yaml:"port" validate:"required_if=Enabled true,omitempty,min=1024,max=65535"`
+	Username string `yaml:"username,omitempty"` // 留空使用 prism
+	Password string `yaml:"password,omitempty"` // 主實例使用全局密碼
+	QUIC     bool   `yaml:"quic,omitempty"`     // 同時監聽 UDP 以支持 HTTP/3
+	SNI      string `yaml:"sni,omitempty" validate:"omitempty,fqdn"`
+
+	// 證書模式配置
+	CertMode   string `yaml:"cert_mode" validate:"omitempty,oneof=acme self_signed"`
+	CertDomain string `yaml:"cert_domain,omitempty"`
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
+}
+
+// ShadowsocksConfig Shadowsocks 2022 入站配置
+// 設置 Users 時為多用戶模式 (EIH)，設置 Relays 時作為中轉服務器按用戶密鑰轉發到目標服務器
+type ShadowsocksConfig struct {
+	Enabled  bool               `yaml:"enabled"`
+	Port     int                `yaml:"port" validate:"required_if=Enabled true,omitempty,min=1024,max=65535"`
+	Method   string             `yaml:"method,omitempty"`   // 2022-blake3-aes-128-gcm / 2022-blake3-aes-256-gcm / 2022-blake3-chacha20-poly1305
+	Password string             `yaml:"password,omitempty"` // 服務端密鑰 (Base64)，長度由加密方式決定
+	Users    []ShadowsocksUser  `yaml:"users,omitempty"`
+	Relays   []ShadowsocksRelay `yaml:"relays,omitempty"`
+
+	// 入站路由策略
+	Routing InboundRouting `yaml:"routing,omitempty"`
+	// 入站防火牆策略
+	Firewall InboundFirewall `yaml:"firewall,omitempty"`
+}
+
+// ShadowsocksUser Shadowsocks 2022 多用戶模式下的用戶
+type ShadowsocksUser struct {
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"` // 用戶密鑰 (Base64)
+}
+
+// ShadowsocksRelay Shadowsocks 2022 中轉目標
+type ShadowsocksRelay struct {
+	Name       string `yaml:"name"`
+	Server     string `yaml:"server"`
+	ServerPort int    `yaml:"server_port"`
+	Password   string `yaml:"password"` // 目標服務器密鑰 (Base64)
 }
 
 // WARPConfig WARP 配置
@@ -176,8 +307,101 @@ type WARPConfig struct {
 	Domains    []string `yaml:"domains"`
 	PrivateKey string   `yaml:"private_key"`
 	IPv6       string   `yaml:"ipv6"`
-	Reserved   string   `yaml:"reserved"`
+	Reserved   string   `yaml:"reserved"` // 格式: "12,34,56"
 	LicenseKey string   `json:"license_key" yaml:"license_key"`
+
+	// 設備註冊信息（由 WARP 註冊流程寫入）
+	DeviceID      string `yaml:"device_id,omitempty"`
+	AccessToken   string `yaml:"access_token,omitempty"`
+	AccountType   string `yaml:"account_type,omitempty"` // free / unlimited (WARP+)
+	IPv4          string `yaml:"ipv4,omitempty"`
+	PeerPublicKey string `yaml:"peer_public_key,omitempty"`
+	Endpoint      string `yaml:"endpoint,omitempty"` // host:port，留空使用默認接入點
+
+	// 接入點優選
+	ScanTargets  []string `yaml:"scan_targets,omitempty"`  // IP / CIDR / IP:端口，留空使用內置列表
+	ScanPorts    []int    `yaml:"scan_ports,omitempty"`    // 留空使用內置端口
+	AutoOptimize bool     `yaml:"auto_optimize,omitempty"` // 定時任務中自動優選
+}
+
+const (
+	// DefaultWARPEndpoint WARP 默認接入點
+	DefaultWARPEndpoint = "162.159.192.1:2408"
+	// DefaultWARPPeerPublicKey Cloudflare WARP 公鑰
+	DefaultWARPPeerPublicKey = "bmXOC+F1FxEMF9dyiK2H5/1SUtzH0JuVo51h2wPfgyo="
+	// DefaultWARPIPv4 WARP 默認分配的 IPv4 地址
+	DefaultWARPIPv4 = "172.16.0.2"
+)
+
+// IsRegistered 是否已完成設備註冊
+func (w *WARPConfig) IsRegistered() bool {
+	return w.DeviceID != "" && w.AccessToken != "" && w.PrivateKey != ""
+}
+
+// GetEndpoint 獲取接入點 (host, port)，未設置或格式錯誤時返回默認值
+func (w *WARPConfig) GetEndpoint() (string, int) {
+	endpoint := w.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultWARPEndpoint
+	}
+
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host, portStr, _ = net.SplitHostPort(DefaultWARPEndpoint)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		port = 2408
+	}
+	return host, port
+}
+
+// GetPeerPublicKey 獲取對端公鑰
+func (w *WARPConfig) GetPeerPublicKey() string {
+	if w.PeerPublicKey != "" {
+		return w.PeerPublicKey
+	}
+	return DefaultWARPPeerPublicKey
+}
+
+// GetIPv4 獲取本地 IPv4 地址
+func (w *WARPConfig) GetIPv4() string {
+	if w.IPv4 != "" {
+		return w.IPv4
+	}
+	return DefaultWARPIPv4
+}
+
+// ReservedBytes 解析 reserved 字段，兼容 "1,2,3" 與 "[1, 2, 3]" 兩種寫法
+func (w *WARPConfig) ReservedBytes() []int {
+	raw := strings.Trim(strings.TrimSpace(w.Reserved), "[]")
+	if raw == "" {
+		return nil
+	}
+
+	parts := strings.Split(raw, ",")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	result := make([]int, 0, 3)
+	for _, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 || n > 255 {
+			return nil
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// SetReserved 以 "1,2,3" 格式寫入 reserved 字段
+func (w *WARPConfig) SetReserved(reserved []int) {
+	parts := make([]string, 0, len(reserved))
+	for _, b := range reserved {
+		parts = append(parts, strconv.Itoa(b))
+	}
+	w.Reserved = strings.Join(parts, ",")
 }
 
 // IPv6SplitConfig IPv6 分流配置
@@ -243,98 +467,40 @@ func (c *Config) EncryptSensitiveFields(encryptor *crypto.Encryptor) error {
 		c.Password = encrypted
 	}
 
-	// 2. Reality Vision 私钥
-	if c.Protocols.RealityVision.PrivateKey != "" && !crypto.IsEncrypted(c.Protocols.RealityVision.PrivateKey) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.RealityVision.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("加密 RealityVision 私钥失败: %w", err)
-		}
-		c.Protocols.RealityVision.PrivateKey = encrypted
-	}
-
-	// 3. Reality gRPC 私钥
-	if c.Protocols.RealityGRPC.PrivateKey != "" && !crypto.IsEncrypted(c.Protocols.RealityGRPC.PrivateKey) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.RealityGRPC.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("加密 RealityGRPC 私钥失败: %w", err)
+	// 2. 協議實例的私鑰與密碼
+	for _, field := range c.Protocols.secretFields() {
+		if *field == "" || crypto.IsEncrypted(*field) {
+			continue
 		}
-		c.Protocols.RealityGRPC.PrivateKey = encrypted
-	}
-
-	// 4. Hysteria2 密码
-	if c.Protocols.Hysteria2.Password != "" && !crypto.IsEncrypted(c.Protocols.Hysteria2.Password) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.Hysteria2.Password)
+		encrypted, err := encryptor.Encrypt(*field)
 		if err != nil {
-			return fmt.Errorf("加密 Hysteria2 密码失败: %w", err)
+			return fmt.Errorf("加密協議敏感字段失败: %w", err)
 		}
-		c.Protocols.Hysteria2.Password = encrypted
+		*field = encrypted
 	}
 
-	// 5. TUIC 密码
-	if c.Protocols.TUIC.Password != "" && !crypto.IsEncrypted(c.Protocols.TUIC.Password) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.TUIC.Password)
-		if err != nil {
-			return fmt.Errorf("加密 TUIC 密码失败: %w", err)
-		}
-		c.Protocols.TUIC.Password = encrypted
-	}
-
-	// 6. AnyTLS 密码
-	if c.Protocols.AnyTLS.Password != "" && !crypto.IsEncrypted(c.Protocols.AnyTLS.Password) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.AnyTLS.Password)
-		if err != nil {
-			return fmt.Errorf("加密 AnyTLS 密码失败: %w", err)
-		}
-		c.Protocols.AnyTLS.Password = encrypted
-	}
-
-	// 7. AnyTLS Reality 私钥和密码
-	if c.Protocols.AnyTLSReality.PrivateKey != "" && !crypto.IsEncrypted(c.Protocols.AnyTLSReality.PrivateKey) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.AnyTLSReality.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("加密 AnyTLSReality 私钥失败: %w", err)
-		}
-		c.Protocols.AnyTLSReality.PrivateKey = encrypted
-	}
-	if c.Protocols.AnyTLSReality.Password != "" && !crypto.IsEncrypted(c.Protocols.AnyTLSReality.Password) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.AnyTLSReality.Password)
-		if err != nil {
-			return fmt.Errorf("加密 AnyTLSReality 密码失败: %w", err)
-		}
-		c.Protocols.AnyTLSReality.Password = encrypted
-	}
-
-	// 8. ShadowTLS 密码
-	if c.Protocols.ShadowTLS.Password != "" && !crypto.IsEncrypted(c.Protocols.ShadowTLS.Password) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.ShadowTLS.Password)
-		if err != nil {
-			return fmt.Errorf("加密 ShadowTLS 密码失败: %w", err)
-		}
-		c.Protocols.ShadowTLS.Password = encrypted
-	}
-	if c.Protocols.ShadowTLS.SSPassword != "" && !crypto.IsEncrypted(c.Protocols.ShadowTLS.SSPassword) {
-		encrypted, err := encryptor.Encrypt(c.Protocols.ShadowTLS.SSPassword)
-		if err != nil {
-			return fmt.Errorf("加密 ShadowTLS SS密码失败: %w", err)
-		}
-		c.Protocols.ShadowTLS.SSPassword = encrypted
-	}
-
-	// 9. 证书配置
+	// 3. 证书配置
 	if err := c.Certificate.EncryptSensitiveFields(encryptor); err != nil {
 		return err
 	}
 
-	// 10. WARP
+	// 4. WARP
 	if err := c.Routing.WARP.EncryptSensitiveFields(encryptor); err != nil {
 		return err
 	}
 
-	// 11. Socks5
+	// 5. Socks5
 	if err := c.Routing.Socks5.EncryptSensitiveFields(encryptor); err != nil {
 		return err
 	}
 
+	// 6. 自定義出站
+	for i := range c.Routing.Outbounds {
+		if err := c.Routing.Outbounds[i].EncryptSensitiveFields(encryptor); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -353,108 +519,50 @@ func (c *Config) DecryptSensitiveFields(encryptor *crypto.Encryptor) error {
 		c.Password = decrypted
 	}
 
-	// 2. Reality Vision 私钥
-	if crypto.IsEncrypted(c.Protocols.RealityVision.PrivateKey) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.RealityVision.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("解密 RealityVision 私钥失败: %w", err)
-		}
-		c.Protocols.RealityVision.PrivateKey = decrypted
-	}
-
-	// 3. Reality gRPC 私钥
-	if crypto.IsEncrypted(c.Protocols.RealityGRPC.PrivateKey) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.RealityGRPC.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("解密 RealityGRPC 私钥失败: %w", err)
-		}
-		c.Protocols.RealityGRPC.PrivateKey = decrypted
-	}
-
-	// 4. Hysteria2 密码
-	if crypto.IsEncrypted(c.Protocols.Hysteria2.Password) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.Hysteria2.Password)
-		if err != nil {
-			return fmt.Errorf("解密 Hysteria2 密码失败: %w", err)
-		}
-		c.Protocols.Hysteria2.Password = decrypted
-	}
-
-	// 5. TUIC 密码
-	if crypto.IsEncrypted(c.Protocols.TUIC.Password) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.TUIC.Password)
-		if err != nil {
-			return fmt.Errorf("解密 TUIC 密码失败: %w", err)
-		}
-		c.Protocols.TUIC.Password = decrypted
-	}
-
-	// 6. AnyTLS 密码
-	if crypto.IsEncrypted(c.Protocols.AnyTLS.Password) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.AnyTLS.Password)
-		if err != nil {
-			return fmt.Errorf("解密 AnyTLS 密码失败: %w", err)
-		}
-		c.Protocols.AnyTLS.Password = decrypted
-	}
-
-	// 7. AnyTLS Reality 私钥和密码
-	if crypto.IsEncrypted(c.Protocols.AnyTLSReality.PrivateKey) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.AnyTLSReality.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("解密 AnyTLSReality 私钥失败: %w", err)
-		}
-		c.Protocols.AnyTLSReality.PrivateKey = decrypted
-	}
-	if crypto.IsEncrypted(c.Protocols.AnyTLSReality.Password) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.AnyTLSReality.Password)
-		if err != nil {
-			return fmt.Errorf("解密 AnyTLSReality 密码失败: %w", err)
-		}
-		c.Protocols.AnyTLSReality.Password = decrypted
-	}
-
-	// 8. ShadowTLS 密码
-	if crypto.IsEncrypted(c.Protocols.ShadowTLS.Password) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.ShadowTLS.Password)
-		if err != nil {
-			return fmt.Errorf("解密 ShadowTLS 密码失败: %w", err)
+	// 2. 協議實例的私鑰與密碼
+	for _, field := range c.Protocols.secretFields() {
+		if !crypto.IsEncrypted(*field) {
+			continue
 		}
-		c.Protocols.ShadowTLS.Password = decrypted
-	}
-	if crypto.IsEncrypted(c.Protocols.ShadowTLS.SSPassword) {
-		decrypted, err := encryptor.Decrypt(c.Protocols.ShadowTLS.SSPassword)
+		decrypted, err := encryptor.Decrypt(*field)
 		if err != nil {
-			return fmt.Errorf("解密 ShadowTLS SS密码失败: %w", err)
+			return fmt.Errorf("解密協議敏感字段失败: %w", err)
 		}
-		c.Protocols.ShadowTLS.SSPassword = decrypted
+		*field = decrypted
 	}
 
-	// 9. 证书配置
+	// 3. 证书配置
 	if err := c.Certificate.DecryptSensitiveFields(encryptor); err != nil {
 		return err
 	}
 
-	// 10. WARP
+	// 4. WARP
 	if err := c.Routing.WARP.DecryptSensitiveFields(encryptor); err != nil {
 		return err
 	}
 
-	// 11. Socks5
+	// 5. Socks5
 	if err := c.Routing.Socks5.DecryptSensitiveFields(encryptor); err != nil {
 		return err
 	}
 
+	// 6. 自定義出站
+	for i := range c.Routing.Outbounds {
+		if err := c.Routing.Outbounds[i].DecryptSensitiveFields(encryptor); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// EncryptSensitiveFields 加密 WARP 私鑰
+// EncryptSensitiveFields 加密 WARP 私鑰與訪問令牌
 func (w *WARPConfig) EncryptSensitiveFields(encryptor *crypto.Encryptor) error {
-	if encryptor == nil || w.PrivateKey == "" {
+	if encryptor == nil {
 		return nil
 	}
 
-	if !crypto.IsEncrypted(w.PrivateKey) {
+	if w.PrivateKey != "" && !crypto.IsEncrypted(w.PrivateKey) {
 		encrypted, err := encryptor.Encrypt(w.PrivateKey)
 		if err != nil {
 			return fmt.Errorf("加密 WARP PrivateKey 失敗: %w", err)
@@ -462,12 +570,20 @@ func (w *WARPConfig) EncryptSensitiveFields(encryptor *crypto.Encryptor) error {
 		w.PrivateKey = encrypted
 	}
 
+	if w.AccessToken != "" && !crypto.IsEncrypted(w.AccessToken) {
+		encrypted, err := encryptor.Encrypt(w.AccessToken)
+		if err != nil {
+			return fmt.Errorf("加密 WARP AccessToken 失敗: %w", err)
+		}
+		w.AccessToken = encrypted
+	}
+
 	return nil
 }
 
-// DecryptSensitiveFields 解密 WARP 私鑰
+// DecryptSensitiveFields 解密 WARP 私鑰與訪問令牌
 func (w *WARPConfig) DecryptSensitiveFields(encryptor *crypto.Encryptor) error {
-	if encryptor == nil || w.PrivateKey == "" {
+	if encryptor == nil {
 		return nil
 	}
 
@@ -479,6 +595,14 @@ func (w *WARPConfig) DecryptSensitiveFields(encryptor *crypto.Encryptor) error {
 		w.PrivateKey = decrypted
 	}
 
+	if crypto.IsEncrypted(w.AccessToken) {
+		decrypted, err := encryptor.Decrypt(w.AccessToken)
+		if err != nil {
+			return fmt.Errorf("解密 WARP AccessToken 失敗: %w", err)
+		}
+		w.AccessToken = decrypted
+	}
+
 	return nil
 }
 
@@ -573,7 +697,7 @@ func DefaultConfig() *Config {
 	realityShortID := generateShortID()
 
 	return &Config{
-		Version: 2,
+		Version: ConfigVersionLatest,
 		Server: ServerConfig{
 			Host: "0.0.0.0",
 			Port: 443,
@@ -588,6 +712,10 @@ func DefaultConfig() *Config {
 		},
 		UUID:     uuid.New().String(),
 		Password: defaultPassword,
+		ClashAPI: ClashAPIConfig{
+			Listen: DefaultClashAPIListen,
+			Secret: generateClashAPISecret(),
+		},
 		Backup: BackupConfig{
 			Enabled:       true,
 			MaxFiles:      30,
@@ -596,80 +724,137 @@ func DefaultConfig() *Config {
 		},
 
 		Protocols: ProtocolsConfig{
-			RealityVision: RealityVisionConfig{
-				Enabled:    true,
-				Port:       randomPort(),
-				SNI:        "www.microsoft.com",
-				PublicKey:  realityPublicKey,
-				PrivateKey: realityPrivateKey,
-				ShortID:    realityShortID,
-			},
-			RealityGRPC: RealityGRPCConfig{
-				Enabled:    false,
-				Port:       randomPort(),
-				SNI:        "www.microsoft.com",
-				PublicKey:  realityPublicKey,
-				PrivateKey: realityPrivateKey,
-				ShortID:    realityShortID,
-			},
-			Hysteria2: Hysteria2Config{
-				Enabled:     true,
-				Port:        randomPort(),
-				Password:    "",
-				PortHopping: "",
-				Obfs:        "",
-				UpMbps:      100,
-				DownMbps:    100,
-				ALPN:        "h3",
-				SNI:         "www.bing.com",
-				CertMode:    "self_signed",
-				CertDomain:  "",
-			},
-			TUIC: TUICConfig{
-				Enabled:           true,
-				Port:              randomPort(),
-				UUID:              "",
-				Password:          "",
-				SNI:               "www.bing.com",
-				ALPN:              []string{"h3"},
-				CongestionControl: "bbr",
-				ZeroRTTHandshake:  false,
-				CertMode:          "self_signed",
-				CertDomain:        "",
-			},
-			AnyTLS: AnyTLSConfig{
-				Enabled:       false,
-				Port:          randomPort(),
-				Username:      "",
-				Password:      "",
-				SNI:           "www.bing.com",
-				PaddingMode:   "official",
-				PaddingScheme: nil,
-				ALPN:          []string{"h2", "http/1.1"},
-				CertMode:      "self_signed",
-				CertDomain:    "",
-			},
-			AnyTLSReality: AnyTLSRealityConfig{
-				Enabled:       false,
-				Port:          randomPort(),
-				Username:      "",
-				Password:      "",
-				SNI:           "www.microsoft.com",
-				PublicKey:     realityPublicKey,
-				PrivateKey:    realityPrivateKey,
-				ShortID:       realityShortID,
-				PaddingMode:   "official",
-				PaddingScheme: nil,
-			},
-			ShadowTLS: ShadowTLSConfig{
-				Enabled:    false,
-				Port:       randomPort(),
-				Password:   "",
-				SSPassword: "",
-				SSMethod:   "2022-blake3-aes-128-gcm",
-				SNI:        "www.microsoft.com",
-				DetourPort: 10000,
-				StrictMode: true,
+			Instances: []ProtocolInstance{
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeRealityVision), Type: ProtocolTypeRealityVision,
+					RealityVision: &RealityVisionConfig{
+						Enabled:    true,
+						Port:       randomPort(),
+						SNI:        "www.microsoft.com",
+						PublicKey:  realityPublicKey,
+						PrivateKey: realityPrivateKey,
+						ShortID:    realityShortID,
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeRealityGRPC), Type: ProtocolTypeRealityGRPC,
+					RealityGRPC: &RealityGRPCConfig{
+						Enabled:    false,
+						Port:       randomPort(),
+						SNI:        "www.microsoft.com",
+						PublicKey:  realityPublicKey,
+						PrivateKey: realityPrivateKey,
+						ShortID:    realityShortID,
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeHysteria2), Type: ProtocolTypeHysteria2,
+					Hysteria2: &Hysteria2Config{
+						Enabled:     true,
+						Port:        randomPort(),
+						Password:    "",
+						PortHopping: "",
+						Obfs:        "",
+						UpMbps:      100,
+						DownMbps:    100,
+						ALPN:        "h3",
+						SNI:         "www.bing.com",
+						CertMode:    "self_signed",
+						CertDomain:  "",
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeTUIC), Type: ProtocolTypeTUIC,
+					TUIC: &TUICConfig{
+						Enabled:           true,
+						Port:              randomPort(),
+						UUID:              "",
+						Password:          "",
+						SNI:               "www.bing.com",
+						ALPN:              []string{"h3"},
+						CongestionControl: "bbr",
+						ZeroRTTHandshake:  false,
+						CertMode:          "self_signed",
+						CertDomain:        "",
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeAnyTLS), Type: ProtocolTypeAnyTLS,
+					AnyTLS: &AnyTLSConfig{
+						Enabled:       false,
+						Port:          randomPort(),
+						Username:      "",
+						Password:      "",
+						SNI:           "www.bing.com",
+						PaddingMode:   "official",
+						PaddingScheme: nil,
+						ALPN:          []string{"h2", "http/1.1"},
+						CertMode:      "self_signed",
+						CertDomain:    "",
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeAnyTLSReality), Type: ProtocolTypeAnyTLSReality,
+					AnyTLSReality: &AnyTLSRealityConfig{
+						Enabled:       false,
+						Port:          randomPort(),
+						Username:      "",
+						Password:      "",
+						SNI:           "www.microsoft.com",
+						PublicKey:     realityPublicKey,
+						PrivateKey:    realityPrivateKey,
+						ShortID:       realityShortID,
+						PaddingMode:   "official",
+						PaddingScheme: nil,
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeShadowTLS), Type: ProtocolTypeShadowTLS,
+					ShadowTLS: &ShadowTLSConfig{
+						Enabled:    false,
+						Port:       randomPort(),
+						Password:   "",
+						SSPassword: "",
+						SSMethod:   "2022-blake3-aes-128-gcm",
+						SNI:        "www.microsoft.com",
+						DetourPort: 10000,
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeCDN), Type: ProtocolTypeCDN,
+					CDN: &CDNConfig{
+						Enabled:     false,
+						Port:        DefaultCDNPort,
+						Proxy:       CDNProxyVLESS,
+						Transport:   CDNTransportWS,
+						Path:        "/" + generateShortID(),
+						ServiceName: generateShortID(),
+						SNI:         "",
+						CertMode:    "self_signed",
+						CertDomain:  "",
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeShadowsocks), Type: ProtocolTypeShadowsocks,
+					Shadowsocks: &ShadowsocksConfig{
+						Enabled:  false,
+						Port:     randomPort(),
+						Method:   DefaultShadowsocksMethod,
+						Password: GenerateSSKey(DefaultShadowsocksMethod),
+					},
+				},
+				{
+					Tag: DefaultProtocolTag(ProtocolTypeNaive), Type: ProtocolTypeNaive,
+					Naive: &NaiveConfig{
+						Enabled:    false,
+						Port:       randomPort(),
+						Username:   DefaultNaiveUsername,
+						Password:   "",
+						SNI:        "",
+						CertMode:   "self_signed",
+						CertDomain: "",
+					},
+				},
 			},
 		},
 		Routing: RoutingConfig{
@@ -760,7 +945,25 @@ func generateShortID() string {
 
 // Validate 驗證配置
 func (c *Config) Validate() error {
-	return nil
+	if err := c.Routing.ValidateOutbounds(); err != nil {
+		return err
+	}
+	if err := c.Routing.ValidateRules(); err != nil {
+		return err
+	}
+	if err := c.Protocols.ValidateRouting(&c.Routing); err != nil {
+		return err
+	}
+	if err := c.Protocols.ValidateInstances(); err != nil {
+		return err
+	}
+	if err := c.Rotation.Validate(); err != nil {
+		return err
+	}
+	if err := c.ClashAPI.Validate(); err != nil {
+		return err
+	}
+	return c.Protocols.ValidateFirewall()
 }
 
 // DeepCopy 深拷貝配置 (重構版：序列化回環策略)
@@ -825,60 +1028,30 @@ func (c *CertificateConfig) SetDNSProvider(name, id, secret string) {
 	}
 }
 
-// FillDefaults 自動填充協議默認值
-func (c *Config) FillDefaults() {
-	// Hysteria2
-	if c.Protocols.Hysteria2.Password == "" {
-		c.Protocols.Hysteria2.Password = c.Password
-	}
-
-	// TUIC
-	if c.Protocols.TUIC.UUID == "" {
-		c.Protocols.TUIC.UUID = c.UUID
-	}
-	if c.Protocols.TUIC.Password == "" {
-		c.Protocols.TUIC.Password = c.Password
-	}
-
-	// AnyTLS
-	if c.Protocols.AnyTLS.Username == "" {
-		c.Protocols.AnyTLS.Username = "prism"
-	}
-	if c.Protocols.AnyTLS.Password == "" {
-		c.Protocols.AnyTLS.Password = c.Password
-	}
+// FillDefaults 自動填充協議默認值 (補齊缺失的協議主實例)
+func (c *Config) FillDefaults() error {
+	c.ClashAPI.FillDefaults()
 
-	// AnyTLS-Reality
-	if c.Protocols.AnyTLSReality.Username == "" {
-		c.Protocols.AnyTLSReality.Username = "prism"
-	}
-	if c.Protocols.AnyTLSReality.Password == "" {
-		c.Protocols.AnyTLSReality.Password = c.Password
+	if err := c.Protocols.EnsurePrimaries(); err != nil {
+		return fmt.Errorf("補齊協議主實例失敗: %w", err)
 	}
+	rv := c.Protocols.RealityVision()
+	for i := range c.Protocols.Instances {
+		inst := &c.Protocols.Instances[i]
+		inst.fillDefaults(c.UUID, c.Password)
 
-	// ShadowTLS
-	if c.Protocols.ShadowTLS.Password == "" {
-		c.Protocols.ShadowTLS.Password = c.Password
-	}
-	if c.Protocols.ShadowTLS.SSPassword == "" {
-		c.Protocols.ShadowTLS.SSPassword = c.Password
-	}
-}
-
-func (h *Hysteria2Config) ValidatePortHopping() error {
-	if h.PortHopping == "" {
-		return nil
-	}
-
-	var start, end int
-	if _, err := fmt.Sscanf(h.PortHopping, "%d-%d", &start, &end); err == nil {
-		if start < 1024 || end > 65535 || start >= end {
-			return fmt.Errorf("端口跳躍範圍無效: %s", h.PortHopping)
+		// 早期版本的 AnyTLS Reality 沿用 Reality Vision 的密鑰，缺失時補齊
+		if ar := inst.AnyTLSReality; ar != nil && ar.PrivateKey == "" && ar.PublicKey == "" {
+			ar.PrivateKey, ar.PublicKey = rv.PrivateKey, rv.PublicKey
+			if ar.ShortID == "" {
+				ar.ShortID = rv.ShortID
+			}
+			if ar.SNI == "" {
+				ar.SNI = rv.SNI
+			}
 		}
-		return nil
 	}
-
-	return fmt.Errorf("端口跳躍格式錯誤: %s", h.PortHopping)
+	return nil
 }
 
 // ========================================
@@ -893,6 +1066,9 @@ type RoutingConfig struct {
 	DNS            DNSRoutingConfig `yaml:"dns_routing"`
 	SNIProxy       SNIProxyConfig   `yaml:"sni_proxy"`
 	DomainStrategy string           `yaml:"domain_strategy,omitempty" validate:"omitempty,oneof=prefer_ipv4 prefer_ipv6 ipv4_only ipv6_only"`
+	Outbounds      []CustomOutbound `yaml:"outbounds,omitempty"` // 自定義上游出站
+	Rules          []RoutingRule    `yaml:"rules,omitempty"`     // 路由規則 (按優先級匹配)
+	Final          string           `yaml:"final,omitempty"`     // 默認出站，留空為 direct
 }
 
 // Socks5Config Socks5 分流配置
@@ -3,6 +3,7 @@ package config
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestDefaultConfig 測試默認配置生成
@@ -35,13 +36,13 @@ func TestDefaultConfig(t *testing.T) {
 
 	// 4. 驗證關鍵協議默認值
 	// Reality Vision (默認啟用)
-	if !cfg.Protocols.RealityVision.Enabled {
+	if !cfg.Protocols.RealityVision().Enabled {
 		t.Error("RealityVision should be enabled by default")
 	}
-	if cfg.Protocols.RealityVision.PrivateKey == "" {
+	if cfg.Protocols.RealityVision().PrivateKey == "" {
 		t.Error("RealityVision PrivateKey should be generated")
 	}
-	if cfg.Protocols.RealityVision.ShortID == "" {
+	if cfg.Protocols.RealityVision().ShortID == "" {
 		t.Error("RealityVision ShortID should be generated")
 	}
 
@@ -72,47 +73,43 @@ func TestFillDefaults(t *testing.T) {
 	cfg := &Config{
 		UUID:     "global-uuid",
 		Password: "global-password",
-		Protocols: ProtocolsConfig{
-			Hysteria2: Hysteria2Config{},
-			TUIC:      TUICConfig{},
-			AnyTLS:    AnyTLSConfig{},
-			ShadowTLS: ShadowTLSConfig{},
-		},
 	}
 
 	// 執行填充
-	cfg.FillDefaults()
+	if err := cfg.FillDefaults(); err != nil {
+		t.Fatalf("FillDefaults: %v", err)
+	}
 
 	// 驗證 Hysteria2 是否繼承了全局密碼
-	if cfg.Protocols.Hysteria2.Password != "global-password" {
-		t.Errorf("Hysteria2 password should inherit global password, got '%s'", cfg.Protocols.Hysteria2.Password)
+	if cfg.Protocols.Hysteria2().Password != "global-password" {
+		t.Errorf("Hysteria2 password should inherit global password, got '%s'", cfg.Protocols.Hysteria2().Password)
 	}
 
 	// 驗證 TUIC 是否繼承了全局 UUID 和密碼
-	if cfg.Protocols.TUIC.UUID != "global-uuid" {
-		t.Errorf("TUIC UUID should inherit global UUID, got '%s'", cfg.Protocols.TUIC.UUID)
+	if cfg.Protocols.TUIC().UUID != "global-uuid" {
+		t.Errorf("TUIC UUID should inherit global UUID, got '%s'", cfg.Protocols.TUIC().UUID)
 	}
-	if cfg.Protocols.TUIC.Password != "global-password" {
+	if cfg.Protocols.TUIC().Password != "global-password" {
 		t.Errorf("TUIC password should inherit global password")
 	}
 
 	// 驗證 AnyTLS 默認用戶名
-	if cfg.Protocols.AnyTLS.Username != "prism" {
-		t.Errorf("AnyTLS username should default to 'prism', got '%s'", cfg.Protocols.AnyTLS.Username)
+	if cfg.Protocols.AnyTLS().Username != "prism" {
+		t.Errorf("AnyTLS username should default to 'prism', got '%s'", cfg.Protocols.AnyTLS().Username)
 	}
 }
 
 // TestDeepCopy 測試深拷貝邏輯
 func TestDeepCopy(t *testing.T) {
 	original := DefaultConfig()
-	original.Protocols.RealityVision.SNI = "original.com"
+	original.Protocols.RealityVision().SNI = "original.com"
 	original.Routing.WARP.Domains = []string{"site1.com", "site2.com"}
 
 	// 執行拷貝
 	copied := original.DeepCopy()
 
 	// 驗證內容一致性
-	if copied.Protocols.RealityVision.SNI != "original.com" {
+	if copied.Protocols.RealityVision().SNI != "original.com" {
 		t.Error("DeepCopy failed to copy basic field")
 	}
 	if len(copied.Routing.WARP.Domains) != 2 {
@@ -120,10 +117,10 @@ func TestDeepCopy(t *testing.T) {
 	}
 
 	// 驗證內存獨立性 (修改副本不應影響原件)
-	copied.Protocols.RealityVision.SNI = "modified.com"
+	copied.Protocols.RealityVision().SNI = "modified.com"
 	copied.Routing.WARP.Domains[0] = "hacked.com"
 
-	if original.Protocols.RealityVision.SNI == "modified.com" {
+	if original.Protocols.RealityVision().SNI == "modified.com" {
 		t.Error("DeepCopy is shallow: modifying copy affected original struct")
 	}
 	if original.Routing.WARP.Domains[0] == "hacked.com" {
@@ -137,12 +134,17 @@ func TestPortHoppingValidation(t *testing.T) {
 		input   string
 		wantErr bool
 	}{
-		{"20000-30000", false}, // 合法
-		{"1000-2000", true},    // 起始端口太小 (<1024)
-		{"60000-70000", true},  // 結束端口太大 (>65535)
-		{"30000-20000", true},  // 起始 > 結束
-		{"invalid", true},      // 格式錯誤
-		{"", false},            // 空字符串視為不啟用，合法
+		{"20000-30000", false},             // 合法
+		{"1000-2000", true},                // 起始端口太小 (<1024)
+		{"60000-70000", true},              // 結束端口太大 (>65535)
+		{"30000-20000", true},              // 起始 > 結束
+		{"invalid", true},                  // 格式錯誤
+		{"", false},                        // 空字符串視為不啟用，合法
+		{"20000-30000,40000-45000", false}, // 多個範圍
+		{"20000-30000, 35000", false},      // 範圍與單端口混合
+		{"20000-30000,25000-35000", true},  // 範圍重疊
+		{"20000-30000,", false},            // 忽略多餘的逗號
+		{",", true},                        // 無任何範圍
 	}
 
 	for _, tt := range tests {
@@ -152,6 +154,88 @@ func TestPortHoppingValidation(t *testing.T) {
 			t.Errorf("ValidatePortHopping(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
 		}
 	}
+
+	// 跳躍間隔
+	intervals := map[string]bool{"30s": false, "1m": false, "3s": true, "abc": true}
+	for in, wantErr := range intervals {
+		h := Hysteria2Config{HopInterval: in}
+		if err := h.ValidatePortHopping(); (err != nil) != wantErr {
+			t.Errorf("HopInterval %q error = %v, wantErr %v", in, err, wantErr)
+		}
+	}
+}
+
+// TestPortRotation 測試端口輪換策略的驗證與到期判斷
+func TestPortRotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     PortRotationConfig
+		wantErr bool
+	}{
+		{"未啟用", PortRotationConfig{}, false},
+		{"定時輪換", PortRotationConfig{Enabled: true, Interval: "72h"}, false},
+		{"探測缺少地址", PortRotationConfig{Enabled: true, Probe: true}, true},
+		{"無觸發條件", PortRotationConfig{Enabled: true}, true},
+		{"間隔過短", PortRotationConfig{Enabled: true, Interval: "10m"}, true},
+		{"間隔格式錯誤", PortRotationConfig{Enabled: true, Interval: "3d"}, true},
+		{"探測地址無效", PortRotationConfig{Enabled: true, Probe: true, ProbeURL: "ftp://x"}, true},
+		{"外部探測", PortRotationConfig{Enabled: true, Probe: true, ProbeURL: "https://probe.example.com/?p={port}"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	r := PortRotationConfig{Enabled: true, Interval: "24h"}
+	if r.Due(now) {
+		t.Error("從未輪換過時不應到期")
+	}
+	r.LastRotated = now.Add(-23 * time.Hour)
+	if r.Due(now) {
+		t.Error("未滿間隔時不應到期")
+	}
+	r.LastRotated = now.Add(-24 * time.Hour)
+	if !r.Due(now) {
+		t.Error("滿間隔時應到期")
+	}
+
+	if !r.Includes(3) {
+		t.Error("協議列表為空時應包含所有協議")
+	}
+	r.Protocols = []int{1, 2}
+	if r.Includes(3) || !r.Includes(2) {
+		t.Error("協議列表過濾錯誤")
+	}
+}
+
+// TestParsePortRanges 測試跳躍端口列表的解析與格式化
+func TestParsePortRanges(t *testing.T) {
+	ranges, err := ParsePortRanges(" 40000-45000 ,20000-30000,35000")
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	want := []PortRange{{20000, 30000}, {35000, 35000}, {40000, 45000}}
+	if len(ranges) != len(want) {
+		t.Fatalf("預期 %v, 得到 %v", want, ranges)
+	}
+	for i := range want {
+		if ranges[i] != want[i] {
+			t.Errorf("第 %d 個範圍: 預期 %v, 得到 %v", i, want[i], ranges[i])
+		}
+	}
+	if s := FormatPortRanges(ranges); s != "20000-30000,35000,40000-45000" {
+		t.Errorf("格式化錯誤: %s", s)
+	}
+
+	h := Hysteria2Config{PortHopping: "20000-30000", HopInterval: "45s"}
+	if got := h.HoppingRanges(); len(got) != 1 || !got[0].Contains(25000) || got[0].Contains(30001) {
+		t.Errorf("HoppingRanges 錯誤: %v", got)
+	}
+	if d := h.HopIntervalDuration(); d.Seconds() != 45 {
+		t.Errorf("HopIntervalDuration 錯誤: %v", d)
+	}
 }
 
 // TestGetACMEURL 測試 ACME URL 獲取邏輯
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 偽裝網站模式
+const (
+	DecoyModeFile   = "file"   // 靜態站點目錄
+	DecoyModeProxy  = "proxy"  // 反向代理上游網站
+	DecoyModeString = "string" // 固定響應內容
+)
+
+// 本機偽裝站點服務的監聽地址 (prism decoy serve)
+// Trojan 回落轉發的是明文 HTTP，內置站點經此服務提供
+const (
+	DecoySiteHost = "127.0.0.1"
+	DecoySitePort = 18780
+)
+
+// DecoyConfig 偽裝網站配置，主動探測或認證失敗時返回的內容
+// Hysteria2 對應 masquerade，Trojan 對應 fallback (支持內置站點與 http:// 上游)
+type DecoyConfig struct {
+	Mode       string `yaml:"mode,omitempty"`        // file / proxy / string，留空關閉
+	Directory  string `yaml:"directory,omitempty"`   // file: 站點目錄，留空使用內置站點
+	URL        string `yaml:"url,omitempty"`         // proxy: 上游地址
+	StatusCode int    `yaml:"status_code,omitempty"` // string: 狀態碼，留空為 200
+	Content    string `yaml:"content,omitempty"`     // string: 響應內容
+}
+
+// Enabled 是否配置了偽裝網站
+func (d DecoyConfig) Enabled() bool {
+	return d.Mode != ""
+}
+
+// UsesBundledSite 是否使用數據目錄下的內置站點
+func (d DecoyConfig) UsesBundledSite() bool {
+	return d.Mode == DecoyModeFile && d.Directory == ""
+}
+
+// GetStatusCode 返回 string 模式的狀態碼，留空為 200
+func (d DecoyConfig) GetStatusCode() int {
+	if d.StatusCode == 0 {
+		return 200
+	}
+	return d.StatusCode
+}
+
+// Summary 返回簡短描述，用於 TUI 顯示
+func (d DecoyConfig) Summary() string {
+	switch d.Mode {
+	case DecoyModeFile:
+		if d.Directory == "" {
+			return "內置站點"
+		}
+		return "站點 " + d.Directory
+	case DecoyModeProxy:
+		return "代理 " + d.URL
+	case DecoyModeString:
+		return fmt.Sprintf("固定響應 %d", d.GetStatusCode())
+	}
+	return "關閉"
+}
+
+// FallbackAddress 返回 Trojan 回落目標的主機與端口
+// 回落轉發的是解密後的明文流量：內置站點由本機偽裝站點服務提供，proxy 上游只能是 http://
+func (d DecoyConfig) FallbackAddress() (string, int, error) {
+	if d.Mode == DecoyModeFile {
+		if !d.UsesBundledSite() {
+			return "", 0, fmt.Errorf("回落僅支持內置站點 (file 留空目錄)，自定義目錄請使用 Nginx 等服務並設置 proxy 上游")
+		}
+		return DecoySiteHost, DecoySitePort, nil
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil || u.Scheme != "http" || u.Hostname() == "" {
+		return "", 0, fmt.Errorf("回落上游必須為 http:// 地址: %s", d.URL)
+	}
+	port := 80
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return "", 0, fmt.Errorf("回落上游端口無效: %s", p)
+		}
+	}
+	return u.Hostname(), port, nil
+}
+
+// validate 驗證偽裝網站配置，modes 為協議支持的模式
+func (d DecoyConfig) validate(modes ...string) error {
+	if !d.Enabled() {
+		return nil
+	}
+	if !containsString(modes, d.Mode) {
+		return fmt.Errorf("不支持的偽裝模式 %q (可選: %s)", d.Mode, strings.Join(modes, ", "))
+	}
+	switch d.Mode {
+	case DecoyModeProxy:
+		u, err := url.Parse(d.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("偽裝上游必須為 http:// 或 https:// 地址: %q", d.URL)
+		}
+	case DecoyModeString:
+		if d.StatusCode != 0 && (d.StatusCode < 100 || d.StatusCode > 599) {
+			return fmt.Errorf("無效的狀態碼: %d", d.StatusCode)
+		}
+	}
+	return nil
+}
+
+// ParseDecoy 解析偽裝網站設置
+// 格式: off | file [目錄] | proxy <URL> | string [狀態碼] [內容]
+func ParseDecoy(value string) (DecoyConfig, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || strings.EqualFold(fields[0], "off") {
+		return DecoyConfig{}, nil
+	}
+
+	mode := strings.ToLower(fields[0])
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), fields[0]))
+	switch mode {
+	case DecoyModeFile:
+		return DecoyConfig{Mode: mode, Directory: rest}, nil
+	case DecoyModeProxy:
+		if rest == "" {
+			return DecoyConfig{}, fmt.Errorf("格式錯誤，應為: proxy <URL>")
+		}
+		return DecoyConfig{Mode: mode, URL: rest}, nil
+	case DecoyModeString:
+		d := DecoyConfig{Mode: mode, Content: rest}
+		if len(fields) > 1 {
+			if code, err := strconv.Atoi(fields[1]); err == nil {
+				d.StatusCode = code
+				d.Content = strings.TrimSpace(strings.TrimPrefix(rest, fields[1]))
+			}
+		}
+		return d, nil
+	}
+	return DecoyConfig{}, fmt.Errorf("未知的偽裝模式: %s (應為 off/file/proxy/string)", fields[0])
+}
+
+// UsesBundledDecoy 是否有已啟用的實例使用內置偽裝站點
+func (p *ProtocolsConfig) UsesBundledDecoy() bool {
+	for _, inst := range p.InstancesOf(ProtocolTypeHysteria2) {
+		if inst.Hysteria2.Enabled && inst.Hysteria2.Decoy.UsesBundledSite() {
+			return true
+		}
+	}
+	return p.NeedsDecoyServer()
+}
+
+// NeedsDecoyServer 是否有已啟用的 Trojan 實例回落到內置站點，需要運行本機偽裝站點服務
+// Hysteria2 由 sing-box 直接讀取站點目錄，不需要該服務
+func (p *ProtocolsConfig) NeedsDecoyServer() bool {
+	for _, inst := range p.InstancesOf(ProtocolTypeCDN) {
+		c := inst.CDN
+		if c.Enabled && c.Proxy == CDNProxyTrojan && c.Decoy.UsesBundledSite() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDecoy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    DecoyConfig
+		wantErr bool
+	}{
+		{input: "", want: DecoyConfig{}},
+		{input: "off", want: DecoyConfig{}},
+		{input: "file", want: DecoyConfig{Mode: DecoyModeFile}},
+		{input: "FILE /var/www/site", want: DecoyConfig{Mode: DecoyModeFile, Directory: "/var/www/site"}},
+		{input: "proxy https://example.com", want: DecoyConfig{Mode: DecoyModeProxy, URL: "https://example.com"}},
+		{input: "string 404 Not Found", want: DecoyConfig{Mode: DecoyModeString, StatusCode: 404, Content: "Not Found"}},
+		{input: "string hello world", want: DecoyConfig{Mode: DecoyModeString, Content: "hello world"}},
+		{input: "proxy", wantErr: true},
+		{input: "redirect https://example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDecoy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDecoy(%q) 錯誤 = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseDecoy(%q) = %+v, 期望 %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestDecoyOptions Hysteria2 支持全部模式，CDN 僅 Trojan 可回落到內置站點或 http:// 上游
+func TestDecoyOptions(t *testing.T) {
+	cfg := DefaultConfig()
+	hy2 := cfg.Protocols.Instance("hysteria2-in")
+	cdn := cfg.Protocols.Instance("cdn-in")
+
+	if err := hy2.SetOption(OptionDecoy, "file"); err != nil || !hy2.Hysteria2.Decoy.UsesBundledSite() {
+		t.Fatalf("設置內置站點失敗: %v", err)
+	}
+	hy2.Hysteria2.Enabled = true
+	if !cfg.Protocols.UsesBundledDecoy() {
+		t.Error("啟用的 Hysteria2 使用內置站點時應返回 true")
+	}
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Fatalf("配置應有效: %v", err)
+	}
+
+	hy2.Hysteria2.Obfs = "secret"
+	if err := cfg.Protocols.ValidateInstances(); err == nil || !strings.Contains(err.Error(), "混淆") {
+		t.Errorf("開啟混淆時應拒絕偽裝網站: %v", err)
+	}
+	hy2.Hysteria2.Obfs = ""
+
+	if err := hy2.SetOption(OptionDecoy, "string 700 oops"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err == nil {
+		t.Error("無效狀態碼應返回錯誤")
+	}
+	_ = hy2.SetOption(OptionDecoy, "off")
+
+	// CDN: VLESS 不支持回落，Trojan 只接受 http:// 上游
+	if err := cdn.SetOption(OptionDecoy, "proxy http://127.0.0.1:8080"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err == nil {
+		t.Error("VLESS 不應支持回落")
+	}
+	cdn.CDN.Proxy = CDNProxyTrojan
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Errorf("Trojan 回落到 http 上游應有效: %v", err)
+	}
+	host, port, _ := cdn.CDN.Decoy.FallbackAddress()
+	if host != "127.0.0.1" || port != 8080 {
+		t.Errorf("回落地址解析錯誤: %s:%d", host, port)
+	}
+	_ = cdn.SetOption(OptionDecoy, "proxy https://example.com")
+	if err := cfg.Protocols.ValidateInstances(); err == nil {
+		t.Error("Trojan 回落不應接受 https 上游")
+	}
+	if cfg.Protocols.NeedsDecoyServer() {
+		t.Error("回落到上游時不需要本機偽裝站點服務")
+	}
+
+	// 內置站點經本機偽裝站點服務回落
+	_ = cdn.SetOption(OptionDecoy, "file")
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Errorf("Trojan 回落到內置站點應有效: %v", err)
+	}
+	host, port, _ = cdn.CDN.Decoy.FallbackAddress()
+	if host != DecoySiteHost || port != DecoySitePort {
+		t.Errorf("內置站點回落地址錯誤: %s:%d", host, port)
+	}
+	cdn.CDN.Enabled = true
+	if !cfg.Protocols.NeedsDecoyServer() || !cfg.Protocols.UsesBundledDecoy() {
+		t.Error("Trojan 回落到內置站點時應運行本機偽裝站點服務")
+	}
+	_ = cdn.SetOption(OptionDecoy, "file /var/www/site")
+	if err := cfg.Protocols.ValidateInstances(); err == nil {
+		t.Error("Trojan 回落不應接受自定義目錄")
+	}
+	if err := cfg.Protocols.Instance("tuic-in").SetOption(OptionDecoy, "file"); err == nil {
+		t.Error("TUIC 不支持偽裝網站")
+	}
+	if err := cfg.Protocols.Instance("naive-in").SetOption(OptionDecoy, "file"); err == nil || !strings.Contains(err.Error(), "NaiveProxy") {
+		t.Errorf("NaiveProxy 不支持回落，應明確提示: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// InboundFirewall 入站防火牆策略
+// 作用於該協議的監聽端口，未設置時端口對所有來源開放
+type InboundFirewall struct {
+	Allow          []string `yaml:"allow,omitempty"`           // 允許的來源 (IP / CIDR)，設置後僅放行列表內來源
+	Deny           []string `yaml:"deny,omitempty"`            // 拒絕的來源 (IP / CIDR)
+	RateLimit      int      `yaml:"rate_limit,omitempty"`      // 每個來源每分鐘新連接數上限，0 為不限制
+	RateBurst      int      `yaml:"rate_burst,omitempty"`      // 突發連接數，0 使用默認值
+	BlockCountries []string `yaml:"block_countries,omitempty"` // 屏蔽的國家代碼 (ISO 3166，如 CN、RU)
+}
+
+// DefaultRateBurst 未設置突發值時使用的默認值
+const DefaultRateBurst = 10
+
+// IsEmpty 是否未設置任何策略
+func (f *InboundFirewall) IsEmpty() bool {
+	return len(f.Allow) == 0 && len(f.Deny) == 0 && f.RateLimit == 0 && len(f.BlockCountries) == 0
+}
+
+// Burst 返回突發連接數
+func (f *InboundFirewall) Burst() int {
+	if f.RateBurst > 0 {
+		return f.RateBurst
+	}
+	return DefaultRateBurst
+}
+
+// Validate 驗證入站防火牆策略
+func (f *InboundFirewall) Validate() error {
+	for _, s := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if _, err := NormalizeCIDR(s); err != nil {
+			return err
+		}
+	}
+	if f.RateLimit < 0 || f.RateBurst < 0 {
+		return fmt.Errorf("連接速率限制不能為負數")
+	}
+	for _, c := range f.BlockCountries {
+		if len(c) != 2 || strings.Trim(strings.ToUpper(c), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return fmt.Errorf("無效的國家代碼: %s", c)
+		}
+	}
+	return nil
+}
+
+// NormalizeCIDR 將 IP 或 CIDR 規範化為網段表示，單個 IP 轉為 /32 或 /128
+func NormalizeCIDR(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return "", fmt.Errorf("無效的 IP 或網段: %s", s)
+	}
+	return network.String(), nil
+}
+
+// FirewallTarget 入站防火牆策略及其作用的監聽端口
+type FirewallTarget struct {
+	Name     string // 協議類型 (主實例) 或實例標籤
+	Network  string // tcp / udp
+	Port     int
+	Firewall InboundFirewall
+}
+
+// FirewallTargets 返回已啟用且設置了防火牆策略的協議，按名稱排序
+// 端口跳躍的流量在 NAT 重定向後到達監聽端口，因此只需作用於監聽端口
+func (p *ProtocolsConfig) FirewallTargets() []FirewallTarget {
+	var targets []FirewallTarget
+	for _, sec := range p.Sections() {
+		if !*sec.Enabled || *sec.Port <= 0 || sec.Firewall.IsEmpty() {
+			continue
+		}
+		targets = append(targets, FirewallTarget{Name: sec.Name(), Network: sec.Network, Port: *sec.Port, Firewall: *sec.Firewall})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+	return targets
+}
+
+// ValidateFirewall 驗證所有協議的入站防火牆策略
+func (p *ProtocolsConfig) ValidateFirewall() error {
+	for _, sec := range p.Sections() {
+		if err := sec.Firewall.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", sec.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 可屏蔽的流量類別及其對應的匹配條件
+// 私有地址使用 ip_is_private (sing-geoip 沒有 private 規則集)，bittorrent 依賴協議嗅探
+var blockCategories = map[string]RoutingRule{
+	"ads":        {RuleSet: []string{"geosite-category-ads-all"}},
+	"porn":       {RuleSet: []string{"geosite-category-porn"}},
+	"gambling":   {RuleSet: []string{"geosite-category-gambling"}},
+	"cn":         {RuleSet: []string{"geosite-cn", "geoip-cn"}},
+	"private":    {IPIsPrivate: true},
+	"bittorrent": {Protocol: []string{"bittorrent"}},
+}
+
+// 入站 IPv6 偏好 (sing-box 域名解析策略)
+var ipv6Preferences = map[string]bool{
+	"prefer_ipv4": true, "prefer_ipv6": true, "ipv4_only": true, "ipv6_only": true,
+}
+
+// InboundRouting 入站路由策略
+// 僅作用於從該協議入站的流量，未設置的字段沿用全局路由
+type InboundRouting struct {
+	Outbound string   `yaml:"outbound,omitempty"` // 默認出站
+	Block    []string `yaml:"block,omitempty"`    // 屏蔽類別
+	IPv6     string   `yaml:"ipv6,omitempty"`     // IPv6 偏好
+}
+
+// BlockCategories 返回所有可屏蔽的類別名稱
+func BlockCategories() []string {
+	names := make([]string, 0, len(blockCategories))
+	for name := range blockCategories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BlockCategoryRule 返回屏蔽類別對應的拒絕規則
+func BlockCategoryRule(category string) (RoutingRule, bool) {
+	matcher, ok := blockCategories[category]
+	if !ok {
+		return RoutingRule{}, false
+	}
+	rule := RoutingRule{
+		Name:        "block-" + category,
+		RuleSet:     append([]string{}, matcher.RuleSet...),
+		IPIsPrivate: matcher.IPIsPrivate,
+		Protocol:    append([]string{}, matcher.Protocol...),
+		Action:      RuleActionReject,
+	}
+	return rule, true
+}
+
+// IsEmpty 是否未設置任何策略
+func (p *InboundRouting) IsEmpty() bool {
+	return p.Outbound == "" && len(p.Block) == 0 && p.IPv6 == ""
+}
+
+// Validate 驗證入站路由策略
+func (p *InboundRouting) Validate(r *RoutingConfig) error {
+	if p.Outbound != "" && !r.IsRouteTarget(p.Outbound) {
+		return fmt.Errorf("入站默認出站不存在: %s", p.Outbound)
+	}
+	for _, c := range p.Block {
+		if _, ok := blockCategories[c]; !ok {
+			return fmt.Errorf("未知的屏蔽類別: %s (可選: %s)", c, strings.Join(BlockCategories(), ", "))
+		}
+	}
+	if p.IPv6 != "" && !ipv6Preferences[p.IPv6] {
+		return fmt.Errorf("無效的 IPv6 偏好: %s", p.IPv6)
+	}
+	return nil
+}
+
+// ValidateRouting 驗證所有協議的入站路由策略
+func (p *ProtocolsConfig) ValidateRouting(r *RoutingConfig) error {
+	for _, sec := range p.Sections() {
+		if err := sec.Routing.Validate(r); err != nil {
+			return fmt.Errorf("%s: %w", sec.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/pkg/crypto"
+)
+
+// 自定義出站類型
+const (
+	OutboundTypeWireGuard   = "wireguard"
+	OutboundTypeHTTP        = "http"
+	OutboundTypeShadowsocks = "shadowsocks"
+	OutboundTypeVLESS       = "vless"
+	OutboundTypeTrojan      = "trojan"
+	OutboundTypeSOCKS       = "socks"
+)
+
+// 內置出站標籤，自定義出站不可佔用
+var reservedOutboundTags = map[string]bool{
+	"direct":   true,
+	"block":    true,
+	"dns-out":  true,
+	"warp-out": true,
+	"ipv6-out": true,
+}
+
+var (
+	outboundTagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,31}$`)
+	ruleSetTagPattern  = regexp.MustCompile(`^(geosite|geoip)-[a-z0-9!@._-]+$`)
+)
+
+// 支持的 Shadowsocks 加密方式
+var shadowsocksMethods = map[string]bool{
+	"2022-blake3-aes-128-gcm":       true,
+	"2022-blake3-aes-256-gcm":       true,
+	"2022-blake3-chacha20-poly1305": true,
+	"aes-128-gcm":                   true,
+	"aes-256-gcm":                   true,
+	"chacha20-ietf-poly1305":        true,
+	"xchacha20-ietf-poly1305":       true,
+}
+
+// CustomOutbound 自定義上游出站 (鏈式代理)
+type CustomOutbound struct {
+	Tag    string `yaml:"tag"`
+	Type   string `yaml:"type"` // wireguard / http / socks / shadowsocks / vless / trojan
+	Server string `yaml:"server"`
+	Port   int    `yaml:"port"`
+	Detour string `yaml:"detour,omitempty"` // 經由另一個出站連接上游
+
+	Username string `yaml:"username,omitempty"` // http / socks
+	Password string `yaml:"password,omitempty"` // http / socks / shadowsocks / trojan
+	Method   string `yaml:"method,omitempty"`   // shadowsocks
+	UUID     string `yaml:"uuid,omitempty"`     // vless
+	Flow     string `yaml:"flow,omitempty"`     // vless
+
+	TLS OutboundTLSConfig `yaml:"tls,omitempty"`
+
+	// WireGuard
+	PrivateKey    string   `yaml:"private_key,omitempty"`
+	PeerPublicKey string   `yaml:"peer_public_key,omitempty"`
+	PreSharedKey  string   `yaml:"pre_shared_key,omitempty"`
+	LocalAddress  []string `yaml:"local_address,omitempty"`
+	Reserved      []int    `yaml:"reserved,omitempty"`
+	MTU           int      `yaml:"mtu,omitempty"`
+
+	// 分流：匹配的流量走此出站
+	Domains  []string `yaml:"domains,omitempty"`
+	RuleSets []string `yaml:"rule_sets,omitempty"` // 例如 geosite-netflix、geoip-jp
+}
+
+// OutboundTLSConfig 上游 TLS / Reality 配置
+type OutboundTLSConfig struct {
+	Enabled          bool   `yaml:"enabled,omitempty"`
+	ServerName       string `yaml:"server_name,omitempty"`
+	Insecure         bool   `yaml:"insecure,omitempty"`
+	Fingerprint      string `yaml:"fingerprint,omitempty"` // uTLS 指紋
+	RealityPublicKey string `yaml:"reality_public_key,omitempty"`
+	RealityShortID   string `yaml:"reality_short_id,omitempty"`
+}
+
+// Address 返回 host:port
+func (o *CustomOutbound) Address() string {
+	return net.JoinHostPort(o.Server, strconv.Itoa(o.Port))
+}
+
+// Validate 驗證單個出站的字段
+func (o *CustomOutbound) Validate() error {
+	if !outboundTagPattern.MatchString(o.Tag) {
+		return fmt.Errorf("出站標籤 %q 無效 (僅限字母、數字、_.-，最長 32 字符)", o.Tag)
+	}
+	if reservedOutboundTags[o.Tag] {
+		return fmt.Errorf("出站標籤 %q 為內置保留標籤", o.Tag)
+	}
+	if o.Server == "" {
+		return fmt.Errorf("出站 %s 缺少服務器地址", o.Tag)
+	}
+	if o.Port < 1 || o.Port > 65535 {
+		return fmt.Errorf("出站 %s 端口無效: %d", o.Tag, o.Port)
+	}
+
+	switch o.Type {
+	case OutboundTypeHTTP, OutboundTypeSOCKS:
+	case OutboundTypeShadowsocks:
+		if !shadowsocksMethods[o.Method] {
+			return fmt.Errorf("出站 %s 不支持的加密方式: %s", o.Tag, o.Method)
+		}
+		if o.Password == "" {
+			return fmt.Errorf("出站 %s 缺少密碼", o.Tag)
+		}
+	case OutboundTypeVLESS:
+		if o.UUID == "" {
+			return fmt.Errorf("出站 %s 缺少 UUID", o.Tag)
+		}
+	case OutboundTypeTrojan:
+		if o.Password == "" {
+			return fmt.Errorf("出站 %s 缺少密碼", o.Tag)
+		}
+	case OutboundTypeWireGuard:
+		if o.PrivateKey == "" || o.PeerPublicKey == "" {
+			return fmt.Errorf("出站 %s 缺少 WireGuard 密鑰", o.Tag)
+		}
+		if len(o.LocalAddress) == 0 {
+			return fmt.Errorf("出站 %s 缺少本地地址", o.Tag)
+		}
+		for _, addr := range o.LocalAddress {
+			if _, _, err := net.ParseCIDR(addr); err != nil {
+				return fmt.Errorf("出站 %s 本地地址無效: %s", o.Tag, addr)
+			}
+		}
+		if len(o.Reserved) != 0 && len(o.Reserved) != 3 {
+			return fmt.Errorf("出站 %s reserved 應為 3 個字節", o.Tag)
+		}
+	default:
+		return fmt.Errorf("出站 %s 類型不支持: %s", o.Tag, o.Type)
+	}
+
+	for _, rs := range o.RuleSets {
+		if !ruleSetTagPattern.MatchString(rs) {
+			return fmt.Errorf("出站 %s 規則集名稱無效: %s", o.Tag, rs)
+		}
+	}
+
+	return nil
+}
+
+// FindOutbound 按標籤查找自定義出站
+func (r *RoutingConfig) FindOutbound(tag string) *CustomOutbound {
+	for i := range r.Outbounds {
+		if r.Outbounds[i].Tag == tag {
+			return &r.Outbounds[i]
+		}
+	}
+	return nil
+}
+
+// ValidateOutbounds 驗證自定義出站：標籤唯一、前置代理可用且無循環
+func (r *RoutingConfig) ValidateOutbounds() error {
+	seen := make(map[string]bool)
+	for i := range r.Outbounds {
+		o := &r.Outbounds[i]
+		if err := o.Validate(); err != nil {
+			return err
+		}
+		if seen[o.Tag] {
+			return fmt.Errorf("出站標籤重複: %s", o.Tag)
+		}
+		seen[o.Tag] = true
+	}
+
+	for i := range r.Outbounds {
+		o := &r.Outbounds[i]
+		if o.Detour == "" {
+			continue
+		}
+		if o.Detour == o.Tag {
+			return fmt.Errorf("出站 %s 不能以自身作為前置代理", o.Tag)
+		}
+		// 前置代理必須是實際會生成的出站，WARP / IPv6 出口未啟用時不會生成
+		if !r.IsOutboundAvailable(o.Detour) {
+			if reservedOutboundTags[o.Detour] {
+				return fmt.Errorf("出站 %s 的前置代理 %s 未啟用", o.Tag, o.Detour)
+			}
+			return fmt.Errorf("出站 %s 的前置代理 %s 不存在", o.Tag, o.Detour)
+		}
+
+		// 沿 detour 鏈檢查循環
+		visited := map[string]bool{o.Tag: true}
+		for next := r.FindOutbound(o.Detour); next != nil && next.Detour != ""; next = r.FindOutbound(next.Detour) {
+			if visited[next.Tag] {
+				return fmt.Errorf("出站 %s 的前置代理鏈存在循環", o.Tag)
+			}
+			visited[next.Tag] = true
+		}
+	}
+
+	return nil
+}
+
+// NormalizeRuleSetTag 規範化規則集名稱，未帶前綴時默認為 geosite
+func NormalizeRuleSetTag(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.HasPrefix(name, "geosite-") || strings.HasPrefix(name, "geoip-") {
+		return name
+	}
+	return "geosite-" + name
+}
+
+// ParseOutboundURI 從分享鏈接解析出站
+// 支持 ss://、vless://、trojan://、http(s)://、socks5://、wireguard:// (wg://)，鏈接片段 (#) 作為標籤
+func ParseOutboundURI(raw string) (*CustomOutbound, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("鏈接格式錯誤: %w", err)
+	}
+
+	out := &CustomOutbound{Tag: u.Fragment, Server: u.Hostname()}
+	if portStr := u.Port(); portStr != "" {
+		if out.Port, err = strconv.Atoi(portStr); err != nil {
+			return nil, fmt.Errorf("端口無效: %s", portStr)
+		}
+	}
+
+	q := u.Query()
+	out.Detour = q.Get("detour")
+
+	switch strings.ToLower(u.Scheme) {
+	case "ss":
+		out.Type = OutboundTypeShadowsocks
+		if err := parseShadowsocksUserInfo(u, out); err != nil {
+			return nil, err
+		}
+	case "vless":
+		out.Type = OutboundTypeVLESS
+		out.UUID = u.User.Username()
+		out.Flow = q.Get("flow")
+		parseOutboundTLS(q, out)
+	case "trojan":
+		out.Type = OutboundTypeTrojan
+		out.Password = u.User.Username()
+		parseOutboundTLS(q, out)
+		// trojan 默認使用 TLS
+		out.TLS.Enabled = q.Get("security") != "none"
+	case "http", "https":
+		out.Type = OutboundTypeHTTP
+		if u.User != nil {
+			out.Username = u.User.Username()
+			out.Password, _ = u.User.Password()
+		}
+		if u.Scheme == "https" {
+			out.TLS.Enabled = true
+			out.TLS.ServerName = q.Get("sni")
+			out.TLS.Insecure = q.Get("allowInsecure") == "1"
+		}
+	case "socks", "socks5":
+		out.Type = OutboundTypeSOCKS
+		if u.User != nil {
+			out.Username = u.User.Username()
+			out.Password, _ = u.User.Password()
+		}
+	case "wireguard", "wg":
+		out.Type = OutboundTypeWireGuard
+		out.PrivateKey = u.User.Username()
+		out.PeerPublicKey = q.Get("publickey")
+		out.PreSharedKey = q.Get("presharedkey")
+		for _, addr := range strings.Split(q.Get("address"), ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				out.LocalAddress = append(out.LocalAddress, normalizeLocalAddress(addr))
+			}
+		}
+		if reserved := q.Get("reserved"); reserved != "" {
+			for _, p := range strings.Split(reserved, ",") {
+				v, err := strconv.Atoi(strings.TrimSpace(p))
+				if err != nil || v < 0 || v > 255 {
+					return nil, fmt.Errorf("reserved 格式錯誤: %s", reserved)
+				}
+				out.Reserved = append(out.Reserved, v)
+			}
+		}
+		if mtu := q.Get("mtu"); mtu != "" {
+			out.MTU, _ = strconv.Atoi(mtu)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的鏈接類型: %s", u.Scheme)
+	}
+
+	if out.Tag == "" {
+		out.Tag = fmt.Sprintf("%s-%s", out.Type, out.Server)
+	}
+
+	if err := out.Validate(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseShadowsocksUserInfo 兼容 SIP002 (base64 用戶信息) 與明文 method:password
+func parseShadowsocksUserInfo(u *url.URL, out *CustomOutbound) error {
+	if u.User == nil {
+		return fmt.Errorf("缺少加密方式與密碼")
+	}
+
+	if password, ok := u.User.Password(); ok {
+		out.Method = u.User.Username()
+		out.Password = password
+		return nil
+	}
+
+	info := u.User.Username()
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(info, "="))
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(info, "="))
+		if err != nil {
+			return fmt.Errorf("無法解析用戶信息: %w", err)
+		}
+	}
+
+	method, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return fmt.Errorf("用戶信息格式錯誤，應為 method:password")
+	}
+	out.Method = method
+	out.Password = password
+	return nil
+}
+
+// parseOutboundTLS 解析分享鏈接中的 TLS / Reality 參數
+func parseOutboundTLS(q url.Values, out *CustomOutbound) {
+	security := q.Get("security")
+	out.TLS.Enabled = security == "tls" || security == "reality"
+	out.TLS.ServerName = q.Get("sni")
+	out.TLS.Fingerprint = q.Get("fp")
+	out.TLS.Insecure = q.Get("allowInsecure") == "1"
+	if security == "reality" {
+		out.TLS.RealityPublicKey = q.Get("pbk")
+		out.TLS.RealityShortID = q.Get("sid")
+	}
+}
+
+// normalizeLocalAddress 單個 IP 補全前綴長度
+func normalizeLocalAddress(addr string) string {
+	if strings.Contains(addr, "/") {
+		return addr
+	}
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		return addr + "/128"
+	}
+	return addr + "/32"
+}
+
+// EncryptSensitiveFields 加密出站密碼與私鑰
+func (o *CustomOutbound) EncryptSensitiveFields(encryptor *crypto.Encryptor) error {
+	if encryptor == nil {
+		return nil
+	}
+
+	for _, field := range []*string{&o.Password, &o.PrivateKey} {
+		if *field == "" || crypto.IsEncrypted(*field) {
+			continue
+		}
+		encrypted, err := encryptor.Encrypt(*field)
+		if err != nil {
+			return fmt.Errorf("加密出站 %s 敏感字段失敗: %w", o.Tag, err)
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
+// DecryptSensitiveFields 解密出站密碼與私鑰
+func (o *CustomOutbound) DecryptSensitiveFields(encryptor *crypto.Encryptor) error {
+	if encryptor == nil {
+		return nil
+	}
+
+	for _, field := range []*string{&o.Password, &o.PrivateKey} {
+		if !crypto.IsEncrypted(*field) {
+			continue
+		}
+		decrypted, err := encryptor.Decrypt(*field)
+		if err != nil {
+			return fmt.Errorf("解密出站 %s 敏感字段失敗: %w", o.Tag, err)
+		}
+		*field = decrypted
+	}
+	return nil
+}
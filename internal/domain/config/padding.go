@@ -0,0 +1,386 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnyTLS 填充模式
+const (
+	PaddingModeBalanced   = "balanced"
+	PaddingModeMinimal    = "minimal"
+	PaddingModeHighResist = "high_resist"
+	PaddingModeVideo      = "video"
+	PaddingModeOfficial   = "official"
+	PaddingModeCustom     = "custom" // 使用 padding_scheme 中的自定義方案
+)
+
+// 填充方案的取值範圍
+const (
+	PaddingMaxPackets = 64    // stop 上限，更大的值只會增加握手後的開銷
+	PaddingMaxSize    = 65535 // 單個片段的最大字節數
+)
+
+// paddingPresets 內置填充方案
+var paddingPresets = map[string][]string{
+	PaddingModeBalanced: {
+		"stop=6",
+		"0=10-60",
+		"1=30-150",
+		"2=200-500,c,400-800",
+		"3=100-300",
+		"4=500-1200",
+	},
+	PaddingModeMinimal: {
+		"stop=4",
+		"0=15-35",
+		"1=20-100",
+		"2=100-200",
+	},
+	PaddingModeHighResist: {
+		"stop=10",
+		"0=50-100",
+		"1=500-800",
+		"2=c,800-1200",
+		"3=50-50",
+		"4=c,1000-1500",
+		"5=100-600",
+	},
+	PaddingModeVideo: {
+		"stop=9",
+		"0=40-80",
+		"1=600-900",
+		"2=c,900-1400",
+		"3=80-150",
+		"4=c,800-1200",
+		"5=200-400",
+		"6=150-600",
+	},
+	// 官方默認方案
+	PaddingModeOfficial: {
+		"stop=8",
+		"0=30-30",
+		"1=100-400",
+		"2=400-500,c,500-1000,c,500-1000,c,500-1000,c,500-1000",
+		"3=9-9,500-1000",
+		"4=500-1000",
+		"5=500-1000",
+		"6=500-1000",
+		"7=500-1000",
+	},
+}
+
+// PaddingPreset 返回內置填充方案，未知模式返回官方默認方案
+func PaddingPreset(mode string) []string {
+	scheme, ok := paddingPresets[mode]
+	if !ok {
+		scheme = paddingPresets[PaddingModeOfficial]
+	}
+	return append([]string(nil), scheme...)
+}
+
+// PaddingProfile 用戶保存的命名填充方案
+type PaddingProfile struct {
+	Name   string   `yaml:"name"`
+	Scheme []string `yaml:"scheme"`
+}
+
+// PaddingSegment 填充片段，Check 為 c 標記: 用戶數據已發完時停止後續填充
+type PaddingSegment struct {
+	Min   int
+	Max   int
+	Check bool
+}
+
+// PaddingScheme 解析後的填充方案
+type PaddingScheme struct {
+	Stop    int                      // 對前 Stop 個包進行填充
+	Packets map[int][]PaddingSegment // 包序號 -> 片段
+}
+
+// ParsePaddingScheme 解析 stop=N / 序號=a-b,c,... 格式的填充方案，錯誤信息帶行號
+func ParsePaddingScheme(lines []string) (*PaddingScheme, error) {
+	s := &PaddingScheme{Packets: make(map[int][]PaddingSegment)}
+	hasStop := false
+
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("第 %d 行: 缺少 '=': %s", i+1, line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if key == "stop" {
+			if hasStop {
+				return nil, fmt.Errorf("第 %d 行: stop 重複定義", i+1)
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > PaddingMaxPackets {
+				return nil, fmt.Errorf("第 %d 行: stop 應為 1-%d 的整數: %s", i+1, PaddingMaxPackets, value)
+			}
+			s.Stop, hasStop = n, true
+			continue
+		}
+
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("第 %d 行: 無效的包序號 %q (應為 stop 或非負整數)", i+1, key)
+		}
+		if _, dup := s.Packets[index]; dup {
+			return nil, fmt.Errorf("第 %d 行: 包序號 %d 重複定義", i+1, index)
+		}
+		segments, err := parsePaddingSegments(value)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", i+1, err)
+		}
+		s.Packets[index] = segments
+	}
+
+	if !hasStop {
+		return nil, fmt.Errorf("缺少 stop=N 行")
+	}
+	for index := range s.Packets {
+		if index >= s.Stop {
+			return nil, fmt.Errorf("包序號 %d 超出 stop=%d，不會生效", index, s.Stop)
+		}
+	}
+	return s, nil
+}
+
+// parsePaddingSegments 解析 a-b,c,... 形式的片段列表
+func parsePaddingSegments(value string) ([]PaddingSegment, error) {
+	var segments []PaddingSegment
+	check := false
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "c" {
+			check = true
+			continue
+		}
+		lo, hi, ok := strings.Cut(item, "-")
+		if !ok {
+			return nil, fmt.Errorf("片段應為 a-b 或 c: %q", item)
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || from < 0 || to > PaddingMaxSize {
+			return nil, fmt.Errorf("片段長度應為 0-%d 的整數: %q", PaddingMaxSize, item)
+		}
+		if from > to {
+			return nil, fmt.Errorf("片段下限大於上限: %q", item)
+		}
+		segments = append(segments, PaddingSegment{Min: from, Max: to, Check: check})
+		check = false
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("至少需要一個 a-b 片段")
+	}
+	if check {
+		return nil, fmt.Errorf("c 不能位於末尾")
+	}
+	return segments, nil
+}
+
+// Segments 返回前 packets 個包內的所有片段，按包序號排序
+func (s *PaddingScheme) Segments(packets int) []PaddingSegment {
+	indexes := make([]int, 0, len(s.Packets))
+	for index := range s.Packets {
+		if index < packets && index < s.Stop {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+
+	var out []PaddingSegment
+	for _, index := range indexes {
+		out = append(out, s.Packets[index]...)
+	}
+	return out
+}
+
+// PaddingBucket 包長分佈的一個區間，Count 為落入該區間的期望片段數
+type PaddingBucket struct {
+	Min   int
+	Max   int
+	Count float64
+}
+
+// PaddingHistogram 將片段長度分佈劃分為 buckets 個等寬區間
+// 片段長度在 [Min, Max] 內均勻隨機，按重疊比例分攤到各區間
+func PaddingHistogram(segments []PaddingSegment, buckets int) []PaddingBucket {
+	if len(segments) == 0 || buckets < 1 {
+		return nil
+	}
+	lo, hi := segments[0].Min, segments[0].Max
+	for _, seg := range segments[1:] {
+		lo, hi = min(lo, seg.Min), max(hi, seg.Max)
+	}
+
+	width := (hi - lo + buckets) / buckets
+	out := make([]PaddingBucket, buckets)
+	for i := range out {
+		out[i].Min = lo + i*width
+		out[i].Max = out[i].Min + width - 1
+	}
+	for _, seg := range segments {
+		span := float64(seg.Max - seg.Min + 1)
+		for i := range out {
+			overlap := min(seg.Max, out[i].Max) - max(seg.Min, out[i].Min) + 1
+			if overlap > 0 {
+				out[i].Count += float64(overlap) / span
+			}
+		}
+	}
+	// 去掉末尾超出最大長度的空區間
+	for len(out) > 1 && out[len(out)-1].Min > hi {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// SplitPaddingScheme 將單行輸入按 ; 拆分為方案行
+func SplitPaddingScheme(input string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(input, func(r rune) bool { return r == ';' || r == '\n' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// PaddingPresetModes 內置填充模式，按 TUI 菜單順序排列
+var PaddingPresetModes = []string{
+	PaddingModeBalanced,
+	PaddingModeMinimal,
+	PaddingModeHighResist,
+	PaddingModeVideo,
+	PaddingModeOfficial,
+}
+
+// MergePaddingLines 將 鍵=值 形式的修改合併到方案中，值為空時刪除該鍵
+// 返回的方案 stop 在前，其餘按包序號排序；語法由 ParsePaddingScheme 另行校驗
+func MergePaddingLines(scheme, edits []string) ([]string, error) {
+	values := make(map[string]string)
+	for _, line := range scheme {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	for _, line := range edits {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("應為 鍵=值 形式: %s", line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if value == "" {
+			delete(values, key)
+			continue
+		}
+		values[key] = value
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == "stop" || keys[j] == "stop" {
+			return keys[i] == "stop"
+		}
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA != nil || errB != nil {
+			return keys[i] < keys[j]
+		}
+		return a < b
+	})
+
+	merged := make([]string, 0, len(keys))
+	for _, key := range keys {
+		merged = append(merged, key+"="+values[key])
+	}
+	return merged, nil
+}
+
+// FindPaddingProfile 按名稱查找已保存的填充方案
+func (p *ProtocolsConfig) FindPaddingProfile(name string) *PaddingProfile {
+	for i := range p.PaddingProfiles {
+		if p.PaddingProfiles[i].Name == name {
+			return &p.PaddingProfiles[i]
+		}
+	}
+	return nil
+}
+
+// SavePaddingProfile 保存命名填充方案，同名時覆蓋
+func (p *ProtocolsConfig) SavePaddingProfile(name string, scheme []string) error {
+	if name == "" || strings.ContainsAny(name, " \t;") {
+		return fmt.Errorf("方案名稱不能為空或包含空白")
+	}
+	if _, ok := paddingPresets[name]; ok || name == PaddingModeCustom {
+		return fmt.Errorf("方案名稱 %s 與內置方案衝突", name)
+	}
+	if _, err := ParsePaddingScheme(scheme); err != nil {
+		return err
+	}
+	scheme = append([]string(nil), scheme...)
+	if existing := p.FindPaddingProfile(name); existing != nil {
+		existing.Scheme = scheme
+		return nil
+	}
+	p.PaddingProfiles = append(p.PaddingProfiles, PaddingProfile{Name: name, Scheme: scheme})
+	return nil
+}
+
+// DeletePaddingProfile 刪除命名填充方案
+func (p *ProtocolsConfig) DeletePaddingProfile(name string) error {
+	for i := range p.PaddingProfiles {
+		if p.PaddingProfiles[i].Name == name {
+			p.PaddingProfiles = append(p.PaddingProfiles[:i], p.PaddingProfiles[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("未找到填充方案: %s", name)
+}
+
+// SetPadding 為所有 AnyTLS / AnyTLS Reality 實例設置填充方案
+// mode 為內置模式時清空自定義方案，否則寫入 scheme 並標記為 custom
+func (p *ProtocolsConfig) SetPadding(mode string, scheme []string) {
+	if mode != PaddingModeCustom {
+		scheme = nil
+	}
+	for _, inst := range p.InstancesOf(ProtocolTypeAnyTLS) {
+		inst.AnyTLS.PaddingMode = mode
+		inst.AnyTLS.PaddingScheme = append([]string(nil), scheme...)
+	}
+	for _, inst := range p.InstancesOf(ProtocolTypeAnyTLSReality) {
+		inst.AnyTLSReality.PaddingMode = mode
+		inst.AnyTLSReality.PaddingScheme = append([]string(nil), scheme...)
+	}
+}
+
+// EffectivePaddingScheme 返回實例實際使用的填充方案
+func EffectivePaddingScheme(mode string, scheme []string) []string {
+	if len(scheme) > 0 {
+		return scheme
+	}
+	return PaddingPreset(mode)
+}
+
+// validatePadding 校驗自定義填充方案
+func validatePadding(scheme []string) error {
+	if len(scheme) == 0 {
+		return nil
+	}
+	if _, err := ParsePaddingScheme(scheme); err != nil {
+		return fmt.Errorf("填充方案錯誤: %w", err)
+	}
+	return nil
+}
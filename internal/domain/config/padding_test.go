@@ -0,0 +1,119 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPaddingPresetsValid 內置方案均應通過語法校驗
+func TestPaddingPresetsValid(t *testing.T) {
+	for _, mode := range PaddingPresetModes {
+		if _, err := ParsePaddingScheme(PaddingPreset(mode)); err != nil {
+			t.Errorf("內置方案 %s 無效: %v", mode, err)
+		}
+	}
+}
+
+// TestParsePaddingScheme 語法錯誤應帶行號並說明原因
+func TestParsePaddingScheme(t *testing.T) {
+	s, err := ParsePaddingScheme([]string{"stop=3", "0=30-30", "2=100-200,c,300-400"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Stop != 3 || len(s.Packets[2]) != 2 || !s.Packets[2][1].Check {
+		t.Errorf("解析結果錯誤: %+v", s)
+	}
+	if got := s.Segments(2); len(got) != 1 || got[0].Min != 30 {
+		t.Errorf("前 2 個包應只有 1 個片段: %+v", got)
+	}
+
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"0=10-20"}, "缺少 stop"},
+		{[]string{"stop=2", "0=10"}, "第 2 行"},
+		{[]string{"stop=2", "x=10-20"}, "無效的包序號"},
+		{[]string{"stop=2", "0=20-10"}, "下限大於上限"},
+		{[]string{"stop=2", "0=10-20,c"}, "末尾"},
+		{[]string{"stop=2", "0=10-20", "0=5-5"}, "重複定義"},
+		{[]string{"stop=2", "5=10-20"}, "超出 stop"},
+		{[]string{"stop=0"}, "stop 應為"},
+		{[]string{"stop=2", "0=1-70000"}, "0-65535"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePaddingScheme(tt.lines)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: 預期錯誤包含 %q，實際 %v", tt.lines, tt.want, err)
+		}
+	}
+}
+
+// TestMergePaddingLines 按鍵合併修改並排序，空值刪除
+func TestMergePaddingLines(t *testing.T) {
+	got, err := MergePaddingLines([]string{"stop=3", "0=30-30", "1=100-400"}, []string{"2=5-5", "1=", "stop=10", "10=1-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ";") != "stop=10;0=30-30;2=5-5;10=1-1" {
+		t.Errorf("合併結果錯誤: %v", got)
+	}
+	if _, err := MergePaddingLines(nil, []string{"oops"}); err == nil {
+		t.Error("缺少 = 應返回錯誤")
+	}
+}
+
+// TestPaddingHistogram 片段按重疊比例分攤，總數等於片段數
+func TestPaddingHistogram(t *testing.T) {
+	segments := []PaddingSegment{{Min: 0, Max: 99}, {Min: 100, Max: 199}, {Min: 150, Max: 150}}
+	buckets := PaddingHistogram(segments, 2)
+	if len(buckets) != 2 {
+		t.Fatalf("區間數錯誤: %+v", buckets)
+	}
+	if buckets[0].Count != 1 || buckets[1].Count != 2 {
+		t.Errorf("分佈錯誤: %+v", buckets)
+	}
+	if PaddingHistogram(nil, 4) != nil {
+		t.Error("無片段時應返回 nil")
+	}
+}
+
+// TestPaddingProfiles 命名方案的保存、覆蓋、應用與刪除
+func TestPaddingProfiles(t *testing.T) {
+	cfg := DefaultConfig()
+	p := &cfg.Protocols
+	scheme := []string{"stop=2", "0=10-20", "1=100-200"}
+
+	if err := p.SavePaddingProfile("official", scheme); err == nil {
+		t.Error("與內置方案同名應返回錯誤")
+	}
+	if err := p.SavePaddingProfile("mobile", []string{"0=10-20"}); err == nil {
+		t.Error("無效方案不應保存")
+	}
+	if err := p.SavePaddingProfile("mobile", scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SavePaddingProfile("mobile", PaddingPreset(PaddingModeMinimal)); err != nil || len(p.PaddingProfiles) != 1 {
+		t.Errorf("同名應覆蓋: %v %d", err, len(p.PaddingProfiles))
+	}
+
+	p.SetPadding(PaddingModeCustom, scheme)
+	if c := p.AnyTLSReality(); c.PaddingMode != PaddingModeCustom || len(c.PaddingScheme) != 3 {
+		t.Errorf("AnyTLS Reality 應同步自定義方案: %+v", c)
+	}
+	p.AnyTLS().PaddingScheme = []string{"stop=1", "3=1-1"}
+	if err := p.ValidateInstances(); err == nil {
+		t.Error("無效的自定義方案應校驗失敗")
+	}
+	p.SetPadding(PaddingModeVideo, nil)
+	if c := p.AnyTLS(); c.PaddingMode != PaddingModeVideo || c.PaddingScheme != nil {
+		t.Errorf("切換內置方案應清空自定義方案: %+v", c)
+	}
+
+	if err := p.DeletePaddingProfile("mobile"); err != nil || p.FindPaddingProfile("mobile") != nil {
+		t.Errorf("刪除失敗: %v", err)
+	}
+	if err := p.DeletePaddingProfile("mobile"); err == nil {
+		t.Error("刪除不存在的方案應返回錯誤")
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 跳躍端口允許的範圍
+const (
+	minHoppingPort = 1024
+	maxHoppingPort = 65535
+)
+
+// MinHopInterval Hysteria2 客戶端允許的最小跳躍間隔
+const MinHopInterval = 5 * time.Second
+
+// PortRange 端口範圍，單個端口時 Start 與 End 相同
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// String 返回 "20000-30000" 或單端口 "40000"
+func (r PortRange) String() string {
+	if r.End > r.Start {
+		return fmt.Sprintf("%d-%d", r.Start, r.End)
+	}
+	return strconv.Itoa(r.Start)
+}
+
+// Contains 端口是否位於範圍內
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// ParsePortRanges 解析逗號分隔的跳躍端口列表，如 "20000-30000,40000-45000,50000"
+// 結果按起始端口排序，範圍之間不允許重疊
+func ParsePortRanges(s string) ([]PortRange, error) {
+	var ranges []PortRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		startStr, endStr, isRange := strings.Cut(part, "-")
+		start, err1 := strconv.Atoi(strings.TrimSpace(startStr))
+		end, err2 := start, error(nil)
+		if isRange {
+			end, err2 = strconv.Atoi(strings.TrimSpace(endStr))
+		}
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("端口跳躍格式錯誤: %s (應為 '20000-30000')", part)
+		}
+		if start < minHoppingPort || end > maxHoppingPort || (isRange && start >= end) {
+			return nil, fmt.Errorf("端口跳躍範圍無效: %s (應在 %d-%d 之間且 start < end)", part, minHoppingPort, maxHoppingPort)
+		}
+		ranges = append(ranges, PortRange{Start: start, End: end})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("端口跳躍格式錯誤: %q", s)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].Start <= ranges[i-1].End {
+			return nil, fmt.Errorf("端口跳躍範圍重疊: %s 與 %s", ranges[i-1], ranges[i])
+		}
+	}
+	return ranges, nil
+}
+
+// FormatPortRanges 將範圍列表格式化為逗號分隔的字符串
+func FormatPortRanges(ranges []PortRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// HoppingRanges 返回已配置的跳躍端口範圍，未設置或格式錯誤時返回 nil
+func (h *Hysteria2Config) HoppingRanges() []PortRange {
+	if h.PortHopping == "" {
+		return nil
+	}
+	ranges, err := ParsePortRanges(h.PortHopping)
+	if err != nil {
+		return nil
+	}
+	return ranges
+}
+
+// HopIntervalDuration 返回客戶端跳躍間隔，未設置或無效時返回 0 (使用客戶端默認值)
+func (h *Hysteria2Config) HopIntervalDuration() time.Duration {
+	d, err := time.ParseDuration(h.HopInterval)
+	if err != nil || d < MinHopInterval {
+		return 0
+	}
+	return d
+}
+
+// ValidatePortHopping 驗證跳躍端口列表與跳躍間隔
+func (h *Hysteria2Config) ValidatePortHopping() error {
+	if h.PortHopping != "" {
+		if _, err := ParsePortRanges(h.PortHopping); err != nil {
+			return err
+		}
+	}
+
+	if h.HopInterval != "" {
+		d, err := time.ParseDuration(h.HopInterval)
+		if err != nil {
+			return fmt.Errorf("跳躍間隔格式錯誤: %s (應為 '30s' 形式)", h.HopInterval)
+		}
+		if d < MinHopInterval {
+			return fmt.Errorf("跳躍間隔不能小於 %s", MinHopInterval)
+		}
+	}
+	return nil
+}
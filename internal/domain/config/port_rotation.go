@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// MinRotationInterval 定時輪換允許的最小間隔，避免客戶端來不及更新訂閱
+const MinRotationInterval = time.Hour
+
+// PortRotationConfig 端口自動輪換策略 (由定時任務執行)
+type PortRotationConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Protocols []int  `yaml:"protocols,omitempty"` // 參與輪換的協議 ID，留空表示所有已啟用協議
+	Interval  string `yaml:"interval,omitempty"`  // 定時輪換間隔，如 72h，留空則只在探測失敗時輪換
+
+	// 可達性探測：端口不可達時立即輪換
+	// 封鎖發生在客戶端所在網絡，須由位於該網絡的外部探測服務判斷，本機無法自行探測
+	Probe    bool   `yaml:"probe,omitempty"`
+	ProbeURL string `yaml:"probe_url,omitempty"` // 外部探測服務，支持 {host} {port} {network} 佔位符，啟用探測時必填
+
+	LastRotated time.Time `yaml:"last_rotated,omitempty"` // 上次輪換時間，由輪換流程寫入
+}
+
+// IntervalDuration 返回定時輪換間隔，未設置或無效時返回 0
+func (r *PortRotationConfig) IntervalDuration() time.Duration {
+	d, err := time.ParseDuration(r.Interval)
+	if err != nil || d < MinRotationInterval {
+		return 0
+	}
+	return d
+}
+
+// Due 定時輪換是否已到期
+// 從未輪換過的節點以首次執行為起點，不會在啟用後立即更換端口
+func (r *PortRotationConfig) Due(now time.Time) bool {
+	interval := r.IntervalDuration()
+	if !r.Enabled || interval == 0 || r.LastRotated.IsZero() {
+		return false
+	}
+	return !now.Before(r.LastRotated.Add(interval))
+}
+
+// Includes 協議是否參與輪換
+func (r *PortRotationConfig) Includes(id int) bool {
+	if len(r.Protocols) == 0 {
+		return true
+	}
+	for _, p := range r.Protocols {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 驗證輪換策略
+func (r *PortRotationConfig) Validate() error {
+	if r.Interval != "" {
+		d, err := time.ParseDuration(r.Interval)
+		if err != nil {
+			return fmt.Errorf("輪換間隔格式錯誤: %s (應為 '72h' 形式)", r.Interval)
+		}
+		if d < MinRotationInterval {
+			return fmt.Errorf("輪換間隔不能小於 %s", MinRotationInterval)
+		}
+	}
+
+	if r.Probe && r.ProbeURL == "" {
+		return fmt.Errorf("可達性探測需要設置外部探測地址 (probe_url)")
+	}
+	if r.ProbeURL != "" {
+		u, err := url.Parse(r.ProbeURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("探測地址無效: %s (應為 http(s) 地址)", r.ProbeURL)
+		}
+	}
+
+	if r.Enabled && r.Interval == "" && !r.Probe {
+		return fmt.Errorf("端口輪換已啟用，但未設置輪換間隔或可達性探測")
+	}
+	return nil
+}
@@ -0,0 +1,409 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 協議類型，與實例中對應配置字段的 YAML 鍵一致
+const (
+	ProtocolTypeRealityVision = "reality_vision"
+	ProtocolTypeRealityGRPC   = "reality_grpc"
+	ProtocolTypeHysteria2     = "hysteria2"
+	ProtocolTypeTUIC          = "tuic"
+	ProtocolTypeAnyTLS        = "anytls"
+	ProtocolTypeAnyTLSReality = "anytls_reality"
+	ProtocolTypeShadowTLS     = "shadowtls"
+	ProtocolTypeCDN           = "cdn"
+	ProtocolTypeShadowsocks   = "shadowsocks"
+	ProtocolTypeNaive         = "naive"
+)
+
+// ProtocolTypes 返回所有協議類型 (順序與協議編號一致)
+func ProtocolTypes() []string {
+	types := make([]string, 0, len(protocolSchemas))
+	for _, s := range protocolSchemas {
+		types = append(types, s.typ)
+	}
+	return types
+}
+
+// IsProtocolType 判斷是否為已知的協議類型
+func IsProtocolType(protocolType string) bool {
+	_, ok := lookupSchema(protocolType)
+	return ok
+}
+
+// DefaultProtocolTag 返回協議主實例的標籤，如 reality_vision -> reality-vision-in
+func DefaultProtocolTag(protocolType string) string {
+	return strings.ReplaceAll(protocolType, "_", "-") + "-in"
+}
+
+// 實例標籤同時用作 sing-box 入站標籤與防火牆策略名稱，限制字符與長度
+var protocolTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ProtocolInstance 協議實例
+// 同一協議可配置多個實例 (如不同端口 / SNI)，以標籤唯一區分；
+// 僅與 Type 對應的配置字段非空
+type ProtocolInstance struct {
+	Tag  string `yaml:"tag"`
+	Type string `yaml:"type"`
+
+	RealityVision *RealityVisionConfig `yaml:"reality_vision,omitempty"`
+	RealityGRPC   *RealityGRPCConfig   `yaml:"reality_grpc,omitempty"`
+	Hysteria2     *Hysteria2Config     `yaml:"hysteria2,omitempty"`
+	TUIC          *TUICConfig          `yaml:"tuic,omitempty"`
+	AnyTLS        *AnyTLSConfig        `yaml:"anytls,omitempty"`
+	AnyTLSReality *AnyTLSRealityConfig `yaml:"anytls_reality,omitempty"`
+	ShadowTLS     *ShadowTLSConfig     `yaml:"shadowtls,omitempty"`
+	CDN           *CDNConfig           `yaml:"cdn,omitempty"`
+	Shadowsocks   *ShadowsocksConfig   `yaml:"shadowsocks,omitempty"`
+	Naive         *NaiveConfig         `yaml:"naive,omitempty"`
+}
+
+// NewProtocolInstance 創建指定類型的空白協議實例
+func NewProtocolInstance(tag, protocolType string) (ProtocolInstance, error) {
+	schema, ok := lookupSchema(protocolType)
+	if !ok {
+		return ProtocolInstance{}, fmt.Errorf("未知的協議類型: %s", protocolType)
+	}
+	inst := ProtocolInstance{Tag: tag, Type: protocolType}
+	schema.new(&inst)
+	return inst, nil
+}
+
+// IsPrimary 是否為協議主實例 (使用默認標籤)
+// 主實例沿用全局 UUID / 密碼，並對應協議編號的開關與端口設置
+func (inst *ProtocolInstance) IsPrimary() bool {
+	return inst.Tag == DefaultProtocolTag(inst.Type)
+}
+
+// Credential 返回實例使用的憑據 (UUID / 密碼)
+// 主實例沿用全局值 (隨全局設置同步變更)，其餘實例優先使用自身配置
+func (inst *ProtocolInstance) Credential(own, global string) string {
+	if !inst.IsPrimary() && own != "" {
+		return own
+	}
+	return global
+}
+
+// Clone 深拷貝實例並使用新標籤
+func (inst *ProtocolInstance) Clone(tag string) ProtocolInstance {
+	data, err := yaml.Marshal(inst)
+	if err != nil {
+		panic(fmt.Errorf("Clone 序列化失敗 (這是一個 Bug): %w", err))
+	}
+	var clone ProtocolInstance
+	if err := yaml.Unmarshal(data, &clone); err != nil {
+		panic(fmt.Errorf("Clone 反序列化失敗 (這是一個 Bug): %w", err))
+	}
+	clone.Tag = tag
+	return clone
+}
+
+// Section 返回實例的公共字段視圖，類型與配置不匹配時返回 false
+func (inst *ProtocolInstance) Section() (ProtocolSection, bool) {
+	c := inst.config()
+	if c == nil {
+		return ProtocolSection{}, false
+	}
+	s := ProtocolSection{Key: inst.Type, Tag: inst.Tag, Primary: inst.IsPrimary()}
+	c.section(&s)
+	return s, true
+}
+
+// configCount 返回實例中非空的協議配置數量
+func (inst *ProtocolInstance) configCount() int {
+	return len(inst.configs())
+}
+
+// isBlank 實例是否為 NewProtocolInstance 創建後未作任何修改的空白實例
+func (inst *ProtocolInstance) isBlank() bool {
+	if inst.configCount() == 0 {
+		return true
+	}
+	blank, err := NewProtocolInstance(inst.Tag, inst.Type)
+	return err == nil && reflect.DeepEqual(*inst, blank)
+}
+
+// secretFields 返回實例中需要加密存儲的字段
+func (inst *ProtocolInstance) secretFields() []*string {
+	var fields []*string
+	for _, c := range inst.configs() {
+		fields = append(fields, c.secretFields()...)
+	}
+	return fields
+}
+
+// fillDefaults 填充實例憑據默認值
+func (inst *ProtocolInstance) fillDefaults(uuid, password string) {
+	for _, c := range inst.configs() {
+		c.fillDefaults(uuid, password)
+	}
+}
+
+// Instance 按標籤查找協議實例
+// 返回的指針指向 Instances 內部，增刪實例後失效
+func (p *ProtocolsConfig) Instance(tag string) *ProtocolInstance {
+	for i := range p.Instances {
+		if p.Instances[i].Tag == tag {
+			return &p.Instances[i]
+		}
+	}
+	return nil
+}
+
+// InstancesOf 返回指定類型的所有實例 (主實例在前，其餘保持配置順序)
+// 缺少對應配置的無效實例被跳過，返回的實例可直接訪問類型對應的配置字段
+func (p *ProtocolsConfig) InstancesOf(protocolType string) []*ProtocolInstance {
+	var primary, extras []*ProtocolInstance
+	for i := range p.Instances {
+		inst := &p.Instances[i]
+		if inst.Type != protocolType {
+			continue
+		}
+		if _, ok := inst.Section(); !ok {
+			continue
+		}
+		if inst.IsPrimary() {
+			primary = append(primary, inst)
+		} else {
+			extras = append(extras, inst)
+		}
+	}
+	return append(primary, extras...)
+}
+
+// primary 返回協議主實例
+// 不存在或缺少對應配置時返回未加入配置的空白實例，訪問器總能拿到非 nil 的零值配置，
+// 對其修改不會保存；主實例由 EnsurePrimaries 在加載配置時補齊
+func (p *ProtocolsConfig) primary(protocolType string) *ProtocolInstance {
+	tag := DefaultProtocolTag(protocolType)
+	if inst := p.Instance(tag); inst != nil && inst.Type == protocolType && inst.config() != nil {
+		return inst
+	}
+	blank, _ := NewProtocolInstance(tag, protocolType)
+	return &blank
+}
+
+// RealityVision 返回 Reality Vision 主實例配置
+func (p *ProtocolsConfig) RealityVision() *RealityVisionConfig {
+	return p.primary(ProtocolTypeRealityVision).RealityVision
+}
+
+// RealityGRPC 返回 Reality gRPC 主實例配置
+func (p *ProtocolsConfig) RealityGRPC() *RealityGRPCConfig {
+	return p.primary(ProtocolTypeRealityGRPC).RealityGRPC
+}
+
+// Hysteria2 返回 Hysteria2 主實例配置
+func (p *ProtocolsConfig) Hysteria2() *Hysteria2Config {
+	return p.primary(ProtocolTypeHysteria2).Hysteria2
+}
+
+// TUIC 返回 TUIC 主實例配置
+func (p *ProtocolsConfig) TUIC() *TUICConfig {
+	return p.primary(ProtocolTypeTUIC).TUIC
+}
+
+// AnyTLS 返回 AnyTLS 主實例配置
+func (p *ProtocolsConfig) AnyTLS() *AnyTLSConfig {
+	return p.primary(ProtocolTypeAnyTLS).AnyTLS
+}
+
+// AnyTLSReality 返回 AnyTLS Reality 主實例配置
+func (p *ProtocolsConfig) AnyTLSReality() *AnyTLSRealityConfig {
+	return p.primary(ProtocolTypeAnyTLSReality).AnyTLSReality
+}
+
+// ShadowTLS 返回 ShadowTLS 主實例配置
+func (p *ProtocolsConfig) ShadowTLS() *ShadowTLSConfig {
+	return p.primary(ProtocolTypeShadowTLS).ShadowTLS
+}
+
+// CDN 返回 CDN 中轉主實例配置
+func (p *ProtocolsConfig) CDN() *CDNConfig {
+	return p.primary(ProtocolTypeCDN).CDN
+}
+
+// Shadowsocks 返回 Shadowsocks 2022 主實例配置
+func (p *ProtocolsConfig) Shadowsocks() *ShadowsocksConfig {
+	return p.primary(ProtocolTypeShadowsocks).Shadowsocks
+}
+
+// Naive 返回 NaiveProxy 主實例配置
+func (p *ProtocolsConfig) Naive() *NaiveConfig {
+	return p.primary(ProtocolTypeNaive).Naive
+}
+
+// EnsurePrimaries 補齊缺失的協議主實例，並重建缺少對應配置的主實例
+// 訪問器不會創建實例，加載或重建配置時需先調用此方法
+func (p *ProtocolsConfig) EnsurePrimaries() error {
+	for _, t := range ProtocolTypes() {
+		tag := DefaultProtocolTag(t)
+		inst := p.Instance(tag)
+		if inst != nil && inst.Type != t {
+			return fmt.Errorf("協議實例 %s: 標籤與 %s 主實例衝突", tag, t)
+		}
+		if inst != nil && inst.configCount() > 0 {
+			continue
+		}
+		blank, err := NewProtocolInstance(tag, t)
+		if err != nil {
+			return err
+		}
+		if inst != nil {
+			*inst = blank
+			continue
+		}
+		p.Instances = append(p.Instances, blank)
+	}
+	return nil
+}
+
+// AddInstance 添加協議實例
+func (p *ProtocolsConfig) AddInstance(inst ProtocolInstance) error {
+	if err := inst.validate(); err != nil {
+		return err
+	}
+	if p.Instance(inst.Tag) != nil {
+		return fmt.Errorf("協議實例標籤已存在: %s", inst.Tag)
+	}
+	p.Instances = append(p.Instances, inst)
+	return nil
+}
+
+// RemoveInstance 刪除協議實例，主實例不可刪除
+func (p *ProtocolsConfig) RemoveInstance(tag string) error {
+	for i := range p.Instances {
+		if p.Instances[i].Tag != tag {
+			continue
+		}
+		if p.Instances[i].IsPrimary() {
+			return fmt.Errorf("主實例不可刪除: %s", tag)
+		}
+		p.Instances = append(p.Instances[:i], p.Instances[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("協議實例不存在: %s", tag)
+}
+
+// validate 驗證實例的標籤與類型
+func (inst *ProtocolInstance) validate() error {
+	if !protocolTagPattern.MatchString(inst.Tag) {
+		return fmt.Errorf("無效的協議實例標籤 %q: 僅允許小寫字母、數字與連字符，最長 32 個字符", inst.Tag)
+	}
+	if !IsProtocolType(inst.Type) {
+		return fmt.Errorf("協議實例 %s: 未知的協議類型 %q", inst.Tag, inst.Type)
+	}
+	if _, ok := inst.Section(); !ok || inst.configCount() != 1 {
+		return fmt.Errorf("協議實例 %s: 必須且只能包含 %s 配置", inst.Tag, inst.Type)
+	}
+	return nil
+}
+
+// ValidateInstances 驗證協議實例列表
+// 標籤必須唯一，且不能佔用其他類型主實例的默認標籤
+func (p *ProtocolsConfig) ValidateInstances() error {
+	seen := make(map[string]bool, len(p.Instances))
+	detourPorts := map[int]string{}
+	for i := range p.Instances {
+		inst := &p.Instances[i]
+		if err := inst.validate(); err != nil {
+			return err
+		}
+		if seen[inst.Tag] {
+			return fmt.Errorf("協議實例標籤重複: %s", inst.Tag)
+		}
+		seen[inst.Tag] = true
+		for _, t := range ProtocolTypes() {
+			if t != inst.Type && inst.Tag == DefaultProtocolTag(t) {
+				return fmt.Errorf("協議實例 %s: 標籤與 %s 主實例衝突", inst.Tag, t)
+			}
+		}
+		if err := inst.validateOptions(); err != nil {
+			return err
+		}
+		// 主實例未設置時使用 10000 作為本地轉交端口，其餘實例必須各自配置且不能重複
+		if c := inst.ShadowTLS; c != nil {
+			if !inst.IsPrimary() && c.DetourPort <= 0 {
+				return fmt.Errorf("協議實例 %s: 必須設置獨立的 detour_port", inst.Tag)
+			}
+			if other, ok := detourPorts[c.GetDetourPort()]; ok {
+				return fmt.Errorf("協議實例 %s: detour_port %d 已被 %s 使用", inst.Tag, c.GetDetourPort(), other)
+			}
+			detourPorts[c.GetDetourPort()] = inst.Tag
+		}
+	}
+	return nil
+}
+
+// legacyInstance 將 V3 及更早版本的固定佈局包裝為實例 (僅用於遍歷字段)
+func (p *ProtocolsConfig) legacyInstance() *ProtocolInstance {
+	return &ProtocolInstance{
+		RealityVision: p.LegacyRealityVision,
+		RealityGRPC:   p.LegacyRealityGRPC,
+		Hysteria2:     p.LegacyHysteria2,
+		TUIC:          p.LegacyTUIC,
+		AnyTLS:        p.LegacyAnyTLS,
+		AnyTLSReality: p.LegacyAnyTLSReality,
+		ShadowTLS:     p.LegacyShadowTLS,
+	}
+}
+
+// secretFields 返回所有實例 (含未遷移的舊佈局) 中需要加密存儲的字段
+// 解密在遷移之前執行，因此舊佈局字段也需要處理
+func (p *ProtocolsConfig) secretFields() []*string {
+	var fields []*string
+	for i := range p.Instances {
+		fields = append(fields, p.Instances[i].secretFields()...)
+	}
+	return append(fields, p.legacyInstance().secretFields()...)
+}
+
+// migrateLegacy 將固定佈局的協議配置轉換為實例列表 (使用默認標籤)
+func (p *ProtocolsConfig) migrateLegacy() {
+	legacy := p.legacyInstance()
+	for _, t := range ProtocolTypes() {
+		inst := ProtocolInstance{Tag: DefaultProtocolTag(t), Type: t}
+		switch t {
+		case ProtocolTypeRealityVision:
+			inst.RealityVision = legacy.RealityVision
+		case ProtocolTypeRealityGRPC:
+			inst.RealityGRPC = legacy.RealityGRPC
+		case ProtocolTypeHysteria2:
+			inst.Hysteria2 = legacy.Hysteria2
+		case ProtocolTypeTUIC:
+			inst.TUIC = legacy.TUIC
+		case ProtocolTypeAnyTLS:
+			inst.AnyTLS = legacy.AnyTLS
+		case ProtocolTypeAnyTLSReality:
+			inst.AnyTLSReality = legacy.AnyTLSReality
+		case ProtocolTypeShadowTLS:
+			inst.ShadowTLS = legacy.ShadowTLS
+		}
+		if inst.configCount() == 0 {
+			continue
+		}
+		// 已存在的同名主實例若仍為空白 (如遷移前已補齊默認實例)，以舊配置覆蓋
+		if existing := p.Instance(inst.Tag); existing != nil {
+			if existing.Type == t && existing.isBlank() {
+				*existing = inst
+			}
+			continue
+		}
+		p.Instances = append(p.Instances, inst)
+	}
+
+	p.LegacyRealityVision = nil
+	p.LegacyRealityGRPC = nil
+	p.LegacyHysteria2 = nil
+	p.LegacyTUIC = nil
+	p.LegacyAnyTLS = nil
+	p.LegacyAnyTLSReality = nil
+	p.LegacyShadowTLS = nil
+}
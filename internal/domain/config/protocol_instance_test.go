@@ -0,0 +1,308 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestProtocolInstances 測試協議實例的添加、刪除與主實例訪問
+func TestProtocolInstances(t *testing.T) {
+	cfg := DefaultConfig()
+	p := &cfg.Protocols
+
+	extra := p.Instance(DefaultProtocolTag(ProtocolTypeRealityVision)).Clone("vision-cdn")
+	extra.RealityVision.Port = 9443
+	if err := p.AddInstance(extra); err != nil {
+		t.Fatalf("添加實例失敗: %v", err)
+	}
+	if err := p.AddInstance(extra); err == nil {
+		t.Error("重複標籤應返回錯誤")
+	}
+
+	list := p.InstancesOf(ProtocolTypeRealityVision)
+	if len(list) != 2 || !list[0].IsPrimary() || list[1].Tag != "vision-cdn" {
+		t.Fatalf("實例順序錯誤: %+v", list)
+	}
+	// 克隆不應共享底層配置
+	if p.RealityVision().Port == 9443 {
+		t.Error("克隆實例修改了主實例配置")
+	}
+	if got := list[1].Credential("own-uuid", "global"); got != "own-uuid" {
+		t.Errorf("非主實例應使用自身憑據: %s", got)
+	}
+	if got := list[0].Credential("own-uuid", "global"); got != "global" {
+		t.Errorf("主實例應沿用全局憑據: %s", got)
+	}
+
+	if err := p.RemoveInstance(DefaultProtocolTag(ProtocolTypeRealityVision)); err == nil {
+		t.Error("主實例不應可刪除")
+	}
+	if err := p.RemoveInstance("vision-cdn"); err != nil {
+		t.Errorf("刪除實例失敗: %v", err)
+	}
+	if p.Instance("vision-cdn") != nil {
+		t.Error("實例未被刪除")
+	}
+}
+
+// TestPrimaryAccessorsMissing 主實例缺失時訪問器應返回零值配置且不修改配置
+func TestPrimaryAccessorsMissing(t *testing.T) {
+	p := &ProtocolsConfig{}
+	if p.Hysteria2() == nil || p.AnyTLS() == nil || p.Naive() == nil {
+		t.Fatal("主實例缺失時訪問器不應返回 nil")
+	}
+	if p.Hysteria2().PortHopping != "" {
+		t.Error("應返回零值配置")
+	}
+	p.Hysteria2().Port = 1234
+	if len(p.Instances) != 0 {
+		t.Error("訪問器不應添加實例")
+	}
+}
+
+// TestValidateInstances 測試實例列表驗證
+func TestValidateInstances(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(p *ProtocolsConfig)
+		wantErr string
+	}{
+		{
+			name:   "默認配置",
+			mutate: func(p *ProtocolsConfig) {},
+		},
+		{
+			name: "無效標籤",
+			mutate: func(p *ProtocolsConfig) {
+				p.Instances = append(p.Instances, ProtocolInstance{Tag: "Bad Tag", Type: ProtocolTypeTUIC, TUIC: &TUICConfig{}})
+			},
+			wantErr: "無效的協議實例標籤",
+		},
+		{
+			name: "標籤重複",
+			mutate: func(p *ProtocolsConfig) {
+				p.Instances = append(p.Instances, p.Instance("tuic-in").Clone("tuic-in"))
+			},
+			wantErr: "標籤重複",
+		},
+		{
+			name: "佔用其他類型默認標籤",
+			mutate: func(p *ProtocolsConfig) {
+				p.Instances = []ProtocolInstance{p.Instance("tuic-in").Clone("anytls-in")}
+			},
+			wantErr: "主實例衝突",
+		},
+		{
+			name: "配置與類型不符",
+			mutate: func(p *ProtocolsConfig) {
+				p.Instances = append(p.Instances, ProtocolInstance{Tag: "tuic-2", Type: ProtocolTypeTUIC, AnyTLS: &AnyTLSConfig{}})
+			},
+			wantErr: "必須且只能包含",
+		},
+		{
+			name: "ShadowTLS 缺少轉交端口",
+			mutate: func(p *ProtocolsConfig) {
+				inst := p.Instance("shadowtls-in").Clone("stls-2")
+				inst.ShadowTLS.DetourPort = 0
+				p.Instances = append(p.Instances, inst)
+			},
+			wantErr: "detour_port",
+		},
+		{
+			name: "ShadowTLS 轉交端口與主實例重複",
+			mutate: func(p *ProtocolsConfig) {
+				inst := p.Instance("shadowtls-in").Clone("stls-2")
+				inst.ShadowTLS.DetourPort = DefaultShadowTLSDetourPort
+				p.Instances = append(p.Instances, inst)
+			},
+			wantErr: "已被 shadowtls-in 使用",
+		},
+		{
+			name: "不支持的擁塞控制算法",
+			mutate: func(p *ProtocolsConfig) {
+				p.TUIC().CongestionControl = "reno2"
+			},
+			wantErr: "擁塞控制",
+		},
+		{
+			name: "無效的 Short ID",
+			mutate: func(p *ProtocolsConfig) {
+				p.AnyTLSReality().ShortID = "xyz"
+			},
+			wantErr: "short_id",
+		},
+		{
+			name: "CDN 不支持的傳輸方式",
+			mutate: func(p *ProtocolsConfig) {
+				p.CDN().Transport = "quic"
+			},
+			wantErr: "傳輸方式",
+		},
+		{
+			name: "CDN 路徑格式錯誤",
+			mutate: func(p *ProtocolsConfig) {
+				p.CDN().Path = "/ray?ed=2048"
+			},
+			wantErr: "路徑",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg.Protocols)
+			err := cfg.Protocols.ValidateInstances()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("不應返回錯誤: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("錯誤應包含 %q，實際: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestCDNOptions CDN 參數修改後自動補齊默認值，客戶端端口與 Host 按優先級推導
+func TestCDNOptions(t *testing.T) {
+	cfg := DefaultConfig()
+	inst := cfg.Protocols.Instance("cdn-in")
+	c := inst.CDN
+
+	if err := inst.SetOption(OptionCDNPath, "ray"); err != nil || c.Path != "/ray" {
+		t.Errorf("路徑應自動補齊 /: %q %v", c.Path, err)
+	}
+	if err := inst.SetOption(OptionCDNPath, ""); err != nil || !strings.HasPrefix(c.Path, "/") || c.Path == "/ray" {
+		t.Errorf("清空路徑應重新生成: %q %v", c.Path, err)
+	}
+	if err := inst.SetOption(OptionCDNPort, "70000"); err == nil {
+		t.Error("無效端口應返回錯誤")
+	}
+	if err := inst.SetOption(OptionCDNProxy, "trojan"); err != nil || c.Proxy != CDNProxyTrojan {
+		t.Errorf("代理協議修改失敗: %v", err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Fatalf("修改後配置應有效: %v", err)
+	}
+
+	if c.GetCDNPort() != c.Port {
+		t.Errorf("未設置 CDN 端口時應與監聽端口相同: %d", c.GetCDNPort())
+	}
+	c.CDNPort = 443
+	if c.GetCDNPort() != 443 {
+		t.Errorf("CDN 端口未生效: %d", c.GetCDNPort())
+	}
+
+	c.SNI = "sni.example.com"
+	c.CDNHost = "104.16.1.1"
+	if c.GetHost() != "sni.example.com" {
+		t.Errorf("CDN 地址為 IP 時 Host 應使用 SNI: %s", c.GetHost())
+	}
+	c.CDNHost = "edge.example.com"
+	if c.GetHost() != "edge.example.com" {
+		t.Errorf("Host 應使用 CDN 域名: %s", c.GetHost())
+	}
+}
+
+// TestNaiveOptions NaiveProxy 用戶名校驗與 HTTP/3 開關
+func TestNaiveOptions(t *testing.T) {
+	cfg := DefaultConfig()
+	inst := cfg.Protocols.Instance("naive-in")
+	c := inst.Naive
+
+	if c.GetUsername() != DefaultNaiveUsername {
+		t.Errorf("默認用戶名錯誤: %s", c.GetUsername())
+	}
+	if err := inst.SetOption(OptionUsername, "alice"); err != nil || c.Username != "alice" {
+		t.Errorf("用戶名修改失敗: %q %v", c.Username, err)
+	}
+	if err := inst.SetOption(OptionNaiveQUIC, "on"); err != nil || !c.QUIC {
+		t.Errorf("HTTP/3 開關失敗: %v", err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Fatalf("修改後配置應有效: %v", err)
+	}
+
+	c.Username = "a:b"
+	if err := cfg.Protocols.ValidateInstances(); err == nil {
+		t.Error("用戶名包含冒號應返回錯誤")
+	}
+}
+
+// TestHy2BandwidthOptions 帶寬支持「上行 [下行]」輸入，Brutal 默認開啟
+func TestHy2BandwidthOptions(t *testing.T) {
+	cfg := DefaultConfig()
+	inst := cfg.Protocols.Instance("hysteria2-in")
+	c := inst.Hysteria2
+
+	if err := inst.SetOption(OptionBandwidth, "200 500"); err != nil || c.UpMbps != 200 || c.DownMbps != 500 {
+		t.Errorf("帶寬設置失敗: %d/%d %v", c.UpMbps, c.DownMbps, err)
+	}
+	if err := inst.SetOption(OptionBandwidth, "300mbps"); err != nil || c.UpMbps != 300 || c.DownMbps != 300 {
+		t.Errorf("單值應同時設置上下行: %d/%d %v", c.UpMbps, c.DownMbps, err)
+	}
+	if err := inst.SetOption(OptionBandwidth, "0"); err == nil {
+		t.Error("帶寬為 0 應返回錯誤")
+	}
+	if err := inst.SetOption(OptionBandwidth, ""); err != nil {
+		t.Fatal(err)
+	}
+	if up, down := c.GetBandwidth(); up != DefaultHy2BandwidthMbps || down != DefaultHy2BandwidthMbps {
+		t.Errorf("清空後應恢復默認帶寬: %d/%d", up, down)
+	}
+
+	if err := inst.SetOption(OptionBrutal, "off"); err != nil || !c.IgnoreClientBandwidth {
+		t.Errorf("關閉 Brutal 失敗: %v", err)
+	}
+	if err := inst.SetOption(OptionBrutal, ""); err != nil || c.IgnoreClientBandwidth {
+		t.Errorf("空值應恢復開啟 Brutal: %v", err)
+	}
+	if err := cfg.Protocols.Instance("tuic-in").SetOption(OptionBrutal, "off"); err == nil {
+		t.Error("TUIC 不支持 Brutal 開關")
+	}
+}
+
+// TestTUICAdvancedOptions UDP 轉發模式與超時校驗，純數字按秒處理
+func TestTUICAdvancedOptions(t *testing.T) {
+	cfg := DefaultConfig()
+	inst := cfg.Protocols.Instance("tuic-in")
+	c := inst.TUIC
+
+	if c.GetUDPRelayMode() != "native" || c.GetAuthTimeout() != "3s" || c.GetHeartbeat() != "10s" {
+		t.Errorf("默認值錯誤: %s %s %s", c.GetUDPRelayMode(), c.GetAuthTimeout(), c.GetHeartbeat())
+	}
+	if err := inst.SetOption(OptionUDPRelayMode, "QUIC"); err != nil || c.UDPRelayMode != "quic" {
+		t.Errorf("UDP 轉發模式修改失敗: %q %v", c.UDPRelayMode, err)
+	}
+	if err := inst.SetOption(OptionHeartbeat, "15"); err != nil || c.Heartbeat != "15s" {
+		t.Errorf("純數字應按秒處理: %q %v", c.Heartbeat, err)
+	}
+	if err := inst.SetOption(OptionAuthTimeout, "500ms"); err != nil || c.AuthTimeout != "500ms" {
+		t.Errorf("認證超時修改失敗: %q %v", c.AuthTimeout, err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Fatalf("修改後配置應有效: %v", err)
+	}
+
+	for _, bad := range []struct{ field, value string }{
+		{"udp", "tcp"},
+		{"heartbeat", "abc"},
+		{"heartbeat", "-1s"},
+	} {
+		c.UDPRelayMode, c.Heartbeat = "", ""
+		if bad.field == "udp" {
+			c.UDPRelayMode = bad.value
+		} else {
+			c.Heartbeat = bad.value
+		}
+		if err := cfg.Protocols.ValidateInstances(); err == nil {
+			t.Errorf("%s=%s 應返回錯誤", bad.field, bad.value)
+		}
+	}
+
+	if err := cfg.Protocols.Instance("hysteria2-in").SetOption(OptionHeartbeat, "10s"); err == nil {
+		t.Error("Hysteria2 不支持心跳設置")
+	}
+}
@@ -0,0 +1,514 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultShadowTLSDetourPort ShadowTLS 主實例默認的本地 Shadowsocks 轉交端口
+const DefaultShadowTLSDetourPort = 10000
+
+// 協議高級字段的默認值 (配置留空時使用)
+const (
+	DefaultTUICCongestionControl = "bbr"
+	DefaultTUICUDPRelayMode      = "native"
+	DefaultTUICAuthTimeout       = "3s"
+	DefaultTUICHeartbeat         = "10s"
+	DefaultShadowTLSSSMethod     = "2022-blake3-aes-128-gcm"
+	DefaultAnyTLSUsername        = "prism"
+	DefaultNaiveUsername         = "prism"
+)
+
+// TUICCongestionControls TUIC 支持的擁塞控制算法
+var TUICCongestionControls = []string{"bbr", "cubic", "new_reno"}
+
+// TUICUDPRelayModes TUIC 客戶端的 UDP 轉發模式
+// native 使用 QUIC 數據報，延遲低；quic 使用 QUIC 流，無丟包但有隊頭阻塞
+var TUICUDPRelayModes = []string{"native", "quic"}
+
+// ShadowTLSSSMethods ShadowTLS 轉交的 Shadowsocks 支持的加密方式
+var ShadowTLSSSMethods = []string{
+	"2022-blake3-aes-128-gcm",
+	"2022-blake3-aes-256-gcm",
+	"2022-blake3-chacha20-poly1305",
+	"aes-128-gcm",
+	"aes-256-gcm",
+	"chacha20-ietf-poly1305",
+}
+
+// CDN 中轉支持的代理協議與傳輸方式
+const (
+	CDNProxyVLESS  = "vless"
+	CDNProxyVMess  = "vmess"
+	CDNProxyTrojan = "trojan"
+
+	CDNTransportWS          = "ws"
+	CDNTransportHTTPUpgrade = "httpupgrade"
+	CDNTransportGRPC        = "grpc"
+)
+
+// DefaultCDNPort CDN 中轉默認監聽端口 (Cloudflare 支持回源的 HTTPS 端口之一)
+const DefaultCDNPort = 2053
+
+// CDNProxies CDN 中轉支持的代理協議
+var CDNProxies = []string{CDNProxyVLESS, CDNProxyVMess, CDNProxyTrojan}
+
+// CDNTransports CDN 中轉支持的傳輸方式
+var CDNTransports = []string{CDNTransportWS, CDNTransportHTTPUpgrade, CDNTransportGRPC}
+
+// ParseALPN 解析逗號分隔的 ALPN 列表，忽略空白項
+func ParseALPN(raw string) []string {
+	var alpn []string
+	for _, s := range strings.Split(raw, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			alpn = append(alpn, s)
+		}
+	}
+	return alpn
+}
+
+// GetALPN 獲取 ALPN 列表 (配置為逗號分隔字符串)，未設置時返回 h3
+func (c *Hysteria2Config) GetALPN() []string {
+	if alpn := ParseALPN(c.ALPN); len(alpn) > 0 {
+		return alpn
+	}
+	return []string{"h3"}
+}
+
+// GetALPN 獲取 ALPN 列表，未設置時返回 h3
+func (c *TUICConfig) GetALPN() []string {
+	if len(c.ALPN) > 0 {
+		return c.ALPN
+	}
+	return []string{"h3"}
+}
+
+// GetCongestionControl 獲取擁塞控制算法，未設置時返回 bbr
+func (c *TUICConfig) GetCongestionControl() string {
+	if c.CongestionControl != "" {
+		return c.CongestionControl
+	}
+	return DefaultTUICCongestionControl
+}
+
+// GetUDPRelayMode 獲取 UDP 轉發模式，未設置時返回 native
+func (c *TUICConfig) GetUDPRelayMode() string {
+	if c.UDPRelayMode != "" {
+		return c.UDPRelayMode
+	}
+	return DefaultTUICUDPRelayMode
+}
+
+// GetAuthTimeout 獲取認證超時，未設置時返回 3s
+func (c *TUICConfig) GetAuthTimeout() string {
+	if c.AuthTimeout != "" {
+		return c.AuthTimeout
+	}
+	return DefaultTUICAuthTimeout
+}
+
+// GetHeartbeat 獲取心跳間隔，未設置時返回 10s
+func (c *TUICConfig) GetHeartbeat() string {
+	if c.Heartbeat != "" {
+		return c.Heartbeat
+	}
+	return DefaultTUICHeartbeat
+}
+
+// GetALPN 獲取 ALPN 列表，未設置時返回 h2, http/1.1
+func (c *AnyTLSConfig) GetALPN() []string {
+	if len(c.ALPN) > 0 {
+		return c.ALPN
+	}
+	return []string{"h2", "http/1.1"}
+}
+
+// GetUsername 獲取用戶名，未設置時返回 prism
+func (c *AnyTLSConfig) GetUsername() string {
+	if c.Username != "" {
+		return c.Username
+	}
+	return DefaultAnyTLSUsername
+}
+
+// GetUsername 獲取用戶名，未設置時返回 prism
+func (c *AnyTLSRealityConfig) GetUsername() string {
+	if c.Username != "" {
+		return c.Username
+	}
+	return DefaultAnyTLSUsername
+}
+
+// GetUsername 獲取用戶名，未設置時返回 prism
+func (c *NaiveConfig) GetUsername() string {
+	if c.Username != "" {
+		return c.Username
+	}
+	return DefaultNaiveUsername
+}
+
+// GetSSMethod 獲取 Shadowsocks 加密方式，未設置時返回 2022-blake3-aes-128-gcm
+func (c *ShadowTLSConfig) GetSSMethod() string {
+	if c.SSMethod != "" {
+		return c.SSMethod
+	}
+	return DefaultShadowTLSSSMethod
+}
+
+// GetDetourPort 獲取本地轉交端口，未設置時返回 10000
+func (c *ShadowTLSConfig) GetDetourPort() int {
+	if c.DetourPort > 0 {
+		return c.DetourPort
+	}
+	return DefaultShadowTLSDetourPort
+}
+
+// IsStrictMode 是否啟用嚴格模式，未設置時默認開啟
+func (c *ShadowTLSConfig) IsStrictMode() bool {
+	return c.StrictMode == nil || *c.StrictMode
+}
+
+// GetSSPassword 獲取 Shadowsocks 密碼，未設置時沿用全局密碼
+// 2022 加密方式要求固定長度的密鑰，長度不符時轉換為合法密鑰
+func (c *ShadowTLSConfig) GetSSPassword(global string) string {
+	password := global
+	if c.SSPassword != "" {
+		password = c.SSPassword
+	}
+	return DeriveSSKey(c.GetSSMethod(), password)
+}
+
+// GetCDNPort 獲取客戶端連接端口，未設置時與監聽端口相同
+func (c *CDNConfig) GetCDNPort() int {
+	if c.CDNPort > 0 {
+		return c.CDNPort
+	}
+	return c.Port
+}
+
+// GetHost 獲取 Host 頭，未設置時使用 CDN 域名，再退回 SNI
+func (c *CDNConfig) GetHost() string {
+	switch {
+	case c.Host != "":
+		return c.Host
+	case c.CDNHost != "" && net.ParseIP(c.CDNHost) == nil:
+		return c.CDNHost
+	}
+	return c.SNI
+}
+
+// validateOptions 驗證實例的高級字段取值
+func (inst *ProtocolInstance) validateOptions() error {
+	if c := inst.config(); c != nil {
+		if err := c.validateOptions(); err != nil {
+			return fmt.Errorf("協議實例 %s: %w", inst.Tag, err)
+		}
+	}
+	if sec, ok := inst.Section(); ok && sec.NeedsReality() && *sec.ShortID != "" && !isHexShortID(*sec.ShortID) {
+		return fmt.Errorf("協議實例 %s: short_id 必須為最長 16 位的十六進制字符串", inst.Tag)
+	}
+	return nil
+}
+
+// isHexShortID Reality short_id 為偶數長度、最長 16 位的十六進制字符串
+func isHexShortID(s string) bool {
+	if len(s) > 16 || len(s)%2 != 0 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString 列表中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 可在 TUI 中單獨編輯的協議高級字段
+const (
+	OptionALPN              = "alpn"
+	OptionCongestionControl = "congestion_control"
+	OptionZeroRTT           = "zero_rtt"
+	OptionUsername          = "username"
+	OptionShortID           = "short_id"
+	OptionSSMethod          = "ss_method"
+	OptionSSPassword        = "ss_password"
+	OptionDetourPort        = "detour_port"
+	OptionStrictMode        = "strict_mode"
+	OptionCDNProxy          = "proxy"
+	OptionCDNTransport      = "transport"
+	OptionCDNPath           = "path"
+	OptionCDNHost           = "host"
+	OptionCDNServiceName    = "service_name"
+	OptionCDNAddress        = "cdn_host"
+	OptionCDNPort           = "cdn_port"
+	OptionNaiveQUIC         = "quic"
+	OptionSSUserAdd         = "ss_user_add"
+	OptionSSRelayAdd        = "ss_relay_add"
+	OptionSSRemove          = "ss_remove"
+	OptionDecoy             = "decoy"
+	OptionBandwidth         = "bandwidth"
+	OptionUDPRelayMode      = "udp_relay_mode"
+	OptionAuthTimeout       = "auth_timeout"
+	OptionHeartbeat         = "heartbeat"
+	OptionBrutal            = "brutal"
+)
+
+// SetOption 修改實例的高級字段，空值恢復默認
+// 字段不適用於該協議類型時返回錯誤，取值合法性由 ValidateInstances 統一檢查
+func (inst *ProtocolInstance) SetOption(key, value string) error {
+	value = strings.TrimSpace(value)
+	unsupported := fmt.Errorf("協議實例 %s (%s) 不支持字段 %s", inst.Tag, inst.Type, key)
+
+	switch key {
+	case OptionALPN:
+		switch {
+		case inst.Hysteria2 != nil:
+			inst.Hysteria2.ALPN = strings.Join(ParseALPN(value), ",")
+		case inst.TUIC != nil:
+			inst.TUIC.ALPN = ParseALPN(value)
+		case inst.AnyTLS != nil:
+			inst.AnyTLS.ALPN = ParseALPN(value)
+		default:
+			return unsupported
+		}
+	case OptionCongestionControl:
+		if inst.TUIC == nil {
+			return unsupported
+		}
+		inst.TUIC.CongestionControl = strings.ToLower(value)
+	case OptionZeroRTT:
+		if inst.TUIC == nil {
+			return unsupported
+		}
+		on, err := parseSwitch(value)
+		if err != nil {
+			return err
+		}
+		inst.TUIC.ZeroRTTHandshake = on
+	case OptionUDPRelayMode, OptionAuthTimeout, OptionHeartbeat:
+		c := inst.TUIC
+		if c == nil {
+			return unsupported
+		}
+		switch key {
+		case OptionUDPRelayMode:
+			c.UDPRelayMode = strings.ToLower(value)
+		case OptionAuthTimeout:
+			c.AuthTimeout = normalizeDuration(value)
+		case OptionHeartbeat:
+			c.Heartbeat = normalizeDuration(value)
+		}
+	case OptionUsername:
+		switch {
+		case inst.AnyTLS != nil:
+			inst.AnyTLS.Username = value
+		case inst.AnyTLSReality != nil:
+			inst.AnyTLSReality.Username = value
+		case inst.Naive != nil:
+			inst.Naive.Username = value
+		default:
+			return unsupported
+		}
+	case OptionNaiveQUIC:
+		if inst.Naive == nil {
+			return unsupported
+		}
+		on, err := parseSwitch(value)
+		if err != nil {
+			return err
+		}
+		inst.Naive.QUIC = on
+	case OptionShortID:
+		value = strings.ToLower(value)
+		switch {
+		case inst.RealityVision != nil:
+			inst.RealityVision.ShortID = value
+		case inst.RealityGRPC != nil:
+			inst.RealityGRPC.ShortID = value
+		case inst.AnyTLSReality != nil:
+			inst.AnyTLSReality.ShortID = value
+		default:
+			return unsupported
+		}
+	case OptionSSMethod, OptionSSPassword, OptionSSUserAdd, OptionSSRelayAdd, OptionSSRemove:
+		if inst.Shadowsocks != nil {
+			return inst.Shadowsocks.setOption(key, value)
+		}
+		if key != OptionSSMethod && key != OptionSSPassword {
+			return unsupported
+		}
+		fallthrough
+	case OptionDetourPort, OptionStrictMode:
+		c := inst.ShadowTLS
+		if c == nil {
+			return unsupported
+		}
+		switch key {
+		case OptionSSMethod:
+			c.SSMethod = strings.ToLower(value)
+		case OptionSSPassword:
+			c.SSPassword = value
+		case OptionDetourPort:
+			if value == "" {
+				c.DetourPort = 0
+				break
+			}
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("無效的轉交端口: %s", value)
+			}
+			c.DetourPort = port
+		case OptionStrictMode:
+			on, err := parseSwitch(value)
+			if err != nil {
+				return err
+			}
+			c.StrictMode = &on
+		}
+	case OptionCDNProxy, OptionCDNTransport, OptionCDNPath, OptionCDNHost,
+		OptionCDNServiceName, OptionCDNAddress, OptionCDNPort:
+		c := inst.CDN
+		if c == nil {
+			return unsupported
+		}
+		switch key {
+		case OptionCDNProxy:
+			c.Proxy = strings.ToLower(value)
+		case OptionCDNTransport:
+			c.Transport = strings.ToLower(value)
+		case OptionCDNPath:
+			if value != "" && !strings.HasPrefix(value, "/") {
+				value = "/" + value
+			}
+			c.Path = value
+		case OptionCDNHost:
+			c.Host = value
+		case OptionCDNServiceName:
+			c.ServiceName = value
+		case OptionCDNAddress:
+			c.CDNHost = value
+		case OptionCDNPort:
+			if value == "" {
+				c.CDNPort = 0
+				break
+			}
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("無效的 CDN 端口: %s", value)
+			}
+			c.CDNPort = port
+		}
+		// 協議、傳輸、路徑與服務名留空時重新生成默認值
+		inst.fillDefaults("", "")
+	case OptionBandwidth:
+		if inst.Hysteria2 == nil {
+			return unsupported
+		}
+		up, down, err := ParseBandwidth(value)
+		if err != nil {
+			return err
+		}
+		inst.Hysteria2.UpMbps, inst.Hysteria2.DownMbps = up, down
+	case OptionBrutal:
+		if inst.Hysteria2 == nil {
+			return unsupported
+		}
+		// 空值恢復默認 (開啟 Brutal)
+		on := true
+		if value != "" {
+			var err error
+			if on, err = parseSwitch(value); err != nil {
+				return err
+			}
+		}
+		inst.Hysteria2.IgnoreClientBandwidth = !on
+	case OptionDecoy:
+		decoy, err := ParseDecoy(value)
+		if err != nil {
+			return err
+		}
+		switch {
+		case inst.Hysteria2 != nil:
+			inst.Hysteria2.Decoy = decoy
+		case inst.CDN != nil:
+			inst.CDN.Decoy = decoy
+		case inst.Naive != nil:
+			// sing-box 的 naive 入站直接斷開未認證的請求，沒有回落選項
+			return fmt.Errorf("協議實例 %s: NaiveProxy 入站不支持偽裝網站回落 (sing-box 未提供該功能)", inst.Tag)
+		default:
+			return unsupported
+		}
+	default:
+		return fmt.Errorf("未知的協議字段: %s", key)
+	}
+	return nil
+}
+
+// 未設置帶寬時 Hysteria2 使用的默認值 (Mbps)
+const DefaultHy2BandwidthMbps = 100
+
+// GetBandwidth 返回上下行帶寬 (Mbps)，未設置時為默認值
+func (c *Hysteria2Config) GetBandwidth() (up, down int) {
+	up, down = c.UpMbps, c.DownMbps
+	if up == 0 {
+		up = DefaultHy2BandwidthMbps
+	}
+	if down == 0 {
+		down = DefaultHy2BandwidthMbps
+	}
+	return up, down
+}
+
+// ParseBandwidth 解析「上行 [下行]」帶寬 (Mbps)，只輸入一個值時上下行相同，空值恢復默認
+func ParseBandwidth(value string) (up, down int, err error) {
+	fields := strings.Fields(strings.ReplaceAll(value, "/", " "))
+	if len(fields) == 0 {
+		return 0, 0, nil
+	}
+	if len(fields) > 2 {
+		return 0, 0, fmt.Errorf("格式錯誤，應為: 上行 [下行] (Mbps)")
+	}
+	vals := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(f), "mbps"))
+		if err != nil || n < 1 || n > 100000 {
+			return 0, 0, fmt.Errorf("無效的帶寬: %s (應為 1-100000 Mbps)", f)
+		}
+		vals[i] = n
+	}
+	if len(vals) == 1 {
+		return vals[0], vals[0], nil
+	}
+	return vals[0], vals[1], nil
+}
+
+// normalizeDuration 純數字按秒處理，如 10 -> 10s
+func normalizeDuration(value string) string {
+	if _, err := strconv.Atoi(value); err == nil {
+		return value + "s"
+	}
+	return strings.ToLower(value)
+}
+
+// parseSwitch 解析開關取值，空值視為關閉
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "true", "1", "yes":
+		return true, nil
+	case "", "off", "false", "0", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("無效的開關取值: %s (應為 on/off)", value)
+}
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// protocolConfig 協議配置的通用行為
+// 實例的公共字段視圖、敏感字段、默認值與驗證均經此分派，不再按協議類型分支
+type protocolConfig interface {
+	// section 填充公共字段視圖 (傳輸層協議與各字段指針)
+	section(s *ProtocolSection)
+	// secretFields 返回需要加密存儲的字段
+	secretFields() []*string
+	// fillDefaults 填充憑據默認值
+	fillDefaults(uuid, password string)
+	// validateOptions 驗證協議特有字段
+	validateOptions() error
+}
+
+// protocolSchema 協議類型與實例中配置字段的對應
+type protocolSchema struct {
+	typ string
+	get func(inst *ProtocolInstance) protocolConfig // 字段為空時返回 nil
+	new func(inst *ProtocolInstance)
+}
+
+// protocolSchemas 已知的協議類型 (順序與協議編號一致)
+// 新增協議時在此登記配置字段，並為配置類型實現 protocolConfig
+var protocolSchemas = []protocolSchema{
+	{
+		typ: ProtocolTypeRealityVision,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.RealityVision) },
+		new: func(inst *ProtocolInstance) { inst.RealityVision = &RealityVisionConfig{} },
+	},
+	{
+		typ: ProtocolTypeRealityGRPC,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.RealityGRPC) },
+		new: func(inst *ProtocolInstance) { inst.RealityGRPC = &RealityGRPCConfig{} },
+	},
+	{
+		typ: ProtocolTypeHysteria2,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.Hysteria2) },
+		new: func(inst *ProtocolInstance) { inst.Hysteria2 = &Hysteria2Config{} },
+	},
+	{
+		typ: ProtocolTypeTUIC,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.TUIC) },
+		new: func(inst *ProtocolInstance) { inst.TUIC = &TUICConfig{} },
+	},
+	{
+		typ: ProtocolTypeAnyTLS,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.AnyTLS) },
+		new: func(inst *ProtocolInstance) { inst.AnyTLS = &AnyTLSConfig{} },
+	},
+	{
+		typ: ProtocolTypeAnyTLSReality,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.AnyTLSReality) },
+		new: func(inst *ProtocolInstance) { inst.AnyTLSReality = &AnyTLSRealityConfig{} },
+	},
+	{
+		typ: ProtocolTypeShadowTLS,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.ShadowTLS) },
+		new: func(inst *ProtocolInstance) { inst.ShadowTLS = &ShadowTLSConfig{} },
+	},
+	{
+		typ: ProtocolTypeCDN,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.CDN) },
+		new: func(inst *ProtocolInstance) { inst.CDN = &CDNConfig{} },
+	},
+	{
+		typ: ProtocolTypeShadowsocks,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.Shadowsocks) },
+		new: func(inst *ProtocolInstance) { inst.Shadowsocks = &ShadowsocksConfig{} },
+	},
+	{
+		typ: ProtocolTypeNaive,
+		get: func(inst *ProtocolInstance) protocolConfig { return optional(inst.Naive) },
+		new: func(inst *ProtocolInstance) { inst.Naive = &NaiveConfig{} },
+	},
+}
+
+// optional 將可能為 nil 的配置指針轉換為接口，避免接口包裹 nil 指針
+func optional[T any, P interface {
+	*T
+	protocolConfig
+}](c P) protocolConfig {
+	if c == nil {
+		return nil
+	}
+	return c
+}
+
+// lookupSchema 按類型查找協議配置描述
+func lookupSchema(protocolType string) (protocolSchema, bool) {
+	for _, s := range protocolSchemas {
+		if s.typ == protocolType {
+			return s, true
+		}
+	}
+	return protocolSchema{}, false
+}
+
+// config 返回實例中與類型對應的協議配置，缺失時返回 nil
+func (inst *ProtocolInstance) config() protocolConfig {
+	s, ok := lookupSchema(inst.Type)
+	if !ok {
+		return nil
+	}
+	return s.get(inst)
+}
+
+// configs 返回實例中所有非空的協議配置 (不論類型)
+func (inst *ProtocolInstance) configs() []protocolConfig {
+	var configs []protocolConfig
+	for _, s := range protocolSchemas {
+		if c := s.get(inst); c != nil {
+			configs = append(configs, c)
+		}
+	}
+	return configs
+}
+
+// ==================== Reality Vision ====================
+
+func (c *RealityVisionConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.PublicKey, s.ShortID = &c.PublicKey, &c.ShortID
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *RealityVisionConfig) secretFields() []*string { return []*string{&c.PrivateKey} }
+
+func (c *RealityVisionConfig) fillDefaults(uuid, password string) {}
+
+func (c *RealityVisionConfig) validateOptions() error { return nil }
+
+// ==================== Reality gRPC ====================
+
+func (c *RealityGRPCConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.PublicKey, s.ShortID = &c.PublicKey, &c.ShortID
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *RealityGRPCConfig) secretFields() []*string { return []*string{&c.PrivateKey} }
+
+func (c *RealityGRPCConfig) fillDefaults(uuid, password string) {}
+
+func (c *RealityGRPCConfig) validateOptions() error { return nil }
+
+// ==================== Hysteria2 ====================
+
+func (c *Hysteria2Config) section(s *ProtocolSection) {
+	s.Network = "udp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.CertMode, s.CertDomain = &c.CertMode, &c.CertDomain
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *Hysteria2Config) secretFields() []*string { return []*string{&c.Password} }
+
+func (c *Hysteria2Config) fillDefaults(uuid, password string) {
+	fillString(&c.Password, password)
+}
+
+func (c *Hysteria2Config) validateOptions() error {
+	if err := c.ValidatePortHopping(); err != nil {
+		return err
+	}
+	if err := c.Decoy.validate(DecoyModeFile, DecoyModeProxy, DecoyModeString); err != nil {
+		return err
+	}
+	if c.Decoy.Enabled() && c.Obfs != "" {
+		return fmt.Errorf("開啟混淆時偽裝網站不會生效，請先關閉混淆")
+	}
+	return nil
+}
+
+// ==================== TUIC ====================
+
+func (c *TUICConfig) section(s *ProtocolSection) {
+	s.Network = "udp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.CertMode, s.CertDomain = &c.CertMode, &c.CertDomain
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *TUICConfig) secretFields() []*string { return []*string{&c.Password} }
+
+func (c *TUICConfig) fillDefaults(uuid, password string) {
+	fillString(&c.UUID, uuid)
+	fillString(&c.Password, password)
+}
+
+func (c *TUICConfig) validateOptions() error {
+	if c.CongestionControl != "" && !containsString(TUICCongestionControls, c.CongestionControl) {
+		return fmt.Errorf("不支持的擁塞控制算法 %q (可選: %s)",
+			c.CongestionControl, strings.Join(TUICCongestionControls, ", "))
+	}
+	if c.UDPRelayMode != "" && !containsString(TUICUDPRelayModes, c.UDPRelayMode) {
+		return fmt.Errorf("不支持的 UDP 轉發模式 %q (可選: %s)",
+			c.UDPRelayMode, strings.Join(TUICUDPRelayModes, ", "))
+	}
+	for name, v := range map[string]string{"auth_timeout": c.AuthTimeout, "heartbeat": c.Heartbeat} {
+		if d, err := time.ParseDuration(v); v != "" && (err != nil || d <= 0) {
+			return fmt.Errorf("%s 格式錯誤: %s (應為 '10s' 形式)", name, v)
+		}
+	}
+	return nil
+}
+
+// ==================== AnyTLS ====================
+
+func (c *AnyTLSConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.CertMode, s.CertDomain = &c.CertMode, &c.CertDomain
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *AnyTLSConfig) secretFields() []*string { return []*string{&c.Password} }
+
+func (c *AnyTLSConfig) fillDefaults(uuid, password string) {
+	fillString(&c.Username, "prism")
+	fillString(&c.Password, password)
+}
+
+func (c *AnyTLSConfig) validateOptions() error { return validatePadding(c.PaddingScheme) }
+
+// ==================== AnyTLS Reality ====================
+
+func (c *AnyTLSRealityConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.PublicKey, s.ShortID = &c.PublicKey, &c.ShortID
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *AnyTLSRealityConfig) secretFields() []*string {
+	return []*string{&c.PrivateKey, &c.Password}
+}
+
+func (c *AnyTLSRealityConfig) fillDefaults(uuid, password string) {
+	fillString(&c.Username, "prism")
+	fillString(&c.Password, password)
+}
+
+func (c *AnyTLSRealityConfig) validateOptions() error { return validatePadding(c.PaddingScheme) }
+
+// ==================== ShadowTLS ====================
+
+func (c *ShadowTLSConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *ShadowTLSConfig) secretFields() []*string {
+	return []*string{&c.Password, &c.SSPassword}
+}
+
+func (c *ShadowTLSConfig) fillDefaults(uuid, password string) {
+	fillString(&c.Password, password)
+	fillString(&c.SSPassword, password)
+}
+
+func (c *ShadowTLSConfig) validateOptions() error {
+	if c.SSMethod != "" && !containsString(ShadowTLSSSMethods, c.SSMethod) {
+		return fmt.Errorf("不支持的加密方式 %q", c.SSMethod)
+	}
+	if c.GetDetourPort() == c.Port {
+		return fmt.Errorf("detour_port 不能與監聽端口相同")
+	}
+	return nil
+}
+
+// ==================== CDN ====================
+
+func (c *CDNConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.CertMode, s.CertDomain = &c.CertMode, &c.CertDomain
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *CDNConfig) secretFields() []*string { return []*string{&c.Password} }
+
+func (c *CDNConfig) fillDefaults(uuid, password string) {
+	fillString(&c.UUID, uuid)
+	fillString(&c.Password, password)
+	fillString(&c.Proxy, CDNProxyVLESS)
+	fillString(&c.Transport, CDNTransportWS)
+	fillString(&c.Path, "/"+generateShortID())
+	fillString(&c.ServiceName, generateShortID())
+	if c.Port == 0 {
+		c.Port = DefaultCDNPort
+	}
+}
+
+func (c *CDNConfig) validateOptions() error {
+	if !containsString(CDNProxies, c.Proxy) {
+		return fmt.Errorf("不支持的代理協議 %q (可選: %s)", c.Proxy, strings.Join(CDNProxies, ", "))
+	}
+	if !containsString(CDNTransports, c.Transport) {
+		return fmt.Errorf("不支持的傳輸方式 %q (可選: %s)", c.Transport, strings.Join(CDNTransports, ", "))
+	}
+	if c.Transport == CDNTransportGRPC {
+		if c.ServiceName == "" || strings.ContainsAny(c.ServiceName, "/ ") {
+			return fmt.Errorf("gRPC 服務名不能為空且不能包含 / 或空格")
+		}
+	} else if !strings.HasPrefix(c.Path, "/") || strings.ContainsAny(c.Path, " ?#") {
+		return fmt.Errorf("路徑必須以 / 開頭且不能包含空格、? 或 #")
+	}
+	if c.CDNPort < 0 || c.CDNPort > 65535 {
+		return fmt.Errorf("無效的 CDN 端口 %d", c.CDNPort)
+	}
+	if c.Decoy.Enabled() {
+		if c.Proxy != CDNProxyTrojan {
+			return fmt.Errorf("僅 Trojan 支持偽裝網站回落")
+		}
+		if err := c.Decoy.validate(DecoyModeFile, DecoyModeProxy); err != nil {
+			return err
+		}
+		if _, _, err := c.Decoy.FallbackAddress(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ==================== Shadowsocks ====================
+
+func (c *ShadowsocksConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	// Shadowsocks 不使用 TLS，SNI 視圖指向臨時變量，寫入被忽略
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, new(string)
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *ShadowsocksConfig) secretFields() []*string {
+	fields := []*string{&c.Password}
+	for i := range c.Users {
+		fields = append(fields, &c.Users[i].Password)
+	}
+	for i := range c.Relays {
+		fields = append(fields, &c.Relays[i].Password)
+	}
+	return fields
+}
+
+func (c *ShadowsocksConfig) fillDefaults(uuid, password string) {
+	// Shadowsocks 2022 的密鑰長度由加密方式決定，不沿用全局密碼
+	fillString(&c.Method, DefaultShadowsocksMethod)
+	fillString(&c.Password, GenerateSSKey(c.Method))
+	for i := range c.Users {
+		fillString(&c.Users[i].Password, GenerateSSKey(c.Method))
+	}
+}
+
+func (c *ShadowsocksConfig) validateOptions() error { return c.validate() }
+
+// ==================== NaiveProxy ====================
+
+func (c *NaiveConfig) section(s *ProtocolSection) {
+	s.Network = "tcp"
+	s.Enabled, s.Port, s.SNI = &c.Enabled, &c.Port, &c.SNI
+	s.CertMode, s.CertDomain = &c.CertMode, &c.CertDomain
+	s.Routing, s.Firewall = &c.Routing, &c.Firewall
+}
+
+func (c *NaiveConfig) secretFields() []*string { return []*string{&c.Password} }
+
+func (c *NaiveConfig) fillDefaults(uuid, password string) {
+	fillString(&c.Username, DefaultNaiveUsername)
+	fillString(&c.Password, password)
+}
+
+func (c *NaiveConfig) validateOptions() error {
+	if strings.ContainsAny(c.Username, ": ") {
+		return fmt.Errorf("NaiveProxy 用戶名不能包含冒號或空格")
+	}
+	return nil
+}
+
+// fillString 字段為空時填充默認值
+func fillString(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+// ProtocolSection 協議實例中各協議共有字段的視圖
+// 指針均指向實例配置內部，可直接讀寫；協議沒有的字段為 nil
+type ProtocolSection struct {
+	Key     string // 協議類型 (YAML 鍵)
+	Tag     string // 實例標籤
+	Primary bool   // 是否為主實例
+	Network string // 監聽使用的傳輸層協議 (tcp / udp)
+
+	Enabled *bool
+	Port    *int
+	SNI     *string
+
+	// 使用本地證書的協議
+	CertMode   *string
+	CertDomain *string
+
+	// 使用 Reality 密鑰的協議
+	PublicKey *string
+	ShortID   *string
+
+	Routing  *InboundRouting
+	Firewall *InboundFirewall
+}
+
+// NeedsCert 協議是否使用本地證書 (自簽名 / ACME)
+func (s ProtocolSection) NeedsCert() bool {
+	return s.CertMode != nil && s.CertDomain != nil
+}
+
+// NeedsReality 協議是否使用 Reality 密鑰
+func (s ProtocolSection) NeedsReality() bool {
+	return s.PublicKey != nil && s.ShortID != nil
+}
+
+// Name 返回實例的顯示名稱：主實例使用協議類型，其餘使用標籤
+func (s ProtocolSection) Name() string {
+	if s.Primary {
+		return s.Key
+	}
+	return s.Tag
+}
+
+// Sections 返回所有協議實例的公共字段視圖 (按配置順序)
+func (p *ProtocolsConfig) Sections() []ProtocolSection {
+	var sections []ProtocolSection
+	for i := range p.Instances {
+		if s, ok := p.Instances[i].Section(); ok {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// Section 返回協議主實例的公共字段視圖，主實例不存在時返回 false
+func (p *ProtocolsConfig) Section(protocolType string) (ProtocolSection, bool) {
+	if !IsProtocolType(protocolType) {
+		return ProtocolSection{}, false
+	}
+	inst := p.Instance(DefaultProtocolTag(protocolType))
+	if inst == nil || inst.Type != protocolType {
+		return ProtocolSection{}, false
+	}
+	return inst.Section()
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestProtocolSectionsCoverAllProtocols 確保實例中每個協議配置字段都有對應的類型與公共字段視圖
+func TestProtocolSectionsCoverAllProtocols(t *testing.T) {
+	typ := reflect.TypeOf(ProtocolInstance{})
+	var keys []string
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Type.Kind() != reflect.Ptr {
+			continue
+		}
+		keys = append(keys, strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0])
+	}
+	if !reflect.DeepEqual(keys, ProtocolTypes()) {
+		t.Fatalf("協議類型 %v 與實例配置字段 %v 不一致", ProtocolTypes(), keys)
+	}
+
+	p := &ProtocolsConfig{}
+	if _, ok := p.Section(ProtocolTypeTUIC); ok || len(p.Instances) != 0 {
+		t.Fatal("訪問器不應創建主實例")
+	}
+	if err := p.EnsurePrimaries(); err != nil {
+		t.Fatalf("EnsurePrimaries: %v", err)
+	}
+	for _, key := range keys {
+		sec, ok := p.Section(key)
+		if !ok {
+			t.Errorf("缺少協議 %s 的視圖", key)
+			continue
+		}
+		if sec.Enabled == nil || sec.Port == nil || sec.SNI == nil || sec.Routing == nil || sec.Firewall == nil {
+			t.Errorf("%s 的公共字段不完整", key)
+		}
+		if sec.Network != "tcp" && sec.Network != "udp" {
+			t.Errorf("%s 的傳輸層協議無效: %q", key, sec.Network)
+		}
+		if sec.Tag != DefaultProtocolTag(key) || !sec.Primary {
+			t.Errorf("%s 的主實例標籤錯誤: %s", key, sec.Tag)
+		}
+	}
+	if len(p.Sections()) != len(keys) {
+		t.Errorf("應為每個協議創建一個主實例，實際 %d 個", len(p.Sections()))
+	}
+}
+
+// TestProtocolSectionWritesThrough 通過視圖修改應直接作用於配置
+func TestProtocolSectionWritesThrough(t *testing.T) {
+	p := &ProtocolsConfig{}
+	if err := p.EnsurePrimaries(); err != nil {
+		t.Fatalf("EnsurePrimaries: %v", err)
+	}
+	sec, _ := p.Section("tuic")
+	*sec.Port = 8443
+	*sec.CertMode = "acme"
+	if p.TUIC().Port != 8443 || p.TUIC().CertMode != "acme" {
+		t.Errorf("視圖修改未生效: %+v", p.TUIC())
+	}
+	if !sec.NeedsCert() || sec.NeedsReality() {
+		t.Error("TUIC 應使用本地證書且不使用 Reality 密鑰")
+	}
+
+	rv, _ := p.Section("reality_vision")
+	if rv.NeedsCert() || !rv.NeedsReality() {
+		t.Error("Reality Vision 應使用 Reality 密鑰")
+	}
+}
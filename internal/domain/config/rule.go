@@ -0,0 +1,464 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 路由規則動作
+const (
+	RuleActionRoute     = "route"      // 轉發到指定出站
+	RuleActionReject    = "reject"     // 拒絕連接
+	RuleActionHijackDNS = "hijack-dns" // 劫持 DNS 請求
+)
+
+// 內置出站標籤
+const (
+	OutboundDirect = "direct"
+	OutboundWARP   = "warp-out"
+	OutboundIPv6   = "ipv6-out"
+
+	// Socks5OutboundTag Socks5 落地機對應的自定義出站標籤
+	Socks5OutboundTag = "socks5-out"
+)
+
+// 可匹配的嗅探協議
+var sniffProtocols = map[string]bool{
+	"http": true, "tls": true, "quic": true, "dns": true,
+	"stun": true, "bittorrent": true, "dtls": true, "ssh": true, "rdp": true,
+}
+
+var portRangePattern = regexp.MustCompile(`^\d*:\d*$`)
+
+// RoutingRule 路由規則
+// 同一規則內不同類型的匹配條件為「與」關係，同類型多個值為「或」關係
+type RoutingRule struct {
+	Name     string `yaml:"name,omitempty"`
+	Priority int    `yaml:"priority"` // 數值越小越先匹配
+	Disabled bool   `yaml:"disabled,omitempty"`
+
+	Domain        []string `yaml:"domain,omitempty"`
+	DomainSuffix  []string `yaml:"domain_suffix,omitempty"`
+	DomainKeyword []string `yaml:"domain_keyword,omitempty"`
+	DomainRegex   []string `yaml:"domain_regex,omitempty"`
+	RuleSet       []string `yaml:"rule_set,omitempty"` // geosite-xxx / geoip-xxx
+	IPCIDR        []string `yaml:"ip_cidr,omitempty"`
+	IPIsPrivate   bool     `yaml:"ip_is_private,omitempty"` // 匹配私有地址 (局域網、回環等)
+	Port          []int    `yaml:"port,omitempty"`
+	PortRange     []string `yaml:"port_range,omitempty"` // 例如 1000:2000
+	Inbound       []string `yaml:"inbound,omitempty"`    // 入站標籤
+	Protocol      []string `yaml:"protocol,omitempty"`   // 嗅探協議: http / tls / quic ...
+	Network       string   `yaml:"network,omitempty"`    // tcp / udp
+
+	Action   string `yaml:"action,omitempty"` // 默認 route
+	Outbound string `yaml:"outbound,omitempty"`
+}
+
+// GetAction 返回規則動作，未設置時為 route
+func (r *RoutingRule) GetAction() string {
+	if r.Action == "" {
+		return RuleActionRoute
+	}
+	return r.Action
+}
+
+// HasMatcher 是否至少包含一個匹配條件
+func (r *RoutingRule) HasMatcher() bool {
+	return len(r.Domain) > 0 || len(r.DomainSuffix) > 0 || len(r.DomainKeyword) > 0 ||
+		len(r.DomainRegex) > 0 || len(r.RuleSet) > 0 || len(r.IPCIDR) > 0 || r.IPIsPrivate ||
+		len(r.Port) > 0 || len(r.PortRange) > 0 || len(r.Inbound) > 0 ||
+		len(r.Protocol) > 0 || r.Network != ""
+}
+
+// HasDomainMatcher 是否包含域名類匹配條件 (需要 DNS 解析/嗅探)
+func (r *RoutingRule) HasDomainMatcher() bool {
+	return len(r.Domain) > 0 || len(r.DomainSuffix) > 0 || len(r.DomainKeyword) > 0 ||
+		len(r.DomainRegex) > 0 || len(r.RuleSet) > 0
+}
+
+// Target 返回規則目標的描述 (出站標籤或動作)
+func (r *RoutingRule) Target() string {
+	if r.GetAction() == RuleActionRoute {
+		return r.Outbound
+	}
+	return r.GetAction()
+}
+
+// Validate 驗證規則字段 (不檢查出站是否存在)
+func (r *RoutingRule) Validate() error {
+	name := r.Name
+	if name == "" {
+		name = fmt.Sprintf("#%d", r.Priority)
+	}
+
+	if !r.HasMatcher() {
+		return fmt.Errorf("規則 %s 缺少匹配條件", name)
+	}
+
+	switch r.GetAction() {
+	case RuleActionRoute:
+		if r.Outbound == "" {
+			return fmt.Errorf("規則 %s 缺少目標出站", name)
+		}
+	case RuleActionReject, RuleActionHijackDNS:
+		if r.Outbound != "" {
+			return fmt.Errorf("規則 %s 的動作 %s 不能指定出站", name, r.Action)
+		}
+	default:
+		return fmt.Errorf("規則 %s 動作無效: %s", name, r.Action)
+	}
+
+	for _, re := range r.DomainRegex {
+		if _, err := regexp.Compile(re); err != nil {
+			return fmt.Errorf("規則 %s 正則表達式無效: %s", name, re)
+		}
+	}
+	for _, rs := range r.RuleSet {
+		if !ruleSetTagPattern.MatchString(rs) {
+			return fmt.Errorf("規則 %s 規則集名稱無效: %s", name, rs)
+		}
+	}
+	for _, cidr := range r.IPCIDR {
+		if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+			return fmt.Errorf("規則 %s IP 段無效: %s", name, cidr)
+		}
+	}
+	for _, p := range r.Port {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("規則 %s 端口無效: %d", name, p)
+		}
+	}
+	for _, pr := range r.PortRange {
+		if !portRangePattern.MatchString(pr) || pr == ":" {
+			return fmt.Errorf("規則 %s 端口範圍無效: %s", name, pr)
+		}
+	}
+	for _, p := range r.Protocol {
+		if !sniffProtocols[p] {
+			return fmt.Errorf("規則 %s 協議無效: %s", name, p)
+		}
+	}
+	if r.Network != "" && r.Network != "tcp" && r.Network != "udp" {
+		return fmt.Errorf("規則 %s 網絡類型無效: %s", name, r.Network)
+	}
+
+	return nil
+}
+
+// SortedRules 返回按優先級排序的已啟用規則 (優先級相同時保持配置順序)
+func (r *RoutingConfig) SortedRules() []RoutingRule {
+	var rules []RoutingRule
+	for _, i := range r.RuleOrder() {
+		if !r.Rules[i].Disabled {
+			rules = append(rules, r.Rules[i])
+		}
+	}
+	return rules
+}
+
+// RuleOrder 返回按優先級排序後的規則下標 (包含已禁用規則)
+func (r *RoutingConfig) RuleOrder() []int {
+	order := make([]int, len(r.Rules))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return r.Rules[order[a]].Priority < r.Rules[order[b]].Priority
+	})
+	return order
+}
+
+// NextRulePriority 返回新規則的默認優先級 (排在現有規則之後)
+func (r *RoutingConfig) NextRulePriority() int {
+	next := 100
+	for _, rule := range r.Rules {
+		if rule.Priority >= next {
+			next = rule.Priority + 10
+		}
+	}
+	return next
+}
+
+// ValidateRules 驗證所有路由規則及其引用的出站
+func (r *RoutingConfig) ValidateRules() error {
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if rule.GetAction() == RuleActionRoute && !r.IsRouteTarget(rule.Outbound) {
+			return fmt.Errorf("規則引用了不存在的出站: %s", rule.Outbound)
+		}
+	}
+
+	if r.Final != "" && !r.IsRouteTarget(r.Final) {
+		return fmt.Errorf("默認出站不存在: %s", r.Final)
+	}
+
+	return nil
+}
+
+// IsRouteTarget 判斷標籤是否可作為路由規則的目標出站
+func (r *RoutingConfig) IsRouteTarget(tag string) bool {
+	switch tag {
+	case OutboundDirect, OutboundWARP, OutboundIPv6:
+		return true
+	}
+	return r.FindOutbound(tag) != nil
+}
+
+// IsOutboundAvailable 判斷出站在當前配置下是否會被生成
+func (r *RoutingConfig) IsOutboundAvailable(tag string) bool {
+	switch tag {
+	case OutboundDirect:
+		return true
+	case OutboundWARP:
+		return r.WARP.Enabled
+	case OutboundIPv6:
+		return r.IPv6Split.Enabled
+	}
+	return r.FindOutbound(tag) != nil
+}
+
+// DomainsFor 返回以完整域名方式分流到指定出站的所有域名
+func (r *RoutingConfig) DomainsFor(outbound string) []string {
+	var domains []string
+	for _, rule := range r.Rules {
+		if rule.GetAction() == RuleActionRoute && rule.Outbound == outbound {
+			domains = append(domains, rule.Domain...)
+		}
+	}
+	return domains
+}
+
+// AddDomainRule 將域名分流到指定出站
+// 優先追加到同名的域名規則中，不存在時新建一條規則
+func (r *RoutingConfig) AddDomainRule(outbound, domain string) error {
+	name := outbound + "-domains"
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.Name != name {
+			continue
+		}
+		for _, d := range rule.Domain {
+			if d == domain {
+				return fmt.Errorf("域名已存在")
+			}
+		}
+		rule.Domain = append(rule.Domain, domain)
+		return nil
+	}
+
+	r.Rules = append(r.Rules, RoutingRule{
+		Name:     name,
+		Priority: r.NextRulePriority(),
+		Domain:   []string{domain},
+		Outbound: outbound,
+	})
+	return nil
+}
+
+// SetSocks5Server 設置 Socks5 落地機地址，對應的自定義出站不存在時新建
+func (r *RoutingConfig) SetSocks5Server(server string, port int) error {
+	if out := r.FindOutbound(Socks5OutboundTag); out != nil {
+		out.Server, out.Port = server, port
+		return out.Validate()
+	}
+
+	out := CustomOutbound{Tag: Socks5OutboundTag, Type: OutboundTypeSOCKS, Server: server, Port: port}
+	if err := out.Validate(); err != nil {
+		return err
+	}
+	r.Outbounds = append(r.Outbounds, out)
+	return nil
+}
+
+// SetSocks5Auth 設置 Socks5 落地機認證，用戶名為空時清除
+func (r *RoutingConfig) SetSocks5Auth(username, password string) error {
+	out := r.FindOutbound(Socks5OutboundTag)
+	if out == nil {
+		return fmt.Errorf("請先設置 Socks5 落地機地址")
+	}
+	out.Username, out.Password = username, password
+	return nil
+}
+
+// Socks5Enabled 判斷是否有流量經 Socks5 落地機轉發 (存在啓用的分流規則或作為默認出站)
+func (r *RoutingConfig) Socks5Enabled() bool {
+	if r.FindOutbound(Socks5OutboundTag) == nil {
+		return false
+	}
+	if r.Final == Socks5OutboundTag {
+		return true
+	}
+	for _, rule := range r.Rules {
+		if !rule.Disabled && rule.GetAction() == RuleActionRoute && rule.Outbound == Socks5OutboundTag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSocks5Enabled 啓用或禁用所有指向 Socks5 落地機的分流規則
+// 禁用時一併取消全局轉發，保留出站與域名以便重新啓用
+func (r *RoutingConfig) SetSocks5Enabled(enabled bool) error {
+	if enabled && r.FindOutbound(Socks5OutboundTag) == nil {
+		return fmt.Errorf("請先設置 Socks5 落地機地址")
+	}
+	for i := range r.Rules {
+		if r.Rules[i].GetAction() == RuleActionRoute && r.Rules[i].Outbound == Socks5OutboundTag {
+			r.Rules[i].Disabled = !enabled
+		}
+	}
+	if !enabled && r.Final == Socks5OutboundTag {
+		r.Final = ""
+	}
+	return nil
+}
+
+// SetSocks5Global 設置是否將默認出站切換為 Socks5 落地機
+func (r *RoutingConfig) SetSocks5Global(global bool) error {
+	if !global {
+		if r.Final == Socks5OutboundTag {
+			r.Final = ""
+		}
+		return nil
+	}
+	if r.FindOutbound(Socks5OutboundTag) == nil {
+		return fmt.Errorf("請先設置 Socks5 落地機地址")
+	}
+	r.Final = Socks5OutboundTag
+	return nil
+}
+
+// ParseRoutingRule 解析簡寫規則
+// 格式: "匹配類型=值1,值2 [匹配類型=值 ...] [priority=N] [name=名稱] => 目標"
+// 目標為出站標籤，或 reject / hijack-dns
+func ParseRoutingRule(spec string) (*RoutingRule, error) {
+	matchPart, target, ok := strings.Cut(spec, "=>")
+	if !ok {
+		return nil, fmt.Errorf("缺少目標，格式: 條件 => 出站")
+	}
+
+	rule := &RoutingRule{}
+	target = strings.TrimSpace(target)
+	switch target {
+	case "":
+		return nil, fmt.Errorf("目標不能為空")
+	case RuleActionReject, RuleActionHijackDNS:
+		rule.Action = target
+	default:
+		rule.Outbound = target
+	}
+
+	for _, token := range strings.Fields(matchPart) {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("條件格式錯誤: %s", token)
+		}
+		values := strings.Split(value, ",")
+		key = strings.ToLower(key)
+
+		switch key {
+		case "domain":
+			rule.Domain = append(rule.Domain, values...)
+		case "domain_suffix", "suffix":
+			rule.DomainSuffix = append(rule.DomainSuffix, values...)
+		case "domain_keyword", "keyword":
+			rule.DomainKeyword = append(rule.DomainKeyword, values...)
+		case "domain_regex", "regex":
+			rule.DomainRegex = append(rule.DomainRegex, values...)
+		case "rule_set", "geosite", "geoip":
+			for _, v := range values {
+				if key == "geoip" && !strings.HasPrefix(v, "geoip-") {
+					v = "geoip-" + v
+				}
+				tag := NormalizeRuleSetTag(v)
+				// sing-geoip 沒有 private 規則集，改用 ip_is_private 匹配
+				if tag == "geoip-private" {
+					rule.IPIsPrivate = true
+					continue
+				}
+				rule.RuleSet = append(rule.RuleSet, tag)
+			}
+		case "ip_cidr", "ip":
+			rule.IPCIDR = append(rule.IPCIDR, values...)
+		case "ip_is_private":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("ip_is_private 應為 true 或 false: %s", value)
+			}
+			rule.IPIsPrivate = b
+		case "port":
+			for _, v := range values {
+				if strings.Contains(v, ":") {
+					rule.PortRange = append(rule.PortRange, v)
+					continue
+				}
+				p, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, fmt.Errorf("端口無效: %s", v)
+				}
+				rule.Port = append(rule.Port, p)
+			}
+		case "inbound":
+			rule.Inbound = append(rule.Inbound, values...)
+		case "protocol":
+			rule.Protocol = append(rule.Protocol, values...)
+		case "network":
+			rule.Network = value
+		case "priority":
+			p, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("優先級無效: %s", value)
+			}
+			rule.Priority = p
+		case "name":
+			rule.Name = value
+		default:
+			return nil, fmt.Errorf("未知的匹配類型: %s", key)
+		}
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// String 返回規則的簡寫形式，與 ParseRoutingRule 互逆
+func (r RoutingRule) String() string {
+	var parts []string
+	add := func(key string, values []string) {
+		if len(values) > 0 {
+			parts = append(parts, key+"="+strings.Join(values, ","))
+		}
+	}
+
+	add("domain", r.Domain)
+	add("domain_suffix", r.DomainSuffix)
+	add("domain_keyword", r.DomainKeyword)
+	add("domain_regex", r.DomainRegex)
+	add("rule_set", r.RuleSet)
+	add("ip_cidr", r.IPCIDR)
+	if r.IPIsPrivate {
+		parts = append(parts, "ip_is_private=true")
+	}
+
+	var ports []string
+	for _, p := range r.Port {
+		ports = append(ports, strconv.Itoa(p))
+	}
+	add("port", append(ports, r.PortRange...))
+	add("inbound", r.Inbound)
+	add("protocol", r.Protocol)
+	if r.Network != "" {
+		parts = append(parts, "network="+r.Network)
+	}
+
+	return strings.Join(parts, " ") + " => " + r.Target()
+}
@@ -0,0 +1,213 @@
+package config
+
+import "testing"
+
+// TestParseRoutingRule 測試規則文本解析與還原
+func TestParseRoutingRule(t *testing.T) {
+	rule, err := ParseRoutingRule("suffix=openai.com,chatgpt.com geosite=netflix port=443,8000:9000 network=tcp priority=50 name=ai => warp-out")
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	if rule.Priority != 50 || rule.Name != "ai" || rule.Outbound != OutboundWARP {
+		t.Errorf("基本字段錯誤: %+v", rule)
+	}
+	if len(rule.DomainSuffix) != 2 || len(rule.RuleSet) != 1 || rule.RuleSet[0] != "geosite-netflix" {
+		t.Errorf("匹配條件錯誤: %+v", rule)
+	}
+	if len(rule.Port) != 1 || rule.Port[0] != 443 || len(rule.PortRange) != 1 {
+		t.Errorf("端口解析錯誤: %v %v", rule.Port, rule.PortRange)
+	}
+
+	again, err := ParseRoutingRule(rule.String())
+	if err != nil {
+		t.Fatalf("還原後解析失敗: %v", err)
+	}
+	if again.String() != rule.String() {
+		t.Errorf("String 與解析不互逆: %q != %q", again.String(), rule.String())
+	}
+
+	reject, err := ParseRoutingRule("protocol=bittorrent => reject")
+	if err != nil {
+		t.Fatalf("解析 reject 失敗: %v", err)
+	}
+	if reject.GetAction() != RuleActionReject || reject.Outbound != "" {
+		t.Errorf("reject 動作錯誤: %+v", reject)
+	}
+
+	// geoip-private 沒有對應的遠程規則集，轉為 ip_is_private
+	private, err := ParseRoutingRule("geoip=private => reject")
+	if err != nil {
+		t.Fatalf("解析 geoip=private 失敗: %v", err)
+	}
+	if !private.IPIsPrivate || len(private.RuleSet) != 0 || private.String() != "ip_is_private=true => reject" {
+		t.Errorf("私有地址規則錯誤: %+v", private)
+	}
+
+	for _, bad := range []string{
+		"ip_is_private=maybe => direct", // 非法布爾值
+		"domain=example.com",            // 缺少目標
+		"=> direct",                     // 缺少匹配條件
+		"protocol=foo => direct",        // 未知協議
+		"ip=999.0.0.1/8 => direct",      // 非法 CIDR
+		"unknown=1 => direct",           // 未知條件
+		"domain_regex=[a-z => direct",   // 非法正則
+	} {
+		if _, err := ParseRoutingRule(bad); err == nil {
+			t.Errorf("非法規則 %q 應被拒絕", bad)
+		}
+	}
+}
+
+// TestValidateRules 測試出站引用校驗
+func TestValidateRules(t *testing.T) {
+	r := RoutingConfig{
+		Outbounds: []CustomOutbound{{Tag: "jp", Type: OutboundTypeTrojan, Server: "example.com", Port: 443, Password: "p"}},
+		Rules: []RoutingRule{
+			{Priority: 10, Domain: []string{"a.com"}, Outbound: "jp"},
+			{Priority: 20, Domain: []string{"b.com"}, Outbound: OutboundWARP},
+		},
+		Final: "jp",
+	}
+	if err := r.ValidateRules(); err != nil {
+		t.Fatalf("合法規則被拒絕: %v", err)
+	}
+
+	r.Rules = append(r.Rules, RoutingRule{Priority: 30, Domain: []string{"c.com"}, Outbound: "missing"})
+	if err := r.ValidateRules(); err == nil {
+		t.Error("引用不存在的出站應被拒絕")
+	}
+
+	r.Rules = r.Rules[:2]
+	r.Final = "missing"
+	if err := r.ValidateRules(); err == nil {
+		t.Error("不存在的默認出站應被拒絕")
+	}
+}
+
+// TestRuleOrder 測試優先級排序
+func TestRuleOrder(t *testing.T) {
+	r := RoutingConfig{Rules: []RoutingRule{
+		{Name: "c", Priority: 30, Domain: []string{"c"}, Outbound: OutboundDirect},
+		{Name: "a", Priority: 10, Domain: []string{"a"}, Outbound: OutboundDirect, Disabled: true},
+		{Name: "b1", Priority: 20, Domain: []string{"b"}, Outbound: OutboundDirect},
+		{Name: "b2", Priority: 20, Domain: []string{"b"}, Outbound: OutboundDirect},
+	}}
+
+	order := r.RuleOrder()
+	want := []int{1, 2, 3, 0}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("排序錯誤: %v", order)
+		}
+	}
+
+	sorted := r.SortedRules()
+	if len(sorted) != 3 || sorted[0].Name != "b1" || sorted[1].Name != "b2" {
+		t.Errorf("啓用規則排序錯誤: %+v", sorted)
+	}
+
+	if p := r.NextRulePriority(); p <= 30 {
+		t.Errorf("下一個優先級應大於現有最大值: %d", p)
+	}
+}
+
+// TestSocks5Routing 測試 Socks5 落地機編輯作用於自定義出站與分流規則
+func TestSocks5Routing(t *testing.T) {
+	var r RoutingConfig
+	if err := r.SetSocks5Global(true); err == nil {
+		t.Fatal("未設置落地機時應拒絕全局轉發")
+	}
+
+	if err := r.SetSocks5Server("1.2.3.4", 1080); err != nil {
+		t.Fatalf("設置落地機失敗: %v", err)
+	}
+	if err := r.SetSocks5Auth("u", "p"); err != nil {
+		t.Fatalf("設置認證失敗: %v", err)
+	}
+	out := r.FindOutbound(Socks5OutboundTag)
+	if out == nil || out.Type != OutboundTypeSOCKS || out.Username != "u" {
+		t.Fatalf("自定義出站錯誤: %+v", out)
+	}
+
+	if err := r.AddDomainRule(Socks5OutboundTag, "netflix.com"); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Socks5Enabled() {
+		t.Error("存在分流規則時應視為已啓用")
+	}
+	if err := r.SetSocks5Global(true); err != nil || r.Final != Socks5OutboundTag {
+		t.Fatalf("全局轉發未生效: %v %q", err, r.Final)
+	}
+
+	if err := r.SetSocks5Enabled(false); err != nil {
+		t.Fatal(err)
+	}
+	if r.Socks5Enabled() || r.Final != "" || len(r.SortedRules()) != 0 {
+		t.Errorf("禁用後仍有流量經 Socks5: final=%q rules=%+v", r.Final, r.SortedRules())
+	}
+	if err := r.ValidateRules(); err != nil {
+		t.Errorf("禁用後規則應仍有效: %v", err)
+	}
+}
+
+// TestValidateInboundRouting 測試入站路由策略校驗
+func TestValidateInboundRouting(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Protocols.Hysteria2().Routing = InboundRouting{Outbound: OutboundWARP, Block: []string{"ads", "cn"}, IPv6: "prefer_ipv6"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("合法策略被拒絕: %v", err)
+	}
+
+	cfg.Protocols.Hysteria2().Routing.Block = []string{"unknown"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("未知屏蔽類別應被拒絕")
+	}
+
+	cfg.Protocols.Hysteria2().Routing = InboundRouting{Outbound: "missing"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("不存在的出站應被拒絕")
+	}
+
+	cfg.Protocols.Hysteria2().Routing = InboundRouting{IPv6: "ipv6_first"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("無效的 IPv6 偏好應被拒絕")
+	}
+}
+
+// TestValidateInboundFirewall 測試入站防火牆策略校驗
+func TestValidateInboundFirewall(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Protocols.TUIC().Enabled = true
+	cfg.Protocols.TUIC().Port = 9443
+	cfg.Protocols.TUIC().Firewall = InboundFirewall{
+		Allow:          []string{"203.0.113.7", "2001:db8::/32"},
+		Deny:           []string{"198.51.100.0/24"},
+		RateLimit:      30,
+		BlockCountries: []string{"ru"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("合法策略被拒絕: %v", err)
+	}
+
+	targets := cfg.Protocols.FirewallTargets()
+	if len(targets) != 1 || targets[0].Name != "tuic" || targets[0].Network != "udp" || targets[0].Port != 9443 {
+		t.Fatalf("策略目標錯誤: %+v", targets)
+	}
+
+	if cidr, _ := NormalizeCIDR("203.0.113.7"); cidr != "203.0.113.7/32" {
+		t.Errorf("單個 IP 應轉為 /32: %s", cidr)
+	}
+	if cidr, _ := NormalizeCIDR("10.1.2.3/8"); cidr != "10.0.0.0/8" {
+		t.Errorf("網段應規範化: %s", cidr)
+	}
+
+	cfg.Protocols.TUIC().Firewall.Deny = []string{"not-an-ip"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("無效網段應被拒絕")
+	}
+
+	cfg.Protocols.TUIC().Firewall = InboundFirewall{BlockCountries: []string{"CHN"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("無效國家代碼應被拒絕")
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Shadowsocks 2022 加密方式
+const (
+	SS2022AES128GCM        = "2022-blake3-aes-128-gcm"
+	SS2022AES256GCM        = "2022-blake3-aes-256-gcm"
+	SS2022ChaCha20Poly1305 = "2022-blake3-chacha20-poly1305"
+
+	DefaultShadowsocksMethod = SS2022AES128GCM
+)
+
+// ShadowsocksMethods 獨立 Shadowsocks 入站支持的加密方式
+var ShadowsocksMethods = []string{SS2022AES128GCM, SS2022AES256GCM, SS2022ChaCha20Poly1305}
+
+// SSKeyLength 返回 Shadowsocks 2022 加密方式要求的密鑰字節數，非 2022 加密方式返回 0
+func SSKeyLength(method string) int {
+	switch method {
+	case SS2022AES128GCM:
+		return 16
+	case SS2022AES256GCM, SS2022ChaCha20Poly1305:
+		return 32
+	}
+	return 0
+}
+
+// GenerateSSKey 生成符合加密方式長度要求的 Base64 密鑰
+func GenerateSSKey(method string) string {
+	n := SSKeyLength(method)
+	if n == 0 {
+		n = 32
+	}
+	key := make([]byte, n)
+	if _, err := crand.Read(key); err != nil {
+		panic("failed to generate shadowsocks key: " + err.Error())
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ValidSSKey 檢查密鑰是否符合加密方式的要求 (2022 加密方式需為指定長度的 Base64)
+func ValidSSKey(method, key string) bool {
+	if key == "" {
+		return false
+	}
+	n := SSKeyLength(method)
+	if n == 0 {
+		return true
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(raw) == n
+}
+
+// DeriveSSKey 將任意密碼轉換為加密方式可用的密鑰
+// 已合法的密鑰原樣返回，否則由 SHA-256 摘要截取，保證同一密碼總是得到同一密鑰
+func DeriveSSKey(method, secret string) string {
+	if ValidSSKey(method, secret) {
+		return secret
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return base64.StdEncoding.EncodeToString(sum[:SSKeyLength(method)])
+}
+
+// MultiUser 是否為多用戶模式 (EIH)
+func (c *ShadowsocksConfig) MultiUser() bool {
+	return len(c.Users) > 0
+}
+
+// IsRelay 是否為中轉模式
+func (c *ShadowsocksConfig) IsRelay() bool {
+	return len(c.Relays) > 0
+}
+
+// validate 驗證 Shadowsocks 2022 的加密方式、密鑰、用戶與中轉目標
+func (c *ShadowsocksConfig) validate() error {
+	if !containsString(ShadowsocksMethods, c.Method) {
+		return fmt.Errorf("不支持的加密方式 %q (可選: %s)", c.Method, strings.Join(ShadowsocksMethods, ", "))
+	}
+	if !ValidSSKey(c.Method, c.Password) {
+		return fmt.Errorf("服務端密鑰必須為 %d 字節的 Base64 字符串", SSKeyLength(c.Method))
+	}
+	if c.MultiUser() && c.IsRelay() {
+		return fmt.Errorf("多用戶模式與中轉模式不能同時啟用")
+	}
+	if (c.MultiUser() || c.IsRelay()) && c.Method == SS2022ChaCha20Poly1305 {
+		return fmt.Errorf("%s 不支持多用戶與中轉模式", SS2022ChaCha20Poly1305)
+	}
+
+	names := make(map[string]bool)
+	for _, u := range c.Users {
+		if u.Name == "" || names[u.Name] {
+			return fmt.Errorf("用戶名為空或重複: %q", u.Name)
+		}
+		names[u.Name] = true
+		if !ValidSSKey(c.Method, u.Password) {
+			return fmt.Errorf("用戶 %s 的密鑰必須為 %d 字節的 Base64 字符串", u.Name, SSKeyLength(c.Method))
+		}
+	}
+	for _, r := range c.Relays {
+		if r.Name == "" || names[r.Name] {
+			return fmt.Errorf("中轉目標名稱為空或重複: %q", r.Name)
+		}
+		names[r.Name] = true
+		if r.Server == "" || r.ServerPort < 1 || r.ServerPort > 65535 {
+			return fmt.Errorf("中轉目標 %s 的地址無效: %s:%d", r.Name, r.Server, r.ServerPort)
+		}
+		if !ValidSSKey(c.Method, r.Password) {
+			return fmt.Errorf("中轉目標 %s 的密鑰必須為 %d 字節的 Base64 字符串", r.Name, SSKeyLength(c.Method))
+		}
+	}
+	return nil
+}
+
+// setMethod 修改加密方式，已有密鑰長度不符時重新生成
+func (c *ShadowsocksConfig) setMethod(method string) {
+	if method == "" {
+		method = DefaultShadowsocksMethod
+	}
+	c.Method = method
+	if SSKeyLength(method) == 0 {
+		return
+	}
+	if !ValidSSKey(method, c.Password) {
+		c.Password = GenerateSSKey(method)
+	}
+	for i := range c.Users {
+		if !ValidSSKey(method, c.Users[i].Password) {
+			c.Users[i].Password = GenerateSSKey(method)
+		}
+	}
+}
+
+// setOption 修改 Shadowsocks 2022 的加密方式、服務端密鑰、用戶與中轉目標
+func (c *ShadowsocksConfig) setOption(key, value string) error {
+	switch key {
+	case OptionSSMethod:
+		c.setMethod(strings.ToLower(value))
+	case OptionSSPassword:
+		// 留空時重新生成服務端密鑰
+		if value == "" {
+			value = GenerateSSKey(c.Method)
+		}
+		c.Password = value
+	case OptionSSUserAdd:
+		return c.addUser(value)
+	case OptionSSRelayAdd:
+		return c.addRelay(value)
+	case OptionSSRemove:
+		return c.removeNamed(value)
+	}
+	return nil
+}
+
+// addUser 解析「用戶名 [密鑰]」並添加用戶，未提供密鑰時自動生成
+func (c *ShadowsocksConfig) addUser(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("格式錯誤，應為: 用戶名 [密鑰]")
+	}
+	user := ShadowsocksUser{Name: fields[0], Password: GenerateSSKey(c.Method)}
+	if len(fields) == 2 {
+		user.Password = fields[1]
+	}
+	for _, u := range c.Users {
+		if u.Name == user.Name {
+			return fmt.Errorf("用戶已存在: %s", user.Name)
+		}
+	}
+	c.Users = append(c.Users, user)
+	return nil
+}
+
+// addRelay 解析「名稱 地址:端口 密鑰」並添加中轉目標
+func (c *ShadowsocksConfig) addRelay(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return fmt.Errorf("格式錯誤，應為: 名稱 地址:端口 密鑰")
+	}
+	host, portStr, err := net.SplitHostPort(fields[1])
+	if err != nil {
+		return fmt.Errorf("中轉目標地址無效: %s", fields[1])
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("中轉目標端口無效: %s", portStr)
+	}
+	for _, r := range c.Relays {
+		if r.Name == fields[0] {
+			return fmt.Errorf("中轉目標已存在: %s", fields[0])
+		}
+	}
+	c.Relays = append(c.Relays, ShadowsocksRelay{Name: fields[0], Server: host, ServerPort: port, Password: fields[2]})
+	return nil
+}
+
+// removeNamed 按名稱刪除用戶或中轉目標
+func (c *ShadowsocksConfig) removeNamed(name string) error {
+	for i, u := range c.Users {
+		if u.Name == name {
+			c.Users = append(c.Users[:i], c.Users[i+1:]...)
+			return nil
+		}
+	}
+	for i, r := range c.Relays {
+		if r.Name == name {
+			c.Relays = append(c.Relays[:i], c.Relays[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("用戶或中轉目標不存在: %s", name)
+}
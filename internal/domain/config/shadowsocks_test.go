@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// TestSSKeys 密鑰長度由加密方式決定，非法密鑰可轉換為固定長度的合法密鑰
+func TestSSKeys(t *testing.T) {
+	for method, n := range map[string]int{SS2022AES128GCM: 16, SS2022AES256GCM: 32, SS2022ChaCha20Poly1305: 32} {
+		key := GenerateSSKey(method)
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(raw) != n || !ValidSSKey(method, key) {
+			t.Errorf("%s 生成的密鑰長度錯誤: %s", method, key)
+		}
+	}
+
+	long := GenerateSSKey(SS2022AES256GCM)
+	if ValidSSKey(SS2022AES128GCM, long) {
+		t.Error("32 字節密鑰不應被 aes-128 接受")
+	}
+	derived := DeriveSSKey(SS2022AES128GCM, long)
+	if !ValidSSKey(SS2022AES128GCM, derived) || derived != DeriveSSKey(SS2022AES128GCM, long) {
+		t.Errorf("轉換後的密鑰應合法且穩定: %s", derived)
+	}
+	if DeriveSSKey("aes-256-gcm", "plain") != "plain" {
+		t.Error("非 2022 加密方式不應轉換密碼")
+	}
+
+	// ShadowTLS 默認使用 aes-128，全局密碼為 32 字節時也應得到合法密鑰
+	stls := &ShadowTLSConfig{}
+	if !ValidSSKey(DefaultShadowTLSSSMethod, stls.GetSSPassword(long)) {
+		t.Error("ShadowTLS 的 SS 密碼長度應與加密方式匹配")
+	}
+}
+
+// TestShadowsocksOptions 用戶與中轉目標的增刪、加密方式切換與驗證
+func TestShadowsocksOptions(t *testing.T) {
+	cfg := DefaultConfig()
+	inst := cfg.Protocols.Instance("shadowsocks-in")
+	c := inst.Shadowsocks
+
+	if err := inst.SetOption(OptionSSUserAdd, "alice"); err != nil || len(c.Users) != 1 || !ValidSSKey(c.Method, c.Users[0].Password) {
+		t.Fatalf("添加用戶失敗: %v %+v", err, c.Users)
+	}
+	if err := inst.SetOption(OptionSSUserAdd, "alice"); err == nil {
+		t.Error("重複用戶應返回錯誤")
+	}
+
+	if err := inst.SetOption(OptionSSMethod, SS2022AES256GCM); err != nil {
+		t.Fatal(err)
+	}
+	if !ValidSSKey(SS2022AES256GCM, c.Password) || !ValidSSKey(SS2022AES256GCM, c.Users[0].Password) {
+		t.Error("切換加密方式後應重新生成長度不符的密鑰")
+	}
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Fatalf("多用戶配置應有效: %v", err)
+	}
+
+	// 多用戶與中轉互斥
+	if err := inst.SetOption(OptionSSRelayAdd, "hk 203.0.113.5:8388 "+GenerateSSKey(SS2022AES256GCM)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err == nil || !strings.Contains(err.Error(), "不能同時啟用") {
+		t.Errorf("多用戶與中轉同時啟用應返回錯誤: %v", err)
+	}
+	if err := inst.SetOption(OptionSSRemove, "alice"); err != nil || len(c.Users) != 0 {
+		t.Fatalf("刪除用戶失敗: %v", err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err != nil {
+		t.Errorf("中轉配置應有效: %v", err)
+	}
+	if c.Relays[0].Server != "203.0.113.5" || c.Relays[0].ServerPort != 8388 {
+		t.Errorf("中轉目標解析錯誤: %+v", c.Relays[0])
+	}
+
+	if err := inst.SetOption(OptionSSMethod, SS2022ChaCha20Poly1305); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Protocols.ValidateInstances(); err == nil {
+		t.Error("chacha20-poly1305 不支持中轉模式")
+	}
+
+	if err := inst.SetOption(OptionDetourPort, "10001"); err == nil {
+		t.Error("Shadowsocks 不應支持 ShadowTLS 專屬字段")
+	}
+}
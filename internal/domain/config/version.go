@@ -9,10 +9,19 @@ import (
 
 const (
 	// ConfigVersionLatest 最新配置版本
-	ConfigVersionLatest = 2
+	ConfigVersionLatest = ConfigVersionV4
 
 	// ConfigVersionV1 V1 版本（舊版）
 	ConfigVersionV1 = 1
+
+	// ConfigVersionV2 V2 版本（分流域名列表）
+	ConfigVersionV2 = 2
+
+	// ConfigVersionV3 V3 版本（統一路由規則）
+	ConfigVersionV3 = 3
+
+	// ConfigVersionV4 V4 版本（協議實例列表）
+	ConfigVersionV4 = 4
 )
 
 // Migrator 配置遷移器
@@ -34,14 +43,32 @@ func (m *Migrator) MigrateToLatest(cfg *Config) (*Config, error) {
 		return cfg, nil
 	}
 
+	// 未來版本降級（例如從 V4 回退到 V3）
+	if cfg.Version > ConfigVersionLatest {
+		return nil, fmt.Errorf("配置版本過高 (v%d)，當前程序僅支持 v%d", cfg.Version, ConfigVersionLatest)
+	}
+
 	// V1 -> V2
 	if cfg.Version == ConfigVersionV1 || cfg.Version == 0 {
-		return m.migrateV1ToV2(cfg)
+		migrated, err := m.migrateV1ToV2(cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg = migrated
 	}
 
-	// 未來版本降級（例如從 V3 回退到 V2）
-	if cfg.Version > ConfigVersionLatest {
-		return nil, fmt.Errorf("配置版本過高 (v%d)，當前程序僅支持 v%d", cfg.Version, ConfigVersionLatest)
+	// V2 -> V3
+	if cfg.Version == ConfigVersionV2 {
+		migrated, err := m.migrateV2ToV3(cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg = migrated
+	}
+
+	// V3 -> V4
+	if cfg.Version == ConfigVersionV3 {
+		return m.migrateV3ToV4(cfg)
 	}
 
 	return cfg, nil
@@ -49,33 +76,57 @@ func (m *Migrator) MigrateToLatest(cfg *Config) (*Config, error) {
 
 // migrateV1ToV2 V1 -> V2 遷移邏輯
 func (m *Migrator) migrateV1ToV2(oldCfg *Config) (*Config, error) {
-	newCfg := *oldCfg // 淺拷貝
-	newCfg.Version = ConfigVersionLatest
+	newCfg := oldCfg.DeepCopy()
+	newCfg.Version = ConfigVersionV2
 
 	// 1. 驗證 UUID
 	if oldCfg.UUID == "" || !validator.ValidateUUID(oldCfg.UUID) {
 		newCfg.UUID = uuid.New().String()
 	}
 
+	// V1 配置使用固定佈局，缺失的協議按零值處理
+	legacy := &newCfg.Protocols
+	if legacy.LegacyRealityVision == nil {
+		legacy.LegacyRealityVision = &RealityVisionConfig{}
+	}
+	if legacy.LegacyRealityGRPC == nil {
+		legacy.LegacyRealityGRPC = &RealityGRPCConfig{}
+	}
+	if legacy.LegacyHysteria2 == nil {
+		legacy.LegacyHysteria2 = &Hysteria2Config{}
+	}
+	if legacy.LegacyTUIC == nil {
+		legacy.LegacyTUIC = &TUICConfig{}
+	}
+	if legacy.LegacyAnyTLS == nil {
+		legacy.LegacyAnyTLS = &AnyTLSConfig{}
+	}
+	if legacy.LegacyAnyTLSReality == nil {
+		legacy.LegacyAnyTLSReality = &AnyTLSRealityConfig{}
+	}
+	if legacy.LegacyShadowTLS == nil {
+		legacy.LegacyShadowTLS = &ShadowTLSConfig{}
+	}
+
 	// 2. 遷移 AnyTLS PaddingScheme -> PaddingMode
-	if len(oldCfg.Protocols.AnyTLS.PaddingScheme) > 0 && oldCfg.Protocols.AnyTLS.PaddingMode == "" {
-		newCfg.Protocols.AnyTLS.PaddingMode = "official" // V2 默認值
-		newCfg.Protocols.AnyTLS.PaddingScheme = nil      // 清空舊字段
+	if len(legacy.LegacyAnyTLS.PaddingScheme) > 0 && legacy.LegacyAnyTLS.PaddingMode == "" {
+		legacy.LegacyAnyTLS.PaddingMode = "official" // V2 默認值
+		legacy.LegacyAnyTLS.PaddingScheme = nil      // 清空舊字段
 	}
 
-	if len(oldCfg.Protocols.AnyTLSReality.PaddingScheme) > 0 && oldCfg.Protocols.AnyTLSReality.PaddingMode == "" {
-		newCfg.Protocols.AnyTLSReality.PaddingMode = "official"
-		newCfg.Protocols.AnyTLSReality.PaddingScheme = nil
+	if len(legacy.LegacyAnyTLSReality.PaddingScheme) > 0 && legacy.LegacyAnyTLSReality.PaddingMode == "" {
+		legacy.LegacyAnyTLSReality.PaddingMode = "official"
+		legacy.LegacyAnyTLSReality.PaddingScheme = nil
 	}
 
 	// 3. 驗證並修復 SNI 域名
 	protocols := []struct {
 		sni *string
 	}{
-		{&newCfg.Protocols.RealityVision.SNI},
-		{&newCfg.Protocols.RealityGRPC.SNI},
-		{&newCfg.Protocols.AnyTLSReality.SNI},
-		{&newCfg.Protocols.ShadowTLS.SNI},
+		{&legacy.LegacyRealityVision.SNI},
+		{&legacy.LegacyRealityGRPC.SNI},
+		{&legacy.LegacyAnyTLSReality.SNI},
+		{&legacy.LegacyShadowTLS.SNI},
 	}
 
 	for _, p := range protocols {
@@ -88,13 +139,13 @@ func (m *Migrator) migrateV1ToV2(oldCfg *Config) (*Config, error) {
 	ports := []struct {
 		port *int
 	}{
-		{&newCfg.Protocols.RealityVision.Port},
-		{&newCfg.Protocols.RealityGRPC.Port},
-		{&newCfg.Protocols.Hysteria2.Port},
-		{&newCfg.Protocols.TUIC.Port},
-		{&newCfg.Protocols.AnyTLS.Port},
-		{&newCfg.Protocols.AnyTLSReality.Port},
-		{&newCfg.Protocols.ShadowTLS.Port},
+		{&legacy.LegacyRealityVision.Port},
+		{&legacy.LegacyRealityGRPC.Port},
+		{&legacy.LegacyHysteria2.Port},
+		{&legacy.LegacyTUIC.Port},
+		{&legacy.LegacyAnyTLS.Port},
+		{&legacy.LegacyAnyTLSReality.Port},
+		{&legacy.LegacyShadowTLS.Port},
 	}
 
 	for _, p := range ports {
@@ -103,7 +154,76 @@ func (m *Migrator) migrateV1ToV2(oldCfg *Config) (*Config, error) {
 		}
 	}
 
-	return &newCfg, nil
+	return newCfg, nil
+}
+
+// migrateV2ToV3 V2 -> V3 遷移邏輯
+// 將 WARP / IPv6 / Socks5 的分流域名列表轉換為統一的路由規則
+func (m *Migrator) migrateV2ToV3(oldCfg *Config) (*Config, error) {
+	newCfg := oldCfg.DeepCopy()
+	newCfg.Version = ConfigVersionV3
+	routing := &newCfg.Routing
+
+	// 遷移前的生成順序為 WARP -> IPv6，保持相同的匹配優先級
+	priority := 10
+	addRule := func(name string, domains []string, outbound string, disabled bool) {
+		if len(domains) == 0 {
+			return
+		}
+		routing.Rules = append(routing.Rules, RoutingRule{
+			Name:     name,
+			Priority: priority,
+			Disabled: disabled,
+			Domain:   append([]string{}, domains...),
+			Outbound: outbound,
+		})
+		priority += 10
+	}
+
+	addRule(OutboundWARP+"-domains", routing.WARP.Domains, OutboundWARP, false)
+	routing.WARP.Domains = nil
+
+	addRule(OutboundIPv6+"-domains", routing.IPv6Split.Domains, OutboundIPv6, false)
+	routing.IPv6Split.Domains = nil
+
+	// Socks5 出站轉為自定義出站
+	// 原 Socks5 出站關閉時保留服務器與域名，但規則以禁用狀態遷移，避免升級後改變流量走向
+	socks := routing.Socks5.Outbound
+	if socks.Server != "" && socks.Port > 0 && routing.FindOutbound(Socks5OutboundTag) == nil {
+		routing.Outbounds = append(routing.Outbounds, CustomOutbound{
+			Tag:      Socks5OutboundTag,
+			Type:     OutboundTypeSOCKS,
+			Server:   socks.Server,
+			Port:     socks.Port,
+			Username: socks.Username,
+			Password: socks.Password,
+		})
+		addRule(Socks5OutboundTag+"-domains", socks.DomainRules, Socks5OutboundTag, !socks.Enabled)
+		routing.Socks5.Outbound.DomainRules = nil
+
+		if socks.Enabled && socks.GlobalRoute && routing.Final == "" {
+			routing.Final = Socks5OutboundTag
+		}
+	}
+
+	if err := routing.ValidateRules(); err != nil {
+		return nil, fmt.Errorf("遷移路由規則失敗: %w", err)
+	}
+
+	return newCfg, nil
+}
+
+// migrateV3ToV4 V3 -> V4 遷移邏輯
+// 將每種協議一個配置的固定佈局轉換為協議實例列表，原配置成為使用默認標籤的主實例
+func (m *Migrator) migrateV3ToV4(oldCfg *Config) (*Config, error) {
+	newCfg := oldCfg.DeepCopy()
+	newCfg.Version = ConfigVersionV4
+	newCfg.Protocols.migrateLegacy()
+
+	if err := newCfg.Protocols.ValidateInstances(); err != nil {
+		return nil, fmt.Errorf("遷移協議實例失敗: %w", err)
+	}
+	return newCfg, nil
 }
 
 // NeedsMigration 檢查是否需要遷移
@@ -121,7 +241,15 @@ func (m *Migrator) GetMigrationPath(fromVersion int) string {
 	}
 
 	if fromVersion == ConfigVersionV1 || fromVersion == 0 {
-		return "V1 -> V2: UUID驗證, AnyTLS PaddingScheme遷移, SNI/端口驗證"
+		return "V1 -> V2: UUID驗證, AnyTLS PaddingScheme遷移, SNI/端口驗證; V2 -> V3: 分流域名轉換為路由規則; V3 -> V4: 協議配置轉換為實例列表"
+	}
+
+	if fromVersion == ConfigVersionV2 {
+		return "V2 -> V3: 分流域名轉換為路由規則; V3 -> V4: 協議配置轉換為實例列表"
+	}
+
+	if fromVersion == ConfigVersionV3 {
+		return "V3 -> V4: 協議配置轉換為實例列表"
 	}
 
 	return fmt.Sprintf("未知遷移路徑 (v%d -> v%d)", fromVersion, ConfigVersionLatest)
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestMigrateV2ToV3 測試分流域名遷移為路由規則
+func TestMigrateV2ToV3(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Version = ConfigVersionV2
+	cfg.Routing.WARP.Domains = []string{"openai.com"}
+	cfg.Routing.IPv6Split.Domains = []string{"google.com"}
+	cfg.Routing.Socks5.Outbound.Enabled = true
+	cfg.Routing.Socks5.Outbound.GlobalRoute = true
+	cfg.Routing.Socks5.Outbound.Server = "10.0.0.1"
+	cfg.Routing.Socks5.Outbound.Port = 1080
+	cfg.Routing.Socks5.Outbound.DomainRules = []string{"example.com"}
+
+	migrated, err := NewMigrator().MigrateToLatest(cfg)
+	if err != nil {
+		t.Fatalf("遷移失敗: %v", err)
+	}
+	if migrated.Version != ConfigVersionLatest {
+		t.Errorf("版本未更新: %d", migrated.Version)
+	}
+
+	rules := migrated.Routing.SortedRules()
+	if len(rules) != 3 {
+		t.Fatalf("應生成 3 條規則，實際 %d", len(rules))
+	}
+	if rules[0].Outbound != OutboundWARP || rules[1].Outbound != OutboundIPv6 || rules[2].Outbound != Socks5OutboundTag {
+		t.Errorf("規則順序錯誤: %+v", rules)
+	}
+	if len(migrated.Routing.WARP.Domains) != 0 || len(migrated.Routing.IPv6Split.Domains) != 0 {
+		t.Error("舊域名列表應被清空")
+	}
+
+	if o := migrated.Routing.FindOutbound(Socks5OutboundTag); o == nil || o.Type != OutboundTypeSOCKS {
+		t.Errorf("Socks5 出站未轉換: %+v", o)
+	}
+	if migrated.Routing.Final != Socks5OutboundTag {
+		t.Errorf("全局 Socks5 應成為默認出站: %q", migrated.Routing.Final)
+	}
+
+	// 原配置不應被修改
+	if len(cfg.Routing.WARP.Domains) != 1 {
+		t.Error("遷移不應修改原配置")
+	}
+}
+
+// TestMigrateV2ToV3_DisabledSocks5 關閉的 Socks5 出站遷移後規則保持禁用，不改變流量走向
+func TestMigrateV2ToV3_DisabledSocks5(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Version = ConfigVersionV2
+	cfg.Routing.Socks5.Outbound.Enabled = false
+	cfg.Routing.Socks5.Outbound.GlobalRoute = true
+	cfg.Routing.Socks5.Outbound.Server = "10.0.0.1"
+	cfg.Routing.Socks5.Outbound.Port = 1080
+	cfg.Routing.Socks5.Outbound.DomainRules = []string{"example.com"}
+
+	migrated, err := NewMigrator().MigrateToLatest(cfg)
+	if err != nil {
+		t.Fatalf("遷移失敗: %v", err)
+	}
+	if rules := migrated.Routing.SortedRules(); len(rules) != 0 {
+		t.Errorf("關閉的 Socks5 不應生成生效規則: %+v", rules)
+	}
+	if len(migrated.Routing.Rules) != 1 || !migrated.Routing.Rules[0].Disabled {
+		t.Errorf("域名規則應以禁用狀態保留: %+v", migrated.Routing.Rules)
+	}
+	if migrated.Routing.Final != "" {
+		t.Errorf("關閉的 Socks5 不應成為默認出站: %q", migrated.Routing.Final)
+	}
+}
+
+// TestMigrateV3ToV4 測試固定協議佈局遷移為實例列表
+func TestMigrateV3ToV4(t *testing.T) {
+	data := []byte(`
+version: 3
+uuid: global-uuid
+protocols:
+  reality_vision:
+    enabled: true
+    port: 8443
+    sni: www.apple.com
+  hysteria2:
+    enabled: true
+    port: 9443
+`)
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+
+	migrated, err := NewMigrator().MigrateToLatest(&cfg)
+	if err != nil {
+		t.Fatalf("遷移失敗: %v", err)
+	}
+	if migrated.Version != ConfigVersionLatest {
+		t.Errorf("版本未更新: %d", migrated.Version)
+	}
+	if migrated.Protocols.LegacyRealityVision != nil || migrated.Protocols.LegacyHysteria2 != nil {
+		t.Error("舊佈局字段應被清空")
+	}
+	if len(migrated.Protocols.Instances) != 2 {
+		t.Fatalf("應生成 2 個實例，實際 %d", len(migrated.Protocols.Instances))
+	}
+
+	rv := migrated.Protocols.Instance(DefaultProtocolTag(ProtocolTypeRealityVision))
+	if rv == nil || rv.RealityVision == nil || rv.RealityVision.Port != 8443 || rv.RealityVision.SNI != "www.apple.com" {
+		t.Errorf("Reality Vision 實例未正確遷移: %+v", rv)
+	}
+	hy2 := migrated.Protocols.Instance("hysteria2-in")
+	if hy2 == nil || !hy2.IsPrimary() || hy2.Hysteria2.Port != 9443 {
+		t.Errorf("Hysteria2 實例未正確遷移: %+v", hy2)
+	}
+
+	// 原配置不應被修改
+	if cfg.Protocols.LegacyRealityVision == nil || len(cfg.Protocols.Instances) != 0 {
+		t.Error("遷移不應修改原配置")
+	}
+}
+
+// TestMigrateV3ToV4_BlankPrimary 遷移前已存在的空白主實例應被舊配置覆蓋
+func TestMigrateV3ToV4_BlankPrimary(t *testing.T) {
+	cfg := &Config{Version: ConfigVersionV3}
+	cfg.Protocols.LegacyHysteria2 = &Hysteria2Config{Enabled: true, Port: 45678, UpMbps: 77}
+	blank, _ := NewProtocolInstance(DefaultProtocolTag(ProtocolTypeHysteria2), ProtocolTypeHysteria2)
+	if err := cfg.Protocols.AddInstance(blank); err != nil {
+		t.Fatalf("AddInstance: %v", err)
+	}
+
+	migrated, err := NewMigrator().MigrateToLatest(cfg)
+	if err != nil {
+		t.Fatalf("遷移失敗: %v", err)
+	}
+	hy2 := migrated.Protocols.Hysteria2()
+	if hy2 == nil || !hy2.Enabled || hy2.Port != 45678 || hy2.UpMbps != 77 {
+		t.Errorf("Hysteria2 舊配置被空白主實例覆蓋: %+v", hy2)
+	}
+}
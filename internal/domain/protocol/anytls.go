@@ -2,29 +2,33 @@ package protocol
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
 // AnyTLS AnyTLS 协议 (HTTP/HTTPS 伪装)
 type AnyTLS struct {
 	BaseProtocol
-	Username    string // 用户名
-	Password    string // 密码
-	SNI         string
-	CertPath    string   // 证书路径
-	KeyPath     string   // 密钥路径
-	PaddingMode string   // 填充模式
-	ALPN        []string // ALPN
+	Username      string // 用户名
+	Password      string // 密码
+	SNI           string
+	CertPath      string   // 证书路径
+	KeyPath       string   // 密钥路径
+	PaddingMode   string   // 填充模式
+	PaddingScheme []string // 自定義填充方案，非空時覆蓋 PaddingMode
+	ALPN          []string // ALPN
 }
 
 // PaddingMode 常量
 const (
-	PaddingBalanced   = "balanced"
-	PaddingMinimal    = "minimal"
-	PaddingHighResist = "high_resist"
-	PaddingVideo      = "video"
-	PaddingOfficial   = "official"
+	PaddingBalanced   = domainConfig.PaddingModeBalanced
+	PaddingMinimal    = domainConfig.PaddingModeMinimal
+	PaddingHighResist = domainConfig.PaddingModeHighResist
+	PaddingVideo      = domainConfig.PaddingModeVideo
+	PaddingOfficial   = domainConfig.PaddingModeOfficial
 )
 
 // NewAnyTLS 创建 AnyTLS 协议
@@ -95,7 +99,7 @@ func (a *AnyTLS) ToSingboxInbound() (map[string]interface{}, error) {
 	}
 
 	// ✅ 使用构建器
-	return NewInboundBuilder("anytls", "anytls-in", a.port).
+	return NewInboundBuilder("anytls", a.Tag(), a.port).
 		WithUsers([]map[string]interface{}{
 			{
 				"name":     a.Username,
@@ -115,66 +119,59 @@ func (a *AnyTLS) ToSingboxInbound() (map[string]interface{}, error) {
 
 // getPaddingScheme 获取填充方案
 func (a *AnyTLS) getPaddingScheme() []string {
-	switch a.PaddingMode {
-	case PaddingBalanced:
-		return []string{
-			"stop=6",
-			"0=10-60",
-			"1=30-150",
-			"2=200-500,c,400-800",
-			"3=100-300",
-			"4=500-1200",
-		}
-	case PaddingMinimal:
-		return []string{
-			"stop=4",
-			"0=15-35",
-			"1=20-100",
-			"2=100-200",
-		}
-	case PaddingHighResist:
-		return []string{
-			"stop=10",
-			"0=50-100",
-			"1=500-800",
-			"2=c,800-1200",
-			"3=50-50",
-			"4=c,1000-1500",
-			"5=100-600",
-		}
-	case PaddingVideo:
-		return []string{
-			"stop=9",
-			"0=40-80",
-			"1=600-900",
-			"2=c,900-1400",
-			"3=80-150",
-			"4=c,800-1200",
-			"5=200-400",
-			"6=150-600",
-		}
-	case PaddingOfficial:
-		fallthrough
-	default:
-		// 官方默认方案
-		return []string{
-			"stop=8",
-			"0=30-30",
-			"1=100-400",
-			"2=400-500,c,500-1000,c,500-1000,c,500-1000,c,500-1000",
-			"3=9-9,500-1000",
-			"4=500-1000",
-			"5=500-1000",
-			"6=500-1000",
-			"7=500-1000",
-		}
-	}
+	return domainConfig.EffectivePaddingScheme(a.PaddingMode, a.PaddingScheme)
 }
 
 // GenerateShareLink 生成分享链接
 func (a *AnyTLS) GenerateShareLink(serverIP string) string {
 	return fmt.Sprintf(
-		"anytls://%s:%s@%s:%d?security=tls&sni=%s&alpn=%s#AnyTLS",
-		a.Username, a.Password, serverIP, a.port, a.SNI, "h2,http/1.1",
+		"anytls://%s@%s:%d?sni=%s&alpn=%s&idle_timeout=30s#AnyTLS",
+		url.User(a.Password).String(), serverIP, a.port, a.SNI, strings.Join(a.ALPN, ","),
 	)
 }
+
+func init() {
+	Register(Descriptor{
+		ID:        IDAnyTLS,
+		Type:      TypeAnyTLS,
+		Name:      "AnyTLS",
+		Tag:       "anytls-in",
+		ConfigKey: "anytls",
+		NeedsCert: true,
+		Build:     buildAnyTLS,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*AnyTLS).GenerateShareLink(serverIP)
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*AnyTLS).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildAnyTLS(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.AnyTLS
+	certPath, keyPath := b.CertPath(c.CertMode, c.CertDomain)
+
+	return &AnyTLS{
+		BaseProtocol:  b.base(TypeAnyTLS, "AnyTLS", inst, c.Port),
+		Username:      c.GetUsername(),
+		Password:      inst.Credential(c.Password, cfg.Password),
+		SNI:           b.SNI(c.CertMode, c.CertDomain, c.SNI),
+		CertPath:      certPath,
+		KeyPath:       keyPath,
+		PaddingMode:   c.PaddingMode,
+		PaddingScheme: c.PaddingScheme,
+		ALPN:          c.GetALPN(),
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段
+// AnyTLS 本質是 HTTP/2 代理
+func (a *AnyTLS) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = "http"
+	proxy["username"] = a.Username
+	proxy["password"] = a.Password
+	proxy["tls"] = true
+	proxy["sni"] = a.SNI
+	proxy["skip-cert-verify"] = true
+}
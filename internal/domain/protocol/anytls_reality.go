@@ -2,21 +2,24 @@ package protocol
 
 import (
 	"fmt"
+	"net/url"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
 // AnyTLSReality AnyTLS Reality 协议
 type AnyTLSReality struct {
 	BaseProtocol
-	Username    string // 用户名
-	Password    string // 密码
-	SNI         string // TLS SNI
-	PublicKey   string // Reality 公钥
-	PrivateKey  string // Reality 私钥
-	ShortID     string // Short ID 列表
-	PaddingMode string // 填充模式
-	ALPN        []string
+	Username      string   // 用户名
+	Password      string   // 密码
+	SNI           string   // TLS SNI
+	PublicKey     string   // Reality 公钥
+	PrivateKey    string   // Reality 私钥
+	ShortID       string   // Short ID 列表
+	PaddingMode   string   // 填充模式
+	PaddingScheme []string // 自定義填充方案，非空時覆蓋 PaddingMode
+	ALPN          []string
 }
 
 // NewAnyTLSReality 创建 AnyTLS Reality 协议
@@ -102,7 +105,7 @@ func (a *AnyTLSReality) ToSingboxInbound() (map[string]interface{}, error) {
 
 	return map[string]interface{}{
 		"type":        "anytls",
-		"tag":         "anytls-reality-in",
+		"tag":         a.Tag(),
 		"listen":      "::",
 		"listen_port": a.port,
 		"users": []map[string]interface{}{
@@ -112,7 +115,7 @@ func (a *AnyTLSReality) ToSingboxInbound() (map[string]interface{}, error) {
 		"tls": map[string]interface{}{
 			"enabled":     true,
 			"server_name": a.SNI,
-			"alpn":        []string{"h2", "http/1.1"},
+			"alpn":        a.ALPN,
 			"reality": map[string]interface{}{
 				"enabled": true,
 				"handshake": map[string]interface{}{
@@ -128,66 +131,46 @@ func (a *AnyTLSReality) ToSingboxInbound() (map[string]interface{}, error) {
 
 // getPaddingScheme 与 AnyTLS 相同
 func (a *AnyTLSReality) getPaddingScheme() []string {
-	switch a.PaddingMode {
-	case PaddingBalanced:
-		return []string{
-			"stop=6",
-			"0=10-60",
-			"1=30-150",
-			"2=200-500,c,400-800",
-			"3=100-300",
-			"4=500-1200",
-		}
-	case PaddingMinimal:
-		return []string{
-			"stop=4",
-			"0=15-35",
-			"1=20-100",
-			"2=100-200",
-		}
-	case PaddingHighResist:
-		return []string{
-			"stop=10",
-			"0=50-100",
-			"1=500-800",
-			"2=c,800-1200",
-			"3=50-50",
-			"4=c,1000-1500",
-			"5=100-600",
-		}
-	case PaddingVideo:
-		return []string{
-			"stop=9",
-			"0=40-80",
-			"1=600-900",
-			"2=c,900-1400",
-			"3=80-150",
-			"4=c,800-1200",
-			"5=200-400",
-			"6=150-600",
-		}
-	case PaddingOfficial:
-		fallthrough
-	default:
-		// 官方默认方案
-		return []string{
-			"stop=8",
-			"0=30-30",
-			"1=100-400",
-			"2=400-500,c,500-1000,c,500-1000,c,500-1000,c,500-1000",
-			"3=9-9,500-1000",
-			"4=500-1000",
-			"5=500-1000",
-			"6=500-1000",
-			"7=500-1000",
-		}
-	}
+	return domainConfig.EffectivePaddingScheme(a.PaddingMode, a.PaddingScheme)
 }
 
 // GenerateShareLink 生成分享链接
 func (a *AnyTLSReality) GenerateShareLink(serverIP string) string {
 	return fmt.Sprintf(
-		"anytls://%s:%s@%s:%d?security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s#AnyTLS-Reality",
-		a.Username, a.Password, serverIP, a.port, a.SNI, a.PublicKey, a.ShortID,
+		"anytls://%s@%s:%d?security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s&idle_timeout=30s#AnyTLS-Reality",
+		url.User(a.Password).String(), serverIP, a.port, a.SNI, a.PublicKey, a.ShortID,
 	)
 }
+
+func init() {
+	Register(Descriptor{
+		ID:           IDAnyTLSReality,
+		Type:         TypeAnyTLSReality,
+		Name:         "AnyTLS Reality",
+		Tag:          "anytls-reality-in",
+		ConfigKey:    "anytls_reality",
+		Badge:        "[隧道]",
+		NeedsReality: true,
+		Build:        buildAnyTLSReality,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*AnyTLSReality).GenerateShareLink(serverIP)
+		},
+		// Clash Meta 暫不支持 AnyTLS Reality
+	})
+}
+
+func buildAnyTLSReality(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.AnyTLSReality
+	return &AnyTLSReality{
+		BaseProtocol:  b.base(TypeAnyTLSReality, "AnyTLS Reality", inst, c.Port),
+		Username:      c.GetUsername(),
+		Password:      inst.Credential(c.Password, cfg.Password),
+		SNI:           c.SNI,
+		PublicKey:     c.PublicKey,
+		PrivateKey:    c.PrivateKey,
+		ShortID:       c.ShortID,
+		PaddingMode:   c.PaddingMode,
+		PaddingScheme: c.PaddingScheme,
+		ALPN:          []string{"h2", "http/1.1"},
+	}
+}
@@ -0,0 +1,335 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
+)
+
+// CDN 經 CDN 中轉的 TLS 入站 (VLESS / VMess / Trojan over WS / HTTPUpgrade / gRPC)
+// 服務端監聽本地端口，客戶端連接 CDN 域名，由 CDN 回源到服務端
+type CDN struct {
+	BaseProtocol
+	Proxy       string // vless / vmess / trojan
+	Transport   string // ws / httpupgrade / grpc
+	Path        string // WebSocket / HTTPUpgrade 路徑
+	Host        string // Host 頭
+	ServiceName string // gRPC 服務名
+	UUID        string // VLESS / VMess 用戶 UUID
+	Password    string // Trojan 密碼
+	SNI         string
+	CertPath    string
+	KeyPath     string
+	CDNHost     string                 // 客戶端連接地址，留空使用服務器地址
+	CDNPort     int                    // 客戶端連接端口
+	Fallback    map[string]interface{} // Trojan 認證失敗時的回落目標，nil 表示關閉
+}
+
+// ClientEndpoint 返回客戶端連接的 CDN 地址與端口
+func (c *CDN) ClientEndpoint() (string, int) {
+	return c.CDNHost, c.CDNPort
+}
+
+// Validate 驗證配置
+func (c *CDN) Validate() error {
+	if err := c.BaseProtocol.ValidatePort(); err != nil {
+		return err
+	}
+
+	switch c.Proxy {
+	case domainConfig.CDNProxyVLESS, domainConfig.CDNProxyVMess:
+		if c.UUID == "" {
+			return errors.New("PROTO007", "UUID 不能為空")
+		}
+	case domainConfig.CDNProxyTrojan:
+		if c.Password == "" {
+			return errors.New("PROTO004", "密碼不能為空")
+		}
+	default:
+		return errors.New("PROTO018", "不支持的代理協議: "+c.Proxy)
+	}
+
+	switch c.Transport {
+	case domainConfig.CDNTransportWS, domainConfig.CDNTransportHTTPUpgrade:
+		if !strings.HasPrefix(c.Path, "/") {
+			return errors.New("PROTO019", "路徑必須以 / 開頭")
+		}
+	case domainConfig.CDNTransportGRPC:
+		if c.ServiceName == "" {
+			return errors.New("PROTO019", "gRPC 服務名不能為空")
+		}
+	default:
+		return errors.New("PROTO018", "不支持的傳輸方式: "+c.Transport)
+	}
+
+	if c.SNI == "" {
+		return errors.New("PROTO009", "SNI 不能為空")
+	}
+	if c.enabled && c.CertPath == "" {
+		return errors.New("PROTO005", "證書路徑不能為空")
+	}
+	if c.enabled && c.KeyPath == "" {
+		return errors.New("PROTO006", "密鑰路徑不能為空")
+	}
+	return nil
+}
+
+// alpn gRPC 需要 HTTP/2，WebSocket 與 HTTPUpgrade 使用 HTTP/1.1
+func (c *CDN) alpn() []string {
+	if c.Transport == domainConfig.CDNTransportGRPC {
+		return []string{"h2"}
+	}
+	return []string{"http/1.1"}
+}
+
+// transport 返回 sing-box 傳輸層配置
+func (c *CDN) transport(client bool) map[string]interface{} {
+	switch c.Transport {
+	case domainConfig.CDNTransportGRPC:
+		return map[string]interface{}{
+			"type":         "grpc",
+			"service_name": c.ServiceName,
+		}
+	case domainConfig.CDNTransportHTTPUpgrade:
+		t := map[string]interface{}{
+			"type": "httpupgrade",
+			"path": c.Path,
+		}
+		if client && c.Host != "" {
+			t["host"] = c.Host
+		}
+		return t
+	default:
+		t := map[string]interface{}{
+			"type": "ws",
+			"path": c.Path,
+		}
+		if client && c.Host != "" {
+			t["headers"] = map[string]interface{}{"Host": c.Host}
+		}
+		return t
+	}
+}
+
+// ToSingboxInbound 轉換為 Sing-box inbound 配置
+func (c *CDN) ToSingboxInbound() (map[string]interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	var user map[string]interface{}
+	switch c.Proxy {
+	case domainConfig.CDNProxyTrojan:
+		user = map[string]interface{}{"name": "prism", "password": c.Password}
+	case domainConfig.CDNProxyVMess:
+		user = map[string]interface{}{"name": "prism", "uuid": c.UUID, "alterId": 0}
+	default:
+		user = map[string]interface{}{"name": "prism", "uuid": c.UUID}
+	}
+
+	builder := NewInboundBuilder(c.Proxy, c.Tag(), c.port).
+		WithUsers([]map[string]interface{}{user}).
+		WithTLS(map[string]interface{}{
+			"enabled":          true,
+			"server_name":      c.SNI,
+			"certificate_path": c.CertPath,
+			"key_path":         c.KeyPath,
+			"alpn":             c.alpn(),
+		}).
+		WithTransport(c.transport(false))
+	if c.Proxy == domainConfig.CDNProxyTrojan && c.Fallback != nil {
+		builder.WithField("fallback", c.Fallback)
+	}
+	return builder.Build(), nil
+}
+
+// ToSingboxOutbound 轉換為 Sing-box outbound 配置 (連接 CDN 端口)
+func (c *CDN) ToSingboxOutbound() (map[string]interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	builder := NewOutboundBuilder(c.Proxy, "cdn-out", "127.0.0.1", c.CDNPort).
+		WithTLS(map[string]interface{}{
+			"enabled":     true,
+			"server_name": c.SNI,
+			"alpn":        c.alpn(),
+			"utls": map[string]interface{}{
+				"enabled":     true,
+				"fingerprint": "chrome",
+			},
+		}).
+		WithTransport(c.transport(true))
+
+	switch c.Proxy {
+	case domainConfig.CDNProxyTrojan:
+		builder.WithAuth("password", c.Password)
+	case domainConfig.CDNProxyVMess:
+		builder.WithAuth("uuid", c.UUID).
+			WithField("security", "auto").
+			WithField("alter_id", 0)
+	default:
+		builder.WithAuth("uuid", c.UUID)
+	}
+	return builder.Build(), nil
+}
+
+// GenerateShareLink 生成分享鏈接，地址優先使用 CDN 域名
+func (c *CDN) GenerateShareLink(serverIP string) string {
+	host := serverIP
+	if c.CDNHost != "" {
+		host = c.CDNHost
+	}
+
+	if c.Proxy == domainConfig.CDNProxyVMess {
+		return c.vmessLink(host)
+	}
+
+	q := url.Values{}
+	q.Set("security", "tls")
+	q.Set("sni", c.SNI)
+	q.Set("fp", "chrome")
+	q.Set("alpn", strings.Join(c.alpn(), ","))
+	q.Set("type", c.Transport)
+	if c.Transport == domainConfig.CDNTransportGRPC {
+		q.Set("serviceName", c.ServiceName)
+		q.Set("mode", "gun")
+	} else {
+		q.Set("path", c.Path)
+		if c.Host != "" {
+			q.Set("host", c.Host)
+		}
+	}
+
+	cred := c.UUID
+	if c.Proxy == domainConfig.CDNProxyTrojan {
+		cred = url.User(c.Password).String()
+	} else {
+		q.Set("encryption", "none")
+	}
+	return fmt.Sprintf("%s://%s@%s?%s#%s",
+		c.Proxy, cred, joinHostPort(host, c.CDNPort), q.Encode(), url.PathEscape(c.Name()))
+}
+
+// vmessLink 生成 v2rayN 格式的 VMess 鏈接 (Base64 JSON)，節點名稱寫在 ps 字段
+func (c *CDN) vmessLink(host string) string {
+	path := c.Path
+	if c.Transport == domainConfig.CDNTransportGRPC {
+		path = c.ServiceName
+	}
+	data, _ := json.Marshal(map[string]string{
+		"v":    "2",
+		"ps":   c.Name(),
+		"add":  host,
+		"port": strconv.Itoa(c.CDNPort),
+		"id":   c.UUID,
+		"aid":  "0",
+		"scy":  "auto",
+		"net":  c.Transport,
+		"type": "none",
+		"host": c.Host,
+		"path": path,
+		"tls":  "tls",
+		"sni":  c.SNI,
+		"alpn": strings.Join(c.alpn(), ","),
+		"fp":   "chrome",
+	})
+	return "vmess://" + base64.StdEncoding.EncodeToString(data)
+}
+
+// joinHostPort 拼接地址與端口，IPv6 地址加方括號
+func joinHostPort(host string, port int) string {
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+func init() {
+	Register(Descriptor{
+		ID:          IDCDN,
+		Type:        TypeCDN,
+		Name:        "CDN 中轉",
+		Tag:         "cdn-in",
+		ConfigKey:   "cdn",
+		Badge:       "[抗封]",
+		Description: "VLESS / VMess / Trojan 經 CDN 中轉，服務器 IP 被封鎖時仍可連接",
+		NeedsCert:   true,
+		Build:       buildCDN,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*CDN).GenerateShareLink(serverIP)
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*CDN).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildCDN(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.CDN
+	certPath, keyPath := b.CertPath(c.CertMode, c.CertDomain)
+
+	name := fmt.Sprintf("%s %s CDN", strings.ToUpper(c.Proxy), strings.ToUpper(c.Transport))
+	return &CDN{
+		BaseProtocol: b.base(TypeCDN, name, inst, c.Port),
+		Proxy:        c.Proxy,
+		Transport:    c.Transport,
+		Path:         c.Path,
+		Host:         c.GetHost(),
+		ServiceName:  c.ServiceName,
+		UUID:         inst.Credential(c.UUID, cfg.UUID),
+		Password:     inst.Credential(c.Password, cfg.Password),
+		SNI:          b.SNI(c.CertMode, c.CertDomain, c.SNI),
+		CertPath:     certPath,
+		KeyPath:      keyPath,
+		CDNHost:      c.CDNHost,
+		CDNPort:      c.GetCDNPort(),
+		Fallback:     trojanFallback(c.Decoy),
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段
+// HTTPUpgrade 在 Clash Meta 中以 ws + v2ray-http-upgrade 表示
+func (c *CDN) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = c.Proxy
+	proxy["tls"] = true
+	proxy["udp"] = true
+	proxy["client-fingerprint"] = "chrome"
+	proxy["alpn"] = c.alpn()
+
+	switch c.Proxy {
+	case domainConfig.CDNProxyTrojan:
+		proxy["password"] = c.Password
+		proxy["sni"] = c.SNI
+	case domainConfig.CDNProxyVMess:
+		proxy["uuid"] = c.UUID
+		proxy["alterId"] = 0
+		proxy["cipher"] = "auto"
+		proxy["servername"] = c.SNI
+	default:
+		proxy["uuid"] = c.UUID
+		proxy["servername"] = c.SNI
+	}
+
+	if c.Transport == domainConfig.CDNTransportGRPC {
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]interface{}{"grpc-service-name": c.ServiceName}
+		return
+	}
+
+	opts := map[string]interface{}{"path": c.Path}
+	if c.Host != "" {
+		opts["headers"] = map[string]interface{}{"Host": c.Host}
+	}
+	if c.Transport == domainConfig.CDNTransportHTTPUpgrade {
+		opts["v2ray-http-upgrade"] = true
+	}
+	proxy["network"] = "ws"
+	proxy["ws-opts"] = opts
+}
@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+)
+
+func newTestCDN(proxy, transport string) *CDN {
+	return &CDN{
+		BaseProtocol: BaseProtocol{type_: TypeCDN, name: "CDN", tag: "cdn-in", port: 2053, enabled: true},
+		Proxy:        proxy,
+		Transport:    transport,
+		Path:         "/ray",
+		Host:         "edge.example.com",
+		ServiceName:  "grpc-svc",
+		UUID:         "b831381d-6324-4d53-ad4f-8cda48b30811",
+		Password:     "p@ss",
+		SNI:          "edge.example.com",
+		CertPath:     "/etc/prism/certs/edge.example.com.crt",
+		KeyPath:      "/etc/prism/certs/edge.example.com.key",
+		CDNHost:      "edge.example.com",
+		CDNPort:      443,
+	}
+}
+
+// TestCDN_ShareLinks 分享鏈接連接 CDN 地址與端口並攜帶傳輸參數
+func TestCDN_ShareLinks(t *testing.T) {
+	vless := newTestCDN(config.CDNProxyVLESS, config.CDNTransportWS).GenerateShareLink("1.2.3.4")
+	for _, want := range []string{"vless://b831381d-6324-4d53-ad4f-8cda48b30811@edge.example.com:443?", "type=ws", "path=%2Fray", "host=edge.example.com", "security=tls"} {
+		if !strings.Contains(vless, want) {
+			t.Errorf("VLESS 鏈接缺少 %s: %s", want, vless)
+		}
+	}
+
+	trojan := newTestCDN(config.CDNProxyTrojan, config.CDNTransportGRPC).GenerateShareLink("1.2.3.4")
+	for _, want := range []string{"trojan://p%40ss@edge.example.com:443?", "type=grpc", "serviceName=grpc-svc", "mode=gun"} {
+		if !strings.Contains(trojan, want) {
+			t.Errorf("Trojan 鏈接缺少 %s: %s", want, trojan)
+		}
+	}
+
+	vmess := newTestCDN(config.CDNProxyVMess, config.CDNTransportHTTPUpgrade).GenerateShareLink("1.2.3.4")
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(vmess, "vmess://"))
+	if err != nil {
+		t.Fatalf("VMess 鏈接不是 Base64: %v", err)
+	}
+	var v map[string]string
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatalf("VMess 鏈接內容不是 JSON: %v", err)
+	}
+	if v["add"] != "edge.example.com" || v["port"] != "443" || v["net"] != "httpupgrade" || v["path"] != "/ray" {
+		t.Errorf("VMess 鏈接字段錯誤: %v", v)
+	}
+
+	c := newTestCDN(config.CDNProxyVLESS, config.CDNTransportWS)
+	c.CDNHost = ""
+	if link := c.GenerateShareLink("1.2.3.4"); !strings.Contains(link, "@1.2.3.4:443?") {
+		t.Errorf("未設置 CDN 地址時應使用服務器地址: %s", link)
+	}
+}
+
+// TestCDN_ClientEndpoint 客戶端配置使用 CDN 地址與端口
+func TestCDN_ClientEndpoint(t *testing.T) {
+	c := newTestCDN(config.CDNProxyVLESS, config.CDNTransportHTTPUpgrade)
+	if got := ClientAddress(config.DefaultConfig(), c, "1.2.3.4"); got != "edge.example.com" {
+		t.Errorf("應連接 CDN 地址, got %s", got)
+	}
+	if got := ClientPort(c); got != 443 {
+		t.Errorf("應連接 CDN 端口, got %d", got)
+	}
+
+	out, err := c.ToSingboxOutbound()
+	if err != nil {
+		t.Fatalf("生成出站失敗: %v", err)
+	}
+	expectField(t, out, 443, "server_port")
+	expectField(t, out, "edge.example.com", "transport", "host")
+
+	proxy := map[string]interface{}{}
+	c.fillClashProxy(proxy)
+	expectField(t, proxy, "ws", "network")
+	expectField(t, proxy, true, "ws-opts", "v2ray-http-upgrade")
+	expectField(t, proxy, "/ray", "ws-opts", "path")
+
+	c.Transport = config.CDNTransportGRPC
+	proxy = map[string]interface{}{}
+	c.fillClashProxy(proxy)
+	expectField(t, proxy, "grpc-svc", "grpc-opts", "grpc-service-name")
+}
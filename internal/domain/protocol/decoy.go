@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+)
+
+// DecoyDir 返回內置偽裝站點目錄，未配置路徑時為空
+func (b *BuildContext) DecoyDir() string {
+	if b.paths == nil {
+		return ""
+	}
+	return b.paths.DecoySiteDir()
+}
+
+// masquerade 轉換為 Hysteria2 masquerade 配置，未啟用時返回 nil
+func masquerade(d domainConfig.DecoyConfig, bundledDir string) map[string]interface{} {
+	switch d.Mode {
+	case domainConfig.DecoyModeFile:
+		dir := d.Directory
+		if dir == "" {
+			dir = bundledDir
+		}
+		return map[string]interface{}{"type": "file", "directory": dir}
+	case domainConfig.DecoyModeProxy:
+		return map[string]interface{}{"type": "proxy", "url": d.URL, "rewrite_host": true}
+	case domainConfig.DecoyModeString:
+		return map[string]interface{}{"type": "string", "status_code": d.GetStatusCode(), "content": d.Content}
+	}
+	return nil
+}
+
+// trojanFallback 轉換為 Trojan fallback 配置，未啟用或地址無效時返回 nil
+// 內置站點回落到本機偽裝站點服務
+func trojanFallback(d domainConfig.DecoyConfig) map[string]interface{} {
+	if d.Mode != domainConfig.DecoyModeFile && d.Mode != domainConfig.DecoyModeProxy {
+		return nil
+	}
+	host, port, err := d.FallbackAddress()
+	if err != nil {
+		return nil
+	}
+	return map[string]interface{}{"server": host, "server_port": port}
+}
@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+)
+
+// TestDecoy_Inbounds Hysteria2 輸出 masquerade，CDN Trojan 輸出 fallback
+func TestDecoy_Inbounds(t *testing.T) {
+	cfg := config.DefaultConfig()
+	hy2 := cfg.Protocols.Hysteria2()
+	hy2.Decoy = config.DecoyConfig{Mode: config.DecoyModeFile}
+
+	in := buildInbound(t, cfg, IDHysteria2)
+	expectField(t, in, "file", "masquerade", "type")
+	expectField(t, in, "/etc/prism/data/decoy", "masquerade", "directory")
+
+	hy2.Decoy = config.DecoyConfig{Mode: config.DecoyModeProxy, URL: "https://example.com"}
+	in = buildInbound(t, cfg, IDHysteria2)
+	expectField(t, in, "https://example.com", "masquerade", "url")
+	expectField(t, in, true, "masquerade", "rewrite_host")
+
+	hy2.Decoy = config.DecoyConfig{Mode: config.DecoyModeString, Content: "ok"}
+	in = buildInbound(t, cfg, IDHysteria2)
+	expectField(t, in, 200, "masquerade", "status_code")
+
+	hy2.Decoy = config.DecoyConfig{}
+	if _, ok := buildInbound(t, cfg, IDHysteria2)["masquerade"]; ok {
+		t.Error("未配置時不應輸出 masquerade")
+	}
+
+	c := cfg.Protocols.CDN()
+	c.Proxy = config.CDNProxyTrojan
+	c.Decoy = config.DecoyConfig{Mode: config.DecoyModeProxy, URL: "http://127.0.0.1:8080/"}
+	in = buildInbound(t, cfg, IDCDN)
+	expectField(t, in, "127.0.0.1", "fallback", "server")
+	expectField(t, in, 8080, "fallback", "server_port")
+
+	c.Proxy = config.CDNProxyVLESS
+	if _, ok := buildInbound(t, cfg, IDCDN)["fallback"]; ok {
+		t.Error("VLESS 入站不應輸出 fallback")
+	}
+}
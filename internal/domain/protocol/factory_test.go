@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Yat-Muk/prism-v2/internal/domain/config"
@@ -59,12 +60,12 @@ func TestFactory_FromConfig(t *testing.T) {
 	cfg.Password = "test-pass"
 
 	// 1. 測試 Reality Vision
-	cfg.Protocols.RealityVision.Enabled = true
-	cfg.Protocols.RealityVision.Port = 12345
+	cfg.Protocols.RealityVision().Enabled = true
+	cfg.Protocols.RealityVision().Port = 12345
 
 	// 2. 測試 Hysteria2 (帶寬默認值)
-	cfg.Protocols.Hysteria2.Enabled = true
-	cfg.Protocols.Hysteria2.UpMbps = 0 // 應觸發默認值 100
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.Hysteria2().UpMbps = 0 // 應觸發默認值 100
 
 	protocols := factory.FromConfig(cfg)
 
@@ -101,6 +102,9 @@ func TestFactory_FromConfig(t *testing.T) {
 // TestGetTLSDomain 測試全局 TLS 域名推導
 func TestGetTLSDomain(t *testing.T) {
 	p := &config.ProtocolsConfig{}
+	if err := p.EnsurePrimaries(); err != nil {
+		t.Fatalf("EnsurePrimaries: %v", err)
+	}
 
 	// 初始應返回默認
 	if d := getTLSDomain(p); d != "www.bing.com" {
@@ -108,10 +112,58 @@ func TestGetTLSDomain(t *testing.T) {
 	}
 
 	// 設置 TUIC 為 ACME 模式
-	p.TUIC.CertMode = "acme"
-	p.TUIC.CertDomain = "tuic-cert.com"
+	p.TUIC().CertMode = "acme"
+	p.TUIC().CertDomain = "tuic-cert.com"
 
 	if d := getTLSDomain(p); d != "tuic-cert.com" {
 		t.Errorf("應從 TUIC 推導域名，得到 %s", d)
 	}
 }
+
+// TestFactory_MultipleInstances 測試同一協議的多個實例
+func TestFactory_MultipleInstances(t *testing.T) {
+	factory := NewFactory(&appctx.Paths{CertDir: "/etc/prism/certs"})
+
+	cfg := config.DefaultConfig()
+	cfg.UUID = "test-uuid"
+	for _, d := range Descriptors() {
+		SetEnabled(cfg, d.ID, false)
+	}
+	cfg.Protocols.RealityVision().Enabled = true
+	cfg.Protocols.RealityVision().PrivateKey = "priv"
+	cfg.Protocols.RealityVision().PublicKey = "pub"
+
+	extra := cfg.Protocols.Instance("reality-vision-in").Clone("vision-2")
+	extra.RealityVision.Port = 9443
+	extra.RealityVision.UUID = "extra-uuid"
+	if err := cfg.Protocols.AddInstance(extra); err != nil {
+		t.Fatalf("添加實例失敗: %v", err)
+	}
+
+	protocols := factory.FromConfig(cfg)
+	if len(protocols) != 2 {
+		t.Fatalf("應生成 2 個協議，實際 %d", len(protocols))
+	}
+	if protocols[0].Tag() != "reality-vision-in" || protocols[1].Tag() != "vision-2" {
+		t.Errorf("實例標籤錯誤: %s, %s", protocols[0].Tag(), protocols[1].Tag())
+	}
+	if protocols[1].Port() != 9443 {
+		t.Errorf("實例端口錯誤: %d", protocols[1].Port())
+	}
+
+	inbound, err := protocols[1].ToSingboxInbound()
+	if err != nil {
+		t.Fatalf("生成入站失敗: %v", err)
+	}
+	if inbound["tag"] != "vision-2" {
+		t.Errorf("入站標籤錯誤: %v", inbound["tag"])
+	}
+
+	links := ShareLinks(protocols, "1.2.3.4")
+	if len(links) != 2 {
+		t.Fatalf("應生成 2 條分享鏈接，實際 %d", len(links))
+	}
+	if !strings.Contains(links[1], "extra-uuid@") || !strings.HasSuffix(links[1], "#Reality%20Vision%20%5Bvision-2%5D") {
+		t.Errorf("非主實例鏈接錯誤: %s", links[1])
+	}
+}
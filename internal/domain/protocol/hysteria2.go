@@ -2,9 +2,9 @@ package protocol
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
@@ -15,11 +15,15 @@ type Hysteria2 struct {
 	CertPath    string // 證書路徑
 	KeyPath     string // 密鑰路徑
 	SNI         string
-	ALPN        string // ALPN
-	Obfs        string // 混淆密碼
-	PortHopping string // 端口跳躍範圍 (如: "10000-11000")
-	UpMbps      int    // 上行帶寬 (Mbps)
-	DownMbps    int    // 下行帶寬 (Mbps)
+	ALPN        []string               // ALPN
+	Obfs        string                 // 混淆密碼
+	PortHopping string                 // 端口跳躍範圍，逗號分隔 (如: "10000-11000,20000-21000")
+	HopInterval string                 // 客戶端跳躍間隔 (如: "30s")
+	UpMbps      int                    // 上行帶寬 (Mbps)
+	DownMbps    int                    // 下行帶寬 (Mbps)
+	Masquerade  map[string]interface{} // 偽裝網站，nil 表示關閉
+
+	IgnoreClientBandwidth bool // 關閉 Brutal，客戶端使用 BBR
 }
 
 // NewHysteria2 創建 Hysteria2 協議
@@ -33,27 +37,15 @@ func NewHysteria2(port int, password string) *Hysteria2 {
 		},
 		Password: password,
 		SNI:      "",
-		ALPN:     "h3",
+		ALPN:     []string{"h3"},
 		UpMbps:   100,
 		DownMbps: 100,
 	}
 }
 
-// ParsePortRange 解析端口範圍（支持 - 隔符）
-func ParsePortRange(portRange string) (start, end int) {
-	parts := strings.Split(portRange, "-")
-	if len(parts) != 2 {
-		return 0, 0
-	}
-
-	s, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
-	e, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
-
-	if err1 != nil || err2 != nil {
-		return 0, 0
-	}
-
-	return s, e
+// hoppingConfig 返回跳躍端口配置，便於復用領域層的解析與驗證
+func (h *Hysteria2) hoppingConfig() *domainConfig.Hysteria2Config {
+	return &domainConfig.Hysteria2Config{PortHopping: h.PortHopping, HopInterval: h.HopInterval}
 }
 
 // Validate 驗證配置
@@ -83,14 +75,8 @@ func (h *Hysteria2) Validate() error {
 	}
 
 	// 驗證端口跳躍格式
-	if h.PortHopping != "" {
-		start, end := ParsePortRange(h.PortHopping)
-		if start == 0 || end == 0 || start >= end {
-			return errors.New("PROTO017", fmt.Sprintf("端口跳躍格式錯誤: %s (應為 '20000-30000')", h.PortHopping))
-		}
-		if start < 1024 || end > 65535 {
-			return errors.New("PROTO018", fmt.Sprintf("端口跳躍範圍無效: %s (應在 1024-65535 之間)", h.PortHopping))
-		}
+	if err := h.hoppingConfig().ValidatePortHopping(); err != nil {
+		return errors.New("PROTO017", err.Error())
 	}
 
 	return nil
@@ -107,7 +93,7 @@ func (h *Hysteria2) ToSingboxOutbound() (map[string]interface{}, error) {
 		WithTLS(map[string]interface{}{
 			"enabled":  true,
 			"insecure": false,
-			"alpn":     []string{h.ALPN},
+			"alpn":     h.ALPN,
 		})
 
 	if h.Obfs != "" {
@@ -117,7 +103,22 @@ func (h *Hysteria2) ToSingboxOutbound() (map[string]interface{}, error) {
 		})
 	}
 
-	return builder.Build(), nil
+	out := builder.Build()
+
+	// 端口跳躍: server_ports 與 server_port 互斥
+	if ranges := h.hoppingConfig().HoppingRanges(); len(ranges) > 0 {
+		ports := make([]string, len(ranges))
+		for i, r := range ranges {
+			ports[i] = fmt.Sprintf("%d:%d", r.Start, r.End)
+		}
+		delete(out, "server_port")
+		out["server_ports"] = ports
+		if d := h.hoppingConfig().HopIntervalDuration(); d > 0 {
+			out["hop_interval"] = d.String()
+		}
+	}
+
+	return out, nil
 }
 
 // ToSingboxInbound 轉換為 Sing-box inbound 配置
@@ -126,13 +127,13 @@ func (h *Hysteria2) ToSingboxInbound() (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	builder := NewInboundBuilder("hysteria2", "hysteria2-in", h.port).
+	builder := NewInboundBuilder("hysteria2", h.Tag(), h.port).
 		WithUsers([]map[string]interface{}{
 			{"password": h.Password},
 		}).
 		WithTLS(map[string]interface{}{
 			"enabled":          true,
-			"alpn":             []string{h.ALPN},
+			"alpn":             h.ALPN,
 			"certificate_path": h.CertPath,
 			"key_path":         h.KeyPath,
 		})
@@ -144,12 +145,20 @@ func (h *Hysteria2) ToSingboxInbound() (map[string]interface{}, error) {
 		})
 	}
 
-	if h.UpMbps > 0 {
-		builder.WithField("up_mbps", h.UpMbps)
+	// ignore_client_bandwidth 與 up_mbps/down_mbps 互斥
+	if h.IgnoreClientBandwidth {
+		builder.WithField("ignore_client_bandwidth", true)
+	} else {
+		if h.UpMbps > 0 {
+			builder.WithField("up_mbps", h.UpMbps)
+		}
+		if h.DownMbps > 0 {
+			builder.WithField("down_mbps", h.DownMbps)
+		}
 	}
 
-	if h.DownMbps > 0 {
-		builder.WithField("down_mbps", h.DownMbps)
+	if h.Masquerade != nil {
+		builder.WithField("masquerade", h.Masquerade)
 	}
 
 	return builder.Build(), nil
@@ -159,11 +168,90 @@ func (h *Hysteria2) ToSingboxInbound() (map[string]interface{}, error) {
 func (h *Hysteria2) GenerateShareLink(serverIP string) string {
 	link := fmt.Sprintf("hysteria2://%s@%s:%d", h.Password, serverIP, h.port)
 
+	var params []string
+	if h.SNI != "" {
+		params = append(params, "sni="+h.SNI)
+	}
+	if len(h.ALPN) > 0 {
+		params = append(params, "alpn="+strings.Join(h.ALPN, ","))
+	}
+	params = append(params, "insecure=1")
 	if h.Obfs != "" {
-		link += fmt.Sprintf("?obfs=salamander&obfs-password=%s", h.Obfs)
+		params = append(params, "obfs=salamander", "obfs-password="+h.Obfs)
 	}
+	// 端口跳躍: mport 攜帶完整的範圍列表
+	if ranges := h.hoppingConfig().HoppingRanges(); len(ranges) > 0 {
+		params = append(params, "mport="+domainConfig.FormatPortRanges(ranges))
+	}
+	link += "?" + strings.Join(params, "&")
 
 	link += "#Hysteria2"
 
 	return link
 }
+
+func init() {
+	Register(Descriptor{
+		ID:          IDHysteria2,
+		Type:        TypeHysteria2,
+		Name:        "Hysteria 2",
+		Tag:         "hysteria2-in",
+		ConfigKey:   "hysteria2",
+		Badge:       "[高速]",
+		Description: "基於 UDP，適合惡劣網絡環境搶佔帶寬",
+		NeedsCert:   true,
+		Build:       buildHysteria2,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*Hysteria2).GenerateShareLink(serverIP)
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*Hysteria2).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildHysteria2(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.Hysteria2
+	certPath, keyPath := b.CertPath(c.CertMode, c.CertDomain)
+
+	// 從配置讀取帶寬，如果為 0 則使用默認值 100
+	upMbps, downMbps := c.GetBandwidth()
+
+	return &Hysteria2{
+		BaseProtocol: b.base(TypeHysteria2, "Hysteria2", inst, c.Port),
+		Password:     inst.Credential(c.Password, cfg.Password),
+		CertPath:     certPath,
+		KeyPath:      keyPath,
+		SNI:          b.SNI(c.CertMode, c.CertDomain, c.SNI),
+		ALPN:         c.GetALPN(),
+		UpMbps:       upMbps,
+		DownMbps:     downMbps,
+		Obfs:         c.Obfs,
+		PortHopping:  c.PortHopping,
+		HopInterval:  c.HopInterval,
+		Masquerade:   masquerade(c.Decoy, b.DecoyDir()),
+
+		IgnoreClientBandwidth: c.IgnoreClientBandwidth,
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段
+func (h *Hysteria2) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = "hysteria2"
+	proxy["password"] = h.Password
+	proxy["sni"] = h.SNI
+	proxy["skip-cert-verify"] = true
+	proxy["alpn"] = h.ALPN
+	if h.Obfs != "" {
+		proxy["obfs"] = "salamander"
+		proxy["obfs-password"] = h.Obfs
+	}
+	// 端口跳躍: ports 覆蓋 port，多個範圍以逗號分隔
+	hopping := h.hoppingConfig()
+	if ranges := hopping.HoppingRanges(); len(ranges) > 0 {
+		proxy["ports"] = domainConfig.FormatPortRanges(ranges)
+		if d := hopping.HopIntervalDuration(); d > 0 {
+			proxy["hop-interval"] = int(d.Seconds())
+		}
+	}
+}
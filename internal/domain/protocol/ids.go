@@ -1,6 +1,7 @@
 package protocol
 
 // ID 定義協議的唯一標識符類型
+// 編號會寫入配置 (如端口輪換列表)，已分配的編號不可變更
 type ID int
 
 const (
@@ -12,71 +13,78 @@ const (
 	IDAnyTLS        ID = 5
 	IDAnyTLSReality ID = 6
 	IDShadowTLS     ID = 7
+	IDCDN           ID = 8
+	IDShadowsocks   ID = 9
+	IDNaive         ID = 10
 )
 
+// valid 檢查編號本身是否合法 (不要求已註冊)
+func (id ID) valid() bool {
+	return id > IDNone
+}
+
 // String 實現 Stringer 接口，用於日誌和顯示
 func (id ID) String() string {
-	switch id {
-	case IDRealityVision:
-		return "VLESS Reality Vision"
-	case IDRealityGRPC:
-		return "VLESS Reality gRPC"
-	case IDHysteria2:
-		return "Hysteria 2"
-	case IDTUIC:
-		return "TUIC v5"
-	case IDAnyTLS:
-		return "AnyTLS"
-	case IDAnyTLSReality:
-		return "AnyTLS Reality"
-	case IDShadowTLS:
-		return "ShadowTLS v3"
-	default:
-		return "Unknown"
+	if d, ok := Lookup(id); ok {
+		return d.Name
 	}
+	return "Unknown"
 }
 
-// IsValid 檢查 ID 是否有效
+// IsValid 檢查 ID 是否對應已註冊的協議
 func (id ID) IsValid() bool {
-	return id >= IDRealityVision && id <= IDShadowTLS
+	_, ok := Lookup(id)
+	return ok
 }
 
-// AllIDs 返回所有支持的協議 ID 列表 (用於遍歷)
+// AllIDs 返回所有已註冊的協議 ID 列表 (按編號排序，用於遍歷)
 func AllIDs() []ID {
-	return []ID{
-		IDRealityVision,
-		IDRealityGRPC,
-		IDHysteria2,
-		IDTUIC,
-		IDAnyTLS,
-		IDAnyTLSReality,
-		IDShadowTLS,
+	list := Descriptors()
+	ids := make([]ID, 0, len(list))
+	for _, d := range list {
+		ids = append(ids, d.ID)
 	}
+	return ids
 }
 
+// Tag 返回協議主實例在 sing-box 配置中的入站標籤
 func (id ID) Tag() string {
-	switch id {
-	case IDRealityVision:
-		return "[推薦]"
-	case IDHysteria2:
-		return "[高速]"
-	case IDAnyTLSReality:
-		return "[隧道]"
-	case IDTUIC:
-		return "[極客]"
-	default:
-		return ""
+	if d, ok := Lookup(id); ok {
+		return d.Tag
+	}
+	return ""
+}
+
+// InboundTags 返回主實例流量經過的所有入站標籤
+// 例如 ShadowTLS 握手後轉交本地 Shadowsocks 入站，路由匹配時需同時包含兩者
+func (id ID) InboundTags() []string {
+	d, ok := Lookup(id)
+	if !ok {
+		return nil
+	}
+	return d.InboundTags(d.Tag)
+}
+
+// Network 返回協議監聽使用的傳輸層協議 (tcp / udp)
+func (id ID) Network() string {
+	if d, ok := Lookup(id); ok {
+		return d.Network()
+	}
+	return ""
+}
+
+// Badge 用於列表中顯示的推薦標記
+func (id ID) Badge() string {
+	if d, ok := Lookup(id); ok {
+		return d.Badge
 	}
+	return ""
 }
 
-// 用於顯示詳細說明
+// Description 用於顯示詳細說明
 func (id ID) Description() string {
-	switch id {
-	case IDRealityVision:
-		return "適合大多數網絡環境，穩定性高"
-	case IDHysteria2:
-		return "基於 UDP，適合惡劣網絡環境搶佔帶寬"
-	default:
-		return ""
+	if d, ok := Lookup(id); ok {
+		return d.Description
 	}
+	return ""
 }
@@ -0,0 +1,143 @@
+package protocol
+
+import (
+	"fmt"
+	"net/url"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
+)
+
+// Naive NaiveProxy 協議 (HTTP/2 CONNECT over TLS，可選 HTTP/3)
+// 流量與訪問普通 HTTPS 網站一致，需要受信任的證書，建議使用 ACME
+type Naive struct {
+	BaseProtocol
+	Username string
+	Password string
+	QUIC     bool
+	SNI      string
+	CertPath string
+	KeyPath  string
+	Domain   string // ACME 證書域名，客戶端必須以此地址連接，否則 TLS 校驗失敗
+}
+
+// Validate 驗證配置
+func (n *Naive) Validate() error {
+	if err := n.BaseProtocol.ValidatePort(); err != nil {
+		return err
+	}
+	if n.Username == "" {
+		return errors.New("PROTO021", "用戶名不能為空")
+	}
+	if n.Password == "" {
+		return errors.New("PROTO004", "密碼不能為空")
+	}
+	if n.SNI == "" {
+		return errors.New("PROTO009", "SNI 不能為空")
+	}
+	if n.enabled && n.CertPath == "" {
+		return errors.New("PROTO005", "證書路徑不能為空")
+	}
+	if n.enabled && n.KeyPath == "" {
+		return errors.New("PROTO006", "密鑰路徑不能為空")
+	}
+	return nil
+}
+
+// ToSingboxInbound 轉換為 Sing-box inbound 配置
+func (n *Naive) ToSingboxInbound() (map[string]interface{}, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	builder := NewInboundBuilder("naive", n.Tag(), n.port).
+		WithUsers([]map[string]interface{}{
+			{"username": n.Username, "password": n.Password},
+		}).
+		WithTLS(map[string]interface{}{
+			"enabled":          true,
+			"server_name":      n.SNI,
+			"certificate_path": n.CertPath,
+			"key_path":         n.KeyPath,
+		})
+	// 未指定 network 時同時監聽 TCP 與 UDP (HTTP/3)
+	if !n.QUIC {
+		builder.WithField("network", "tcp")
+	}
+	return builder.Build(), nil
+}
+
+// ToSingboxOutbound 轉換為 Sing-box outbound 配置
+func (n *Naive) ToSingboxOutbound() (map[string]interface{}, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	builder := NewOutboundBuilder("naive", "naive-out", "127.0.0.1", n.port).
+		WithAuth("username", n.Username).
+		WithAuth("password", n.Password).
+		WithTLS(map[string]interface{}{
+			"enabled":     true,
+			"server_name": n.SNI,
+		})
+	if n.QUIC {
+		builder.WithField("quic", true)
+	}
+	return builder.Build(), nil
+}
+
+// GenerateShareLink 生成分享鏈接 (naive+https:// 或 naive+quic://)
+// 使用 ACME 證書時以證書域名代替服務器 IP
+func (n *Naive) GenerateShareLink(serverIP string) string {
+	host := serverIP
+	if n.Domain != "" {
+		host = n.Domain
+	}
+	scheme := "naive+https"
+	if n.QUIC {
+		scheme = "naive+quic"
+	}
+	return fmt.Sprintf("%s://%s@%s#%s",
+		scheme, url.UserPassword(n.Username, n.Password).String(), joinHostPort(host, n.port), url.PathEscape(n.Name()))
+}
+
+func init() {
+	Register(Descriptor{
+		ID:          IDNaive,
+		Type:        TypeNaive,
+		Name:        "NaiveProxy",
+		Tag:         "naive-in",
+		ConfigKey:   "naive",
+		Badge:       "[擬真]",
+		Description: "HTTP/2 CONNECT 代理，流量特徵與真實 HTTPS 網站一致，需要 ACME 證書",
+		NeedsCert:   true,
+		Build:       buildNaive,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*Naive).GenerateShareLink(serverIP)
+		},
+		// Clash Meta 不支持 NaiveProxy，不生成 Clash 節點
+	})
+}
+
+func buildNaive(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.Naive
+	certPath, keyPath := b.CertPath(c.CertMode, c.CertDomain)
+	return &Naive{
+		BaseProtocol: b.base(TypeNaive, "NaiveProxy", inst, c.Port),
+		Username:     c.GetUsername(),
+		Password:     inst.Credential(c.Password, cfg.Password),
+		QUIC:         c.QUIC,
+		SNI:          b.SNI(c.CertMode, c.CertDomain, c.SNI),
+		CertPath:     certPath,
+		KeyPath:      keyPath,
+		Domain:       naiveDomain(c),
+	}
+}
+
+// naiveDomain 返回 ACME 模式下的證書域名
+func naiveDomain(c *domainConfig.NaiveConfig) string {
+	if c.CertMode == "acme" {
+		return c.CertDomain
+	}
+	return ""
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
@@ -18,12 +19,16 @@ const (
 	TypeAnyTLS        Type = "anytls"
 	TypeAnyTLSReality Type = "anytls_reality"
 	TypeShadowTLS     Type = "shadowtls"
+	TypeCDN           Type = "cdn"
+	TypeShadowsocks   Type = "shadowsocks"
+	TypeNaive         Type = "naive"
 )
 
 // Protocol 协议接口
 type Protocol interface {
 	Type() Type
 	Name() string
+	Tag() string // sing-box 入站標籤 (實例標籤)
 	Port() int
 	IsEnabled() bool
 	Validate() error
@@ -37,6 +42,7 @@ type Protocol interface {
 type BaseProtocol struct {
 	type_   Type
 	name    string
+	tag     string
 	port    int
 	enabled bool
 }
@@ -44,6 +50,19 @@ type BaseProtocol struct {
 func (p *BaseProtocol) Type() Type   { return p.type_ }
 func (p *BaseProtocol) Name() string { return p.name }
 func (p *BaseProtocol) Port() int    { return p.port }
+
+// Tag 返回入站標籤，未設置時使用協議主實例的默認標籤
+func (p *BaseProtocol) Tag() string {
+	if p.tag != "" {
+		return p.tag
+	}
+	return domainConfig.DefaultProtocolTag(string(p.type_))
+}
+
+// isPrimary 是否為協議主實例
+func (p *BaseProtocol) isPrimary() bool {
+	return p.Tag() == domainConfig.DefaultProtocolTag(string(p.type_))
+}
 func (p *BaseProtocol) IsEnabled() bool {
 	return p.enabled
 }
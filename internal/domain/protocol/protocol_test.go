@@ -1,7 +1,11 @@
 package protocol
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
 )
 
 // TestShadowTLS_DeepAnalysis 深入測試 ShadowTLS 的入站與出站跳轉邏輯
@@ -118,3 +122,90 @@ func TestProtocol_ValidationErrors(t *testing.T) {
 		t.Error("預期缺少私鑰時報錯，但未報錯")
 	}
 }
+
+// TestHysteria2_PortHopping 測試多範圍端口跳躍在客戶端出站與分享鏈接中的表示
+func TestHysteria2_PortHopping(t *testing.T) {
+	h := NewHysteria2(8443, "pass")
+	h.CertPath, h.KeyPath = "/tmp/cert.pem", "/tmp/key.pem"
+	h.PortHopping = "40000-45000,20000-30000"
+	h.HopInterval = "45s"
+	h.enabled = true
+
+	outbound, err := h.ToSingboxOutbound()
+	if err != nil {
+		t.Fatalf("Hysteria2 Outbound 轉換失敗: %v", err)
+	}
+	if _, ok := outbound["server_port"]; ok {
+		t.Error("設置 server_ports 時不應同時存在 server_port")
+	}
+	ports, _ := outbound["server_ports"].([]string)
+	if len(ports) != 2 || ports[0] != "20000:30000" || ports[1] != "40000:45000" {
+		t.Errorf("server_ports 錯誤: %v", outbound["server_ports"])
+	}
+	if outbound["hop_interval"] != "45s" {
+		t.Errorf("hop_interval 錯誤: %v", outbound["hop_interval"])
+	}
+
+	link := h.GenerateShareLink("1.2.3.4")
+	if link != "hysteria2://pass@1.2.3.4:8443?alpn=h3&insecure=1&mport=20000-30000,40000-45000#Hysteria2" {
+		t.Errorf("分享鏈接錯誤: %s", link)
+	}
+
+	h.PortHopping = "20000-30000,25000-26000"
+	if err := h.Validate(); err == nil {
+		t.Error("重疊的跳躍範圍應驗證失敗")
+	}
+}
+
+// TestHysteria2_Brutal 關閉 Brutal 時輸出 ignore_client_bandwidth 且不再攜帶帶寬
+func TestHysteria2_Brutal(t *testing.T) {
+	cfg := config.DefaultConfig()
+	c := cfg.Protocols.Hysteria2()
+	c.UpMbps, c.DownMbps = 200, 500
+
+	in := buildInbound(t, cfg, IDHysteria2)
+	expectField(t, in, 200, "up_mbps")
+	expectField(t, in, 500, "down_mbps")
+	if _, ok := in["ignore_client_bandwidth"]; ok {
+		t.Error("開啟 Brutal 時不應輸出 ignore_client_bandwidth")
+	}
+
+	c.IgnoreClientBandwidth = true
+	in = buildInbound(t, cfg, IDHysteria2)
+	expectField(t, in, true, "ignore_client_bandwidth")
+	for _, key := range []string{"up_mbps", "down_mbps"} {
+		if _, ok := in[key]; ok {
+			t.Errorf("ignore_client_bandwidth 與 %s 互斥", key)
+		}
+	}
+}
+
+// TestTUIC_ClientAgreement 擁塞控制、UDP 轉發模式與心跳在出站、Clash 與分享鏈接中保持一致
+func TestTUIC_ClientAgreement(t *testing.T) {
+	cfg := config.DefaultConfig()
+	c := cfg.Protocols.TUIC()
+	c.CongestionControl, c.UDPRelayMode, c.Heartbeat = "new_reno", "quic", "15s"
+	buildInbound(t, cfg, IDTUIC)
+
+	p := NewFactory(&appctx.Paths{CertDir: "/etc/prism/certs"}).FromConfig(cfg)[0].(*TUIC)
+	out, err := p.ToSingboxOutbound()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectField(t, out, "new_reno", "congestion_control")
+	expectField(t, out, "quic", "udp_relay_mode")
+	expectField(t, out, "15s", "heartbeat")
+
+	proxy := map[string]interface{}{}
+	p.fillClashProxy(proxy)
+	expectField(t, proxy, "new_reno", "congestion-controller")
+	expectField(t, proxy, "quic", "udp-relay-mode")
+	expectField(t, proxy, int64(15000), "heartbeat-interval")
+
+	link := p.GenerateShareLink("1.2.3.4")
+	for _, want := range []string{"congestion_control=new_reno", "udp_relay_mode=quic"} {
+		if !strings.Contains(link, want) {
+			t.Errorf("分享鏈接缺少 %s: %s", want, link)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package protocol
 import (
 	"fmt"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
@@ -113,7 +114,7 @@ func (r *RealityGRPC) ToSingboxInbound() (map[string]interface{}, error) {
 	}
 
 	// 使用构建器
-	return NewInboundBuilder("vless", "reality-grpc-in", r.port).
+	return NewInboundBuilder("vless", r.Tag(), r.port).
 		WithUsers(users).
 		WithTransport(map[string]interface{}{
 			"type":         "grpc",
@@ -146,7 +147,7 @@ func (r *RealityGRPC) AddUser(uuid string) {
 // GenerateShareLink 生成分享链接
 func (r *RealityGRPC) GenerateShareLink(serverIP string, user User) string {
 	return fmt.Sprintf(
-		"vless://%s@%s:%d?type=grpc&serviceName=%s&security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s#Reality-gRPC",
+		"vless://%s@%s:%d?encryption=none&type=grpc&serviceName=%s&mode=gun&security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s#Reality-gRPC",
 		user.UUID,
 		serverIP,
 		r.port,
@@ -156,3 +157,56 @@ func (r *RealityGRPC) GenerateShareLink(serverIP string, user User) string {
 		r.ShortID,
 	)
 }
+
+func init() {
+	Register(Descriptor{
+		ID:           IDRealityGRPC,
+		Type:         TypeRealityGRPC,
+		Name:         "VLESS Reality gRPC",
+		Tag:          "reality-grpc-in",
+		ConfigKey:    "reality_grpc",
+		NeedsReality: true,
+		Build:        buildRealityGRPC,
+		ShareLink: func(p Protocol, serverIP string) string {
+			r := p.(*RealityGRPC)
+			if len(r.Users) == 0 {
+				return ""
+			}
+			return r.GenerateShareLink(serverIP, r.Users[0])
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*RealityGRPC).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildRealityGRPC(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.RealityGRPC
+	return &RealityGRPC{
+		BaseProtocol: b.base(TypeRealityGRPC, "Reality gRPC", inst, c.Port),
+		SNI:          c.SNI,
+		PublicKey:    c.PublicKey,
+		PrivateKey:   c.PrivateKey,
+		ShortID:      c.ShortID,
+		ServiceName:  "grpc",
+		Users:        []User{{UUID: inst.Credential(c.UUID, cfg.UUID), Flow: ""}},
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段
+func (r *RealityGRPC) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = "vless"
+	proxy["uuid"] = r.Users[0].UUID
+	proxy["network"] = "grpc"
+	proxy["tls"] = true
+	proxy["udp"] = true
+	proxy["servername"] = r.SNI
+	proxy["client-fingerprint"] = "chrome"
+	proxy["grpc-opts"] = map[string]interface{}{
+		"grpc-service-name": r.ServiceName,
+	}
+	proxy["reality-opts"] = map[string]interface{}{
+		"public-key": r.PublicKey,
+		"short-id":   r.ShortID,
+	}
+}
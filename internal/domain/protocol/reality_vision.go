@@ -3,6 +3,7 @@ package protocol
 import (
 	"fmt"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
@@ -111,7 +112,7 @@ func (r *RealityVision) ToSingboxInbound() (map[string]interface{}, error) {
 	}
 
 	// 使用构建器
-	return NewInboundBuilder("vless", "reality-vision-in", r.port).
+	return NewInboundBuilder("vless", r.Tag(), r.port).
 		WithUsers(users).
 		WithTLS(map[string]interface{}{
 			"enabled":     true,
@@ -140,7 +141,7 @@ func (r *RealityVision) AddUser(uuid, flow string) {
 // GenerateShareLink 生成分享链接
 func (r *RealityVision) GenerateShareLink(serverIP string, user User) string {
 	return fmt.Sprintf(
-		"vless://%s@%s:%d?type=tcp&security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s&flow=%s#Reality-Vision",
+		"vless://%s@%s:%d?encryption=none&type=tcp&headerType=none&security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s&flow=%s#Reality-Vision",
 		user.UUID,
 		serverIP,
 		r.port,
@@ -150,3 +151,55 @@ func (r *RealityVision) GenerateShareLink(serverIP string, user User) string {
 		user.Flow,
 	)
 }
+
+func init() {
+	Register(Descriptor{
+		ID:           IDRealityVision,
+		Type:         TypeRealityVision,
+		Name:         "VLESS Reality Vision",
+		Tag:          "reality-vision-in",
+		ConfigKey:    "reality_vision",
+		Badge:        "[推薦]",
+		Description:  "適合大多數網絡環境，穩定性高",
+		NeedsReality: true,
+		Build:        buildRealityVision,
+		ShareLink: func(p Protocol, serverIP string) string {
+			r := p.(*RealityVision)
+			if len(r.Users) == 0 {
+				return ""
+			}
+			return r.GenerateShareLink(serverIP, r.Users[0])
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*RealityVision).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildRealityVision(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.RealityVision
+	return &RealityVision{
+		BaseProtocol: b.base(TypeRealityVision, "Reality Vision", inst, c.Port),
+		SNI:          c.SNI,
+		PublicKey:    c.PublicKey,
+		PrivateKey:   c.PrivateKey,
+		ShortID:      c.ShortID,
+		Users:        []User{{UUID: inst.Credential(c.UUID, cfg.UUID), Flow: "xtls-rprx-vision"}},
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段
+func (r *RealityVision) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = "vless"
+	proxy["uuid"] = r.Users[0].UUID
+	proxy["network"] = "tcp"
+	proxy["tls"] = true
+	proxy["udp"] = true
+	proxy["flow"] = "xtls-rprx-vision"
+	proxy["servername"] = r.SNI
+	proxy["client-fingerprint"] = "chrome"
+	proxy["reality-opts"] = map[string]interface{}{
+		"public-key": r.PublicKey,
+		"short-id":   r.ShortID,
+	}
+}
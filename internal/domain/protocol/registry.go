@@ -0,0 +1,260 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+)
+
+// Descriptor 協議描述符
+// 每個協議在自身文件的 init 中註冊，工廠、端口、分享鏈接、客戶端配置與 TUI 列表均遍歷註冊表。
+// 新增協議另需：ids.go 中的編號、config 包中的配置類型 (含 protocolSchemas 登記、默認值與驗證)，
+// 以及協議特有的 TUI 參數頁與高級選項
+type Descriptor struct {
+	ID          ID
+	Type        Type
+	Name        string // 顯示名稱
+	Tag         string // 主實例的 sing-box 入站標籤
+	ConfigKey   string // 配置中的協議類型，對應 ProtocolSection.Key
+	Badge       string // 列表中的推薦標記
+	Description string // 詳細說明
+
+	// ExtraInboundTags 按實例標籤返回流量還會經過的入站標籤 (如 ShadowTLS 轉交的本地 Shadowsocks 入站)
+	ExtraInboundTags func(tag string) []string
+
+	// NeedsCert 使用本地證書 (自簽名 / ACME)；NeedsReality 使用 Reality 密鑰
+	NeedsCert    bool
+	NeedsReality bool
+
+	// Build 由配置中的協議實例構建協議 (僅對已啟用的實例調用)
+	Build func(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol
+	// ShareLink 生成分享鏈接，返回空字符串表示無法生成
+	ShareLink func(p Protocol, serverIP string) string
+	// Clash 填充 Clash Meta 代理字段 (name / server / port 已預先設置)，為 nil 表示不支持
+	Clash func(p Protocol, proxy map[string]interface{})
+}
+
+// Section 返回協議主實例在配置中的公共字段視圖
+func (d *Descriptor) Section(cfg *domainConfig.Config) (domainConfig.ProtocolSection, bool) {
+	if cfg == nil {
+		return domainConfig.ProtocolSection{}, false
+	}
+	return cfg.Protocols.Section(d.ConfigKey)
+}
+
+// blankSection 返回協議空白主實例的公共字段視圖 (不依賴具體配置)
+func (d *Descriptor) blankSection() (domainConfig.ProtocolSection, bool) {
+	inst, err := domainConfig.NewProtocolInstance(domainConfig.DefaultProtocolTag(d.ConfigKey), d.ConfigKey)
+	if err != nil {
+		return domainConfig.ProtocolSection{}, false
+	}
+	return inst.Section()
+}
+
+// Network 返回協議監聽使用的傳輸層協議
+func (d *Descriptor) Network() string {
+	sec, _ := d.blankSection()
+	return sec.Network
+}
+
+// InstanceName 返回協議實例的顯示名稱，非主實例附加標籤以保證客戶端節點名稱唯一
+func (d *Descriptor) InstanceName(inst *domainConfig.ProtocolInstance) string {
+	if inst == nil || inst.IsPrimary() {
+		return d.Name
+	}
+	return fmt.Sprintf("%s [%s]", d.Name, inst.Tag)
+}
+
+// InstanceName 返回協議實例的顯示名稱
+func InstanceName(inst *domainConfig.ProtocolInstance) string {
+	if d, ok := LookupType(Type(inst.Type)); ok {
+		return d.InstanceName(inst)
+	}
+	return inst.Tag
+}
+
+// InboundTags 返回協議實例流量經過的所有入站標籤
+func (d *Descriptor) InboundTags(tag string) []string {
+	tags := []string{tag}
+	if d.ExtraInboundTags != nil {
+		tags = append(tags, d.ExtraInboundTags(tag)...)
+	}
+	return tags
+}
+
+// Fronted 由前置服務 (如 CDN) 轉發的協議，客戶端連接地址與端口不同於服務端監聽
+type Fronted interface {
+	ClientEndpoint() (host string, port int)
+}
+
+// ClientAddress 返回客戶端連接協議實例使用的地址
+// 經 CDN 中轉時使用 CDN 域名；ACME 證書模式下必須使用證書域名，否則 TLS 校驗失敗
+func ClientAddress(cfg *domainConfig.Config, p Protocol, defaultHost string) string {
+	if f, ok := p.(Fronted); ok {
+		if host, _ := f.ClientEndpoint(); host != "" {
+			return host
+		}
+	}
+	if cfg == nil {
+		return defaultHost
+	}
+	inst := cfg.Protocols.Instance(p.Tag())
+	if inst == nil {
+		return defaultHost
+	}
+	sec, ok := inst.Section()
+	if !ok || !sec.NeedsCert() {
+		return defaultHost
+	}
+	if *sec.CertMode == "acme" && *sec.CertDomain != "" {
+		return *sec.CertDomain
+	}
+	return defaultHost
+}
+
+// ClientPort 返回客戶端連接協議實例使用的端口
+func ClientPort(p Protocol) int {
+	if f, ok := p.(Fronted); ok {
+		if _, port := f.ClientEndpoint(); port > 0 {
+			return port
+		}
+	}
+	return p.Port()
+}
+
+var (
+	registryMu  sync.RWMutex
+	descriptors = map[ID]*Descriptor{}
+)
+
+// Register 註冊協議描述符，描述不完整或重複註冊時 panic
+func Register(d Descriptor) {
+	if !d.ID.valid() {
+		panic(fmt.Sprintf("protocol: invalid id %d", d.ID))
+	}
+	if d.Build == nil || d.ShareLink == nil {
+		panic(fmt.Sprintf("protocol: %s missing builder or share link codec", d.Type))
+	}
+	sec, ok := d.blankSection()
+	if !ok {
+		panic(fmt.Sprintf("protocol: %s has no config section %q", d.Type, d.ConfigKey))
+	}
+	if d.NeedsCert != sec.NeedsCert() || d.NeedsReality != sec.NeedsReality() {
+		panic(fmt.Sprintf("protocol: %s cert/reality flags do not match config section", d.Type))
+	}
+	if d.Tag != sec.Tag {
+		panic(fmt.Sprintf("protocol: %s tag %q does not match primary instance tag %q", d.Type, d.Tag, sec.Tag))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, existing := range descriptors {
+		if existing.ID == d.ID || existing.Type == d.Type || existing.Tag == d.Tag {
+			panic(fmt.Sprintf("protocol: %s registered twice", d.Type))
+		}
+	}
+	descriptors[d.ID] = &d
+}
+
+// Lookup 按 ID 查找協議描述符
+func Lookup(id ID) (*Descriptor, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	d, ok := descriptors[id]
+	return d, ok
+}
+
+// LookupType 按協議類型查找描述符
+func LookupType(t Type) (*Descriptor, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, d := range descriptors {
+		if d.Type == t {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// Descriptors 返回所有已註冊的協議描述符，按 ID 排序
+func Descriptors() []*Descriptor {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	list := make([]*Descriptor, 0, len(descriptors))
+	for _, d := range descriptors {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// CertIDs 從給定的協議編號中篩選出使用本地證書的協議 (保持輸入順序)
+func CertIDs(ids []int) []ID {
+	var result []ID
+	for _, n := range ids {
+		if d, ok := Lookup(ID(n)); ok && d.NeedsCert {
+			result = append(result, d.ID)
+		}
+	}
+	return result
+}
+
+// section 返回協議 ID 對應主實例的配置字段視圖
+func section(cfg *domainConfig.Config, id ID) (domainConfig.ProtocolSection, bool) {
+	d, ok := Lookup(id)
+	if !ok {
+		return domainConfig.ProtocolSection{}, false
+	}
+	return d.Section(cfg)
+}
+
+// IsEnabled 判斷協議主實例在配置中是否啓用
+func IsEnabled(cfg *domainConfig.Config, id ID) bool {
+	sec, ok := section(cfg, id)
+	return ok && *sec.Enabled
+}
+
+// SetEnabled 設置協議主實例開關，協議未註冊時返回 false
+func SetEnabled(cfg *domainConfig.Config, id ID, enabled bool) bool {
+	sec, ok := section(cfg, id)
+	if ok {
+		*sec.Enabled = enabled
+	}
+	return ok
+}
+
+// PortOf 返回協議主實例在配置中的監聽端口
+func PortOf(cfg *domainConfig.Config, id ID) int {
+	if sec, ok := section(cfg, id); ok {
+		return *sec.Port
+	}
+	return 0
+}
+
+// SetPort 設置協議主實例監聽端口，協議未註冊時返回 false
+func SetPort(cfg *domainConfig.Config, id ID, port int) bool {
+	sec, ok := section(cfg, id)
+	if ok {
+		*sec.Port = port
+	}
+	return ok
+}
+
+// SetSNI 設置協議主實例 SNI，協議未註冊時返回 false
+func SetSNI(cfg *domainConfig.Config, id ID, sni string) bool {
+	sec, ok := section(cfg, id)
+	if ok {
+		*sec.SNI = sni
+	}
+	return ok
+}
+
+// RoutingProfile 返回協議主實例的入站路由策略 (可直接修改)
+func RoutingProfile(cfg *domainConfig.Config, id ID) *domainConfig.InboundRouting {
+	if sec, ok := section(cfg, id); ok {
+		return sec.Routing
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+)
+
+// TestRegistry_BuiltinProtocols 內置協議的編號、標籤與傳輸層協議保持不變
+func TestRegistry_BuiltinProtocols(t *testing.T) {
+	want := []struct {
+		id      ID
+		name    string
+		tag     string
+		network string
+	}{
+		{IDRealityVision, "VLESS Reality Vision", "reality-vision-in", "tcp"},
+		{IDRealityGRPC, "VLESS Reality gRPC", "reality-grpc-in", "tcp"},
+		{IDHysteria2, "Hysteria 2", "hysteria2-in", "udp"},
+		{IDTUIC, "TUIC v5", "tuic-in", "udp"},
+		{IDAnyTLS, "AnyTLS", "anytls-in", "tcp"},
+		{IDAnyTLSReality, "AnyTLS Reality", "anytls-reality-in", "tcp"},
+		{IDShadowTLS, "ShadowTLS v3", "shadowtls-in", "tcp"},
+		{IDCDN, "CDN 中轉", "cdn-in", "tcp"},
+		{IDShadowsocks, "Shadowsocks 2022", "shadowsocks-in", "tcp"},
+		{IDNaive, "NaiveProxy", "naive-in", "tcp"},
+	}
+
+	ids := AllIDs()
+	if len(ids) != len(want) {
+		t.Fatalf("已註冊協議數量錯誤: %v", ids)
+	}
+	for i, w := range want {
+		if ids[i] != w.id {
+			t.Errorf("AllIDs 順序錯誤: %v", ids)
+		}
+		if w.id.String() != w.name || w.id.Tag() != w.tag || w.id.Network() != w.network {
+			t.Errorf("%d 描述錯誤: %s %s %s", w.id, w.id.String(), w.id.Tag(), w.id.Network())
+		}
+	}
+
+	if got := IDShadowTLS.InboundTags(); len(got) != 2 || got[1] != ShadowTLSDetourTag {
+		t.Errorf("ShadowTLS 應包含 detour 入站標籤: %v", got)
+	}
+	if ID(99).IsValid() || ID(99).String() != "Unknown" || ID(99).Network() != "" {
+		t.Error("未註冊的 ID 不應有效")
+	}
+}
+
+// TestRegistry_RejectsDuplicate 重複註冊應 panic
+func TestRegistry_RejectsDuplicate(t *testing.T) {
+	d, _ := Lookup(IDTUIC)
+	defer func() {
+		if recover() == nil {
+			t.Error("重複註冊應 panic")
+		}
+	}()
+	Register(*d)
+}
+
+// TestRegistry_Descriptors 每個協議描述符都能構建實例並生成鏈接與客戶端配置
+func TestRegistry_Descriptors(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.UUID = "b831381d-6324-4d53-ad4f-8cda48b30811"
+	cfg.Password = "test-pass"
+
+	b := &BuildContext{paths: &appctx.Paths{CertDir: "/etc/prism/certs"}, tlsDomain: "www.bing.com"}
+
+	for _, d := range Descriptors() {
+		t.Run(string(d.Type), func(t *testing.T) {
+			SetEnabled(cfg, d.ID, true)
+			SetPort(cfg, d.ID, 20000+int(d.ID))
+			if !IsEnabled(cfg, d.ID) || PortOf(cfg, d.ID) != 20000+int(d.ID) {
+				t.Fatal("配置字段讀寫失敗")
+			}
+
+			p := d.Build(b, cfg, cfg.Protocols.Instance(d.Tag))
+			if p.Type() != d.Type || p.Port() != 20000+int(d.ID) || !p.IsEnabled() {
+				t.Fatalf("構建的實例不匹配: %s %d", p.Type(), p.Port())
+			}
+			if _, err := p.ToSingboxInbound(); err != nil && !d.NeedsReality {
+				t.Errorf("生成入站失敗: %v", err)
+			}
+
+			if link := d.ShareLink(p, "1.2.3.4"); !strings.Contains(link, "@1.2.3.4:") {
+				t.Errorf("分享鏈接錯誤: %s", link)
+			}
+
+			if d.Clash != nil {
+				proxy := map[string]interface{}{}
+				d.Clash(p, proxy)
+				if proxy["type"] == nil {
+					t.Error("Clash 映射缺少 type")
+				}
+			}
+
+			if RoutingProfile(cfg, d.ID) == nil {
+				t.Error("缺少入站路由策略")
+			}
+		})
+	}
+}
+
+// TestClientAddress ACME 模式下客戶端應連接證書域名
+func TestClientAddress(t *testing.T) {
+	cfg := config.DefaultConfig()
+	hy2 := &Hysteria2{BaseProtocol: BaseProtocol{type_: TypeHysteria2}}
+	rv := &RealityVision{BaseProtocol: BaseProtocol{type_: TypeRealityVision}}
+
+	cfg.Protocols.Hysteria2().CertMode = "self_signed"
+	if got := ClientAddress(cfg, hy2, "1.2.3.4"); got != "1.2.3.4" {
+		t.Errorf("自簽名模式應使用服務器地址, got %s", got)
+	}
+
+	cfg.Protocols.Hysteria2().CertMode = "acme"
+	cfg.Protocols.Hysteria2().CertDomain = "hy2.example.com"
+	if got := ClientAddress(cfg, hy2, "1.2.3.4"); got != "hy2.example.com" {
+		t.Errorf("ACME 模式應使用證書域名, got %s", got)
+	}
+	if got := ClientAddress(cfg, rv, "1.2.3.4"); got != "1.2.3.4" {
+		t.Errorf("Reality 協議不受證書模式影響, got %s", got)
+	}
+
+	if got := CertIDs([]int{1, 3, 4, 7, 99}); len(got) != 2 || got[0] != IDHysteria2 || got[1] != IDTUIC {
+		t.Errorf("CertIDs 篩選錯誤: %v", got)
+	}
+}
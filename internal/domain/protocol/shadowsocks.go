@@ -0,0 +1,170 @@
+package protocol
+
+import (
+	"fmt"
+	"net/url"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
+)
+
+// Shadowsocks Shadowsocks 2022 協議
+// 多用戶模式下客戶端密碼為「服務端密鑰:用戶密鑰」(EIH)，中轉模式下為「中轉密鑰:目標密鑰」
+type Shadowsocks struct {
+	BaseProtocol
+	Method   string
+	Password string // 服務端密鑰
+	Users    []domainConfig.ShadowsocksUser
+	Relays   []domainConfig.ShadowsocksRelay
+}
+
+// SSUserLink 單個用戶或中轉目標的分享鏈接
+type SSUserLink struct {
+	Name string
+	URL  string
+}
+
+// Validate 驗證配置
+func (s *Shadowsocks) Validate() error {
+	if err := s.BaseProtocol.ValidatePort(); err != nil {
+		return err
+	}
+	if !domainConfig.ValidSSKey(s.Method, s.Password) {
+		return errors.New("PROTO011", fmt.Sprintf("%s 的密鑰必須為 %d 字節的 Base64 字符串", s.Method, domainConfig.SSKeyLength(s.Method)))
+	}
+	if len(s.Users) > 0 && len(s.Relays) > 0 {
+		return errors.New("PROTO020", "多用戶模式與中轉模式不能同時啟用")
+	}
+	return nil
+}
+
+// ssCredential 客戶端的名稱與密碼
+type ssCredential struct {
+	name     string
+	password string
+}
+
+// credentials 返回每個客戶端的憑據，單用戶模式只有服務端密鑰
+func (s *Shadowsocks) credentials() []ssCredential {
+	var creds []ssCredential
+	for _, u := range s.Users {
+		creds = append(creds, ssCredential{u.Name, s.Password + ":" + u.Password})
+	}
+	for _, r := range s.Relays {
+		creds = append(creds, ssCredential{r.Name, s.Password + ":" + r.Password})
+	}
+	if len(creds) == 0 {
+		creds = append(creds, ssCredential{"", s.Password})
+	}
+	return creds
+}
+
+// clientPassword 返回客戶端配置使用的密碼 (多用戶或中轉模式取第一個)
+func (s *Shadowsocks) clientPassword() string {
+	return s.credentials()[0].password
+}
+
+// ToSingboxInbound 轉換為 Sing-box inbound 配置
+func (s *Shadowsocks) ToSingboxInbound() (map[string]interface{}, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	builder := NewInboundBuilder("shadowsocks", s.Tag(), s.port).
+		WithField("method", s.Method).
+		WithField("password", s.Password)
+
+	if len(s.Users) > 0 {
+		users := make([]map[string]interface{}, 0, len(s.Users))
+		for _, u := range s.Users {
+			users = append(users, map[string]interface{}{"name": u.Name, "password": u.Password})
+		}
+		builder.WithUsers(users)
+	}
+	if len(s.Relays) > 0 {
+		destinations := make([]map[string]interface{}, 0, len(s.Relays))
+		for _, r := range s.Relays {
+			destinations = append(destinations, map[string]interface{}{
+				"name":        r.Name,
+				"server":      r.Server,
+				"server_port": r.ServerPort,
+				"password":    r.Password,
+			})
+		}
+		builder.WithField("destinations", destinations)
+	}
+	return builder.Build(), nil
+}
+
+// ToSingboxOutbound 轉換為 Sing-box outbound 配置
+func (s *Shadowsocks) ToSingboxOutbound() (map[string]interface{}, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return NewOutboundBuilder("shadowsocks", "shadowsocks-out", "127.0.0.1", s.port).
+		WithField("method", s.Method).
+		WithAuth("password", s.clientPassword()).
+		Build(), nil
+}
+
+// UserLinks 為每個用戶 (或中轉目標) 生成 SIP002 分享鏈接
+// 2022 加密方式按 SIP022 使用百分號編碼的「加密方式:密碼」而非 Base64
+func (s *Shadowsocks) UserLinks(serverIP string) []SSUserLink {
+	var links []SSUserLink
+	for _, c := range s.credentials() {
+		name := s.Name()
+		if c.name != "" {
+			name = fmt.Sprintf("%s [%s]", name, c.name)
+		}
+		links = append(links, SSUserLink{
+			Name: name,
+			URL: fmt.Sprintf("ss://%s:%s@%s#%s",
+				s.Method, url.QueryEscape(c.password), joinHostPort(serverIP, s.port), url.PathEscape(name)),
+		})
+	}
+	return links
+}
+
+// GenerateShareLink 生成分享鏈接 (多用戶模式為第一個用戶)
+func (s *Shadowsocks) GenerateShareLink(serverIP string) string {
+	return s.UserLinks(serverIP)[0].URL
+}
+
+func init() {
+	Register(Descriptor{
+		ID:          IDShadowsocks,
+		Type:        TypeShadowsocks,
+		Name:        "Shadowsocks 2022",
+		Tag:         "shadowsocks-in",
+		ConfigKey:   "shadowsocks",
+		Badge:       "[經典]",
+		Description: "SS 2022 多用戶 (EIH) 與中轉，客戶端兼容性最好",
+		Build:       buildShadowsocks,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*Shadowsocks).GenerateShareLink(serverIP)
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*Shadowsocks).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildShadowsocks(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.Shadowsocks
+	return &Shadowsocks{
+		BaseProtocol: b.base(TypeShadowsocks, "Shadowsocks 2022", inst, c.Port),
+		Method:       c.Method,
+		Password:     c.Password,
+		Users:        c.Users,
+		Relays:       c.Relays,
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段 (多用戶模式為第一個用戶)
+func (s *Shadowsocks) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = "ss"
+	proxy["cipher"] = s.Method
+	proxy["password"] = s.clientPassword()
+	proxy["udp"] = true
+}
@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+)
+
+// TestShadowsocks_Modes 單用戶、多用戶與中轉模式的入站、鏈接與客戶端配置
+func TestShadowsocks_Modes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	c := cfg.Protocols.Shadowsocks()
+	c.Port = 20009
+	c.Password = "3lx6tvAlCqDJIYcSgy4clw=="
+
+	in := buildInbound(t, cfg, IDShadowsocks)
+	expectField(t, in, "shadowsocks", "type")
+	expectField(t, in, config.SS2022AES128GCM, "method")
+	expectField(t, in, c.Password, "password")
+	if _, ok := in["users"]; ok {
+		t.Error("單用戶模式不應包含 users")
+	}
+
+	p := NewFactory(nil).FromConfig(cfg)[0].(*Shadowsocks)
+	if link := p.GenerateShareLink("1.2.3.4"); link != "ss://2022-blake3-aes-128-gcm:3lx6tvAlCqDJIYcSgy4clw%3D%3D@1.2.3.4:20009#Shadowsocks%202022" {
+		t.Errorf("SIP002 鏈接錯誤: %s", link)
+	}
+
+	// 多用戶: 客戶端密碼為「服務端密鑰:用戶密鑰」
+	c.Users = []config.ShadowsocksUser{
+		{Name: "alice", Password: "8JCsPssfgS8tiRwiMlhARg=="},
+		{Name: "bob", Password: "bBWByFZ7qAIu3v5fMDD0rA=="},
+	}
+	in = buildInbound(t, cfg, IDShadowsocks)
+	users := in["users"].([]map[string]interface{})
+	if len(users) != 2 || users[1]["name"] != "bob" {
+		t.Errorf("多用戶映射錯誤: %v", users)
+	}
+
+	p = NewFactory(nil).FromConfig(cfg)[0].(*Shadowsocks)
+	links := p.UserLinks("1.2.3.4")
+	if len(links) != 2 || !strings.Contains(links[1].URL, "3lx6tvAlCqDJIYcSgy4clw%3D%3D%3AbBWByFZ7qAIu3v5fMDD0rA%3D%3D@") {
+		t.Errorf("多用戶鏈接錯誤: %+v", links)
+	}
+	proxy := map[string]interface{}{}
+	p.fillClashProxy(proxy)
+	expectField(t, proxy, "3lx6tvAlCqDJIYcSgy4clw==:8JCsPssfgS8tiRwiMlhARg==", "password")
+	expectField(t, proxy, config.SS2022AES128GCM, "cipher")
+
+	// 中轉: 目標寫入 destinations
+	c.Users = nil
+	c.Relays = []config.ShadowsocksRelay{{Name: "hk", Server: "203.0.113.5", ServerPort: 8388, Password: "bBWByFZ7qAIu3v5fMDD0rA=="}}
+	in = buildInbound(t, cfg, IDShadowsocks)
+	dest := in["destinations"].([]map[string]interface{})
+	if len(dest) != 1 || dest[0]["server"] != "203.0.113.5" || dest[0]["server_port"] != 8388 {
+		t.Errorf("中轉目標映射錯誤: %v", dest)
+	}
+
+	c.Password = "too-short"
+	if _, err := NewFactory(nil).FromConfig(cfg)[0].ToSingboxInbound(); err == nil {
+		t.Error("密鑰長度不符應返回錯誤")
+	}
+}
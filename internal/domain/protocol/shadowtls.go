@@ -4,9 +4,29 @@ import (
 	"encoding/base64"
 	"fmt"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
+// ShadowTLSDetourTag ShadowTLS 主實例轉交的本地 Shadowsocks 入站標籤
+const ShadowTLSDetourTag = "shadowtls-ss-in"
+
+// shadowTLSDetourTag 返回實例轉交的本地 Shadowsocks 入站標籤
+func shadowTLSDetourTag(tag string) string {
+	if tag == domainConfig.DefaultProtocolTag(string(TypeShadowTLS)) {
+		return ShadowTLSDetourTag
+	}
+	return tag + "-ss"
+}
+
+// detourOutboundTag 返回客戶端 shadowtls 出站標籤
+func (s *ShadowTLS) detourOutboundTag() string {
+	if s.isPrimary() {
+		return "shadowtls-detour"
+	}
+	return s.Tag() + "-detour"
+}
+
 // ShadowTLS ShadowTLS v3 协议
 type ShadowTLS struct {
 	BaseProtocol
@@ -29,9 +49,9 @@ func NewShadowTLS(port int, password, ssPassword string) *ShadowTLS {
 		},
 		Password:   password,
 		SSPassword: ssPassword,
-		SSMethod:   "2022-blake3-aes-128-gcm",
+		SSMethod:   domainConfig.DefaultShadowTLSSSMethod,
 		SNI:        "www.microsoft.com",
-		DetourPort: 10000,
+		DetourPort: domainConfig.DefaultShadowTLSDetourPort,
 		StrictMode: true,
 	}
 }
@@ -73,7 +93,7 @@ func (s *ShadowTLS) ToSingboxOutbound() (map[string]interface{}, error) {
 
 	// ✅ 使用构建器 - shadowsocks outbound with detour
 	return NewOutboundBuilder("shadowsocks", "shadowtls-out", "", 0).
-		WithField("detour", "shadowtls-detour").
+		WithField("detour", s.detourOutboundTag()).
 		WithField("method", s.SSMethod).
 		WithField("password", s.SSPassword).
 		Build(), nil
@@ -82,7 +102,7 @@ func (s *ShadowTLS) ToSingboxOutbound() (map[string]interface{}, error) {
 // GetDetourOutbound 获取 detour outbound 配置
 func (s *ShadowTLS) GetDetourOutbound() map[string]interface{} {
 	// ✅ 使用构建器
-	return NewOutboundBuilder("shadowtls", "shadowtls-detour", "127.0.0.1", s.port).
+	return NewOutboundBuilder("shadowtls", s.detourOutboundTag(), "127.0.0.1", s.port).
 		WithField("version", 3).
 		WithField("password", s.Password).
 		WithTLS(map[string]interface{}{
@@ -98,7 +118,7 @@ func (s *ShadowTLS) ToSingboxInbound() (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	return NewInboundBuilder("shadowtls", "shadowtls-in", s.Port()).
+	return NewInboundBuilder("shadowtls", s.Tag(), s.Port()).
 		WithField("version", 3).
 		WithUsers([]map[string]interface{}{
 			{"password": s.Password},
@@ -107,7 +127,7 @@ func (s *ShadowTLS) ToSingboxInbound() (map[string]interface{}, error) {
 			"server":      s.SNI,
 			"server_port": 443,
 		}).
-		WithField("detour", "shadowtls-ss-in").
+		WithField("detour", shadowTLSDetourTag(s.Tag())).
 		WithField("strict_mode", s.StrictMode).
 		Build(), nil
 }
@@ -115,7 +135,7 @@ func (s *ShadowTLS) ToSingboxInbound() (map[string]interface{}, error) {
 // GetDetourInbound 获取 detour inbound 配置
 func (s *ShadowTLS) GetDetourInbound() map[string]interface{} {
 	// ✅ 使用构建器（注意：这里 listen 是 127.0.0.1，不是 ::）
-	builder := NewInboundBuilder("shadowsocks", "shadowtls-ss-in", s.DetourPort)
+	builder := NewInboundBuilder("shadowsocks", shadowTLSDetourTag(s.Tag()), s.DetourPort)
 	config := builder.Build()
 	config["listen"] = "127.0.0.1" // 覆盖默认的 "::"
 	config["method"] = s.SSMethod
@@ -134,3 +154,48 @@ func (s *ShadowTLS) GenerateShareLink(serverIP string) string {
 		ssInfoB64, serverIP, s.port, s.SNI, s.Password,
 	)
 }
+
+func init() {
+	Register(Descriptor{
+		ID:               IDShadowTLS,
+		Type:             TypeShadowTLS,
+		Name:             "ShadowTLS v3",
+		Tag:              "shadowtls-in",
+		ConfigKey:        "shadowtls",
+		ExtraInboundTags: func(tag string) []string { return []string{shadowTLSDetourTag(tag)} },
+		Build:            buildShadowTLS,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*ShadowTLS).GenerateShareLink(serverIP)
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*ShadowTLS).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildShadowTLS(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.ShadowTLS
+	return &ShadowTLS{
+		BaseProtocol: b.base(TypeShadowTLS, "ShadowTLS v3", inst, c.Port),
+		Password:     inst.Credential(c.Password, cfg.Password),
+		SSPassword:   c.GetSSPassword(cfg.Password),
+		SSMethod:     c.GetSSMethod(),
+		SNI:          c.SNI,
+		DetourPort:   c.GetDetourPort(),
+		StrictMode:   c.IsStrictMode(),
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段
+// Clash Meta 支持 shadow-tls 作為 SS 的插件
+func (s *ShadowTLS) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = "ss"
+	proxy["cipher"] = s.SSMethod
+	proxy["password"] = s.SSPassword
+	proxy["plugin"] = "shadow-tls"
+	proxy["plugin-opts"] = map[string]interface{}{
+		"host":     s.SNI,
+		"password": s.Password,
+		"version":  3,
+	}
+}
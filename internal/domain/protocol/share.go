@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NodeLink 單條節點分享鏈接
+type NodeLink struct {
+	Name string // 顯示名稱
+	URL  string
+	Port int // 客戶端連接端口 (經 CDN 中轉時為 CDN 端口)
+}
+
+// multiUserLinker 多用戶協議為每個用戶生成一條鏈接
+type multiUserLinker interface {
+	UserLinks(serverIP string) []SSUserLink
+}
+
+// NodeLinks 為協議列表生成分享鏈接 (順序與輸入一致)，TUI 節點信息與訂閱文件共用
+// 鏈接格式由各協議描述符提供，無法生成鏈接的協議被跳過
+func NodeLinks(protos []Protocol, serverIP string) []NodeLink {
+	var links []NodeLink
+	for _, p := range protos {
+		if !p.IsEnabled() {
+			continue
+		}
+		d, ok := LookupType(p.Type())
+		if !ok {
+			continue
+		}
+
+		if m, ok := p.(multiUserLinker); ok {
+			for _, l := range m.UserLinks(serverIP) {
+				links = append(links, NodeLink{Name: l.Name, URL: l.URL, Port: ClientPort(p)})
+			}
+			continue
+		}
+
+		link := d.ShareLink(p, serverIP)
+		if link == "" {
+			continue
+		}
+		name := d.Name
+		// 非主實例使用帶標籤的名稱，避免客戶端導入後節點重名
+		// VMess 鏈接的名稱在 Base64 內容中，由協議自行處理
+		if p.Tag() != d.Tag {
+			name = fmt.Sprintf("%s [%s]", d.Name, p.Tag())
+			if !strings.HasPrefix(link, "vmess://") {
+				link = withFragment(link, p.Name())
+			}
+		}
+		links = append(links, NodeLink{Name: name, URL: link, Port: ClientPort(p)})
+	}
+	return links
+}
+
+// ShareLinks 為協議列表生成分享鏈接，返回鏈接文本
+func ShareLinks(protos []Protocol, serverIP string) []string {
+	var links []string
+	for _, l := range NodeLinks(protos, serverIP) {
+		links = append(links, l.URL)
+	}
+	return links
+}
+
+// withFragment 替換鏈接中的節點名稱 (# 之後的部分)
+func withFragment(link, name string) string {
+	if i := strings.LastIndex(link, "#"); i >= 0 {
+		link = link[:i]
+	}
+	return link + "#" + url.PathEscape(name)
+}
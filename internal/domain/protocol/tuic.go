@@ -2,7 +2,10 @@ package protocol
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
@@ -17,6 +20,9 @@ type TUIC struct {
 	ALPN              []string // ALPN
 	CongestionControl string   `json:"congestion_control,omitempty"` // bbr
 	ZeroRTTHandshake  bool     `json:"zero_rtt_handshake,omitempty"` // false
+	UDPRelayMode      string   // 客户端 UDP 转发模式 native / quic
+	AuthTimeout       string   // 服务端认证超时
+	Heartbeat         string   // 心跳间隔，服务端与客户端需一致
 }
 
 // NewTUIC 创建 TUIC 协议
@@ -34,6 +40,9 @@ func NewTUIC(port int, uuid, password string) *TUIC {
 		ALPN:              []string{"h3"},
 		CongestionControl: "bbr",
 		ZeroRTTHandshake:  false,
+		UDPRelayMode:      "native",
+		AuthTimeout:       "3s",
+		Heartbeat:         "10s",
 	}
 }
 
@@ -84,8 +93,8 @@ func (t *TUIC) ToSingboxOutbound() (map[string]interface{}, error) {
 		}).
 		WithField("congestion_control", t.CongestionControl).
 		WithField("zero_rtt_handshake", t.ZeroRTTHandshake).
-		WithField("udp_relay_mode", "native").
-		WithField("heartbeat", "10s").
+		WithField("udp_relay_mode", t.UDPRelayMode).
+		WithField("heartbeat", t.Heartbeat).
 		WithField("network", "tcp,udp").
 		Build(), nil
 }
@@ -97,7 +106,7 @@ func (t *TUIC) ToSingboxInbound() (map[string]interface{}, error) {
 	}
 
 	// ✅ 使用构建器
-	return NewInboundBuilder("tuic", "tuic-in", t.port).
+	return NewInboundBuilder("tuic", t.Tag(), t.port).
 		WithUsers([]map[string]interface{}{
 			{
 				"name":     "prism",
@@ -113,15 +122,73 @@ func (t *TUIC) ToSingboxInbound() (map[string]interface{}, error) {
 		}).
 		WithField("congestion_control", t.CongestionControl).
 		WithField("zero_rtt_handshake", t.ZeroRTTHandshake).
-		WithField("auth_timeout", "3s").
-		WithField("heartbeat", "10s").
+		WithField("auth_timeout", t.AuthTimeout).
+		WithField("heartbeat", t.Heartbeat).
 		Build(), nil
 }
 
 // GenerateShareLink 生成分享链接
 func (t *TUIC) GenerateShareLink(serverIP string) string {
 	return fmt.Sprintf(
-		"tuic://%s:%s@%s:%d?congestion_control=%s&alpn=%s#TUIC",
-		t.UUID, t.Password, serverIP, t.port, t.CongestionControl, t.ALPN[0],
+		"tuic://%s:%s@%s:%d?sni=%s&congestion_control=%s&udp_relay_mode=%s&alpn=%s#TUIC",
+		t.UUID, t.Password, serverIP, t.port, t.SNI, t.CongestionControl, t.UDPRelayMode, strings.Join(t.ALPN, ","),
 	)
 }
+
+func init() {
+	Register(Descriptor{
+		ID:        IDTUIC,
+		Type:      TypeTUIC,
+		Name:      "TUIC v5",
+		Tag:       "tuic-in",
+		ConfigKey: "tuic",
+		Badge:     "[極客]",
+		NeedsCert: true,
+		Build:     buildTUIC,
+		ShareLink: func(p Protocol, serverIP string) string {
+			return p.(*TUIC).GenerateShareLink(serverIP)
+		},
+		Clash: func(p Protocol, proxy map[string]interface{}) {
+			p.(*TUIC).fillClashProxy(proxy)
+		},
+	})
+}
+
+func buildTUIC(b *BuildContext, cfg *domainConfig.Config, inst *domainConfig.ProtocolInstance) Protocol {
+	c := inst.TUIC
+	certPath, keyPath := b.CertPath(c.CertMode, c.CertDomain)
+
+	return &TUIC{
+		BaseProtocol:      b.base(TypeTUIC, "TUIC", inst, c.Port),
+		UUID:              inst.Credential(c.UUID, cfg.UUID),
+		Password:          inst.Credential(c.Password, cfg.Password),
+		SNI:               b.SNI(c.CertMode, c.CertDomain, c.SNI),
+		CertPath:          certPath,
+		KeyPath:           keyPath,
+		ALPN:              c.GetALPN(),
+		CongestionControl: c.GetCongestionControl(),
+		ZeroRTTHandshake:  c.ZeroRTTHandshake,
+		UDPRelayMode:      c.GetUDPRelayMode(),
+		AuthTimeout:       c.GetAuthTimeout(),
+		Heartbeat:         c.GetHeartbeat(),
+	}
+}
+
+// fillClashProxy 填充 Clash Meta 代理字段
+func (t *TUIC) fillClashProxy(proxy map[string]interface{}) {
+	proxy["type"] = "tuic"
+	proxy["uuid"] = t.UUID
+	proxy["password"] = t.Password
+	proxy["sni"] = t.SNI
+	proxy["skip-cert-verify"] = true
+	proxy["alpn"] = t.ALPN
+	proxy["congestion-controller"] = t.CongestionControl
+	proxy["udp-relay-mode"] = t.UDPRelayMode
+	// Clash Meta 的心跳以毫秒表示
+	if d, err := time.ParseDuration(t.Heartbeat); err == nil {
+		proxy["heartbeat-interval"] = d.Milliseconds()
+	}
+	if t.ZeroRTTHandshake {
+		proxy["reduce-rtt"] = true
+	}
+}
@@ -0,0 +1,155 @@
+package singbox
+
+import (
+	"fmt"
+	"strings"
+
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+)
+
+// 遠程規則集下載地址
+const (
+	geositeRuleSetURL = "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set/%s.srs"
+	geoipRuleSetURL   = "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set/%s.srs"
+)
+
+// generateCustomOutbound 將自定義出站轉換為 sing-box outbound
+func (g *generator) generateCustomOutbound(o *domainConfig.CustomOutbound) Outbound {
+	out := Outbound{
+		"type":        o.Type,
+		"tag":         o.Tag,
+		"server":      o.Server,
+		"server_port": o.Port,
+	}
+
+	if o.Detour != "" {
+		out["detour"] = o.Detour
+	}
+
+	switch o.Type {
+	case domainConfig.OutboundTypeHTTP, domainConfig.OutboundTypeSOCKS:
+		if o.Type == domainConfig.OutboundTypeSOCKS {
+			out["version"] = "5"
+		}
+		if o.Username != "" {
+			out["username"] = o.Username
+			out["password"] = o.Password
+		}
+	case domainConfig.OutboundTypeShadowsocks:
+		out["method"] = o.Method
+		out["password"] = o.Password
+	case domainConfig.OutboundTypeVLESS:
+		out["uuid"] = o.UUID
+		if o.Flow != "" {
+			out["flow"] = o.Flow
+		}
+	case domainConfig.OutboundTypeTrojan:
+		out["password"] = o.Password
+	case domainConfig.OutboundTypeWireGuard:
+		out["local_address"] = o.LocalAddress
+		out["private_key"] = o.PrivateKey
+		out["peer_public_key"] = o.PeerPublicKey
+		if o.PreSharedKey != "" {
+			out["pre_shared_key"] = o.PreSharedKey
+		}
+		if len(o.Reserved) > 0 {
+			out["reserved"] = o.Reserved
+		}
+		mtu := o.MTU
+		if mtu == 0 {
+			mtu = 1280
+		}
+		out["mtu"] = mtu
+	}
+
+	if tls := generateOutboundTLS(&o.TLS); tls != nil {
+		out["tls"] = tls
+	}
+
+	return out
+}
+
+// generateOutboundTLS 生成上游 TLS 配置，未啟用時返回 nil
+func generateOutboundTLS(t *domainConfig.OutboundTLSConfig) map[string]interface{} {
+	if !t.Enabled {
+		return nil
+	}
+
+	tls := map[string]interface{}{"enabled": true}
+	if t.ServerName != "" {
+		tls["server_name"] = t.ServerName
+	}
+	if t.Insecure {
+		tls["insecure"] = true
+	}
+
+	fingerprint := t.Fingerprint
+	if t.RealityPublicKey != "" {
+		tls["reality"] = map[string]interface{}{
+			"enabled":    true,
+			"public_key": t.RealityPublicKey,
+			"short_id":   t.RealityShortID,
+		}
+		// Reality 必須啟用 uTLS
+		if fingerprint == "" {
+			fingerprint = "chrome"
+		}
+	}
+	if fingerprint != "" {
+		tls["utls"] = map[string]interface{}{
+			"enabled":     true,
+			"fingerprint": fingerprint,
+		}
+	}
+
+	return tls
+}
+
+// generateCustomOutboundRules 為自定義出站生成域名與規則集分流規則
+func (g *generator) generateCustomOutboundRules(cfg *domainConfig.Config) []RouteRule {
+	var rules []RouteRule
+
+	for _, o := range cfg.Routing.Outbounds {
+		if len(o.Domains) > 0 {
+			rules = append(rules, RouteRule{Domain: o.Domains, Outbound: o.Tag})
+		}
+		if len(o.RuleSets) > 0 {
+			rules = append(rules, RouteRule{RuleSet: o.RuleSets, Outbound: o.Tag})
+		}
+	}
+
+	return rules
+}
+
+// collectRuleSets 為規則中引用的所有規則集生成遠程定義
+func collectRuleSets(rules []RouteRule) []RuleSet {
+	var ruleSets []RuleSet
+	seen := make(map[string]bool)
+
+	for _, rule := range rules {
+		for _, tag := range rule.RuleSet {
+			if !seen[tag] {
+				seen[tag] = true
+				ruleSets = append(ruleSets, newRemoteRuleSet(tag))
+			}
+		}
+	}
+
+	return ruleSets
+}
+
+// newRemoteRuleSet 根據標籤 (geosite-xxx / geoip-xxx) 生成遠程規則集定義
+func newRemoteRuleSet(tag string) RuleSet {
+	urlFormat := geositeRuleSetURL
+	if strings.HasPrefix(tag, "geoip-") {
+		urlFormat = geoipRuleSetURL
+	}
+
+	return RuleSet{
+		Tag:            tag,
+		Type:           "remote",
+		Format:         "binary",
+		URL:            fmt.Sprintf(urlFormat, tag),
+		DownloadDetour: "direct",
+	}
+}
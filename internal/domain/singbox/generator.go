@@ -23,6 +23,19 @@ type Config struct {
 	Inbounds  []Inbound  `json:"inbounds"`
 	Outbounds []Outbound `json:"outbounds"`
 	Route     *Route     `json:"route"`
+
+	Experimental *Experimental `json:"experimental,omitempty"`
+}
+
+// Experimental sing-box 實驗性功能
+type Experimental struct {
+	ClashAPI *ClashAPI `json:"clash_api,omitempty"`
+}
+
+// ClashAPI 供流量面板讀取連接與流量統計
+type ClashAPI struct {
+	ExternalController string `json:"external_controller"`
+	Secret             string `json:"secret,omitempty"`
 }
 
 type Log struct {
@@ -65,11 +78,21 @@ type Route struct {
 }
 
 type RouteRule struct {
-	Protocol string   `json:"protocol,omitempty"`
-	RuleSet  []string `json:"rule_set,omitempty"`
-	Domain   []string `json:"domain,omitempty"`
-	Action   string   `json:"action,omitempty"`
-	Outbound string   `json:"outbound,omitempty"`
+	Inbound       []string `json:"inbound,omitempty"`
+	Network       string   `json:"network,omitempty"`
+	Protocol      []string `json:"protocol,omitempty"`
+	RuleSet       []string `json:"rule_set,omitempty"`
+	Domain        []string `json:"domain,omitempty"`
+	DomainSuffix  []string `json:"domain_suffix,omitempty"`
+	DomainKeyword []string `json:"domain_keyword,omitempty"`
+	DomainRegex   []string `json:"domain_regex,omitempty"`
+	IPCIDR        []string `json:"ip_cidr,omitempty"`
+	IPIsPrivate   bool     `json:"ip_is_private,omitempty"`
+	Port          []int    `json:"port,omitempty"`
+	PortRange     []string `json:"port_range,omitempty"`
+	Action        string   `json:"action,omitempty"`
+	Strategy      string   `json:"strategy,omitempty"` // resolve 動作的解析策略
+	Outbound      string   `json:"outbound,omitempty"`
 }
 
 type RuleSet struct {
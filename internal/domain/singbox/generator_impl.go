@@ -100,27 +100,42 @@ func (g *generator) needDNS(cfg *domainConfig.Config) bool {
 		return true
 	}
 
+	for _, o := range cfg.Routing.Outbounds {
+		if len(o.Domains) > 0 || len(o.RuleSets) > 0 {
+			return true
+		}
+	}
+
+	for _, rule := range cfg.Routing.SortedRules() {
+		if rule.HasDomainMatcher() && cfg.Routing.IsOutboundAvailable(rule.Outbound) {
+			return true
+		}
+	}
+
+	// resolve 動作依賴 DNS 服務器
+	for _, ip := range enabledInboundProfiles(cfg) {
+		if ip.profile.IPv6 != "" {
+			return true
+		}
+	}
+
 	return false
 }
 
+// detourInbounder 需要額外本地入站的協議
+type detourInbounder interface {
+	GetDetourInbound() map[string]interface{}
+}
+
 func (g *generator) generateInboundsFromProtocols(protocols []protocol.Protocol) []Inbound {
 	var inbounds []Inbound
 
 	for _, proto := range protocols {
-		// 特殊處理 ShadowTLS：需要先添加 Shadowsocks detour 入站
-		if shadowtls, ok := proto.(*protocol.ShadowTLS); ok {
-			inbounds = append(inbounds, Inbound(shadowtls.GetDetourInbound()))
-
-			if inboundMap, err := proto.ToSingboxInbound(); err == nil {
-				inbounds = append(inbounds, Inbound(inboundMap))
-			} else {
-				log.Warnf("跳過協議 %s (端口 %d): %v",
-					proto.Name(), proto.Port(), err)
-			}
-			continue
+		// 需要轉交本地入站的協議 (如 ShadowTLS) 先添加 detour 入站
+		if d, ok := proto.(detourInbounder); ok {
+			inbounds = append(inbounds, Inbound(d.GetDetourInbound()))
 		}
 
-		// 其他協議正常處理
 		if inboundMap, err := proto.ToSingboxInbound(); err == nil {
 			inbounds = append(inbounds, Inbound(inboundMap))
 		} else {
@@ -150,6 +165,9 @@ func (g *generator) Generate(ctx context.Context, cfg *domainConfig.Config) (*Co
 
 	protocols := g.protocolFactory.FromConfig(cfg)
 	inbounds := g.generateInboundsFromProtocols(protocols)
+	if g.isLegacyCore() {
+		applyInboundDomainStrategy(inbounds, cfg)
+	}
 
 	outbounds, err := g.GenerateOutbounds(ctx, cfg)
 	if err != nil {
@@ -160,16 +178,33 @@ func (g *generator) Generate(ctx context.Context, cfg *domainConfig.Config) (*Co
 	if err != nil {
 		return nil, errors.Wrap(err, "SINGBOX005", "生成路由配置失敗")
 	}
+	if g.isLegacyCore() && needsSniff(route.Rules) {
+		applyInboundSniff(inbounds)
+	}
 
 	return &Config{
-		Log:       log,
-		DNS:       dns,
-		Inbounds:  inbounds,
-		Outbounds: outbounds,
-		Route:     route,
+		Log:          log,
+		DNS:          dns,
+		Inbounds:     inbounds,
+		Outbounds:    outbounds,
+		Route:        route,
+		Experimental: g.generateExperimental(cfg),
 	}, nil
 }
 
+// generateExperimental 啟用本機 Clash API，供流量面板讀取統計
+func (g *generator) generateExperimental(cfg *domainConfig.Config) *Experimental {
+	if !cfg.ClashAPI.IsEnabled() {
+		return nil
+	}
+	return &Experimental{
+		ClashAPI: &ClashAPI{
+			ExternalController: cfg.ClashAPI.Listen,
+			Secret:             cfg.ClashAPI.Secret,
+		},
+	}
+}
+
 func (g *generator) generateLog(cfg *domainConfig.Config) *Log {
 	return &Log{
 		Level:     cfg.Log.Level,
@@ -241,40 +276,68 @@ func (g *generator) GenerateOutbounds(ctx context.Context, cfg *domainConfig.Con
 		outbounds = append(outbounds, g.generateWARPOutbound(cfg))
 	}
 
+	if err := cfg.Routing.ValidateOutbounds(); err != nil {
+		return nil, errors.Wrap(err, "SINGBOX006", "自定義出站配置無效")
+	}
+	for i := range cfg.Routing.Outbounds {
+		outbounds = append(outbounds, g.generateCustomOutbound(&cfg.Routing.Outbounds[i]))
+	}
+
 	return outbounds, nil
 }
 
 func (g *generator) generateWARPOutbound(cfg *domainConfig.Config) Outbound {
-	localAddr := []string{"172.16.0.2/32"}
-	if cfg.Routing.WARP.IPv6 != "" {
-		localAddr = append(localAddr, cfg.Routing.WARP.IPv6+"/128")
+	warp := cfg.Routing.WARP
+
+	localAddr := []string{warp.GetIPv4() + "/32"}
+	if warp.IPv6 != "" {
+		localAddr = append(localAddr, warp.IPv6+"/128")
 	}
 
-	return Outbound{
+	server, port := warp.GetEndpoint()
+
+	out := Outbound{
 		"type":            "wireguard",
 		"tag":             "warp-out",
-		"server":          "162.159.192.1",
-		"server_port":     2408,
+		"server":          server,
+		"server_port":     port,
 		"local_address":   localAddr,
-		"private_key":     cfg.Routing.WARP.PrivateKey,
-		"peer_public_key": "bmXOC+F1FxEMF9dyiK2H5/1SUtzH0JuVo51h2wPfgyo=",
+		"private_key":     warp.PrivateKey,
+		"peer_public_key": warp.GetPeerPublicKey(),
 		"mtu":             1280,
 	}
+
+	if reserved := warp.ReservedBytes(); reserved != nil {
+		out["reserved"] = reserved
+	}
+
+	return out
 }
 
 func (g *generator) GenerateRoute(ctx context.Context, cfg *domainConfig.Config) (*Route, error) {
+	if err := cfg.Routing.ValidateRules(); err != nil {
+		return nil, errors.Wrap(err, "SINGBOX007", "路由規則無效")
+	}
+
 	var rules []RouteRule
-	var ruleSets []RuleSet
 
 	if g.needDNS(cfg) && g.isLegacyCore() {
 		rules = append(rules,
 			RouteRule{
-				Protocol: "dns",
+				Protocol: []string{"dns"},
 				Outbound: "dns-out",
 			},
 		)
 	}
 
+	// 入站策略：解析偏好與屏蔽類別優先於用戶規則
+	inboundHead, inboundTail := g.generateInboundPolicyRules(cfg)
+	rules = append(rules, inboundHead...)
+
+	// 用戶路由規則 (按優先級)
+	rules = append(rules, g.generateRoutingRules(cfg)...)
+
+	// 兼容未遷移的舊版分流域名列表
 	if cfg.Routing.WARP.Enabled && len(cfg.Routing.WARP.Domains) > 0 {
 		rules = append(rules, RouteRule{Domain: cfg.Routing.WARP.Domains, Outbound: "warp-out"})
 	}
@@ -283,10 +346,21 @@ func (g *generator) GenerateRoute(ctx context.Context, cfg *domainConfig.Config)
 		rules = append(rules, RouteRule{Domain: cfg.Routing.IPv6Split.Domains, Outbound: "ipv6-out"})
 	}
 
+	rules = append(rules, g.generateCustomOutboundRules(cfg)...)
+
+	// 入站默認出站，僅在以上規則均未命中時生效
+	rules = append(rules, inboundTail...)
+
+	// 按協議匹配的規則依賴嗅探結果，新版內核需在最前面加入 sniff 動作
+	// 舊版內核不支持規則動作，改為在入站上開啟 sniff (見 Generate)
+	if !g.isLegacyCore() && needsSniff(rules) {
+		rules = append([]RouteRule{{Action: "sniff"}}, rules...)
+	}
+
 	route := &Route{
 		Rules:               rules,
-		RuleSet:             ruleSets,
-		Final:               "direct",
+		RuleSet:             collectRuleSets(rules),
+		Final:               g.finalOutbound(cfg),
 		AutoDetectInterface: true,
 	}
 
@@ -20,6 +20,7 @@ type MockProtocol struct {
 func (m *MockProtocol) Name() string        { return m.NameStr }
 func (m *MockProtocol) Port() int           { return m.PortInt }
 func (m *MockProtocol) Type() protocol.Type { return protocol.Type(m.NameStr) }
+func (m *MockProtocol) Tag() string         { return m.NameStr + "-in" }
 func (m *MockProtocol) IsEnabled() bool     { return true }
 func (m *MockProtocol) Validate() error     { return nil } // 補全 Validate 方法
 
@@ -82,6 +83,31 @@ func TestGenerate(t *testing.T) {
 		}
 	})
 
+	t.Run("測試 Clash API", func(t *testing.T) {
+		g := NewGenerator("1.10.0", factory)
+		sbCfg, err := g.Generate(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("Generate 失敗: %v", err)
+		}
+		if sbCfg.Experimental == nil || sbCfg.Experimental.ClashAPI == nil {
+			t.Fatal("默認配置應啟用 Clash API")
+		}
+		api := sbCfg.Experimental.ClashAPI
+		if api.ExternalController != domainConfig.DefaultClashAPIListen || api.Secret != cfg.ClashAPI.Secret {
+			t.Errorf("Clash API 配置錯誤: %+v", api)
+		}
+
+		disabled := cfg.DeepCopy()
+		disabled.ClashAPI.Disabled = true
+		sbCfg, err = g.Generate(context.Background(), disabled)
+		if err != nil {
+			t.Fatalf("Generate 失敗: %v", err)
+		}
+		if sbCfg.Experimental != nil {
+			t.Error("禁用後不應生成 experimental 配置")
+		}
+	})
+
 	t.Run("測試舊版 (<1.8) 生成邏輯", func(t *testing.T) {
 		g := NewGenerator("1.7.9", factory)
 		sbCfg, err := g.Generate(context.Background(), cfg)
@@ -138,3 +164,278 @@ func TestVersionLogic(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateWARPOutbound(t *testing.T) {
+	g := &generator{version: "1.12.0"}
+
+	t.Run("未註冊時使用默認接入點", func(t *testing.T) {
+		cfg := domainConfig.DefaultConfig()
+		out := g.generateWARPOutbound(cfg)
+
+		if out["server"] != "162.159.192.1" || out["server_port"] != 2408 {
+			t.Errorf("默認接入點錯誤: %v:%v", out["server"], out["server_port"])
+		}
+		if _, ok := out["reserved"]; ok {
+			t.Error("未設置 reserved 時不應輸出該字段")
+		}
+	})
+
+	t.Run("使用註冊得到的參數", func(t *testing.T) {
+		cfg := domainConfig.DefaultConfig()
+		cfg.Routing.WARP.IPv4 = "172.16.0.9"
+		cfg.Routing.WARP.IPv6 = "2606:4700::9"
+		cfg.Routing.WARP.Reserved = "[1, 2, 3]"
+		cfg.Routing.WARP.PeerPublicKey = "peer"
+		cfg.Routing.WARP.Endpoint = "[2606:4700:d0::1]:500"
+
+		out := g.generateWARPOutbound(cfg)
+
+		if out["server"] != "2606:4700:d0::1" || out["server_port"] != 500 {
+			t.Errorf("接入點錯誤: %v:%v", out["server"], out["server_port"])
+		}
+		addrs := out["local_address"].([]string)
+		if len(addrs) != 2 || addrs[0] != "172.16.0.9/32" || addrs[1] != "2606:4700::9/128" {
+			t.Errorf("local_address 錯誤: %v", addrs)
+		}
+		if reserved, ok := out["reserved"].([]int); !ok || len(reserved) != 3 || reserved[2] != 3 {
+			t.Errorf("reserved 錯誤: %v", out["reserved"])
+		}
+		if out["peer_public_key"] != "peer" {
+			t.Errorf("peer_public_key 錯誤: %v", out["peer_public_key"])
+		}
+	})
+}
+
+func TestGenerateCustomOutbounds(t *testing.T) {
+	g := &generator{version: "1.12.0"}
+	ctx := context.Background()
+
+	cfg := domainConfig.DefaultConfig()
+	cfg.Routing.Outbounds = []domainConfig.CustomOutbound{
+		{Tag: "hk", Type: domainConfig.OutboundTypeShadowsocks, Server: "1.2.3.4", Port: 8388, Method: "aes-128-gcm", Password: "p"},
+		{
+			Tag: "jp", Type: domainConfig.OutboundTypeVLESS, Server: "example.com", Port: 443, UUID: "u", Detour: "hk",
+			TLS:      domainConfig.OutboundTLSConfig{Enabled: true, ServerName: "www.apple.com", RealityPublicKey: "pub"},
+			Domains:  []string{"openai.com"},
+			RuleSets: []string{"geosite-netflix", "geoip-jp"},
+		},
+	}
+
+	outbounds, err := g.GenerateOutbounds(ctx, cfg)
+	if err != nil {
+		t.Fatalf("GenerateOutbounds 失敗: %v", err)
+	}
+
+	var jp Outbound
+	for _, o := range outbounds {
+		if o["tag"] == "jp" {
+			jp = o
+		}
+	}
+	if jp == nil {
+		t.Fatal("未生成自定義出站 jp")
+	}
+	if jp["detour"] != "hk" {
+		t.Errorf("detour 錯誤: %v", jp["detour"])
+	}
+	tls := jp["tls"].(map[string]interface{})
+	if _, ok := tls["reality"]; !ok {
+		t.Error("應生成 reality 配置")
+	}
+	if _, ok := tls["utls"]; !ok {
+		t.Error("Reality 應默認啟用 uTLS")
+	}
+
+	route, err := g.GenerateRoute(ctx, cfg)
+	if err != nil {
+		t.Fatalf("GenerateRoute 失敗: %v", err)
+	}
+	if len(route.RuleSet) != 2 || route.RuleSet[1].URL != "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set/geoip-jp.srs" {
+		t.Errorf("規則集定義錯誤: %+v", route.RuleSet)
+	}
+	if len(route.Rules) != 2 || route.Rules[0].Outbound != "jp" {
+		t.Errorf("分流規則錯誤: %+v", route.Rules)
+	}
+
+	// 前置代理不存在時應報錯
+	cfg.Routing.Outbounds[1].Detour = "missing"
+	if _, err := g.GenerateOutbounds(ctx, cfg); err == nil {
+		t.Error("無效的前置代理應導致生成失敗")
+	}
+}
+
+// TestGenerateRoutingRules 測試路由規則按優先級生成
+func TestGenerateRoutingRules(t *testing.T) {
+	g := &generator{version: "1.12.0"}
+	ctx := context.Background()
+
+	cfg := domainConfig.DefaultConfig()
+	cfg.Routing.Outbounds = []domainConfig.CustomOutbound{
+		{Tag: "hk", Type: domainConfig.OutboundTypeShadowsocks, Server: "1.2.3.4", Port: 8388, Method: "aes-128-gcm", Password: "p"},
+	}
+	cfg.Routing.Rules = []domainConfig.RoutingRule{
+		{Priority: 30, DomainSuffix: []string{"example.com"}, Outbound: "hk"},
+		{Priority: 10, Protocol: []string{"bittorrent"}, Action: domainConfig.RuleActionReject},
+		{Priority: 20, Domain: []string{"openai.com"}, Outbound: domainConfig.OutboundWARP}, // WARP 未啟用，應跳過
+		{Priority: 5, Domain: []string{"disabled.com"}, Outbound: "hk", Disabled: true},
+	}
+	cfg.Routing.Final = "hk"
+
+	route, err := g.GenerateRoute(ctx, cfg)
+	if err != nil {
+		t.Fatalf("GenerateRoute 失敗: %v", err)
+	}
+
+	// 存在按協議匹配的規則時，嗅探動作必須位於所有規則之前
+	if len(route.Rules) == 0 || route.Rules[0].Action != "sniff" {
+		t.Fatalf("缺少前置 sniff 動作: %+v", route.Rules)
+	}
+
+	var user []RouteRule
+	for _, r := range route.Rules[1:] {
+		if len(r.Protocol) == 1 && r.Protocol[0] == "dns" {
+			continue
+		}
+		user = append(user, r)
+	}
+	if len(user) != 2 {
+		t.Fatalf("應生成 2 條用戶規則，實際 %d: %+v", len(user), user)
+	}
+	if user[0].Action != domainConfig.RuleActionReject || user[1].Outbound != "hk" {
+		t.Errorf("規則順序或動作錯誤: %+v", user)
+	}
+	if route.Final != "hk" {
+		t.Errorf("默認出站錯誤: %s", route.Final)
+	}
+
+	// 舊版內核使用 block 出站代替 reject 動作
+	legacy := &generator{version: "1.7.0"}
+	route, err = legacy.GenerateRoute(ctx, cfg)
+	if err != nil {
+		t.Fatalf("GenerateRoute 失敗: %v", err)
+	}
+	found := false
+	for _, r := range route.Rules {
+		if r.Outbound == "block" && r.Action == "" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("舊版內核應將 reject 轉為 block 出站")
+	}
+	for _, r := range route.Rules {
+		if r.Action == "sniff" {
+			t.Error("舊版內核不支持 sniff 動作")
+		}
+	}
+
+	// 舊版內核改為在入站上開啟嗅探
+	legacy.protocolFactory = &MockFactory{protocols: []protocol.Protocol{&MockProtocol{NameStr: "vless", PortInt: 443}}}
+	legacyCfg, err := legacy.Generate(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Generate 失敗: %v", err)
+	}
+	if legacyCfg.Inbounds[0]["sniff"] != true {
+		t.Errorf("舊版內核應在入站上開啟 sniff: %+v", legacyCfg.Inbounds[0])
+	}
+
+	// 沒有按協議匹配的規則時不嗅探
+	plain := cfg.DeepCopy()
+	plain.Routing.Rules = plain.Routing.Rules[:1]
+	route, err = g.GenerateRoute(ctx, plain)
+	if err != nil {
+		t.Fatalf("GenerateRoute 失敗: %v", err)
+	}
+	if len(route.Rules) > 0 && route.Rules[0].Action == "sniff" {
+		t.Error("無協議匹配時不應加入 sniff 動作")
+	}
+
+	// 引用不存在的出站應報錯
+	cfg.Routing.Rules[0].Outbound = "missing"
+	if _, err := g.GenerateRoute(ctx, cfg); err == nil {
+		t.Error("無效的規則出站應導致生成失敗")
+	}
+}
+
+// TestGenerateInboundRouting 測試入站路由策略
+func TestGenerateInboundRouting(t *testing.T) {
+	g := &generator{version: "1.12.0"}
+	ctx := context.Background()
+
+	cfg := domainConfig.DefaultConfig()
+	cfg.Routing.WARP.Enabled = true
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.Hysteria2().Routing = domainConfig.InboundRouting{
+		Outbound: domainConfig.OutboundWARP,
+		Block:    []string{"ads"},
+		IPv6:     "prefer_ipv6",
+	}
+	cfg.Protocols.RealityVision().Enabled = true
+	cfg.Protocols.RealityVision().Routing = domainConfig.InboundRouting{Outbound: domainConfig.OutboundDirect}
+	cfg.Protocols.TUIC().Enabled = false
+	cfg.Protocols.TUIC().Routing = domainConfig.InboundRouting{Outbound: domainConfig.OutboundWARP} // 未啟用，應忽略
+	cfg.Routing.Rules = []domainConfig.RoutingRule{
+		{Priority: 10, Domain: []string{"example.com"}, Outbound: domainConfig.OutboundDirect},
+	}
+
+	route, err := g.GenerateRoute(ctx, cfg)
+	if err != nil {
+		t.Fatalf("GenerateRoute 失敗: %v", err)
+	}
+
+	n := len(route.Rules)
+	if n != 5 {
+		t.Fatalf("應生成 5 條規則，實際 %d: %+v", n, route.Rules)
+	}
+	if r := route.Rules[0]; r.Action != "resolve" || r.Strategy != "prefer_ipv6" || r.Inbound[0] != "hysteria2-in" {
+		t.Errorf("解析策略規則錯誤: %+v", r)
+	}
+	if r := route.Rules[1]; r.Action != domainConfig.RuleActionReject || r.RuleSet[0] != "geosite-category-ads-all" {
+		t.Errorf("屏蔽規則錯誤: %+v", r)
+	}
+	if route.Rules[2].Domain[0] != "example.com" {
+		t.Errorf("用戶規則應位於入站默認出站之前: %+v", route.Rules[2])
+	}
+	if r := route.Rules[n-2]; r.Inbound[0] != "reality-vision-in" || r.Outbound != domainConfig.OutboundDirect {
+		t.Errorf("Reality Vision 默認出站錯誤: %+v", r)
+	}
+	if r := route.Rules[n-1]; r.Inbound[0] != "hysteria2-in" || r.Outbound != domainConfig.OutboundWARP {
+		t.Errorf("Hysteria2 默認出站錯誤: %+v", r)
+	}
+
+	// 舊版內核改用入站 domain_strategy
+	inbounds := []Inbound{{"tag": "hysteria2-in"}, {"tag": "reality-vision-in"}}
+	applyInboundDomainStrategy(inbounds, cfg)
+	if inbounds[0]["domain_strategy"] != "prefer_ipv6" || inbounds[1]["domain_strategy"] != nil {
+		t.Errorf("domain_strategy 設置錯誤: %+v", inbounds)
+	}
+}
+
+// TestGenerateBlockCategories 測試私有地址與 BT 屏蔽類別
+func TestGenerateBlockCategories(t *testing.T) {
+	g := &generator{version: "1.12.0"}
+
+	cfg := domainConfig.DefaultConfig()
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.Hysteria2().Routing = domainConfig.InboundRouting{Block: []string{"private", "bittorrent"}}
+
+	route, err := g.GenerateRoute(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("GenerateRoute 失敗: %v", err)
+	}
+
+	// BT 屏蔽依賴協議嗅探
+	if len(route.Rules) != 3 || route.Rules[0].Action != "sniff" {
+		t.Fatalf("BT 屏蔽規則前應有 sniff 動作: %+v", route.Rules)
+	}
+	if r := route.Rules[1]; !r.IPIsPrivate || len(r.RuleSet) != 0 || r.Action != domainConfig.RuleActionReject {
+		t.Errorf("私有地址應使用 ip_is_private 匹配: %+v", r)
+	}
+	if r := route.Rules[2]; len(r.Protocol) != 1 || r.Protocol[0] != "bittorrent" {
+		t.Errorf("BT 屏蔽規則錯誤: %+v", r)
+	}
+	// sing-geoip 沒有 private 規則集，不應引用遠程規則集
+	if len(route.RuleSet) != 0 {
+		t.Errorf("不應生成遠程規則集: %+v", route.RuleSet)
+	}
+}
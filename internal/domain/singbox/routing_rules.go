@@ -0,0 +1,158 @@
+package singbox
+
+import (
+	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+)
+
+// generateRoutingRules 將用戶路由規則按優先級轉換為 sing-box 規則
+// 目標出站在當前配置下未生成 (如 WARP 已禁用) 的規則會被跳過
+func (g *generator) generateRoutingRules(cfg *domainConfig.Config) []RouteRule {
+	var rules []RouteRule
+
+	for _, rule := range cfg.Routing.SortedRules() {
+		if out, ok := g.toRouteRule(cfg, rule); ok {
+			rules = append(rules, out)
+		}
+	}
+
+	return rules
+}
+
+// toRouteRule 轉換單條路由規則
+func (g *generator) toRouteRule(cfg *domainConfig.Config, rule domainConfig.RoutingRule) (RouteRule, bool) {
+	out := RouteRule{
+		Inbound:       rule.Inbound,
+		Network:       rule.Network,
+		Protocol:      rule.Protocol,
+		RuleSet:       rule.RuleSet,
+		Domain:        rule.Domain,
+		DomainSuffix:  rule.DomainSuffix,
+		DomainKeyword: rule.DomainKeyword,
+		DomainRegex:   rule.DomainRegex,
+		IPCIDR:        rule.IPCIDR,
+		IPIsPrivate:   rule.IPIsPrivate,
+		Port:          rule.Port,
+		PortRange:     rule.PortRange,
+	}
+
+	switch rule.GetAction() {
+	case domainConfig.RuleActionReject:
+		if g.isLegacyCore() {
+			out.Outbound = "block"
+		} else {
+			out.Action = domainConfig.RuleActionReject
+		}
+	case domainConfig.RuleActionHijackDNS:
+		if g.isLegacyCore() {
+			out.Outbound = "dns-out"
+		} else {
+			out.Action = domainConfig.RuleActionHijackDNS
+		}
+	default:
+		if !cfg.Routing.IsOutboundAvailable(rule.Outbound) {
+			return RouteRule{}, false
+		}
+		out.Outbound = rule.Outbound
+	}
+
+	return out, true
+}
+
+// inboundProfile 已啓用協議實例的入站路由策略
+type inboundProfile struct {
+	tags    []string
+	profile *domainConfig.InboundRouting
+}
+
+// enabledInboundProfiles 返回所有已啓用且設置了路由策略的協議實例 (按協議編號順序)
+func enabledInboundProfiles(cfg *domainConfig.Config) []inboundProfile {
+	var profiles []inboundProfile
+	for _, d := range protocol.Descriptors() {
+		for _, inst := range cfg.Protocols.InstancesOf(d.ConfigKey) {
+			sec, _ := inst.Section()
+			if !*sec.Enabled || sec.Routing.IsEmpty() {
+				continue
+			}
+			profiles = append(profiles, inboundProfile{tags: d.InboundTags(inst.Tag), profile: sec.Routing})
+		}
+	}
+	return profiles
+}
+
+// generateInboundPolicyRules 生成入站路由策略規則
+// head 需位於用戶規則之前 (解析策略與屏蔽)，tail 為各入站的默認出站，位於所有規則之後
+func (g *generator) generateInboundPolicyRules(cfg *domainConfig.Config) (head, tail []RouteRule) {
+	for _, ip := range enabledInboundProfiles(cfg) {
+		p := ip.profile
+
+		// 舊版內核不支持 resolve 動作，改為在入站上設置 domain_strategy
+		if p.IPv6 != "" && !g.isLegacyCore() {
+			head = append(head, RouteRule{Inbound: ip.tags, Action: "resolve", Strategy: p.IPv6})
+		}
+
+		for _, category := range p.Block {
+			rule, ok := domainConfig.BlockCategoryRule(category)
+			if !ok {
+				continue
+			}
+			rule.Inbound = ip.tags
+			if out, ok := g.toRouteRule(cfg, rule); ok {
+				head = append(head, out)
+			}
+		}
+
+		if p.Outbound != "" && cfg.Routing.IsOutboundAvailable(p.Outbound) {
+			tail = append(tail, RouteRule{Inbound: ip.tags, Outbound: p.Outbound})
+		}
+	}
+	return head, tail
+}
+
+// applyInboundDomainStrategy 舊版內核通過入站 domain_strategy 實現 IPv6 偏好
+func applyInboundDomainStrategy(inbounds []Inbound, cfg *domainConfig.Config) {
+	strategies := make(map[string]string)
+	for _, ip := range enabledInboundProfiles(cfg) {
+		if ip.profile.IPv6 == "" {
+			continue
+		}
+		for _, tag := range ip.tags {
+			strategies[tag] = ip.profile.IPv6
+		}
+	}
+	for _, in := range inbounds {
+		if tag, ok := in["tag"].(string); ok && strategies[tag] != "" {
+			in["domain_strategy"] = strategies[tag]
+		}
+	}
+}
+
+// needsSniff 是否有規則按嗅探協議 (tls / quic / bittorrent ...) 匹配
+// 服務端默認不嗅探，這類規則需要先開啟嗅探才能命中
+func needsSniff(rules []RouteRule) bool {
+	for _, r := range rules {
+		if len(r.Protocol) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// applyInboundSniff 舊版內核通過入站 sniff 開啟協議嗅探
+func applyInboundSniff(inbounds []Inbound) {
+	for _, in := range inbounds {
+		in["sniff"] = true
+	}
+}
+
+// finalOutbound 計算默認出站
+// 優先使用 Routing.Final，其次為 WARP 全局模式，否則直連
+func (g *generator) finalOutbound(cfg *domainConfig.Config) string {
+	if final := cfg.Routing.Final; final != "" && cfg.Routing.IsOutboundAvailable(final) {
+		return final
+	}
+	if cfg.Routing.WARP.Enabled && cfg.Routing.WARP.Global {
+		return domainConfig.OutboundWARP
+	}
+	return domainConfig.OutboundDirect
+}
@@ -0,0 +1,111 @@
+package clashapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client sing-box Clash API 客戶端
+type Client struct {
+	baseURL    string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewClient 創建客戶端，addr 為 external_controller 地址 (如 127.0.0.1:9090)
+func NewClient(addr, secret string) *Client {
+	base := addr
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(base, "/"),
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Traffic 讀取當前總速率
+// /traffic 為每秒推送一條記錄的流式接口，只讀取第一條
+func (c *Client) Traffic(ctx context.Context) (*Traffic, error) {
+	var t Traffic
+	if err := c.first(ctx, "/traffic", &t); err != nil {
+		return nil, fmt.Errorf("讀取流量統計失敗: %w", err)
+	}
+	return &t, nil
+}
+
+// Memory 讀取核心內存佔用 (流式接口，只讀取第一條)
+func (c *Client) Memory(ctx context.Context) (*Memory, error) {
+	var m Memory
+	if err := c.first(ctx, "/memory", &m); err != nil {
+		return nil, fmt.Errorf("讀取內存統計失敗: %w", err)
+	}
+	return &m, nil
+}
+
+// Connections 讀取活動連接快照
+func (c *Client) Connections(ctx context.Context) (*Snapshot, error) {
+	var s Snapshot
+	if err := c.first(ctx, "/connections", &s); err != nil {
+		return nil, fmt.Errorf("讀取連接列表失敗: %w", err)
+	}
+	return &s, nil
+}
+
+// CloseConnection 關閉指定連接
+func (c *Client) CloseConnection(ctx context.Context, id string) error {
+	resp, err := c.request(ctx, http.MethodDelete, "/connections/"+url.PathEscape(id))
+	if err != nil {
+		return fmt.Errorf("關閉連接失敗: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// first 解碼響應中的第一個 JSON 對象後立即斷開，兼容流式與一次性接口
+func (c *Client) first(ctx context.Context, path string, out interface{}) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := c.request(ctx, http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(out); err != nil {
+		return fmt.Errorf("解析響應失敗: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) request(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+c.secret)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("訪問密鑰錯誤 (HTTP 401)")
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return resp, nil
+}
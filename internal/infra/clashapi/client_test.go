@@ -0,0 +1,105 @@
+package clashapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "s3cret"
+
+// newFakeAPI 模擬 sing-box Clash API，/traffic 與 /memory 按秒持續推送
+func newFakeAPI(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var closed []string
+	mux := http.NewServeMux()
+	stream := func(lines ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			flusher := w.(http.Flusher)
+			for _, line := range lines {
+				fmt.Fprintln(w, line)
+				flusher.Flush()
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(time.Second):
+				}
+			}
+		}
+	}
+	mux.HandleFunc("/traffic", stream(`{"up":1024,"down":4096}`, `{"up":1,"down":1}`))
+	mux.HandleFunc("/memory", stream(`{"inuse":52428800,"oslimit":0}`))
+	mux.HandleFunc("/connections", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"downloadTotal": 9000, "uploadTotal": 3000,
+			"connections": [{
+				"id": "c1",
+				"metadata": {"network":"tcp","type":"vless/reality-vision-in","sourceIP":"198.51.100.7","sourcePort":"50000",
+					"destinationIP":"142.250.1.1","destinationPort":"443","host":"www.google.com"},
+				"upload": 100, "download": 2000,
+				"start": "2026-05-01T12:00:00Z",
+				"chains": ["direct"], "rule": "final"
+			}]
+		}`))
+	})
+	mux.HandleFunc("/connections/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		closed = append(closed, strings.TrimPrefix(r.URL.Path, "/connections/"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &closed
+}
+
+func TestClientReadsStats(t *testing.T) {
+	srv, closed := newFakeAPI(t)
+	c := NewClient(strings.TrimPrefix(srv.URL, "http://"), testSecret)
+	ctx := context.Background()
+
+	start := time.Now()
+	traffic, err := c.Traffic(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Traffic{Up: 1024, Down: 4096}, traffic)
+	assert.Less(t, time.Since(start), time.Second, "流式接口應在讀取第一條後返回")
+
+	mem, err := c.Memory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(52428800), mem.InUse)
+
+	snap, err := c.Connections(ctx)
+	require.NoError(t, err)
+	require.Len(t, snap.Connections, 1)
+	conn := snap.Connections[0]
+	assert.Equal(t, "reality-vision-in", conn.Inbound())
+	assert.Equal(t, "www.google.com", conn.Destination())
+	assert.Equal(t, "direct", conn.Outbound())
+	assert.Equal(t, int64(2000), conn.Download)
+
+	require.NoError(t, c.CloseConnection(ctx, "c1"))
+	assert.Equal(t, []string{"c1"}, *closed)
+}
+
+func TestClientRejectsWrongSecret(t *testing.T) {
+	srv, _ := newFakeAPI(t)
+	c := NewClient(srv.URL, "wrong")
+
+	_, err := c.Connections(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
@@ -0,0 +1,71 @@
+package clashapi
+
+import (
+	"strings"
+	"time"
+)
+
+// Traffic 實時總速率 (字節/秒)
+type Traffic struct {
+	Up   int64 `json:"up"`
+	Down int64 `json:"down"`
+}
+
+// Memory 核心內存佔用 (字節)
+type Memory struct {
+	InUse   uint64 `json:"inuse"`
+	OSLimit uint64 `json:"oslimit"`
+}
+
+// Snapshot /connections 返回的連接快照
+type Snapshot struct {
+	DownloadTotal int64        `json:"downloadTotal"`
+	UploadTotal   int64        `json:"uploadTotal"`
+	Connections   []Connection `json:"connections"`
+}
+
+// Connection 單個活動連接，Upload/Download 為連接建立以來的累計字節
+type Connection struct {
+	ID       string    `json:"id"`
+	Metadata Metadata  `json:"metadata"`
+	Upload   int64     `json:"upload"`
+	Download int64     `json:"download"`
+	Start    time.Time `json:"start"`
+	Chains   []string  `json:"chains"`
+	Rule     string    `json:"rule"`
+}
+
+// Metadata 連接元數據，sing-box 中 Type 為 "入站類型/入站標籤"
+type Metadata struct {
+	Network         string `json:"network"`
+	Type            string `json:"type"`
+	SourceIP        string `json:"sourceIP"`
+	SourcePort      string `json:"sourcePort"`
+	DestinationIP   string `json:"destinationIP"`
+	DestinationPort string `json:"destinationPort"`
+	Host            string `json:"host"`
+}
+
+// Inbound 返回連接所屬的入站標籤
+func (c Connection) Inbound() string {
+	if i := strings.LastIndex(c.Metadata.Type, "/"); i >= 0 {
+		return c.Metadata.Type[i+1:]
+	}
+	return c.Metadata.Type
+}
+
+// Destination 返回目標地址，優先使用域名
+func (c Connection) Destination() string {
+	if c.Metadata.Host != "" {
+		return c.Metadata.Host
+	}
+	return c.Metadata.DestinationIP
+}
+
+// Outbound 返回實際使用的出站 (Chains 首項為最終出站)
+func (c Connection) Outbound() string {
+	if len(c.Chains) == 0 {
+		return ""
+	}
+	return c.Chains[0]
+}
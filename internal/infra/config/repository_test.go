@@ -48,8 +48,8 @@ func TestFileRepository_SaveAndLoad(t *testing.T) {
 
 	// 修改一些可验证的字段
 	cfg.Certificate.DNSProvider = "cloudflare"
-	cfg.Protocols.Hysteria2.Enabled = true
-	cfg.Protocols.Hysteria2.Port = 8443
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.Hysteria2().Port = 8443
 
 	// 保存
 	err := repo.Save(ctx, cfg)
@@ -68,8 +68,8 @@ func TestFileRepository_SaveAndLoad(t *testing.T) {
 
 	// 验证数据一致性
 	assert.Equal(t, "cloudflare", loadedCfg.Certificate.DNSProvider)
-	assert.True(t, loadedCfg.Protocols.Hysteria2.Enabled)
-	assert.Equal(t, 8443, loadedCfg.Protocols.Hysteria2.Port)
+	assert.True(t, loadedCfg.Protocols.Hysteria2().Enabled)
+	assert.Equal(t, 8443, loadedCfg.Protocols.Hysteria2().Port)
 }
 
 func TestFileRepository_Cache(t *testing.T) {
@@ -109,24 +109,24 @@ func TestFileRepository_HotReload(t *testing.T) {
 
 	// 初始保存
 	cfg1 := domainConfig.DefaultConfig()
-	cfg1.Protocols.Hysteria2.Port = 9001
+	cfg1.Protocols.Hysteria2().Port = 9001
 	repo.Save(ctx, cfg1)
 
 	// 加载
 	loaded1, _ := repo.Load(ctx)
-	assert.Equal(t, 9001, loaded1.Protocols.Hysteria2.Port)
+	assert.Equal(t, 9001, loaded1.Protocols.Hysteria2().Port)
 
 	// 等待一小段时间确保文件修改时间变化
 	time.Sleep(10 * time.Millisecond)
 
 	// 外部修改文件
 	cfg2 := domainConfig.DefaultConfig()
-	cfg2.Protocols.Hysteria2.Port = 9002
+	cfg2.Protocols.Hysteria2().Port = 9002
 	repo.Save(ctx, cfg2)
 
 	// 再次加载应该检测到变化
 	loaded2, _ := repo.Load(ctx)
-	assert.Equal(t, 9002, loaded2.Protocols.Hysteria2.Port)
+	assert.Equal(t, 9002, loaded2.Protocols.Hysteria2().Port)
 }
 
 func TestFileRepository_Save_NilConfig(t *testing.T) {
@@ -149,7 +149,7 @@ func TestFileRepository_AtomicWrite(t *testing.T) {
 	ctx := context.Background()
 
 	cfg := domainConfig.DefaultConfig()
-	cfg.Protocols.TUIC.Enabled = true
+	cfg.Protocols.TUIC().Enabled = true
 
 	// 保存
 	err := repo.Save(ctx, cfg)
@@ -202,8 +202,8 @@ func TestFileRepository_DeepCopy(t *testing.T) {
 
 	// 保存配置
 	cfg := domainConfig.DefaultConfig()
-	cfg.Protocols.Hysteria2.Enabled = true
-	cfg.Protocols.Hysteria2.Port = 12345
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.Hysteria2().Port = 12345
 	repo.Save(ctx, cfg)
 
 	// 第一次加载
@@ -215,10 +215,10 @@ func TestFileRepository_DeepCopy(t *testing.T) {
 	require.NoError(t, err)
 
 	// 修改 cfg1
-	cfg1.Protocols.Hysteria2.Port = 99999
+	cfg1.Protocols.Hysteria2().Port = 99999
 
 	// cfg2 不应受影响
-	assert.Equal(t, 12345, cfg2.Protocols.Hysteria2.Port, "深拷贝失败：cfg2 受到了 cfg1 的影响")
+	assert.Equal(t, 12345, cfg2.Protocols.Hysteria2().Port, "深拷贝失败：cfg2 受到了 cfg1 的影响")
 }
 
 func TestFileRepository_MultipleFields(t *testing.T) {
@@ -233,8 +233,8 @@ func TestFileRepository_MultipleFields(t *testing.T) {
 	cfg.Certificate.DNSProvider = "cloudflare"
 	cfg.Certificate.DNSProviderID = "test-id"
 	cfg.Certificate.DNSProviderSecret = "test-secret"
-	cfg.Protocols.Hysteria2.Enabled = true
-	cfg.Protocols.TUIC.Enabled = false
+	cfg.Protocols.Hysteria2().Enabled = true
+	cfg.Protocols.TUIC().Enabled = false
 
 	// 保存
 	err := repo.Save(ctx, cfg)
@@ -247,6 +247,6 @@ func TestFileRepository_MultipleFields(t *testing.T) {
 	assert.Equal(t, "cloudflare", loaded.Certificate.DNSProvider)
 	assert.Equal(t, "test-id", loaded.Certificate.DNSProviderID)
 	assert.Equal(t, "test-secret", loaded.Certificate.DNSProviderSecret)
-	assert.True(t, loaded.Protocols.Hysteria2.Enabled)
-	assert.False(t, loaded.Protocols.TUIC.Enabled)
+	assert.True(t, loaded.Protocols.Hysteria2().Enabled)
+	assert.False(t, loaded.Protocols.TUIC().Enabled)
 }
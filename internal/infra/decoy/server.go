@@ -0,0 +1,164 @@
+package decoy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Yat-Muk/prism-v2/internal/infra/system"
+)
+
+// ServiceName 本機偽裝站點服務的 systemd 單元名
+const ServiceName = "prism-decoy"
+
+// NewHandler 返回靜態站點處理器
+// 不列出目錄內容，缺失的頁面返回 404
+func NewHandler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			name := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+r.URL.Path)), "index.html")
+			if _, err := os.Stat(name); err != nil {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// Serve 在 host:port 上提供目錄中的靜態站點，ctx 取消時優雅退出
+func Serve(ctx context.Context, host string, port int, dir string) error {
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(host, strconv.Itoa(port)),
+		Handler:           NewHandler(dir),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("偽裝站點服務退出: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+const unitTemplate = `[Unit]
+Description=Prism decoy website
+After=network.target
+
+[Service]
+ExecStart={{.ExecStart}}
+Restart=on-failure
+RestartSec=3s
+NoNewPrivileges=yes
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// Service 本機偽裝站點服務的安裝與啟停
+// 服務以 systemd 單元運行，與 sing-box 一樣隨系統啟動，不依賴 TUI 進程
+type Service struct {
+	systemd  system.SystemdManager
+	unitPath string
+	command  []string
+	log      *zap.Logger
+}
+
+// NewService 創建本機偽裝站點服務，command 為啟動服務的完整命令行
+func NewService(systemd system.SystemdManager, unitPath string, command []string, log *zap.Logger) *Service {
+	return &Service{
+		systemd:  systemd,
+		unitPath: unitPath,
+		command:  command,
+		log:      log,
+	}
+}
+
+// unit 渲染 systemd 單元文件
+func (s *Service) unit() ([]byte, error) {
+	tmpl, err := template.New("unit").Parse(unitTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{"ExecStart": strings.Join(s.command, " ")})
+	return buf.Bytes(), err
+}
+
+// Ensure 安裝並啟動服務，單元文件變化時重啟
+func (s *Service) Ensure(ctx context.Context) error {
+	content, err := s.unit()
+	if err != nil {
+		return err
+	}
+	old, _ := os.ReadFile(s.unitPath)
+	changed := !bytes.Equal(old, content)
+	if changed {
+		if err := os.WriteFile(s.unitPath, content, 0644); err != nil {
+			return fmt.Errorf("寫入服務文件失敗: %w", err)
+		}
+		if err := exec.CommandContext(ctx, "systemctl", "daemon-reload").Run(); err != nil {
+			return fmt.Errorf("daemon-reload 失敗: %w", err)
+		}
+	}
+	if err := s.systemd.Enable(ctx, ServiceName); err != nil {
+		return fmt.Errorf("啟用服務失敗: %w", err)
+	}
+
+	active, _ := s.systemd.IsActive(ctx, ServiceName)
+	switch {
+	case changed && active:
+		err = s.systemd.Restart(ctx, ServiceName)
+	case !active:
+		err = s.systemd.Start(ctx, ServiceName)
+	}
+	if err != nil {
+		return fmt.Errorf("啟動服務失敗: %w", err)
+	}
+	s.log.Info("✅ 本機偽裝站點服務運行中", zap.String("unit", ServiceName))
+	return nil
+}
+
+// Remove 停止並刪除服務，未安裝時忽略
+func (s *Service) Remove(ctx context.Context) error {
+	if _, err := os.Stat(s.unitPath); os.IsNotExist(err) {
+		return nil
+	}
+	_ = s.systemd.Stop(ctx, ServiceName)
+	_ = s.systemd.Disable(ctx, ServiceName)
+	if err := os.Remove(s.unitPath); err != nil {
+		return fmt.Errorf("刪除服務文件失敗: %w", err)
+	}
+	if err := exec.CommandContext(ctx, "systemctl", "daemon-reload").Run(); err != nil {
+		return fmt.Errorf("daemon-reload 失敗: %w", err)
+	}
+	s.log.Info("已移除本機偽裝站點服務", zap.String("unit", ServiceName))
+	return nil
+}
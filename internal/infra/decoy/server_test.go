@@ -0,0 +1,58 @@
+package decoy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := EnsureSite(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(NewHandler(dir))
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := get("/"); code != http.StatusOK || !strings.Contains(body, "<!DOCTYPE html>") {
+		t.Errorf("首頁應返回站點內容: %d", code)
+	}
+	if code, _ := get("/style.css"); code != http.StatusOK {
+		t.Errorf("靜態文件應可訪問: %d", code)
+	}
+	if code, _ := get("/assets/"); code != http.StatusNotFound {
+		t.Errorf("不應列出目錄內容: %d", code)
+	}
+	if code, _ := get("/missing.html"); code != http.StatusNotFound {
+		t.Errorf("缺失頁面應返回 404: %d", code)
+	}
+}
+
+func TestServiceUnit(t *testing.T) {
+	s := NewService(nil, "", []string{"/usr/local/bin/prism", "-dir", "/etc/prism", "decoy", "serve"}, nil)
+	unit, err := s.unit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(unit), "ExecStart=/usr/local/bin/prism -dir /etc/prism decoy serve\n") {
+		t.Errorf("單元文件命令錯誤:\n%s", unit)
+	}
+}
@@ -0,0 +1,129 @@
+package decoy
+
+import (
+	"crypto/rand"
+	"fmt"
+	"html/template"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// site 內置站點的模板數據
+type site struct {
+	Name    string
+	Tagline string
+	Year    int
+}
+
+// 站點名稱與簡介，生成時隨機組合，避免所有服務器返回相同頁面
+var (
+	siteNames    = []string{"Northwind Studio", "Lumen Labs", "Harbor & Pine", "Cedar Notes", "Bluefield Works", "Quiet Orbit"}
+	siteTaglines = []string{
+		"Design and engineering for small teams.",
+		"Notes on software, photography and travel.",
+		"Independent consulting since 2014.",
+		"Building simple tools for everyday work.",
+	}
+)
+
+// 站點文件 (文件名 -> 模板)，index.html 作為已生成的標記
+var pages = map[string]string{
+	"index.html": `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<link rel="stylesheet" href="/style.css">
+</head>
+<body>
+<header><a href="/">{{.Name}}</a><nav><a href="/about.html">About</a></nav></header>
+<main>
+<h1>{{.Name}}</h1>
+<p>{{.Tagline}}</p>
+<p>We are currently updating this site. Please check back soon.</p>
+</main>
+<footer>&copy; {{.Year}} {{.Name}}</footer>
+</body>
+</html>
+`,
+	"about.html": `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>About - {{.Name}}</title>
+<link rel="stylesheet" href="/style.css">
+</head>
+<body>
+<header><a href="/">{{.Name}}</a><nav><a href="/about.html">About</a></nav></header>
+<main>
+<h1>About</h1>
+<p>{{.Name}} is a small independent team. {{.Tagline}}</p>
+</main>
+<footer>&copy; {{.Year}} {{.Name}}</footer>
+</body>
+</html>
+`,
+	"style.css": `body{margin:0;font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;color:#222;background:#fafafa}
+header,main,footer{max-width:720px;margin:0 auto;padding:1.5rem}
+header{display:flex;justify-content:space-between}
+a{color:#2a5db0;text-decoration:none}
+footer{color:#888;font-size:.875rem}
+`,
+	"robots.txt": "User-agent: *\nAllow: /\n",
+}
+
+// EnsureSite 在目錄下生成最小靜態站點
+// 已存在 index.html 時不做修改，保留用戶自定義的內容，返回是否新生成
+func EnsureSite(dir string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, "index.html")); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("創建站點目錄失敗: %w", err)
+	}
+
+	data := site{
+		Name:    pick(siteNames),
+		Tagline: pick(siteTaglines),
+		Year:    time.Now().Year(),
+	}
+
+	// index.html 最後寫入，中途失敗時下次會重新生成
+	names := []string{"about.html", "style.css", "robots.txt", "index.html"}
+	for _, name := range names {
+		if err := writePage(filepath.Join(dir, name), pages[name], data); err != nil {
+			return false, fmt.Errorf("寫入 %s 失敗: %w", name, err)
+		}
+	}
+	return true, nil
+}
+
+// writePage 渲染模板並寫入文件
+func writePage(path, text string, data site) error {
+	tmpl, err := template.New(filepath.Base(path)).Parse(text)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// pick 隨機選取一項
+func pick(list []string) string {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(list))))
+	if err != nil {
+		return list[0]
+	}
+	return list[n.Int64()]
+}
@@ -0,0 +1,39 @@
+package decoy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsureSite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "decoy")
+
+	created, err := EnsureSite(dir)
+	if err != nil || !created {
+		t.Fatalf("首次應生成站點: %v %v", created, err)
+	}
+	for name := range pages {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("缺少文件 %s: %v", name, err)
+		}
+	}
+	index, _ := os.ReadFile(filepath.Join(dir, "index.html"))
+	if strings.Contains(string(index), "{{") {
+		t.Errorf("模板未渲染: %s", index)
+	}
+
+	// 用戶修改後不應被覆蓋
+	custom := []byte("<h1>custom</h1>")
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), custom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	created, err = EnsureSite(dir)
+	if err != nil || created {
+		t.Fatalf("已存在站點時不應重新生成: %v %v", created, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "index.html")); string(got) != string(custom) {
+		t.Errorf("用戶內容被覆蓋: %s", got)
+	}
+}
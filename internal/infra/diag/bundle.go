@@ -0,0 +1,273 @@
+package diag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Yat-Muk/prism-v2/internal/infra/certinfo"
+	"github.com/Yat-Muk/prism-v2/internal/infra/firewall"
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
+	"github.com/Yat-Muk/prism-v2/internal/infra/singbox"
+	"github.com/Yat-Muk/prism-v2/internal/infra/system"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/version"
+)
+
+// 診斷包收集的日誌範圍
+const (
+	logWindow   = 72 * time.Hour
+	logMaxLines = 5000
+)
+
+// Options 診斷包的信息來源，未提供的項目會被跳過
+type Options struct {
+	Paths       *appctx.Paths
+	Firewall    firewall.Manager
+	SysInfo     *system.SystemInfo
+	CoreVersion string // 留空時通過 sing-box version 檢測
+	SingboxUnit string // 留空使用 sing-box 默認服務名
+}
+
+// file 診斷包中的單個文件
+type file struct {
+	name    string
+	content string
+}
+
+// Build 收集診斷信息並打包為 tar.gz，返回生成的文件路徑
+// dir 為輸出目錄，留空使用系統臨時目錄
+// 單項收集失敗不會中斷打包，錯誤記錄在 errors.txt 中
+func Build(ctx context.Context, opts Options, dir string) (string, error) {
+	if opts.Paths == nil {
+		return "", fmt.Errorf("缺少路徑配置")
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if opts.SingboxUnit == "" {
+		opts.SingboxUnit = singbox.ServiceName
+	}
+
+	c := &collector{opts: opts, redactor: NewRedactor()}
+	files := c.collect(ctx)
+
+	name := fmt.Sprintf("prism-diag-%s.tar.gz", time.Now().Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+	if err := writeArchive(path, strings.TrimSuffix(name, ".tar.gz"), files); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+type collector struct {
+	opts     Options
+	redactor *Redactor
+	errs     []string
+}
+
+func (c *collector) fail(item string, err error) {
+	c.errs = append(c.errs, fmt.Sprintf("%s: %v", item, err))
+}
+
+// collect 收集所有文件
+// 先讀取配置登記敏感值，再對每個文本文件統一脫敏
+func (c *collector) collect(ctx context.Context) []file {
+	paths := c.opts.Paths
+
+	configYAML, err := os.ReadFile(paths.ConfigFile)
+	if err != nil {
+		c.fail("config.yaml", err)
+	} else if err := c.redactor.CollectYAML(configYAML); err != nil {
+		c.fail("config.yaml", err)
+	}
+
+	singboxJSON, err := os.ReadFile(filepath.Join(paths.ConfigDir, "config.json"))
+	if err != nil {
+		c.fail("sing-box config.json", err)
+	} else if err := c.redactor.CollectJSON(singboxJSON); err != nil {
+		c.fail("sing-box config.json", err)
+	}
+
+	var files []file
+	files = append(files, file{"summary.txt", c.summary()})
+	files = append(files, c.systemStats()...)
+	files = append(files, file{"firewall.txt", c.firewallState()})
+	files = append(files, file{"certs.json", c.certs()})
+	if configYAML != nil {
+		files = append(files, file{"config.yaml", string(configYAML)})
+	}
+	if singboxJSON != nil {
+		files = append(files, file{"sing-box.json", string(singboxJSON)})
+	}
+	files = append(files, c.logs(ctx)...)
+
+	if len(c.errs) > 0 {
+		files = append(files, file{"errors.txt", strings.Join(c.errs, "\n") + "\n"})
+	}
+
+	// 所有文本文件統一經過脫敏
+	for i := range files {
+		files[i].content = c.redactor.Text(files[i].content)
+	}
+	return files
+}
+
+func (c *collector) summary() string {
+	coreVersion := c.opts.CoreVersion
+	if coreVersion == "" || coreVersion == "unknown" {
+		coreVersion = detectCoreVersion(c.opts.Paths.CoreBinPath)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "生成時間: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "Prism: %s\n", version.Info())
+	fmt.Fprintf(&sb, "sing-box: %s\n", coreVersion)
+	fmt.Fprintf(&sb, "運行環境: %s/%s %s\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	return sb.String()
+}
+
+// systemStats 系統狀態，公網 IP 登記為敏感值
+func (c *collector) systemStats() []file {
+	if c.opts.SysInfo == nil {
+		return nil
+	}
+	stats, err := c.opts.SysInfo.GetStats()
+	if err != nil {
+		c.fail("系統信息", err)
+		return nil
+	}
+	c.redactor.Add("ipv4", stats.IPv4)
+	c.redactor.Add("ipv6", stats.IPv6)
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		c.fail("系統信息", err)
+		return nil
+	}
+	return []file{{"system.json", string(data)}}
+}
+
+func (c *collector) firewallState() string {
+	fw := c.opts.Firewall
+	if fw == nil {
+		return "防火牆: 未檢測\n"
+	}
+	caps := fw.Capabilities()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "後端: %s\n", fw.Type())
+	fmt.Fprintf(&sb, "IPv6: %v\n端口跳躍: %v\n規則備註: %v\n", caps.SupportIPv6, caps.SupportPortHopping, caps.SupportComment)
+	fmt.Fprintf(&sb, "來源過濾: %v\n速率限制: %v\n國家屏蔽: %v\n", caps.SupportSourceFilter, caps.SupportRateLimit, caps.SupportGeoIP)
+	fmt.Fprintf(&sb, "已開放端口: %v\n", fw.GetOpenedPorts())
+	return sb.String()
+}
+
+func (c *collector) certs() string {
+	certDir := c.opts.Paths.CertDir
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"summary": certinfo.GetCertSummary(certDir),
+		"certs":   certinfo.GetAllCertList(certDir),
+	}, "", "  ")
+	if err != nil {
+		c.fail("證書信息", err)
+		return "{}"
+	}
+	return string(data)
+}
+
+// logs 收集最近的 Prism 與 sing-box 日誌 (包含輪轉文件)
+func (c *collector) logs(ctx context.Context) []file {
+	q := logs.Query{Since: time.Now().Add(-logWindow), Limit: logMaxLines}
+
+	prismLog := filepath.Join(c.opts.Paths.LogDir, "prism.log")
+	var files []file
+	if entries, err := logs.SearchFiles(ctx, prismLog, q); err != nil {
+		c.fail("Prism 日誌", err)
+	} else {
+		files = append(files, file{"logs/prism.log", joinEntries(entries)})
+	}
+
+	searcher := &logs.Searcher{
+		SingboxUnit: c.opts.SingboxUnit,
+		SingboxLog:  filepath.Join(c.opts.Paths.LogDir, "sing-box.log"),
+	}
+	q.Origin = logs.OriginSingbox
+	if entries, err := searcher.Search(ctx, q); err != nil {
+		c.fail("sing-box 日誌", err)
+	} else {
+		files = append(files, file{"logs/sing-box.log", joinEntries(entries)})
+	}
+	return files
+}
+
+func joinEntries(entries []logs.Entry) string {
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(e.Raw)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// detectCoreVersion 從 "sing-box version 1.x.y" 輸出中解析版本
+func detectCoreVersion(bin string) string {
+	if _, err := os.Stat(bin); err != nil {
+		if bin, err = exec.LookPath("sing-box"); err != nil {
+			return "未安裝"
+		}
+	}
+	out, err := exec.Command(bin, "version").Output()
+	if err != nil {
+		return "unknown"
+	}
+	fields := strings.Fields(strings.SplitN(string(out), "\n", 2)[0])
+	if len(fields) >= 3 {
+		return fields[2]
+	}
+	return "unknown"
+}
+
+// writeArchive 寫入 tar.gz，文件權限僅限所有者讀取
+func writeArchive(path, root string, files []file) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("創建診斷包失敗: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name:    root + "/" + file.name,
+			Mode:    0600,
+			Size:    int64(len(file.content)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("寫入診斷包失敗: %w", err)
+		}
+		if _, err := tw.Write([]byte(file.content)); err != nil {
+			return fmt.Errorf("寫入診斷包失敗: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("寫入診斷包失敗: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("寫入診斷包失敗: %w", err)
+	}
+	return f.Close()
+}
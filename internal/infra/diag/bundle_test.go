@@ -0,0 +1,91 @@
+package diag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/infra/firewall"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUUID     = "3f2c9a8e-1b4d-4e6f-9a7b-2c8d1e5f6a70"
+	testPassword = "Hy2-Secret-Passw0rd"
+	testKey      = "kLmN0pQrStUvWxYz1234567890abcdefGHIJKLMNOPQ"
+	testDNSID    = "dns-api-id-7788aa"
+	testDNSKey   = "cf-api-key-99ccdd"
+)
+
+func TestBuild(t *testing.T) {
+	paths, err := appctx.NewPaths(t.TempDir())
+	require.NoError(t, err)
+
+	configYAML := "version: 3\nuuid: " + testUUID + "\npassword: " + testPassword + "\n" +
+		"certificate:\n  dns_provider_id: " + testDNSID + "\n  dns_providers:\n    cloudflare:\n      id: " + testDNSKey + "\n"
+	require.NoError(t, os.WriteFile(paths.ConfigFile, []byte(configYAML), 0600))
+	singboxJSON := `{"inbounds":[{"tag":"hysteria2-in","users":[{"password":"` + testPassword + `"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(paths.ConfigDir, "config.json"), []byte(singboxJSON), 0600))
+	logLine := `{"level":"info","ts":"2099-01-01T00:00:00.000Z","msg":"生成鏈接 hysteria2://` + testPassword + `@example.com:443"}`
+	require.NoError(t, os.WriteFile(filepath.Join(paths.LogDir, "prism.log"), []byte(logLine+"\n"), 0600))
+
+	out, err := Build(context.Background(), Options{
+		Paths:       paths,
+		Firewall:    &firewall.NoOpManager{},
+		CoreVersion: "1.12.0",
+	}, t.TempDir())
+	require.NoError(t, err)
+
+	info, err := os.Stat(out)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	files := readArchive(t, out)
+	for _, name := range []string{"summary.txt", "firewall.txt", "certs.json", "config.yaml", "sing-box.json"} {
+		assert.Contains(t, files, name)
+	}
+	assert.Contains(t, files["summary.txt"], "1.12.0")
+	assert.Contains(t, files["firewall.txt"], "none")
+
+	for name, content := range files {
+		assert.NotContains(t, content, testPassword, name)
+		assert.NotContains(t, content, testUUID, name)
+		assert.NotContains(t, content, testDNSID, name)
+		assert.NotContains(t, content, testDNSKey, name)
+	}
+	assert.Contains(t, files["sing-box.json"], "[REDACTED:password-1]")
+}
+
+func readArchive(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		// 去掉根目錄
+		_, name, _ := strings.Cut(hdr.Name, "/")
+		files[name] = string(data)
+	}
+	return files
+}
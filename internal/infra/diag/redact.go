@@ -0,0 +1,160 @@
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/pkg/logger"
+	"github.com/Yat-Muk/prism-v2/internal/pkg/sanitizer"
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeys 已知的敏感字段 (config.yaml 與 sing-box JSON 的鍵名)
+var secretKeys = map[string]bool{
+	"password":            true,
+	"ss_password":         true,
+	"uuid":                true,
+	"private_key":         true,
+	"pre_shared_key":      true,
+	"short_id":            true,
+	"reality_short_id":    true,
+	"license_key":         true,
+	"access_token":        true,
+	"token":               true,
+	"api_token":           true,
+	"secret":              true,
+	"dns_provider_id":     true,
+	"dns_provider_secret": true,
+	"eab_key_id":          true,
+	"eab_hmac_key":        true,
+	"access_key_id":       true,
+	"secret_access_key":   true,
+	"acme_email":          true,
+	"email":               true,
+}
+
+// scopedSecretKeys 僅在指定父字段之下才敏感的鍵名 (鍵名 -> 祖先字段)
+// 如 dns_providers.<name>.id 為 DNS API Key，其他位置的 id 不做處理
+var scopedSecretKeys = map[string]string{
+	"id": "dns_providers",
+}
+
+// 過短的值容易誤傷普通文本，不做全文替換
+const minSecretLength = 6
+
+// Redactor 脫敏器
+// 先按已知的敏感值全文替換為穩定的佔位符，保證同一密鑰在所有文件中的掩碼一致，
+// 再使用通用規則兜底處理未登記的密碼、UUID 與郵箱
+type Redactor struct {
+	secrets map[string]string // 原值 -> 佔位符
+	counts  map[string]int    // 字段 -> 已分配的序號
+	ordered []string          // 按長度降序，避免短值截斷長值
+}
+
+// NewRedactor 創建脫敏器
+func NewRedactor() *Redactor {
+	return &Redactor{
+		secrets: make(map[string]string),
+		counts:  make(map[string]int),
+	}
+}
+
+// Add 登記一個敏感值，重複登記返回已分配的佔位符
+func (r *Redactor) Add(kind, value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return ""
+	}
+	if p, ok := r.secrets[value]; ok {
+		return p
+	}
+	r.counts[kind]++
+	p := fmt.Sprintf("[REDACTED:%s-%d]", kind, r.counts[kind])
+	r.secrets[value] = p
+	r.ordered = nil
+	return p
+}
+
+// CollectYAML 登記 YAML 文檔中已知敏感字段的值
+func (r *Redactor) CollectYAML(data []byte) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析 YAML 失敗: %w", err)
+	}
+	r.collect(nil, "", doc)
+	return nil
+}
+
+// CollectJSON 登記 JSON 文檔中已知敏感字段的值
+func (r *Redactor) CollectJSON(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析 JSON 失敗: %w", err)
+	}
+	r.collect(nil, "", doc)
+	return nil
+}
+
+// collect 遞歸登記敏感值，parents 為從根到當前字段的父字段名
+func (r *Redactor) collect(parents []string, key string, v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		path := parents
+		if key != "" {
+			path = append(parents[:len(parents):len(parents)], key)
+		}
+		for k, child := range val {
+			r.collect(path, strings.ToLower(k), child)
+		}
+	case []interface{}:
+		// 數組繼承父字段名 (如 sing-box 的 short_id 列表)
+		for _, child := range val {
+			r.collect(parents, key, child)
+		}
+	case string:
+		if secretKeys[key] || r.scopedSecret(parents, key) {
+			r.Add(key, val)
+		}
+	}
+}
+
+// scopedSecret 字段是否位於使其敏感的祖先字段之下
+func (r *Redactor) scopedSecret(parents []string, key string) bool {
+	ancestor, ok := scopedSecretKeys[key]
+	if !ok {
+		return false
+	}
+	for _, p := range parents {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Text 對文本脫敏
+func (r *Redactor) Text(s string) string {
+	if r.ordered == nil {
+		for v := range r.secrets {
+			r.ordered = append(r.ordered, v)
+		}
+		sort.Slice(r.ordered, func(i, j int) bool {
+			if len(r.ordered[i]) != len(r.ordered[j]) {
+				return len(r.ordered[i]) > len(r.ordered[j])
+			}
+			return r.ordered[i] < r.ordered[j]
+		})
+	}
+
+	for _, v := range r.ordered {
+		s = strings.ReplaceAll(s, v, r.secrets[v])
+	}
+
+	s = logger.MaskSensitive(s)
+	if out, ok := sanitizer.Sanitize(s).(string); ok {
+		s = out
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package diag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactorConsistent(t *testing.T) {
+	r := NewRedactor()
+	require.NoError(t, r.CollectYAML([]byte("uuid: "+testUUID+"\nprotocols:\n  hysteria2:\n    password: "+testPassword+"\n")))
+	require.NoError(t, r.CollectJSON([]byte(`{"inbounds":[{"tls":{"reality":{"private_key":"`+testKey+`","short_id":["a1b2c3d4"]}}}]}`)))
+
+	a := r.Text(`auth ok, password ` + testPassword + ` key=` + testKey)
+	b := r.Text(`{"password":"` + testPassword + `","private_key":"` + testKey + `","short_id":["a1b2c3d4"]}`)
+
+	for _, out := range []string{a, b} {
+		assert.NotContains(t, out, testPassword)
+		assert.NotContains(t, out, testKey)
+	}
+	// 同一密鑰在不同文本中使用相同的佔位符
+	assert.Contains(t, a, "[REDACTED:password-1]")
+	assert.Contains(t, b, "[REDACTED:password-1]")
+	assert.Contains(t, b, "[REDACTED:short_id-1]")
+
+	// 未登記的值由通用規則兜底
+	out := r.Text("user admin@example.com id " + testUUID)
+	assert.NotContains(t, out, "admin@")
+	assert.NotContains(t, out, testUUID)
+}
+
+func TestRedactorDNSProviderCredentials(t *testing.T) {
+	const apiID = "dns-api-id-123456"
+	const apiKey = "cf-api-key-abcdef"
+	const nodeID = "node-identifier-42"
+
+	r := NewRedactor()
+	require.NoError(t, r.CollectYAML([]byte("cert:\n  dns_provider_id: "+apiID+
+		"\n  dns_providers:\n    cloudflare:\n      id: "+apiKey+"\n      secret: x\nnode:\n  id: "+nodeID+"\n")))
+
+	out := r.Text("dns_provider_id=" + apiID + " id=" + apiKey + " node=" + nodeID)
+	assert.NotContains(t, out, apiID)
+	assert.NotContains(t, out, apiKey)
+	assert.Contains(t, out, "[REDACTED:dns_provider_id-1]")
+	assert.Contains(t, out, "[REDACTED:id-1]")
+	// dns_providers 之外的 id 不屬於憑證
+	assert.Contains(t, out, nodeID)
+}
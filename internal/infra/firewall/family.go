@@ -0,0 +1,55 @@
+package firewall
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// 地址族，用於區分分別保存 IPv4/IPv6 規則的後端 (如 iptables 與 ip6tables)
+const (
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+)
+
+// familyOps 按地址族分別保存規則的後端實現
+// 某條規則只存在於部分地址族時，由 Reconcile 調用 addFamily 補齊
+type familyOps interface {
+	addFamily(ctx context.Context, r Rule, family string) error
+}
+
+// hostIPv6Enabled 主機是否啟用了 IPv6
+// 啟用時 IPv6 規則添加失敗視為錯誤，否則 IPv6 客戶端會繞過端口跳躍等規則
+func hostIPv6Enabled() bool {
+	if data, err := os.ReadFile("/proc/sys/net/ipv6/conf/all/disable_ipv6"); err == nil && strings.TrimSpace(string(data)) == "1" {
+		return false
+	}
+	// 未加載 IPv6 模塊或沒有任何 IPv6 地址時該文件不存在或為空
+	data, err := os.ReadFile("/proc/net/if_inet6")
+	return err == nil && strings.TrimSpace(string(data)) != ""
+}
+
+// missingFamilies 返回規則尚未覆蓋的地址族，不區分地址族的規則 (如 nft inet 表) 返回 nil
+func missingFamilies(have []string, ipv6 bool) []string {
+	if len(have) == 0 {
+		return nil
+	}
+	want := []string{familyIPv4}
+	if ipv6 {
+		want = append(want, familyIPv6)
+	}
+	var missing []string
+	for _, f := range want {
+		found := false
+		for _, h := range have {
+			if h == f {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
@@ -4,26 +4,40 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
-	"sort"
+	"regexp"
+	"strconv"
 	"strings"
-	"sync"
 
 	"go.uber.org/zap"
-
-	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
+// firewalld 不支持規則備註，Prism 的端口統一放在專用服務中，以服務歸屬識別
+const firewalldService = "prism"
+
+// 源地址策略使用的 ipset 名稱前綴，策略富規則以引用的 ipset 識別
+const firewalldIPSetPrefix = "prism-"
+
+// "443/tcp", "20000-30000/udp"
+var firewalldPortPattern = regexp.MustCompile(`^(\d+)(?:-(\d+))?/(tcp|udp)$`)
+
+// 端口跳躍的本地重定向富規則 (不帶 to-addr)，IPv6 轉發端口只能通過富規則配置
+// rule family="ipv4" forward-port port="20000-30000" protocol="udp" to-port="443"
+var firewalldForwardPattern = regexp.MustCompile(`^rule family="(ipv4|ipv6)" forward-port port="(\d+)(?:-(\d+))?" protocol="(tcp|udp)" to-port="(\d+)"$`)
+
 type FirewalldManager struct {
-	log         *zap.Logger
-	openedPorts map[int]bool
-	mu          sync.Mutex
+	*ruleManager
 }
 
 func NewFirewalld(log *zap.Logger) *FirewalldManager {
-	return &FirewalldManager{
-		log:         log,
-		openedPorts: make(map[int]bool),
-	}
+	f := newFirewalld(log, execRunner{})
+	f.ipv6 = hostIPv6Enabled
+	return f
+}
+
+func newFirewalld(log *zap.Logger, runner Runner) *FirewalldManager {
+	f := &FirewalldManager{}
+	f.ruleManager = &ruleManager{log: log, run: runner, ops: f}
+	return f
 }
 
 func (f *FirewalldManager) Type() string {
@@ -33,120 +47,297 @@ func (f *FirewalldManager) Type() string {
 func (f *FirewalldManager) Capabilities() Capabilities {
 	return Capabilities{
 		SupportIPv6:        true,  // firewalld 默認支持
-		SupportPortHopping: false, // firewalld 不適合做 DNAT
-		SupportComment:     false, // firewalld 不支持規則標記
+		SupportPortHopping: true,  // forward-port 富規則 (IPv4/IPv6 各一條)
+		SupportComment:     false, // firewalld 不支持規則標記 (以專用服務代替)
 		SupportBoth:        false,
+
+		SupportSourceFilter: true,  // ipset + 富規則
+		SupportRateLimit:    false, // 富規則的 limit 不區分來源
+		SupportGeoIP:        true,  // hash:net ipset
 	}
 }
 
-func (f *FirewalldManager) OpenPort(ctx context.Context, port int, protocol string) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	if protocol == "both" {
-		if err := f.openSinglePort(ctx, port, "tcp"); err != nil {
-			return err
-		}
-		return f.openSinglePort(ctx, port, "udp")
+func firewalldSpec(r Rule) string {
+	if r.IsRange() {
+		return fmt.Sprintf("%d-%d/%s", r.Start, r.End, r.Protocol)
 	}
-	return f.openSinglePort(ctx, port, protocol)
+	return fmt.Sprintf("%d/%s", r.Start, r.Protocol)
 }
 
-func (f *FirewalldManager) openSinglePort(ctx context.Context, port int, protocol string) error {
-	portStr := fmt.Sprintf("%d/%s", port, strings.ToLower(protocol))
-
-	// 檢查規則是否已存在
-	checkCmd := exec.CommandContext(ctx, "firewall-cmd", "--query-port="+portStr)
-	if checkCmd.Run() == nil {
-		f.openedPorts[port] = true
-		return nil
+// list 讀取 prism 服務中的永久端口與端口跳躍富規則
+func (f *FirewalldManager) list(ctx context.Context) ([]installedRule, error) {
+	rules, err := f.listServicePorts(ctx)
+	if err != nil {
+		return nil, err
 	}
+	redirects, err := f.listForwardPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(rules, redirects...), nil
+}
 
-	// 添加規則（臨時）
-	cmd := exec.CommandContext(ctx, "firewall-cmd", "--add-port="+portStr)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("添加臨時規則失敗: %w", err)
+// listServicePorts 讀取 prism 服務中的永久端口，服務不存在時視為無規則
+func (f *FirewalldManager) listServicePorts(ctx context.Context) ([]installedRule, error) {
+	services, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--get-services")
+	if err != nil {
+		return nil, fmt.Errorf("獲取 firewalld 服務列表失敗: %w", err)
+	}
+	if !containsField(services, firewalldService) {
+		return nil, nil
 	}
 
-	// 添加規則（永久）
-	cmd = exec.CommandContext(ctx, "firewall-cmd", "--permanent", "--add-port="+portStr)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("添加永久規則失敗: %w", err)
+	output, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--service="+firewalldService, "--get-ports")
+	if err != nil {
+		return nil, fmt.Errorf("獲取 prism 服務端口失敗: %w", err)
 	}
 
-	f.openedPorts[port] = true
-	return nil
+	var rules []installedRule
+	for _, spec := range strings.Fields(output) {
+		m := firewalldPortPattern.FindStringSubmatch(spec)
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[1])
+		end := start
+		if m[2] != "" {
+			end, _ = strconv.Atoi(m[2])
+		}
+		rules = append(rules, installedRule{
+			Rule: Rule{Protocol: m[3], Start: start, End: end},
+			deletes: [][]string{{
+				"firewall-cmd", "--permanent", "--service=" + firewalldService, "--remove-port=" + spec,
+			}},
+		})
+	}
+	return rules, nil
 }
 
-func (f *FirewalldManager) OpenPortRange(ctx context.Context, startPort, endPort int, protocol string) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	rangeStr := fmt.Sprintf("%d-%d/%s", startPort, endPort, strings.ToLower(protocol))
+// listForwardPorts 讀取默認區域中的端口跳躍富規則
+func (f *FirewalldManager) listForwardPorts(ctx context.Context) ([]installedRule, error) {
+	output, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--list-rich-rules")
+	if err != nil {
+		return nil, fmt.Errorf("獲取富規則失敗: %w", err)
+	}
 
-	// 臨時規則
-	cmd := exec.CommandContext(ctx, "firewall-cmd", "--add-port="+rangeStr)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("添加臨時範圍規則失敗: %w", err)
+	var rules []installedRule
+	for _, line := range strings.Split(output, "\n") {
+		rule := strings.TrimSpace(line)
+		m := firewalldForwardPattern.FindStringSubmatch(rule)
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[2])
+		end := start
+		if m[3] != "" {
+			end, _ = strconv.Atoi(m[3])
+		}
+		to, _ := strconv.Atoi(m[5])
+		rules = append(rules, installedRule{
+			Rule:     Rule{Protocol: m[4], Start: start, End: end, RedirectTo: to},
+			deletes:  [][]string{{"firewall-cmd", "--permanent", "--remove-rich-rule=" + rule}},
+			families: []string{m[1]},
+		})
 	}
+	return rules, nil
+}
 
-	// 永久規則
-	cmd = exec.CommandContext(ctx, "firewall-cmd", "--permanent", "--add-port="+rangeStr)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("添加永久範圍規則失敗: %w", err)
+// firewalldForwardRule 端口跳躍的富規則表示
+func firewalldForwardRule(r Rule, family string) string {
+	ports := strconv.Itoa(r.Start)
+	if r.IsRange() {
+		ports = fmt.Sprintf("%d-%d", r.Start, r.End)
 	}
+	return fmt.Sprintf(`rule family="%s" forward-port port="%s" protocol="%s" to-port="%d"`, family, ports, r.Protocol, r.RedirectTo)
+}
 
+func (f *FirewalldManager) add(ctx context.Context, r Rule) error {
+	if r.IsRedirect() {
+		if err := f.addFamily(ctx, r, familyIPv4); err != nil {
+			return err
+		}
+		if !f.ipv6Enabled() {
+			return nil
+		}
+		return f.addFamily(ctx, r, familyIPv6)
+	}
+	if err := f.ensureService(ctx); err != nil {
+		return err
+	}
+	if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--service="+firewalldService, "--add-port="+firewalldSpec(r)); err != nil {
+		return fmt.Errorf("添加永久規則失敗: %w", err)
+	}
 	return nil
 }
 
-func (f *FirewalldManager) OpenHysteria2PortHopping(ctx context.Context, listenPort, startPort, endPort int) error {
-	f.log.Warn("⚠️ firewalld 不支持端口跳躍功能",
-		zap.Int("listen", listenPort),
-		zap.Int("start", startPort),
-		zap.Int("end", endPort),
-		zap.String("建議", "切換到 nftables 或 iptables"))
-	return errors.New("FIREWALL_UNSUPPORTED", "firewalld 不支持端口跳躍功能，請改用 nftables 或 iptables")
+// addFamily 添加指定地址族的端口跳躍富規則
+func (f *FirewalldManager) addFamily(ctx context.Context, r Rule, family string) error {
+	if !r.IsRedirect() {
+		// 服務端口不區分地址族
+		return f.add(ctx, r)
+	}
+	if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--add-rich-rule="+firewalldForwardRule(r, family)); err != nil {
+		return fmt.Errorf("添加 %s 端口跳躍規則失敗: %w", family, err)
+	}
+	return nil
 }
 
-func (f *FirewalldManager) GetOpenedPorts() []int {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+// ensureService 創建 prism 服務並加入默認區域
+func (f *FirewalldManager) ensureService(ctx context.Context) error {
+	services, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--get-services")
+	if err != nil {
+		return fmt.Errorf("獲取 firewalld 服務列表失敗: %w", err)
+	}
+	if !containsField(services, firewalldService) {
+		if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--new-service="+firewalldService); err != nil {
+			return fmt.Errorf("創建 prism 服務失敗: %w", err)
+		}
+	}
 
-	ports := make([]int, 0, len(f.openedPorts))
-	for port := range f.openedPorts {
-		ports = append(ports, port)
+	if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--query-service="+firewalldService); err != nil {
+		if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--add-service="+firewalldService); err != nil {
+			return fmt.Errorf("啟用 prism 服務失敗: %w", err)
+		}
 	}
-	sort.Ints(ports)
-	return ports
+	return nil
 }
 
-func (f *FirewalldManager) SaveRules(ctx context.Context) error {
-	// firewalld 自動持久化規則
-	cmd := exec.CommandContext(ctx, "firewall-cmd", "--reload")
-	if err := cmd.Run(); err != nil {
+// commit 重載使永久規則生效 (已建立的連接不受影響)
+func (f *FirewalldManager) commit(ctx context.Context) error {
+	if _, err := f.run.Run(ctx, "firewall-cmd", "--reload"); err != nil {
 		return fmt.Errorf("重載 firewalld 失敗: %w", err)
 	}
-	f.log.Info("✅ firewalld 規則已重載")
 	return nil
 }
 
-func (f *FirewalldManager) FlushRules(ctx context.Context) error {
+func (f *FirewalldManager) SaveRules(ctx context.Context) error {
+	// 規則均以 --permanent 寫入，在 commit 時已重載生效
+	f.log.Info("firewalld 規則已持久化")
+	return nil
+}
+
+// ApplyPolicies 刪除舊的策略富規則與 ipset 後按新策略重建，最後重載生效
+// 拒絕類富規則在 firewalld 中先於服務放行匹配
+func (f *FirewalldManager) ApplyPolicies(ctx context.Context, policies []Policy) error {
+	policies, unsupported := supportedPolicies(f.Capabilities(), policies)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	f.log.Info("🔥 重載 firewalld 配置")
+	if err := f.clearPolicies(ctx); err != nil {
+		return err
+	}
 
-	// firewalld 沒有按標記刪除的功能，只能重載配置
-	cmd := exec.CommandContext(ctx, "firewall-cmd", "--reload")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("重載失敗: %w", err)
+	for _, p := range policies {
+		prefix := fmt.Sprintf("%s%s-%d", firewalldIPSetPrefix, p.Protocol, p.Port)
+		deny4, deny6 := splitFamilies(append(append([]string{}, p.Deny...), p.Countries...))
+		allow4, allow6 := splitFamilies(p.Allow)
+
+		exempt4, exempt6 := splitFamilies(p.Exempt)
+
+		sets := []struct {
+			name, family string
+			entries      []string
+			negate, want bool
+			action       string
+		}{
+			// 豁免使用負優先級，先於所有拒絕規則匹配
+			{prefix + "-exempt4", "ipv4", exempt4, false, len(exempt4) > 0, "accept"},
+			{prefix + "-exempt6", "ipv6", exempt6, false, len(exempt6) > 0, "accept"},
+			{prefix + "-deny4", "ipv4", deny4, false, len(deny4) > 0, "drop"},
+			{prefix + "-deny6", "ipv6", deny6, false, len(deny6) > 0, "drop"},
+			// 白名單中某地址族無條目時，空集合取反即丟棄該地址族全部來源
+			{prefix + "-allow4", "ipv4", allow4, true, len(p.Allow) > 0, "drop"},
+			{prefix + "-allow6", "ipv6", allow6, true, len(p.Allow) > 0, "drop"},
+		}
+		for _, set := range sets {
+			if !set.want {
+				continue
+			}
+			if err := f.createIPSet(ctx, set.name, set.family, set.entries); err != nil {
+				return err
+			}
+			source := fmt.Sprintf(`source ipset="%s"`, set.name)
+			if set.negate {
+				source = fmt.Sprintf(`source NOT ipset="%s"`, set.name)
+			}
+			rule := fmt.Sprintf(`rule family="%s" %s port port="%d" protocol="%s" %s`, set.family, source, p.Port, p.Protocol, set.action)
+			if set.action == "accept" {
+				rule = `rule priority="-100"` + strings.TrimPrefix(rule, "rule")
+			}
+			if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--add-rich-rule="+rule); err != nil {
+				return fmt.Errorf("添加富規則失敗: %w", err)
+			}
+		}
 	}
 
-	f.openedPorts = make(map[int]bool)
-	f.log.Info("✅ firewalld 已重載")
+	if err := f.commit(ctx); err != nil {
+		return err
+	}
+	return unsupported
+}
+
+// clearPolicies 刪除引用 Prism ipset 的富規則及 ipset 本身
+func (f *FirewalldManager) clearPolicies(ctx context.Context) error {
+	output, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--list-rich-rules")
+	if err != nil {
+		return fmt.Errorf("獲取富規則失敗: %w", err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		rule := strings.TrimSpace(line)
+		if !strings.Contains(rule, `ipset="`+firewalldIPSetPrefix) {
+			continue
+		}
+		if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--remove-rich-rule="+rule); err != nil {
+			return fmt.Errorf("刪除舊策略失敗: %w", err)
+		}
+	}
+
+	ipsets, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--get-ipsets")
+	if err != nil {
+		return fmt.Errorf("獲取 ipset 列表失敗: %w", err)
+	}
+	for _, name := range strings.Fields(ipsets) {
+		if !strings.HasPrefix(name, firewalldIPSetPrefix) {
+			continue
+		}
+		if _, err := f.run.Run(ctx, "firewall-cmd", "--permanent", "--delete-ipset="+name); err != nil {
+			return fmt.Errorf("刪除舊 ipset 失敗: %w", err)
+		}
+	}
+	return nil
+}
+
+func (f *FirewalldManager) createIPSet(ctx context.Context, name, family string, entries []string) error {
+	args := []string{"--permanent", "--new-ipset=" + name, "--type=hash:net"}
+	if family == "ipv6" {
+		args = append(args, "--option=family=inet6")
+	}
+	if _, err := f.run.Run(ctx, "firewall-cmd", args...); err != nil {
+		return fmt.Errorf("創建 ipset 失敗: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	// 國家網段可能有數千條，使用文件批量導入
+	err := runWithFile(ctx, f.run, strings.Join(entries, "\n")+"\n", func(path string) []string {
+		return []string{"firewall-cmd", "--permanent", "--ipset=" + name, "--add-entries-from-file=" + path}
+	})
+	if err != nil {
+		return fmt.Errorf("寫入 ipset 失敗: %w", err)
+	}
 	return nil
 }
 
+func containsField(s, want string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == want {
+			return true
+		}
+	}
+	return false
+}
+
 // Backend 實現
 type firewalldBackend struct{}
 
@@ -0,0 +1,68 @@
+package firewall
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFirewalldReconcile(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["firewall-cmd --permanent --get-services"] = "dhcpv6-client prism ssh\n"
+	runner.outputs["firewall-cmd --permanent --service=prism --get-ports"] = "443/tcp 8443/udp 20000-30000/udp\n"
+	f := newFirewalld(zap.NewNop(), runner)
+
+	plan, err := f.Reconcile(context.Background(), []Rule{PortRule(443, "tcp"), RangeRule(20000, 30000, "udp")})
+	require.NoError(t, err)
+	assert.Equal(t, "-udp/8443", plan.String())
+	assert.Equal(t, []string{
+		"firewall-cmd --permanent --service=prism --remove-port=8443/udp",
+		"firewall-cmd --reload",
+	}, runner.mutations("firewall-cmd --permanent --get-services", "firewall-cmd --permanent --service=prism --get-ports", "firewall-cmd --permanent --list-rich-rules"))
+}
+
+func TestFirewalldNoChangesSkipsReload(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["firewall-cmd --permanent --get-services"] = "prism ssh"
+	runner.outputs["firewall-cmd --permanent --service=prism --get-ports"] = "443/tcp"
+	f := newFirewalld(zap.NewNop(), runner)
+
+	plan, err := f.Reconcile(context.Background(), []Rule{PortRule(443, "tcp")})
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+	assert.NotContains(t, runner.calls, "firewall-cmd --reload")
+}
+
+func TestFirewalldPortHoppingRichRules(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["firewall-cmd --permanent --get-services"] = "prism ssh"
+	runner.outputs["firewall-cmd --permanent --service=prism --get-ports"] = "8443/udp 20000-30000/udp"
+	runner.outputs["firewall-cmd --permanent --list-rich-rules"] = `rule family="ipv4" forward-port port="20000-30000" protocol="udp" to-port="8443"
+rule family="ipv4" forward-port port="5000" protocol="tcp" to-port="80" to-addr="10.0.0.2"
+rule family="ipv4" source ipset="prism-udp-8443-deny4" port port="8443" protocol="udp" drop`
+	f := newFirewalld(zap.NewNop(), runner)
+	f.ipv6 = func() bool { return true }
+
+	// 已有 IPv4 重定向: 補齊 IPv6 富規則，不觸碰非 Prism 的轉發
+	desired := append([]Rule{PortRule(8443, "udp")}, HoppingRules(8443, 20000, 30000)...)
+	plan, err := f.Reconcile(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Equal(t, "~udp/20000-30000->8443", plan.String())
+	assert.Equal(t, []string{
+		`firewall-cmd --permanent --add-rich-rule=rule family="ipv6" forward-port port="20000-30000" protocol="udp" to-port="8443"`,
+		"firewall-cmd --reload",
+	}, runner.mutations("firewall-cmd --permanent --get-services", "firewall-cmd --permanent --service=prism --get-ports", "firewall-cmd --permanent --list-rich-rules"))
+
+	// 重定向到新端口: 新增雙棧規則並刪除舊規則
+	runner.calls = nil
+	desired = append([]Rule{PortRule(9443, "udp")}, HoppingRules(9443, 20000, 30000)...)
+	_, err = f.Reconcile(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Contains(t, runner.calls, `firewall-cmd --permanent --add-rich-rule=rule family="ipv4" forward-port port="20000-30000" protocol="udp" to-port="9443"`)
+	assert.Contains(t, runner.calls, `firewall-cmd --permanent --add-rich-rule=rule family="ipv6" forward-port port="20000-30000" protocol="udp" to-port="9443"`)
+	assert.Contains(t, runner.calls, `firewall-cmd --permanent --remove-rich-rule=rule family="ipv4" forward-port port="20000-30000" protocol="udp" to-port="8443"`)
+	assert.NotContains(t, runner.calls, `firewall-cmd --permanent --remove-rich-rule=rule family="ipv4" forward-port port="5000" protocol="tcp" to-port="80" to-addr="10.0.0.2"`)
+}
@@ -0,0 +1,388 @@
+package firewall
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 默認 sshd 配置文件
+const sshdConfigPath = "/etc/ssh/sshd_config"
+
+const (
+	// RollbackUnit 進程外回滾使用的 systemd 臨時單元名
+	RollbackUnit = "prism-firewall-rollback"
+	// 進程外回滾比進程內計時晚觸發，本進程存活時由本進程回滾並取消外部計時器
+	rollbackGrace = 10 * time.Second
+)
+
+// SSHAccess 當前的 SSH 訪問信息
+type SSHAccess struct {
+	Ports    []int  // sshd 監聽端口
+	ClientIP string // 當前會話的客戶端 IP，非 SSH 會話時為空
+}
+
+// Guard 防 SSH 鎖死的防火牆保護層
+// 任何規則同步都會保留 SSH 端口，源地址策略不作用於 SSH 端口且豁免當前會話的客戶端 IP
+type Guard struct {
+	Manager
+	log *zap.Logger
+	run Runner
+
+	sshdConfig string
+	getenv     func(string) string
+
+	mu       sync.Mutex
+	policies []Policy // 最近一次應用的源地址策略
+	known    bool     // 本進程內是否應用過策略
+	pending  *Pending
+	external *ExternalRollback
+}
+
+// ExternalRollback 進程外回滾：規則快照寫入文件，由 systemd 臨時計時器在限時後執行回滾命令
+// 確認前本進程退出 (如 SSH 斷開收到 SIGHUP) 時仍能恢復規則
+type ExternalRollback struct {
+	SnapshotPath string   // 規則快照文件
+	Command      []string // 回滾命令 (使用絕對路徑)，應調用 RestoreSnapshot
+}
+
+// SetExternalRollback 啓用進程外回滾，ApplyWithConfirm 將同時安排外部計時器
+func (g *Guard) SetExternalRollback(r ExternalRollback) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.external = &r
+}
+
+// NewGuard 包裝防火牆管理器
+func NewGuard(inner Manager, log *zap.Logger) *Guard {
+	return newGuard(inner, log, execRunner{})
+}
+
+func newGuard(inner Manager, log *zap.Logger, runner Runner) *Guard {
+	return &Guard{
+		Manager:    inner,
+		log:        log,
+		run:        runner,
+		sshdConfig: sshdConfigPath,
+		getenv:     os.Getenv,
+	}
+}
+
+// Access 檢測 SSH 端口與當前會話的客戶端 IP
+// 端口優先從 sshd -T 讀取，失敗時解析 sshd_config，均無結果時使用 22
+func (g *Guard) Access(ctx context.Context) SSHAccess {
+	var access SSHAccess
+	ports := make(map[int]bool)
+
+	if output, err := g.run.Run(ctx, "sshd", "-T"); err == nil {
+		for _, p := range parseSSHDPorts(output) {
+			ports[p] = true
+		}
+	} else if data, err := os.ReadFile(g.sshdConfig); err == nil {
+		for _, p := range parseSSHDPorts(string(data)) {
+			ports[p] = true
+		}
+	}
+
+	// SSH_CONNECTION: <客戶端 IP> <客戶端端口> <服務端 IP> <服務端端口>
+	if fields := strings.Fields(g.getenv("SSH_CONNECTION")); len(fields) == 4 {
+		access.ClientIP = fields[0]
+		if p, err := strconv.Atoi(fields[3]); err == nil {
+			ports[p] = true
+		}
+	} else if fields := strings.Fields(g.getenv("SSH_CLIENT")); len(fields) == 3 {
+		access.ClientIP = fields[0]
+	} else if output, err := g.run.Run(ctx, "who", "-m"); err == nil {
+		// sudo 會清除 SSH 環境變量，從登錄記錄中讀取: "root pts/0 2024-01-01 10:00 (203.0.113.7)"
+		if start, end := strings.LastIndex(output, "("), strings.LastIndex(output, ")"); start >= 0 && end > start {
+			access.ClientIP = output[start+1 : end]
+		}
+	}
+	if net.ParseIP(access.ClientIP) == nil {
+		access.ClientIP = ""
+	}
+
+	if len(ports) == 0 {
+		ports[22] = true
+	}
+	for p := range ports {
+		access.Ports = append(access.Ports, p)
+	}
+	sort.Ints(access.Ports)
+	return access
+}
+
+// parseSSHDPorts 解析 sshd -T 輸出或 sshd_config 中的 Port 指令
+func parseSSHDPorts(text string) []int {
+	var ports []int
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "port") {
+			continue
+		}
+		if p, err := strconv.Atoi(fields[1]); err == nil && p > 0 && p <= 65535 {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// Reconcile 同步規則，SSH 端口始終保留
+func (g *Guard) Reconcile(ctx context.Context, desired []Rule) (Plan, error) {
+	access := g.Access(ctx)
+	rules := append([]Rule{}, desired...)
+	for _, p := range access.Ports {
+		rules = append(rules, PortRule(p, "tcp"))
+	}
+	return g.Manager.Reconcile(ctx, rules)
+}
+
+// FlushRules 刪除 Prism 規則，但保留 SSH 端口
+func (g *Guard) FlushRules(ctx context.Context) error {
+	_, err := g.Reconcile(ctx, nil)
+	return err
+}
+
+// ApplyPolicies 應用源地址策略
+// 作用於 SSH 端口的策略會被跳過，當前會話的客戶端 IP 豁免所有策略
+func (g *Guard) ApplyPolicies(ctx context.Context, policies []Policy) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.applyPolicies(ctx, policies)
+}
+
+func (g *Guard) applyPolicies(ctx context.Context, policies []Policy) error {
+	access := g.Access(ctx)
+	ssh := make(map[int]bool)
+	for _, p := range access.Ports {
+		ssh[p] = true
+	}
+
+	var guarded []Policy
+	for _, p := range policies {
+		if p.Protocol == "tcp" && ssh[p.Port] {
+			g.log.Warn("跳過作用於 SSH 端口的源地址策略", zap.String("port", p.String()))
+			continue
+		}
+		if access.ClientIP != "" {
+			p.Exempt = append(append([]string{}, p.Exempt...), hostCIDR(access.ClientIP))
+		}
+		guarded = append(guarded, p)
+	}
+
+	err := g.Manager.ApplyPolicies(ctx, guarded)
+	g.policies = policies
+	g.known = true
+	return err
+}
+
+// hostCIDR 將單個 IP 轉為 /32 或 /128
+func hostCIDR(ip string) string {
+	if strings.Contains(ip, ":") {
+		return ip + "/128"
+	}
+	return ip + "/32"
+}
+
+// ========================================
+// 限時確認
+// ========================================
+
+// Pending 等待確認的防火牆變更
+type Pending struct {
+	deadline time.Time
+	confirm  chan struct{}
+	done     chan struct{}
+	once     sync.Once
+	reverted bool
+	err      error
+}
+
+// Deadline 自動回滾的時間
+func (p *Pending) Deadline() time.Time {
+	return p.deadline
+}
+
+// Confirm 確認變更，返回是否在回滾前確認成功
+func (p *Pending) Confirm() bool {
+	p.once.Do(func() { close(p.confirm) })
+	<-p.done
+	return !p.reverted
+}
+
+// Done 變更被確認或回滾後關閉
+func (p *Pending) Done() <-chan struct{} {
+	return p.done
+}
+
+// Reverted 是否已回滾，err 為回滾過程中的錯誤
+func (p *Pending) Reverted() (bool, error) {
+	<-p.done
+	return p.reverted, p.err
+}
+
+// ApplyWithConfirm 執行 apply 並在 timeout 內等待確認，超時未確認則恢復變更前的規則與策略
+// 回滾在本進程中計時；若本進程之前未應用過策略，回滾時清除策略 (放寬限制不會造成鎖死)
+// 啓用進程外回滾時另行安排 systemd 計時器，安排失敗僅記錄警告並退回進程內計時
+func (g *Guard) ApplyWithConfirm(ctx context.Context, timeout time.Duration, apply func(ctx context.Context) error) (*Pending, error) {
+	g.mu.Lock()
+	if g.pending != nil {
+		g.mu.Unlock()
+		return nil, fmt.Errorf("已有等待確認的防火牆變更")
+	}
+	g.mu.Unlock()
+
+	snapshot, err := g.CurrentRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("讀取當前規則失敗: %w", err)
+	}
+	g.mu.Lock()
+	prevPolicies := append([]Policy(nil), g.policies...)
+	g.mu.Unlock()
+
+	if err := g.scheduleRollback(ctx, snapshot, timeout+rollbackGrace); err != nil {
+		g.log.Warn("安排進程外回滾失敗，僅在本進程內計時", zap.Error(err))
+	}
+
+	if err := apply(ctx); err != nil {
+		g.cancelRollback()
+		g.revert(snapshot, prevPolicies)
+		return nil, err
+	}
+
+	p := &Pending{
+		deadline: time.Now().Add(timeout),
+		confirm:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	g.mu.Lock()
+	g.pending = p
+	g.mu.Unlock()
+
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-p.confirm:
+			g.log.Info("防火牆變更已確認")
+			g.cancelRollback()
+		case <-timer.C:
+			g.log.Warn("防火牆變更未在限時內確認，正在回滾", zap.Duration("timeout", timeout))
+			g.cancelRollback()
+			p.reverted = true
+			p.err = g.revert(snapshot, prevPolicies)
+		}
+
+		g.mu.Lock()
+		g.pending = nil
+		g.mu.Unlock()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// scheduleRollback 寫入規則快照並通過 systemd-run 安排進程外回滾
+func (g *Guard) scheduleRollback(ctx context.Context, snapshot []Rule, delay time.Duration) error {
+	g.mu.Lock()
+	external := g.external
+	g.mu.Unlock()
+	if external == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("序列化規則快照失敗: %w", err)
+	}
+	if err := os.WriteFile(external.SnapshotPath, data, 0600); err != nil {
+		return fmt.Errorf("寫入規則快照失敗: %w", err)
+	}
+
+	// 清理上次遺留的同名單元，避免 systemd-run 因單元已存在而失敗
+	g.run.Run(ctx, "systemctl", "stop", RollbackUnit+".timer")
+	g.run.Run(ctx, "systemctl", "reset-failed", RollbackUnit+".service")
+
+	args := []string{
+		"--unit=" + RollbackUnit,
+		"--on-active=" + strconv.Itoa(int(delay.Seconds())),
+		"--timer-property=AccuracySec=1s",
+		"--collect",
+		"--quiet",
+	}
+	if _, err := g.run.Run(ctx, "systemd-run", append(args, external.Command...)...); err != nil {
+		os.Remove(external.SnapshotPath)
+		return err
+	}
+	return nil
+}
+
+// cancelRollback 取消進程外回滾並刪除規則快照
+func (g *Guard) cancelRollback() {
+	g.mu.Lock()
+	external := g.external
+	g.mu.Unlock()
+	if external == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := g.run.Run(ctx, "systemctl", "stop", RollbackUnit+".timer"); err != nil {
+		g.log.Warn("取消進程外回滾失敗", zap.Error(err))
+	}
+	os.Remove(external.SnapshotPath)
+}
+
+// RestoreSnapshot 從快照文件恢復規則並清除源地址策略，供進程外回滾命令調用
+func (g *Guard) RestoreSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("讀取規則快照失敗: %w", err)
+	}
+	var snapshot []Rule
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("解析規則快照失敗: %w", err)
+	}
+
+	if err := g.revert(snapshot, nil); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// revert 恢復規則快照與策略並保存
+func (g *Guard) revert(snapshot []Rule, policies []Policy) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var failures []string
+	if _, err := g.Reconcile(ctx, snapshot); err != nil {
+		failures = append(failures, err.Error())
+	}
+	g.mu.Lock()
+	err := g.applyPolicies(ctx, policies)
+	g.mu.Unlock()
+	if err != nil && !IsUnsupported(err) {
+		failures = append(failures, err.Error())
+	}
+	if err := g.SaveRules(ctx); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("回滾防火牆變更失敗: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package firewall
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// recordingManager 記錄規則與策略的內存實現
+type recordingManager struct {
+	NoOpManager
+	rules    []Rule
+	policies []Policy
+	saves    int
+}
+
+func (m *recordingManager) CurrentRules(ctx context.Context) ([]Rule, error) {
+	return m.rules, nil
+}
+
+func (m *recordingManager) Reconcile(ctx context.Context, desired []Rule) (Plan, error) {
+	plan := Diff(m.rules, desired)
+	m.rules = Normalize(desired)
+	return plan, nil
+}
+
+func (m *recordingManager) ApplyPolicies(ctx context.Context, policies []Policy) error {
+	m.policies = policies
+	return nil
+}
+
+func (m *recordingManager) SaveRules(ctx context.Context) error {
+	m.saves++
+	return nil
+}
+
+func newTestGuard(inner Manager, env map[string]string) (*Guard, *fakeRunner) {
+	runner := newFakeRunner()
+	runner.outputs["sshd -T"] = "port 2222\naddressfamily any\nport 22\n"
+	g := newGuard(inner, zap.NewNop(), runner)
+	g.getenv = func(k string) string { return env[k] }
+	return g, runner
+}
+
+func TestGuardAccess(t *testing.T) {
+	g, runner := newTestGuard(&recordingManager{}, map[string]string{
+		"SSH_CONNECTION": "203.0.113.7 51234 192.0.2.1 2200",
+	})
+	access := g.Access(context.Background())
+	assert.Equal(t, []int{22, 2200, 2222}, access.Ports)
+	assert.Equal(t, "203.0.113.7", access.ClientIP)
+
+	// sshd -T 失敗時讀取配置文件，sudo 下從 who -m 獲取客戶端
+	runner.errs["sshd -T"] = assert.AnError
+	runner.outputs["who -m"] = "root     pts/0        2024-01-01 10:00 (2001:db8::7)\n"
+	g.sshdConfig = t.TempDir() + "/missing"
+	g.getenv = func(string) string { return "" }
+	access = g.Access(context.Background())
+	assert.Equal(t, []int{22}, access.Ports)
+	assert.Equal(t, "2001:db8::7", access.ClientIP)
+}
+
+func TestParseSSHDPorts(t *testing.T) {
+	config := "# Port 2020\nPort 2022\n  port   2023\nPasswordAuthentication no\nPort abc\n"
+	assert.Equal(t, []int{2022, 2023}, parseSSHDPorts(config))
+}
+
+func TestGuardPreservesSSH(t *testing.T) {
+	inner := &recordingManager{}
+	g, _ := newTestGuard(inner, map[string]string{"SSH_CLIENT": "203.0.113.7 51234 22"})
+	ctx := context.Background()
+
+	_, err := g.Reconcile(ctx, []Rule{PortRule(443, "tcp")})
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{PortRule(22, "tcp"), PortRule(443, "tcp"), PortRule(2222, "tcp")}, inner.rules)
+
+	require.NoError(t, g.FlushRules(ctx))
+	assert.Equal(t, []Rule{PortRule(22, "tcp"), PortRule(2222, "tcp")}, inner.rules)
+
+	require.NoError(t, g.ApplyPolicies(ctx, []Policy{
+		{Protocol: "tcp", Port: 22, Allow: []string{"198.51.100.0/24"}},
+		{Protocol: "udp", Port: 22, Deny: []string{"198.51.100.0/24"}},
+	}))
+	require.Len(t, inner.policies, 1)
+	assert.Equal(t, "udp", inner.policies[0].Protocol)
+	assert.Equal(t, []string{"203.0.113.7/32"}, inner.policies[0].Exempt)
+}
+
+func TestApplyWithConfirm(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("超時回滾", func(t *testing.T) {
+		inner := &recordingManager{rules: []Rule{PortRule(22, "tcp"), PortRule(443, "tcp")}}
+		g, _ := newTestGuard(inner, nil)
+
+		pending, err := g.ApplyWithConfirm(ctx, 20*time.Millisecond, func(ctx context.Context) error {
+			if _, err := g.Reconcile(ctx, []Rule{PortRule(8443, "udp")}); err != nil {
+				return err
+			}
+			return g.ApplyPolicies(ctx, []Policy{{Protocol: "udp", Port: 8443, Deny: []string{"198.51.100.0/24"}}})
+		})
+		require.NoError(t, err)
+
+		reverted, err := pending.Reverted()
+		require.NoError(t, err)
+		assert.True(t, reverted)
+		assert.False(t, pending.Confirm())
+		assert.Equal(t, []Rule{PortRule(22, "tcp"), PortRule(443, "tcp"), PortRule(2222, "tcp")}, inner.rules)
+		assert.Empty(t, inner.policies)
+		assert.Equal(t, 1, inner.saves)
+	})
+
+	t.Run("確認後保留", func(t *testing.T) {
+		inner := &recordingManager{rules: []Rule{PortRule(443, "tcp")}}
+		g, _ := newTestGuard(inner, nil)
+
+		pending, err := g.ApplyWithConfirm(ctx, time.Minute, func(ctx context.Context) error {
+			_, err := g.Reconcile(ctx, []Rule{PortRule(8443, "udp")})
+			return err
+		})
+		require.NoError(t, err)
+
+		// 等待期間不允許再次發起
+		_, err = g.ApplyWithConfirm(ctx, time.Minute, func(context.Context) error { return nil })
+		assert.Error(t, err)
+
+		assert.True(t, pending.Confirm())
+		reverted, _ := pending.Reverted()
+		assert.False(t, reverted)
+		assert.Contains(t, inner.rules, PortRule(8443, "udp"))
+		assert.Equal(t, 0, inner.saves)
+	})
+}
+
+func TestExternalRollback(t *testing.T) {
+	ctx := context.Background()
+	snapshotPath := t.TempDir() + "/rollback.json"
+
+	inner := &recordingManager{rules: []Rule{PortRule(443, "tcp")}}
+	g, runner := newTestGuard(inner, nil)
+	g.SetExternalRollback(ExternalRollback{SnapshotPath: snapshotPath, Command: []string{"/usr/bin/prism", "firewall", "rollback"}})
+
+	pending, err := g.ApplyWithConfirm(ctx, time.Minute, func(ctx context.Context) error {
+		_, err := g.Reconcile(ctx, []Rule{PortRule(8443, "udp")})
+		return err
+	})
+	require.NoError(t, err)
+	assert.Contains(t, runner.calls, "systemd-run --unit="+RollbackUnit+" --on-active=70 --timer-property=AccuracySec=1s --collect --quiet /usr/bin/prism firewall rollback")
+	assert.FileExists(t, snapshotPath)
+
+	// 本進程退出後由外部命令按快照恢復
+	restored := &recordingManager{rules: inner.rules}
+	other, _ := newTestGuard(restored, nil)
+	require.NoError(t, other.RestoreSnapshot(snapshotPath))
+	assert.Equal(t, []Rule{PortRule(22, "tcp"), PortRule(443, "tcp"), PortRule(2222, "tcp")}, restored.rules)
+	assert.NoFileExists(t, snapshotPath)
+
+	// 確認時取消外部計時器
+	assert.True(t, pending.Confirm())
+	assert.Contains(t, runner.calls[len(runner.calls)-1], "systemctl stop "+RollbackUnit+".timer")
+}
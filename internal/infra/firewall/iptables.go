@@ -2,29 +2,29 @@ package firewall
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"os/exec"
-	"sort"
 	"strconv"
 	"strings"
-	"sync"
 
 	"go.uber.org/zap"
 )
 
 type IPTablesManager struct {
-	log         *zap.Logger
-	openedPorts map[int]bool
-	mu          sync.Mutex
+	*ruleManager
 }
 
 func NewIPTables(log *zap.Logger) *IPTablesManager {
-	return &IPTablesManager{
-		log:         log,
-		openedPorts: make(map[int]bool),
-	}
+	i := newIPTables(log, execRunner{})
+	i.ipv6 = hostIPv6Enabled
+	return i
+}
+
+func newIPTables(log *zap.Logger, runner Runner) *IPTablesManager {
+	i := &IPTablesManager{}
+	i.ruleManager = &ruleManager{log: log, run: runner, ops: i}
+	return i
 }
 
 func (i *IPTablesManager) Type() string {
@@ -37,255 +37,279 @@ func (i *IPTablesManager) Capabilities() Capabilities {
 		SupportPortHopping: true, // 通過 PREROUTING REDIRECT
 		SupportComment:     true,
 		SupportBoth:        false, // 需要分別調用 tcp/udp
+
+		SupportSourceFilter: true,
+		SupportRateLimit:    true,  // hashlimit
+		SupportGeoIP:        false, // 逐條規則匹配，大量網段會嚴重影響性能
 	}
 }
 
-func (i *IPTablesManager) OpenPort(ctx context.Context, port int, protocol string) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
-
-	if protocol == "both" {
-		if err := i.openSinglePort(ctx, port, "tcp"); err != nil {
-			return err
+// 源地址策略鏈，由 INPUT 首條規則跳轉
+const iptablesPolicyChain = "PRISM-POLICY"
+
+// list 從 iptables-save / ip6tables-save 中讀取帶 prism 備註的規則
+// 同一規則的 IPv4 與 IPv6 版本會在上層合併，刪除時一併處理
+func (i *IPTablesManager) list(ctx context.Context) ([]installedRule, error) {
+	var rules []installedRule
+	for _, binary := range []string{"iptables", "ip6tables"} {
+		output, err := i.run.Run(ctx, binary+"-save")
+		if err != nil {
+			if binary == "iptables" {
+				return nil, fmt.Errorf("執行 %s-save 失敗: %w", binary, err)
+			}
+			// IPv6 不可用時不影響核心功能
+			i.log.Debug("讀取 IPv6 規則失敗（可能未啟用 IPv6）", zap.Error(err))
+			continue
+		}
+		family := familyIPv4
+		if binary == "ip6tables" {
+			family = familyIPv6
+		}
+		for _, ir := range parseIPTablesSave(binary, output) {
+			ir.families = []string{family}
+			rules = append(rules, ir)
 		}
-		return i.openSinglePort(ctx, port, "udp")
 	}
-	return i.openSinglePort(ctx, port, protocol)
+	return rules, nil
 }
 
-func (i *IPTablesManager) openSinglePort(ctx context.Context, port int, protocol string) error {
-	portStr := strconv.Itoa(port)
-	comment := fmt.Sprintf("prism-%s-%d", protocol, port)
+// iptablesBinaries 各地址族對應的命令
+var iptablesBinaries = map[string]string{familyIPv4: "iptables", familyIPv6: "ip6tables"}
 
-	// 檢查規則是否已存在（IPv4）
-	// 注意：這裡使用 -C (Check) 可以避免重複添加
-	checkCmd := exec.CommandContext(ctx, "iptables", "-C", "INPUT", "-p", protocol,
-		"--dport", portStr, "-j", "ACCEPT", "-m", "comment", "--comment", comment)
-	if checkCmd.Run() == nil {
-		i.openedPorts[port] = true
-		return nil
-	}
+// parseIPTablesSave 解析 *-save 輸出
+// 示例: -A INPUT -p tcp -m tcp --dport 443 -m comment --comment prism-tcp-443 -j ACCEPT
+func parseIPTablesSave(binary, output string) []installedRule {
+	var rules []installedRule
+	var table string
 
-	// IPv4
-	cmd := exec.CommandContext(ctx, "iptables", "-I", "INPUT", "-p", protocol,
-		"--dport", portStr, "-j", "ACCEPT", "-m", "comment", "--comment", comment)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("IPv4 失敗: %s", string(output))
-	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
 
-	// IPv6
-	cmd = exec.CommandContext(ctx, "ip6tables", "-I", "INPUT", "-p", protocol,
-		"--dport", portStr, "-j", "ACCEPT", "-m", "comment", "--comment", comment)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		i.log.Debug("IPv6 規則添加跳過（可能不支持）",
-			zap.Error(err),
-			zap.String("output", string(output)))
-	}
+		// 識別表名 (例如 *filter, *nat)
+		if strings.HasPrefix(line, "*") {
+			table = line[1:]
+			continue
+		}
+		if !strings.HasPrefix(line, "-A ") {
+			continue
+		}
 
-	i.openedPorts[port] = true
-	return nil
-}
+		args := strings.Fields(line)
+		var chain, protocol, ports, comment, target, toPorts string
+		for k := 0; k+1 < len(args); k++ {
+			switch args[k] {
+			case "-A":
+				chain = args[k+1]
+			case "-p":
+				protocol = args[k+1]
+			case "--dport", "--dports":
+				ports = args[k+1]
+			case "--comment":
+				comment = strings.Trim(args[k+1], `"`)
+			case "-j":
+				target = args[k+1]
+			case "--to-ports":
+				toPorts = args[k+1]
+			}
+		}
+		if !strings.HasPrefix(comment, "prism") || ports == "" || strings.Contains(ports, ",") {
+			continue
+		}
 
-func (i *IPTablesManager) OpenPortRange(ctx context.Context, startPort, endPort int, protocol string) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
+		r := Rule{Protocol: protocol}
+		startStr, endStr, isRange := strings.Cut(ports, ":")
+		r.Start, _ = strconv.Atoi(startStr)
+		r.End = r.Start
+		if isRange {
+			r.End, _ = strconv.Atoi(endStr)
+		}
 
-	portRange := fmt.Sprintf("%d:%d", startPort, endPort)
-	comment := fmt.Sprintf("prism-range-%s-%d-%d", protocol, startPort, endPort)
+		switch {
+		case table == "filter" && chain == "INPUT" && target == "ACCEPT":
+		case table == "nat" && chain == "PREROUTING" && target == "REDIRECT":
+			r.RedirectTo, _ = strconv.Atoi(toPorts)
+			if r.RedirectTo == 0 {
+				continue
+			}
+		default:
+			continue
+		}
 
-	// IPv4
-	cmd := exec.CommandContext(ctx, "iptables", "-I", "INPUT", "-p", protocol,
-		"-m", "multiport", "--dports", portRange, "-j", "ACCEPT", "-m", "comment", "--comment", comment)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("IPv4 失敗: %s", string(output))
+		// 將 -A (Append) 替換為 -D (Delete): <binary> -t <table> -D <chain> ...
+		args[0] = "-D"
+		del := append([]string{binary, "-t", table}, args...)
+		rules = append(rules, installedRule{Rule: r, deletes: [][]string{del}})
 	}
+	return rules
+}
 
-	// IPv6
-	cmd = exec.CommandContext(ctx, "ip6tables", "-I", "INPUT", "-p", protocol,
-		"-m", "multiport", "--dports", portRange, "-j", "ACCEPT", "-m", "comment", "--comment", comment)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		i.log.Debug("IPv6 規則添加跳過",
-			zap.Error(err),
-			zap.String("output", string(output)))
+// iptablesMatchArgs 構造協議與端口匹配參數
+func iptablesMatchArgs(r Rule) []string {
+	if r.IsRange() {
+		return []string{"-p", r.Protocol, "-m", "multiport", "--dports", fmt.Sprintf("%d:%d", r.Start, r.End)}
 	}
-
-	return nil
+	return []string{"-p", r.Protocol, "--dport", strconv.Itoa(r.Start)}
 }
 
-func (i *IPTablesManager) OpenHysteria2PortHopping(ctx context.Context, listenPort, startPort, endPort int) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
-
-	portRange := fmt.Sprintf("%d:%d", startPort, endPort)
-	comment := fmt.Sprintf("prism-hy2-hop-%d-%d", startPort, endPort)
-	listenPortStr := strconv.Itoa(listenPort)
-
-	i.log.Info("配置 Hysteria2 端口跳躍（iptables）",
-		zap.Int("listen", listenPort),
-		zap.String("range", portRange))
-
-	// 1. Accept 規則（IPv4）
-	cmd := exec.CommandContext(ctx, "iptables", "-I", "INPUT", "-p", "udp",
-		"-m", "multiport", "--dports", portRange, "-j", "ACCEPT", "-m", "comment", "--comment", comment)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("IPv4 accept 失敗: %s", string(output))
+func (i *IPTablesManager) add(ctx context.Context, r Rule) error {
+	if err := i.addFamily(ctx, r, familyIPv4); err != nil {
+		return err
 	}
-
-	// 2. REDIRECT 規則（IPv4）
-	cmd = exec.CommandContext(ctx, "iptables", "-t", "nat", "-I", "PREROUTING",
-		"-p", "udp", "-m", "multiport", "--dports", portRange,
-		"-j", "REDIRECT", "--to-ports", listenPortStr,
-		"-m", "comment", "--comment", comment+"-dnat")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("IPv4 REDIRECT 失敗: %s", string(output))
+	// 主機啟用 IPv6 時 ip6tables 失敗視為錯誤，否則 IPv6 客戶端會繞過規則
+	if err := i.addFamily(ctx, r, familyIPv6); err != nil {
+		if i.ipv6Enabled() {
+			return err
+		}
+		i.log.Debug("IPv6 規則添加跳過（主機未啟用 IPv6）", zap.Error(err))
 	}
+	return nil
+}
 
-	// 3. IPv6
-	cmd = exec.CommandContext(ctx, "ip6tables", "-I", "INPUT", "-p", "udp",
-		"-m", "multiport", "--dports", portRange, "-j", "ACCEPT", "-m", "comment", "--comment", comment)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		i.log.Debug("IPv6 Hopping Accept 跳過", zap.Error(err), zap.String("output", string(output)))
+// addFamily 僅在指定地址族中插入規則
+func (i *IPTablesManager) addFamily(ctx context.Context, r Rule, family string) error {
+	var table, chain, comment string
+	var target []string
+
+	switch {
+	case r.IsRedirect():
+		table, chain = "nat", "PREROUTING"
+		comment = fmt.Sprintf("prism-hy2-hop-%d-%d-dnat", r.Start, r.End)
+		target = []string{"-j", "REDIRECT", "--to-ports", strconv.Itoa(r.RedirectTo)}
+	case r.IsRange():
+		table, chain = "filter", "INPUT"
+		comment = fmt.Sprintf("prism-range-%s-%d-%d", r.Protocol, r.Start, r.End)
+		target = []string{"-j", "ACCEPT"}
+	default:
+		table, chain = "filter", "INPUT"
+		comment = fmt.Sprintf("prism-%s-%d", r.Protocol, r.Start)
+		target = []string{"-j", "ACCEPT"}
 	}
 
-	cmd = exec.CommandContext(ctx, "ip6tables", "-t", "nat", "-I", "PREROUTING",
-		"-p", "udp", "-m", "multiport", "--dports", portRange,
-		"-j", "REDIRECT", "--to-ports", listenPortStr,
-		"-m", "comment", "--comment", comment+"-dnat")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		i.log.Debug("IPv6 Hopping REDIRECT 跳過", zap.Error(err), zap.String("output", string(output)))
-	}
+	args := append([]string{"-t", table, "-I", chain}, iptablesMatchArgs(r)...)
+	args = append(args, target...)
+	args = append(args, "-m", "comment", "--comment", comment)
 
+	if _, err := i.run.Run(ctx, iptablesBinaries[family], args...); err != nil {
+		return fmt.Errorf("%s 失敗: %w", family, err)
+	}
 	return nil
 }
 
-func (i *IPTablesManager) GetOpenedPorts() []int {
+// ApplyPolicies 重建策略鏈 (IPv4 與 IPv6 分別處理)
+func (i *IPTablesManager) ApplyPolicies(ctx context.Context, policies []Policy) error {
+	policies, unsupported := supportedPolicies(i.Capabilities(), policies)
+
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	ports := make([]int, 0, len(i.openedPorts))
-	for port := range i.openedPorts {
-		ports = append(ports, port)
+	for _, binary := range []string{"iptables", "ip6tables"} {
+		if err := i.applyPolicyChain(ctx, binary, policies); err != nil {
+			if binary == "iptables" {
+				return fmt.Errorf("應用源地址策略失敗: %w", err)
+			}
+			i.log.Debug("IPv6 源地址策略跳過（可能不支持）", zap.Error(err))
+		}
 	}
-	sort.Ints(ports)
-	return ports
+	return unsupported
 }
 
-func (i *IPTablesManager) SaveRules(ctx context.Context) error {
-	// 優先嘗試 netfilter-persistent (Debian/Ubuntu 標準)
-	if commandExists("netfilter-persistent") {
-		cmd := exec.CommandContext(ctx, "netfilter-persistent", "save")
-		if err := cmd.Run(); err == nil {
-			i.log.Info("規則已保存（netfilter-persistent）")
-			return nil
-		}
-	}
+func (i *IPTablesManager) applyPolicyChain(ctx context.Context, binary string, policies []Policy) error {
+	jump := []string{"INPUT", "-j", iptablesPolicyChain, "-m", "comment", "--comment", policyComment}
 
-	// 通用兜底方法：直接導出到文件
-	// 注意：這裡使用 sh -c 是為了重定向，但比起複雜的管道，這是單一命令，相對安全
-	cmd := exec.CommandContext(ctx, "sh", "-c", "iptables-save > /etc/iptables/rules.v4")
-	if err := cmd.Run(); err != nil {
-		i.log.Warn("保存 iptables 規則失敗", zap.Error(err))
+	if len(policies) == 0 {
+		// 清除策略: 刪除跳轉後刪除鏈，不存在時忽略
+		_, _ = i.run.Run(ctx, binary, append([]string{"-D"}, jump...)...)
+		_, _ = i.run.Run(ctx, binary, "-F", iptablesPolicyChain)
+		_, _ = i.run.Run(ctx, binary, "-X", iptablesPolicyChain)
+		return nil
 	}
 
-	cmd = exec.CommandContext(ctx, "sh", "-c", "ip6tables-save > /etc/iptables/rules.v6")
-	if err := cmd.Run(); err != nil {
-		i.log.Warn("保存 ip6tables 規則失敗", zap.Error(err))
+	// 鏈已存在時 -N 會失敗，直接清空後重建
+	_, _ = i.run.Run(ctx, binary, "-N", iptablesPolicyChain)
+	if _, err := i.run.Run(ctx, binary, "-F", iptablesPolicyChain); err != nil {
+		return err
 	}
-
-	return nil
-}
-
-// FlushRules 清空規則 (穩健版)
-// 使用 iptables-save 獲取所有規則，在內存中過濾，然後生成刪除命令
-func (i *IPTablesManager) FlushRules(ctx context.Context) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
-
-	i.log.Info("開始清空 iptables 規則")
-
-	// 1. 清理 IPv4
-	if err := i.flushBinaryRules(ctx, "iptables"); err != nil {
-		i.log.Error("清理 IPv4 規則時出錯", zap.Error(err))
+	for _, args := range iptablesPolicyRules(binary == "ip6tables", policies) {
+		if _, err := i.run.Run(ctx, binary, append([]string{"-A", iptablesPolicyChain}, args...)...); err != nil {
+			return err
+		}
 	}
-
-	// 2. 清理 IPv6
-	if err := i.flushBinaryRules(ctx, "ip6tables"); err != nil {
-		// IPv6 失敗通常不影響核心功能，降級為 Debug 日誌
-		i.log.Debug("清理 IPv6 規則時出錯（可能未啟用 IPv6）", zap.Error(err))
+	if _, err := i.run.Run(ctx, binary, append([]string{"-C"}, jump...)...); err != nil {
+		if _, err := i.run.Run(ctx, binary, append([]string{"-I", jump[0], "1"}, jump[1:]...)...); err != nil {
+			return err
+		}
 	}
-
-	i.openedPorts = make(map[int]bool)
-	i.log.Info("iptables 規則清空完成")
 	return nil
 }
 
-// flushBinaryRules 內部輔助函數：處理指定二進制（iptables 或 ip6tables）的清理
-func (i *IPTablesManager) flushBinaryRules(ctx context.Context, binary string) error {
-	// 1. 檢查命令是否存在
-	if !commandExists(binary + "-save") {
-		return fmt.Errorf("命令 %s-save 不存在", binary)
-	}
-
-	// 2. 獲取當前所有規則
-	out, err := exec.CommandContext(ctx, binary+"-save").Output()
-	if err != nil {
-		return fmt.Errorf("執行 %s-save 失敗: %w", binary, err)
+// iptablesPolicyRules 生成策略鏈規則 (不含 -A 鏈名)
+// 順序: 豁免 RETURN -> 黑名單 -> 速率限制 -> 白名單 RETURN -> 其餘丟棄
+func iptablesPolicyRules(ipv6 bool, policies []Policy) [][]string {
+	pick := func(cidrs []string) []string {
+		v4, v6 := splitFamilies(cidrs)
+		if ipv6 {
+			return v6
+		}
+		return v4
 	}
-
-	scanner := bufio.NewScanner(bytes.NewReader(out))
-	var currentTable string
-	var deleteCmds [][]string
-
-	// 3. 解析輸出
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// 識別表名 (例如 *filter, *nat)
-		if strings.HasPrefix(line, "*") {
-			currentTable = line[1:] // 去掉 * 號
-			continue
+	comment := []string{"-m", "comment", "--comment", policyComment}
+
+	var rules [][]string
+	for _, p := range policies {
+		match := []string{"-p", p.Protocol, "--dport", strconv.Itoa(p.Port)}
+		rule := func(extra ...string) []string {
+			r := append(append([]string{}, match...), extra...)
+			return append(r, comment...)
 		}
 
-		// 識別規則 (以 -A 開頭) 且 包含 prism 標記
-		if strings.HasPrefix(line, "-A") && strings.Contains(line, "prism") {
-			// 將 -A (Append) 替換為 -D (Delete)
-			// line 示例: -A INPUT -p tcp --dport 80 ...
-			args := strings.Fields(line)
-			if len(args) > 0 {
-				args[0] = "-D" // 修改動作為刪除
-				// 構造完整命令參數: -t <table> -D <chain> ...
-				fullArgs := append([]string{"-t", currentTable}, args...)
-				deleteCmds = append(deleteCmds, fullArgs)
+		for _, cidr := range pick(p.Exempt) {
+			rules = append(rules, rule("-s", cidr, "-j", "RETURN"))
+		}
+		for _, cidr := range pick(p.Deny) {
+			rules = append(rules, rule("-s", cidr, "-j", "DROP"))
+		}
+		if p.RateLimit > 0 {
+			rules = append(rules, rule(
+				"-m", "conntrack", "--ctstate", "NEW",
+				"-m", "hashlimit",
+				"--hashlimit-name", fmt.Sprintf("prism-%d-%s", p.Port, p.Protocol),
+				"--hashlimit-mode", "srcip",
+				"--hashlimit-above", fmt.Sprintf("%d/min", p.RateLimit),
+				"--hashlimit-burst", strconv.Itoa(p.RateBurst),
+				"-j", "DROP"))
+		}
+		if len(p.Allow) > 0 {
+			for _, cidr := range pick(p.Allow) {
+				rules = append(rules, rule("-s", cidr, "-j", "RETURN"))
 			}
+			rules = append(rules, rule("-j", "DROP"))
 		}
 	}
+	return rules
+}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("解析規則失敗: %w", err)
-	}
+func (i *IPTablesManager) commit(ctx context.Context) error {
+	return nil
+}
 
-	// 4. 執行刪除
-	// 為了防止刪除順序問題（雖然刪除特定規則通常不依賴順序），我們按順序執行
-	successCount := 0
-	for _, args := range deleteCmds {
-		// 使用 wait 參數確保每條命令執行完畢
-		cmd := exec.CommandContext(ctx, binary, args...)
-		if output, err := cmd.CombinedOutput(); err != nil {
-			// 刪除失敗可能是規則已不存在，記錄為 Debug
-			i.log.Debug("刪除規則失敗",
-				zap.String("binary", binary),
-				zap.Strings("args", args),
-				zap.String("output", string(output)))
-		} else {
-			successCount++
+func (i *IPTablesManager) SaveRules(ctx context.Context) error {
+	// 優先嘗試 netfilter-persistent (Debian/Ubuntu 標準)
+	if commandExists("netfilter-persistent") {
+		if _, err := i.run.Run(ctx, "netfilter-persistent", "save"); err == nil {
+			i.log.Info("規則已保存（netfilter-persistent）")
+			return nil
 		}
 	}
 
-	if len(deleteCmds) > 0 {
-		i.log.Debug("規則清理統計",
-			zap.String("binary", binary),
-			zap.Int("found", len(deleteCmds)),
-			zap.Int("deleted", successCount))
+	// 通用兜底方法：直接導出到文件
+	// 注意：這裡使用 sh -c 是為了重定向，但比起複雜的管道，這是單一命令，相對安全
+	if _, err := i.run.Run(ctx, "sh", "-c", "iptables-save > /etc/iptables/rules.v4"); err != nil {
+		i.log.Warn("保存 iptables 規則失敗", zap.Error(err))
+	}
+
+	if _, err := i.run.Run(ctx, "sh", "-c", "ip6tables-save > /etc/iptables/rules.v6"); err != nil {
+		i.log.Warn("保存 ip6tables 規則失敗", zap.Error(err))
 	}
 
 	return nil
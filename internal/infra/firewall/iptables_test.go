@@ -0,0 +1,56 @@
+package firewall
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const iptablesSaveOutput = `*nat
+:PREROUTING ACCEPT [0:0]
+-A PREROUTING -p udp -m multiport --dports 20000:30000 -m comment --comment prism-hy2-hop-20000-30000-dnat -j REDIRECT --to-ports 8443
+COMMIT
+*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp -m tcp --dport 443 -m comment --comment prism-tcp-443 -j ACCEPT
+-A INPUT -p udp -m udp --dport 8443 -m comment --comment prism-udp-8443 -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT
+COMMIT
+`
+
+func TestParseIPTablesSave(t *testing.T) {
+	rules := parseIPTablesSave("iptables", iptablesSaveOutput)
+	require.Len(t, rules, 3)
+
+	assert.Equal(t, Rule{Protocol: "udp", Start: 20000, End: 30000, RedirectTo: 8443}, rules[0].Rule)
+	assert.Equal(t, [][]string{{
+		"iptables", "-t", "nat", "-D", "PREROUTING", "-p", "udp", "-m", "multiport", "--dports", "20000:30000",
+		"-m", "comment", "--comment", "prism-hy2-hop-20000-30000-dnat", "-j", "REDIRECT", "--to-ports", "8443",
+	}}, rules[0].deletes)
+	assert.Equal(t, PortRule(443, "tcp"), rules[1].Rule)
+	assert.Equal(t, PortRule(8443, "udp"), rules[2].Rule)
+}
+
+func TestIPTablesReconcileRemovesBothFamilies(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["iptables-save"] = iptablesSaveOutput
+	runner.outputs["ip6tables-save"] = `*filter
+-A INPUT -p udp -m udp --dport 8443 -m comment --comment prism-udp-8443 -j ACCEPT
+COMMIT
+`
+	i := newIPTables(zap.NewNop(), runner)
+
+	desired := append([]Rule{PortRule(443, "tcp")}, HoppingRules(8443, 20000, 30000)...)
+	plan, err := i.Reconcile(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Equal(t, "+udp/20000-30000 -udp/8443", plan.String())
+	assert.Equal(t, []string{
+		"iptables -t filter -I INPUT -p udp -m multiport --dports 20000:30000 -j ACCEPT -m comment --comment prism-range-udp-20000-30000",
+		"ip6tables -t filter -I INPUT -p udp -m multiport --dports 20000:30000 -j ACCEPT -m comment --comment prism-range-udp-20000-30000",
+		"iptables -t filter -D INPUT -p udp -m udp --dport 8443 -m comment --comment prism-udp-8443 -j ACCEPT",
+		"ip6tables -t filter -D INPUT -p udp -m udp --dport 8443 -m comment --comment prism-udp-8443 -j ACCEPT",
+	}, runner.mutations("iptables-save", "ip6tables-save"))
+}
@@ -2,12 +2,16 @@ package firewall
 
 import (
 	"context"
+	stderrors "errors"
 	"os/exec"
 
 	"go.uber.org/zap"
+
+	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
 // Manager 防火牆管理器接口
+// 規則狀態均從系統中實際讀取 (按 Prism 標記識別)，不依賴進程內緩存
 type Manager interface {
 	Type() string
 	GetOpenedPorts() []int
@@ -17,6 +21,25 @@ type Manager interface {
 	SaveRules(ctx context.Context) error
 	FlushRules(ctx context.Context) error
 	Capabilities() Capabilities
+
+	// CurrentRules 讀取系統中帶 Prism 標記的規則
+	CurrentRules(ctx context.Context) ([]Rule, error)
+	// Reconcile 將 Prism 規則同步為期望集合，只增刪差異部分
+	Reconcile(ctx context.Context, desired []Rule) (Plan, error)
+	// ApplyPolicies 以給定集合替換所有源地址策略，傳入空集合即清除
+	// 後端不支持的功能會被忽略並返回 FIREWALL_UNSUPPORTED 錯誤，其餘策略照常應用
+	ApplyPolicies(ctx context.Context, policies []Policy) error
+}
+
+// CodeUnsupported 後端不支持所請求功能的錯誤碼
+const CodeUnsupported = "FIREWALL_UNSUPPORTED"
+
+var errUnsupportedHopping = errors.New(CodeUnsupported, "當前防火牆不支持端口跳躍 (DNAT)，請改用 nftables 或 iptables")
+
+// IsUnsupported 是否為後端不支持功能的錯誤 (其餘部分已正常應用)
+func IsUnsupported(err error) bool {
+	var e *errors.Error
+	return stderrors.As(err, &e) && e.Code == CodeUnsupported
 }
 
 // Capabilities 防火牆能力聲明
@@ -25,10 +48,21 @@ type Capabilities struct {
 	SupportPortHopping bool
 	SupportComment     bool
 	SupportBoth        bool
+
+	// 源地址策略
+	SupportSourceFilter bool // 白名單 / 黑名單
+	SupportRateLimit    bool // 按來源的連接速率限制
+	SupportGeoIP        bool // 國家屏蔽 (大量網段)
 }
 
 // NewManager 工廠函數：自動檢測並返回合適的防火牆實現
+// 返回的管理器經過 Guard 包裝，任何變更都不會關閉 SSH 訪問
 func NewManager(log *zap.Logger) Manager {
+	return NewGuard(Detect(log), log)
+}
+
+// Detect 檢測並返回防火牆後端 (未經 Guard 包裝)
+func Detect(log *zap.Logger) Manager {
 	// 1. 優先檢測 UFW (Debian/Ubuntu 常見)
 	if isCommandAvailable("ufw") && isServiceRunning("ufw") {
 		log.Info("檢測到 UFW，使用 UFW 後端")
@@ -84,3 +118,11 @@ func (m *NoOpManager) OpenHysteria2PortHopping(ctx context.Context, l, s, e int)
 func (m *NoOpManager) SaveRules(ctx context.Context) error                             { return nil }
 func (m *NoOpManager) FlushRules(ctx context.Context) error                            { return nil }
 func (m *NoOpManager) Capabilities() Capabilities                                      { return Capabilities{} }
+func (m *NoOpManager) CurrentRules(ctx context.Context) ([]Rule, error)                { return nil, nil }
+func (m *NoOpManager) Reconcile(ctx context.Context, desired []Rule) (Plan, error) {
+	return Plan{}, nil
+}
+func (m *NoOpManager) ApplyPolicies(ctx context.Context, policies []Policy) error {
+	_, err := supportedPolicies(m.Capabilities(), policies)
+	return err
+}
@@ -1,12 +1,14 @@
 package firewall
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
-	"sort"
-	"sync"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -22,22 +24,34 @@ const (
 	NftIPv4NatTableName = "prism_nat_v4"
 	NftIPv6NatTableName = "prism_nat_v6"
 	NftNatChainName     = "prerouting"
+
+	// 源地址策略表 (獨立表，整表原子替換)
+	// 優先級早於 Filter 表，丟棄的流量不會再被放行
+	NftPolicyTableName = "prism_policy"
+)
+
+// nft -a list 輸出中的 Prism 規則
+var (
+	nftAcceptPattern   = regexp.MustCompile(`^(tcp|udp) dport (\d+)(?:-(\d+))? accept comment "prism[^"]*" # handle (\d+)$`)
+	nftRedirectPattern = regexp.MustCompile(`^(tcp|udp) dport (\d+)(?:-(\d+))? redirect to :(\d+) comment "prism[^"]*" # handle (\d+)$`)
 )
 
 // NFTablesManager 實現 Manager 接口
 type NFTablesManager struct {
-	log         *zap.Logger
-	openedPorts map[int]bool
-	mu          sync.Mutex
+	*ruleManager
 }
 
 // NewNFTables 創建管理器實例
 func NewNFTables(log *zap.Logger) *NFTablesManager {
-	mgr := &NFTablesManager{
-		log:         log,
-		openedPorts: make(map[int]bool),
-	}
-	return mgr
+	n := newNFTables(log, execRunner{})
+	n.ipv6 = hostIPv6Enabled
+	return n
+}
+
+func newNFTables(log *zap.Logger, runner Runner) *NFTablesManager {
+	n := &NFTablesManager{}
+	n.ruleManager = &ruleManager{log: log, run: runner, ops: n}
+	return n
 }
 
 func (n *NFTablesManager) Type() string {
@@ -50,190 +64,271 @@ func (n *NFTablesManager) Capabilities() Capabilities {
 		SupportPortHopping: true,
 		SupportComment:     true,
 		SupportBoth:        false,
+
+		SupportSourceFilter: true,
+		SupportRateLimit:    true, // meter + limit
+		SupportGeoIP:        true, // interval 集合
 	}
 }
 
 // ensureTableExists 確保基礎 Filter 表和鏈存在
 func (n *NFTablesManager) ensureTableExists(ctx context.Context) error {
 	// 1. 創建 Filter 表
-	cmd := exec.CommandContext(ctx, "nft", "add", "table", NftTableType, NftTableName)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("創建 Filter 表失敗: %s, %w", string(out), err)
+	if _, err := n.run.Run(ctx, "nft", "add", "table", NftTableType, NftTableName); err != nil {
+		return fmt.Errorf("創建 Filter 表失敗: %w", err)
 	}
 
 	// 2. 創建 Input 鏈
 	// priority 0, policy accept
 	chainDef := fmt.Sprintf("add chain %s %s %s { type filter hook input priority 0; policy accept; }",
 		NftTableType, NftTableName, NftChainName)
-	cmd = exec.CommandContext(ctx, "nft", chainDef)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("創建 Filter 鏈失敗: %s, %w", string(out), err)
+	if _, err := n.run.Run(ctx, "nft", chainDef); err != nil {
+		return fmt.Errorf("創建 Filter 鏈失敗: %w", err)
 	}
 
 	return nil
 }
 
-// ensureNatTablesExists 確保 NAT 表和鏈存在 (專門用於端口跳躍)
-func (n *NFTablesManager) ensureNatTablesExists(ctx context.Context) error {
-	// --- IPv4 NAT ---
-	// 1. 創建表
-	cmd := exec.CommandContext(ctx, "nft", "add", "table", "ip", NftIPv4NatTableName)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("創建 IPv4 NAT 表失敗: %s", string(out))
+// nftNatTables 各地址族的 NAT 表
+var nftNatTables = map[string]struct{ family, table string }{
+	familyIPv4: {"ip", NftIPv4NatTableName},
+	familyIPv6: {"ip6", NftIPv6NatTableName},
+}
+
+// ensureNatTableExists 確保指定地址族的 NAT 表和鏈存在 (專門用於端口跳躍)
+func (n *NFTablesManager) ensureNatTableExists(ctx context.Context, family string) error {
+	t := nftNatTables[family]
+	if _, err := n.run.Run(ctx, "nft", "add", "table", t.family, t.table); err != nil {
+		return fmt.Errorf("創建 %s NAT 表失敗: %w", family, err)
 	}
-	// 2. 創建鏈 (dstnat priority -100)
 	// add chain ip prism_nat_v4 prerouting { type nat hook prerouting priority dstnat; policy accept; }
-	chainDefV4 := fmt.Sprintf("add chain ip %s %s { type nat hook prerouting priority dstnat; policy accept; }",
-		NftIPv4NatTableName, NftNatChainName)
-	cmd = exec.CommandContext(ctx, "nft", chainDefV4)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("創建 IPv4 NAT 鏈失敗: %s", string(out))
+	chainDef := fmt.Sprintf("add chain %s %s %s { type nat hook prerouting priority dstnat; policy accept; }",
+		t.family, t.table, NftNatChainName)
+	if _, err := n.run.Run(ctx, "nft", chainDef); err != nil {
+		return fmt.Errorf("創建 %s NAT 鏈失敗: %w", family, err)
 	}
+	return nil
+}
 
-	// --- IPv6 NAT ---
-	// 1. 創建表
-	cmd = exec.CommandContext(ctx, "nft", "add", "table", "ip6", NftIPv6NatTableName)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		n.log.Debug("創建 IPv6 NAT 表失敗 (可能不支持 IPv6 NAT)", zap.String("err", string(out)))
-		return nil // 不阻斷流程
+func nftPorts(r Rule) string {
+	if r.IsRange() {
+		return fmt.Sprintf("%d-%d", r.Start, r.End)
 	}
-	// 2. 創建鏈
-	chainDefV6 := fmt.Sprintf("add chain ip6 %s %s { type nat hook prerouting priority dstnat; policy accept; }",
-		NftIPv6NatTableName, NftNatChainName)
-	cmd = exec.CommandContext(ctx, "nft", chainDefV6)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		n.log.Debug("創建 IPv6 NAT 鏈失敗", zap.String("err", string(out)))
+	return strconv.Itoa(r.Start)
+}
+
+// list 讀取 Filter 表與 NAT 表中帶 prism 備註的規則，表不存在時視為無規則
+func (n *NFTablesManager) list(ctx context.Context) ([]installedRule, error) {
+	var rules []installedRule
+
+	chains := []struct {
+		family, table, chain string
+		pattern              *regexp.Regexp
+		addrFamily           string
+	}{
+		{NftTableType, NftTableName, NftChainName, nftAcceptPattern, ""},
+		{"ip", NftIPv4NatTableName, NftNatChainName, nftRedirectPattern, familyIPv4},
+		{"ip6", NftIPv6NatTableName, NftNatChainName, nftRedirectPattern, familyIPv6},
 	}
 
-	return nil
+	for _, c := range chains {
+		output, err := n.run.Run(ctx, "nft", "-a", "list", "chain", c.family, c.table, c.chain)
+		if err != nil {
+			n.log.Debug("讀取 nftables 鏈失敗 (可能尚未創建)", zap.String("table", c.table), zap.Error(err))
+			continue
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(output))
+		for scanner.Scan() {
+			m := c.pattern.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+			if m == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(m[2])
+			end := start
+			if m[3] != "" {
+				end, _ = strconv.Atoi(m[3])
+			}
+			r := Rule{Protocol: m[1], Start: start, End: end}
+			handle := m[4]
+			if c.pattern == nftRedirectPattern {
+				r.RedirectTo, _ = strconv.Atoi(m[4])
+				handle = m[5]
+			}
+			ir := installedRule{
+				Rule:    r,
+				deletes: [][]string{{"nft", "delete", "rule", c.family, c.table, c.chain, "handle", handle}},
+			}
+			if c.addrFamily != "" {
+				ir.families = []string{c.addrFamily}
+			}
+			rules = append(rules, ir)
+		}
+	}
+	return rules, nil
 }
 
-func (n *NFTablesManager) OpenPort(ctx context.Context, port int, protocol string) error {
-	n.mu.Lock()
-	defer n.mu.Unlock()
+func (n *NFTablesManager) add(ctx context.Context, r Rule) error {
+	if r.IsRedirect() {
+		return n.addRedirect(ctx, r)
+	}
 
 	if err := n.ensureTableExists(ctx); err != nil {
 		return err
 	}
-
-	if protocol == "both" {
-		if err := n.openSinglePort(ctx, port, "tcp"); err != nil {
-			return err
-		}
-		return n.openSinglePort(ctx, port, "udp")
+	comment := "\"prism-managed\""
+	if r.IsRange() {
+		comment = "\"prism-managed-range\""
 	}
-	return n.openSinglePort(ctx, port, protocol)
-}
-
-func (n *NFTablesManager) openSinglePort(ctx context.Context, port int, protocol string) error {
-	cmd := exec.CommandContext(ctx, "nft", "add", "rule",
+	if _, err := n.run.Run(ctx, "nft", "add", "rule",
 		NftTableType, NftTableName, NftChainName,
-		protocol, "dport", fmt.Sprintf("%d", port),
-		"accept", "comment", "\"prism-managed\"")
-
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("開放端口失敗: %s", string(out))
+		r.Protocol, "dport", nftPorts(r),
+		"accept", "comment", comment); err != nil {
+		return fmt.Errorf("開放端口失敗: %w", err)
 	}
-
-	n.openedPorts[port] = true
 	return nil
 }
 
-func (n *NFTablesManager) OpenPortRange(ctx context.Context, start, end int, protocol string) error {
-	n.mu.Lock()
-	defer n.mu.Unlock()
-
-	if err := n.ensureTableExists(ctx); err != nil {
+// addRedirect 端口跳躍 NAT 重定向，IPv4 與 IPv6 各一條
+// 主機啟用 IPv6 時 IPv6 規則失敗視為錯誤，否則 IPv6 客戶端無法使用跳躍端口
+func (n *NFTablesManager) addRedirect(ctx context.Context, r Rule) error {
+	if err := n.addFamily(ctx, r, familyIPv4); err != nil {
 		return err
 	}
-
-	if protocol == "both" {
-		if err := n.openRange(ctx, start, end, "tcp"); err != nil {
+	if err := n.addFamily(ctx, r, familyIPv6); err != nil {
+		if n.ipv6Enabled() {
 			return err
 		}
-		return n.openRange(ctx, start, end, "udp")
+		n.log.Debug("IPv6 NAT 規則跳過 (主機未啟用 IPv6)", zap.Error(err))
 	}
-	return n.openRange(ctx, start, end, protocol)
+	return nil
 }
 
-func (n *NFTablesManager) openRange(ctx context.Context, start, end int, protocol string) error {
-	rangeStr := fmt.Sprintf("%d-%d", start, end)
-	cmd := exec.CommandContext(ctx, "nft", "add", "rule",
-		NftTableType, NftTableName, NftChainName,
-		protocol, "dport", rangeStr,
-		"accept", "comment", "\"prism-managed-range\"")
+// addFamily 在指定地址族的 NAT 表中添加重定向規則
+// nft add rule ip prism_nat_v4 prerouting udp dport 10000-20000 redirect to :443
+func (n *NFTablesManager) addFamily(ctx context.Context, r Rule, family string) error {
+	if !r.IsRedirect() {
+		// Filter 表為 inet 族，不區分地址族
+		return n.add(ctx, r)
+	}
+	if err := n.ensureNatTableExists(ctx, family); err != nil {
+		return err
+	}
 
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("開放範圍失敗: %s", string(out))
+	t := nftNatTables[family]
+	comment := fmt.Sprintf("\"prism-hy2-hop-%d-%d\"", r.Start, r.End)
+	if _, err := n.run.Run(ctx, "nft", "add", "rule",
+		t.family, t.table, NftNatChainName,
+		r.Protocol, "dport", nftPorts(r),
+		"redirect", "to", fmt.Sprintf(":%d", r.RedirectTo),
+		"comment", comment); err != nil {
+		return fmt.Errorf("%s NAT 規則失敗: %w", family, err)
 	}
 	return nil
 }
 
-// OpenHysteria2PortHopping 實現端口跳躍 (Filter Accept + NAT Redirect)
-func (n *NFTablesManager) OpenHysteria2PortHopping(ctx context.Context, listenPort, start, end int) error {
+func (n *NFTablesManager) commit(ctx context.Context) error {
+	return nil
+}
+
+// ApplyPolicies 以 nft -f 原子替換整個策略表
+func (n *NFTablesManager) ApplyPolicies(ctx context.Context, policies []Policy) error {
+	policies, unsupported := supportedPolicies(n.Capabilities(), policies)
+
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	n.log.Info("配置 NFTables 端口跳躍", zap.Int("listen", listenPort), zap.Int("start", start), zap.Int("end", end))
-
-	// 1. Filter 表放行 (UDP)
-	// Hysteria 2 使用 UDP，必須先允許流量進入
-	if err := n.ensureTableExists(ctx); err != nil {
-		return err
-	}
-	if err := n.openRange(ctx, start, end, "udp"); err != nil {
-		return fmt.Errorf("Filter 表放行失敗: %w", err)
+	script := nftPolicyScript(policies)
+	if err := runWithFile(ctx, n.run, script, func(path string) []string {
+		return []string{"nft", "-f", path}
+	}); err != nil {
+		return fmt.Errorf("應用源地址策略失敗: %w", err)
 	}
+	return unsupported
+}
 
-	// 2. NAT 表轉發 (Redirect)
-	if err := n.ensureNatTablesExists(ctx); err != nil {
-		return err
+// nftPolicyScript 生成策略表腳本
+// 先創建再刪除舊表保證腳本在表不存在時也能執行，整個腳本在同一事務中生效
+func nftPolicyScript(policies []Policy) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "add table %s %s\n", NftTableType, NftPolicyTableName)
+	fmt.Fprintf(&sb, "delete table %s %s\n", NftTableType, NftPolicyTableName)
+	if len(policies) == 0 {
+		return sb.String()
 	}
 
-	rangeStr := fmt.Sprintf("%d-%d", start, end)
-	toPort := fmt.Sprintf(":%d", listenPort)
-	comment := fmt.Sprintf("\"prism-hy2-hop-%s\"", rangeStr)
-
-	// 添加 IPv4 NAT 規則
-	// nft add rule ip prism_nat_v4 prerouting udp dport 10000-20000 redirect to :443
-	cmd := exec.CommandContext(ctx, "nft", "add", "rule",
-		"ip", NftIPv4NatTableName, NftNatChainName,
-		"udp", "dport", rangeStr,
-		"redirect", "to", toPort,
-		"comment", comment)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("IPv4 NAT 規則失敗: %s", string(out))
+	var sets, rules []string
+	addSet := func(name, addrType string, elements []string) {
+		def := fmt.Sprintf("\tset %s {\n\t\ttype %s; flags interval; auto-merge;\n", name, addrType)
+		if len(elements) > 0 {
+			def += fmt.Sprintf("\t\telements = { %s }\n", strings.Join(elements, ", "))
+		}
+		sets = append(sets, def+"\t}\n")
 	}
-
-	// 添加 IPv6 NAT 規則
-	cmd = exec.CommandContext(ctx, "nft", "add", "rule",
-		"ip6", NftIPv6NatTableName, NftNatChainName,
-		"udp", "dport", rangeStr,
-		"redirect", "to", toPort,
-		"comment", comment)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		n.log.Debug("IPv6 NAT 規則失敗 (可能忽略)", zap.String("err", string(out)))
+	addRule := func(format string, args ...interface{}) {
+		rules = append(rules, fmt.Sprintf("\t\t"+format+" comment \"%s\"\n", append(args, policyComment)...))
 	}
 
-	return nil
-}
+	for _, p := range policies {
+		prefix := fmt.Sprintf("p_%s_%d", p.Protocol, p.Port)
+		match := fmt.Sprintf("%s dport %d", p.Protocol, p.Port)
 
-func (n *NFTablesManager) GetOpenedPorts() []int {
-	n.mu.Lock()
-	defer n.mu.Unlock()
-	ports := make([]int, 0, len(n.openedPorts))
-	for k := range n.openedPorts {
-		ports = append(ports, k)
+		// 0. 豁免來源直接返回 (基礎鏈返回即按鏈默認策略放行)
+		exempt4, exempt6 := splitFamilies(p.Exempt)
+		if len(exempt4) > 0 {
+			addSet(prefix+"_exempt4", "ipv4_addr", exempt4)
+			addRule("%s ip saddr @%s_exempt4 return", match, prefix)
+		}
+		if len(exempt6) > 0 {
+			addSet(prefix+"_exempt6", "ipv6_addr", exempt6)
+			addRule("%s ip6 saddr @%s_exempt6 return", match, prefix)
+		}
+
+		// 1. 黑名單與國家屏蔽
+		deny4, deny6 := splitFamilies(append(append([]string{}, p.Deny...), p.Countries...))
+		if len(deny4) > 0 {
+			addSet(prefix+"_deny4", "ipv4_addr", deny4)
+			addRule("%s ip saddr @%s_deny4 drop", match, prefix)
+		}
+		if len(deny6) > 0 {
+			addSet(prefix+"_deny6", "ipv6_addr", deny6)
+			addRule("%s ip6 saddr @%s_deny6 drop", match, prefix)
+		}
+
+		// 2. 白名單: 某地址族無條目時該地址族全部丟棄
+		if len(p.Allow) > 0 {
+			allow4, allow6 := splitFamilies(p.Allow)
+			addSet(prefix+"_allow4", "ipv4_addr", allow4)
+			addSet(prefix+"_allow6", "ipv6_addr", allow6)
+			addRule("meta nfproto ipv4 %s ip saddr != @%s_allow4 drop", match, prefix)
+			addRule("meta nfproto ipv6 %s ip6 saddr != @%s_allow6 drop", match, prefix)
+		}
+
+		// 3. 按來源的新連接速率限制
+		if p.RateLimit > 0 {
+			addRule("%s ct state new meter %s_rate4 { ip saddr limit rate over %d/minute burst %d packets } drop",
+				match, prefix, p.RateLimit, p.RateBurst)
+			addRule("%s ct state new meter %s_rate6 { ip6 saddr limit rate over %d/minute burst %d packets } drop",
+				match, prefix, p.RateLimit, p.RateBurst)
+		}
+	}
+
+	fmt.Fprintf(&sb, "table %s %s {\n", NftTableType, NftPolicyTableName)
+	for _, s := range sets {
+		sb.WriteString(s)
 	}
-	sort.Ints(ports)
-	return ports
+	sb.WriteString("\tchain input {\n\t\ttype filter hook input priority -10; policy accept;\n")
+	for _, r := range rules {
+		sb.WriteString(r)
+	}
+	sb.WriteString("\t}\n}\n")
+	return sb.String()
 }
 
 // SaveRules 保存規則到文件
 func (n *NFTablesManager) SaveRules(ctx context.Context) error {
 	// 1. 導出當前規則集
-	cmd := exec.CommandContext(ctx, "nft", "list", "ruleset")
-	output, err := cmd.Output()
+	output, err := n.run.Run(ctx, "nft", "list", "ruleset")
 	if err != nil {
 		return fmt.Errorf("導出規則失敗: %w", err)
 	}
@@ -251,42 +346,17 @@ func (n *NFTablesManager) SaveRules(ctx context.Context) error {
 	}
 
 	// 3. 寫入新規則
-	if err := os.WriteFile(configPath, output, 0644); err != nil {
+	if err := os.WriteFile(configPath, []byte(output), 0644); err != nil {
 		return fmt.Errorf("寫入配置文件失敗: %w", err)
 	}
 
 	// 4. 嘗試啟用 nftables 服務（確保開機自啟）
-	_ = exec.CommandContext(ctx, "systemctl", "enable", "nftables").Run()
+	_, _ = n.run.Run(ctx, "systemctl", "enable", "nftables")
 
 	n.log.Info("✅ NFTables 規則已保存並備份原配置")
 	return nil
 }
 
-// FlushRules 清空 Prism 相關的所有規則 (Filter + NAT)
-func (n *NFTablesManager) FlushRules(ctx context.Context) error {
-	n.mu.Lock()
-	defer n.mu.Unlock()
-
-	n.log.Info("🔥 正在清理 Prism 防火牆規則...")
-
-	// 1. 刪除 Filter 表
-	exec.CommandContext(ctx, "nft", "delete", "table", NftTableType, NftTableName).Run()
-
-	// 2. 刪除 NAT 表 (IPv4 & IPv6)
-	exec.CommandContext(ctx, "nft", "delete", "table", "ip", NftIPv4NatTableName).Run()
-	exec.CommandContext(ctx, "nft", "delete", "table", "ip6", NftIPv6NatTableName).Run()
-
-	n.openedPorts = make(map[int]bool)
-
-	// 3. 重建基礎 Filter 表結構 (NAT 表按需創建)
-	if err := n.ensureTableExists(ctx); err != nil {
-		return fmt.Errorf("重建表失敗: %w", err)
-	}
-
-	n.log.Info("✅ Prism 規則已清理")
-	return nil
-}
-
 // Backend 實現保持不變 ...
 type nftablesBackend struct{}
 
@@ -0,0 +1,44 @@
+package firewall
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNFTablesListAndRemove(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["nft -a list chain inet prism input"] = `table inet prism {
+	chain input { # handle 1
+		type filter hook input priority filter; policy accept;
+		tcp dport 443 accept comment "prism-managed" # handle 4
+		udp dport 8443 accept comment "prism-managed" # handle 5
+		udp dport 20000-30000 accept comment "prism-managed-range" # handle 6
+		tcp dport 22 accept # handle 7
+	}
+}`
+	runner.outputs["nft -a list chain ip prism_nat_v4 prerouting"] = `table ip prism_nat_v4 {
+	chain prerouting { # handle 1
+		type nat hook prerouting priority dstnat; policy accept;
+		udp dport 20000-30000 redirect to :8443 comment "prism-hy2-hop-20000-30000" # handle 2
+	}
+}`
+	n := newNFTables(zap.NewNop(), runner)
+
+	rules, err := n.CurrentRules(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, rules, 4)
+
+	// 跳躍重定向到新端口: 添加新規則，刪除舊的 8443 與舊重定向
+	desired := append([]Rule{PortRule(443, "tcp"), PortRule(9443, "udp")}, HoppingRules(9443, 20000, 30000)...)
+	plan, err := n.Reconcile(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Equal(t, "+udp/9443 +udp/20000-30000->9443 -udp/8443 -udp/20000-30000->8443", plan.String())
+	assert.Contains(t, runner.calls, "nft delete rule inet prism input handle 5")
+	assert.Contains(t, runner.calls, "nft delete rule ip prism_nat_v4 prerouting handle 2")
+	assert.NotContains(t, runner.calls, "nft delete rule inet prism input handle 4")
+	assert.NotContains(t, runner.calls, "nft delete rule inet prism input handle 7")
+}
@@ -0,0 +1,141 @@
+package firewall
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
+)
+
+const (
+	// 源地址策略規則的標記
+	policyComment = "prism-policy"
+	// 未設置突發值時的默認突發連接數
+	defaultRateBurst = 10
+)
+
+// Policy 單個入站端口的源地址策略
+// 規則按 豁免 -> 拒絕 -> 白名單 -> 速率限制 的順序匹配，除豁免外均只丟棄不放行，放行仍由端口規則負責
+type Policy struct {
+	Name      string   // 來源協議，用於日誌
+	Protocol  string   // tcp / udp
+	Port      int      // 監聽端口
+	Allow     []string // 白名單 CIDR，非空時僅放行列表內來源 (按地址族分別生效)
+	Deny      []string // 黑名單 CIDR
+	Countries []string // 國家屏蔽解析出的 CIDR (數量可能很大，需後端支持集合)
+	RateLimit int      // 每個來源每分鐘新連接數上限，0 不限制
+	RateBurst int
+	Exempt    []string // 不受本策略限制的來源 (如當前 SSH 會話的客戶端)
+}
+
+// IsEmpty 是否未設置任何限制
+func (p Policy) IsEmpty() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0 && len(p.Countries) == 0 && p.RateLimit == 0
+}
+
+// String 返回策略作用的端口，如 tcp/443
+func (p Policy) String() string {
+	return PortRule(p.Port, p.Protocol).String()
+}
+
+// splitFamilies 按地址族拆分 CIDR
+func splitFamilies(cidrs []string) (v4, v6 []string) {
+	for _, c := range cidrs {
+		if strings.Contains(c, ":") {
+			v6 = append(v6, c)
+		} else {
+			v4 = append(v4, c)
+		}
+	}
+	return v4, v6
+}
+
+// supportedPolicies 去除後端不支持的功能，返回可應用的策略
+// 有功能被忽略時同時返回錯誤，調用方可記錄後繼續
+func supportedPolicies(caps Capabilities, policies []Policy) ([]Policy, error) {
+	var result []Policy
+	var ignored []string
+
+	for _, p := range policies {
+		if (len(p.Allow) > 0 || len(p.Deny) > 0) && !caps.SupportSourceFilter {
+			ignored = append(ignored, p.String()+" 來源過濾")
+			p.Allow, p.Deny = nil, nil
+		}
+		if p.RateLimit > 0 && !caps.SupportRateLimit {
+			ignored = append(ignored, p.String()+" 速率限制")
+			p.RateLimit = 0
+		}
+		if len(p.Countries) > 0 && !caps.SupportGeoIP {
+			ignored = append(ignored, p.String()+" 國家屏蔽")
+			p.Countries = nil
+		}
+		if p.RateLimit > 0 && p.RateBurst <= 0 {
+			p.RateBurst = defaultRateBurst
+		}
+		if !p.IsEmpty() {
+			result = append(result, p)
+		}
+	}
+
+	if len(ignored) > 0 {
+		return result, errors.New(CodeUnsupported, "當前防火牆不支持以下策略，已忽略: "+strings.Join(ignored, ", "))
+	}
+	return result, nil
+}
+
+// LoadCountryCIDRs 從本地 GeoIP 數據目錄讀取國家網段
+// 目錄中每個國家一個文件 <cc>.zone (小寫國家代碼)，每行一個 IPv4/IPv6 CIDR，# 開頭為註釋
+// 與 ipdeny 等來源的 aggregated zone 文件格式兼容
+func LoadCountryCIDRs(dir string, countries []string) ([]string, error) {
+	var cidrs []string
+	for _, cc := range countries {
+		path := filepath.Join(dir, strings.ToLower(cc)+".zone")
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("讀取 GeoIP 數據失敗 (%s): %w", strings.ToUpper(cc), err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if _, network, err := net.ParseCIDR(line); err == nil {
+				cidrs = append(cidrs, network.String())
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("讀取 GeoIP 數據失敗 (%s): %w", strings.ToUpper(cc), err)
+		}
+	}
+	return cidrs, nil
+}
+
+// runWithFile 將內容寫入臨時文件後執行命令 (如 nft -f)，命令參數由 args 根據文件路徑生成
+func runWithFile(ctx context.Context, run Runner, content string, args func(path string) []string) error {
+	f, err := os.CreateTemp("", "prism-fw-*")
+	if err != nil {
+		return fmt.Errorf("創建臨時文件失敗: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return fmt.Errorf("寫入臨時文件失敗: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("寫入臨時文件失敗: %w", err)
+	}
+
+	cmd := args(f.Name())
+	_, err = run.Run(ctx, cmd[0], cmd[1:]...)
+	return err
+}
@@ -0,0 +1,135 @@
+package firewall
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSupportedPolicies(t *testing.T) {
+	policies := []Policy{
+		{Protocol: "tcp", Port: 443, Deny: []string{"198.51.100.0/24"}, RateLimit: 30},
+		{Protocol: "udp", Port: 8443, Countries: []string{"1.0.0.0/8"}},
+	}
+
+	result, err := supportedPolicies(Capabilities{SupportSourceFilter: true}, policies)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tcp/443 速率限制")
+	assert.Contains(t, err.Error(), "udp/8443 國家屏蔽")
+	// 只剩國家屏蔽的策略被整體去除
+	require.Len(t, result, 1)
+	assert.Equal(t, 0, result[0].RateLimit)
+
+	all := Capabilities{SupportSourceFilter: true, SupportRateLimit: true, SupportGeoIP: true}
+	result, err = supportedPolicies(all, policies)
+	require.NoError(t, err)
+	assert.Equal(t, defaultRateBurst, result[0].RateBurst)
+}
+
+func TestLoadCountryCIDRs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "xx.zone"), []byte("# test\n1.2.3.0/24\n\n2001:db8::/32\ninvalid\n"), 0644))
+
+	cidrs, err := LoadCountryCIDRs(dir, []string{"XX"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.3.0/24", "2001:db8::/32"}, cidrs)
+
+	_, err = LoadCountryCIDRs(dir, []string{"YY"})
+	assert.Error(t, err)
+}
+
+func TestNFTPolicyScript(t *testing.T) {
+	assert.Equal(t, "add table inet prism_policy\ndelete table inet prism_policy\n", nftPolicyScript(nil))
+
+	script := nftPolicyScript([]Policy{{
+		Protocol:  "tcp",
+		Port:      443,
+		Allow:     []string{"203.0.113.0/24"},
+		Deny:      []string{"198.51.100.7/32", "2001:db8::/32"},
+		RateLimit: 30,
+		RateBurst: 5,
+		Exempt:    []string{"192.0.2.10/32"},
+	}})
+
+	assert.Contains(t, script, "set p_tcp_443_deny4 {\n\t\ttype ipv4_addr; flags interval; auto-merge;\n\t\telements = { 198.51.100.7/32 }\n\t}")
+	assert.Contains(t, script, "tcp dport 443 ip6 saddr @p_tcp_443_deny6 drop")
+	// 白名單沒有 IPv6 條目時，IPv6 來源全部丟棄
+	assert.Contains(t, script, "set p_tcp_443_allow6 {\n\t\ttype ipv6_addr; flags interval; auto-merge;\n\t}")
+	assert.Contains(t, script, "meta nfproto ipv6 tcp dport 443 ip6 saddr != @p_tcp_443_allow6 drop")
+	assert.Contains(t, script, "meter p_tcp_443_rate4 { ip saddr limit rate over 30/minute burst 5 packets } drop")
+	assert.Contains(t, script, "type filter hook input priority -10; policy accept;")
+
+	// 規則順序: 豁免 -> 黑名單 -> 白名單 -> 速率限制
+	exempt := strings.Index(script, "tcp dport 443 ip saddr @p_tcp_443_exempt4 return")
+	deny := strings.Index(script, "@p_tcp_443_deny4 drop")
+	allow := strings.Index(script, "@p_tcp_443_allow4 drop")
+	rate := strings.Index(script, "p_tcp_443_rate4")
+	assert.True(t, exempt >= 0 && exempt < deny && deny < allow && allow < rate, script)
+}
+
+func TestIPTablesPolicyRules(t *testing.T) {
+	policies := []Policy{{Protocol: "udp", Port: 8443, Allow: []string{"203.0.113.0/24"}, Deny: []string{"2001:db8::/32"}, RateLimit: 60, RateBurst: 10}}
+
+	v4 := iptablesPolicyRules(false, policies)
+	require.Len(t, v4, 3)
+	assert.Equal(t, []string{"-p", "udp", "--dport", "8443", "-m", "conntrack", "--ctstate", "NEW", "-m", "hashlimit",
+		"--hashlimit-name", "prism-8443-udp", "--hashlimit-mode", "srcip", "--hashlimit-above", "60/min", "--hashlimit-burst", "10",
+		"-j", "DROP", "-m", "comment", "--comment", "prism-policy"}, v4[0])
+	assert.Equal(t, []string{"-p", "udp", "--dport", "8443", "-s", "203.0.113.0/24", "-j", "RETURN", "-m", "comment", "--comment", "prism-policy"}, v4[1])
+	assert.Equal(t, []string{"-p", "udp", "--dport", "8443", "-j", "DROP", "-m", "comment", "--comment", "prism-policy"}, v4[2])
+
+	v6 := iptablesPolicyRules(true, policies)
+	require.Len(t, v6, 3)
+	assert.Equal(t, []string{"-p", "udp", "--dport", "8443", "-s", "2001:db8::/32", "-j", "DROP", "-m", "comment", "--comment", "prism-policy"}, v6[0])
+}
+
+func TestUFWApplyPolicies(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["ufw status numbered"] = `Status: active
+
+     To                         Action      From
+     --                         ------      ----
+[ 1] 443/tcp                    DENY IN     198.51.100.0/24            # prism-policy
+[ 2] 443/tcp                    DENY IN     Anywhere                   # prism-policy
+[ 3] 443/tcp                    ALLOW IN    Anywhere                   # prism
+`
+	u := newUFW(zap.NewNop(), runner)
+
+	err := u.ApplyPolicies(context.Background(), []Policy{
+		{Protocol: "tcp", Port: 443, Allow: []string{"203.0.113.0/24"}, RateLimit: 30},
+	})
+	// 速率限制不受支持，其餘策略照常應用
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"ufw --force delete 2",
+		"ufw --force delete 1",
+		"ufw prepend deny to any port 443 proto tcp comment prism-policy",
+		"ufw prepend allow from 203.0.113.0/24 to any port 443 proto tcp comment prism-policy",
+	}, runner.mutations("ufw status"))
+}
+
+func TestFirewalldApplyPolicies(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["firewall-cmd --permanent --list-rich-rules"] = `rule family="ipv4" source ipset="prism-tcp-443-deny4" port port="443" protocol="tcp" drop
+rule family="ipv4" source address="10.0.0.1" accept
+`
+	runner.outputs["firewall-cmd --permanent --get-ipsets"] = "prism-tcp-443-deny4 other"
+	f := newFirewalld(zap.NewNop(), runner)
+
+	require.NoError(t, f.ApplyPolicies(context.Background(), []Policy{{Protocol: "tcp", Port: 443, Deny: []string{"198.51.100.0/24"}}}))
+
+	calls := runner.mutations("firewall-cmd --permanent --list-rich-rules", "firewall-cmd --permanent --get-ipsets", "firewall-cmd --permanent --ipset=")
+	assert.Equal(t, []string{
+		`firewall-cmd --permanent --remove-rich-rule=rule family="ipv4" source ipset="prism-tcp-443-deny4" port port="443" protocol="tcp" drop`,
+		"firewall-cmd --permanent --delete-ipset=prism-tcp-443-deny4",
+		"firewall-cmd --permanent --new-ipset=prism-tcp-443-deny4 --type=hash:net",
+		`firewall-cmd --permanent --add-rich-rule=rule family="ipv4" source ipset="prism-tcp-443-deny4" port port="443" protocol="tcp" drop`,
+		"firewall-cmd --reload",
+	}, calls)
+}
@@ -0,0 +1,279 @@
+package firewall
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Runner 命令執行器，測試中可替換為模擬實現
+type Runner interface {
+	// Run 執行命令並返回標準輸出，失敗時錯誤中包含標準錯誤內容
+	Run(ctx context.Context, name string, args ...string) (string, error)
+}
+
+type execRunner struct{}
+
+func (execRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg != "" {
+			return stdout.String(), fmt.Errorf("%s: %w", msg, err)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// installedRule 系統中已存在的 Prism 規則及刪除它所需的操作
+type installedRule struct {
+	Rule
+	deletes  [][]string                        // 刪除命令
+	undo     []func(ctx context.Context) error // 非命令方式的刪除 (如編輯規則文件)
+	families []string                          // 規則所在的地址族，不區分地址族時為空
+}
+
+// ruleOps 各後端需要實現的規則操作
+type ruleOps interface {
+	// list 讀取系統中帶 Prism 標記的規則
+	list(ctx context.Context) ([]installedRule, error)
+	// add 添加一條規則
+	add(ctx context.Context, r Rule) error
+	// commit 在有變更後調用，使變更生效 (如 firewalld 重載)，無需處理的後端直接返回
+	commit(ctx context.Context) error
+	Capabilities() Capabilities
+}
+
+// ruleManager 基於實際規則狀態的通用實現
+// 各後端只負責讀取與添加規則，差異計算和增刪順序由這裡統一處理
+type ruleManager struct {
+	log  *zap.Logger
+	run  Runner
+	ops  ruleOps
+	ipv6 func() bool // 主機是否啟用 IPv6，為 nil 時按未啟用處理
+	mu   sync.Mutex
+}
+
+// ipv6Enabled 主機啟用 IPv6 時，IPv6 規則是必需的
+func (m *ruleManager) ipv6Enabled() bool {
+	return m.ipv6 != nil && m.ipv6()
+}
+
+func (m *ruleManager) exec(ctx context.Context, args []string) error {
+	_, err := m.run.Run(ctx, args[0], args[1:]...)
+	return err
+}
+
+// installed 讀取並按規則合併 (如 IPv4/IPv6 各一條)
+func (m *ruleManager) installed(ctx context.Context) (map[string]*installedRule, error) {
+	list, err := m.ops.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*installedRule, len(list))
+	for _, ir := range list {
+		key := ir.Rule.String()
+		if existing, ok := result[key]; ok {
+			existing.deletes = append(existing.deletes, ir.deletes...)
+			existing.undo = append(existing.undo, ir.undo...)
+			existing.families = append(existing.families, ir.families...)
+			continue
+		}
+		copied := ir
+		result[key] = &copied
+	}
+	return result, nil
+}
+
+// CurrentRules 返回系統中實際存在的 Prism 規則
+func (m *ruleManager) CurrentRules(ctx context.Context) ([]Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	installed, err := m.installed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(installed))
+	for _, ir := range installed {
+		rules = append(rules, ir.Rule)
+	}
+	sortRules(rules)
+	return rules, nil
+}
+
+// Reconcile 將 Prism 規則同步為期望集合
+// 先添加缺失的規則再刪除多餘的規則，變更端口時不會出現全部斷開的窗口
+func (m *ruleManager) Reconcile(ctx context.Context, desired []Rule) (Plan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	installed, err := m.installed(ctx)
+	if err != nil {
+		return Plan{}, fmt.Errorf("讀取防火牆規則失敗: %w", err)
+	}
+	current := make([]Rule, 0, len(installed))
+	for _, ir := range installed {
+		current = append(current, ir.Rule)
+	}
+
+	plan := Diff(current, desired)
+	repairs := m.missingFamilies(installed, desired)
+	for _, r := range repairs {
+		plan.Repair = append(plan.Repair, r.Rule)
+	}
+	if plan.IsEmpty() {
+		return plan, nil
+	}
+
+	var failures []string
+	for _, r := range plan.Add {
+		if err := m.ops.add(ctx, r); err != nil {
+			failures = append(failures, fmt.Sprintf("添加 %s: %v", r, err))
+		}
+	}
+	for _, r := range repairs {
+		for _, family := range r.families {
+			if err := m.ops.(familyOps).addFamily(ctx, r.Rule, family); err != nil {
+				failures = append(failures, fmt.Sprintf("補齊 %s (%s): %v", r.Rule, family, err))
+			}
+		}
+	}
+	for _, r := range plan.Remove {
+		if err := m.remove(ctx, installed[r.String()]); err != nil {
+			failures = append(failures, fmt.Sprintf("刪除 %s: %v", r, err))
+		}
+	}
+	if err := m.ops.commit(ctx); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	m.log.Info("防火牆規則已同步", zap.String("changes", plan.String()))
+	if len(failures) > 0 {
+		return plan, fmt.Errorf("部分規則同步失敗: %s", strings.Join(failures, "; "))
+	}
+	return plan, nil
+}
+
+// missingFamilies 找出已存在但缺少部分地址族的期望規則，families 為需要補齊的地址族
+func (m *ruleManager) missingFamilies(installed map[string]*installedRule, desired []Rule) []installedRule {
+	if _, ok := m.ops.(familyOps); !ok {
+		return nil
+	}
+	var repairs []installedRule
+	for _, r := range Normalize(desired) {
+		ir, ok := installed[r.String()]
+		if !ok {
+			continue
+		}
+		if missing := missingFamilies(ir.families, m.ipv6Enabled()); len(missing) > 0 {
+			repairs = append(repairs, installedRule{Rule: r, families: missing})
+		}
+	}
+	return repairs
+}
+
+func (m *ruleManager) remove(ctx context.Context, ir *installedRule) error {
+	if ir == nil {
+		return nil
+	}
+	for _, args := range ir.deletes {
+		if err := m.exec(ctx, args); err != nil {
+			return err
+		}
+	}
+	for _, undo := range ir.undo {
+		if err := undo(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensure 確保規則存在 (單條添加)
+func (m *ruleManager) ensure(ctx context.Context, rules ...Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	installed, err := m.installed(ctx)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, r := range Normalize(rules) {
+		if _, ok := installed[r.String()]; ok {
+			continue
+		}
+		if err := m.ops.add(ctx, r); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if changed {
+		return m.ops.commit(ctx)
+	}
+	return nil
+}
+
+// OpenPort 開放單個端口，protocol 可為 tcp / udp / both
+func (m *ruleManager) OpenPort(ctx context.Context, port int, protocol string) error {
+	return m.ensure(ctx, PortRule(port, protocol))
+}
+
+// OpenPortRange 開放端口範圍
+func (m *ruleManager) OpenPortRange(ctx context.Context, startPort, endPort int, protocol string) error {
+	return m.ensure(ctx, RangeRule(startPort, endPort, protocol))
+}
+
+// OpenHysteria2PortHopping 開放跳躍範圍並重定向到監聽端口
+func (m *ruleManager) OpenHysteria2PortHopping(ctx context.Context, listenPort, startPort, endPort int) error {
+	if !m.ops.Capabilities().SupportPortHopping {
+		return errUnsupportedHopping
+	}
+	return m.ensure(ctx, HoppingRules(listenPort, startPort, endPort)...)
+}
+
+// GetOpenedPorts 返回系統中實際放行的單端口
+func (m *ruleManager) GetOpenedPorts() []int {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rules, err := m.CurrentRules(ctx)
+	if err != nil {
+		m.log.Debug("讀取防火牆規則失敗", zap.Error(err))
+		return []int{}
+	}
+
+	seen := make(map[int]bool)
+	ports := []int{}
+	for _, r := range rules {
+		if r.IsRedirect() || r.IsRange() || seen[r.Start] {
+			continue
+		}
+		seen[r.Start] = true
+		ports = append(ports, r.Start)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// FlushRules 刪除所有 Prism 規則
+func (m *ruleManager) FlushRules(ctx context.Context) error {
+	_, err := m.Reconcile(ctx, nil)
+	return err
+}
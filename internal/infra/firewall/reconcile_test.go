@@ -0,0 +1,141 @@
+package firewall
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeRunner 模擬命令執行，按完整命令行返回預設輸出並記錄調用
+type fakeRunner struct {
+	outputs map[string]string
+	errs    map[string]error
+	calls   []string
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{outputs: map[string]string{}, errs: map[string]error{}}
+}
+
+func (f *fakeRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, line)
+	if err, ok := f.errs[line]; ok {
+		return "", err
+	}
+	return f.outputs[line], nil
+}
+
+// mutations 返回除讀取外的命令
+func (f *fakeRunner) mutations(readOnly ...string) []string {
+	var out []string
+	for _, c := range f.calls {
+		skip := false
+		for _, r := range readOnly {
+			if strings.HasPrefix(c, r) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func TestReconcileAddsBeforeRemoving(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["ufw status"] = `Status: active
+
+To                         Action      From
+--                         ------      ----
+443/tcp                    ALLOW       Anywhere                   # prism
+8443/udp                   ALLOW       Anywhere                   # prism
+443/tcp (v6)               ALLOW       Anywhere (v6)              # prism
+`
+	u := newUFW(zap.NewNop(), runner)
+
+	plan, err := u.Reconcile(context.Background(), []Rule{PortRule(443, "tcp"), PortRule(9443, "udp")})
+	require.NoError(t, err)
+	assert.Equal(t, "+udp/9443 -udp/8443", plan.String())
+	assert.Equal(t, []string{
+		"ufw allow 9443/udp comment prism",
+		"ufw --force delete allow 8443/udp",
+	}, runner.mutations("ufw status"))
+}
+
+func TestReconcileReportsFailures(t *testing.T) {
+	runner := newFakeRunner()
+	runner.errs["ufw allow 9443/udp comment prism"] = errors.New("boom")
+	u := newUFW(zap.NewNop(), runner)
+	u.rulesDir = t.TempDir() // 無 before.rules，重定向規則寫入失敗
+
+	// 不支持的重定向與執行失敗都會匯總返回，其餘規則照常應用
+	desired := append([]Rule{PortRule(9443, "udp"), PortRule(443, "tcp")}, HoppingRules(9443, 20000, 30000)...)
+	_, err := u.Reconcile(context.Background(), desired)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "udp/9443")
+	assert.Contains(t, err.Error(), "udp/20000-30000->9443")
+	assert.Contains(t, runner.calls, "ufw allow 443/tcp comment prism")
+}
+
+func TestGetOpenedPortsReadsSystemState(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["ufw status"] = `443/tcp                    ALLOW       Anywhere                   # prism
+443/udp                    ALLOW       Anywhere                   # prism
+20000:30000/udp            ALLOW       Anywhere                   # prism
+22/tcp                     ALLOW       Anywhere
+`
+	u := newUFW(zap.NewNop(), runner)
+	assert.Equal(t, []int{443}, u.GetOpenedPorts())
+}
+
+func TestReconcileRepairsMissingFamily(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["iptables-save"] = iptablesSaveOutput
+	runner.outputs["ip6tables-save"] = `*filter
+-A INPUT -p tcp -m tcp --dport 443 -m comment --comment prism-tcp-443 -j ACCEPT
+COMMIT
+`
+	i := newIPTables(zap.NewNop(), runner)
+	i.ipv6 = func() bool { return true }
+
+	// IPv6 缺少跳躍重定向: 只補齊 ip6tables，不重複添加 IPv4 規則
+	desired := append([]Rule{PortRule(443, "tcp")}, Rule{Protocol: "udp", Start: 20000, End: 30000, RedirectTo: 8443})
+	plan, err := i.Reconcile(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Equal(t, "-udp/8443 ~udp/20000-30000->8443", plan.String())
+	assert.Contains(t, runner.calls, "ip6tables -t nat -I PREROUTING -p udp -m multiport --dports 20000:30000 -j REDIRECT --to-ports 8443 -m comment --comment prism-hy2-hop-20000-30000-dnat")
+	assert.NotContains(t, runner.calls, "iptables -t nat -I PREROUTING -p udp -m multiport --dports 20000:30000 -j REDIRECT --to-ports 8443 -m comment --comment prism-hy2-hop-20000-30000-dnat")
+
+	// 主機未啟用 IPv6 時不補齊
+	runner.calls = nil
+	i.ipv6 = func() bool { return false }
+	plan, err = i.Reconcile(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Equal(t, "-udp/8443", plan.String())
+}
+
+func TestIPv6RedirectFailureIsReported(t *testing.T) {
+	runner := newFakeRunner()
+	runner.errs["nft add table ip6 prism_nat_v6"] = errors.New("ip6 nat not supported")
+	n := newNFTables(zap.NewNop(), runner)
+	hop := Rule{Protocol: "udp", Start: 20000, End: 30000, RedirectTo: 8443}
+
+	// 主機未啟用 IPv6: 僅記錄日誌
+	_, err := n.Reconcile(context.Background(), []Rule{hop})
+	require.NoError(t, err)
+
+	// 主機啟用 IPv6: IPv6 重定向失敗必須報錯
+	runner.calls = nil
+	n.ipv6 = func() bool { return true }
+	_, err = n.Reconcile(context.Background(), []Rule{hop})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ipv6")
+}
@@ -0,0 +1,155 @@
+package firewall
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rule 一條由 Prism 管理的防火牆規則
+// RedirectTo > 0 時表示端口跳躍的 NAT 重定向規則，否則為放行規則
+type Rule struct {
+	Protocol   string // tcp / udp
+	Start      int
+	End        int // 單端口時與 Start 相同
+	RedirectTo int
+}
+
+// PortRule 單端口放行規則
+func PortRule(port int, protocol string) Rule {
+	return Rule{Protocol: protocol, Start: port, End: port}
+}
+
+// RangeRule 端口範圍放行規則
+func RangeRule(start, end int, protocol string) Rule {
+	return Rule{Protocol: protocol, Start: start, End: end}
+}
+
+// HoppingRules Hysteria2 端口跳躍所需的規則 (範圍放行 + 重定向到監聽端口)
+func HoppingRules(listenPort, start, end int) []Rule {
+	return []Rule{
+		RangeRule(start, end, "udp"),
+		{Protocol: "udp", Start: start, End: end, RedirectTo: listenPort},
+	}
+}
+
+// IsRange 是否為端口範圍
+func (r Rule) IsRange() bool {
+	return r.End > r.Start
+}
+
+// IsRedirect 是否為重定向規則
+func (r Rule) IsRedirect() bool {
+	return r.RedirectTo > 0
+}
+
+// String 規則的唯一標識，如 tcp/443、udp/20000-30000、udp/20000-30000->443
+func (r Rule) String() string {
+	s := fmt.Sprintf("%s/%d", r.Protocol, r.Start)
+	if r.IsRange() {
+		s = fmt.Sprintf("%s/%d-%d", r.Protocol, r.Start, r.End)
+	}
+	if r.IsRedirect() {
+		s += fmt.Sprintf("->%d", r.RedirectTo)
+	}
+	return s
+}
+
+// Normalize 展開 both 協議、去重並排序
+func Normalize(rules []Rule) []Rule {
+	seen := make(map[string]bool)
+	var out []Rule
+	for _, r := range rules {
+		if r.End < r.Start {
+			r.End = r.Start
+		}
+		protocols := []string{strings.ToLower(r.Protocol)}
+		if protocols[0] == "both" {
+			protocols = []string{"tcp", "udp"}
+		}
+		for _, p := range protocols {
+			nr := r
+			nr.Protocol = p
+			if key := nr.String(); !seen[key] {
+				seen[key] = true
+				out = append(out, nr)
+			}
+		}
+	}
+	sortRules(out)
+	return out
+}
+
+func sortRules(rules []Rule) {
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.RedirectTo < b.RedirectTo
+	})
+}
+
+// Plan 同步計劃
+type Plan struct {
+	Add    []Rule
+	Remove []Rule
+	Repair []Rule // 已存在但缺少部分地址族 (如只有 IPv4) 的規則
+}
+
+// IsEmpty 是否無需變更
+func (p Plan) IsEmpty() bool {
+	return len(p.Add) == 0 && len(p.Remove) == 0 && len(p.Repair) == 0
+}
+
+// String 返回便於日誌輸出的摘要
+func (p Plan) String() string {
+	if p.IsEmpty() {
+		return "無變更"
+	}
+	var parts []string
+	for _, r := range p.Add {
+		parts = append(parts, "+"+r.String())
+	}
+	for _, r := range p.Remove {
+		parts = append(parts, "-"+r.String())
+	}
+	for _, r := range p.Repair {
+		parts = append(parts, "~"+r.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// Diff 計算從當前規則到期望規則需要的增刪
+func Diff(current, desired []Rule) Plan {
+	current = Normalize(current)
+	desired = Normalize(desired)
+
+	have := make(map[string]bool, len(current))
+	for _, r := range current {
+		have[r.String()] = true
+	}
+	want := make(map[string]bool, len(desired))
+	for _, r := range desired {
+		want[r.String()] = true
+	}
+
+	var plan Plan
+	for _, r := range desired {
+		if !have[r.String()] {
+			plan.Add = append(plan.Add, r)
+		}
+	}
+	for _, r := range current {
+		if !want[r.String()] {
+			plan.Remove = append(plan.Remove, r)
+		}
+	}
+	return plan
+}
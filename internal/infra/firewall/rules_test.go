@@ -0,0 +1,37 @@
+package firewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	rules := Normalize([]Rule{
+		PortRule(443, "both"),
+		PortRule(443, "tcp"), // 重複
+		RangeRule(20000, 30000, "udp"),
+		{Protocol: "UDP", Start: 8443},
+	})
+
+	var keys []string
+	for _, r := range rules {
+		keys = append(keys, r.String())
+	}
+	assert.Equal(t, []string{"tcp/443", "udp/443", "udp/8443", "udp/20000-30000"}, keys)
+}
+
+func TestDiff(t *testing.T) {
+	current := []Rule{
+		PortRule(443, "tcp"),
+		PortRule(8443, "udp"),
+		{Protocol: "udp", Start: 20000, End: 30000, RedirectTo: 8443},
+	}
+	desired := append([]Rule{PortRule(443, "tcp"), PortRule(9443, "udp")}, HoppingRules(9443, 20000, 30000)...)
+
+	plan := Diff(current, desired)
+	assert.Equal(t, []Rule{PortRule(9443, "udp"), RangeRule(20000, 30000, "udp"), {Protocol: "udp", Start: 20000, End: 30000, RedirectTo: 9443}}, plan.Add)
+	assert.Equal(t, []Rule{PortRule(8443, "udp"), {Protocol: "udp", Start: 20000, End: 30000, RedirectTo: 8443}}, plan.Remove)
+
+	assert.True(t, Diff(desired, desired).IsEmpty())
+}
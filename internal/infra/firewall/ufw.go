@@ -2,30 +2,39 @@ package firewall
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"os/exec"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
-	"sync"
 
 	"go.uber.org/zap"
-
-	"github.com/Yat-Muk/prism-v2/internal/pkg/errors"
 )
 
+// ufw 規則備註
+const ufwComment = "prism"
+
+// "443/tcp", "20000:30000/udp"
+var ufwPortPattern = regexp.MustCompile(`^(\d+)(?::(\d+))?/(tcp|udp)$`)
+
 type UFWManager struct {
-	log         *zap.Logger
-	openedPorts map[int]bool
-	mu          sync.Mutex
+	*ruleManager
+	rulesDir string // ufw 配置目錄，端口跳躍規則寫入其中的 before.rules / before6.rules
+	reload   bool   // 規則文件有變更，commit 時需要 ufw reload
 }
 
 func NewUFW(log *zap.Logger) *UFWManager {
-	return &UFWManager{
-		log:         log,
-		openedPorts: make(map[int]bool),
-	}
+	u := newUFW(log, execRunner{})
+	u.ipv6 = hostIPv6Enabled
+	return u
+}
+
+func newUFW(log *zap.Logger, runner Runner) *UFWManager {
+	u := &UFWManager{rulesDir: "/etc/ufw"}
+	u.ruleManager = &ruleManager{log: log, run: runner, ops: u}
+	return u
 }
 
 func (u *UFWManager) Type() string {
@@ -34,146 +43,171 @@ func (u *UFWManager) Type() string {
 
 func (u *UFWManager) Capabilities() Capabilities {
 	return Capabilities{
-		SupportIPv6:        true,  // ufw 支持
-		SupportPortHopping: false, // ufw 無法做 DNAT
+		SupportIPv6:        true, // ufw 支持
+		SupportPortHopping: true, // 寫入 before.rules / before6.rules 的 nat 表
 		SupportComment:     true,
 		SupportBoth:        false,
+
+		SupportSourceFilter: true,
+		SupportRateLimit:    false, // ufw limit 僅支持固定閾值
+		SupportGeoIP:        false,
 	}
 }
 
-func (u *UFWManager) OpenPort(ctx context.Context, port int, protocol string) error {
-	u.mu.Lock()
-	defer u.mu.Unlock()
+// "[ 3] 443/tcp   DENY IN   203.0.113.0/24   # prism-policy"
+var ufwNumberedPattern = regexp.MustCompile(`^\[\s*(\d+)\]`)
 
-	if protocol == "both" {
-		if err := u.openSinglePort(ctx, port, "tcp"); err != nil {
-			return err
-		}
-		return u.openSinglePort(ctx, port, "udp")
+// ufwSpec 規則的 ufw 表示，如 8080/tcp、1000:2000/udp
+func ufwSpec(r Rule) string {
+	if r.IsRange() {
+		return fmt.Sprintf("%d:%d/%s", r.Start, r.End, r.Protocol)
 	}
-	return u.openSinglePort(ctx, port, protocol)
+	return fmt.Sprintf("%d/%s", r.Start, r.Protocol)
 }
 
-func (u *UFWManager) openSinglePort(ctx context.Context, port int, protocol string) error {
-	// 格式規範：ufw 規則通常為 "8080/tcp"
-	portStr := fmt.Sprintf("%d/%s", port, strings.ToLower(protocol))
-	comment := "prism"
-
-	// 檢查規則是否已存在 (避免重複添加)
-	if u.checkRuleExists(ctx, portStr) {
-		u.openedPorts[port] = true
-		return nil
+// list 解析 ufw status 中帶 "# prism" 備註的規則
+// IPv6 規則 (v6) 與 IPv4 規則同時添加和刪除，這裡忽略
+func (u *UFWManager) list(ctx context.Context) ([]installedRule, error) {
+	output, err := u.run.Run(ctx, "ufw", "status")
+	if err != nil {
+		return nil, fmt.Errorf("獲取 ufw 狀態失敗: %w", err)
 	}
 
-	// 添加規則: ufw allow 8080/tcp comment 'prism'
-	cmd := exec.CommandContext(ctx, "ufw", "allow", portStr, "comment", comment)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("添加規則失敗: %s", string(output))
+	var rules []installedRule
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasSuffix(line, "# "+ufwComment) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[1] == "(v6)" {
+			continue
+		}
+		m := ufwPortPattern.FindStringSubmatch(fields[0])
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[1])
+		end := start
+		if m[2] != "" {
+			end, _ = strconv.Atoi(m[2])
+		}
+		r := Rule{Protocol: m[3], Start: start, End: end}
+		rules = append(rules, installedRule{
+			Rule:    r,
+			deletes: [][]string{{"ufw", "--force", "delete", "allow", ufwSpec(r)}},
+		})
 	}
 
-	u.openedPorts[port] = true
-	return nil
-}
-
-func (u *UFWManager) OpenPortRange(ctx context.Context, startPort, endPort int, protocol string) error {
-	u.mu.Lock()
-	defer u.mu.Unlock()
-
-	rangeStr := fmt.Sprintf("%d:%d/%s", startPort, endPort, strings.ToLower(protocol))
-
-	// ufw allow 1000:2000/tcp comment 'prism'
-	cmd := exec.CommandContext(ctx, "ufw", "allow", rangeStr, "comment", "prism")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("添加範圍規則失敗: %s", string(output))
+	for _, family := range []string{familyIPv4, familyIPv6} {
+		redirects, err := u.listRedirects(family)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, redirects...)
 	}
-
-	return nil
-}
-
-func (u *UFWManager) OpenHysteria2PortHopping(ctx context.Context, listenPort, startPort, endPort int) error {
-	u.log.Warn("⚠️ ufw 不支持端口跳躍功能 (NAT)",
-		zap.Int("listen", listenPort),
-		zap.Int("start", startPort),
-		zap.Int("end", endPort),
-		zap.String("建議", "請禁用 UFW 並使用 iptables 模式"))
-	return errors.New("FIREWALL_UNSUPPORTED", "ufw 不支持端口跳躍 (DNAT)，請切換到 iptables 模式")
+	return rules, nil
 }
 
-func (u *UFWManager) GetOpenedPorts() []int {
-	u.mu.Lock()
-	defer u.mu.Unlock()
-
-	ports := make([]int, 0, len(u.openedPorts))
-	for port := range u.openedPorts {
-		ports = append(ports, port)
+func (u *UFWManager) add(ctx context.Context, r Rule) error {
+	if r.IsRedirect() {
+		if err := u.addFamily(ctx, r, familyIPv4); err != nil {
+			return err
+		}
+		if !u.ipv6Enabled() {
+			return nil
+		}
+		return u.addFamily(ctx, r, familyIPv6)
+	}
+	// ufw allow 8080/tcp comment 'prism'
+	if _, err := u.run.Run(ctx, "ufw", "allow", ufwSpec(r), "comment", ufwComment); err != nil {
+		return fmt.Errorf("添加規則失敗: %w", err)
 	}
-	sort.Ints(ports)
-	return ports
-}
-
-func (u *UFWManager) SaveRules(ctx context.Context) error {
-	// UFW 自動持久化規則，無需手動 save
-	u.log.Info("ufw 規則已自動生效")
 	return nil
 }
 
-// FlushRules 清空規則 (穩健版)
-func (u *UFWManager) FlushRules(ctx context.Context) error {
+// ApplyPolicies 刪除舊的策略規則後重新插入到規則列表頂部
+func (u *UFWManager) ApplyPolicies(ctx context.Context, policies []Policy) error {
+	policies, unsupported := supportedPolicies(u.Capabilities(), policies)
+
 	u.mu.Lock()
 	defer u.mu.Unlock()
 
-	u.log.Info("開始清空 ufw 的 prism 規則")
-
-	// 1. 獲取當前狀態
-	cmd := exec.CommandContext(ctx, "ufw", "status")
-	output, err := cmd.Output()
+	output, err := u.run.Run(ctx, "ufw", "status", "numbered")
 	if err != nil {
 		return fmt.Errorf("獲取 ufw 狀態失敗: %w", err)
 	}
 
-	// 2. 解析並收集需要刪除的規則
-	scanner := bufio.NewScanner(bytes.NewReader(output))
-	var rulesToDelete []string
-
+	// 從後往前刪除，避免編號變化
+	var numbers []int
+	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if strings.Contains(line, "# prism") {
-			fields := strings.Fields(line)
-			if len(fields) > 0 {
-				target := fields[0]
-				if target != "To" && target != "--" {
-					rulesToDelete = append(rulesToDelete, target)
-				}
-			}
+		if !strings.HasSuffix(line, "# "+policyComment) {
+			continue
+		}
+		if m := ufwNumberedPattern.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(numbers)))
+	for _, n := range numbers {
+		if _, err := u.run.Run(ctx, "ufw", "--force", "delete", strconv.Itoa(n)); err != nil {
+			return fmt.Errorf("刪除舊策略失敗: %w", err)
 		}
 	}
 
-	// 3. 執行刪除
-	successCount := 0
-	for _, ruleTarget := range rulesToDelete {
-		delCmd := exec.CommandContext(ctx, "ufw", "--force", "delete", "allow", ruleTarget)
-		if out, err := delCmd.CombinedOutput(); err != nil {
-			u.log.Debug("刪除 ufw 規則失敗",
-				zap.String("target", ruleTarget),
-				zap.String("output", string(out)))
-		} else {
-			successCount++
+	// prepend 每次插入到頂部，因此倒序添加
+	rules := ufwPolicyRules(policies)
+	for k := len(rules) - 1; k >= 0; k-- {
+		args := append([]string{"prepend"}, rules[k]...)
+		args = append(args, "comment", policyComment)
+		if _, err := u.run.Run(ctx, "ufw", args...); err != nil {
+			return fmt.Errorf("應用源地址策略失敗: %w", err)
 		}
 	}
+	return unsupported
+}
 
-	u.openedPorts = make(map[int]bool)
-	u.log.Info("ufw 規則清理統計", zap.Int("deleted", successCount))
-	return nil
+// ufwPolicyRules 按匹配順序生成策略規則: 豁免放行 -> 黑名單 -> 白名單放行 -> 其餘拒絕
+func ufwPolicyRules(policies []Policy) [][]string {
+	var rules [][]string
+	for _, p := range policies {
+		target := []string{"to", "any", "port", strconv.Itoa(p.Port), "proto", p.Protocol}
+		for _, cidr := range p.Exempt {
+			rules = append(rules, append([]string{"allow", "from", cidr}, target...))
+		}
+		for _, cidr := range p.Deny {
+			rules = append(rules, append([]string{"deny", "from", cidr}, target...))
+		}
+		if len(p.Allow) > 0 {
+			for _, cidr := range p.Allow {
+				rules = append(rules, append([]string{"allow", "from", cidr}, target...))
+			}
+			rules = append(rules, append([]string{"deny"}, target...))
+		}
+	}
+	return rules
 }
 
-// 輔助函數：檢查規則是否存在
-func (u *UFWManager) checkRuleExists(ctx context.Context, portStr string) bool {
-	cmd := exec.CommandContext(ctx, "ufw", "status")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
+// commit 規則文件有變更時重新加載 ufw
+func (u *UFWManager) commit(ctx context.Context) error {
+	if !u.reload {
+		return nil
 	}
-	return strings.Contains(string(output), portStr)
+	if _, err := u.run.Run(ctx, "ufw", "reload"); err != nil {
+		return fmt.Errorf("重載 ufw 失敗: %w", err)
+	}
+	u.reload = false
+	return nil
+}
+
+func (u *UFWManager) SaveRules(ctx context.Context) error {
+	// UFW 自動持久化規則，無需手動 save
+	u.log.Info("ufw 規則已自動生效")
+	return nil
 }
 
 // Backend 實現
@@ -0,0 +1,143 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ufw 命令行無法配置 NAT，端口跳躍的重定向規則寫入 before.rules 頂部的獨立 nat 段
+// Prism 只維護標記之間的內容，文件其餘部分保持不變
+const (
+	ufwNatBegin = "# BEGIN prism-hy2-hop"
+	ufwNatEnd   = "# END prism-hy2-hop"
+)
+
+// ufwRulesFiles 各地址族對應的規則文件
+var ufwRulesFiles = map[string]string{familyIPv4: "before.rules", familyIPv6: "before6.rules"}
+
+func (u *UFWManager) rulesFile(family string) string {
+	return filepath.Join(u.rulesDir, ufwRulesFiles[family])
+}
+
+// ufwRedirectLine 重定向規則在規則文件中的表示
+func ufwRedirectLine(r Rule) string {
+	args := append([]string{"-A", "PREROUTING"}, iptablesMatchArgs(r)...)
+	args = append(args,
+		"-m", "comment", "--comment", fmt.Sprintf("prism-hy2-hop-%d-%d", r.Start, r.End),
+		"-j", "REDIRECT", "--to-ports", fmt.Sprint(r.RedirectTo))
+	return strings.Join(args, " ")
+}
+
+// splitNatBlock 將規則文件拆分為 Prism 段內的規則行與其餘內容
+func splitNatBlock(content string) (rules []string, rest []string) {
+	inBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == ufwNatBegin:
+			inBlock = true
+		case trimmed == ufwNatEnd:
+			inBlock = false
+		case inBlock:
+			if strings.HasPrefix(trimmed, "-A ") {
+				rules = append(rules, trimmed)
+			}
+		default:
+			rest = append(rest, line)
+		}
+	}
+	return rules, rest
+}
+
+// listRedirects 讀取規則文件中 Prism 段的重定向規則，文件不存在時視為無規則
+func (u *UFWManager) listRedirects(family string) ([]installedRule, error) {
+	content, err := os.ReadFile(u.rulesFile(family))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("讀取 %s 失敗: %w", ufwRulesFiles[family], err)
+	}
+
+	lines, _ := splitNatBlock(string(content))
+	parsed := parseIPTablesSave("", "*nat\n"+strings.Join(lines, "\n"))
+
+	rules := make([]installedRule, 0, len(parsed))
+	for _, ir := range parsed {
+		r := ir.Rule
+		rules = append(rules, installedRule{
+			Rule:     r,
+			families: []string{family},
+			undo: []func(ctx context.Context) error{func(ctx context.Context) error {
+				return u.editNatBlock(family, func(lines []string) []string {
+					var kept []string
+					for _, l := range lines {
+						if l != ufwRedirectLine(r) {
+							kept = append(kept, l)
+						}
+					}
+					return kept
+				})
+			}},
+		})
+	}
+	return rules, nil
+}
+
+// addFamily 在指定地址族的規則文件中添加重定向規則
+func (u *UFWManager) addFamily(ctx context.Context, r Rule, family string) error {
+	if !r.IsRedirect() {
+		// ufw allow 同時作用於 IPv4 與 IPv6
+		return u.add(ctx, r)
+	}
+	line := ufwRedirectLine(r)
+	return u.editNatBlock(family, func(lines []string) []string {
+		for _, l := range lines {
+			if l == line {
+				return lines
+			}
+		}
+		return append(lines, line)
+	})
+}
+
+// editNatBlock 修改 Prism 段中的規則並寫回文件，段為空時整段移除
+func (u *UFWManager) editNatBlock(family string, edit func([]string) []string) error {
+	path := u.rulesFile(family)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("讀取 %s 失敗: %w", ufwRulesFiles[family], err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("讀取 %s 失敗: %w", ufwRulesFiles[family], err)
+	}
+
+	lines, rest := splitNatBlock(string(content))
+	lines = edit(lines)
+
+	// nat 段必須位於 *filter 之前，因此放在文件頂部
+	var sb strings.Builder
+	if len(lines) > 0 {
+		sb.WriteString(ufwNatBegin + "\n*nat\n:PREROUTING ACCEPT [0:0]\n")
+		for _, l := range lines {
+			sb.WriteString(l + "\n")
+		}
+		sb.WriteString("COMMIT\n" + ufwNatEnd + "\n")
+	}
+	sb.WriteString(strings.Join(rest, "\n"))
+
+	tmp := path + ".prism.tmp"
+	if err := os.WriteFile(tmp, []byte(sb.String()), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("寫入 %s 失敗: %w", ufwRulesFiles[family], err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("寫入 %s 失敗: %w", ufwRulesFiles[family], err)
+	}
+	u.reload = true
+	return nil
+}
@@ -0,0 +1,65 @@
+package firewall
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const ufwBeforeRules = `# rules.before
+*filter
+:ufw-before-input - [0:0]
+COMMIT
+`
+
+func TestUFWPortHoppingInBeforeRules(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"before.rules", "before6.rules"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(ufwBeforeRules), 0640))
+	}
+
+	runner := newFakeRunner()
+	u := newUFW(zap.NewNop(), runner)
+	u.rulesDir = dir
+	u.ipv6 = func() bool { return true }
+
+	desired := append(HoppingRules(8443, 20000, 30000), HoppingRules(8443, 40000, 41000)...)
+	_, err := u.Reconcile(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Contains(t, runner.calls, "ufw reload")
+
+	for _, name := range []string{"before.rules", "before6.rules"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		text := string(content)
+		assert.True(t, strings.HasPrefix(text, ufwNatBegin+"\n*nat\n"), name)
+		assert.Contains(t, text, "-A PREROUTING -p udp -m multiport --dports 20000:30000 -m comment --comment prism-hy2-hop-20000-30000 -j REDIRECT --to-ports 8443")
+		assert.Contains(t, text, "--dports 40000:41000")
+		assert.True(t, strings.HasSuffix(text, ufwBeforeRules), "原有規則應保持不變")
+	}
+
+	// 讀回的規則包含兩個地址族，再次同步無變更
+	rules, err := u.CurrentRules(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, rules, Rule{Protocol: "udp", Start: 40000, End: 41000, RedirectTo: 8443})
+
+	// 移除一個範圍後只保留另一個，全部移除後恢復原文件
+	_, err = u.Reconcile(context.Background(), HoppingRules(8443, 20000, 30000))
+	require.NoError(t, err)
+	content, _ := os.ReadFile(filepath.Join(dir, "before6.rules"))
+	assert.NotContains(t, string(content), "40000:41000")
+	assert.Contains(t, string(content), "20000:30000")
+
+	_, err = u.Reconcile(context.Background(), nil)
+	require.NoError(t, err)
+	for _, name := range []string{"before.rules", "before6.rules"} {
+		content, _ := os.ReadFile(filepath.Join(dir, name))
+		assert.Equal(t, ufwBeforeRules, string(content), name)
+	}
+}
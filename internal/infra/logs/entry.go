@@ -0,0 +1,193 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 日誌來源
+const (
+	OriginPrism   = "prism"
+	OriginSingbox = "sing-box"
+)
+
+// Level 日誌級別
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = map[Level]string{
+	LevelTrace: "TRACE",
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+	LevelFatal: "FATAL",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return "INFO"
+}
+
+// ParseLevel 解析級別名稱 (不區分大小寫)
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "TRACE":
+		return LevelTrace, true
+	case "DEBUG":
+		return LevelDebug, true
+	case "INFO":
+		return LevelInfo, true
+	case "WARN", "WARNING":
+		return LevelWarn, true
+	case "ERROR":
+		return LevelError, true
+	case "FATAL", "PANIC", "DPANIC":
+		return LevelFatal, true
+	}
+	return LevelInfo, false
+}
+
+// Entry 單條日誌記錄
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Inbound string // 入站標籤 (sing-box 連接日誌)
+	Message string
+	Fields  map[string]string // 結構化字段 (zap 字段或從 sing-box 消息中提取)
+	Origin  string            // 來源: prism / sing-box
+	Raw     string            // 原始行
+}
+
+// String 返回用於顯示的單行文本
+func (e Entry) String() string {
+	ts := ""
+	if !e.Time.IsZero() {
+		ts = e.Time.Format("01-02 15:04:05") + " "
+	}
+	return ts + "[" + e.Level.String() + "] " + e.Message
+}
+
+// Detail 返回包含來源與結構化字段的完整描述
+func (e Entry) Detail() string {
+	var sb strings.Builder
+	if !e.Time.IsZero() {
+		sb.WriteString(e.Time.Local().Format("2006-01-02 15:04:05") + " ")
+	}
+	sb.WriteString(fmt.Sprintf("%-5s ", e.Level))
+	if e.Origin != "" {
+		sb.WriteString("[" + e.Origin + "] ")
+	}
+	sb.WriteString(e.Message)
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		if k != "caller" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(" " + k + "=" + e.Fields[k])
+	}
+	return sb.String()
+}
+
+var (
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	// sing-box 默認格式: "+0800 2024-01-02 15:04:05 INFO [123 0ms] inbound/hysteria2[hysteria2-in]: ..."
+	singboxLinePattern = regexp.MustCompile(`^(?:[+-]\d{4} )?(?:(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) )?(TRACE|DEBUG|INFO|WARN|ERROR|FATAL|PANIC)\s+(.*)$`)
+
+	inboundPattern     = regexp.MustCompile(`inbound/[\w-]+\[([^\]]+)\]`)
+	outboundPattern    = regexp.MustCompile(`outbound/[\w-]+\[([^\]]+)\]`)
+	destinationPattern = regexp.MustCompile(`connection (?:to|from) (\[[0-9a-fA-F:.]+\]|[^\s:]+):(\d+)`)
+)
+
+// ParseSingboxLine 解析 sing-box 文本日誌行
+// 無法識別級別時默認為 INFO，時間留空由調用方補充
+func ParseSingboxLine(line string) Entry {
+	line = strings.TrimRight(ansiPattern.ReplaceAllString(line, ""), "\r\n")
+
+	e := Entry{Level: LevelInfo, Message: line}
+	if m := singboxLinePattern.FindStringSubmatch(line); m != nil {
+		if m[1] != "" {
+			if t, err := time.ParseInLocation("2006-01-02 15:04:05", m[1], time.Local); err == nil {
+				e.Time = t
+			}
+		}
+		e.Level, _ = ParseLevel(m[2])
+		e.Message = m[3]
+	}
+
+	if m := inboundPattern.FindStringSubmatch(e.Message); m != nil {
+		e.Inbound = m[1]
+	}
+	e.Fields = singboxFields(e.Message)
+	e.Origin = OriginSingbox
+	e.Raw = line
+	return e
+}
+
+// singboxFields 從 sing-box 消息中提取可查詢的字段
+func singboxFields(message string) map[string]string {
+	fields := make(map[string]string)
+	if m := inboundPattern.FindStringSubmatch(message); m != nil {
+		fields["inbound"] = m[1]
+	}
+	if m := outboundPattern.FindStringSubmatch(message); m != nil {
+		fields["outbound"] = m[1]
+	}
+	if m := destinationPattern.FindStringSubmatch(message); m != nil {
+		host := strings.Trim(m[1], "[]")
+		if strings.Contains(message, "connection to") {
+			fields["domain"] = host
+			fields["port"] = m[2]
+		} else {
+			fields["source"] = host
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// Filter 日誌過濾條件，零值匹配所有記錄
+type Filter struct {
+	MinLevel Level
+	Inbound  string // 入站標籤 (精確匹配)
+	Search   string // 關鍵字 (不區分大小寫)
+}
+
+// IsEmpty 是否未設置任何條件
+func (f Filter) IsEmpty() bool {
+	return f.MinLevel == LevelTrace && f.Inbound == "" && f.Search == ""
+}
+
+// Match 判斷記錄是否滿足過濾條件
+func (f Filter) Match(e Entry) bool {
+	if e.Level < f.MinLevel {
+		return false
+	}
+	if f.Inbound != "" && e.Inbound != f.Inbound {
+		return false
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(e.Message), strings.ToLower(f.Search)) {
+		return false
+	}
+	return true
+}
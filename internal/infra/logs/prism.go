@@ -0,0 +1,132 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// zap 時間格式 (ISO8601TimeEncoder)
+const zapTimeLayout = "2006-01-02T15:04:05.000Z0700"
+
+// ParsePrismLine 解析 Prism (zap) 日誌行
+// 支持 JSON 編碼 (文件輸出) 與 console 編碼 (制表符分隔)
+func ParsePrismLine(line string) (Entry, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	clean := ansiPattern.ReplaceAllString(line, "")
+
+	if strings.HasPrefix(strings.TrimSpace(clean), "{") {
+		if e, ok := parseZapJSON(clean); ok {
+			e.Raw = line
+			return e, true
+		}
+	}
+
+	if e, ok := parseZapConsole(clean); ok {
+		e.Raw = line
+		return e, true
+	}
+	return Entry{}, false
+}
+
+// ParseLine 解析任意來源的日誌行
+// 無法識別為 zap 格式時按 sing-box 格式處理
+func ParseLine(line string) Entry {
+	if e, ok := ParsePrismLine(line); ok {
+		return e
+	}
+	return ParseSingboxLine(line)
+}
+
+func parseZapJSON(line string) (Entry, bool) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return Entry{}, false
+	}
+
+	e := Entry{Level: LevelInfo, Origin: OriginPrism}
+	if lv, ok := raw["level"].(string); ok {
+		e.Level, _ = ParseLevel(ansiPattern.ReplaceAllString(lv, ""))
+	}
+	if ts, ok := raw["ts"]; ok {
+		e.Time = parseZapTime(ts)
+	}
+	if msg, ok := raw["msg"].(string); ok {
+		e.Message = msg
+	}
+
+	for k, v := range raw {
+		switch k {
+		case "level", "ts", "msg", "stacktrace":
+			continue
+		}
+		if e.Fields == nil {
+			e.Fields = make(map[string]string)
+		}
+		if s, ok := v.(string); ok {
+			e.Fields[k] = s
+		} else {
+			e.Fields[k] = fmt.Sprint(v)
+		}
+	}
+	if in := e.Fields["inbound"]; in != "" {
+		e.Inbound = in
+	}
+	return e, true
+}
+
+// parseZapConsole 解析 "時間\t級別\t調用方\t消息\t{字段}" 格式
+func parseZapConsole(line string) (Entry, bool) {
+	parts := strings.Split(line, "\t")
+	if len(parts) < 3 {
+		return Entry{}, false
+	}
+
+	t, err := time.Parse(zapTimeLayout, parts[0])
+	if err != nil {
+		return Entry{}, false
+	}
+	level, ok := ParseLevel(parts[1])
+	if !ok {
+		return Entry{}, false
+	}
+
+	e := Entry{Time: t, Level: level, Origin: OriginPrism}
+
+	rest := parts[2:]
+	// 調用方形如 "pkg/file.go:12"，可能被關閉
+	if len(rest) > 1 && strings.Contains(rest[0], ".go:") {
+		e.Fields = map[string]string{"caller": rest[0]}
+		rest = rest[1:]
+	}
+	e.Message = rest[0]
+
+	if len(rest) > 1 {
+		if fe, ok := parseZapJSON(rest[len(rest)-1]); ok {
+			for k, v := range fe.Fields {
+				if e.Fields == nil {
+					e.Fields = make(map[string]string)
+				}
+				e.Fields[k] = v
+			}
+			e.Inbound = fe.Inbound
+		}
+	}
+	return e, true
+}
+
+func parseZapTime(v interface{}) time.Time {
+	switch ts := v.(type) {
+	case string:
+		for _, layout := range []string{zapTimeLayout, time.RFC3339Nano} {
+			if t, err := time.Parse(layout, ts); err == nil {
+				return t
+			}
+		}
+	case float64:
+		sec := int64(ts)
+		return time.Unix(sec, int64((ts-float64(sec))*1e9))
+	}
+	return time.Time{}
+}
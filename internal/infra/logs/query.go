@@ -0,0 +1,393 @@
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默認返回的最大記錄數
+const DefaultQueryLimit = 500
+
+// lumberjack 輪轉文件名中的時間格式
+const rotatedTimeLayout = "2006-01-02T15-04-05.000"
+
+// Query 日誌查詢條件
+type Query struct {
+	Filter
+	Since  time.Time         // 起始時間 (含)
+	Until  time.Time         // 結束時間 (不含)
+	Fields map[string]string // 字段匹配，值為子串匹配 (不區分大小寫)
+	Limit  int               // 最多返回最近的 N 條，<=0 使用默認值
+	Origin string            // 限定來源: prism / sing-box，留空查詢全部
+}
+
+// ParseQuery 解析查詢表達式
+// 語法: key=value 形式的條件與普通關鍵字，以空格分隔
+//
+//	level=warn since=2h until=2024-01-02T15:00 inbound=hysteria2-in domain=google limit=100 timeout
+//
+// since / until 支持相對時長 (30m、2h、7d) 與絕對時間；source=prism|sing-box 限定來源
+func ParseQuery(expr string, now time.Time) (Query, error) {
+	var q Query
+	var words []string
+
+	for _, tok := range strings.Fields(expr) {
+		key, value, ok := strings.Cut(tok, "=")
+		if !ok || key == "" {
+			words = append(words, tok)
+			continue
+		}
+		key = strings.ToLower(key)
+
+		switch key {
+		case "level":
+			level, ok := ParseLevel(value)
+			if !ok {
+				return Query{}, fmt.Errorf("未知的日誌級別: %s", value)
+			}
+			q.MinLevel = level
+		case "since", "until":
+			t, err := parseQueryTime(value, now)
+			if err != nil {
+				return Query{}, err
+			}
+			if key == "since" {
+				q.Since = t
+			} else {
+				q.Until = t
+			}
+		case "inbound":
+			q.Inbound = value
+		case "source":
+			switch strings.ToLower(value) {
+			case OriginPrism, OriginSingbox:
+				q.Origin = strings.ToLower(value)
+			case "all":
+				q.Origin = ""
+			default:
+				return Query{}, fmt.Errorf("未知的日誌來源: %s", value)
+			}
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return Query{}, fmt.Errorf("無效的 limit: %s", value)
+			}
+			q.Limit = n
+		default:
+			if q.Fields == nil {
+				q.Fields = make(map[string]string)
+			}
+			q.Fields[key] = value
+		}
+	}
+
+	q.Search = strings.Join(words, " ")
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		return Query{}, fmt.Errorf("結束時間必須晚於起始時間")
+	}
+	return q, nil
+}
+
+// parseQueryTime 解析相對時長或絕對時間
+func parseQueryTime(s string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(s, "d") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("無法解析時間: %s", s)
+}
+
+// Match 判斷記錄是否滿足查詢條件
+func (q Query) Match(e Entry) bool {
+	if !q.Filter.Match(e) {
+		return false
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		// 設置了時間範圍時，無法確定時間的記錄不參與匹配
+		if e.Time.IsZero() {
+			return false
+		}
+		if !q.Since.IsZero() && e.Time.Before(q.Since) {
+			return false
+		}
+		if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+			return false
+		}
+	}
+	for k, want := range q.Fields {
+		got, ok := e.Fields[k]
+		if !ok || !strings.Contains(strings.ToLower(got), strings.ToLower(want)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q Query) limit() int {
+	if q.Limit <= 0 {
+		return DefaultQueryLimit
+	}
+	return q.Limit
+}
+
+// collector 保留最近 limit 條匹配記錄
+type collector struct {
+	limit   int
+	entries []Entry
+}
+
+func (c *collector) add(e Entry) {
+	c.entries = append(c.entries, e)
+	if len(c.entries) > c.limit*2 {
+		c.entries = append([]Entry(nil), c.entries[len(c.entries)-c.limit:]...)
+	}
+}
+
+func (c *collector) result() []Entry {
+	if len(c.entries) > c.limit {
+		return c.entries[len(c.entries)-c.limit:]
+	}
+	return c.entries
+}
+
+// ========================================
+// 文件查詢
+// ========================================
+
+// RotatedFiles 返回日誌文件及其 lumberjack 輪轉文件 (含 .gz)，按時間從舊到新排列
+// 輪轉文件命名: <name>-<2006-01-02T15-04-05.000><ext>[.gz]
+func RotatedFiles(path string) []string {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	var backups []string
+	entries, _ := os.ReadDir(dir)
+	for _, de := range entries {
+		name := de.Name()
+		if de.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if _, ok := rotatedAt(name, prefix, ext); ok {
+			backups = append(backups, filepath.Join(dir, name))
+		}
+	}
+	// 時間戳格式固定，按名稱排序即按時間排序
+	sort.Strings(backups)
+
+	if _, err := os.Stat(path); err == nil {
+		backups = append(backups, path)
+	}
+	return backups
+}
+
+// rotatedAt 從輪轉文件名解析輪轉時間
+func rotatedAt(name, prefix, ext string) (time.Time, bool) {
+	ts := strings.TrimPrefix(name, prefix)
+	ts = strings.TrimSuffix(ts, ".gz")
+	if !strings.HasSuffix(ts, ext) {
+		return time.Time{}, false
+	}
+	ts = strings.TrimSuffix(ts, ext)
+	t, err := time.Parse(rotatedTimeLayout, ts)
+	if err != nil {
+		// lumberjack 開啟 LocalTime 時使用本地時間
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// SearchFiles 在日誌文件 (含輪轉與壓縮文件) 中查詢
+func SearchFiles(ctx context.Context, path string, q Query) ([]Entry, error) {
+	c := &collector{limit: q.limit()}
+	err := scanFiles(ctx, path, q, func(e Entry) {
+		c.add(e)
+	})
+	return c.result(), err
+}
+
+// CountFiles 統計日誌文件 (含輪轉與壓縮文件) 中匹配的記錄數
+func CountFiles(ctx context.Context, path string, q Query) (int, error) {
+	count := 0
+	err := scanFiles(ctx, path, q, func(Entry) {
+		count++
+	})
+	return count, err
+}
+
+func scanFiles(ctx context.Context, path string, q Query, fn func(Entry)) error {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	files := RotatedFiles(path)
+	if len(files) == 0 {
+		return fmt.Errorf("日誌文件不存在: %s", path)
+	}
+
+	for _, file := range files {
+		// 輪轉文件只包含輪轉時間之前的記錄，早於起始時間的可直接跳過
+		if rotated, ok := rotatedAt(filepath.Base(file), prefix, ext); ok && !q.Since.IsZero() && rotated.Before(q.Since) {
+			continue
+		}
+		if err := scanFile(ctx, file, q, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanFile(ctx context.Context, path string, q Query, fn func(Entry)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打開日誌文件失敗: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("解壓日誌文件失敗 %s: %w", filepath.Base(path), err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	n := 0
+	return scanLines(r, func(line []byte) bool {
+		// 定期檢查取消
+		if n++; n%1000 == 0 && ctx.Err() != nil {
+			return false
+		}
+		if len(line) == 0 {
+			return true
+		}
+		if e := ParseLine(string(line)); q.Match(e) {
+			fn(e)
+		}
+		return true
+	})
+}
+
+// ========================================
+// journal 查詢
+// ========================================
+
+// SearchJournal 在 systemd 單元日誌中查詢
+func SearchJournal(ctx context.Context, unit string, q Query) ([]Entry, error) {
+	const journalTimeLayout = "2006-01-02 15:04:05"
+
+	args := []string{"-u", unit, "-o", "json", "--no-pager"}
+	if !q.Since.IsZero() {
+		args = append(args, "--since", q.Since.Local().Format(journalTimeLayout))
+	}
+	if !q.Until.IsZero() {
+		args = append(args, "--until", q.Until.Local().Format(journalTimeLayout))
+	}
+	if q.MinLevel == LevelTrace && len(q.Fields) == 0 && q.Search == "" && q.Inbound == "" {
+		// 無內容過濾時直接讓 journalctl 截取最近的記錄
+		args = append(args, "-n", strconv.Itoa(q.limit()))
+	}
+
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("創建輸出管道失敗: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("啟動 journalctl 失敗: %w", err)
+	}
+
+	c := &collector{limit: q.limit()}
+	scanErr := scanLines(bufio.NewReader(stdout), func(line []byte) bool {
+		if e, ok := parseJournalLine(line); ok && q.Match(e) {
+			c.add(e)
+		}
+		return true
+	})
+	waitErr := cmd.Wait()
+
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	if waitErr != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("journalctl 執行失敗: %w", waitErr)
+	}
+	return c.result(), nil
+}
+
+// Merge 按時間合併多個來源的結果，並保留最近 limit 條
+func Merge(limit int, sets ...[]Entry) []Entry {
+	var all []Entry
+	for _, s := range sets {
+		all = append(all, s...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Time.Before(all[j].Time)
+	})
+	if limit > 0 && len(all) > limit {
+		all = all[len(all)-limit:]
+	}
+	return all
+}
+
+// Searcher 組合查詢 Prism 與 sing-box 日誌
+type Searcher struct {
+	PrismLog    string // Prism 日誌文件
+	SingboxUnit string // sing-box systemd 單元
+	SingboxLog  string // journal 不可用時使用的 sing-box 日誌文件
+}
+
+// Search 按查詢條件搜索各來源並按時間合併
+// 部分來源失敗時返回其餘來源的結果，全部失敗時返回錯誤
+func (s *Searcher) Search(ctx context.Context, q Query) ([]Entry, error) {
+	var sets [][]Entry
+	var errs []string
+
+	if q.Origin == "" || q.Origin == OriginPrism {
+		if entries, err := SearchFiles(ctx, s.PrismLog, q); err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			sets = append(sets, entries)
+		}
+	}
+
+	if q.Origin == "" || q.Origin == OriginSingbox {
+		var entries []Entry
+		var err error
+		if _, ok := NewSource(s.SingboxUnit, s.SingboxLog).(*JournalSource); ok {
+			entries, err = SearchJournal(ctx, s.SingboxUnit, q)
+		} else {
+			entries, err = SearchFiles(ctx, s.SingboxLog, q)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			sets = append(sets, entries)
+		}
+	}
+
+	if len(sets) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("查詢日誌失敗: %s", strings.Join(errs, "; "))
+	}
+	return Merge(q.limit(), sets...), nil
+}
@@ -0,0 +1,122 @@
+package logs
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrismLine(t *testing.T) {
+	e, ok := ParsePrismLine(`{"level":"\u001b[31mERROR\u001b[0m","ts":"2024-01-02T15:04:05.000+0800","caller":"app/x.go:1","msg":"證書續期失敗","domain":"example.com","attempt":3}`)
+	require.True(t, ok)
+	assert.Equal(t, LevelError, e.Level)
+	assert.Equal(t, OriginPrism, e.Origin)
+	assert.Equal(t, "example.com", e.Fields["domain"])
+	assert.Equal(t, "3", e.Fields["attempt"])
+	assert.Equal(t, 2024, e.Time.Year())
+
+	e, ok = ParsePrismLine("2024-01-02T15:04:05.000+0800\tWARN\tapp/y.go:9\t重試中\t{\"inbound\":\"tuic-in\"}")
+	require.True(t, ok)
+	assert.Equal(t, LevelWarn, e.Level)
+	assert.Equal(t, "重試中", e.Message)
+	assert.Equal(t, "tuic-in", e.Inbound)
+
+	_, ok = ParsePrismLine("INFO plain sing-box line")
+	assert.False(t, ok)
+
+	sb := ParseLine("+0000 2024-01-02 15:04:05 INFO [1 0ms] inbound/vless[reality-vision-in]: inbound connection to www.google.com:443")
+	assert.Equal(t, OriginSingbox, sb.Origin)
+	assert.Equal(t, "www.google.com", sb.Fields["domain"])
+	assert.Equal(t, "443", sb.Fields["port"])
+}
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	q, err := ParseQuery("level=warn since=2h inbound=tuic-in domain=google limit=10 dial timeout", now)
+	require.NoError(t, err)
+	assert.Equal(t, LevelWarn, q.MinLevel)
+	assert.Equal(t, now.Add(-2*time.Hour), q.Since)
+	assert.Equal(t, "tuic-in", q.Inbound)
+	assert.Equal(t, "google", q.Fields["domain"])
+	assert.Equal(t, 10, q.Limit)
+	assert.Equal(t, "dial timeout", q.Search)
+
+	q, err = ParseQuery("since=1d until=2024-01-02T11:00", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -1), q.Since)
+	assert.Equal(t, 11, q.Until.Hour())
+
+	for _, bad := range []string{"level=loud", "since=yesterday", "limit=-1", "since=1h until=2h"} {
+		_, err := ParseQuery(bad, now)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSearchFilesRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prism.log")
+
+	// 舊的壓縮輪轉文件
+	gzPath := filepath.Join(dir, "prism-2024-01-01T00-00-00.000.log.gz")
+	f, err := os.Create(gzPath)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, _ = gz.Write([]byte(`{"level":"ERROR","ts":"2023-12-31T23:00:00.000Z","msg":"old error"}` + "\n"))
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	// 較新的未壓縮輪轉文件
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prism-2024-01-02T00-00-00.000.log"),
+		[]byte(`{"level":"INFO","ts":"2024-01-01T12:00:00.000Z","msg":"hello"}`+"\n"+
+			`{"level":"ERROR","ts":"2024-01-01T13:00:00.000Z","msg":"mid error","domain":"example.com"}`+"\n"), 0644))
+
+	require.NoError(t, os.WriteFile(path,
+		[]byte(`{"level":"ERROR","ts":"2024-01-02T08:00:00.000Z","msg":"new error"}`+"\n"), 0644))
+
+	files := RotatedFiles(path)
+	require.Len(t, files, 3)
+	assert.Equal(t, path, files[2])
+
+	ctx := context.Background()
+	got, err := SearchFiles(ctx, path, Query{Filter: Filter{MinLevel: LevelError}})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, "old error", got[0].Message)
+	assert.Equal(t, "new error", got[2].Message)
+
+	// 時間範圍會跳過較早輪轉的文件
+	since := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	got, err = SearchFiles(ctx, path, Query{Filter: Filter{MinLevel: LevelError}, Since: since})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	got, err = SearchFiles(ctx, path, Query{Fields: map[string]string{"domain": "example"}})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "mid error", got[0].Message)
+
+	n, err := CountFiles(ctx, path, Query{Filter: Filter{MinLevel: LevelError}, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	got, err = SearchFiles(ctx, path, Query{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "new error", got[0].Message)
+}
+
+func TestMerge(t *testing.T) {
+	t0 := time.Now()
+	a := []Entry{{Time: t0, Message: "a"}, {Time: t0.Add(2 * time.Second), Message: "c"}}
+	b := []Entry{{Time: t0.Add(time.Second), Message: "b"}}
+	got := Merge(2, a, b)
+	require.Len(t, got, 2)
+	assert.Equal(t, "b", got[0].Message)
+	assert.Equal(t, "c", got[1].Message)
+}
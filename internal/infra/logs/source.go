@@ -0,0 +1,321 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source 日誌來源
+type Source interface {
+	// Name 來源描述，用於界面顯示
+	Name() string
+	// Follow 先輸出最近 backlog 條記錄，然後持續跟蹤新日誌
+	// 阻塞直到 ctx 取消、來源結束或 emit 返回 false
+	Follow(ctx context.Context, backlog int, emit func(Entry) bool) error
+}
+
+// NewSource 根據環境選擇日誌來源
+// 優先使用 systemd journal，不可用時回退到日誌文件
+func NewSource(unit, logFile string) Source {
+	if _, err := exec.LookPath("journalctl"); err == nil {
+		if _, err := os.Stat("/run/systemd/system"); err == nil {
+			return &JournalSource{Unit: unit}
+		}
+	}
+	return &FileSource{Path: logFile}
+}
+
+// Stream 在後臺跟蹤日誌來源
+// 通道容量有限：消費方處理不及時會阻塞讀取，由此實現背壓
+// 日誌通道在結束時關閉，之後可從錯誤通道讀取結束原因 (正常結束為 nil)
+func Stream(ctx context.Context, src Source, backlog, buffer int) (<-chan Entry, <-chan error) {
+	entries := make(chan Entry, buffer)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+		defer close(entries)
+
+		err := src.Follow(ctx, backlog, func(e Entry) bool {
+			select {
+			case entries <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if ctx.Err() != nil {
+			err = nil
+		}
+		errCh <- err
+	}()
+
+	return entries, errCh
+}
+
+// ========================================
+// systemd journal
+// ========================================
+
+// JournalSource 通過 journalctl 讀取 systemd 單元日誌
+type JournalSource struct {
+	Unit string
+}
+
+func (s *JournalSource) Name() string {
+	return "journalctl -u " + s.Unit
+}
+
+func (s *JournalSource) Follow(ctx context.Context, backlog int, emit func(Entry) bool) error {
+	cmd := exec.CommandContext(ctx, "journalctl",
+		"-u", s.Unit, "-o", "json", "-f", "--no-pager", "-n", strconv.Itoa(backlog))
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("創建輸出管道失敗: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("啟動 journalctl 失敗: %w", err)
+	}
+
+	scanErr := scanLines(stdout, func(line []byte) bool {
+		e, ok := parseJournalLine(line)
+		if !ok {
+			return true
+		}
+		return emit(e)
+	})
+
+	// emit 提前結束時，需終止進程以免阻塞在寫管道上
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+
+	if scanErr != nil {
+		return scanErr
+	}
+	if ctx.Err() == nil && waitErr != nil {
+		return fmt.Errorf("journalctl 異常退出: %w", waitErr)
+	}
+	return nil
+}
+
+// journalRecord journalctl -o json 輸出的字段
+// MESSAGE 含非 UTF-8 字符時會被編碼為字節數組
+type journalRecord struct {
+	Message   json.RawMessage `json:"MESSAGE"`
+	Priority  string          `json:"PRIORITY"`
+	Timestamp string          `json:"__REALTIME_TIMESTAMP"`
+}
+
+// parseJournalLine 解析一行 journal JSON
+func parseJournalLine(line []byte) (Entry, bool) {
+	var rec journalRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return Entry{}, false
+	}
+
+	var message string
+	if err := json.Unmarshal(rec.Message, &message); err != nil {
+		var raw []byte
+		if err := json.Unmarshal(rec.Message, &raw); err != nil {
+			return Entry{}, false
+		}
+		message = string(raw)
+	}
+
+	e := ParseSingboxLine(message)
+
+	// 消息中沒有級別標記時使用 syslog 優先級
+	if !singboxLinePattern.MatchString(ansiPattern.ReplaceAllString(message, "")) {
+		e.Level = priorityLevel(rec.Priority)
+	}
+
+	if e.Time.IsZero() {
+		if usec, err := strconv.ParseInt(rec.Timestamp, 10, 64); err == nil {
+			e.Time = time.UnixMicro(usec)
+		}
+	}
+	return e, true
+}
+
+// priorityLevel syslog 優先級轉換為日誌級別
+func priorityLevel(p string) Level {
+	n, err := strconv.Atoi(p)
+	if err != nil {
+		return LevelInfo
+	}
+	switch {
+	case n <= 2:
+		return LevelFatal
+	case n == 3:
+		return LevelError
+	case n == 4:
+		return LevelWarn
+	case n == 7:
+		return LevelDebug
+	default:
+		return LevelInfo
+	}
+}
+
+// ========================================
+// 日誌文件
+// ========================================
+
+// FileSource 跟蹤普通日誌文件 (類似 tail -F)
+type FileSource struct {
+	Path         string
+	PollInterval time.Duration // 默認 500ms
+}
+
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+func (s *FileSource) Follow(ctx context.Context, backlog int, emit func(Entry) bool) error {
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("打開日誌文件失敗: %w", err)
+	}
+	defer func() { f.Close() }()
+
+	// 1. 輸出文件末尾的歷史記錄
+	lines, offset, err := tailLines(f, backlog)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if !emit(ParseSingboxLine(line)) {
+			return nil
+		}
+	}
+
+	// 2. 輪詢追加內容，處理截斷與輪轉
+	reader := bufio.NewReader(f)
+	var pending strings.Builder
+	for {
+		chunk, err := reader.ReadString('\n')
+		if chunk != "" {
+			offset += int64(len(chunk))
+			pending.WriteString(chunk)
+		}
+		if err == nil {
+			if !emit(ParseSingboxLine(pending.String())) {
+				return nil
+			}
+			pending.Reset()
+			continue
+		}
+		if err != io.EOF {
+			return fmt.Errorf("讀取日誌文件失敗: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		if reopened, ok := s.reopenIfRotated(f, offset); ok {
+			f = reopened
+			offset = 0
+			pending.Reset()
+			reader.Reset(f)
+		}
+	}
+}
+
+// reopenIfRotated 文件被截斷或替換時重新打開
+func (s *FileSource) reopenIfRotated(f *os.File, offset int64) (*os.File, bool) {
+	cur, err := f.Stat()
+	if err != nil {
+		return nil, false
+	}
+	latest, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, false
+	}
+	if os.SameFile(cur, latest) && latest.Size() >= offset {
+		return nil, false
+	}
+
+	nf, err := os.Open(s.Path)
+	if err != nil {
+		return nil, false
+	}
+	f.Close()
+	return nf, true
+}
+
+// tailLines 讀取文件末尾最多 n 行，並返回讀取結束時的偏移量
+func tailLines(f *os.File, n int) ([]string, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+
+	const maxTail = 1 << 20
+	start := int64(0)
+	if size > maxTail {
+		start = size - maxTail
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, size-start))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 只保留完整的行，未以換行結尾的部分留給後續跟蹤讀取
+	end := strings.LastIndexByte(string(data), '\n') + 1
+	text := string(data[:end])
+	if start > 0 {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	if n >= 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	offset := start + int64(end)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	return lines, offset, nil
+}
+
+// scanLines 按行讀取，允許單行最長 1MB
+func scanLines(r io.Reader, fn func([]byte) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if !fn(scanner.Bytes()) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
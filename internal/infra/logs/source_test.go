@@ -0,0 +1,94 @@
+package logs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSingboxLine(t *testing.T) {
+	e := ParseSingboxLine("\x1b[36m+0800 2024-01-02 15:04:05 \x1b[31mERROR\x1b[0m [3942 0ms] inbound/hysteria2[hysteria2-in]: process connection: EOF\n")
+	assert.Equal(t, LevelError, e.Level)
+	assert.Equal(t, "hysteria2-in", e.Inbound)
+	assert.Equal(t, 2024, e.Time.Year())
+	assert.Contains(t, e.Message, "process connection")
+
+	plain := ParseSingboxLine("something without level")
+	assert.Equal(t, LevelInfo, plain.Level)
+	assert.Equal(t, "something without level", plain.Message)
+}
+
+func TestParseJournalLine(t *testing.T) {
+	e, ok := parseJournalLine([]byte(`{"MESSAGE":"WARN [1 0ms] inbound/vless[reality-vision-in]: tls handshake","PRIORITY":"6","__REALTIME_TIMESTAMP":"1704179045000000"}`))
+	require.True(t, ok)
+	assert.Equal(t, LevelWarn, e.Level)
+	assert.Equal(t, "reality-vision-in", e.Inbound)
+	assert.False(t, e.Time.IsZero())
+
+	// 無級別標記時使用 PRIORITY；MESSAGE 為字節數組
+	e, ok = parseJournalLine([]byte(`{"MESSAGE":[104,105],"PRIORITY":"3","__REALTIME_TIMESTAMP":"1"}`))
+	require.True(t, ok)
+	assert.Equal(t, LevelError, e.Level)
+	assert.Equal(t, "hi", e.Message)
+
+	_, ok = parseJournalLine([]byte("not json"))
+	assert.False(t, ok)
+}
+
+func TestFilterMatch(t *testing.T) {
+	e := Entry{Level: LevelWarn, Inbound: "tuic-in", Message: "Dial Timeout"}
+	assert.True(t, Filter{}.Match(e))
+	assert.True(t, Filter{MinLevel: LevelWarn, Inbound: "tuic-in", Search: "timeout"}.Match(e))
+	assert.False(t, Filter{MinLevel: LevelError}.Match(e))
+	assert.False(t, Filter{Inbound: "hysteria2-in"}.Match(e))
+}
+
+func TestFileSourceFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sing-box.log")
+	require.NoError(t, os.WriteFile(path, []byte("INFO one\nINFO two\nINFO three\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries, errCh := Stream(ctx, &FileSource{Path: path, PollInterval: 10 * time.Millisecond}, 2, 4)
+
+	next := func() Entry {
+		select {
+		case e := <-entries:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("等待日誌超時")
+		}
+		return Entry{}
+	}
+
+	assert.Equal(t, "two", next().Message)
+	assert.Equal(t, "three", next().Message)
+
+	// 追加寫入 (含不完整的行)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, _ = f.WriteString("ERROR fo")
+	_ = f.Sync()
+	time.Sleep(30 * time.Millisecond)
+	_, _ = f.WriteString("ur\n")
+	f.Close()
+
+	e := next()
+	assert.Equal(t, "four", e.Message)
+	assert.Equal(t, LevelError, e.Level)
+
+	// 截斷後從頭讀取
+	require.NoError(t, os.WriteFile(path, []byte("WARN five\n"), 0644))
+	assert.Equal(t, "five", next().Message)
+
+	cancel()
+	for range entries {
+	}
+	assert.NoError(t, <-errCh)
+}
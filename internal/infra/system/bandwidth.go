@@ -0,0 +1,162 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 帶寬推薦參數
+const (
+	// Hysteria2 的 QUIC 加解密在用戶態完成，單連接吞吐約為本機 TCP 回環的 1/10
+	quicCostFactor = 10
+	// 推薦值預留 20% 餘量，避免 Brutal 持續以超過線路的速率發送造成丟包
+	bandwidthHeadroom = 0.8
+	// 無法讀取網卡速率 (虛擬網卡通常如此) 時假設的線路上限
+	unknownLinkMbps  = 1000
+	minBandwidthMbps = 10
+)
+
+// BandwidthReport 帶寬檢測結果
+type BandwidthReport struct {
+	Interface    string  // 默認路由網卡
+	LinkMbps     int     // 網卡協商速率，0 表示未知
+	LoopbackMbps float64 // 本機 TCP 回環吞吐，反映 CPU 處理能力
+}
+
+// CPULimitMbps 按回環吞吐估算的 Hysteria2 處理上限
+func (r *BandwidthReport) CPULimitMbps() int {
+	return int(r.LoopbackMbps / quicCostFactor)
+}
+
+// RecommendedMbps 推薦的 Hysteria2 上下行帶寬
+// 取網卡速率與 CPU 上限中較小者並預留餘量，網卡速率未知時按 1 Gbps 估算
+func (r *BandwidthReport) RecommendedMbps() int {
+	limit := r.LinkMbps
+	if limit <= 0 {
+		limit = unknownLinkMbps
+	}
+	if cpu := r.CPULimitMbps(); cpu > 0 && cpu < limit {
+		limit = cpu
+	}
+	mbps := int(float64(limit)*bandwidthHeadroom) / 10 * 10
+	if mbps < minBandwidthMbps {
+		mbps = minBandwidthMbps
+	}
+	return mbps
+}
+
+// BandwidthMeter 讀取網卡速率並測試本機回環吞吐
+type BandwidthMeter struct {
+	procRoot string
+	sysRoot  string
+}
+
+// NewBandwidthMeter 創建帶寬檢測器
+func NewBandwidthMeter() *BandwidthMeter {
+	return &BandwidthMeter{procRoot: "/proc", sysRoot: "/sys"}
+}
+
+// Measure 檢測默認路由網卡速率與回環吞吐，測速持續 duration
+func (m *BandwidthMeter) Measure(ctx context.Context, duration time.Duration) (*BandwidthReport, error) {
+	report := &BandwidthReport{}
+	if iface, err := m.DefaultInterface(); err == nil {
+		report.Interface = iface
+		report.LinkMbps, _ = m.InterfaceSpeed(iface)
+	}
+
+	mbps, err := LoopbackThroughput(ctx, duration)
+	if err != nil {
+		return nil, err
+	}
+	report.LoopbackMbps = mbps
+	return report, nil
+}
+
+// DefaultInterface 從 /proc/net/route 讀取默認路由所在的網卡
+func (m *BandwidthMeter) DefaultInterface() (string, error) {
+	f, err := os.Open(filepath.Join(m.procRoot, "net", "route"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// Iface Destination Gateway ...，目標為 00000000 即默認路由
+		if len(fields) > 1 && fields[1] == "00000000" {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("未找到默認路由")
+}
+
+// InterfaceSpeed 讀取網卡協商速率 (Mbps)，虛擬網卡通常返回 -1 或無法讀取
+func (m *BandwidthMeter) InterfaceSpeed(iface string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(m.sysRoot, "class", "net", iface, "speed"))
+	if err != nil {
+		return 0, err
+	}
+	speed, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("網卡 %s 未報告速率", iface)
+	}
+	return speed, nil
+}
+
+// LoopbackThroughput 在 127.0.0.1 上建立 TCP 連接並持續發送 duration，返回吞吐 (Mbps)
+func LoopbackThroughput(ctx context.Context, duration time.Duration) (float64, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("創建測速監聽失敗: %w", err)
+	}
+	defer ln.Close()
+
+	// 接收端: 丟棄所有數據並統計字節數
+	received := make(chan int64, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- 0
+			return
+		}
+		defer conn.Close()
+		n, _ := io.Copy(io.Discard, conn)
+		received <- n
+	}()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", ln.Addr().String())
+	if err != nil {
+		return 0, fmt.Errorf("連接測速端失敗: %w", err)
+	}
+
+	buf := make([]byte, 128*1024)
+	start := time.Now()
+	deadline := start.Add(duration)
+	_ = conn.SetWriteDeadline(deadline)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		if _, err := conn.Write(buf); err != nil {
+			break
+		}
+	}
+	conn.Close()
+	elapsed := time.Since(start).Seconds()
+
+	n := <-received
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if n == 0 || elapsed <= 0 {
+		return 0, fmt.Errorf("測速未收到數據")
+	}
+	return float64(n) * 8 / elapsed / 1e6, nil
+}
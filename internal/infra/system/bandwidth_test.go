@@ -0,0 +1,71 @@
+package system
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBandwidthMeterInterface(t *testing.T) {
+	root := t.TempDir()
+	m := &BandwidthMeter{procRoot: filepath.Join(root, "proc"), sysRoot: filepath.Join(root, "sys")}
+
+	route := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n" +
+		"eth1\t00000000\t0100A8C0\t0003\t0\t0\t0\t00000000\t0\t0\t0\n"
+	files := map[string]string{
+		"proc/net/route":            route,
+		"sys/class/net/eth1/speed":  "10000\n",
+		"sys/class/net/virt0/speed": "-1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	iface, err := m.DefaultInterface()
+	if err != nil || iface != "eth1" {
+		t.Fatalf("默認路由網卡錯誤: %q %v", iface, err)
+	}
+	if speed, err := m.InterfaceSpeed("eth1"); err != nil || speed != 10000 {
+		t.Errorf("網卡速率錯誤: %d %v", speed, err)
+	}
+	if _, err := m.InterfaceSpeed("virt0"); err == nil {
+		t.Error("速率為 -1 時應返回錯誤")
+	}
+}
+
+func TestBandwidthRecommendation(t *testing.T) {
+	tests := []struct {
+		name   string
+		report BandwidthReport
+		want   int
+	}{
+		{"網卡為瓶頸", BandwidthReport{LinkMbps: 1000, LoopbackMbps: 40000}, 800},
+		{"CPU 為瓶頸", BandwidthReport{LinkMbps: 10000, LoopbackMbps: 20000}, 1600},
+		{"網卡速率未知", BandwidthReport{LoopbackMbps: 40000}, 800},
+		{"最低值", BandwidthReport{LinkMbps: 10, LoopbackMbps: 40000}, 10},
+	}
+	for _, tt := range tests {
+		if got := tt.report.RecommendedMbps(); got != tt.want {
+			t.Errorf("%s: 推薦 %d, 期望 %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoopbackThroughput(t *testing.T) {
+	mbps, err := LoopbackThroughput(context.Background(), 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("回環測速失敗: %v", err)
+	}
+	if mbps <= 0 {
+		t.Errorf("吞吐應大於 0: %f", mbps)
+	}
+}
@@ -0,0 +1,165 @@
+package system
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// /proc/net 中的套接字狀態
+const (
+	tcpStateListen = "0A" // TCP_LISTEN
+	udpStateBound  = "07" // TCP_CLOSE: 未連接的 UDP 套接字即處於監聽
+)
+
+// Listener 系統中正在監聽的端口
+type Listener struct {
+	Network string // tcp / udp
+	Address string // 監聽地址
+	Port    int
+	Inode   string
+	Process string // 進程名，無法識別時為空
+}
+
+// String 返回便於展示的描述，如 "8443/udp (nginx)"
+func (l Listener) String() string {
+	s := fmt.Sprintf("%d/%s", l.Port, l.Network)
+	if l.Process != "" {
+		s += " (" + l.Process + ")"
+	}
+	return s
+}
+
+// PortScanner 讀取 /proc/net/{tcp,tcp6,udp,udp6} 獲取監聽端口
+type PortScanner struct {
+	procRoot string
+}
+
+// NewPortScanner 創建端口掃描器
+func NewPortScanner() *PortScanner {
+	return &PortScanner{procRoot: "/proc"}
+}
+
+// Listeners 返回所有 TCP 監聽端口與已綁定的 UDP 端口，並盡量識別所屬進程
+func (s *PortScanner) Listeners() ([]Listener, error) {
+	files := []struct{ name, network, state string }{
+		{"tcp", "tcp", tcpStateListen},
+		{"tcp6", "tcp", tcpStateListen},
+		{"udp", "udp", udpStateBound},
+		{"udp6", "udp", udpStateBound},
+	}
+
+	var listeners []Listener
+	read := 0
+	for _, f := range files {
+		entries, err := parseProcNet(filepath.Join(s.procRoot, "net", f.name), f.network, f.state)
+		if err != nil {
+			// IPv6 未啟用時 tcp6/udp6 不存在
+			continue
+		}
+		read++
+		listeners = append(listeners, entries...)
+	}
+	if read == 0 {
+		return nil, fmt.Errorf("無法讀取 %s/net 下的端口信息", s.procRoot)
+	}
+
+	owners := s.socketOwners()
+	for i := range listeners {
+		listeners[i].Process = owners[listeners[i].Inode]
+	}
+	return listeners, nil
+}
+
+// parseProcNet 解析 /proc/net/tcp 格式的文件
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//	0: 00000000:01BB 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 12345 ...
+func parseProcNet(path, network, state string) ([]Listener, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var listeners []Listener
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // 表頭
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != state {
+			continue
+		}
+		addr, port, ok := parseHexAddr(fields[1])
+		if !ok {
+			continue
+		}
+		listeners = append(listeners, Listener{
+			Network: network,
+			Address: addr,
+			Port:    port,
+			Inode:   fields[9],
+		})
+	}
+	return listeners, scanner.Err()
+}
+
+// parseHexAddr 解析 "0100007F:1F90" 形式的地址 (IP 按 32 位小端分組存儲)
+func parseHexAddr(s string) (string, int, bool) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, false
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, false
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, false
+	}
+	for i := 0; i+4 <= len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return net.IP(raw).String(), int(port), true
+}
+
+// socketOwners 掃描 /proc/<pid>/fd 建立套接字 inode 到進程名的映射
+// 無權限讀取的進程會被跳過
+func (s *PortScanner) socketOwners() map[string]string {
+	owners := make(map[string]string)
+	procs, err := os.ReadDir(s.procRoot)
+	if err != nil {
+		return owners
+	}
+
+	for _, p := range procs {
+		if _, err := strconv.Atoi(p.Name()); err != nil {
+			continue
+		}
+		fdDir := filepath.Join(s.procRoot, p.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+
+		var comm string
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if comm == "" {
+				data, _ := os.ReadFile(filepath.Join(s.procRoot, p.Name(), "comm"))
+				comm = strings.TrimSpace(string(data))
+			}
+			owners[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = comm
+		}
+	}
+	return owners
+}
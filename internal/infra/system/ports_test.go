@@ -0,0 +1,70 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPortScannerListeners(t *testing.T) {
+	root := t.TempDir()
+	netDir := filepath.Join(root, "net")
+	if err := os.MkdirAll(netDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	header := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	files := map[string]string{
+		// 0.0.0.0:443 監聽, 127.0.0.1:8080 監聽, 已建立的連接應忽略
+		"tcp": header +
+			"   0: 00000000:01BB 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0 100 0 0 10 0\n" +
+			"   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0 100 0 0 10 0\n" +
+			"   2: 0100007F:1F90 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0 100 0 0 10 0\n",
+		// [::]:8443/udp
+		"udp6": header +
+			"   0: 00000000000000000000000000000000:20FB 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 2001 2 0 0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(netDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 模擬 pid 42 (nginx) 持有 inode 1001
+	fdDir := filepath.Join(root, "42", "fd")
+	if err := os.MkdirAll(fdDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("socket:[1001]", filepath.Join(fdDir, "3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "42", "comm"), []byte("nginx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := &PortScanner{procRoot: root}
+	listeners, err := scanner.Listeners()
+	if err != nil {
+		t.Fatalf("Listeners 失敗: %v", err)
+	}
+	if len(listeners) != 3 {
+		t.Fatalf("預期 3 個監聽端口，實際 %d: %+v", len(listeners), listeners)
+	}
+
+	if l := listeners[0]; l.Network != "tcp" || l.Port != 443 || l.Address != "0.0.0.0" || l.Process != "nginx" {
+		t.Errorf("解析錯誤: %+v", l)
+	}
+	if l := listeners[1]; l.Port != 8080 || l.Address != "127.0.0.1" || l.Process != "" {
+		t.Errorf("解析錯誤: %+v", l)
+	}
+	if l := listeners[2]; l.Network != "udp" || l.Port != 8443 || l.Address != "::" {
+		t.Errorf("解析錯誤: %+v", l)
+	}
+	if s := listeners[0].String(); s != "443/tcp (nginx)" {
+		t.Errorf("String 錯誤: %s", s)
+	}
+
+	if _, err := (&PortScanner{procRoot: t.TempDir()}).Listeners(); err == nil {
+		t.Error("無端口信息時應返回錯誤")
+	}
+}
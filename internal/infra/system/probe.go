@@ -0,0 +1,82 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrProbeUnsupported 未配置外部探測服務，無法判斷端口是否可達
+var ErrProbeUnsupported = errors.New("不支持的探測方式")
+
+// ErrProbeUnavailable 探測服務本身無法訪問 (DNS 失敗、超時、連接被拒等)，不代表端口被封鎖
+var ErrProbeUnavailable = errors.New("探測服務不可用")
+
+// ErrPortUnreachable 探測服務明確判定端口不可達 (返回非 2xx)
+var ErrPortUnreachable = errors.New("端口不可達")
+
+const defaultProbeTimeout = 5 * time.Second
+
+// ProbeTarget 可達性探測目標
+type ProbeTarget struct {
+	Network  string // tcp / udp
+	Host     string
+	Port     int
+	CheckURL string // 外部探測服務地址模板
+}
+
+// ReachabilityProber 檢測節點端口是否可達
+// 由外部探測服務 (如位於受限網絡內的探針) 判斷，返回 2xx 視為可達
+// 本機直連自身地址無法發現上游封鎖，且在 NAT 主機上必然失敗，因此不做本地探測
+type ReachabilityProber struct {
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewReachabilityProber 創建可達性探測器，timeout 為 0 時使用默認值
+func NewReachabilityProber(timeout time.Duration) *ReachabilityProber {
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	return &ReachabilityProber{
+		timeout: timeout,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Probe 探測目標端口
+// 探測服務判定不可達時返回 ErrPortUnreachable，探測服務無法訪問時返回 ErrProbeUnavailable
+func (p *ReachabilityProber) Probe(ctx context.Context, target ProbeTarget) error {
+	if target.CheckURL == "" {
+		return ErrProbeUnsupported
+	}
+
+	checkURL := strings.NewReplacer(
+		"{host}", url.QueryEscape(target.Host),
+		"{port}", strconv.Itoa(target.Port),
+		"{network}", target.Network,
+	).Replace(target.CheckURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: 創建探測請求失敗: %v", ErrProbeUnavailable, err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: 請求探測服務失敗: %v", ErrProbeUnavailable, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %d/%s (探測服務返回 %d)", ErrPortUnreachable, target.Port, target.Network, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReachabilityProberWithoutService(t *testing.T) {
+	p := NewReachabilityProber(time.Second)
+	for _, network := range []string{"tcp", "udp"} {
+		err := p.Probe(context.Background(), ProbeTarget{Network: network, Host: "127.0.0.1", Port: 443})
+		if !errors.Is(err, ErrProbeUnsupported) {
+			t.Errorf("未配置探測服務時應返回 ErrProbeUnsupported (%s), got %v", network, err)
+		}
+	}
+}
+
+func TestReachabilityProberRemote(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		if r.URL.Query().Get("port") == "443" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	p := NewReachabilityProber(time.Second)
+	target := ProbeTarget{
+		Network:  "udp",
+		Host:     "203.0.113.5",
+		Port:     443,
+		CheckURL: srv.URL + "/check?host={host}&port={port}&network={network}",
+	}
+
+	if err := p.Probe(context.Background(), target); err != nil {
+		t.Errorf("探測服務返回 200 應視為可達: %v", err)
+	}
+	if gotQuery != "host=203.0.113.5&port=443&network=udp" {
+		t.Errorf("佔位符替換錯誤: %s", gotQuery)
+	}
+
+	target.Port = 8443
+	if err := p.Probe(context.Background(), target); !errors.Is(err, ErrPortUnreachable) {
+		t.Errorf("探測服務返回 503 應視為不可達, got %v", err)
+	}
+
+	srv.Close()
+	if err := p.Probe(context.Background(), target); !errors.Is(err, ErrProbeUnavailable) {
+		t.Errorf("探測服務無法訪問時應返回 ErrProbeUnavailable, got %v", err)
+	}
+}
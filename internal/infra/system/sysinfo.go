@@ -151,6 +151,27 @@ func (s *SystemInfo) refreshPublicIPs() {
 	wg.Wait()
 }
 
+// PublicIPv4 返回公網 IPv4，後台尚未獲取到時同步查詢一次 (供非交互流程使用)
+func (s *SystemInfo) PublicIPv4() string {
+	s.ipMutex.RLock()
+	ip := s.publicIPv4
+	s.ipMutex.RUnlock()
+	if ip != "" {
+		return ip
+	}
+
+	ip = s.fetchIPFromAPI("https://4.ipw.cn")
+	if ip == "" {
+		ip = s.getIPv4FromCmd()
+	}
+	if ip != "" {
+		s.ipMutex.Lock()
+		s.publicIPv4 = ip
+		s.ipMutex.Unlock()
+	}
+	return ip
+}
+
 // fetchIPFromAPI 通用 HTTP 獲取 IP 函數
 func (s *SystemInfo) fetchIPFromAPI(url string) string {
 	client := &http.Client{
@@ -0,0 +1,183 @@
+package warp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Client Cloudflare WARP 設備註冊客戶端
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	log        *zap.Logger
+}
+
+// NewClient 創建 WARP 客戶端
+// baseURL 為空時使用 Cloudflare 官方 API，測試時可指向本地模擬服務
+func NewClient(baseURL string, log *zap.Logger) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIBase
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		log:        log,
+	}
+}
+
+// Register 註冊新設備
+// 流程：本地生成密鑰對 -> 上傳公鑰 -> 解析分配的地址、reserved 與接入點
+func (c *Client) Register(ctx context.Context) (*Account, error) {
+	privateKey, publicKey, err := GenerateKeypair()
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody := registerRequest{
+		Key:    publicKey,
+		TOS:    time.Now().UTC().Format(time.RFC3339),
+		Model:  "PC",
+		Type:   "Android",
+		Locale: "en_US",
+	}
+
+	var resp deviceResponse
+	if err := c.do(ctx, http.MethodPost, "/reg", "", reqBody, &resp); err != nil {
+		return nil, fmt.Errorf("註冊 WARP 設備失敗: %w", err)
+	}
+
+	if resp.ID == "" || resp.Token == "" {
+		return nil, fmt.Errorf("註冊 WARP 設備失敗: 響應缺少設備 ID 或令牌")
+	}
+
+	acct, err := accountFromResponse(&resp)
+	if err != nil {
+		return nil, err
+	}
+	acct.PrivateKey = privateKey
+	acct.PublicKey = publicKey
+
+	c.log.Info("WARP 設備註冊成功",
+		zap.String("device_id", acct.DeviceID),
+		zap.String("account_type", acct.AccountType),
+	)
+
+	return acct, nil
+}
+
+// BindLicense 為已註冊設備綁定 WARP+ 許可證，並返回刷新後的賬戶信息
+func (c *Client) BindLicense(ctx context.Context, deviceID, token, license string) (*AccountInfo, error) {
+	if deviceID == "" || token == "" {
+		return nil, fmt.Errorf("設備未註冊，無法綁定許可證")
+	}
+
+	path := fmt.Sprintf("/reg/%s/account", deviceID)
+	if err := c.do(ctx, http.MethodPut, path, token, licenseRequest{License: license}, nil); err != nil {
+		return nil, fmt.Errorf("綁定 WARP+ 許可證失敗: %w", err)
+	}
+
+	return c.GetAccount(ctx, deviceID, token)
+}
+
+// GetAccount 查詢設備當前的賬戶狀態
+func (c *Client) GetAccount(ctx context.Context, deviceID, token string) (*AccountInfo, error) {
+	var acct AccountInfo
+	path := fmt.Sprintf("/reg/%s/account", deviceID)
+	if err := c.do(ctx, http.MethodGet, path, token, nil, &acct); err != nil {
+		return nil, fmt.Errorf("查詢 WARP 賬戶失敗: %w", err)
+	}
+	return &acct, nil
+}
+
+// do 發送 API 請求並解析 JSON 響應
+func (c *Client) do(ctx context.Context, method, path, token string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("序列化請求失敗: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("CF-Client-Version", clientVersion)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("讀取響應失敗: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析響應失敗: %w", err)
+	}
+	return nil
+}
+
+// accountFromResponse 將註冊響應轉換為 Account
+func accountFromResponse(resp *deviceResponse) (*Account, error) {
+	reserved, err := ReservedFromClientID(resp.Config.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	acct := &Account{
+		DeviceID:    resp.ID,
+		AccessToken: resp.Token,
+		ClientID:    resp.Config.ClientID,
+		Reserved:    reserved,
+		IPv4:        resp.Config.Interface.Addresses.V4,
+		IPv6:        resp.Config.Interface.Addresses.V6,
+		AccountType: resp.Account.AccountType,
+		WARPPlus:    resp.Account.WARPPlus,
+		License:     resp.Account.License,
+	}
+
+	if len(resp.Config.Peers) > 0 {
+		peer := resp.Config.Peers[0]
+		acct.PeerPublicKey = peer.PublicKey
+		acct.Endpoints = Endpoints{
+			V4:   peer.Endpoint.V4,
+			V6:   peer.Endpoint.V6,
+			Host: peer.Endpoint.Host,
+		}
+	}
+
+	return acct, nil
+}
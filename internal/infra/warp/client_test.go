@@ -0,0 +1,144 @@
+package warp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockAPI 模擬 Cloudflare WARP API
+func newMockAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	accountType := AccountTypeFree
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reg", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, clientVersion, r.Header.Get("CF-Client-Version"))
+
+		var req registerRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.NotEmpty(t, req.Key)
+
+		_, _ = w.Write([]byte(`{
+			"id": "device-1",
+			"token": "token-1",
+			"account": {"id": "acc-1", "account_type": "free", "warp_plus": false},
+			"config": {
+				"client_id": "` + base64.StdEncoding.EncodeToString([]byte{1, 2, 3}) + `",
+				"interface": {"addresses": {"v4": "172.16.0.2", "v6": "2606:4700:110:8a36::1"}},
+				"peers": [{
+					"public_key": "peer-key",
+					"endpoint": {
+						"v4": "162.159.192.1:0",
+						"v6": "[2606:4700:d0::a29f:c001]:0",
+						"host": "engage.cloudflareclient.com:2408"
+					}
+				}]
+			}
+		}`))
+	})
+	mux.HandleFunc("/reg/device-1/account", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var req licenseRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.License != "valid-license" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":[{"message":"invalid license"}]}`))
+				return
+			}
+			accountType = AccountTypePlus
+			_, _ = w.Write([]byte(`{}`))
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(AccountInfo{
+				ID:          "acc-1",
+				AccountType: accountType,
+				WARPPlus:    accountType == AccountTypePlus,
+			})
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRegister(t *testing.T) {
+	srv := newMockAPI(t)
+	client := NewClient(srv.URL, nil)
+
+	acct, err := client.Register(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "device-1", acct.DeviceID)
+	assert.Equal(t, "token-1", acct.AccessToken)
+	assert.Equal(t, []int{1, 2, 3}, acct.Reserved)
+	assert.Equal(t, "172.16.0.2", acct.IPv4)
+	assert.Equal(t, "2606:4700:110:8a36::1", acct.IPv6)
+	assert.Equal(t, "peer-key", acct.PeerPublicKey)
+
+	// 本地私鑰應與上傳的公鑰匹配
+	pub, err := PublicKeyFromPrivate(acct.PrivateKey)
+	require.NoError(t, err)
+	assert.Equal(t, acct.PublicKey, pub)
+}
+
+func TestBindLicense(t *testing.T) {
+	srv := newMockAPI(t)
+	client := NewClient(srv.URL, nil)
+	ctx := context.Background()
+
+	info, err := client.BindLicense(ctx, "device-1", "token-1", "valid-license")
+	require.NoError(t, err)
+	assert.True(t, info.WARPPlus)
+	assert.Equal(t, AccountTypePlus, info.AccountType)
+
+	_, err = client.BindLicense(ctx, "device-1", "token-1", "bad-license")
+	assert.Error(t, err)
+
+	_, err = client.BindLicense(ctx, "", "", "valid-license")
+	assert.Error(t, err, "未註冊設備不應發起請求")
+}
+
+func TestSelectEndpoint(t *testing.T) {
+	acct := &Account{Endpoints: Endpoints{
+		V4:   "162.159.192.1:0",
+		V6:   "[2606:4700:d0::a29f:c001]:0",
+		Host: "engage.cloudflareclient.com:2408",
+	}}
+
+	assert.Equal(t, "162.159.192.1:2408", acct.SelectEndpoint(false))
+	assert.Equal(t, "[2606:4700:d0::a29f:c001]:2408", acct.SelectEndpoint(true))
+
+	acct.Endpoints.V6 = ""
+	assert.Equal(t, "162.159.192.1:2408", acct.SelectEndpoint(true), "IPv6 不可用時應回退到 IPv4")
+
+	empty := &Account{}
+	assert.Empty(t, empty.SelectEndpoint(false))
+}
+
+func TestReservedFromClientID(t *testing.T) {
+	reserved, err := ReservedFromClientID(base64.StdEncoding.EncodeToString([]byte{10, 200, 255}))
+	require.NoError(t, err)
+	assert.Equal(t, []int{10, 200, 255}, reserved)
+
+	_, err = ReservedFromClientID("not-base64!")
+	assert.Error(t, err)
+
+	reserved, err = ReservedFromClientID("")
+	assert.NoError(t, err)
+	assert.Nil(t, reserved)
+}
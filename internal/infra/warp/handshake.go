@@ -0,0 +1,174 @@
+package warp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// WireGuard 協議常量 (參考 https://www.wireguard.com/protocol/)
+const (
+	noiseConstruction = "Noise_IKpsk2_25519_ChaChaPoly_BLAKE2s"
+	wgIdentifier      = "WireGuard v1 zx2c4 Jason@zx2c4.com"
+	wgLabelMAC1       = "mac1----"
+
+	messageInitiationType = 1
+	messageResponseType   = 2
+	messageInitiationSize = 148
+	messageResponseSize   = 92
+)
+
+// BuildHandshakeInitiation 構造一個 WireGuard 握手發起包
+// WARP 服務端只會響應攜帶有效 MAC1 的握手包，因此需要完整執行 Noise IK 的發起方流程；
+// reserved 會寫入包頭的 3 個保留字節，用於 Cloudflare 識別設備
+func BuildHandshakeInitiation(privateKey, peerPublicKey string, reserved []int) ([]byte, error) {
+	staticPriv, err := decodeKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("無效的私鑰: %w", err)
+	}
+	peerPub, err := decodeKey(peerPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("無效的對端公鑰: %w", err)
+	}
+
+	staticPub, err := curve25519.X25519(staticPriv, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralPriv := make([]byte, 32)
+	if _, err := rand.Read(ephemeralPriv); err != nil {
+		return nil, err
+	}
+	ephemeralPub, err := curve25519.X25519(ephemeralPriv, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, messageInitiationSize)
+	msg[0] = messageInitiationType
+	for i := 0; i < 3 && i < len(reserved); i++ {
+		msg[1+i] = byte(reserved[i])
+	}
+	if _, err := rand.Read(msg[4:8]); err != nil { // sender index
+		return nil, err
+	}
+
+	// C = HASH(CONSTRUCTION), H = HASH(C || IDENTIFIER || S_pub_r)
+	chainKey := blake2s.Sum256([]byte(noiseConstruction))
+	h := mixHash(chainKey, []byte(wgIdentifier))
+	h = mixHash(h, peerPub)
+
+	// ephemeral
+	copy(msg[8:40], ephemeralPub)
+	chainKey = kdf1(chainKey[:], ephemeralPub)
+	h = mixHash(h, ephemeralPub)
+
+	// static
+	dh, err := curve25519.X25519(ephemeralPriv, peerPub)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	chainKey, key = kdf2(chainKey[:], dh)
+	sealed, err := aeadSeal(key, staticPub, h[:])
+	if err != nil {
+		return nil, err
+	}
+	copy(msg[40:88], sealed)
+	h = mixHash(h, sealed)
+
+	// timestamp
+	dh, err = curve25519.X25519(staticPriv, peerPub)
+	if err != nil {
+		return nil, err
+	}
+	_, key = kdf2(chainKey[:], dh)
+	sealed, err = aeadSeal(key, tai64n(time.Now()), h[:])
+	if err != nil {
+		return nil, err
+	}
+	copy(msg[88:116], sealed)
+
+	// mac1 = MAC(HASH(LABEL_MAC1 || S_pub_r), msg[:116])，mac2 保持全零
+	mac1Key := blake2s.Sum256(append([]byte(wgLabelMAC1), peerPub...))
+	mac, err := blake2s.New128(mac1Key[:])
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(msg[:116])
+	copy(msg[116:132], mac.Sum(nil))
+
+	return msg, nil
+}
+
+// isHandshakeResponse 判斷收到的數據是否為握手響應
+func isHandshakeResponse(data []byte) bool {
+	return len(data) == messageResponseSize && data[0] == messageResponseType
+}
+
+func decodeKey(key string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("密鑰長度錯誤: %d", len(raw))
+	}
+	return raw, nil
+}
+
+func mixHash(h [32]byte, data []byte) [32]byte {
+	return blake2s.Sum256(append(h[:], data...))
+}
+
+func newBlake2s() hash.Hash {
+	h, _ := blake2s.New256(nil)
+	return h
+}
+
+func hmacBlake2s(key, data []byte) []byte {
+	mac := hmac.New(newBlake2s, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func kdf1(key, input []byte) [32]byte {
+	var out [32]byte
+	prk := hmacBlake2s(key, input)
+	copy(out[:], hmacBlake2s(prk, []byte{0x1}))
+	return out
+}
+
+func kdf2(key, input []byte) ([32]byte, [32]byte) {
+	var t1, t2 [32]byte
+	prk := hmacBlake2s(key, input)
+	copy(t1[:], hmacBlake2s(prk, []byte{0x1}))
+	copy(t2[:], hmacBlake2s(prk, append(t1[:], 0x2)))
+	return t1, t2
+}
+
+func aeadSeal(key [32]byte, plaintext, ad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key[:])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	return aead.Seal(nil, nonce, plaintext, ad), nil
+}
+
+// tai64n 生成 12 字節 TAI64N 時間戳
+func tai64n(t time.Time) []byte {
+	buf := make([]byte, 12)
+	binary.BigEndian.PutUint64(buf[:8], uint64(0x400000000000000a)+uint64(t.Unix()))
+	binary.BigEndian.PutUint32(buf[8:], uint32(t.Nanosecond()))
+	return buf
+}
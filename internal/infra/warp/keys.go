@@ -0,0 +1,60 @@
+package warp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// GenerateKeypair 生成 WireGuard 密鑰對 (標準 Base64 編碼)
+func GenerateKeypair() (privateKey, publicKey string, err error) {
+	priv := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(priv); err != nil {
+		return "", "", fmt.Errorf("生成 WireGuard 私鑰失敗: %w", err)
+	}
+
+	// WireGuard 私鑰需按 X25519 規範做 clamp
+	priv[0] &= 248
+	priv[31] = (priv[31] & 127) | 64
+
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return "", "", fmt.Errorf("推導 WireGuard 公鑰失敗: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(priv), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// PublicKeyFromPrivate 由私鑰推導公鑰
+func PublicKeyFromPrivate(privateKey string) (string, error) {
+	priv, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil || len(priv) != curve25519.ScalarSize {
+		return "", fmt.Errorf("無效的 WireGuard 私鑰")
+	}
+
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return "", fmt.Errorf("推導 WireGuard 公鑰失敗: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// ReservedFromClientID 將 API 返回的 client_id 轉換為 reserved 字節
+// client_id 為 3 字節的 Base64 字符串，Cloudflare 用它識別同一出口 IP 下的不同設備
+func ReservedFromClientID(clientID string) ([]int, error) {
+	if clientID == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("解析 client_id 失敗: %w", err)
+	}
+	if len(raw) != 3 {
+		return nil, fmt.Errorf("client_id 長度錯誤: %d", len(raw))
+	}
+
+	return []int{int(raw[0]), int(raw[1]), int(raw[2])}, nil
+}
@@ -0,0 +1,300 @@
+package warp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 內置候選網段與端口 (Cloudflare WARP 公開接入點)
+var (
+	DefaultScanTargetsV4 = []string{
+		"162.159.192.0/24",
+		"162.159.193.0/24",
+		"162.159.195.0/24",
+		"188.114.96.0/24",
+		"188.114.97.0/24",
+		"188.114.98.0/24",
+		"188.114.99.0/24",
+	}
+	DefaultScanTargetsV6 = []string{
+		"2606:4700:d0::/48",
+		"2606:4700:d1::/48",
+	}
+	DefaultScanPorts = []int{2408, 500, 1701, 4500, 854, 859, 864, 878, 880, 890, 891, 894, 903, 908, 928, 934, 939, 942}
+)
+
+// ScanOptions 掃描參數
+type ScanOptions struct {
+	PrivateKey    string        // 用於構造握手包的本地私鑰
+	PeerPublicKey string        // Cloudflare 公鑰
+	Reserved      []int         // 設備 reserved 字節
+	Attempts      int           // 每個接入點的握手次數
+	Timeout       time.Duration // 單次握手超時
+	Concurrency   int           // 並發探測數
+}
+
+// ScanResult 單個接入點的探測結果
+type ScanResult struct {
+	Endpoint string
+	Sent     int
+	Received int
+	AvgRTT   time.Duration
+}
+
+// Loss 丟包率 (0-1)
+func (r ScanResult) Loss() float64 {
+	if r.Sent == 0 {
+		return 1
+	}
+	return float64(r.Sent-r.Received) / float64(r.Sent)
+}
+
+// Reachable 是否至少有一次握手成功
+func (r ScanResult) Reachable() bool {
+	return r.Received > 0
+}
+
+// IsIPv6 接入點是否為 IPv6 地址
+func (r ScanResult) IsIPv6() bool {
+	host, _, err := net.SplitHostPort(r.Endpoint)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() == nil
+}
+
+// Scanner WARP 接入點優選器
+// 向候選接入點發送 WireGuard 握手發起包，按丟包率與握手延遲排序
+type Scanner struct {
+	opts  ScanOptions
+	log   *zap.Logger
+	probe func(ctx context.Context, endpoint string, packet []byte, timeout time.Duration) (time.Duration, error)
+}
+
+// NewScanner 創建掃描器
+func NewScanner(opts ScanOptions, log *zap.Logger) (*Scanner, error) {
+	if opts.PrivateKey == "" {
+		// 未註冊時使用臨時密鑰，Cloudflare 仍會響應有效的握手包
+		priv, _, err := GenerateKeypair()
+		if err != nil {
+			return nil, err
+		}
+		opts.PrivateKey = priv
+	}
+	if opts.PeerPublicKey == "" {
+		return nil, fmt.Errorf("缺少 WARP 對端公鑰")
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 32
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	// 預先驗證密鑰，避免掃描過程中才發現配置錯誤
+	if _, err := BuildHandshakeInitiation(opts.PrivateKey, opts.PeerPublicKey, opts.Reserved); err != nil {
+		return nil, err
+	}
+
+	return &Scanner{opts: opts, log: log, probe: probeUDP}, nil
+}
+
+// Scan 探測所有候選接入點，返回按質量排序的結果 (最優在前)
+func (s *Scanner) Scan(ctx context.Context, endpoints []string) []ScanResult {
+	results := make([]ScanResult, len(endpoints))
+	sem := make(chan struct{}, s.opts.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = ScanResult{Endpoint: ep}
+				return
+			}
+			results[i] = s.scanOne(ctx, ep)
+		}(i, ep)
+	}
+	wg.Wait()
+
+	SortResults(results)
+	return results
+}
+
+// scanOne 對單個接入點進行多次握手探測
+func (s *Scanner) scanOne(ctx context.Context, endpoint string) ScanResult {
+	result := ScanResult{Endpoint: endpoint}
+	var total time.Duration
+
+	for i := 0; i < s.opts.Attempts; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		packet, err := BuildHandshakeInitiation(s.opts.PrivateKey, s.opts.PeerPublicKey, s.opts.Reserved)
+		if err != nil {
+			break
+		}
+
+		result.Sent++
+		rtt, err := s.probe(ctx, endpoint, packet, s.opts.Timeout)
+		if err != nil {
+			s.log.Debug("WARP 握手探測失敗", zap.String("endpoint", endpoint), zap.Error(err))
+			continue
+		}
+		result.Received++
+		total += rtt
+	}
+
+	if result.Received > 0 {
+		result.AvgRTT = total / time.Duration(result.Received)
+	}
+	return result
+}
+
+// SortResults 排序規則：可達優先 -> 丟包率低優先 -> 延遲低優先
+func SortResults(results []ScanResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Reachable() != b.Reachable() {
+			return a.Reachable()
+		}
+		if a.Loss() != b.Loss() {
+			return a.Loss() < b.Loss()
+		}
+		return a.AvgRTT < b.AvgRTT
+	})
+}
+
+// probeUDP 發送握手包並等待響應，返回往返時延
+func probeUDP(ctx context.Context, endpoint string, packet []byte, timeout time.Duration) (time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", endpoint)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	_ = conn.SetDeadline(deadline)
+
+	start := time.Now()
+	if _, err := conn.Write(packet); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 256)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		if isHandshakeResponse(buf[:n]) {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// BuildCandidates 根據目標列表與端口生成候選接入點
+// targets 支持單個 IP、IP:端口 或 CIDR 網段；網段內隨機抽取 perPrefix 個地址
+func BuildCandidates(targets []string, ports []int, perPrefix int) []string {
+	if len(ports) == 0 {
+		ports = DefaultScanPorts
+	}
+	if perPrefix <= 0 {
+		perPrefix = 4
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	add := func(ep string) {
+		if !seen[ep] {
+			seen[ep] = true
+			out = append(out, ep)
+		}
+	}
+
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+
+		// 已指定端口
+		if host, port, err := net.SplitHostPort(target); err == nil && net.ParseIP(host) != nil {
+			add(net.JoinHostPort(host, port))
+			continue
+		}
+
+		var ips []net.IP
+		if ip := net.ParseIP(target); ip != nil {
+			ips = []net.IP{ip}
+		} else if _, ipNet, err := net.ParseCIDR(target); err == nil {
+			ips = sampleIPs(ipNet, perPrefix)
+		} else {
+			continue
+		}
+
+		for _, ip := range ips {
+			for _, port := range ports {
+				add(net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+			}
+		}
+	}
+
+	return out
+}
+
+// sampleIPs 在網段內隨機抽取 n 個主機地址
+func sampleIPs(ipNet *net.IPNet, n int) []net.IP {
+	ones, bits := ipNet.Mask.Size()
+	hostBits := bits - ones
+	if hostBits < 2 {
+		return []net.IP{ipNet.IP}
+	}
+
+	seen := make(map[string]bool)
+	var ips []net.IP
+	for tries := 0; len(ips) < n && tries < n*4; tries++ {
+		ip := make(net.IP, len(ipNet.IP))
+		copy(ip, ipNet.IP)
+
+		// 只隨機化最後 (至多) 16 位，足以覆蓋 /24 與 IPv6 接入段
+		span := hostBits
+		if span > 16 {
+			span = 16
+		}
+		offset := rand.Intn(1<<span-2) + 1 // 跳過網絡地址與廣播地址
+		ip[len(ip)-1] |= byte(offset)
+		ip[len(ip)-2] |= byte(offset >> 8)
+
+		if !seen[ip.String()] {
+			seen[ip.String()] = true
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
@@ -0,0 +1,133 @@
+package warp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2s"
+)
+
+// startMockPeer 啟動本地 UDP 服務，僅對 MAC1 校驗通過的握手包返回響應
+func startMockPeer(t *testing.T, peerPub []byte) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	mac1Key := blake2s.Sum256(append([]byte(wgLabelMAC1), peerPub...))
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n != messageInitiationSize || buf[0] != messageInitiationType {
+				continue
+			}
+
+			mac, _ := blake2s.New128(mac1Key[:])
+			mac.Write(buf[:116])
+			if string(mac.Sum(nil)) != string(buf[116:132]) {
+				continue
+			}
+
+			resp := make([]byte, messageResponseSize)
+			resp[0] = messageResponseType
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestBuildHandshakeInitiation(t *testing.T) {
+	priv, _, err := GenerateKeypair()
+	require.NoError(t, err)
+	_, peerPub, err := GenerateKeypair()
+	require.NoError(t, err)
+
+	packet, err := BuildHandshakeInitiation(priv, peerPub, []int{1, 2, 3})
+	require.NoError(t, err)
+
+	assert.Len(t, packet, messageInitiationSize)
+	assert.Equal(t, byte(messageInitiationType), packet[0])
+	assert.Equal(t, []byte{1, 2, 3}, packet[1:4], "reserved 應寫入包頭")
+	assert.Equal(t, make([]byte, 16), packet[132:148], "mac2 應為全零")
+
+	_, err = BuildHandshakeInitiation("invalid", peerPub, nil)
+	assert.Error(t, err)
+}
+
+func TestScannerRanking(t *testing.T) {
+	_, peerPubStr, err := GenerateKeypair()
+	require.NoError(t, err)
+	peerPub, _ := decodeKey(peerPubStr)
+
+	alive := startMockPeer(t, peerPub)
+
+	// 無服務監聽的端口：握手必然超時
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	deadAddr := dead.LocalAddr().String()
+	dead.Close()
+
+	scanner, err := NewScanner(ScanOptions{
+		PeerPublicKey: peerPubStr,
+		Attempts:      2,
+		Timeout:       200 * time.Millisecond,
+	}, nil)
+	require.NoError(t, err)
+
+	results := scanner.Scan(context.Background(), []string{deadAddr, alive})
+	require.Len(t, results, 2)
+
+	assert.Equal(t, alive, results[0].Endpoint, "可達接入點應排在首位")
+	assert.Equal(t, 2, results[0].Received)
+	assert.Zero(t, results[0].Loss())
+	assert.False(t, results[1].Reachable())
+	assert.Equal(t, float64(1), results[1].Loss())
+}
+
+func TestSortResults(t *testing.T) {
+	results := []ScanResult{
+		{Endpoint: "dead", Sent: 3},
+		{Endpoint: "slow", Sent: 3, Received: 3, AvgRTT: 90 * time.Millisecond},
+		{Endpoint: "lossy", Sent: 3, Received: 2, AvgRTT: 10 * time.Millisecond},
+		{Endpoint: "fast", Sent: 3, Received: 3, AvgRTT: 20 * time.Millisecond},
+	}
+	SortResults(results)
+
+	var order []string
+	for _, r := range results {
+		order = append(order, r.Endpoint)
+	}
+	assert.Equal(t, []string{"fast", "slow", "lossy", "dead"}, order)
+}
+
+func TestBuildCandidates(t *testing.T) {
+	candidates := BuildCandidates([]string{"162.159.192.1", "162.159.193.0/24", "[2606:4700:d0::1]:500", "bogus"}, []int{2408, 500}, 3)
+
+	assert.Contains(t, candidates, "162.159.192.1:2408")
+	assert.Contains(t, candidates, "162.159.192.1:500")
+	assert.Contains(t, candidates, "[2606:4700:d0::1]:500")
+
+	fromCIDR := 0
+	for _, c := range candidates {
+		host, _, err := net.SplitHostPort(c)
+		require.NoError(t, err)
+		ip := net.ParseIP(host)
+		require.NotNil(t, ip)
+		if ip.To4() != nil && ip.To4()[2] == 193 {
+			fromCIDR++
+			assert.NotEqual(t, byte(0), ip.To4()[3], "不應選中網絡地址")
+		}
+	}
+	assert.Equal(t, 3*2, fromCIDR)
+}
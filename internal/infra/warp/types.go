@@ -0,0 +1,123 @@
+package warp
+
+import (
+	"net"
+	"strconv"
+)
+
+const (
+	// DefaultAPIBase Cloudflare WARP 客戶端 API 地址
+	DefaultAPIBase = "https://api.cloudflareclient.com/v0a2158"
+
+	// DefaultEndpointPort WARP WireGuard 默認端口
+	// API 返回的 endpoint 端口通常為 0，需要替換為實際可用端口
+	DefaultEndpointPort = 2408
+
+	// 模擬官方 Android 客戶端的請求頭，否則 API 可能拒絕請求
+	clientVersion = "a-6.10-2158"
+	userAgent     = "okhttp/3.12.1"
+)
+
+// 賬戶類型
+const (
+	AccountTypeFree = "free"
+	AccountTypePlus = "unlimited" // WARP+ 賬戶在 API 中的類型名
+)
+
+// Account 註冊後得到的 WARP 設備賬戶
+type Account struct {
+	DeviceID      string
+	AccessToken   string
+	PrivateKey    string // WireGuard 私鑰 (Base64)
+	PublicKey     string // WireGuard 公鑰 (Base64)，已上傳至 Cloudflare
+	PeerPublicKey string // Cloudflare 端公鑰
+	ClientID      string // 用於計算 reserved 字節
+	Reserved      []int  // WireGuard reserved 字段 (3 字節)
+	IPv4          string
+	IPv6          string
+	AccountType   string
+	WARPPlus      bool
+	License       string
+	Endpoints     Endpoints
+}
+
+// Endpoints API 返回的可用接入點
+type Endpoints struct {
+	V4   string // 例如 162.159.192.1:0
+	V6   string // 例如 [2606:4700:d0::a29f:c001]:0
+	Host string // 例如 engage.cloudflareclient.com:2408
+}
+
+// SelectEndpoint 選擇接入點並補全端口
+// preferIPv6 為 true 時優先使用 IPv6 接入點；對應地址不可用時回退到其他接入點
+func (a *Account) SelectEndpoint(preferIPv6 bool) string {
+	candidates := []string{a.Endpoints.V4, a.Endpoints.V6, a.Endpoints.Host}
+	if preferIPv6 {
+		candidates = []string{a.Endpoints.V6, a.Endpoints.V4, a.Endpoints.Host}
+	}
+
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(c)
+		if err != nil || host == "" {
+			continue
+		}
+		if port == "" || port == "0" {
+			port = strconv.Itoa(DefaultEndpointPort)
+		}
+		return net.JoinHostPort(host, port)
+	}
+
+	return ""
+}
+
+// ========================================
+// API 數據結構
+// ========================================
+
+type registerRequest struct {
+	Key       string `json:"key"`
+	InstallID string `json:"install_id"`
+	FCMToken  string `json:"fcm_token"`
+	TOS       string `json:"tos"`
+	Model     string `json:"model"`
+	Type      string `json:"type"`
+	Locale    string `json:"locale"`
+}
+
+type licenseRequest struct {
+	License string `json:"license"`
+}
+
+type deviceResponse struct {
+	ID      string      `json:"id"`
+	Token   string      `json:"token"`
+	Account AccountInfo `json:"account"`
+	Config  struct {
+		ClientID  string `json:"client_id"`
+		Interface struct {
+			Addresses struct {
+				V4 string `json:"v4"`
+				V6 string `json:"v6"`
+			} `json:"addresses"`
+		} `json:"interface"`
+		Peers []struct {
+			PublicKey string `json:"public_key"`
+			Endpoint  struct {
+				V4   string `json:"v4"`
+				V6   string `json:"v6"`
+				Host string `json:"host"`
+			} `json:"endpoint"`
+		} `json:"peers"`
+	} `json:"config"`
+}
+
+// AccountInfo 賬戶狀態 (許可證綁定後用於確認 WARP+ 是否生效)
+type AccountInfo struct {
+	ID          string `json:"id"`
+	AccountType string `json:"account_type"`
+	WARPPlus    bool   `json:"warp_plus"`
+	License     string `json:"license"`
+}
@@ -100,3 +100,13 @@ func NewPaths(baseDir string) (*Paths, error) {
 func isProduction() bool {
 	return os.Geteuid() == 0 || os.Getenv("PRISM_ENV") == "production"
 }
+
+// DecoySiteDir 內置偽裝站點目錄
+func (p *Paths) DecoySiteDir() string {
+	return filepath.Join(p.DataDir, "decoy")
+}
+
+// DecoyServicePath 本機偽裝站點服務的 systemd 單元文件 (與 sing-box 單元同目錄)
+func (p *Paths) DecoyServicePath() string {
+	return filepath.Join(filepath.Dir(p.SystemdServicePath), "prism-decoy.service")
+}
@@ -115,107 +115,18 @@ func GenerateClientConfig(serverCfg *config.Config, defaultHost string, factory
 }
 
 // protocolToClashProxy 將內部協議對象轉換為 Clash Meta 代理 Map
+// 不支持 Clash 的協議返回 nil
 func protocolToClashProxy(p protocol.Protocol, defaultHost string, cfg *config.Config) map[string]interface{} {
-	// [核心修復] 確定地址邏輯
-	finalAddress := defaultHost
-
-	switch p.Type() {
-	case protocol.TypeHysteria2:
-		if cfg.Protocols.Hysteria2.CertMode == "acme" && cfg.Protocols.Hysteria2.CertDomain != "" {
-			finalAddress = cfg.Protocols.Hysteria2.CertDomain
-		}
-	case protocol.TypeTUIC:
-		if cfg.Protocols.TUIC.CertMode == "acme" && cfg.Protocols.TUIC.CertDomain != "" {
-			finalAddress = cfg.Protocols.TUIC.CertDomain
-		}
-	case protocol.TypeAnyTLS:
-		if cfg.Protocols.AnyTLS.CertMode == "acme" && cfg.Protocols.AnyTLS.CertDomain != "" {
-			finalAddress = cfg.Protocols.AnyTLS.CertDomain
-		}
+	d, ok := protocol.LookupType(p.Type())
+	if !ok || d.Clash == nil {
+		return nil
 	}
 
-	base := map[string]interface{}{
+	proxy := map[string]interface{}{
 		"name":   p.Name(),
-		"server": finalAddress,
-		"port":   p.Port(),
-	}
-
-	switch v := p.(type) {
-	case *protocol.RealityVision:
-		base["type"] = "vless"
-		base["uuid"] = v.Users[0].UUID
-		base["network"] = "tcp"
-		base["tls"] = true
-		base["udp"] = true
-		base["flow"] = "xtls-rprx-vision"
-		base["servername"] = v.SNI
-		base["client-fingerprint"] = "chrome"
-		base["reality-opts"] = map[string]interface{}{
-			"public-key": v.PublicKey,
-			"short-id":   v.ShortID,
-		}
-
-	case *protocol.RealityGRPC:
-		base["type"] = "vless"
-		base["uuid"] = v.Users[0].UUID
-		base["network"] = "grpc"
-		base["tls"] = true
-		base["udp"] = true
-		base["servername"] = v.SNI
-		base["client-fingerprint"] = "chrome"
-		base["grpc-opts"] = map[string]interface{}{
-			"grpc-service-name": v.ServiceName,
-		}
-		base["reality-opts"] = map[string]interface{}{
-			"public-key": v.PublicKey,
-			"short-id":   v.ShortID,
-		}
-
-	case *protocol.Hysteria2:
-		base["type"] = "hysteria2"
-		base["password"] = v.Password
-		base["sni"] = v.SNI
-		base["skip-cert-verify"] = true
-		base["alpn"] = []string{v.ALPN}
-		if v.Obfs != "" {
-			base["obfs"] = "salamander"
-			base["obfs-password"] = v.Obfs
-		}
-
-	case *protocol.TUIC:
-		base["type"] = "tuic"
-		base["uuid"] = v.UUID
-		base["password"] = v.Password
-		base["sni"] = v.SNI
-		base["skip-cert-verify"] = true
-		base["alpn"] = v.ALPN
-		base["congestion-controller"] = v.CongestionControl
-		base["udp-relay-mode"] = "native"
-
-	case *protocol.AnyTLS:
-		// AnyTLS 本質是 HTTP/2 代理
-		base["type"] = "http"
-		base["username"] = v.Username
-		base["password"] = v.Password
-		base["tls"] = true
-		base["sni"] = v.SNI
-		base["skip-cert-verify"] = true
-
-	case *protocol.ShadowTLS:
-		// Clash Meta 支持 shadow-tls 作為 SS 的插件
-		base["type"] = "ss"
-		base["cipher"] = v.SSMethod
-		base["password"] = v.SSPassword
-		base["plugin"] = "shadow-tls"
-		base["plugin-opts"] = map[string]interface{}{
-			"host":     v.SNI,
-			"password": v.Password,
-			"version":  3,
-		}
-
-	default:
-		return nil
+		"server": protocol.ClientAddress(cfg, p, defaultHost),
+		"port":   protocol.ClientPort(p),
 	}
-
-	return base
+	d.Clash(p, proxy)
+	return proxy
 }
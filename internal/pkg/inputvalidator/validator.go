@@ -259,21 +259,6 @@ func ValidatePortRange(input string) error {
 	return nil
 }
 
-// ParsePortRange 解析端口範圍字符串
-func ParsePortRange(input string) (int, int, error) {
-	// 1. 復用驗證邏輯 (防禦性編程)
-	if err := ValidatePortRange(input); err != nil {
-		return 0, 0, fmt.Errorf("解析失敗: %w", err)
-	}
-
-	// 2. 執行解析 (因為通過了驗證，這裡可以大膽拆分)
-	parts := strings.Split(input, "-")
-	start, _ := strconv.Atoi(strings.TrimSpace(parts[0]))
-	end, _ := strconv.Atoi(strings.TrimSpace(parts[1]))
-
-	return start, end, nil
-}
-
 // ValidateMenuNumber 驗證菜單數字輸入
 func ValidateMenuNumber(input string, min, max int) error {
 	input = strings.TrimSpace(input)
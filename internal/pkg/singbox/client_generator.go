@@ -22,22 +22,7 @@ func GenerateClientConfig(serverCfg *config.Config, defaultHost string, factory
 			continue
 		}
 
-		finalAddress := defaultHost
-
-		switch p.Type() {
-		case protocol.TypeHysteria2:
-			if serverCfg.Protocols.Hysteria2.CertMode == "acme" && serverCfg.Protocols.Hysteria2.CertDomain != "" {
-				finalAddress = serverCfg.Protocols.Hysteria2.CertDomain
-			}
-		case protocol.TypeTUIC:
-			if serverCfg.Protocols.TUIC.CertMode == "acme" && serverCfg.Protocols.TUIC.CertDomain != "" {
-				finalAddress = serverCfg.Protocols.TUIC.CertDomain
-			}
-		case protocol.TypeAnyTLS:
-			if serverCfg.Protocols.AnyTLS.CertMode == "acme" && serverCfg.Protocols.AnyTLS.CertDomain != "" {
-				finalAddress = serverCfg.Protocols.AnyTLS.CertDomain
-			}
-		}
+		finalAddress := protocol.ClientAddress(serverCfg, p, defaultHost)
 
 		// 應用地址
 		if _, ok := out["server"]; ok {
@@ -21,20 +21,61 @@ const (
 	// ==========================================
 	// 配置菜單 (Config Menu)
 	// ==========================================
-	KeyConfig_Protocol = "1" // 協議開關管理
-	KeyConfig_SNI      = "2" // 修改 SNI 域名
-	KeyConfig_UUID     = "3" // 修改 UUID
-	KeyConfig_Port     = "4" // 修改監聽端口
-	KeyConfig_Padding  = "5" // AnyTLS 填充策略
-	KeyConfig_Apply    = "s" // 應用配置
-	KeyConfig_Reset    = "r" // 重置配置
+	KeyConfig_Protocol  = "1" // 協議開關管理
+	KeyConfig_SNI       = "2" // 修改 SNI 域名
+	KeyConfig_UUID      = "3" // 修改 UUID
+	KeyConfig_Port      = "4" // 修改監聽端口
+	KeyConfig_Padding   = "5" // AnyTLS 填充策略
+	KeyConfig_Routing   = "6" // 入站路由策略
+	KeyConfig_Options   = "7" // 協議高級參數
+	KeyConfig_Bandwidth = "8" // Hysteria2 帶寬與 Brutal
+	KeyConfig_Apply     = "s" // 應用配置
+	KeyConfig_Reset     = "r" // 重置配置
 
 	// AnyTLS Padding 策略
-	KeyPadding_Balanced   = "1" // 均衡流
-	KeyPadding_Minimal    = "2" // 極簡流
-	KeyPadding_HighResist = "3" // 高對抗流
-	KeyPadding_Video      = "4" // 視頻特徵
-	KeyPadding_Official   = "5" // 官方默認
+	KeyPadding_Balanced   = "1"  // 均衡流
+	KeyPadding_Minimal    = "2"  // 極簡流
+	KeyPadding_HighResist = "3"  // 高對抗流
+	KeyPadding_Video      = "4"  // 視頻特徵
+	KeyPadding_Official   = "5"  // 官方默認
+	KeyPadding_Edit       = "6"  // 編輯方案行
+	KeyPadding_Preview    = "7"  // 預覽包數
+	KeyPadding_Apply      = "8"  // 應用草稿
+	KeyPadding_Save       = "9"  // 保存為命名方案
+	KeyPadding_Load       = "10" // 應用命名方案
+	KeyPadding_Delete     = "11" // 刪除命名方案
+
+	// 協議高級參數
+	KeyOption_ALPN              = "1"  // ALPN
+	KeyOption_CongestionControl = "2"  // TUIC 擁塞控制
+	KeyOption_ZeroRTT           = "3"  // TUIC 0-RTT
+	KeyOption_Username          = "4"  // AnyTLS 用戶名
+	KeyOption_ShortID           = "5"  // Reality Short ID
+	KeyOption_SSMethod          = "6"  // ShadowTLS SS 加密方式
+	KeyOption_SSPassword        = "7"  // ShadowTLS SS 密碼
+	KeyOption_DetourPort        = "8"  // ShadowTLS 本地轉交端口
+	KeyOption_StrictMode        = "9"  // ShadowTLS 嚴格模式
+	KeyOption_CDNProxy          = "10" // CDN 代理協議
+	KeyOption_CDNTransport      = "11" // CDN 傳輸方式
+	KeyOption_CDNPath           = "12" // CDN WebSocket / HTTPUpgrade 路徑
+	KeyOption_CDNHost           = "13" // CDN Host 頭
+	KeyOption_CDNServiceName    = "14" // CDN gRPC 服務名
+	KeyOption_CDNAddress        = "15" // CDN 客戶端連接地址
+	KeyOption_CDNPort           = "16" // CDN 客戶端連接端口
+	KeyOption_SSUserAdd         = "17" // Shadowsocks 添加用戶
+	KeyOption_SSRelayAdd        = "18" // Shadowsocks 添加中轉目標
+	KeyOption_SSRemove          = "19" // Shadowsocks 刪除用戶或中轉目標
+	KeyOption_NaiveQUIC         = "20" // NaiveProxy HTTP/3
+	KeyOption_Decoy             = "21" // 偽裝網站 (Hysteria2 / CDN Trojan)
+	KeyOption_UDPRelayMode      = "22" // TUIC UDP 轉發模式
+	KeyOption_AuthTimeout       = "23" // TUIC 認證超時
+	KeyOption_Heartbeat         = "24" // TUIC 心跳間隔
+
+	// 入站路由策略
+	KeyInbound_Outbound = "1" // 默認出站
+	KeyInbound_Block    = "2" // 屏蔽類別
+	KeyInbound_IPv6     = "3" // IPv6 偏好
+	KeyInbound_Clear    = "4" // 清除策略
 
 	// ==========================================
 	// 證書菜單 (Cert Menu)
@@ -69,6 +110,8 @@ const (
 	KeyRoute_IPv6     = "3" // IPv6 分流配置
 	KeyRoute_DNS      = "4" // DNS 分流配置
 	KeyRoute_SNIProxy = "5" // SNI 反向代理
+	KeyRoute_Outbound = "6" // 自定義出站
+	KeyRoute_Rules    = "7" // 路由規則
 
 	// ==========================================
 	// 核心菜單 (Core Menu)
@@ -103,6 +146,20 @@ const (
 	KeyService_Refresh   = "4" // 刷新狀態
 	KeyService_AutoStart = "5" // 開機自啟
 	KeyService_Health    = "6" // 健康檢查
+	KeyService_Traffic   = "7" // 流量面板
+
+	// 流量面板
+	KeyTraffic_Close  = "x" // 關閉連接: x 序號
+	KeyTraffic_Pause  = "p" // 暫停/繼續刷新
+	KeyTraffic_Expand = "a" // 顯示全部連接 / 僅前 10 條
+
+	// 服務實時日誌
+	KeyServiceLog_Pause     = "p" // 暫停/繼續
+	KeyServiceLog_Clear     = "c" // 清除過濾
+	KeyServiceLog_Search    = "/" // 搜索前綴: /關鍵字
+	KeyServiceLog_Level     = "l" // 級別前綴: l warn
+	KeyServiceLog_Inbound   = "i" // 入站前綴: i hysteria2-in 或 i 3
+	KeyServiceLog_Reconnect = "r" // 重新連接
 
 	// ==========================================
 	// 工具菜單 (Tools Menu)
@@ -159,8 +216,9 @@ const (
 	KeyLog_Full     = "2" // 查看完整日誌
 	KeyLog_Error    = "3" // 查看錯誤日誌
 	KeyLog_Level    = "4" // 修改日誌級別
-	KeyLog_Export   = "5" // 導出日誌
+	KeyLog_Export   = "5" // 導出診斷包
 	KeyLog_Clear    = "6" // 清空日誌
+	KeyLog_Query    = "7" // 查詢日誌
 
 	// 日誌級別
 	KeyLevel_Debug = "1" // Debug
@@ -195,6 +253,15 @@ const (
 	KeyPort_Main         = "1" // Hy2 主端口
 	KeyPort_Hopping      = "2" // Hy2 端口跳躍
 	KeyPort_ClearHopping = "3" // Hy2 清除跳躍
+	KeyPort_HopInterval  = "4" // Hy2 跳躍間隔
+
+	// ==========================================
+	// Hysteria2 帶寬與 Brutal
+	// ==========================================
+	KeyBandwidth_Detect = "1" // 檢測帶寬
+	KeyBandwidth_Apply  = "2" // 應用推薦值
+	KeyBandwidth_Manual = "3" // 手動設置
+	KeyBandwidth_Brutal = "4" // Brutal 開關
 
 	// ==========================================
 	// UUID 編輯
@@ -213,21 +280,44 @@ const (
 	// ==========================================
 	// WARP 分流 (WARP Routing)
 	// ==========================================
-	KeyWARP_ToggleIPv4 = "1" // 啟用 WARP IPv4
-	KeyWARP_ToggleIPv6 = "2" // 啟用 WARP IPv6
-	KeyWARP_SetGlobal  = "3" // 設置全局模式
-	KeyWARP_SetDomains = "4" // 添加分流域名
-	KeyWARP_ShowConfig = "5" // 查看配置
-	KeyWARP_Disable    = "6" // 禁用 WARP
-	KeyWARP_SetLicense = "7" // 配置密鑰
+	KeyWARP_ToggleIPv4  = "1"  // 啟用 WARP IPv4
+	KeyWARP_ToggleIPv6  = "2"  // 啟用 WARP IPv6
+	KeyWARP_SetGlobal   = "3"  // 設置全局模式
+	KeyWARP_SetDomains  = "4"  // 添加分流域名
+	KeyWARP_ShowConfig  = "5"  // 查看配置
+	KeyWARP_Disable     = "6"  // 禁用 WARP
+	KeyWARP_SetLicense  = "7"  // 配置密鑰
+	KeyWARP_Register    = "8"  // 註冊 WARP 賬戶
+	KeyWARP_SetEndpoint = "9"  // 設置接入點
+	KeyWARP_Outbound    = "10" // WARP 出站管理 (接入點優選)
 
 	// ==========================================
 	// WARP 出站管理 (WARP Config)
 	// ==========================================
-	KeyWARPConfig_Enable  = "1" // 啟用 WARP
-	KeyWARPConfig_Disable = "2" // 禁用 WARP
-	KeyWARPConfig_License = "3" // 配置許可證密鑰
-	KeyWARPConfig_Test    = "4" // 測試連接
+	KeyWARPConfig_Enable   = "1" // 啟用 WARP
+	KeyWARPConfig_Disable  = "2" // 禁用 WARP
+	KeyWARPConfig_License  = "3" // 配置許可證密鑰
+	KeyWARPConfig_Test     = "4" // 測試連接
+	KeyWARPConfig_Optimize = "5" // 優選接入點
+	KeyWARPConfig_Auto     = "6" // 定時自動優選
+
+	// ==========================================
+	// 自定義出站 (Custom Outbound)
+	// ==========================================
+	KeyCustomOut_Add      = "1" // 添加出站
+	KeyCustomOut_Delete   = "2" // 刪除出站
+	KeyCustomOut_Detour   = "3" // 設置前置代理
+	KeyCustomOut_Domains  = "4" // 分流域名
+	KeyCustomOut_RuleSets = "5" // 分流規則集
+
+	// ==========================================
+	// 路由規則 (Routing Rules)
+	// ==========================================
+	KeyRule_Add      = "1" // 添加規則
+	KeyRule_Delete   = "2" // 刪除規則
+	KeyRule_Priority = "3" // 調整優先級
+	KeyRule_Toggle   = "4" // 啟用/禁用
+	KeyRule_Final    = "5" // 默認出站
 
 	// ==========================================
 	// Socks5 管理
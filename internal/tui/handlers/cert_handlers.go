@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/inputvalidator"
 	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
 	"github.com/Yat-Muk/prism-v2/internal/tui/state"
@@ -310,24 +311,16 @@ func (h *CertHandler) handleModeSwitch(m *state.Manager) (*state.Manager, tea.Cm
 	input := strings.TrimSpace(m.UI().GetInputBuffer())
 	m.UI().ClearInput()
 
-	var protocol string
-	var label string
-
-	switch input {
-	case "1":
-		protocol = "hysteria2"
-		label = "Hysteria 2"
-	case "2":
-		protocol = "tuic"
-		label = "TUIC v5"
-	case "3":
-		protocol = "anytls"
-		label = "AnyTLS"
-	default:
+	// 菜單只列出已啟用且使用本地證書的協議，編號與視圖一致
+	n, err := strconv.Atoi(input)
+	targets := protocol.CertIDs(m.Config().EnabledProtocols)
+	if err != nil || n < 1 || n > len(targets) {
 		return m, nil
 	}
+	d, _ := protocol.Lookup(targets[n-1])
+	label := d.Name
 
 	m.UI().SetStatus(state.StatusInfo, fmt.Sprintf("正在切換 %s 證書模式...", label), "請稍候", true)
 
-	return m, h.cmdBuilder.ToggleCertModeCmd(protocol)
+	return m, h.cmdBuilder.ToggleCertModeCmd(string(d.Type))
 }
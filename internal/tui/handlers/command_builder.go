@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -24,7 +25,9 @@ import (
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
 	"github.com/Yat-Muk/prism-v2/internal/infra/backup"
+	"github.com/Yat-Muk/prism-v2/internal/infra/diag"
 	"github.com/Yat-Muk/prism-v2/internal/infra/firewall"
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
 	infraSingbox "github.com/Yat-Muk/prism-v2/internal/infra/singbox"
 	"github.com/Yat-Muk/prism-v2/internal/infra/system"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/appctx"
@@ -53,6 +56,8 @@ type CommandBuilder struct {
 	executor     system.Executor
 	firewallMgr  firewall.Manager
 	protoFactory protocol.Factory
+	warpSvc      *application.WARPService
+	trafficSvc   *application.TrafficService
 }
 
 // NewCommandBuilder 構造函數
@@ -70,6 +75,8 @@ func NewCommandBuilder(
 	executor system.Executor,
 	firewallMgr firewall.Manager,
 	protoFactory protocol.Factory,
+	warpSvc *application.WARPService,
+	trafficSvc *application.TrafficService,
 ) *CommandBuilder {
 	return &CommandBuilder{
 		log:          log,
@@ -85,6 +92,8 @@ func NewCommandBuilder(
 		executor:     executor,
 		firewallMgr:  firewallMgr,
 		protoFactory: protoFactory,
+		warpSvc:      warpSvc,
+		trafficSvc:   trafficSvc,
 	}
 }
 
@@ -334,6 +343,100 @@ func (b *CommandBuilder) ServiceHealthCheckCmd(m *state.Manager) tea.Cmd {
 	}
 }
 
+// TrafficDashboardCmd 通過 Clash API 採樣一次流量面板
+func (b *CommandBuilder) TrafficDashboardCmd(m *state.Manager) tea.Cmd {
+	return func() tea.Msg {
+		if b.trafficSvc == nil {
+			return msg.TrafficDashboardMsg{Err: fmt.Errorf("流量統計服務未初始化")}
+		}
+
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.TrafficDashboardMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		d, err := b.trafficSvc.Dashboard(ctx, cfg)
+		if err != nil {
+			var netErr *net.OpError
+			if errors.As(err, &netErr) {
+				err = fmt.Errorf("無法連接 Clash API (%s)，請確認服務運行且已應用最新配置", cfg.ClashAPI.Listen)
+			}
+			return msg.TrafficDashboardMsg{Err: err}
+		}
+
+		return msg.TrafficDashboardMsg{Dashboard: toTrafficDashboard(d)}
+	}
+}
+
+// ResetTrafficSampling 清除速率計算的歷史採樣
+func (b *CommandBuilder) ResetTrafficSampling() {
+	if b.trafficSvc != nil {
+		b.trafficSvc.Reset()
+	}
+}
+
+// CloseConnectionCmd 關閉指定連接
+func (b *CommandBuilder) CloseConnectionCmd(m *state.Manager, id string) tea.Cmd {
+	return func() tea.Msg {
+		if b.trafficSvc == nil {
+			return msg.ConnectionClosedMsg{ID: id, Err: fmt.Errorf("流量統計服務未初始化")}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		err := b.trafficSvc.CloseConnection(ctx, m.Config().GetConfig(), id)
+		return msg.ConnectionClosedMsg{ID: id, Err: err}
+	}
+}
+
+// toTrafficDashboard 將統計數據格式化為面板展示模型
+func toTrafficDashboard(d *application.TrafficDashboard) *types.TrafficDashboard {
+	rate := func(n int64) string { return formatBytes(n) + "/s" }
+
+	out := &types.TrafficDashboard{
+		UpRate:        rate(d.UpRate),
+		DownRate:      rate(d.DownRate),
+		UploadTotal:   formatBytes(d.UploadTotal),
+		DownloadTotal: formatBytes(d.DownloadTotal),
+		Memory:        formatBytes(int64(d.MemoryInUse)),
+		TotalConns:    d.TotalConns,
+		UpdateTime:    d.SampledAt.Format("15:04:05"),
+	}
+	for _, in := range d.Inbounds {
+		out.Inbounds = append(out.Inbounds, types.InboundTraffic{
+			Tag:         in.Tag,
+			Connections: in.Connections,
+			UpRate:      rate(in.UpRate),
+			DownRate:    rate(in.DownRate),
+		})
+	}
+	for _, c := range d.Connections {
+		out.Connections = append(out.Connections, types.ConnectionInfo{
+			ID:          c.ID,
+			Inbound:     c.Inbound,
+			Network:     c.Network,
+			Source:      c.Source,
+			Destination: c.Destination,
+			Outbound:    c.Outbound,
+			Traffic:     fmt.Sprintf("↑ %s ↓ %s", formatBytes(c.Upload), formatBytes(c.Download)),
+			Rate:        fmt.Sprintf("↑ %s ↓ %s", rate(c.UpRate), rate(c.DownRate)),
+			Duration:    formatDuration(c.Duration),
+		})
+	}
+	for _, dest := range d.TopDestinations {
+		out.TopDestinations = append(out.TopDestinations, types.DestinationTraffic{
+			Host:        dest.Host,
+			Connections: dest.Connections,
+			Total:       formatBytes(dest.Upload + dest.Download),
+		})
+	}
+	return out
+}
+
 // InstallPrismCmd 安裝 Prism (首次配置生成)
 func (b *CommandBuilder) InstallPrismCmd(m *state.Manager) tea.Cmd {
 	return func() tea.Msg {
@@ -477,24 +580,11 @@ func (b *CommandBuilder) UpdateAnyTLSPaddingCmd(m *state.Manager, profile int) t
 			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置對象為空")}
 		}
 
-		var mode string
-		switch profile {
-		case 1:
-			mode = "balanced"
-		case 2:
-			mode = "minimal"
-		case 3:
-			mode = "highresist"
-		case 4:
-			mode = "video"
-		case 5:
-			mode = "official"
-		default:
+		if profile < 1 || profile > len(domainConfig.PaddingPresetModes) {
 			return msg.ConfigUpdateMsg{Err: fmt.Errorf("無效的配置 ID: %d", profile)}
 		}
-
-		cfg.Protocols.AnyTLS.PaddingMode = mode
-		cfg.Protocols.AnyTLSReality.PaddingMode = mode
+		mode := domainConfig.PaddingPresetModes[profile-1]
+		cfg.Protocols.SetPadding(mode, nil)
 
 		return msg.ConfigUpdateMsg{
 			NewConfig: cfg,
@@ -517,7 +607,11 @@ func (b *CommandBuilder) UpdateSinglePortCmd(m *state.Manager, protoID int, port
 			return msg.ConfigUpdateMsg{Err: err}
 		}
 
-		return msg.ConfigUpdateMsg{NewConfig: newCfg, Applied: false}
+		return msg.ConfigUpdateMsg{
+			NewConfig: newCfg,
+			Applied:   false,
+			Message:   fmt.Sprintf("端口已更新為 %d (未保存)", b.portSvc.GetPort(newCfg, protoID)),
+		}
 	}
 }
 
@@ -534,19 +628,19 @@ func (b *CommandBuilder) ResetPortsCmd(m *state.Manager) tea.Cmd {
 			return msg.ConfigUpdateMsg{Err: err}
 		}
 
-		return msg.ConfigUpdateMsg{NewConfig: newCfg, Applied: false}
+		return msg.ConfigUpdateMsg{NewConfig: newCfg, Applied: false, Message: "已重置所有端口 (未保存)"}
 	}
 }
 
-// UpdateHy2HoppingCmd 僅更新跳躍設置
-func (b *CommandBuilder) UpdateHy2HoppingCmd(m *state.Manager, start, end int) tea.Cmd {
+// UpdateHy2HoppingCmd 僅更新跳躍設置，spec 支持逗號分隔的多個範圍
+func (b *CommandBuilder) UpdateHy2HoppingCmd(m *state.Manager, spec string) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
 
 		cfg := m.Config().GetConfig()
 
-		newCfg, err := b.portSvc.UpdateHy2Hopping(ctx, cfg, start, end)
+		newCfg, err := b.portSvc.UpdateHy2Hopping(ctx, cfg, spec)
 		if err != nil {
 			return msg.ConfigUpdateMsg{Err: err}
 		}
@@ -554,11 +648,30 @@ func (b *CommandBuilder) UpdateHy2HoppingCmd(m *state.Manager, start, end int) t
 		return msg.ConfigUpdateMsg{
 			NewConfig: newCfg,
 			Applied:   false,
-			Message:   fmt.Sprintf("已設置跳躍端口 %d-%d (未保存)", start, end),
+			Message:   fmt.Sprintf("已設置跳躍端口 %s (未保存)", newCfg.Protocols.Hysteria2().PortHopping),
 		}
 	}
 }
 
+// UpdateHy2HopIntervalCmd 僅更新客戶端跳躍間隔
+func (b *CommandBuilder) UpdateHy2HopIntervalCmd(m *state.Manager, interval string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		newCfg, err := b.portSvc.UpdateHy2HopInterval(ctx, m.Config().GetConfig(), interval)
+		if err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		message := "已恢復默認跳躍間隔 (未保存)"
+		if v := newCfg.Protocols.Hysteria2().HopInterval; v != "" {
+			message = fmt.Sprintf("跳躍間隔已設置為 %s (未保存)", v)
+		}
+		return msg.ConfigUpdateMsg{NewConfig: newCfg, Applied: false, Message: message}
+	}
+}
+
 // ClearHy2HoppingCmd 僅清除跳躍設置
 func (b *CommandBuilder) ClearHy2HoppingCmd(m *state.Manager) tea.Cmd {
 	return func() tea.Msg {
@@ -693,11 +806,12 @@ func (b *CommandBuilder) RestoreBackupCmd(m *state.Manager, index int) tea.Cmd {
 			return msg.BackupRestoreMsg{Err: err}
 		}
 
-		// 重載配置
-		newCfg, err := b.configSvc.GetConfig(ctx)
-		if err == nil {
-			b.singboxSvc.ApplyConfig(ctx, newCfg)
+		// 重載配置 (舊版備份先遷移並寫回，再應用)
+		newCfg, err := b.configSvc.LoadWithMigration(ctx)
+		if err != nil {
+			return msg.BackupRestoreMsg{Err: fmt.Errorf("加載恢復的配置失敗: %w", err)}
 		}
+		b.singboxSvc.ApplyConfig(ctx, newCfg)
 
 		return msg.ConfigUpdateMsg{NewConfig: newCfg, Applied: true, Message: "備份已恢復"}
 	}
@@ -968,13 +1082,26 @@ func (b *CommandBuilder) ShowWARPConfigCmd(m *state.Manager) tea.Cmd {
 		}
 
 		domains := "無"
-		if len(warp.Domains) > 0 {
-			domains = strings.Join(warp.Domains, ", ")
+		if list := append(cfg.Routing.DomainsFor(domainConfig.OutboundWARP), warp.Domains...); len(list) > 0 {
+			domains = strings.Join(list, ", ")
+		}
+
+		account := "未註冊"
+		if warp.IsRegistered() {
+			account = warp.AccountType
+			if account == "" {
+				account = "free"
+			}
+		}
+
+		endpoint := warp.Endpoint
+		if endpoint == "" {
+			endpoint = domainConfig.DefaultWARPEndpoint + " (默認)"
 		}
 
 		message := fmt.Sprintf(
-			"WARP 配置：\n狀態：%s\n模式：%s\n分流域名：%s",
-			status, mode, domains,
+			"WARP 配置：\n狀態：%s\n模式：%s\n賬戶：%s\n接入點：%s\n分流域名：%s",
+			status, mode, account, endpoint, domains,
 		)
 
 		return msg.CommandResultMsg{
@@ -997,7 +1124,7 @@ func (b *CommandBuilder) LoadSocks5ConfigCmd(m *state.Manager) tea.Cmd {
 
 		return msg.RoutingConfigLoadedMsg{
 			Type:   "socks5",
-			Config: cfg.Routing.Socks5,
+			Config: cfg.Routing,
 		}
 	}
 }
@@ -1037,10 +1164,14 @@ func (b *CommandBuilder) ToggleSocks5OutboundCmd(m *state.Manager) tea.Cmd {
 			}
 		}
 
-		cfg.Routing.Socks5.Outbound.Enabled = !cfg.Routing.Socks5.Outbound.Enabled
+		// Socks5 出站已遷移為自定義出站 socks5-out，開關作用於指向它的分流規則
+		enable := !cfg.Routing.Socks5Enabled()
+		if err := cfg.Routing.SetSocks5Enabled(enable); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
 
 		status := "禁用"
-		if cfg.Routing.Socks5.Outbound.Enabled {
+		if enable {
 			status = "啓用"
 		}
 
@@ -1064,21 +1195,33 @@ func (b *CommandBuilder) ShowSocks5ConfigCmd(m *state.Manager) tea.Cmd {
 		}
 
 		inbound := cfg.Routing.Socks5.Inbound
-		outbound := cfg.Routing.Socks5.Outbound
+		outbound := cfg.Routing.FindOutbound(domainConfig.Socks5OutboundTag)
 
 		inboundStatus := "已禁用"
 		if inbound.Enabled {
 			inboundStatus = fmt.Sprintf("已啓用 (端口: %d)", inbound.Port)
 		}
 
-		outboundStatus := "已禁用"
-		if outbound.Enabled {
-			outboundStatus = fmt.Sprintf("已啓用 (%s:%d)", outbound.Server, outbound.Port)
+		outboundStatus := "未配置"
+		if outbound != nil {
+			outboundStatus = "已禁用"
+			if cfg.Routing.Socks5Enabled() {
+				outboundStatus = "已啓用"
+			}
+			outboundStatus += fmt.Sprintf(" (%s:%d)", outbound.Server, outbound.Port)
+		}
+		global := "否"
+		if cfg.Routing.Final == domainConfig.Socks5OutboundTag {
+			global = "是"
+		}
+		domains := "無"
+		if list := cfg.Routing.DomainsFor(domainConfig.Socks5OutboundTag); len(list) > 0 {
+			domains = strings.Join(list, ", ")
 		}
 
 		message := fmt.Sprintf(
-			"Socks5 配置：\n入站：%s\n出站：%s",
-			inboundStatus, outboundStatus,
+			"Socks5 配置：\n入站：%s\n出站：%s\n全局轉發：%s\n分流域名：%s",
+			inboundStatus, outboundStatus, global, domains,
 		)
 
 		return msg.CommandResultMsg{
@@ -1347,15 +1490,18 @@ func (b *CommandBuilder) UpdateSocks5OutboundServerCmd(m *state.Manager, serverS
 			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
 		}
 
-		parts := strings.Split(serverStr, ":")
-		if len(parts) >= 2 {
-			cfg.Routing.Socks5.Outbound.Server = parts[0]
-			var port int
-			fmt.Sscanf(parts[1], "%d", &port)
-			cfg.Routing.Socks5.Outbound.Port = port
-			return msg.ConfigUpdateMsg{NewConfig: cfg, Applied: false, Message: "Socks5 落地機地址已更新"}
+		host, portStr, err := net.SplitHostPort(strings.TrimSpace(serverStr))
+		if err != nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("格式錯誤，請使用 IP:Port")}
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("無效端口: %s", portStr)}
+		}
+		if err := cfg.Routing.SetSocks5Server(host, port); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
 		}
-		return msg.ConfigUpdateMsg{Err: fmt.Errorf("格式錯誤，請使用 IP:Port")}
+		return msg.ConfigUpdateMsg{NewConfig: cfg, Applied: false, Message: "Socks5 落地機地址已更新"}
 	}
 }
 
@@ -1372,16 +1518,26 @@ func (b *CommandBuilder) AddRoutingDomainCmd(m *state.Manager, routeType, domain
 			return nil
 		}
 
+		// WARP / IPv6 / Socks5 分流域名統一寫入路由規則
+		ruleTargets := map[string]struct{ outbound, label string }{
+			"ipv6":   {domainConfig.OutboundIPv6, "IPv6"},
+			"warp":   {domainConfig.OutboundWARP, "WARP"},
+			"socks5": {domainConfig.Socks5OutboundTag, "Socks5"},
+		}
+		if target, ok := ruleTargets[routeType]; ok {
+			if !cfg.Routing.IsRouteTarget(target.outbound) {
+				return msg.ConfigUpdateMsg{Err: fmt.Errorf("請先設置 %s 出站", target.label)}
+			}
+			if err := cfg.Routing.AddDomainRule(target.outbound, domain); err != nil {
+				return msg.ConfigUpdateMsg{Err: err}
+			}
+			return msg.ConfigUpdateMsg{NewConfig: cfg, Applied: false, Message: fmt.Sprintf("已添加 %s 分流域名: %s", target.label, domain)}
+		}
+
 		var targetList *[]string
 		var msgStr string
 
 		switch routeType {
-		case "ipv6":
-			targetList = &cfg.Routing.IPv6Split.Domains
-			msgStr = "IPv6"
-		case "warp":
-			targetList = &cfg.Routing.WARP.Domains
-			msgStr = "WARP"
 		case "dns":
 			targetList = &cfg.Routing.DNS.DomainRules
 			msgStr = "DNS"
@@ -1403,6 +1559,372 @@ func (b *CommandBuilder) AddRoutingDomainCmd(m *state.Manager, routeType, domain
 	}
 }
 
+// AddCustomOutboundCmd 從分享鏈接添加自定義出站
+func (b *CommandBuilder) AddCustomOutboundCmd(m *state.Manager, uri string) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		if strings.TrimSpace(uri) == "" {
+			return nil
+		}
+
+		out, err := domainConfig.ParseOutboundURI(uri)
+		if err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		// 先在副本上驗證，避免非法配置污染當前狀態
+		routing := cfg.Routing
+		routing.Outbounds = append(append([]domainConfig.CustomOutbound{}, cfg.Routing.Outbounds...), *out)
+		if err := routing.ValidateOutbounds(); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		cfg.Routing.Outbounds = routing.Outbounds
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   fmt.Sprintf("已添加出站 %s (%s %s)", out.Tag, out.Type, out.Address()),
+		}
+	}
+}
+
+// DeleteCustomOutboundCmd 刪除自定義出站
+func (b *CommandBuilder) DeleteCustomOutboundCmd(m *state.Manager, tag string) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		tag = strings.TrimSpace(tag)
+		if cfg.Routing.FindOutbound(tag) == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("出站 %s 不存在", tag)}
+		}
+
+		for _, o := range cfg.Routing.Outbounds {
+			if o.Detour == tag {
+				return msg.ConfigUpdateMsg{Err: fmt.Errorf("出站 %s 正被 %s 用作前置代理", tag, o.Tag)}
+			}
+		}
+
+		var kept []domainConfig.CustomOutbound
+		for _, o := range cfg.Routing.Outbounds {
+			if o.Tag != tag {
+				kept = append(kept, o)
+			}
+		}
+
+		// 仍被路由規則或入站策略引用時拒絕刪除
+		routing := cfg.Routing
+		routing.Outbounds = kept
+		if err := routing.ValidateRules(); err != nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("出站 %s 仍被引用: %w", tag, err)}
+		}
+		if err := cfg.Protocols.ValidateRouting(&routing); err != nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("出站 %s 仍被引用: %w", tag, err)}
+		}
+		cfg.Routing.Outbounds = kept
+
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   fmt.Sprintf("已刪除出站 %s", tag),
+		}
+	}
+}
+
+// UpdateCustomOutboundCmd 更新自定義出站的前置代理或分流目標
+// 輸入格式: "標籤 值"，值為空時清除對應字段
+func (b *CommandBuilder) UpdateCustomOutboundCmd(m *state.Manager, field, input string) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		tag, value, _ := strings.Cut(strings.TrimSpace(input), " ")
+		value = strings.TrimSpace(value)
+
+		routing := cfg.Routing
+		routing.Outbounds = append([]domainConfig.CustomOutbound{}, cfg.Routing.Outbounds...)
+		target := routing.FindOutbound(tag)
+		if target == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("出站 %s 不存在", tag)}
+		}
+
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		var msgText string
+		switch field {
+		case "detour":
+			target.Detour = value
+			msgText = fmt.Sprintf("出站 %s 已直連上游", tag)
+			if value != "" {
+				msgText = fmt.Sprintf("出站 %s 將經由 %s 連接", tag, value)
+			}
+		case "domains":
+			target.Domains = items
+			msgText = fmt.Sprintf("出站 %s 分流域名已更新 (%d 條)", tag, len(items))
+		case "rule_sets":
+			target.RuleSets = nil
+			for _, item := range items {
+				target.RuleSets = append(target.RuleSets, domainConfig.NormalizeRuleSetTag(item))
+			}
+			msgText = fmt.Sprintf("出站 %s 分流規則集已更新 (%d 個)", tag, len(items))
+		default:
+			return nil
+		}
+
+		if err := routing.ValidateOutbounds(); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		cfg.Routing.Outbounds = routing.Outbounds
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   msgText,
+		}
+	}
+}
+
+// UpdateInboundRoutingCmd 編輯協議的入站路由策略
+// 輸入格式: "協議編號 值"，值為空時清除對應字段
+func (b *CommandBuilder) UpdateInboundRoutingCmd(m *state.Manager, field, input string) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		parts := strings.Fields(input)
+		if len(parts) == 0 || len(parts) > 2 {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("格式錯誤，應為: 協議編號 值")}
+		}
+		n, err := strconv.Atoi(parts[0])
+		if err != nil || !protocol.ID(n).IsValid() {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("無效的協議編號: %s", parts[0])}
+		}
+		id := protocol.ID(n)
+		value := ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+
+		// 在副本上修改並驗證
+		profile := *protocol.RoutingProfile(cfg, id)
+		switch field {
+		case "outbound":
+			profile.Outbound = value
+		case "block":
+			profile.Block = nil
+			for _, c := range strings.Split(value, ",") {
+				if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+					profile.Block = append(profile.Block, c)
+				}
+			}
+		case "ipv6":
+			profile.IPv6 = strings.ToLower(value)
+		case "clear":
+			profile = domainConfig.InboundRouting{}
+		default:
+			return nil
+		}
+
+		if err := profile.Validate(&cfg.Routing); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+		*protocol.RoutingProfile(cfg, id) = profile
+
+		msgText := fmt.Sprintf("%s 入站策略已更新", id)
+		if profile.IsEmpty() {
+			msgText = fmt.Sprintf("%s 已恢復全局路由", id)
+		}
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   msgText,
+		}
+	}
+}
+
+// UpdateProtocolOptionCmd 修改協議實例的高級參數
+// 輸入格式: "協議編號或實例標籤 值"，只輸入目標則恢復默認
+func (b *CommandBuilder) UpdateProtocolOptionCmd(m *state.Manager, field, input string) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		target, value, _ := strings.Cut(strings.TrimSpace(input), " ")
+		if target == "" {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("格式錯誤，應為: 協議編號或實例標籤 值")}
+		}
+		if err := b.protocolSvc.SetInstanceOption(cfg, target, field, value); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   fmt.Sprintf("%s 的 %s 已更新", target, field),
+		}
+	}
+}
+
+// MeasureHy2BandwidthCmd 檢測網卡速率與本機吞吐，給出 Hysteria2 推薦帶寬
+func (b *CommandBuilder) MeasureHy2BandwidthCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		report, err := system.NewBandwidthMeter().Measure(ctx, 3*time.Second)
+		if err != nil {
+			return msg.Hy2BandwidthMsg{Err: err}
+		}
+		return msg.Hy2BandwidthMsg{Info: &types.Hy2BandwidthInfo{
+			Interface:       report.Interface,
+			LinkMbps:        report.LinkMbps,
+			LoopbackMbps:    report.LoopbackMbps,
+			CPULimitMbps:    report.CPULimitMbps(),
+			RecommendedMbps: report.RecommendedMbps(),
+		}}
+	}
+}
+
+// ApplyHy2BandwidthCmd 將推薦帶寬寫入所有 Hysteria2 實例
+func (b *CommandBuilder) ApplyHy2BandwidthCmd(m *state.Manager, mbps int) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		value := strconv.Itoa(mbps)
+		for _, inst := range cfg.Protocols.InstancesOf(domainConfig.ProtocolTypeHysteria2) {
+			if err := b.protocolSvc.SetInstanceOption(cfg, inst.Tag, domainConfig.OptionBandwidth, value); err != nil {
+				return msg.ConfigUpdateMsg{Err: err}
+			}
+		}
+
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   fmt.Sprintf("Hysteria2 帶寬已設為 %d Mbps", mbps),
+		}
+	}
+}
+
+// UpdateRoutingRulesCmd 編輯路由規則
+// 序號為規則按優先級排序後的顯示序號 (從 1 開始)
+func (b *CommandBuilder) UpdateRoutingRulesCmd(m *state.Manager, field, input string) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		input = strings.TrimSpace(input)
+
+		// 在副本上修改並驗證，失敗時不影響當前配置
+		routing := cfg.Routing
+		routing.Rules = append([]domainConfig.RoutingRule{}, cfg.Routing.Rules...)
+
+		ruleAt := func(s string) (*domainConfig.RoutingRule, int, error) {
+			n, err := strconv.Atoi(s)
+			order := routing.RuleOrder()
+			if err != nil || n < 1 || n > len(order) {
+				return nil, 0, fmt.Errorf("規則序號無效: %s", s)
+			}
+			return &routing.Rules[order[n-1]], order[n-1], nil
+		}
+
+		var msgText string
+		switch field {
+		case "add":
+			rule, err := domainConfig.ParseRoutingRule(input)
+			if err != nil {
+				return msg.ConfigUpdateMsg{Err: err}
+			}
+			if rule.Priority == 0 {
+				rule.Priority = routing.NextRulePriority()
+			}
+			routing.Rules = append(routing.Rules, *rule)
+			msgText = fmt.Sprintf("已添加規則 [%d] => %s", rule.Priority, rule.Target())
+
+		case "delete":
+			_, idx, err := ruleAt(input)
+			if err != nil {
+				return msg.ConfigUpdateMsg{Err: err}
+			}
+			routing.Rules = append(routing.Rules[:idx], routing.Rules[idx+1:]...)
+			msgText = "規則已刪除"
+
+		case "priority":
+			parts := strings.Fields(input)
+			if len(parts) != 2 {
+				return msg.ConfigUpdateMsg{Err: fmt.Errorf("格式錯誤，應為: 序號 優先級")}
+			}
+			rule, _, err := ruleAt(parts[0])
+			if err != nil {
+				return msg.ConfigUpdateMsg{Err: err}
+			}
+			priority, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return msg.ConfigUpdateMsg{Err: fmt.Errorf("優先級無效: %s", parts[1])}
+			}
+			rule.Priority = priority
+			msgText = fmt.Sprintf("規則優先級已設置為 %d", priority)
+
+		case "toggle":
+			rule, _, err := ruleAt(input)
+			if err != nil {
+				return msg.ConfigUpdateMsg{Err: err}
+			}
+			rule.Disabled = !rule.Disabled
+			msgText = "規則已啓用"
+			if rule.Disabled {
+				msgText = "規則已禁用"
+			}
+
+		case "final":
+			if input == domainConfig.OutboundDirect {
+				input = ""
+			}
+			routing.Final = input
+			msgText = "默認出站已恢復直連"
+			if input != "" {
+				msgText = fmt.Sprintf("默認出站已設置為 %s", input)
+			}
+
+		default:
+			return nil
+		}
+
+		if err := routing.ValidateRules(); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		cfg.Routing.Rules = routing.Rules
+		cfg.Routing.Final = routing.Final
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   msgText,
+		}
+	}
+}
+
 // EnableRoutingWithTargetCmd 啓用帶目標 IP 的路由
 func (b *CommandBuilder) EnableRoutingWithTargetCmd(m *state.Manager, routeType, target string) tea.Cmd {
 	return func() tea.Msg {
@@ -1431,29 +1953,32 @@ func (b *CommandBuilder) UpdateSocks5OutboundAuthCmd(m *state.Manager, authStr s
 			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
 		}
 
-		parts := strings.SplitN(authStr, ":", 2)
-		if len(parts) == 2 {
-			cfg.Routing.Socks5.Outbound.Username = parts[0]
-			cfg.Routing.Socks5.Outbound.Password = parts[1]
-			return msg.ConfigUpdateMsg{NewConfig: cfg, Applied: false, Message: "Socks5 出站認證已更新"}
+		user, pass, ok := strings.Cut(authStr, ":")
+		if !ok {
+			user, pass = "", ""
 		}
-
-		cfg.Routing.Socks5.Outbound.Username = ""
-		cfg.Routing.Socks5.Outbound.Password = ""
-		return msg.ConfigUpdateMsg{NewConfig: cfg, Applied: false, Message: "Socks5 出站認證已清除"}
+		if err := cfg.Routing.SetSocks5Auth(user, pass); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+		if user == "" {
+			return msg.ConfigUpdateMsg{NewConfig: cfg, Applied: false, Message: "Socks5 出站認證已清除"}
+		}
+		return msg.ConfigUpdateMsg{NewConfig: cfg, Applied: false, Message: "Socks5 出站認證已更新"}
 	}
 }
 
-// SetSocks5GlobalCmd 設置 Socks5 全局轉發
-func (b *CommandBuilder) SetSocks5GlobalCmd(m *state.Manager, global bool) tea.Cmd {
+// ToggleSocks5GlobalCmd 切換 Socks5 全局轉發 (默認出站)
+func (b *CommandBuilder) ToggleSocks5GlobalCmd(m *state.Manager) tea.Cmd {
 	return func() tea.Msg {
 		cfg := m.Config().GetConfig()
 		if cfg == nil {
 			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
 		}
 
-		newState := !cfg.Routing.Socks5.Outbound.GlobalRoute
-		cfg.Routing.Socks5.Outbound.GlobalRoute = newState
+		newState := cfg.Routing.Final != domainConfig.Socks5OutboundTag
+		if err := cfg.Routing.SetSocks5Global(newState); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
 
 		status := "禁用"
 		if newState {
@@ -1492,6 +2017,7 @@ func (b *CommandBuilder) SetWARPStateCmd(m *state.Manager, enable bool) tea.Cmd
 }
 
 // UpdateWARPLicenseCmd 更新 WARP 許可證密鑰
+// 設備已註冊時立即向 Cloudflare 綁定許可證，否則僅保存，待註冊時自動綁定
 func (b *CommandBuilder) UpdateWARPLicenseCmd(m *state.Manager, license string) tea.Cmd {
 	return func() tea.Msg {
 		cfg := m.Config().GetConfig()
@@ -1500,11 +2026,90 @@ func (b *CommandBuilder) UpdateWARPLicenseCmd(m *state.Manager, license string)
 		}
 
 		license = strings.TrimSpace(license)
-		cfg.Routing.WARP.LicenseKey = license
 
-		msgText := "WARP 許可證已更新 (未保存)"
-		if license == "" {
-			msgText = "WARP 許可證已清除 (將使用免費版)"
+		if b.warpSvc == nil || !cfg.Routing.WARP.IsRegistered() || license == "" {
+			cfg.Routing.WARP.LicenseKey = license
+
+			msgText := "WARP 許可證已更新 (註冊設備時自動綁定)"
+			if license == "" {
+				msgText = "WARP 許可證已清除 (將使用免費版)"
+			}
+
+			return msg.ConfigUpdateMsg{
+				NewConfig: cfg,
+				Applied:   false,
+				Message:   msgText,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := b.warpSvc.BindLicense(ctx, cfg, license); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   "WARP+ 許可證綁定成功",
+		}
+	}
+}
+
+// RegisterWARPCmd 向 Cloudflare 註冊 WARP 設備
+func (b *CommandBuilder) RegisterWARPCmd(m *state.Manager) tea.Cmd {
+	return func() tea.Msg {
+		if b.warpSvc == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("WARPService 未初始化")}
+		}
+
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		// 純 IPv6 環境下優先使用 IPv6 接入點
+		preferIPv6 := cfg.Routing.DomainStrategy == "ipv6_only" || cfg.Routing.DomainStrategy == "prefer_ipv6"
+		if err := b.warpSvc.Register(ctx, cfg, preferIPv6); err != nil {
+			b.log.Error("WARP 註冊失敗", zap.Error(err))
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   fmt.Sprintf("WARP 設備註冊成功 (賬戶類型: %s)", cfg.Routing.WARP.AccountType),
+		}
+	}
+}
+
+// SetWARPEndpointCmd 設置 WARP 接入點
+func (b *CommandBuilder) SetWARPEndpointCmd(m *state.Manager, endpoint string) tea.Cmd {
+	return func() tea.Msg {
+		if b.warpSvc == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("WARPService 未初始化")}
+		}
+
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		if strings.EqualFold(strings.TrimSpace(endpoint), "default") {
+			endpoint = ""
+		}
+
+		if err := b.warpSvc.SetEndpoint(cfg, endpoint); err != nil {
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		msgText := "WARP 接入點已恢復默認"
+		if cfg.Routing.WARP.Endpoint != "" {
+			msgText = fmt.Sprintf("WARP 接入點已設置為 %s", cfg.Routing.WARP.Endpoint)
 		}
 
 		return msg.ConfigUpdateMsg{
@@ -1515,6 +2120,95 @@ func (b *CommandBuilder) UpdateWARPLicenseCmd(m *state.Manager, license string)
 	}
 }
 
+// TestWARPEndpointCmd 對當前 WARP 接入點進行握手測試
+func (b *CommandBuilder) TestWARPEndpointCmd(m *state.Manager) tea.Cmd {
+	return func() tea.Msg {
+		if b.warpSvc == nil {
+			return msg.CommandResultMsg{Success: false, Message: "WARPService 未初始化"}
+		}
+
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.CommandResultMsg{Success: false, Message: "配置未加載"}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		result, err := b.warpSvc.TestEndpoint(ctx, cfg)
+		if err != nil {
+			return msg.CommandResultMsg{Success: false, Message: fmt.Sprintf("測試失敗: %v", err)}
+		}
+		if !result.Reachable() {
+			return msg.CommandResultMsg{
+				Success: false,
+				Message: fmt.Sprintf("接入點 %s 無握手響應，建議執行優選", result.Endpoint),
+			}
+		}
+
+		return msg.CommandResultMsg{
+			Success: true,
+			Message: fmt.Sprintf("接入點 %s 可用 (延遲 %dms，丟包 %.0f%%)",
+				result.Endpoint, result.AvgRTT.Milliseconds(), result.Loss()*100),
+		}
+	}
+}
+
+// OptimizeWARPEndpointCmd 掃描候選接入點並寫入最優結果
+func (b *CommandBuilder) OptimizeWARPEndpointCmd(m *state.Manager) tea.Cmd {
+	return func() tea.Msg {
+		if b.warpSvc == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("WARPService 未初始化")}
+		}
+
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		// 本機具備 IPv6 時才探測 IPv6 接入段
+		includeIPv6 := m.System().Stats.IPv6 != ""
+		best, _, err := b.warpSvc.OptimizeEndpoint(ctx, cfg, includeIPv6)
+		if err != nil {
+			b.log.Error("WARP 接入點優選失敗", zap.Error(err))
+			return msg.ConfigUpdateMsg{Err: err}
+		}
+
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message: fmt.Sprintf("最優接入點 %s (延遲 %dms，丟包 %.0f%%)",
+				best.Endpoint, best.AvgRTT.Milliseconds(), best.Loss()*100),
+		}
+	}
+}
+
+// SetWARPAutoOptimizeCmd 設置定時任務是否自動優選接入點
+func (b *CommandBuilder) SetWARPAutoOptimizeCmd(m *state.Manager, enable bool) tea.Cmd {
+	return func() tea.Msg {
+		cfg := m.Config().GetConfig()
+		if cfg == nil {
+			return msg.ConfigUpdateMsg{Err: fmt.Errorf("配置未加載")}
+		}
+
+		cfg.Routing.WARP.AutoOptimize = enable
+
+		status := "已關閉"
+		if enable {
+			status = "已開啓"
+		}
+
+		return msg.ConfigUpdateMsg{
+			NewConfig: cfg,
+			Applied:   false,
+			Message:   fmt.Sprintf("WARP 自動優選%s", status),
+		}
+	}
+}
+
 // ========================================
 // 核心管理命令
 // ========================================
@@ -1909,15 +2603,51 @@ func extractBinaryFromTarGz(tarPath, targetFileName, destPath string) error {
 // 服務管理命令
 // ========================================
 
+// 服務日誌流參數
+const (
+	serviceLogBacklog = 200 // 打開時加載的歷史條數
+	serviceLogBuffer  = 256 // 後臺緩衝，滿時阻塞讀取
+	serviceLogBatch   = 200 // 單次刷新界面的最大條數
+)
+
 // FollowServiceLogCmd 跟蹤服務日誌
+// 優先讀取 systemd journal，不可用時回退到日誌目錄下的 sing-box.log
 func (b *CommandBuilder) FollowServiceLogCmd(m *state.Manager) tea.Cmd {
+	logFile := "sing-box.log"
+	if b.paths != nil {
+		logFile = filepath.Join(b.paths.LogDir, logFile)
+	}
+	src := logs.NewSource(infraSingbox.ServiceName, logFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, errs := logs.Stream(ctx, src, serviceLogBacklog, serviceLogBuffer)
+	m.Service().Log.Start(src.Name(), stream, errs, cancel)
+
+	return b.NextServiceLogCmd(stream, errs)
+}
+
+// NextServiceLogCmd 等待下一批日誌
+// 每批處理完才會讀取下一批，界面繁忙時後臺讀取隨之阻塞
+func (b *CommandBuilder) NextServiceLogCmd(stream <-chan logs.Entry, errs <-chan error) tea.Cmd {
 	return func() tea.Msg {
-		logs := []string{"[INFO] sing-box service started", "[INFO] running..."}
-		return msg.LogViewMsg{
-			Mode:      "service",
-			Logs:      logs,
-			Following: true,
+		e, ok := <-stream
+		if !ok {
+			return msg.ServiceLogMsg{Stream: stream, Closed: true, Err: <-errs}
+		}
+
+		batch := []logs.Entry{e}
+		for len(batch) < serviceLogBatch {
+			select {
+			case e, ok := <-stream:
+				if !ok {
+					return msg.ServiceLogMsg{Stream: stream, Entries: batch, Closed: true, Err: <-errs}
+				}
+				batch = append(batch, e)
+			default:
+				return msg.ServiceLogMsg{Stream: stream, Entries: batch}
+			}
 		}
+		return msg.ServiceLogMsg{Stream: stream, Entries: batch}
 	}
 }
 
@@ -2997,7 +3727,7 @@ func (b *CommandBuilder) LoadLogInfoCmd(m *state.Manager) tea.Cmd {
 
 		if size < 10*1024*1024 {
 			todayLines = countLogLines(logPath)
-			errCount = countErrorLines(logPath)
+			errCount = countTodayErrors(logPath)
 		} else {
 			todayLines = -1
 			errCount = -1
@@ -3016,6 +3746,9 @@ func (b *CommandBuilder) LoadLogInfoCmd(m *state.Manager) tea.Cmd {
 	}
 }
 
+// 日誌查詢超時 (歷史日誌可能較大)
+const logQueryTimeout = 30 * time.Second
+
 // ViewRealtimeLogCmd 查看實時日誌
 func (b *CommandBuilder) ViewRealtimeLogCmd(m *state.Manager) tea.Cmd {
 	return func() tea.Msg {
@@ -3046,19 +3779,70 @@ func (b *CommandBuilder) ViewFullLogCmd(m *state.Manager) tea.Cmd {
 	}
 }
 
-// ViewErrorLogCmd 查看錯誤日誌
+// ViewErrorLogCmd 查看錯誤日誌 (包含輪轉與壓縮的歷史日誌)
 func (b *CommandBuilder) ViewErrorLogCmd(m *state.Manager) tea.Cmd {
 	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), logQueryTimeout)
+		defer cancel()
+
 		logPath := filepath.Join(b.paths.LogDir, "prism.log")
-		logs, err := readLogFileWithFilter(logPath, []string{"ERROR", "FATAL"}, 1024*1024)
+		q := logs.Query{Filter: logs.Filter{MinLevel: logs.LevelError}}
+		entries, err := logs.SearchFiles(ctx, logPath, q)
 		return msg.LogViewMsg{
 			Mode: "error",
-			Logs: logs,
+			Logs: rawLogLines(entries),
 			Err:  err,
 		}
 	}
 }
 
+// QueryLogsCmd 按查詢表達式搜索 Prism 與 sing-box 日誌
+func (b *CommandBuilder) QueryLogsCmd(m *state.Manager, expr string) tea.Cmd {
+	return func() tea.Msg {
+		q, err := logs.ParseQuery(expr, time.Now())
+		if err != nil {
+			return msg.LogViewMsg{Mode: "query", Err: err}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), logQueryTimeout)
+		defer cancel()
+
+		entries, err := b.logSearcher().Search(ctx, q)
+		if err != nil {
+			return msg.LogViewMsg{Mode: "query", Err: err}
+		}
+
+		lines := []string{fmt.Sprintf("--- 查詢: %s (共 %d 條) ---", expr, len(entries))}
+		return msg.LogViewMsg{
+			Mode: "query",
+			Logs: append(lines, rawLogLines(entries)...),
+		}
+	}
+}
+
+// logSearcher 返回覆蓋 Prism 與 sing-box 日誌的查詢器
+func (b *CommandBuilder) logSearcher() *logs.Searcher {
+	return &logs.Searcher{
+		PrismLog:    filepath.Join(b.paths.LogDir, "prism.log"),
+		SingboxUnit: infraSingbox.ServiceName,
+		SingboxLog:  filepath.Join(b.paths.LogDir, "sing-box.log"),
+	}
+}
+
+// rawLogLines 返回日誌原始行，交由查看器按格式著色
+func rawLogLines(entries []logs.Entry) []string {
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Origin == logs.OriginSingbox {
+			// journal 記錄的原始行為 JSON，使用可讀格式
+			lines = append(lines, e.Detail())
+			continue
+		}
+		lines = append(lines, e.Raw)
+	}
+	return lines
+}
+
 // --- 高性能輔助函數 ---
 
 // readLastNLines 讀取文件最後 N 行
@@ -3121,25 +3905,6 @@ func readLastNBytes(path string, n int64) ([]string, error) {
 	return result, nil
 }
 
-// readLogFileWithFilter 優化版過濾
-func readLogFileWithFilter(path string, keywords []string, maxBytes int64) ([]string, error) {
-	rawLines, err := readLastNBytes(path, maxBytes)
-	if err != nil {
-		return nil, err
-	}
-
-	var filtered []string
-	for _, line := range rawLines {
-		for _, kw := range keywords {
-			if strings.Contains(line, kw) {
-				filtered = append(filtered, line)
-				break
-			}
-		}
-	}
-	return filtered, nil
-}
-
 // ChangeLogLevelCmd 修改日誌級別
 func (b *CommandBuilder) ChangeLogLevelCmd(m *state.Manager, newLevel string) tea.Cmd {
 	return func() tea.Msg {
@@ -3180,26 +3945,24 @@ func (b *CommandBuilder) ClearLogCmd(m *state.Manager) tea.Cmd {
 	}
 }
 
-// ExportLogCmd 導出日誌
-func (b *CommandBuilder) ExportLogCmd(m *state.Manager) tea.Cmd {
+// ExportDiagnosticsCmd 導出脫敏後的診斷包 (日誌、配置、防火牆、系統與證書信息)
+func (b *CommandBuilder) ExportDiagnosticsCmd(m *state.Manager) tea.Cmd {
+	coreVersion := m.Core().CoreVersion
 	return func() tea.Msg {
-		srcPath := filepath.Join(b.paths.LogDir, "prism.log")
-		dstPath := filepath.Join(os.TempDir(), fmt.Sprintf("prism-log-%s.log", time.Now().Format("20060102")))
-
-		src, err := os.Open(srcPath)
-		if err != nil {
-			return msg.CommandResultMsg{Success: false, Message: "無法讀取日誌"}
-		}
-		defer src.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
 
-		dst, err := os.Create(dstPath)
+		path, err := diag.Build(ctx, diag.Options{
+			Paths:       b.paths,
+			Firewall:    b.firewallMgr,
+			SysInfo:     b.sysInfo,
+			CoreVersion: coreVersion,
+			SingboxUnit: infraSingbox.ServiceName,
+		}, "")
 		if err != nil {
-			return msg.CommandResultMsg{Success: false, Message: "無法創建導出文件"}
+			return msg.CommandResultMsg{Success: false, Message: "生成診斷包失敗", Err: err}
 		}
-		defer dst.Close()
-
-		io.Copy(dst, src)
-		return msg.CommandResultMsg{Success: true, Message: "日誌已導出至 " + dstPath}
+		return msg.CommandResultMsg{Success: true, Message: "診斷包已導出至 " + path}
 	}
 }
 
@@ -3229,99 +3992,15 @@ func (b *CommandBuilder) GenerateProtocolLinksCmd(m *state.Manager) tea.Cmd {
 			}
 		}
 
-		if cfg.Protocols.RealityVision.Enabled {
-			p := cfg.Protocols.RealityVision
-			rawLink := fmt.Sprintf("vless://%s@%s:%d?encryption=none&flow=xtls-rprx-vision&security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s&type=tcp&headerType=none#Reality-Vision",
-				cfg.UUID, serverIP, p.Port, p.SNI, p.PublicKey, p.ShortID)
-
-			links = append(links, types.ProtocolLink{
-				Name: "VLESS Reality Vision",
-				URL:  rawLink,
-				Port: p.Port,
-			})
-		}
-
-		if cfg.Protocols.RealityGRPC.Enabled {
-			p := cfg.Protocols.RealityGRPC
-			serviceName := "grpc"
-			rawLink := fmt.Sprintf("vless://%s@%s:%d?encryption=none&security=reality&sni=%s&fp=chrome&pbk=%s&sid=%s&type=grpc&serviceName=%s&mode=gun#Reality-gRPC",
-				cfg.UUID, serverIP, p.Port, p.SNI, p.PublicKey, p.ShortID, serviceName)
-
-			links = append(links, types.ProtocolLink{
-				Name: "VLESS Reality gRPC",
-				URL:  rawLink,
-				Port: p.Port,
-			})
-		}
-
-		if cfg.Protocols.Hysteria2.Enabled {
-			p := cfg.Protocols.Hysteria2
-			pass := cfg.Password
-
-			rawLink := fmt.Sprintf("hysteria2://%s@%s:%d?sni=%s&insecure=1#Hysteria2",
-				pass, serverIP, p.Port, p.SNI)
-
-			links = append(links, types.ProtocolLink{
-				Name: "Hysteria 2",
-				URL:  rawLink,
-				Port: p.Port,
-			})
-		}
-
-		if cfg.Protocols.TUIC.Enabled {
-			p := cfg.Protocols.TUIC
-			rawLink := fmt.Sprintf("tuic://%s:%s@%s:%d?sni=%s&congestion_control=bbr&alpn=h3#TUIC-v5",
-				cfg.UUID, cfg.Password, serverIP, p.Port, p.SNI)
-
-			links = append(links, types.ProtocolLink{
-				Name: "TUIC v5",
-				URL:  rawLink,
-				Port: p.Port,
-			})
-		}
-
-		if cfg.Protocols.AnyTLS.Enabled {
-			p := cfg.Protocols.AnyTLS
-			rawLink := fmt.Sprintf("anytls://%s@%s:%d?sni=%s&idle_timeout=30s#AnyTLS",
-				cfg.UUID, serverIP, p.Port, p.SNI)
-
-			links = append(links, types.ProtocolLink{
-				Name: "AnyTLS",
-				URL:  rawLink,
-				Port: p.Port,
-			})
+		// 鏈接統一由協議描述符生成，與訂閱文件保持一致
+		if b.protoFactory == nil {
+			return msg.NodeInfoMsg{Err: fmt.Errorf("協議工廠未初始化，無法生成鏈接")}
 		}
-
-		if cfg.Protocols.AnyTLSReality.Enabled {
-			p := cfg.Protocols.AnyTLSReality
-			rawLink := fmt.Sprintf("anytls://%s@%s:%d?security=reality&sni=%s&pbk=%s&sid=%s&idle_timeout=30s#AnyTLS-Reality",
-				cfg.UUID, serverIP, p.Port, p.SNI, p.PublicKey, p.ShortID)
-
+		for _, l := range protocol.NodeLinks(b.protoFactory.FromConfig(cfg), serverIP) {
 			links = append(links, types.ProtocolLink{
-				Name: "AnyTLS Reality",
-				URL:  rawLink,
-				Port: p.Port,
-			})
-		}
-
-		if cfg.Protocols.ShadowTLS.Enabled {
-			p := cfg.Protocols.ShadowTLS
-
-			method := "2022-blake3-aes-128-gcm"
-			password := cfg.Password
-
-			ssUserInfo := base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", method, password)))
-
-			stlsPass := cfg.Password
-			pluginParam := fmt.Sprintf("host=%s;password=%s;version=3", p.SNI, stlsPass)
-
-			rawLink := fmt.Sprintf("ss://%s@%s:%d?plugin=shadowtls%%3B%s#ShadowTLS-v3",
-				ssUserInfo, serverIP, p.Port, pluginParam)
-
-			links = append(links, types.ProtocolLink{
-				Name: "ShadowTLS v3",
-				URL:  rawLink,
-				Port: p.Port,
+				Name: l.Name,
+				URL:  l.URL,
+				Port: l.Port,
 			})
 		}
 
@@ -3359,8 +4038,15 @@ func (b *CommandBuilder) GenerateSubscriptionCmd(m *state.Manager) tea.Cmd {
 
 		base64Content := base64.StdEncoding.EncodeToString([]byte(rawContent))
 
+		onlineURL := "(本機未部署訂閱後端，請使用離線訂閱)"
+		// 端口輪換會將最新訂閱寫入數據目錄，可由靜態文件服務對外發佈
+		subFile := filepath.Join(b.paths.DataDir, application.SubscriptionFileName)
+		if _, err := os.Stat(subFile); err == nil {
+			onlineURL = "訂閱文件: " + subFile + " (端口輪換時自動更新)"
+		}
+
 		subInfo := &types.SubscriptionInfo{
-			OnlineURL:  "(本機未部署訂閱後端，請使用離線訂閱)",
+			OnlineURL:  onlineURL,
 			OfflineURL: base64Content,
 			UpdateTime: time.Now().Format("2006-01-02 15:04:05"),
 			NodeCount:  len(linkMsg.Links),
@@ -3744,6 +4430,7 @@ func (b *CommandBuilder) UninstallPrismCmd(m *state.Manager) tea.Cmd {
 		}
 		os.Remove("/etc/systemd/system/sing-box.service")
 		b.executor.Execute(ctx, "systemctl", "daemon-reload")
+		addStep("移除偽裝站點服務", b.singboxSvc.RemoveDecoyServer(ctx))
 
 		if binPath, err := exec.LookPath("sing-box"); err == nil {
 			err := os.Remove(binPath)
@@ -3873,20 +4560,17 @@ func countLogLines(path string) int {
 	return count
 }
 
-func countErrorLines(path string) int {
-	file, err := os.Open(path)
-	if err != nil {
-		return 0
-	}
-	defer file.Close()
-	count := 0
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, "ERROR") || strings.Contains(line, "FATAL") {
-			count++
-		}
+// countTodayErrors 統計今日的錯誤日誌數 (包含當日輪轉的文件)
+func countTodayErrors(path string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), logQueryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	q := logs.Query{
+		Filter: logs.Filter{MinLevel: logs.LevelError},
+		Since:  time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
 	}
+	count, _ := logs.CountFiles(ctx, path, q)
 	return count
 }
 
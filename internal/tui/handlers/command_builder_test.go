@@ -3,6 +3,8 @@ package handlers
 import (
 	"testing"
 	"time"
+
+	"github.com/Yat-Muk/prism-v2/internal/application"
 )
 
 // 測試 formatBytes 函數 (CommandBuilder 內部的私有函數)
@@ -54,3 +56,37 @@ func TestFormatDuration(t *testing.T) {
 		})
 	}
 }
+
+// 測試流量面板數據的格式化
+func TestToTrafficDashboard(t *testing.T) {
+	d := &application.TrafficDashboard{
+		UpRate:      1024,
+		DownRate:    2048,
+		MemoryInUse: 1048576,
+		TotalConns:  1,
+		SampledAt:   time.Date(2026, 5, 1, 12, 30, 5, 0, time.UTC),
+		Inbounds:    []application.InboundTraffic{{Tag: "tuic-in", Connections: 1, DownRate: 1536}},
+		Connections: []application.ConnectionStat{{
+			ID: "c1", Inbound: "tuic-in", Upload: 500, Download: 1024, DownRate: 1536, Duration: 90 * time.Second,
+		}},
+		TopDestinations: []application.DestinationTraffic{{Host: "example.com", Connections: 1, Upload: 512, Download: 512}},
+	}
+
+	out := toTrafficDashboard(d)
+	if out.UpRate != "1.00 KB/s" || out.DownRate != "2.00 KB/s" || out.Memory != "1.00 MB" {
+		t.Errorf("總覽格式錯誤: %+v", out)
+	}
+	if out.UpdateTime != "12:30:05" {
+		t.Errorf("更新時間格式錯誤: %s", out.UpdateTime)
+	}
+	if out.Inbounds[0].DownRate != "1.50 KB/s" {
+		t.Errorf("入站速率格式錯誤: %+v", out.Inbounds[0])
+	}
+	c := out.Connections[0]
+	if c.ID != "c1" || c.Traffic != "↑ 500 B ↓ 1.00 KB" || c.Duration != "1分鐘" {
+		t.Errorf("連接格式錯誤: %+v", c)
+	}
+	if out.TopDestinations[0].Total != "1.00 KB" {
+		t.Errorf("目標流量格式錯誤: %+v", out.TopDestinations[0])
+	}
+}
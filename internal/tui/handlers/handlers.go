@@ -26,4 +26,6 @@ type Config struct {
 	Executor        system.Executor
 	FirewallMgr     firewall.Manager
 	ProtoFactory    protocol.Factory
+	WARPService     *application.WARPService
+	TrafficService  *application.TrafficService
 }
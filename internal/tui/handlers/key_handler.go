@@ -7,8 +7,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
 	"github.com/Yat-Muk/prism-v2/internal/pkg/inputvalidator"
 	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
 	"github.com/Yat-Muk/prism-v2/internal/tui/state"
@@ -141,6 +144,12 @@ func (h *KeyHandler) handleInputSubmit(m *state.Manager, view state.View) (*stat
 		return h.submitSNIEdit(m, input)
 	case state.UUIDEditView:
 		return h.submitUUIDEdit(m, input)
+	case state.InboundRoutingView:
+		return h.submitInboundRouting(m, input)
+	case state.ProtocolOptionsView:
+		return h.submitProtocolOptions(m, input)
+	case state.BrutalView:
+		return h.submitHy2Bandwidth(m, input)
 	case state.AnyTLSPaddingView:
 		return h.submitAnyTLSPadding(m, input)
 
@@ -151,6 +160,12 @@ func (h *KeyHandler) handleInputSubmit(m *state.Manager, view state.View) (*stat
 		return h.submitOutboundMenu(m, input)
 	case state.WARPRoutingView:
 		return h.submitWARPRouting(m, input)
+	case state.WARPConfigView:
+		return h.submitWARPConfig(m, input)
+	case state.CustomOutboundView:
+		return h.submitCustomOutbound(m, input)
+	case state.RoutingRulesView:
+		return h.submitRoutingRules(m, input)
 	case state.Socks5RoutingView:
 		return h.submitSocks5Routing(m, input)
 	case state.Socks5InboundView:
@@ -178,9 +193,11 @@ func (h *KeyHandler) handleInputSubmit(m *state.Manager, view state.View) (*stat
 	case state.ServiceMenuView:
 		return h.submitServiceMenu(m, input)
 	case state.ServiceLogView:
-		return m, nil
+		return h.submitServiceLog(m, input)
 	case state.ServiceHealthView:
 		return m, nil
+	case state.TrafficDashboardView:
+		return h.submitTrafficDashboard(m, input)
 
 	// --- 工具箱 ---
 	case state.ToolsMenuView:
@@ -206,9 +223,14 @@ func (h *KeyHandler) handleInputSubmit(m *state.Manager, view state.View) (*stat
 			m.Tools().IsViewingFail2BanLogs = false
 			return m, m.UI().SwitchView(state.Fail2BanMenuView)
 		}
+		if m.Log().IsQueryResult {
+			return m, m.UI().SwitchView(state.LogQueryView)
+		}
 		return m, m.UI().SwitchView(state.LogMenuView)
 	case state.LogLevelEditView:
 		return h.submitLogLevelEdit(m, input)
+	case state.LogQueryView:
+		return h.submitLogQuery(m, input)
 
 	// --- 節點信息 ---
 	case state.NodeInfoView:
@@ -335,14 +357,9 @@ func (h *KeyHandler) submitInstallWizard(m *state.Manager, input string) (*state
 	selected := m.Install().InstallProtocols
 	m.Config().EnabledProtocols = selected
 
-	p := &m.Config().Config.Protocols
-	p.RealityVision.Enabled = contains(selected, 1)
-	p.RealityGRPC.Enabled = contains(selected, 2)
-	p.Hysteria2.Enabled = contains(selected, 3)
-	p.TUIC.Enabled = contains(selected, 4)
-	p.AnyTLS.Enabled = contains(selected, 5)
-	p.AnyTLSReality.Enabled = contains(selected, 6)
-	p.ShadowTLS.Enabled = contains(selected, 7)
+	for _, id := range protocol.AllIDs() {
+		protocol.SetEnabled(m.Config().Config, id, contains(selected, int(id)))
+	}
 
 	// 3. 設置狀態並開始全自動流程
 	m.Install().ResetLogs()
@@ -416,6 +433,12 @@ func (h *KeyHandler) submitConfigMenu(m *state.Manager, input string) (*state.Ma
 		return m, m.UI().SwitchView(state.PortEditView)
 	case constants.KeyConfig_Padding:
 		return m, m.UI().SwitchView(state.AnyTLSPaddingView)
+	case constants.KeyConfig_Routing:
+		return m, m.UI().SwitchView(state.InboundRoutingView)
+	case constants.KeyConfig_Options:
+		return m, m.UI().SwitchView(state.ProtocolOptionsView)
+	case constants.KeyConfig_Bandwidth:
+		return m, m.UI().SwitchView(state.BrutalView)
 
 	case constants.KeyConfig_Reset: // "r"
 		cfgState.ConfirmMode = true
@@ -429,18 +452,75 @@ func (h *KeyHandler) submitConfigMenu(m *state.Manager, input string) (*state.Ma
 }
 
 func (h *KeyHandler) submitProtocolMenu(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
-	newEnabledIDs := toggleIntList(m.Config().EnabledProtocols, input)
+	cfg := m.Config().Config
+	protocolSvc := h.cmdBuilder.protocolSvc
+
+	// 實例管理: "+<編號> <標籤> [端口] [SNI]" 添加，"-<標籤>" 刪除
+	switch {
+	case strings.HasPrefix(input, "+"):
+		fields := strings.Fields(strings.TrimPrefix(input, "+"))
+		if len(fields) < 2 {
+			m.UI().SetStatus(state.StatusError, "格式: +編號 標籤 [端口] [SNI]", "", false)
+			return m, nil
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			m.UI().SetStatus(state.StatusError, "無效的協議編號: "+fields[0], "", false)
+			return m, nil
+		}
+		port, sni := 0, ""
+		if len(fields) > 2 {
+			if port, err = strconv.Atoi(fields[2]); err != nil {
+				m.UI().SetStatus(state.StatusError, "端口必須是數字", "", false)
+				return m, nil
+			}
+		}
+		if len(fields) > 3 {
+			sni = fields[3]
+		}
+		if err := protocolSvc.AddInstance(cfg, id, fields[1], port, sni); err != nil {
+			m.UI().SetStatus(state.StatusError, err.Error(), "", false)
+			return m, nil
+		}
+		h.markConfigChanged(m)
+		m.UI().SetStatus(state.StatusInfo, "已添加協議實例 "+fields[1]+" (未保存)", "", false)
+		return m, nil
+
+	case strings.HasPrefix(input, "-"):
+		tag := strings.TrimSpace(strings.TrimPrefix(input, "-"))
+		if err := protocolSvc.RemoveInstance(cfg, tag); err != nil {
+			m.UI().SetStatus(state.StatusError, err.Error(), "", false)
+			return m, nil
+		}
+		h.markConfigChanged(m)
+		m.UI().SetStatus(state.StatusInfo, "已刪除協議實例 "+tag+" (未保存)", "", false)
+		return m, nil
+	}
+
+	// 編號切換主實例，標籤切換其餘實例
+	var ids []string
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, part)
+			continue
+		}
+		if err := protocolSvc.ToggleInstance(cfg, part); err != nil {
+			m.UI().SetStatus(state.StatusError, err.Error(), "", false)
+			return m, nil
+		}
+	}
+
+	newEnabledIDs := toggleIntList(m.Config().EnabledProtocols, strings.Join(ids, ","))
 	m.Config().EnabledProtocols = newEnabledIDs
 
 	// 同步到內存 Config
-	p := &m.Config().Config.Protocols
-	p.RealityVision.Enabled = contains(newEnabledIDs, 1)
-	p.RealityGRPC.Enabled = contains(newEnabledIDs, 2)
-	p.Hysteria2.Enabled = contains(newEnabledIDs, 3)
-	p.TUIC.Enabled = contains(newEnabledIDs, 4)
-	p.AnyTLS.Enabled = contains(newEnabledIDs, 5)
-	p.AnyTLSReality.Enabled = contains(newEnabledIDs, 6)
-	p.ShadowTLS.Enabled = contains(newEnabledIDs, 7)
+	for _, id := range protocol.AllIDs() {
+		protocol.SetEnabled(cfg, id, contains(newEnabledIDs, int(id)))
+	}
 
 	h.markConfigChanged(m)
 	m.UI().SetStatus(state.StatusInfo, "協議狀態已更新 (未保存)", "", false)
@@ -453,9 +533,8 @@ func (h *KeyHandler) submitPortEdit(m *state.Manager, input string) (*state.Mana
 		protoID := m.Port().PortEditingProtocol
 		m.Port().CancelPortEdit()
 
-		h.markConfigChanged(m)
-		m.UI().SetStatus(state.StatusInfo, "端口已更新 (未保存)", "", false)
-
+		// 衝突檢查在服務層完成，結果由 ConfigUpdateMsg 回報
+		m.UI().SetStatus(state.StatusInfo, "正在檢查端口可用性...", "", false)
 		return m, h.cmdBuilder.UpdateSinglePortCmd(m, protoID, input)
 	}
 
@@ -470,7 +549,7 @@ func (h *KeyHandler) submitPortEdit(m *state.Manager, input string) (*state.Mana
 	if m.Config().ConfirmMode {
 		if strings.EqualFold(input, "y") {
 			m.Config().ConfirmMode = false
-			m.UI().SetStatus(state.StatusInfo, "已重置所有端口 (未保存)", "", true)
+			m.UI().SetStatus(state.StatusInfo, "正在重置所有端口...", "", true)
 			return m, h.cmdBuilder.ResetPortsCmd(m)
 		} else {
 			m.Config().ConfirmMode = false
@@ -521,28 +600,20 @@ func (h *KeyHandler) submitPortEdit(m *state.Manager, input string) (*state.Mana
 func (h *KeyHandler) submitHy2PortEdit(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
 	if m.Port().PortEditingMode {
 		if m.Port().Hy2EditingHopping {
-			// 1. 調用 validator 包進行解析
-			start, end, err := inputvalidator.ParsePortRange(input)
+			// 格式與重疊檢查在服務層完成
+			m.Port().CancelPortEdit()
+			m.UI().SetStatus(state.StatusInfo, "正在檢查跳躍範圍...", "", true)
 
-			if err != nil {
-				m.UI().SetStatus(state.StatusError, err.Error(), "示例: 20000-30000", false)
-				return m, nil
-			}
+			return m, h.cmdBuilder.UpdateHy2HoppingCmd(m, input)
 
-			// 2. 業務執行
+		} else if m.Port().Hy2EditingInterval {
 			m.Port().CancelPortEdit()
-			// 記錄到 state (可選)
-			m.Port().Hy2HoppingRange = input
-			h.markConfigChanged(m)
-			m.UI().SetStatus(state.StatusInfo, fmt.Sprintf("跳躍端口已設置為 %d-%d (未保存)", start, end), "", true)
-
-			return m, h.cmdBuilder.UpdateHy2HoppingCmd(m, start, end)
+			return m, h.cmdBuilder.UpdateHy2HopIntervalCmd(m, input)
 
 		} else {
 			// 提交主端口 (ID 3)
 			m.Port().CancelPortEdit()
-			h.markConfigChanged(m)
-			m.UI().SetStatus(state.StatusInfo, "主端口已更新 (未保存)", input, true)
+			m.UI().SetStatus(state.StatusInfo, "正在檢查端口可用性...", input, true)
 			return m, h.cmdBuilder.UpdateSinglePortCmd(m, 3, input)
 		}
 	}
@@ -561,7 +632,12 @@ func (h *KeyHandler) submitHy2PortEdit(m *state.Manager, input string) (*state.M
 		if currentRange == "" {
 			currentRange = "未設置"
 		}
-		m.UI().SetStatus(state.StatusInfo, fmt.Sprintf("請輸入端口跳躍範圍 (當前: %s)", currentRange), "示例: 20000-30000", true)
+		m.UI().SetStatus(state.StatusInfo, fmt.Sprintf("請輸入端口跳躍範圍 (當前: %s)", currentRange), "示例: 20000-30000,40000-45000", true)
+		return m, nil
+
+	case constants.KeyPort_HopInterval: // "4"
+		m.Port().StartHy2IntervalEdit()
+		m.UI().SetStatus(state.StatusInfo, "請輸入客戶端跳躍間隔 (秒，最少 5)", "示例: 30，輸入 0 恢復默認", true)
 		return m, nil
 
 	case constants.KeyPort_ClearHopping: // "3"
@@ -574,10 +650,10 @@ func (h *KeyHandler) submitHy2PortEdit(m *state.Manager, input string) (*state.M
 func (h *KeyHandler) submitSNIEdit(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
 	if inputvalidator.ValidateDomainInput(input) == nil {
 		p := &m.Config().Config.Protocols
-		p.RealityVision.SNI = input
-		p.RealityGRPC.SNI = input
-		p.AnyTLSReality.SNI = input
-		p.ShadowTLS.SNI = input
+		p.RealityVision().SNI = input
+		p.RealityGRPC().SNI = input
+		p.AnyTLSReality().SNI = input
+		p.ShadowTLS().SNI = input
 
 		h.markConfigChanged(m)
 		m.UI().SetStatus(state.StatusInfo, "SNI 已更新 (未保存)", "", false)
@@ -607,18 +683,248 @@ func (h *KeyHandler) submitUUIDEdit(m *state.Manager, input string) (*state.Mana
 	return m, nil
 }
 
+func (h *KeyHandler) submitInboundRouting(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	if m.Routing().IsEditing() {
+		field := m.Routing().EditingField
+		m.Routing().StopEditing()
+		m.UI().ClearInput()
+		return m, h.cmdBuilder.UpdateInboundRoutingCmd(m, field, input)
+	}
+
+	switch input {
+	case constants.KeyInbound_Outbound:
+		m.Routing().StartEditing("inbound", "outbound")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 協議編號 出站標籤", "例如: 3 warp-out (只輸入編號則清除)", true)
+		return m, nil
+	case constants.KeyInbound_Block:
+		m.Routing().StartEditing("inbound", "block")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 協議編號 屏蔽類別", "例如: 3 ads,bittorrent (只輸入編號則清除)", true)
+		return m, nil
+	case constants.KeyInbound_IPv6:
+		m.Routing().StartEditing("inbound", "ipv6")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 協議編號 IPv6 偏好", "例如: 1 prefer_ipv6 (只輸入編號則清除)", true)
+		return m, nil
+	case constants.KeyInbound_Clear:
+		m.Routing().StartEditing("inbound", "clear")
+		m.UI().SetStatus(state.StatusInfo, "請輸入要清除策略的協議編號", "", true)
+		return m, nil
+	}
+	return m, nil
+}
+
+// protocolOptionPrompts 協議高級參數菜單項對應的字段與輸入提示
+var protocolOptionPrompts = map[string]struct {
+	field   string
+	example string
+}{
+	constants.KeyOption_ALPN:              {config.OptionALPN, "例如: 4 h3 或 tuic-2 h3,spdy/3.1"},
+	constants.KeyOption_CongestionControl: {config.OptionCongestionControl, "例如: 4 cubic"},
+	constants.KeyOption_ZeroRTT:           {config.OptionZeroRTT, "例如: 4 on"},
+	constants.KeyOption_Username:          {config.OptionUsername, "例如: 5 alice 或 10 alice"},
+	constants.KeyOption_ShortID:           {config.OptionShortID, "例如: 6 0123abcd"},
+	constants.KeyOption_SSMethod:          {config.OptionSSMethod, "例如: 7 2022-blake3-aes-256-gcm 或 9 2022-blake3-aes-128-gcm"},
+	constants.KeyOption_SSPassword:        {config.OptionSSPassword, "例如: 7 <密碼> (ShadowTLS 只輸入編號則沿用全局密碼，Shadowsocks 則重新生成密鑰)"},
+	constants.KeyOption_DetourPort:        {config.OptionDetourPort, "例如: 7 10001"},
+	constants.KeyOption_StrictMode:        {config.OptionStrictMode, "例如: 7 off"},
+	constants.KeyOption_CDNProxy:          {config.OptionCDNProxy, "例如: 8 trojan"},
+	constants.KeyOption_CDNTransport:      {config.OptionCDNTransport, "例如: 8 httpupgrade"},
+	constants.KeyOption_CDNPath:           {config.OptionCDNPath, "例如: 8 /ray"},
+	constants.KeyOption_CDNHost:           {config.OptionCDNHost, "例如: 8 cdn.example.com"},
+	constants.KeyOption_CDNServiceName:    {config.OptionCDNServiceName, "例如: 8 prism-grpc"},
+	constants.KeyOption_CDNAddress:        {config.OptionCDNAddress, "例如: 8 cdn.example.com 或優選 IP"},
+	constants.KeyOption_CDNPort:           {config.OptionCDNPort, "例如: 8 443"},
+	constants.KeyOption_SSUserAdd:         {config.OptionSSUserAdd, "例如: 9 alice (密鑰自動生成) 或 9 alice <Base64 密鑰>"},
+	constants.KeyOption_SSRelayAdd:        {config.OptionSSRelayAdd, "例如: 9 hk 203.0.113.5:8388 <目標 Base64 密鑰>"},
+	constants.KeyOption_SSRemove:          {config.OptionSSRemove, "例如: 9 alice"},
+	constants.KeyOption_NaiveQUIC:         {config.OptionNaiveQUIC, "例如: 10 on"},
+	constants.KeyOption_Decoy:             {config.OptionDecoy, "例如: 3 file / 3 proxy https://example.com / 8 file / 8 proxy http://127.0.0.1:8080 / 3 off"},
+	constants.KeyOption_UDPRelayMode:      {config.OptionUDPRelayMode, "例如: 4 quic"},
+	constants.KeyOption_AuthTimeout:       {config.OptionAuthTimeout, "例如: 4 5s"},
+	constants.KeyOption_Heartbeat:         {config.OptionHeartbeat, "例如: 4 15s"},
+}
+
+func (h *KeyHandler) submitProtocolOptions(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	if m.Routing().IsEditing() {
+		field := m.Routing().EditingField
+		m.Routing().StopEditing()
+		m.UI().ClearInput()
+		return m, h.cmdBuilder.UpdateProtocolOptionCmd(m, field, input)
+	}
+
+	prompt, ok := protocolOptionPrompts[input]
+	if !ok {
+		m.UI().SetStatus(state.StatusError, "無效選項", "", false)
+		return m, nil
+	}
+	m.Routing().StartEditing("protocol_option", prompt.field)
+	m.UI().SetStatus(state.StatusInfo, "請輸入 協議編號或實例標籤 值", prompt.example+" (只輸入編號則恢復默認)", true)
+	return m, nil
+}
+
+// submitHy2Bandwidth Hysteria2 帶寬檢測、推薦值應用與 Brutal 開關
+func (h *KeyHandler) submitHy2Bandwidth(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	if m.Routing().IsEditing() {
+		field := m.Routing().EditingField
+		m.Routing().StopEditing()
+		m.UI().ClearInput()
+		return m, h.cmdBuilder.UpdateProtocolOptionCmd(m, field, input)
+	}
+
+	switch input {
+	case constants.KeyBandwidth_Detect:
+		if m.Config().IsMeasuringBandwidth {
+			return m, nil
+		}
+		m.Config().IsMeasuringBandwidth = true
+		m.UI().SetStatus(state.StatusInfo, "正在檢測帶寬...", "", true)
+		return m, h.cmdBuilder.MeasureHy2BandwidthCmd()
+	case constants.KeyBandwidth_Apply:
+		info := m.Config().Bandwidth
+		if info == nil {
+			m.UI().SetStatus(state.StatusError, "請先檢測帶寬", "", false)
+			return m, nil
+		}
+		return m, h.cmdBuilder.ApplyHy2BandwidthCmd(m, info.RecommendedMbps)
+	case constants.KeyBandwidth_Manual:
+		m.Routing().StartEditing("hy2_bandwidth", config.OptionBandwidth)
+		m.UI().SetStatus(state.StatusInfo, "請輸入 協議編號或實例標籤 上行 [下行] (Mbps)", "例如: 3 200 500 (只輸入編號則恢復默認 100)", true)
+		return m, nil
+	case constants.KeyBandwidth_Brutal:
+		m.Routing().StartEditing("hy2_bandwidth", config.OptionBrutal)
+		m.UI().SetStatus(state.StatusInfo, "請輸入 協議編號或實例標籤 on/off", "例如: 3 off (關閉後客戶端改用 BBR)", true)
+		return m, nil
+	}
+	m.UI().SetStatus(state.StatusError, "無效選項", "", false)
+	return m, nil
+}
+
 func (h *KeyHandler) submitAnyTLSPadding(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
-	if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= 5 {
-		modes := []string{"balanced", "minimal", "high_resist", "video", "official"}
-		m.Config().Config.Protocols.AnyTLS.PaddingMode = modes[n-1]
-		m.Config().Config.Protocols.AnyTLSReality.PaddingMode = modes[n-1]
+	cfg := m.Config().GetConfig()
+	if cfg == nil {
+		return m, nil
+	}
+	if m.Routing().IsEditing() {
+		field := m.Routing().EditingField
+		m.Routing().StopEditing()
+		m.UI().ClearInput()
+		return h.editAnyTLSPadding(m, cfg, field, strings.TrimSpace(input))
+	}
+
+	if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(config.PaddingPresetModes) {
+		cfg.Protocols.SetPadding(config.PaddingPresetModes[n-1], nil)
+		m.Config().PaddingDraft = nil
 		h.markConfigChanged(m)
 		m.UI().SetStatus(state.StatusInfo, "Padding 策略已更新 (未保存)", "", false)
 		return m, nil
 	}
+
+	prompts := map[string][2]string{
+		constants.KeyPadding_Edit:    {"scheme", "輸入 鍵=值，多行以 ; 分隔，例如: stop=6;5=200-400,c,300-600 (鍵= 刪除該行，留空則重置草稿)"},
+		constants.KeyPadding_Preview: {"preview", "輸入預覽的包數，例如: 4 (留空則按 stop)"},
+		constants.KeyPadding_Save:    {"save", "輸入方案名稱，例如: mobile"},
+		constants.KeyPadding_Load:    {"load", "輸入已保存的方案名稱"},
+		constants.KeyPadding_Delete:  {"delete", "輸入要刪除的方案名稱"},
+	}
+	if prompt, ok := prompts[input]; ok {
+		m.Routing().StartEditing("anytls_padding", prompt[0])
+		m.UI().SetStatus(state.StatusInfo, "請輸入", prompt[1], true)
+		return m, nil
+	}
+
+	if input == constants.KeyPadding_Apply {
+		draft := m.Config().PaddingDraft
+		if len(draft) == 0 {
+			m.UI().SetStatus(state.StatusError, "請先編輯方案", "", false)
+			return m, nil
+		}
+		if _, err := config.ParsePaddingScheme(draft); err != nil {
+			m.UI().SetStatus(state.StatusError, "方案語法錯誤", err.Error(), true)
+			return m, nil
+		}
+		cfg.Protocols.SetPadding(config.PaddingModeCustom, draft)
+		m.Config().PaddingDraft = nil
+		h.markConfigChanged(m)
+		m.UI().SetStatus(state.StatusInfo, "自定義 Padding 方案已應用 (未保存)", "", false)
+		return m, nil
+	}
+
+	m.UI().SetStatus(state.StatusError, "無效選項", "", false)
 	return m, nil
 }
 
+// editAnyTLSPadding 處理填充方案編輯器的輸入
+func (h *KeyHandler) editAnyTLSPadding(m *state.Manager, cfg *config.Config, field, input string) (*state.Manager, tea.Cmd) {
+	cs := m.Config()
+	switch field {
+	case "scheme":
+		current := cs.PaddingDraft
+		if len(current) == 0 || input == "" {
+			current = currentPaddingScheme(cfg)
+		}
+		draft, err := config.MergePaddingLines(current, config.SplitPaddingScheme(input))
+		if err != nil {
+			m.UI().SetStatus(state.StatusError, "輸入格式錯誤", err.Error(), true)
+			return m, nil
+		}
+		cs.PaddingDraft = draft
+		if _, err := config.ParsePaddingScheme(draft); err != nil {
+			m.UI().SetStatus(state.StatusError, "草稿已更新，但存在語法錯誤", err.Error(), true)
+			return m, nil
+		}
+		m.UI().SetStatus(state.StatusSuccess, "草稿語法正確，已更新預覽", "按 "+constants.KeyPadding_Apply+" 應用到 AnyTLS 實例", true)
+
+	case "preview":
+		n := 0
+		if input != "" {
+			v, err := strconv.Atoi(input)
+			if err != nil || v < 1 || v > config.PaddingMaxPackets {
+				m.UI().SetStatus(state.StatusError, fmt.Sprintf("包數應為 1-%d", config.PaddingMaxPackets), "", false)
+				return m, nil
+			}
+			n = v
+		}
+		cs.PaddingPreview = n
+
+	case "save":
+		scheme := cs.PaddingDraft
+		if len(scheme) == 0 {
+			scheme = currentPaddingScheme(cfg)
+		}
+		if err := cfg.Protocols.SavePaddingProfile(input, scheme); err != nil {
+			m.UI().SetStatus(state.StatusError, "保存失敗", err.Error(), true)
+			return m, nil
+		}
+		h.markConfigChanged(m)
+		m.UI().SetStatus(state.StatusInfo, fmt.Sprintf("方案 %s 已保存 (未寫入磁盤)", input), "", false)
+
+	case "load":
+		profile := cfg.Protocols.FindPaddingProfile(input)
+		if profile == nil {
+			m.UI().SetStatus(state.StatusError, "未找到填充方案: "+input, "", false)
+			return m, nil
+		}
+		cs.PaddingDraft = nil
+		cfg.Protocols.SetPadding(config.PaddingModeCustom, profile.Scheme)
+		h.markConfigChanged(m)
+		m.UI().SetStatus(state.StatusInfo, fmt.Sprintf("已應用方案 %s (未保存)", input), "", false)
+
+	case "delete":
+		if err := cfg.Protocols.DeletePaddingProfile(input); err != nil {
+			m.UI().SetStatus(state.StatusError, err.Error(), "", false)
+			return m, nil
+		}
+		h.markConfigChanged(m)
+		m.UI().SetStatus(state.StatusInfo, fmt.Sprintf("方案 %s 已刪除 (未保存)", input), "", false)
+	}
+	return m, nil
+}
+
+// currentPaddingScheme 返回 AnyTLS 主實例當前生效的填充方案
+func currentPaddingScheme(cfg *config.Config) []string {
+	c := cfg.Protocols.AnyTLS()
+	return config.EffectivePaddingScheme(c.PaddingMode, c.PaddingScheme)
+}
+
 // --- 出口策略 ---
 
 func (h *KeyHandler) submitOutboundMenu(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
@@ -698,6 +1004,12 @@ func (h *KeyHandler) submitRouteMenu(m *state.Manager, input string) (*state.Man
 		cmd1 := m.UI().SwitchView(state.SNIProxyRoutingView)
 		cmd2 := h.cmdBuilder.LoadSNIProxyConfigCmd(m)
 		return m, tea.Batch(cmd1, cmd2)
+
+	case constants.KeyRoute_Outbound:
+		return m, m.UI().SwitchView(state.CustomOutboundView)
+
+	case constants.KeyRoute_Rules:
+		return m, m.UI().SwitchView(state.RoutingRulesView)
 	}
 	return m, nil
 }
@@ -715,6 +1027,10 @@ func (h *KeyHandler) submitWARPRouting(m *state.Manager, input string) (*state.M
 			return m, h.cmdBuilder.UpdateWARPLicenseCmd(m, val)
 		}
 
+		if field == "endpoint" {
+			return m, h.cmdBuilder.SetWARPEndpointCmd(m, val)
+		}
+
 		if field == "domains" && val != "" {
 			return m, h.cmdBuilder.AddRoutingDomainCmd(m, "warp", val)
 		}
@@ -751,11 +1067,145 @@ func (h *KeyHandler) submitWARPRouting(m *state.Manager, input string) (*state.M
 		m.UI().SetStatus(state.StatusInfo, "請輸入分流域名", "例如: chatgpt.com (按 Enter 確認)", true)
 		return m, nil
 
+	case constants.KeyWARP_Register:
+		m.UI().SetStatus(state.StatusInfo, "正在向 Cloudflare 註冊 WARP 設備...", "請稍候", true)
+		return m, h.cmdBuilder.RegisterWARPCmd(m)
+
+	case constants.KeyWARP_SetEndpoint:
+		m.Routing().StartEditing("warp", "endpoint")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 WARP 接入點", "格式 IP:端口 或 [IPv6]:端口 (輸入 default 恢復默認)", true)
+		return m, nil
+
 	case constants.KeyWARP_ShowConfig:
 		return m, h.cmdBuilder.ShowWARPConfigCmd(m)
 
 	case constants.KeyWARP_Disable:
 		return m, h.cmdBuilder.SetWARPStateCmd(m, false)
+
+	case constants.KeyWARP_Outbound:
+		return m, m.UI().SwitchView(state.WARPConfigView)
+	}
+	return m, nil
+}
+
+func (h *KeyHandler) submitWARPConfig(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	if m.Routing().IsEditing() {
+		field := m.Routing().EditingField
+		m.Routing().StopEditing()
+		m.UI().ClearInput()
+
+		if field == "license" {
+			return m, h.cmdBuilder.UpdateWARPLicenseCmd(m, input)
+		}
+		return m, nil
+	}
+
+	switch input {
+	case constants.KeyWARPConfig_Enable:
+		return m, h.cmdBuilder.SetWARPStateCmd(m, true)
+
+	case constants.KeyWARPConfig_Disable:
+		return m, h.cmdBuilder.SetWARPStateCmd(m, false)
+
+	case constants.KeyWARPConfig_License:
+		m.Routing().StartEditing("warp", "license")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 WARP+ 密鑰", "留空則使用免費版 (按 Enter 確認)", true)
+		return m, nil
+
+	case constants.KeyWARPConfig_Test:
+		m.UI().SetStatus(state.StatusInfo, "正在測試 WARP 接入點...", "發送 WireGuard 握手包", true)
+		return m, h.cmdBuilder.TestWARPEndpointCmd(m)
+
+	case constants.KeyWARPConfig_Optimize:
+		m.UI().SetStatus(state.StatusInfo, "正在優選 WARP 接入點...", "掃描候選 IP 與端口，約需 30 秒", true)
+		return m, h.cmdBuilder.OptimizeWARPEndpointCmd(m)
+
+	case constants.KeyWARPConfig_Auto:
+		cfg := m.Config().GetConfig()
+		if cfg != nil {
+			return m, h.cmdBuilder.SetWARPAutoOptimizeCmd(m, !cfg.Routing.WARP.AutoOptimize)
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+func (h *KeyHandler) submitCustomOutbound(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	if m.Routing().IsEditing() {
+		field := m.Routing().EditingField
+		m.Routing().StopEditing()
+
+		val := input
+		m.UI().ClearInput()
+
+		switch field {
+		case "add":
+			return m, h.cmdBuilder.AddCustomOutboundCmd(m, val)
+		case "delete":
+			return m, h.cmdBuilder.DeleteCustomOutboundCmd(m, val)
+		case "detour", "domains", "rule_sets":
+			return m, h.cmdBuilder.UpdateCustomOutboundCmd(m, field, val)
+		}
+		return m, nil
+	}
+
+	switch input {
+	case constants.KeyCustomOut_Add:
+		m.Routing().StartEditing("outbound", "add")
+		m.UI().SetStatus(state.StatusInfo, "請粘貼上游節點鏈接", "例如: ss://...#hk 或 vless://...?detour=hk#jp", true)
+		return m, nil
+	case constants.KeyCustomOut_Delete:
+		m.Routing().StartEditing("outbound", "delete")
+		m.UI().SetStatus(state.StatusInfo, "請輸入要刪除的出站標籤", "", true)
+		return m, nil
+	case constants.KeyCustomOut_Detour:
+		m.Routing().StartEditing("outbound", "detour")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 標籤 前置標籤", "例如: jp hk (僅輸入標籤則清除前置代理)", true)
+		return m, nil
+	case constants.KeyCustomOut_Domains:
+		m.Routing().StartEditing("outbound", "domains")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 標籤 域名列表", "例如: jp openai.com,chatgpt.com (僅輸入標籤則清空)", true)
+		return m, nil
+	case constants.KeyCustomOut_RuleSets:
+		m.Routing().StartEditing("outbound", "rule_sets")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 標籤 規則集列表", "例如: jp netflix,geoip-jp (僅輸入標籤則清空)", true)
+		return m, nil
+	}
+	return m, nil
+}
+
+func (h *KeyHandler) submitRoutingRules(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	if m.Routing().IsEditing() {
+		field := m.Routing().EditingField
+		m.Routing().StopEditing()
+
+		val := input
+		m.UI().ClearInput()
+
+		return m, h.cmdBuilder.UpdateRoutingRulesCmd(m, field, val)
+	}
+
+	switch input {
+	case constants.KeyRule_Add:
+		m.Routing().StartEditing("rules", "add")
+		m.UI().SetStatus(state.StatusInfo, "請輸入規則", "格式: 條件=值1,值2 [條件=值] => 出站 (可用 priority=N 指定優先級)", true)
+		return m, nil
+	case constants.KeyRule_Delete:
+		m.Routing().StartEditing("rules", "delete")
+		m.UI().SetStatus(state.StatusInfo, "請輸入要刪除的規則序號", "", true)
+		return m, nil
+	case constants.KeyRule_Priority:
+		m.Routing().StartEditing("rules", "priority")
+		m.UI().SetStatus(state.StatusInfo, "請輸入 序號 優先級", "例如: 2 50 (數值越小越先匹配)", true)
+		return m, nil
+	case constants.KeyRule_Toggle:
+		m.Routing().StartEditing("rules", "toggle")
+		m.UI().SetStatus(state.StatusInfo, "請輸入要啓用/禁用的規則序號", "", true)
+		return m, nil
+	case constants.KeyRule_Final:
+		m.Routing().StartEditing("rules", "final")
+		m.UI().SetStatus(state.StatusInfo, "請輸入默認出站標籤", "例如: warp-out (輸入 direct 恢復直連)", true)
+		return m, nil
 	}
 	return m, nil
 }
@@ -823,7 +1273,12 @@ func (h *KeyHandler) submitSocks5Outbound(m *state.Manager, input string) (*stat
 			return m, h.cmdBuilder.UpdateSocks5OutboundServerCmd(m, val)
 		case "auth":
 			return m, h.cmdBuilder.UpdateSocks5OutboundAuthCmd(m, val)
+		case "domains":
+			if val != "" {
+				return m, h.cmdBuilder.AddRoutingDomainCmd(m, "socks5", val)
+			}
 		}
+		m.UI().SetStatus(state.StatusInfo, "輸入已取消", "", false)
 		return m, nil
 	}
 
@@ -839,7 +1294,11 @@ func (h *KeyHandler) submitSocks5Outbound(m *state.Manager, input string) (*stat
 		m.UI().SetStatus(state.StatusInfo, "請輸入認證信息", "格式 User:Pass", true)
 		return m, nil
 	case constants.KeySocks5Out_Global:
-		return m, h.cmdBuilder.SetSocks5GlobalCmd(m, true)
+		return m, h.cmdBuilder.ToggleSocks5GlobalCmd(m)
+	case constants.KeySocks5Out_Rule:
+		m.Routing().StartEditing("socks5_outbound", "domains")
+		m.UI().SetStatus(state.StatusInfo, "請輸入分流域名", "例如: netflix.com (按 Enter 確認)", true)
+		return m, nil
 	}
 	return m, nil
 }
@@ -1076,6 +1535,57 @@ func (h *KeyHandler) submitCoreSourceSelect(m *state.Manager, input string) (*st
 
 // --- 服務管理 ---
 
+// submitServiceLog 處理實時日誌查看器的過濾指令
+func (h *KeyHandler) submitServiceLog(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	logState := m.Service().Log
+
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch {
+	case strings.EqualFold(cmd, constants.KeyServiceLog_Pause):
+		logState.TogglePause()
+		return m, nil
+
+	case strings.EqualFold(cmd, constants.KeyServiceLog_Clear):
+		logState.Filter = logs.Filter{}
+		m.UI().SetStatus(state.StatusInfo, "已清除過濾條件", "", false)
+		return m, nil
+
+	case strings.HasPrefix(input, constants.KeyServiceLog_Search):
+		logState.Filter.Search = strings.TrimSpace(strings.TrimPrefix(input, constants.KeyServiceLog_Search))
+		return m, nil
+
+	case strings.EqualFold(cmd, constants.KeyServiceLog_Level):
+		if arg == "" {
+			logState.Filter.MinLevel = logs.LevelTrace
+			return m, nil
+		}
+		level, ok := logs.ParseLevel(arg)
+		if !ok {
+			m.UI().SetStatus(state.StatusError, "未知的日誌級別", "可選: debug / info / warn / error", false)
+			return m, nil
+		}
+		logState.Filter.MinLevel = level
+		return m, nil
+
+	case strings.EqualFold(cmd, constants.KeyServiceLog_Inbound):
+		// 支持直接輸入協議編號
+		if n, err := strconv.Atoi(arg); err == nil && protocol.ID(n).IsValid() {
+			arg = protocol.ID(n).Tag()
+		}
+		logState.Filter.Inbound = arg
+		return m, nil
+
+	case strings.EqualFold(cmd, constants.KeyServiceLog_Reconnect):
+		m.UI().SetStatus(state.StatusInfo, "正在重新連接日誌...", "", false)
+		return m, h.cmdBuilder.FollowServiceLogCmd(m)
+	}
+
+	m.UI().SetStatus(state.StatusError, "未知指令", "p 暫停 | /關鍵字 | l 級別 | i 入站 | c 清除 | r 重連", false)
+	return m, nil
+}
+
 func (h *KeyHandler) submitServiceMenu(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
 	switch input {
 	case constants.KeyService_Restart:
@@ -1094,10 +1604,52 @@ func (h *KeyHandler) submitServiceMenu(m *state.Manager, input string) (*state.M
 		cmd1 := m.UI().SwitchView(state.ServiceHealthView)
 		cmd2 := h.cmdBuilder.ServiceHealthCheckCmd(m)
 		return m, tea.Batch(cmd1, cmd2)
+	case constants.KeyService_Traffic:
+		traffic := m.Service().Traffic
+		traffic.Reset()
+		traffic.Fetching = true
+		h.cmdBuilder.ResetTrafficSampling()
+		cmd1 := m.UI().SwitchView(state.TrafficDashboardView)
+		cmd2 := h.cmdBuilder.TrafficDashboardCmd(m)
+		return m, tea.Batch(cmd1, cmd2)
 	}
 	return m, nil
 }
 
+// submitTrafficDashboard 處理流量面板指令
+func (h *KeyHandler) submitTrafficDashboard(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	traffic := m.Service().Traffic
+
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch {
+	case strings.EqualFold(cmd, constants.KeyTraffic_Pause):
+		traffic.Paused = !traffic.Paused
+		return m, nil
+
+	case strings.EqualFold(cmd, constants.KeyTraffic_Expand):
+		traffic.ShowAll = !traffic.ShowAll
+		return m, nil
+
+	case strings.EqualFold(cmd, constants.KeyTraffic_Close):
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			m.UI().SetStatus(state.StatusError, "請輸入連接序號", "例如: x 3", false)
+			return m, nil
+		}
+		conn, ok := traffic.ConnectionAt(n)
+		if !ok {
+			m.UI().SetStatus(state.StatusError, fmt.Sprintf("連接序號 %d 不存在", n), "", false)
+			return m, nil
+		}
+		return m, h.cmdBuilder.CloseConnectionCmd(m, conn.ID)
+	}
+
+	m.UI().SetStatus(state.StatusError, "未知指令", "x 序號 關閉連接 | a 顯示全部 | p 暫停", false)
+	return m, nil
+}
+
 // --- 工具箱 ---
 
 func (h *KeyHandler) submitToolsMenu(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
@@ -1498,6 +2050,7 @@ func (h *KeyHandler) submitLogMenu(m *state.Manager, input string) (*state.Manag
 		// 清空內容並重置狀態
 		m.Log().UpdateContent("")
 		m.Log().IsFollowing = false
+		m.Log().IsQueryResult = false
 		return m.UI().SwitchView(state.LogViewerView)
 	}
 
@@ -1520,12 +2073,15 @@ func (h *KeyHandler) submitLogMenu(m *state.Manager, input string) (*state.Manag
 		m.UI().SetStatus(state.StatusWarn, "正在搜索錯誤日誌...", "", true)
 		return m, tea.Batch(cmd1, cmd2)
 
+	case constants.KeyLog_Query:
+		return m, m.UI().SwitchView(state.LogQueryView)
+
 	case constants.KeyLog_Level:
 		return m, m.UI().SwitchView(state.LogLevelEditView)
 
 	case constants.KeyLog_Export:
-		m.UI().SetStatus(state.StatusInfo, "正在導出日誌...", "", true)
-		return m, h.cmdBuilder.ExportLogCmd(m)
+		m.UI().SetStatus(state.StatusInfo, "正在生成診斷包...", "敏感信息將自動脫敏", true)
+		return m, h.cmdBuilder.ExportDiagnosticsCmd(m)
 
 	case constants.KeyLog_Clear:
 		m.UI().SetStatus(state.StatusWarn, "⚠️  正在清空日誌文件...", "", true)
@@ -1556,6 +2112,18 @@ func (h *KeyHandler) submitLogLevelEdit(m *state.Manager, input string) (*state.
 	return m, nil
 }
 
+func (h *KeyHandler) submitLogQuery(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
+	// 先在本地校驗語法，避免無效查詢進入結果頁
+	if _, err := logs.ParseQuery(input, time.Now()); err != nil {
+		m.UI().SetStatus(state.StatusError, "查詢語法錯誤", err.Error(), false)
+		return m, nil
+	}
+
+	m.Log().LastQuery = input
+	m.UI().SetStatus(state.StatusInfo, "正在查詢日誌...", "包含輪轉與壓縮的歷史日誌", true)
+	return m, h.cmdBuilder.QueryLogsCmd(m, input)
+}
+
 // --- 節點信息 ---
 
 func (h *KeyHandler) submitNodeInfo(m *state.Manager, input string) (*state.Manager, tea.Cmd) {
@@ -1853,7 +2421,10 @@ func (h *KeyHandler) handleInputEscape(m *state.Manager, view state.View) (*stat
 		state.SNIEditView,
 		state.UUIDEditView,
 		state.PortEditView,
-		state.AnyTLSPaddingView:
+		state.AnyTLSPaddingView,
+		state.InboundRoutingView,
+		state.ProtocolOptionsView,
+		state.BrutalView:
 		return m, m.UI().SwitchView(state.ConfigMenuView)
 
 	case state.Hy2PortModeView:
@@ -1880,13 +2451,18 @@ func (h *KeyHandler) handleInputEscape(m *state.Manager, view state.View) (*stat
 		state.Socks5RoutingView,
 		state.IPv6RoutingView,
 		state.DNSRoutingView,
-		state.SNIProxyRoutingView:
+		state.SNIProxyRoutingView,
+		state.CustomOutboundView,
+		state.RoutingRulesView:
 		return m, m.UI().SwitchView(state.RouteMenuView)
 
 	case state.Socks5InboundView,
 		state.Socks5OutboundView:
 		return m, m.UI().SwitchView(state.Socks5RoutingView)
 
+	case state.WARPConfigView:
+		return m, m.UI().SwitchView(state.WARPRoutingView)
+
 	case state.ScriptUpdateView:
 		m.Core().IsCheckingScript = false
 		return m, m.UI().SwitchView(state.CoreMenuView)
@@ -1895,8 +2471,12 @@ func (h *KeyHandler) handleInputEscape(m *state.Manager, view state.View) (*stat
 		state.CoreSourceSelectView:
 		return m, m.UI().SwitchView(state.CoreMenuView)
 
-	case state.ServiceLogView,
-		state.ServiceHealthView:
+	case state.ServiceLogView:
+		m.Service().Log.Stop()
+		return m, m.UI().SwitchView(state.ServiceMenuView)
+
+	case state.ServiceHealthView,
+		state.TrafficDashboardView:
 		return m, m.UI().SwitchView(state.ServiceMenuView)
 
 	case state.SwapMenuView,
@@ -1913,9 +2493,12 @@ func (h *KeyHandler) handleInputEscape(m *state.Manager, view state.View) (*stat
 			m.Tools().IsViewingFail2BanLogs = false
 			return m, m.UI().SwitchView(state.Fail2BanMenuView)
 		}
+		if m.Log().IsQueryResult {
+			return m, m.UI().SwitchView(state.LogQueryView)
+		}
 		return m, m.UI().SwitchView(state.LogMenuView)
 
-	case state.LogLevelEditView:
+	case state.LogLevelEditView, state.LogQueryView:
 		return m, m.UI().SwitchView(state.LogMenuView)
 
 	case state.ProtocolLinksView,
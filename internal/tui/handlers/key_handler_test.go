@@ -87,8 +87,11 @@ func TestNavigation_Escape(t *testing.T) {
 		{state.ConfigMenuView, state.MainMenuView},
 		{state.ProtocolMenuView, state.ConfigMenuView},
 		{state.PortEditView, state.ConfigMenuView},
+		{state.InboundRoutingView, state.ConfigMenuView},
 		{state.Hy2PortModeView, state.PortEditView},
+		{state.BrutalView, state.ConfigMenuView},
 		{state.ServiceLogView, state.ServiceMenuView},
+		{state.LogQueryView, state.LogMenuView},
 		{unknownView, state.MainMenuView}, // 默認兜底
 	}
 
@@ -136,7 +139,7 @@ func TestConfig_ProtocolToggle(t *testing.T) {
 	}
 
 	// 驗證 Config 結構體同步
-	if !m.Config().Config.Protocols.Hysteria2.Enabled {
+	if !m.Config().Config.Protocols.Hysteria2().Enabled {
 		t.Error("Hysteria2 在 Config 結構體中的狀態未同步開啟")
 	}
 
@@ -255,3 +258,49 @@ func TestEsc_ComplexStates(t *testing.T) {
 		t.Error("離開未保存的配置菜單時應進入 ExitConfirmMode")
 	}
 }
+
+// TestAnyTLSPadding_Editor 編輯草稿、語法錯誤提示、應用與命名方案
+func TestAnyTLSPadding_Editor(t *testing.T) {
+	m, h := setupTestEnv()
+	m.UI().SwitchView(state.AnyTLSPaddingView)
+	p := &m.Config().GetConfig().Protocols
+
+	// 從當前方案開始編輯，修改 stop 並新增一行
+	sendKey(h, m, constants.KeyPadding_Edit)
+	sendKey(h, m, "stop=9;8=100-200")
+	draft := m.Config().PaddingDraft
+	if len(draft) == 0 || draft[0] != "stop=9" || draft[len(draft)-1] != "8=100-200" {
+		t.Fatalf("草稿錯誤: %v", draft)
+	}
+
+	// 語法錯誤保留在草稿中且不能應用
+	sendKey(h, m, constants.KeyPadding_Edit)
+	sendKey(h, m, "8=200-100")
+	if m.UI().Status.Type != state.StatusError {
+		t.Error("語法錯誤應提示")
+	}
+	sendKey(h, m, constants.KeyPadding_Apply)
+	if p.AnyTLS().PaddingMode == domainConfig.PaddingModeCustom {
+		t.Error("存在語法錯誤時不應應用")
+	}
+
+	sendKey(h, m, constants.KeyPadding_Edit)
+	sendKey(h, m, "8=100-200")
+	sendKey(h, m, constants.KeyPadding_Save)
+	sendKey(h, m, "mobile")
+	sendKey(h, m, constants.KeyPadding_Apply)
+	if c := p.AnyTLS(); c.PaddingMode != domainConfig.PaddingModeCustom || c.PaddingScheme[0] != "stop=9" {
+		t.Errorf("應用草稿失敗: %+v", c)
+	}
+
+	// 切回內置方案後再應用命名方案
+	sendKey(h, m, constants.KeyPadding_Minimal)
+	if c := p.AnyTLS(); c.PaddingMode != domainConfig.PaddingModeMinimal || c.PaddingScheme != nil {
+		t.Errorf("切換內置方案失敗: %+v", c)
+	}
+	sendKey(h, m, constants.KeyPadding_Load)
+	sendKey(h, m, "mobile")
+	if c := p.AnyTLSReality(); c.PaddingMode != domainConfig.PaddingModeCustom || c.PaddingScheme[0] != "stop=9" {
+		t.Errorf("應用命名方案失敗: %+v", c)
+	}
+}
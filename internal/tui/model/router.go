@@ -51,6 +51,8 @@ func NewRouter(cfg *handlers.Config) *Router {
 		cfg.Executor,
 		cfg.FirewallMgr,
 		cfg.ProtoFactory,
+		cfg.WARPService,
+		cfg.TrafficService,
 	)
 
 	// 2. 初始化 CertHandler
@@ -133,10 +135,18 @@ func (r *Router) routeMessage(message tea.Msg) tea.Cmd {
 		return cmd
 
 	case TickMsg:
-		return tea.Batch(
+		cmds := []tea.Cmd{
 			r.cmdBuilder.UpdateDataCmd(m),
 			TickCmd(),
-		)
+		}
+		// 流量面板隨定時器每秒採樣，輸入指令 (如 x 3) 期間暫停刷新
+		traffic := m.Service().Traffic
+		traffic.Frozen = m.UI().GetInputBuffer() != ""
+		if m.UI().CurrentView == state.TrafficDashboardView && traffic.ShouldFetch() {
+			traffic.Fetching = true
+			cmds = append(cmds, r.cmdBuilder.TrafficDashboardCmd(m))
+		}
+		return tea.Batch(cmds...)
 
 	// 接收到退出消息，執行標準退出流程 (框架會自動恢復終端)
 	case UninstallExitMsg:
@@ -469,7 +479,7 @@ func (r *Router) routeMessage(message tea.Msg) tea.Cmd {
 				routing.LoadWARPConfig(&cfg)
 			}
 		case "socks5":
-			if cfg, ok := msgType.Config.(domainConfig.Socks5Config); ok {
+			if cfg, ok := msgType.Config.(domainConfig.RoutingConfig); ok {
 				routing.LoadSocks5Config(&cfg)
 			}
 		case "ipv6":
@@ -563,6 +573,17 @@ func (r *Router) routeMessage(message tea.Msg) tea.Cmd {
 		}
 		return nil
 
+	case msg.Hy2BandwidthMsg:
+		ui := m.UI()
+		m.Config().IsMeasuringBandwidth = false
+		if msgType.Err != nil {
+			ui.SetStatus(state.StatusError, "帶寬檢測失敗", msgType.Err.Error(), false)
+		} else {
+			m.Config().Bandwidth = msgType.Info
+			ui.SetStatus(state.StatusSuccess, "檢測完成", fmt.Sprintf("推薦 %d Mbps，按 2 應用", msgType.Info.RecommendedMbps), false)
+		}
+		return nil
+
 	// 處理 BBR 信息更新
 	case msg.BBRInfoMsg:
 		ui := m.UI()
@@ -702,9 +723,30 @@ func (r *Router) routeMessage(message tea.Msg) tea.Cmd {
 
 		// 3. 設置是否為跟蹤模式 (決定狀態欄顯示內容)
 		m.Log().IsFollowing = (msgType.Mode == "realtime")
+		m.Log().IsQueryResult = (msgType.Mode == "query")
 
 		return ui.SwitchView(state.LogViewerView)
 
+	case msg.ServiceLogMsg:
+		logState := m.Service().Log
+		// 已停止或被替換的舊流
+		if msgType.Stream != logState.Stream {
+			return nil
+		}
+
+		logState.Append(msgType.Entries)
+
+		if msgType.Closed {
+			logState.Stop()
+			if msgType.Err != nil {
+				m.UI().SetStatus(state.StatusError, fmt.Sprintf("日誌讀取失敗: %v", msgType.Err), "", false)
+			} else {
+				m.UI().SetStatus(state.StatusWarn, "日誌流已結束", "", false)
+			}
+			return nil
+		}
+		return r.cmdBuilder.NextServiceLogCmd(logState.Stream, logState.Errors)
+
 	case msg.CoreVersionsMsg:
 		if msgType.Err != nil {
 			m.UI().SetStatus(state.StatusError, fmt.Sprintf("加載版本列表失敗: %v", msgType.Err), "", false)
@@ -737,6 +779,25 @@ func (r *Router) routeMessage(message tea.Msg) tea.Cmd {
 		}
 		return nil
 
+	case msg.TrafficDashboardMsg:
+		traffic := m.Service().Traffic
+		if msgType.Err != nil {
+			traffic.Fetching = false
+			traffic.Err = msgType.Err.Error()
+			return nil
+		}
+		traffic.Frozen = m.UI().GetInputBuffer() != ""
+		traffic.Apply(msgType.Dashboard)
+		return nil
+
+	case msg.ConnectionClosedMsg:
+		if msgType.Err != nil {
+			m.UI().SetStatus(state.StatusError, fmt.Sprintf("關閉連接失敗: %v", msgType.Err), "", false)
+		} else {
+			m.UI().SetStatus(state.StatusSuccess, "連接已關閉", "", false)
+		}
+		return nil
+
 	case msg.ServiceHealthMsg:
 		if msgType.Err != nil {
 			m.UI().SetStatus(state.StatusError, fmt.Sprintf("健康檢查失敗: %v", msgType.Err), "", false)
@@ -2,6 +2,7 @@ package msg
 
 import (
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
 	"github.com/Yat-Muk/prism-v2/internal/tui/types"
 )
 
@@ -117,7 +118,7 @@ type LogInfoMsg struct {
 	LogPath    string
 	LogSize    string
 	TodayLines int
-	ErrorCount int
+	ErrorCount int // 今日錯誤級別日誌數 (含當日輪轉文件)
 	RecentLogs []string
 	Err        error
 }
@@ -130,6 +131,26 @@ type LogViewMsg struct {
 	Following bool
 }
 
+// ServiceLogMsg 服務實時日誌增量消息
+type ServiceLogMsg struct {
+	Stream  <-chan logs.Entry // 產生該批日誌的流，用於忽略已停止的舊流
+	Entries []logs.Entry
+	Closed  bool
+	Err     error
+}
+
+// TrafficDashboardMsg 流量面板採樣結果
+type TrafficDashboardMsg struct {
+	Dashboard *types.TrafficDashboard
+	Err       error
+}
+
+// ConnectionClosedMsg 關閉連接結果
+type ConnectionClosedMsg struct {
+	ID  string
+	Err error
+}
+
 // UUIDGeneratedMsg UUID 生成消息
 type UUIDGeneratedMsg struct {
 	UUID string
@@ -159,6 +180,12 @@ type BBRInfoMsg struct {
 	Err  error
 }
 
+// Hy2BandwidthMsg Hysteria2 帶寬檢測結果
+type Hy2BandwidthMsg struct {
+	Info *types.Hy2BandwidthInfo
+	Err  error
+}
+
 // CoreCheckMsg 核心更新檢查結果
 type CoreCheckMsg struct {
 	HasUpdate     bool
@@ -2,6 +2,8 @@ package state
 
 import (
 	domainConfig "github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	"github.com/Yat-Muk/prism-v2/internal/tui/types"
 )
 
 type ConfigState struct {
@@ -11,6 +13,14 @@ type ConfigState struct {
 	HasUnsavedChanges bool  // [新增] 標記是否有未保存的修改 (內存緩存)
 	EnabledProtocols  []int // UI 狀態緩存
 	Dirty             bool
+
+	// Hysteria2 帶寬檢測
+	Bandwidth            *types.Hy2BandwidthInfo
+	IsMeasuringBandwidth bool
+
+	// AnyTLS 填充方案編輯草稿與預覽包數 (0 表示按 stop)
+	PaddingDraft   []string
+	PaddingPreview int
 }
 
 // NewConfigState 構造函數
@@ -59,28 +69,10 @@ func (s *ConfigState) SyncProtocolsFromConfig() {
 	}
 
 	var list []int
-	p := s.Config.Protocols
-
-	if p.RealityVision.Enabled {
-		list = append(list, 1)
-	}
-	if p.RealityGRPC.Enabled {
-		list = append(list, 2)
-	}
-	if p.Hysteria2.Enabled {
-		list = append(list, 3)
-	}
-	if p.TUIC.Enabled {
-		list = append(list, 4)
-	}
-	if p.AnyTLS.Enabled {
-		list = append(list, 5)
-	}
-	if p.AnyTLSReality.Enabled {
-		list = append(list, 6)
-	}
-	if p.ShadowTLS.Enabled {
-		list = append(list, 7)
+	for _, id := range protocol.AllIDs() {
+		if protocol.IsEnabled(s.Config, id) {
+			list = append(list, int(id))
+		}
 	}
 
 	s.EnabledProtocols = list
@@ -96,39 +88,13 @@ func (c *ConfigState) SyncPortsToMap(portState *PortState) {
 	// 初始化 map，确保是 int 类型
 	portState.CurrentPorts = make(map[int]int)
 
-	// 协议 ID 定义：
-	// 1=Reality Vision, 2=Reality gRPC, 3=Hysteria2, 4=TUIC,
-	// 5=AnyTLS, 6=AnyTLS Reality, 7=ShadowTLS
-
-	// 直接赋值 int，不再使用 fmt.Sprintf
-	if c.Config.Protocols.RealityVision.Port > 0 {
-		portState.CurrentPorts[1] = c.Config.Protocols.RealityVision.Port
-	}
-
-	if c.Config.Protocols.RealityGRPC.Port > 0 {
-		portState.CurrentPorts[2] = c.Config.Protocols.RealityGRPC.Port
-	}
-
-	if c.Config.Protocols.Hysteria2.Port > 0 {
-		portState.CurrentPorts[3] = c.Config.Protocols.Hysteria2.Port
-	}
-
-	if c.Config.Protocols.TUIC.Port > 0 {
-		portState.CurrentPorts[4] = c.Config.Protocols.TUIC.Port
-	}
-
-	if c.Config.Protocols.AnyTLS.Port > 0 {
-		portState.CurrentPorts[5] = c.Config.Protocols.AnyTLS.Port
-	}
-
-	if c.Config.Protocols.AnyTLSReality.Port > 0 {
-		portState.CurrentPorts[6] = c.Config.Protocols.AnyTLSReality.Port
-	}
-
-	if c.Config.Protocols.ShadowTLS.Port > 0 {
-		portState.CurrentPorts[7] = c.Config.Protocols.ShadowTLS.Port
+	for _, id := range protocol.AllIDs() {
+		if port := protocol.PortOf(c.Config, id); port > 0 {
+			portState.CurrentPorts[int(id)] = port
+		}
 	}
 
 	// 如果 PortState 有 Hy2HoppingRange 字段，也可以在这里同步
-	portState.Hy2HoppingRange = c.Config.Protocols.Hysteria2.PortHopping
+	portState.Hy2HoppingRange = c.Config.Protocols.Hysteria2().PortHopping
+	portState.Hy2HopInterval = c.Config.Protocols.Hysteria2().HopInterval
 }
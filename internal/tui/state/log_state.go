@@ -17,6 +17,10 @@ type LogState struct {
 
 	// 當前顯示的日誌內容 (緩存)
 	Content string
+
+	// 最近一次查詢表達式，以及當前內容是否為查詢結果 (返回時回到查詢頁)
+	LastQuery     string
+	IsQueryResult bool
 }
 
 func NewLogState() *LogState {
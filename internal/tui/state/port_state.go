@@ -6,13 +6,15 @@ type PortState struct {
 	PortEditingProtocol int  // 當前正在編輯端口的協議 ID
 	PortEditingMode     bool // 是否處於端口編輯模式
 	Hy2EditingHopping   bool // 是否正在編輯 Hysteria 2 跳躍端口
+	Hy2EditingInterval  bool // 是否正在編輯 Hysteria 2 跳躍間隔
 
 	// 當前端口數據 (Data)
 	CurrentPorts map[int]int
 
-	// 專門存儲 Hysteria 2 的跳躍端口範圍字符串 (如 "20000-30000")
+	// 專門存儲 Hysteria 2 的跳躍端口範圍字符串 (如 "20000-30000,40000-45000")
 	// 因為這不是標準端口，需要單獨字段
 	Hy2HoppingRange string
+	Hy2HopInterval  string
 }
 
 // NewPortState 創建端口狀態管理器
@@ -28,17 +30,27 @@ func (s *PortState) StartPortEdit(protoID int) {
 	s.PortEditingProtocol = protoID
 	s.PortEditingMode = true
 	s.Hy2EditingHopping = false
+	s.Hy2EditingInterval = false
 }
 
 func (s *PortState) StartHy2HoppingEdit() {
 	s.PortEditingProtocol = 3 // Hysteria 2 的 ID
 	s.PortEditingMode = true
 	s.Hy2EditingHopping = true
+	s.Hy2EditingInterval = false
+}
+
+func (s *PortState) StartHy2IntervalEdit() {
+	s.PortEditingProtocol = 3
+	s.PortEditingMode = true
+	s.Hy2EditingHopping = false
+	s.Hy2EditingInterval = true
 }
 
 func (s *PortState) CancelPortEdit() {
 	s.PortEditingMode = false
 	s.Hy2EditingHopping = false
+	s.Hy2EditingInterval = false
 	s.PortEditingProtocol = 0
 }
 
@@ -49,4 +61,5 @@ func (s *PortState) IsEditingProtocol(protoID int) bool {
 func (s *PortState) ClearPorts() {
 	s.CurrentPorts = make(map[int]int)
 	s.Hy2HoppingRange = ""
+	s.Hy2HopInterval = ""
 }
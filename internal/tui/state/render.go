@@ -70,6 +70,7 @@ func (m *Manager) Render() string {
 	case ProtocolMenuView:
 		return view.RenderProtocolSwitches(
 			m.config.EnabledProtocols,
+			m.config.GetConfig(),
 			ti,
 			statusMsg,
 		)
@@ -92,6 +93,7 @@ func (m *Manager) Render() string {
 		return view.RenderHy2PortMode(
 			hy2Port,
 			m.port.Hy2HoppingRange,
+			m.port.Hy2HopInterval,
 			ti,
 			statusMsg,
 			m.port.PortEditingMode,
@@ -100,7 +102,7 @@ func (m *Manager) Render() string {
 	case SNIEditView:
 		current := ""
 		if cfg := m.config.GetConfig(); cfg != nil {
-			current = cfg.Protocols.RealityVision.SNI
+			current = cfg.Protocols.RealityVision().SNI
 		}
 		return view.RenderSNIEditView(current, ti, statusMsg)
 
@@ -112,11 +114,16 @@ func (m *Manager) Render() string {
 		return view.RenderUUIDEditView(current, ti, statusMsg)
 
 	case AnyTLSPaddingView:
-		current := ""
-		if cfg := m.config.GetConfig(); cfg != nil {
-			current = cfg.Protocols.AnyTLS.PaddingMode
-		}
-		return view.RenderAnyTLSPaddingMenu(ti, current, statusMsg)
+		return view.RenderAnyTLSPaddingMenu(m.config.GetConfig(), m.config.PaddingDraft, m.config.PaddingPreview, ti, statusMsg)
+
+	case InboundRoutingView:
+		return view.RenderInboundRouting(m.config.GetConfig(), ti, statusMsg)
+
+	case ProtocolOptionsView:
+		return view.RenderProtocolOptions(m.config.GetConfig(), ti, statusMsg)
+
+	case BrutalView:
+		return view.RenderHy2Bandwidth(m.config.GetConfig(), m.config.Bandwidth, m.config.IsMeasuringBandwidth, ti, statusMsg)
 
 	case OutboundMenuView:
 		v4, v6 := false, false
@@ -255,17 +262,42 @@ func (m *Manager) Render() string {
 		cfg := m.config.GetConfig()
 		var warpCfg *domainConfig.WARPConfig
 		if cfg != nil {
-			warpCfg = &cfg.Routing.WARP
+			// 分流域名已遷移至路由規則，合併顯示
+			w := cfg.Routing.WARP
+			w.Domains = append(cfg.Routing.DomainsFor(domainConfig.OutboundWARP), w.Domains...)
+			warpCfg = &w
 		}
 		return view.RenderWARPRouting(warpCfg, ti, statusMsg)
 
+	case RoutingRulesView:
+		var routing *domainConfig.RoutingConfig
+		if cfg := m.config.GetConfig(); cfg != nil {
+			routing = &cfg.Routing
+		}
+		return view.RenderRoutingRules(routing, ti, statusMsg)
+
+	case CustomOutboundView:
+		var outbounds []domainConfig.CustomOutbound
+		if cfg := m.config.GetConfig(); cfg != nil {
+			outbounds = cfg.Routing.Outbounds
+		}
+		return view.RenderCustomOutbounds(outbounds, ti, statusMsg)
+
+	case WARPConfigView:
+		cfg := m.config.GetConfig()
+		var warpCfg *domainConfig.WARPConfig
+		if cfg != nil {
+			warpCfg = &cfg.Routing.WARP
+		}
+		return view.RenderWARPConfig(warpCfg, ti, statusMsg)
+
 	case Socks5RoutingView:
 		cfg := m.config.GetConfig()
-		var socks5Cfg *domainConfig.Socks5Config
+		var routingCfg *domainConfig.RoutingConfig
 		if cfg != nil {
-			socks5Cfg = &cfg.Routing.Socks5
+			routingCfg = &cfg.Routing
 		}
-		return view.RenderSocks5Routing(socks5Cfg, ti, statusMsg)
+		return view.RenderSocks5Routing(routingCfg, ti, statusMsg)
 
 	case Socks5InboundView:
 		cfg := m.config.GetConfig()
@@ -277,17 +309,19 @@ func (m *Manager) Render() string {
 
 	case Socks5OutboundView:
 		cfg := m.config.GetConfig()
-		var outCfg *domainConfig.Socks5OutboundConfig
+		var routingCfg *domainConfig.RoutingConfig
 		if cfg != nil {
-			outCfg = &cfg.Routing.Socks5.Outbound
+			routingCfg = &cfg.Routing
 		}
-		return view.RenderSocks5Outbound(outCfg, ti, statusMsg)
+		return view.RenderSocks5Outbound(routingCfg, ti, statusMsg)
 
 	case IPv6RoutingView:
 		cfg := m.config.GetConfig()
 		var ipv6Cfg *domainConfig.IPv6SplitConfig
 		if cfg != nil {
-			ipv6Cfg = &cfg.Routing.IPv6Split
+			split := cfg.Routing.IPv6Split
+			split.Domains = append(cfg.Routing.DomainsFor(domainConfig.OutboundIPv6), split.Domains...)
+			ipv6Cfg = &split
 		}
 		return view.RenderIPv6Routing(ipv6Cfg, ti, statusMsg)
 
@@ -364,8 +398,32 @@ func (m *Manager) Render() string {
 		return view.RenderServiceMenu(svcStats, m.service.AutoStart, ti, statusMsg)
 
 	case ServiceLogView:
-		logs := []string{"等待日誌..."}
-		return view.RenderServiceLogViewer(logs, true, ti)
+		logState := m.service.Log
+		// 預留頁眉、狀態欄與輸入框的高度
+		limit := m.ui.Height - 14
+		if limit < 10 {
+			limit = 10
+		}
+		return view.RenderServiceLogViewer(view.ServiceLogViewData{
+			Entries:      logState.Visible(limit),
+			Total:        len(logState.Entries),
+			Source:       logState.SourceName,
+			Filter:       logState.Filter,
+			Following:    logState.IsActive(),
+			Paused:       logState.Paused,
+			NewSinceHold: logState.NewSinceHold,
+		}, ti, statusMsg)
+
+	case TrafficDashboardView:
+		traffic := m.service.Traffic
+		return view.RenderTrafficDashboard(view.TrafficDashboardViewData{
+			Dashboard:   traffic.Dashboard,
+			Connections: traffic.VisibleConnections(),
+			Err:         traffic.Err,
+			Paused:      traffic.Paused,
+			Frozen:      traffic.Frozen,
+			ShowAll:     traffic.ShowAll,
+		}, ti, statusMsg)
 
 	case ServiceHealthView:
 		// 假設 service.HealthCheck 已經適配為 types.HealthCheckResult，如果還未適配，這裡傳 nil 防止崩潰
@@ -398,6 +456,9 @@ func (m *Manager) Render() string {
 		}
 		return view.RenderLogLevelEdit(currentLevel, ti, statusMsg)
 
+	case LogQueryView:
+		return view.RenderLogQuery(m.logState.LastQuery, ti, statusMsg)
+
 	case StreamingCheckView:
 		return view.RenderStreamingCheck(
 			m.tools.StreamingResult,
@@ -63,12 +63,13 @@ func (s *RoutingState) LoadWARPConfig(cfg *domainConfig.WARPConfig) {
 	s.WarpDomains = append([]string(nil), cfg.Domains...)
 }
 
-func (s *RoutingState) LoadSocks5Config(cfg *domainConfig.Socks5Config) {
+// LoadSocks5Config 出站狀態取自自定義出站 socks5-out 及其分流規則
+func (s *RoutingState) LoadSocks5Config(cfg *domainConfig.RoutingConfig) {
 	if cfg == nil {
 		return
 	}
-	s.Socks5InboundEnabled = cfg.Inbound.Enabled
-	s.Socks5OutboundEnabled = cfg.Outbound.Enabled
+	s.Socks5InboundEnabled = cfg.Socks5.Inbound.Enabled
+	s.Socks5OutboundEnabled = cfg.Socks5Enabled()
 }
 
 func (s *RoutingState) LoadIPv6Config(cfg *domainConfig.IPv6SplitConfig) {
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"context"
+
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
+)
+
+// 查看器最多保留的日誌條數
+const maxServiceLogEntries = 2000
+
+// ServiceLogState 服務實時日誌狀態
+type ServiceLogState struct {
+	Entries    []logs.Entry
+	SourceName string
+	Filter     logs.Filter
+
+	// 暫停時凍結顯示內容，後臺繼續接收
+	Paused       bool
+	frozen       []logs.Entry
+	NewSinceHold int
+
+	Stream <-chan logs.Entry
+	Errors <-chan error
+	cancel context.CancelFunc
+}
+
+// NewServiceLogState 創建服務日誌狀態
+func NewServiceLogState() *ServiceLogState {
+	return &ServiceLogState{}
+}
+
+// Start 記錄新的日誌流，並停止之前的流
+func (s *ServiceLogState) Start(name string, stream <-chan logs.Entry, errs <-chan error, cancel context.CancelFunc) {
+	s.Stop()
+	s.Entries = nil
+	s.SourceName = name
+	s.Paused = false
+	s.frozen = nil
+	s.NewSinceHold = 0
+	s.Stream = stream
+	s.Errors = errs
+	s.cancel = cancel
+}
+
+// Stop 停止跟蹤
+func (s *ServiceLogState) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.Stream = nil
+	s.Errors = nil
+}
+
+// IsActive 是否正在跟蹤
+func (s *ServiceLogState) IsActive() bool {
+	return s.Stream != nil
+}
+
+// Append 追加日誌，超出上限時丟棄最舊的記錄
+func (s *ServiceLogState) Append(entries []logs.Entry) {
+	s.Entries = append(s.Entries, entries...)
+	if over := len(s.Entries) - maxServiceLogEntries; over > 0 {
+		s.Entries = append([]logs.Entry(nil), s.Entries[over:]...)
+	}
+	if s.Paused {
+		s.NewSinceHold += len(entries)
+	}
+}
+
+// TogglePause 切換暫停狀態
+func (s *ServiceLogState) TogglePause() {
+	s.Paused = !s.Paused
+	s.NewSinceHold = 0
+	if s.Paused {
+		s.frozen = append([]logs.Entry(nil), s.Entries...)
+	} else {
+		s.frozen = nil
+	}
+}
+
+// Visible 返回經過濾後需要顯示的最近 limit 條日誌
+func (s *ServiceLogState) Visible(limit int) []logs.Entry {
+	src := s.Entries
+	if s.Paused {
+		src = s.frozen
+	}
+
+	var out []logs.Entry
+	for i := len(src) - 1; i >= 0 && len(out) < limit; i-- {
+		if s.Filter.Match(src[i]) {
+			out = append(out, src[i])
+		}
+	}
+	// 恢復時間順序
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceLogState_PauseAndFilter(t *testing.T) {
+	s := NewServiceLogState()
+	_, cancel := context.WithCancel(context.Background())
+	s.Start("test", make(chan logs.Entry), nil, cancel)
+	assert.True(t, s.IsActive())
+
+	s.Append([]logs.Entry{
+		{Level: logs.LevelInfo, Inbound: "tuic-in", Message: "a"},
+		{Level: logs.LevelError, Inbound: "hysteria2-in", Message: "b"},
+	})
+
+	s.TogglePause()
+	s.Append([]logs.Entry{{Level: logs.LevelError, Message: "c"}})
+	assert.Len(t, s.Visible(10), 2, "暫停時不應顯示新日誌")
+	assert.Equal(t, 1, s.NewSinceHold)
+
+	s.TogglePause()
+	assert.Len(t, s.Visible(10), 3)
+
+	s.Filter = logs.Filter{MinLevel: logs.LevelError}
+	visible := s.Visible(1)
+	assert.Len(t, visible, 1)
+	assert.Equal(t, "c", visible[0].Message, "應保留最近的日誌")
+
+	s.Filter = logs.Filter{Inbound: "tuic-in"}
+	assert.Equal(t, "a", s.Visible(10)[0].Message)
+
+	s.Stop()
+	assert.False(t, s.IsActive())
+}
+
+func TestServiceLogState_Cap(t *testing.T) {
+	s := NewServiceLogState()
+	s.Append(make([]logs.Entry, maxServiceLogEntries+10))
+	assert.Len(t, s.Entries, maxServiceLogEntries)
+}
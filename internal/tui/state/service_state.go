@@ -9,6 +9,8 @@ type ServiceState struct {
 	AutoStart   bool
 	ConfirmStop bool
 	HealthCheck *types.HealthCheckResult
+	Log         *ServiceLogState
+	Traffic     *TrafficState
 }
 
 // HealthCheckStatus 健康檢查狀態
@@ -24,5 +26,7 @@ func NewServiceState() *ServiceState {
 		AutoStart:   false,
 		ConfirmStop: false,
 		HealthCheck: nil,
+		Log:         NewServiceLogState(),
+		Traffic:     NewTrafficState(),
 	}
 }
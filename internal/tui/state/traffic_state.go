@@ -0,0 +1,64 @@
+package state
+
+import (
+	"github.com/Yat-Muk/prism-v2/internal/tui/types"
+)
+
+// 默認只展示最活躍的連接數
+const trafficCollapsedConns = 10
+
+// TrafficState 流量面板狀態
+type TrafficState struct {
+	Dashboard *types.TrafficDashboard
+	Err       string
+	Paused    bool
+	ShowAll   bool
+	Fetching  bool // 上一次採樣尚未返回時跳過新的採樣
+	Frozen    bool // 輸入指令期間凍結連接列表，保證序號與屏幕顯示一致
+}
+
+// NewTrafficState 創建流量面板狀態
+func NewTrafficState() *TrafficState {
+	return &TrafficState{}
+}
+
+// Reset 重新進入面板時清空舊數據
+func (s *TrafficState) Reset() {
+	*s = TrafficState{}
+}
+
+// ShouldFetch 是否需要發起新的採樣
+func (s *TrafficState) ShouldFetch() bool {
+	return !s.Paused && !s.Fetching && !s.Frozen
+}
+
+// Apply 應用採樣結果，凍結期間丟棄結果以免序號錯位
+func (s *TrafficState) Apply(d *types.TrafficDashboard) {
+	s.Fetching = false
+	s.Err = ""
+	if s.Frozen {
+		return
+	}
+	s.Dashboard = d
+}
+
+// VisibleConnections 返回當前展示的連接
+func (s *TrafficState) VisibleConnections() []types.ConnectionInfo {
+	if s.Dashboard == nil {
+		return nil
+	}
+	conns := s.Dashboard.Connections
+	if !s.ShowAll && len(conns) > trafficCollapsedConns {
+		conns = conns[:trafficCollapsedConns]
+	}
+	return conns
+}
+
+// ConnectionAt 按面板序號 (從 1 開始) 返回連接
+func (s *TrafficState) ConnectionAt(n int) (types.ConnectionInfo, bool) {
+	conns := s.VisibleConnections()
+	if n < 1 || n > len(conns) {
+		return types.ConnectionInfo{}, false
+	}
+	return conns[n-1], true
+}
@@ -0,0 +1,52 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Yat-Muk/prism-v2/internal/tui/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrafficState_ConnectionSelection(t *testing.T) {
+	s := NewTrafficState()
+	assert.True(t, s.ShouldFetch())
+
+	_, ok := s.ConnectionAt(1)
+	assert.False(t, ok, "無數據時不應返回連接")
+
+	d := &types.TrafficDashboard{}
+	for i := 1; i <= 15; i++ {
+		d.Connections = append(d.Connections, types.ConnectionInfo{ID: fmt.Sprintf("c%d", i)})
+	}
+	s.Dashboard = d
+
+	assert.Len(t, s.VisibleConnections(), trafficCollapsedConns)
+	_, ok = s.ConnectionAt(12)
+	assert.False(t, ok, "折疊時不能選擇未顯示的連接")
+
+	s.ShowAll = true
+	conn, ok := s.ConnectionAt(12)
+	assert.True(t, ok)
+	assert.Equal(t, "c12", conn.ID)
+
+	s.Fetching = true
+	assert.False(t, s.ShouldFetch(), "上次採樣未返回時不應重複請求")
+
+	// 輸入指令期間列表凍結，採樣結果不應改變序號
+	s.Frozen = true
+	s.Apply(&types.TrafficDashboard{Connections: []types.ConnectionInfo{{ID: "new"}}})
+	assert.False(t, s.Fetching)
+	assert.False(t, s.ShouldFetch(), "凍結期間不應發起採樣")
+	conn, _ = s.ConnectionAt(12)
+	assert.Equal(t, "c12", conn.ID)
+
+	s.Frozen = false
+	s.Apply(&types.TrafficDashboard{Connections: []types.ConnectionInfo{{ID: "new"}}})
+	conn, _ = s.ConnectionAt(1)
+	assert.Equal(t, "new", conn.ID)
+
+	s.Reset()
+	assert.Nil(t, s.Dashboard)
+	assert.False(t, s.ShowAll)
+}
@@ -43,6 +43,8 @@ const (
 	ShadowTLSView
 	BrutalView
 	AnyTLSPaddingView
+	InboundRoutingView
+	ProtocolOptionsView
 	SNIEditView
 	UUIDEditView
 	OutboundMenuView
@@ -52,12 +54,15 @@ const (
 	// ===================================
 	RouteMenuView
 	WARPRoutingView
+	WARPConfigView
 	Socks5RoutingView
 	Socks5InboundView
 	Socks5OutboundView
 	IPv6RoutingView
 	DNSRoutingView
 	SNIProxyRoutingView
+	CustomOutboundView
+	RoutingRulesView
 
 	// ===================================
 	// 證書管理 (400-499)
@@ -84,6 +89,7 @@ const (
 	ServiceMenuView
 	ServiceLogView
 	ServiceHealthView
+	TrafficDashboardView
 
 	ToolsMenuView
 	ScriptUpdateView
@@ -103,6 +109,7 @@ const (
 	// ===================================
 	LogMenuView
 	LogLevelEditView
+	LogQueryView
 
 	// ===================================
 	// 節點信息 (700-799)
@@ -49,6 +49,15 @@ type BBRInfo struct {
 	Algorithm     string
 }
 
+// --- Hysteria2 帶寬檢測 ---
+type Hy2BandwidthInfo struct {
+	Interface       string  // 默認路由網卡
+	LinkMbps        int     // 網卡速率，0 表示未知
+	LoopbackMbps    float64 // 本機回環吞吐
+	CPULimitMbps    int     // 按回環吞吐估算的處理上限
+	RecommendedMbps int     // 推薦的上下行帶寬
+}
+
 // --- Service Health ---
 type HealthCheckResult struct {
 	OverallStatus   string
@@ -79,6 +88,47 @@ type SubscriptionInfo struct {
 	NodeCount  int
 }
 
+// --- Traffic Dashboard ---
+
+// TrafficDashboard 流量面板 (數值已格式化)
+type TrafficDashboard struct {
+	UpRate          string
+	DownRate        string
+	UploadTotal     string
+	DownloadTotal   string
+	Memory          string
+	TotalConns      int
+	Inbounds        []InboundTraffic
+	Connections     []ConnectionInfo
+	TopDestinations []DestinationTraffic
+	UpdateTime      string
+}
+
+type InboundTraffic struct {
+	Tag         string
+	Connections int
+	UpRate      string
+	DownRate    string
+}
+
+type ConnectionInfo struct {
+	ID          string
+	Inbound     string
+	Network     string
+	Source      string
+	Destination string
+	Outbound    string
+	Traffic     string // 累計 上傳/下載
+	Rate        string // 當前 上傳/下載 速率
+	Duration    string
+}
+
+type DestinationTraffic struct {
+	Host        string
+	Connections int
+	Total       string
+}
+
 type ClientConfigInfo struct {
 	Format     string
 	FilePath   string
@@ -93,7 +143,7 @@ type LogInfo struct {
 	LogPath    string
 	LogSize    string
 	TodayLines int
-	ErrorCount int
+	ErrorCount int // 今日錯誤級別日誌數 (含當日輪轉文件)
 	RecentLogs []string
 }
 
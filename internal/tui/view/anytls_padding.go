@@ -1,17 +1,85 @@
 package view
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
 	"github.com/Yat-Muk/prism-v2/internal/tui/style"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// RenderAnyTLSPaddingMenu 渲染 AnyTLS 填充策略菜單
-// currentName 用來顯示「當前方案: xxx」
-func RenderAnyTLSPaddingMenu(ti textinput.Model, currentName string, statusMsg string) string {
+// 預覽直方圖尺寸
+const (
+	paddingHistogramBuckets = 8
+	paddingHistogramWidth   = 30
+)
+
+// paddingCurrentName 返回當前策略名稱，自定義方案與已保存方案一致時顯示其名稱
+func paddingCurrentName(cfg *config.Config) string {
+	c := cfg.Protocols.AnyTLS()
+	if len(c.PaddingScheme) == 0 {
+		return c.PaddingMode
+	}
+	for _, p := range cfg.Protocols.PaddingProfiles {
+		if slices.Equal(p.Scheme, c.PaddingScheme) {
+			return "自定義 (" + p.Name + ")"
+		}
+	}
+	return "自定義"
+}
+
+// renderPaddingPreview 渲染方案內容、語法檢查結果與前 N 個包的包長分佈
+func renderPaddingPreview(lines []string, packets int) string {
+	labelStyle := lipgloss.NewStyle().Foreground(style.Snow2)
+	valStyle := lipgloss.NewStyle().Foreground(style.Aurora4)
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+
+	out := []string{labelStyle.Render(" 方案: ") + valStyle.Render(strings.Join(lines, "; "))}
+
+	scheme, err := config.ParsePaddingScheme(lines)
+	if err != nil {
+		return strings.Join(append(out,
+			lipgloss.NewStyle().Foreground(style.Error).Render(" ✗ 語法錯誤: "+err.Error())), "\n")
+	}
+
+	if packets <= 0 || packets > scheme.Stop {
+		packets = scheme.Stop
+	}
+	segments := scheme.Segments(packets)
+	total := 0
+	for _, seg := range segments {
+		total += (seg.Min + seg.Max) / 2
+	}
+	out = append(out, labelStyle.Render(fmt.Sprintf(" 前 %d 個包: %d 個填充片段，合計約 %d 字節", packets, len(segments), total)))
+
+	buckets := config.PaddingHistogram(segments, paddingHistogramBuckets)
+	peak := 0.0
+	for _, b := range buckets {
+		peak = max(peak, b.Count)
+	}
+	for _, b := range buckets {
+		bar := 0
+		if peak > 0 {
+			bar = int(b.Count/peak*paddingHistogramWidth + 0.5)
+		}
+		out = append(out, fmt.Sprintf(" %5d-%-5d |%s %s",
+			b.Min, b.Max,
+			valStyle.Render(strings.Repeat("#", bar)+strings.Repeat(" ", paddingHistogramWidth-bar)),
+			mutedStyle.Render(fmt.Sprintf("%.1f", b.Count))))
+	}
+	if len(segments) > 0 && slices.ContainsFunc(segments, func(s config.PaddingSegment) bool { return s.Check }) {
+		out = append(out, mutedStyle.Render(" c 之後的片段僅在仍有數據待發時發送，預覽按全部發送估算"))
+	}
+	return strings.Join(out, "\n")
+}
+
+// RenderAnyTLSPaddingMenu 渲染 AnyTLS 填充策略菜單與方案編輯器
+// draft 為編輯中的方案，為空時預覽當前生效的方案
+func RenderAnyTLSPaddingMenu(cfg *config.Config, draft []string, previewPackets int, ti textinput.Model, statusMsg string) string {
 	header := renderSubpageHeader("AnyTLS 填充策略")
 
 	desc := lipgloss.NewStyle().
@@ -26,12 +94,36 @@ func RenderAnyTLSPaddingMenu(ti textinput.Model, currentName string, statusMsg s
 	labelStyle := lipgloss.NewStyle().Foreground(style.Snow2)
 	nameStyle := lipgloss.NewStyle().Foreground(style.Aurora4)
 
+	currentName := ""
+	var preview []string
+	var profiles []string
+	if cfg != nil {
+		currentName = paddingCurrentName(cfg)
+		c := cfg.Protocols.AnyTLS()
+		preview = config.EffectivePaddingScheme(c.PaddingMode, c.PaddingScheme)
+		for _, p := range cfg.Protocols.PaddingProfiles {
+			profiles = append(profiles, p.Name)
+		}
+	}
+	if len(draft) > 0 {
+		currentName += " | 編輯草稿中"
+		preview = draft
+	}
+
 	currentLine := lipgloss.JoinHorizontal(
 		lipgloss.Left,
 		labelStyle.Render(" 當前策略: "),
 		nameStyle.Render(currentName),
 	)
 
+	infoLines := []string{currentLine}
+	if len(preview) > 0 {
+		infoLines = append(infoLines, renderPaddingPreview(preview, previewPackets))
+	}
+	if len(profiles) > 0 {
+		infoLines = append(infoLines, labelStyle.Render(" 已保存方案: ")+nameStyle.Render(strings.Join(profiles, ", ")))
+	}
+
 	items := []MenuItem{
 		{"", "", "", lipgloss.Color("")},
 		{constants.KeyPadding_Balanced, "均衡流", "(模擬網頁瀏覽，流量自然) [推薦]", style.Aurora1},
@@ -39,14 +131,21 @@ func RenderAnyTLSPaddingMenu(ti textinput.Model, currentName string, statusMsg s
 		{constants.KeyPadding_HighResist, "高對抗流", "(針對被重度干擾時使用)", style.Snow1},
 		{constants.KeyPadding_Video, "視頻特徵", "(模擬視頻啟播流量特徵)", style.Snow1},
 		{constants.KeyPadding_Official, "官方默認", "(Sing-box 官方示例配置)", style.Snow1},
+		{"", "", "", lipgloss.Color("")},
+		{constants.KeyPadding_Edit, "編輯方案", "(stop=N / 序號=a-b,c,...，以 ; 分隔)", style.Snow1},
+		{constants.KeyPadding_Preview, "預覽包數", "(直方圖統計前 N 個包)", style.Snow1},
+		{constants.KeyPadding_Apply, "應用草稿", "(寫入所有 AnyTLS 實例)", style.Snow1},
+		{constants.KeyPadding_Save, "保存為命名方案", "(草稿或當前方案)", style.Snow1},
+		{constants.KeyPadding_Load, "應用命名方案", "", style.Snow1},
+		{constants.KeyPadding_Delete, "刪除命名方案", "", style.Snow1},
 	}
 
 	menu := renderMenuWithAlignment(items, 0, "", false)
 
 	instruction := lipgloss.NewStyle().
 		Foreground(style.Snow3).
-		Render(` 
- 提示： 
+		Render(`
+ 提示：
    • 會相應增加延遲和流量開銷
    • 理論上可將被識別概率降至極低`)
 
@@ -59,7 +158,7 @@ func RenderAnyTLSPaddingMenu(ti textinput.Model, currentName string, statusMsg s
 		header,
 		desc,
 		divider,
-		currentLine,
+		strings.Join(infoLines, "\n"),
 		menu,
 		"",
 		instruction,
@@ -24,13 +24,7 @@ func RenderCertModeMenu(cfg *config.Config, enabledProtos []int, acmeDomains []s
 		Foreground(style.Polar4).
 		Render(strings.Repeat("─", 50))
 
-	var supportedProtos []int
-	for _, pid := range enabledProtos {
-		id := protocol.ID(pid)
-		if id == protocol.IDHysteria2 || id == protocol.IDTUIC || id == protocol.IDAnyTLS {
-			supportedProtos = append(supportedProtos, pid)
-		}
-	}
+	supportedProtos := protocol.CertIDs(enabledProtos)
 
 	var items []MenuItem
 
@@ -44,7 +38,7 @@ func RenderCertModeMenu(cfg *config.Config, enabledProtos []int, acmeDomains []s
 			divider,
 			"",
 			warnStyle.Render(" 提示：當前沒有開啟任何需要本地證書的協議"),
-			snow3Style.Render("      (僅支持 Hysteria2 / TUIC / AnyTLS)"),
+			snow3Style.Render(fmt.Sprintf("      (僅支持 %s)", strings.Join(certProtocolNames(), " / "))),
 			snow3Style.Render("      請先去 [配置與協議] -> [協議管理] 中開啟"),
 		)
 		items = []MenuItem{}
@@ -55,7 +49,7 @@ func RenderCertModeMenu(cfg *config.Config, enabledProtos []int, acmeDomains []s
 
 	maxNameWidth := 0
 	for _, protoID := range supportedProtos {
-		name := getProtocolDisplayName(protoID)
+		name := protoID.String()
 		w := runewidth.StringWidth(name)
 		if w > maxNameWidth {
 			maxNameWidth = w
@@ -67,9 +61,8 @@ func RenderCertModeMenu(cfg *config.Config, enabledProtos []int, acmeDomains []s
 	noneStyle := lipgloss.NewStyle().Foreground(style.Snow3)
 
 	for i, protoID := range supportedProtos {
-		name := getProtocolDisplayName(protoID)
-		certDomain := getProtocolCertDomain(cfg, protoID)
-		certMode := getProtocolCertMode(cfg, protoID)
+		name := protoID.String()
+		certMode, certDomain := getProtocolCert(cfg, protoID)
 
 		nameText := name
 		w := runewidth.StringWidth(nameText)
@@ -132,41 +125,26 @@ func RenderCertModeMenu(cfg *config.Config, enabledProtos []int, acmeDomains []s
 	)
 }
 
-func getProtocolDisplayName(protoID int) string {
-	switch protocol.ID(protoID) {
-	case protocol.IDHysteria2:
-		return "Hysteria 2"
-	case protocol.IDTUIC:
-		return "TUIC v5"
-	case protocol.IDAnyTLS:
-		return "AnyTLS"
-	default:
-		return "未知協議"
+// getProtocolCert 返回協議當前的證書模式與證書域名
+func getProtocolCert(cfg *config.Config, id protocol.ID) (mode, domain string) {
+	d, ok := protocol.Lookup(id)
+	if !ok {
+		return "", ""
 	}
-}
-
-func getProtocolCertDomain(cfg *config.Config, protoID int) string {
-	switch protocol.ID(protoID) {
-	case protocol.IDHysteria2:
-		return cfg.Protocols.Hysteria2.CertDomain
-	case protocol.IDTUIC:
-		return cfg.Protocols.TUIC.CertDomain
-	case protocol.IDAnyTLS:
-		return cfg.Protocols.AnyTLS.CertDomain
-	default:
-		return ""
+	sec, ok := d.Section(cfg)
+	if !ok || !sec.NeedsCert() {
+		return "", ""
 	}
+	return *sec.CertMode, *sec.CertDomain
 }
 
-func getProtocolCertMode(cfg *config.Config, protoID int) string {
-	switch protocol.ID(protoID) {
-	case protocol.IDHysteria2:
-		return cfg.Protocols.Hysteria2.CertMode
-	case protocol.IDTUIC:
-		return cfg.Protocols.TUIC.CertMode
-	case protocol.IDAnyTLS:
-		return cfg.Protocols.AnyTLS.CertMode
-	default:
-		return ""
+// certProtocolNames 返回所有使用本地證書的協議名稱
+func certProtocolNames() []string {
+	var names []string
+	for _, d := range protocol.Descriptors() {
+		if d.NeedsCert {
+			names = append(names, d.Name)
+		}
 	}
+	return names
 }
@@ -34,6 +34,9 @@ func RenderConfigMenu(cursor int, ti textinput.Model, statusMsg string) string {
 		{constants.KeyConfig_UUID, "修改 UUID", "(用戶標識符)", style.Snow1},
 		{constants.KeyConfig_Port, "修改監聽端口", "(服務端口設置)", style.Snow1},
 		{constants.KeyConfig_Padding, "AnyTLS 填充策略", "(調整偽裝流量特徵)", style.Snow1},
+		{constants.KeyConfig_Routing, "入站路由策略", "(按協議分流 / 屏蔽 / IPv6 偏好)", style.Snow1},
+		{constants.KeyConfig_Options, "協議高級參數", "(ALPN / 擁塞控制 / SS 加密等)", style.Snow1},
+		{constants.KeyConfig_Bandwidth, "Hysteria2 帶寬", "(帶寬檢測 / Brutal 開關)", style.Snow1},
 
 		{"", "", "", lipgloss.Color("")}, // 分組線
 
@@ -0,0 +1,84 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
+	"github.com/Yat-Muk/prism-v2/internal/tui/style"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RenderCustomOutbounds 渲染自定義出站管理界面
+func RenderCustomOutbounds(outbounds []config.CustomOutbound, ti textinput.Model, statusMsg string) string {
+	header := renderSubpageHeader("自定義出站")
+
+	desc := lipgloss.NewStyle().
+		Foreground(style.Snow2).
+		Render(" 添加上游節點，支持前置代理 (detour) 鏈式轉發")
+
+	divider := lipgloss.NewStyle().
+		Foreground(style.Polar4).
+		Render(strings.Repeat("─", 50))
+
+	labelStyle := lipgloss.NewStyle().Foreground(style.Snow3)
+	tagStyle := lipgloss.NewStyle().Foreground(style.Aurora2)
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+
+	var lines []string
+	if len(outbounds) == 0 {
+		lines = append(lines, mutedStyle.Render(" 暫無自定義出站"))
+	}
+	for _, o := range outbounds {
+		chain := ""
+		if o.Detour != "" {
+			chain = " ← " + o.Detour
+		}
+		lines = append(lines, fmt.Sprintf(" %s %s%s",
+			tagStyle.Render(o.Tag),
+			labelStyle.Render(fmt.Sprintf("[%s] %s", o.Type, o.Address())),
+			mutedStyle.Render(chain)))
+
+		var targets []string
+		targets = append(targets, o.Domains...)
+		targets = append(targets, o.RuleSets...)
+		if len(targets) > 0 {
+			lines = append(lines, mutedStyle.Render("   分流: "+formatDomainList(targets)))
+		}
+	}
+	list := strings.Join(lines, "\n")
+
+	items := []MenuItem{
+		{"", "", "", lipgloss.Color("")},
+		{constants.KeyCustomOut_Add, "添加出站", "(粘貼 ss/vless/trojan/http/socks5/wg 鏈接)", style.StatusGreen},
+		{constants.KeyCustomOut_Delete, "刪除出站", "(輸入標籤)", style.StatusRed},
+		{constants.KeyCustomOut_Detour, "設置前置代理", "(標籤 前置標籤，留空前置則清除)", style.Snow1},
+		{constants.KeyCustomOut_Domains, "分流域名", "(標籤 域名1,域名2)", style.Snow1},
+		{constants.KeyCustomOut_RuleSets, "分流規則集", "(標籤 netflix,geoip-jp)", style.Snow1},
+	}
+
+	menu := renderMenuWithAlignment(items, 0, "", false)
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 可使用 ?detour=標籤 在鏈接中直接指定前置代理")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+
+	footer := RenderInputFooter(ti)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		desc,
+		divider,
+		list,
+		menu,
+		"",
+		instruction,
+		statusBlock,
+		footer,
+	)
+}
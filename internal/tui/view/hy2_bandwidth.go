@@ -0,0 +1,111 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
+	"github.com/Yat-Muk/prism-v2/internal/tui/style"
+	"github.com/Yat-Muk/prism-v2/internal/tui/types"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// brutalEffect 說明 Brutal 開關對客戶端的影響
+func brutalEffect(c *config.Hysteria2Config) string {
+	if c.IgnoreClientBandwidth {
+		return "關閉: 客戶端使用 BBR，按網絡狀況自動調整速率"
+	}
+	up, down := c.GetBandwidth()
+	return fmt.Sprintf("開啟: 按 %d/%d Mbps 固定速率收發，丟包時不降速", up, down)
+}
+
+// RenderHy2Bandwidth 配置與協議 > Hysteria2 帶寬與 Brutal
+func RenderHy2Bandwidth(cfg *config.Config, info *types.Hy2BandwidthInfo, measuring bool, ti textinput.Model, statusMsg string) string {
+	header := renderSubpageHeader("Hysteria 2 帶寬與 Brutal")
+
+	desc1 := lipgloss.NewStyle().
+		Foreground(style.Snow2).
+		Render(" Brutal 按設定帶寬固定速率發送，設置高於實際線路會大量丟包，低於則浪費帶寬")
+
+	infoSep := lipgloss.NewStyle().
+		Foreground(style.Polar4).
+		Render(strings.Repeat("─", 50))
+
+	labelStyle := lipgloss.NewStyle().Foreground(style.Snow2)
+	valStyle := lipgloss.NewStyle().Foreground(style.Aurora4)
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+
+	var lines []string
+	if cfg != nil {
+		for i, inst := range cfg.Protocols.InstancesOf(config.ProtocolTypeHysteria2) {
+			label := " 3. Hysteria2"
+			if i > 0 {
+				label = " 3 └ " + inst.Tag
+			}
+			line := labelStyle.Render(label+"  ") + valStyle.Render(brutalEffect(inst.Hysteria2))
+			if !inst.Hysteria2.Enabled {
+				line += mutedStyle.Render(" (未啟用)")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	// 檢測結果
+	switch {
+	case measuring:
+		lines = append(lines, "", labelStyle.Render(" 正在測速，約需 3 秒..."))
+	case info != nil:
+		link := "未知 (虛擬網卡，按 1000 Mbps 估算)"
+		if info.LinkMbps > 0 {
+			link = fmt.Sprintf("%d Mbps", info.LinkMbps)
+		}
+		iface := info.Interface
+		if iface == "" {
+			iface = "未知"
+		}
+		lines = append(lines,
+			"",
+			labelStyle.Render(" 網卡: ")+valStyle.Render(fmt.Sprintf("%s (%s)", iface, link)),
+			labelStyle.Render(" 本機處理能力: ")+valStyle.Render(fmt.Sprintf("約 %d Mbps (回環 %.0f Mbps)", info.CPULimitMbps, info.LoopbackMbps)),
+			labelStyle.Render(" 推薦上下行: ")+lipgloss.NewStyle().Foreground(style.Aurora2).Render(fmt.Sprintf("%d Mbps", info.RecommendedMbps)),
+		)
+	}
+
+	infoBlock := lipgloss.JoinVertical(
+		lipgloss.Left,
+		desc1,
+		infoSep,
+		strings.Join(lines, "\n"),
+	)
+
+	items := []MenuItem{
+		{"", "", "", lipgloss.Color("")},
+		{constants.KeyBandwidth_Detect, "檢測帶寬", "(網卡速率 + 本機吞吐測試)", style.Aurora1},
+		{constants.KeyBandwidth_Apply, "應用推薦值", "(寫入所有 Hysteria2 實例)", style.Snow1},
+		{constants.KeyBandwidth_Manual, "手動設置帶寬", "(編號或標籤 上行 [下行]，如 3 200 500)", style.Snow1},
+		{constants.KeyBandwidth_Brutal, "Brutal 開關", "(編號或標籤 on/off，如 3 off)", style.Snow1},
+	}
+
+	menu := renderMenuWithAlignment(items, 0, "", false)
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 網卡速率無法反映服務商限速，請以套餐帶寬為上限")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+
+	footer := RenderInputFooter(ti)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		infoBlock,
+		menu,
+		"",
+		instruction,
+		statusBlock,
+		footer,
+	)
+}
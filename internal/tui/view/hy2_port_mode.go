@@ -11,12 +11,12 @@ import (
 )
 
 // RenderHy2PortMode 渲染 Hysteria 2 端口模式選擇頁
-func RenderHy2PortMode(currentPort int, currentHopping string, ti textinput.Model, statusMsg string, isEditing bool) string {
+func RenderHy2PortMode(currentPort int, currentHopping, currentInterval string, ti textinput.Model, statusMsg string, isEditing bool) string {
 	header := renderSubpageHeader("Hysteria 2 端口設置")
 
 	desc1 := lipgloss.NewStyle().
 		Foreground(style.Snow2).
-		Render(" 跳躍端口格式為 start-end, 例如: 20000-30000")
+		Render(" 跳躍端口格式為 start-end, 多個範圍以逗號分隔, 例如: 20000-30000,40000-45000")
 
 		// 信息顯示區域
 	valStyle := lipgloss.NewStyle().Foreground(style.Aurora4)
@@ -38,6 +38,16 @@ func RenderHy2PortMode(currentPort int, currentHopping string, ti textinput.Mode
 		valStyle.Render(hoppingText),
 	)
 
+	// 構建跳躍間隔行
+	intervalText := "客戶端默認 (30s)"
+	if currentInterval != "" {
+		intervalText = currentInterval
+	}
+	intervalBlock := lipgloss.JoinHorizontal(lipgloss.Left,
+		labelStyle.Render(" 跳躍間隔: "),
+		valStyle.Render(intervalText),
+	)
+
 	// 分隔線
 	infoSep := lipgloss.NewStyle().
 		Foreground(style.Polar4).
@@ -50,12 +60,14 @@ func RenderHy2PortMode(currentPort int, currentHopping string, ti textinput.Mode
 		infoSep,
 		portBlock,
 		hoppingBlock,
+		intervalBlock,
 	)
 
 	items := []MenuItem{
 		{"", "", "", lipgloss.Color("")},
 		{constants.KeyPort_Main, "修改主端口", "", style.Snow1},
 		{constants.KeyPort_Hopping, "設置跳躍端口", "", style.Snow1},
+		{constants.KeyPort_HopInterval, "設置跳躍間隔", "", style.Snow1},
 		{"", "", "", lipgloss.Color("")},
 		{constants.KeyPort_ClearHopping, "清除跳躍端口", "", style.StatusRed},
 	}
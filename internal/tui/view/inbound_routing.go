@@ -0,0 +1,105 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
+	"github.com/Yat-Muk/prism-v2/internal/tui/style"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RenderInboundRouting 配置與協議 > 入站路由策略
+func RenderInboundRouting(cfg *config.Config, ti textinput.Model, statusMsg string) string {
+	header := renderSubpageHeader("入站路由策略")
+
+	desc := lipgloss.NewStyle().
+		Foreground(style.Snow2).
+		Render(" 為各協議單獨設置默認出站、屏蔽類別與 IPv6 偏好")
+
+	divider := lipgloss.NewStyle().
+		Foreground(style.Polar4).
+		Render(strings.Repeat("─", 50))
+
+	labelStyle := lipgloss.NewStyle().Foreground(style.Snow1)
+	valueStyle := lipgloss.NewStyle().Foreground(style.Aurora2)
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+
+	allIDs := protocol.AllIDs()
+	maxNameWidth := 0
+	for _, id := range allIDs {
+		if w := runewidth.StringWidth(id.String()); w > maxNameWidth {
+			maxNameWidth = w
+		}
+	}
+
+	var lines []string
+	for _, id := range allIDs {
+		name := id.String()
+		name += strings.Repeat(" ", maxNameWidth-runewidth.StringWidth(name))
+		line := fmt.Sprintf(" %d. %s  ", id, name)
+
+		p := protocol.RoutingProfile(cfg, id)
+		if p == nil || p.IsEmpty() {
+			summary := "全局路由"
+			if !protocol.IsEnabled(cfg, id) {
+				summary += " (未啟用)"
+			}
+			lines = append(lines, labelStyle.Render(line)+mutedStyle.Render(summary))
+			continue
+		}
+
+		var parts []string
+		if p.Outbound != "" {
+			parts = append(parts, "出站 "+p.Outbound)
+		}
+		if len(p.Block) > 0 {
+			parts = append(parts, "屏蔽 "+strings.Join(p.Block, ","))
+		}
+		if p.IPv6 != "" {
+			parts = append(parts, p.IPv6)
+		}
+		summary := valueStyle.Render(strings.Join(parts, " | "))
+		if !protocol.IsEnabled(cfg, id) {
+			summary += mutedStyle.Render(" (未啟用)")
+		}
+		lines = append(lines, labelStyle.Render(line)+summary)
+	}
+	list := strings.Join(lines, "\n")
+
+	items := []MenuItem{
+		{"", "", "", lipgloss.Color("")},
+		{constants.KeyInbound_Outbound, "默認出站", "(編號 出站標籤，如 3 warp-out)", style.Snow1},
+		{constants.KeyInbound_Block, "屏蔽類別", "(編號 " + strings.Join(config.BlockCategories(), ",") + ")", style.Snow1},
+		{constants.KeyInbound_IPv6, "IPv6 偏好", "(編號 prefer_ipv4/prefer_ipv6/ipv4_only/ipv6_only)", style.Snow1},
+		{constants.KeyInbound_Clear, "清除策略", "(輸入編號，恢復全局路由)", style.StatusRed},
+	}
+
+	menu := renderMenuWithAlignment(items, 0, "", false)
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 只輸入編號可清除對應字段，修改後請記得「應用配置」")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+
+	footer := RenderInputFooter(ti)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		desc,
+		divider,
+		list,
+		menu,
+		"",
+		instruction,
+		statusBlock,
+		footer,
+	)
+}
@@ -72,7 +72,7 @@ func RenderInstallWizard(selected []int, ti textinput.Model, statusMsg string) s
 		items = append(items, MenuItem{
 			Num:       fmt.Sprintf("%d", id), // 動態使用 ID
 			Text:      pad(id.String(), enabled[int(id)]),
-			Desc:      id.Tag(),
+			Desc:      id.Badge(),
 			TextColor: style.Snow1,
 		})
 	}
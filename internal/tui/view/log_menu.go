@@ -47,7 +47,7 @@ func RenderLogMenu(info *types.LogInfo, ti textinput.Model, statusMsg string) st
 			valueStyle.Render(info.LogSize),
 			labelStyle.Render("今日日誌:"),
 			info.TodayLines,
-			labelStyle.Render("錯誤計數:"),
+			labelStyle.Render("今日錯誤:"),
 			errorText,
 		)
 	} else {
@@ -61,8 +61,9 @@ func RenderLogMenu(info *types.LogInfo, ti textinput.Model, statusMsg string) st
 		{constants.KeyLog_Realtime, "查看實時日誌", "(顯示最新的日誌輸出)", style.Aurora1},
 		{constants.KeyLog_Full, "查看完整日誌", "(顯示所有歷史日誌)", style.Snow1},
 		{constants.KeyLog_Error, "查看錯誤日誌", "(僅顯示錯誤級別日誌)", style.StatusRed},
+		{constants.KeyLog_Query, "查詢日誌", "(按時間/級別/字段搜索)", style.Aurora1},
 		{constants.KeyLog_Level, "修改日誌級別", "(Debug/Info/Warn/Error)", style.Snow1},
-		{constants.KeyLog_Export, "導出日誌文件", "(保存到本地)", style.Snow1},
+		{constants.KeyLog_Export, "導出診斷包", "(脫敏後打包日誌與配置)", style.Snow1},
 		{"", "", "", lipgloss.Color("")},
 		{constants.KeyLog_Clear, "清空日誌", "(刪除現有日誌文件)", style.StatusRed},
 	}
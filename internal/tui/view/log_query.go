@@ -0,0 +1,68 @@
+package view
+
+import (
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/tui/style"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RenderLogQuery 渲染日誌查詢頁
+func RenderLogQuery(lastQuery string, ti textinput.Model, statusMsg string) string {
+	header := renderSubpageHeader("查詢日誌")
+
+	desc := lipgloss.NewStyle().
+		Foreground(style.Snow2).
+		Render(" 搜索 Prism 與 sing-box 日誌 (包含輪轉與壓縮的歷史文件)")
+
+	divider := lipgloss.NewStyle().
+		Foreground(style.Polar4).
+		Render(strings.Repeat("─", 50))
+
+	labelStyle := lipgloss.NewStyle().Foreground(style.Aurora2).Width(12)
+	textStyle := lipgloss.NewStyle().Foreground(style.Snow3)
+
+	syntax := [][2]string{
+		{"level=", "最低級別: debug / info / warn / error"},
+		{"since=", "起始時間: 30m、2h、7d 或 2024-01-02T15:00"},
+		{"until=", "結束時間: 格式同 since"},
+		{"inbound=", "入站標籤: hysteria2-in、tuic-in ..."},
+		{"source=", "來源: prism / sing-box / all"},
+		{"limit=", "最多顯示最近 N 條 (默認 500)"},
+		{"字段=值", "按字段匹配: domain=google outbound=warp-out"},
+		{"關鍵字", "不帶 = 的詞按消息內容搜索"},
+	}
+	var lines []string
+	for _, s := range syntax {
+		lines = append(lines, " "+labelStyle.Render(s[0])+textStyle.Render(s[1]))
+	}
+
+	var lastLine string
+	if lastQuery != "" {
+		lastLine = lipgloss.NewStyle().
+			Foreground(style.Muted).
+			Render(" 上次查詢: " + lastQuery)
+	}
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 例如: level=warn since=2h inbound=hysteria2-in timeout")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+
+	footer := RenderInputFooter(ti)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		desc,
+		divider,
+		strings.Join(lines, "\n"),
+		"",
+		lastLine,
+		instruction,
+		statusBlock,
+		footer,
+	)
+}
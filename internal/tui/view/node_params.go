@@ -12,7 +12,7 @@ import (
 // RenderNodeParams 渲染節點詳細參數
 func RenderNodeParams(cfg *config.Config, serverIP string) string {
 	var sb strings.Builder
-	p := cfg.Protocols
+	p := &cfg.Protocols
 
 	// --- 1. 樣式定義 ---
 
@@ -63,194 +63,264 @@ func RenderNodeParams(cfg *config.Config, serverIP string) string {
 		sb.WriteString(fmt.Sprintf("%s%s\n", keyStyle.Render(key+":"), s.Render(vStr)))
 	}
 
-	// ==========================================
-	// 1. VLESS Reality Vision
-	// ==========================================
-	if p.RealityVision.Enabled {
-		sb.WriteString(titleStyle.Render("VLESS Reality Vision") + "\n")
-		renderRow("Server", serverIP, false)
-		renderRow("Server Port", fmt.Sprintf("%d", p.RealityVision.Port), false)
-		renderRow("UUID", cfg.UUID, true) // 高亮
-		renderRow("Packet Encoding", "xudp", false)
-		renderRow("Flow", "xtls-rprx-vision", false)
-		renderRow("Network", "tcp", false)
-		renderRow("Server Name", p.RealityVision.SNI, false)
-		renderRow("Fingerprint", "chrome", false)
-		renderRow("Public Key", p.RealityVision.PublicKey, true) // 高亮
-		renderRow("Short ID", p.RealityVision.ShortID, true)     // 高亮
-	}
-
-	// ==========================================
-	// 2. VLESS Reality gRPC
-	// ==========================================
-	if p.RealityGRPC.Enabled {
-		sb.WriteString(titleStyle.Render("VLESS Reality gRPC") + "\n")
-		renderRow("Server", serverIP, false)
-		renderRow("Server Port", fmt.Sprintf("%d", p.RealityGRPC.Port), false)
-		renderRow("UUID", cfg.UUID, true)
-		renderRow("Network", "grpc", false)
-		renderRow("Service Name", "grpc", false)
-		renderRow("Server Name", p.RealityGRPC.SNI, false)
-		renderRow("Public Key", p.RealityGRPC.PublicKey, true)
-		renderRow("Short ID", p.RealityGRPC.ShortID, true)
-	}
-
-	// ==========================================
-	// 3. Hysteria 2
-	// ==========================================
-	if p.Hysteria2.Enabled {
-		sb.WriteString(titleStyle.Render("Hysteria 2") + "\n")
-
-		addr := serverIP
-		if p.Hysteria2.CertMode == "acme" && p.Hysteria2.CertDomain != "" {
-			addr = p.Hysteria2.CertDomain
-		}
-
-		renderRow("Server", addr, false)
-		renderRow("Server Port", fmt.Sprintf("%d", p.Hysteria2.Port), false)
-
-		if p.Hysteria2.PortHopping != "" {
-			renderRow("Port Hopping", p.Hysteria2.PortHopping, false)
-		}
-
-		pass := p.Hysteria2.Password
-		if pass == "" {
-			pass = cfg.Password
-		}
-		renderRow("Password", pass, true)
-
-		renderRow("Server Name", p.Hysteria2.SNI, false)
-		renderRow("ALPN", p.Hysteria2.ALPN, false)
-
-		// 智能判斷是否不安全
-		isInsecure := (p.Hysteria2.CertMode == "self_signed")
-		renderBoolRow("Insecure", isInsecure, "true", "false")
-
-		if p.Hysteria2.Obfs != "" {
-			renderRow("Obfs Type", "salamander", false)
-			renderRow("Obfs Password", p.Hysteria2.Obfs, true)
-		}
-
-		bw := fmt.Sprintf("%d Mbps (Up) / %d Mbps (Down)", p.Hysteria2.UpMbps, p.Hysteria2.DownMbps)
-		renderRow("Bandwidth", bw, false)
-	}
-
-	// ==========================================
-	// 4. TUIC v5
-	// ==========================================
-	if p.TUIC.Enabled {
-		sb.WriteString(titleStyle.Render("TUIC v5") + "\n")
-
-		addr := serverIP
-		if p.TUIC.CertMode == "acme" && p.TUIC.CertDomain != "" {
-			addr = p.TUIC.CertDomain
-		}
-
-		renderRow("Server", addr, false)
-		renderRow("Server Port", fmt.Sprintf("%d", p.TUIC.Port), false)
-
-		uuid := p.TUIC.UUID
-		if uuid == "" {
-			uuid = cfg.UUID
-		}
-		renderRow("UUID", uuid, true)
-
-		pass := p.TUIC.Password
-		if pass == "" {
-			pass = cfg.Password
+	// 按協議編號順序渲染各協議的所有已啟用實例 (主實例在前)
+	for _, protocolType := range config.ProtocolTypes() {
+		for _, inst := range p.InstancesOf(protocolType) {
+			switch {
+			// 1. VLESS Reality Vision
+			case inst.RealityVision != nil && inst.RealityVision.Enabled:
+				c := inst.RealityVision
+				sb.WriteString(titleStyle.Render(instanceTitle("VLESS Reality Vision", inst)) + "\n")
+				renderRow("Server", serverIP, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+				renderRow("UUID", inst.Credential(c.UUID, cfg.UUID), true) // 高亮
+				renderRow("Packet Encoding", "xudp", false)
+				renderRow("Flow", "xtls-rprx-vision", false)
+				renderRow("Network", "tcp", false)
+				renderRow("Server Name", c.SNI, false)
+				renderRow("Fingerprint", "chrome", false)
+				renderRow("Public Key", c.PublicKey, true) // 高亮
+				renderRow("Short ID", c.ShortID, true)     // 高亮
+			// 2. VLESS Reality gRPC
+			case inst.RealityGRPC != nil && inst.RealityGRPC.Enabled:
+				c := inst.RealityGRPC
+				sb.WriteString(titleStyle.Render(instanceTitle("VLESS Reality gRPC", inst)) + "\n")
+				renderRow("Server", serverIP, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+				renderRow("UUID", inst.Credential(c.UUID, cfg.UUID), true)
+				renderRow("Network", "grpc", false)
+				renderRow("Service Name", "grpc", false)
+				renderRow("Server Name", c.SNI, false)
+				renderRow("Public Key", c.PublicKey, true)
+				renderRow("Short ID", c.ShortID, true)
+			// 3. Hysteria 2
+			case inst.Hysteria2 != nil && inst.Hysteria2.Enabled:
+				c := inst.Hysteria2
+				sb.WriteString(titleStyle.Render(instanceTitle("Hysteria 2", inst)) + "\n")
+
+				addr := serverIP
+				if c.CertMode == "acme" && c.CertDomain != "" {
+					addr = c.CertDomain
+				}
+
+				renderRow("Server", addr, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+
+				if c.PortHopping != "" {
+					renderRow("Port Hopping", c.PortHopping, false)
+				}
+
+				pass := c.Password
+				if pass == "" {
+					pass = cfg.Password
+				}
+				renderRow("Password", pass, true)
+
+				renderRow("Server Name", c.SNI, false)
+				renderRow("ALPN", strings.Join(c.GetALPN(), ", "), false)
+
+				// 智能判斷是否不安全
+				isInsecure := (c.CertMode == "self_signed")
+				renderBoolRow("Insecure", isInsecure, "true", "false")
+
+				if c.Obfs != "" {
+					renderRow("Obfs Type", "salamander", false)
+					renderRow("Obfs Password", c.Obfs, true)
+				}
+
+				// Brutal: 服務端按設定帶寬固定速率發送；關閉後客戶端使用 BBR，帶寬設置不生效
+				if c.IgnoreClientBandwidth {
+					renderRow("Bandwidth", "不限 (由 BBR 自動調整)", false)
+					renderRow("Brutal", "off — 客戶端使用 BBR 擁塞控制，隨網絡狀況升降速", false)
+				} else {
+					up, down := c.GetBandwidth()
+					renderRow("Bandwidth", fmt.Sprintf("%d Mbps (Up) / %d Mbps (Down)", up, down), false)
+					renderRow("Brutal", "on — 按上述帶寬固定速率收發，丟包時不降速；客戶端帶寬應不高於此值", false)
+				}
+			// 4. TUIC v5
+			case inst.TUIC != nil && inst.TUIC.Enabled:
+				c := inst.TUIC
+				sb.WriteString(titleStyle.Render(instanceTitle("TUIC v5", inst)) + "\n")
+
+				addr := serverIP
+				if c.CertMode == "acme" && c.CertDomain != "" {
+					addr = c.CertDomain
+				}
+
+				renderRow("Server", addr, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+
+				uuid := c.UUID
+				if uuid == "" {
+					uuid = cfg.UUID
+				}
+				renderRow("UUID", uuid, true)
+
+				pass := c.Password
+				if pass == "" {
+					pass = cfg.Password
+				}
+				renderRow("Password", pass, true)
+
+				renderRow("Server Name", c.SNI, false)
+				renderRow("Congestion Control", c.GetCongestionControl(), false)
+				renderRow("ALPN", strings.Join(c.GetALPN(), ", "), false)
+				renderBoolRow("Zero-RTT", c.ZeroRTTHandshake, "true", "false")
+				renderRow("UDP Relay Mode", c.GetUDPRelayMode(), false)
+				renderRow("Heartbeat", c.GetHeartbeat(), false)
+				renderRow("UDP Relay Mode", "native", false)
+
+				isInsecure := (c.CertMode == "self_signed")
+				renderBoolRow("Insecure", isInsecure, "true", "false")
+			// 5. AnyTLS (HTTP/2)
+			case inst.AnyTLS != nil && inst.AnyTLS.Enabled:
+				c := inst.AnyTLS
+				sb.WriteString(titleStyle.Render(instanceTitle("AnyTLS", inst)) + "\n")
+
+				addr := serverIP
+				if c.CertMode == "acme" && c.CertDomain != "" {
+					addr = c.CertDomain
+				}
+
+				renderRow("Server", addr, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+				renderRow("Username", c.GetUsername(), false)
+
+				pass := c.Password
+				if pass == "" {
+					pass = cfg.Password
+				}
+				renderRow("Password", pass, true)
+
+				renderRow("Server Name", c.SNI, false)
+				renderRow("ALPN", strings.Join(c.GetALPN(), ", "), false)
+
+				isInsecure := (c.CertMode == "self_signed")
+				renderBoolRow("Insecure", isInsecure, "true", "false")
+			// 6. AnyTLS Reality
+			case inst.AnyTLSReality != nil && inst.AnyTLSReality.Enabled:
+				c := inst.AnyTLSReality
+				sb.WriteString(titleStyle.Render(instanceTitle("AnyTLS Reality", inst)) + "\n")
+				renderRow("Server", serverIP, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+				renderRow("Username", c.GetUsername(), false)
+
+				pass := c.Password
+				if pass == "" {
+					pass = cfg.Password
+				}
+				renderRow("Password", pass, true)
+
+				// 補全了你之前缺失的參數
+				renderRow("Server Name", c.SNI, false)
+				renderRow("Fingerprint", "chrome", false)
+
+				// Reality 核心參數
+				renderRow("Public Key", c.PublicKey, true) // 高亮
+				renderRow("Short ID", c.ShortID, true)     // 高亮
+			// 7. ShadowTLS v3
+			case inst.ShadowTLS != nil && inst.ShadowTLS.Enabled:
+				c := inst.ShadowTLS
+				sb.WriteString(titleStyle.Render(instanceTitle("ShadowTLS v3", inst)) + "\n")
+				renderRow("Server", serverIP, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+
+				// 握手密碼
+				tlsPass := c.Password
+				if tlsPass == "" {
+					tlsPass = cfg.Password
+				}
+				renderRow("Handshake Pwd", tlsPass, true)
+
+				renderRow("Server Name", c.SNI, false)
+				renderRow("Version", "3", false)
+				renderBoolRow("Strict Mode", c.IsStrictMode(), "On", "Off")
+
+				// 底層 SS 分隔
+				sb.WriteString("\n")
+				renderRow("SS Cipher", c.GetSSMethod(), false)
+
+				renderRow("SS Password", c.GetSSPassword(cfg.Password), true)
+			// 8. CDN 中轉
+			case inst.CDN != nil && inst.CDN.Enabled:
+				c := inst.CDN
+				sb.WriteString(titleStyle.Render(instanceTitle("CDN 中轉", inst)) + "\n")
+
+				addr := serverIP
+				if c.CDNHost != "" {
+					addr = c.CDNHost
+				} else if c.CertMode == "acme" && c.CertDomain != "" {
+					addr = c.CertDomain
+				}
+
+				renderRow("Server", addr, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.GetCDNPort()), false)
+				renderRow("Listen Port", fmt.Sprintf("%d", c.Port), false)
+				renderRow("Protocol", strings.ToUpper(c.Proxy), false)
+
+				if c.Proxy == config.CDNProxyTrojan {
+					renderRow("Password", inst.Credential(c.Password, cfg.Password), true)
+				} else {
+					renderRow("UUID", inst.Credential(c.UUID, cfg.UUID), true)
+				}
+
+				renderRow("Network", c.Transport, false)
+				if c.Transport == config.CDNTransportGRPC {
+					renderRow("Service Name", c.ServiceName, false)
+				} else {
+					renderRow("Path", c.Path, false)
+				}
+				if host := c.GetHost(); host != "" {
+					renderRow("Host", host, false)
+				}
+				renderRow("Server Name", c.SNI, false)
+				renderRow("TLS", "true", false)
+			// 9. Shadowsocks 2022
+			case inst.Shadowsocks != nil && inst.Shadowsocks.Enabled:
+				c := inst.Shadowsocks
+				sb.WriteString(titleStyle.Render(instanceTitle("Shadowsocks 2022", inst)) + "\n")
+				renderRow("Server", serverIP, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+				renderRow("Method", c.Method, false)
+				renderRow("Server Key", c.Password, true)
+
+				// 多用戶 / 中轉模式下客戶端密碼為「服務端密鑰:用戶密鑰」
+				for _, u := range c.Users {
+					renderRow("User "+u.Name, c.Password+":"+u.Password, true)
+				}
+				for _, r := range c.Relays {
+					renderRow("Relay "+r.Name, fmt.Sprintf("%s:%d", r.Server, r.ServerPort), false)
+					renderRow("Relay Password", c.Password+":"+r.Password, true)
+				}
+				renderRow("Network", "tcp, udp", false)
+			// 10. NaiveProxy
+			case inst.Naive != nil && inst.Naive.Enabled:
+				c := inst.Naive
+				sb.WriteString(titleStyle.Render(instanceTitle("NaiveProxy", inst)) + "\n")
+
+				addr := serverIP
+				if c.CertMode == "acme" && c.CertDomain != "" {
+					addr = c.CertDomain
+				}
+
+				renderRow("Server", addr, false)
+				renderRow("Server Port", fmt.Sprintf("%d", c.Port), false)
+				renderRow("Username", c.GetUsername(), false)
+				renderRow("Password", inst.Credential(c.Password, cfg.Password), true)
+				renderRow("Server Name", c.SNI, false)
+				renderBoolRow("HTTP/3 (QUIC)", c.QUIC, "true", "false")
+				renderBoolRow("ACME Cert", c.CertMode == "acme", "true", "false (客戶端需信任證書)")
+			}
 		}
-		renderRow("Password", pass, true)
-
-		renderRow("Server Name", p.TUIC.SNI, false)
-		renderRow("Congestion Control", p.TUIC.CongestionControl, false)
-		renderRow("ALPN", "h3", false)
-		renderRow("UDP Relay Mode", "native", false)
-
-		isInsecure := (p.TUIC.CertMode == "self_signed")
-		renderBoolRow("Insecure", isInsecure, "true", "false")
-	}
-
-	// ==========================================
-	// 5. AnyTLS (HTTP/2)
-	// ==========================================
-	if p.AnyTLS.Enabled {
-		sb.WriteString(titleStyle.Render("AnyTLS") + "\n")
-
-		addr := serverIP
-		if p.AnyTLS.CertMode == "acme" && p.AnyTLS.CertDomain != "" {
-			addr = p.AnyTLS.CertDomain
-		}
-
-		renderRow("Server", addr, false)
-		renderRow("Server Port", fmt.Sprintf("%d", p.AnyTLS.Port), false)
-		renderRow("Username", p.AnyTLS.Username, false)
-
-		pass := p.AnyTLS.Password
-		if pass == "" {
-			pass = cfg.Password
-		}
-		renderRow("Password", pass, true)
-
-		renderRow("Server Name", p.AnyTLS.SNI, false)
-		renderRow("ALPN", "h2, http/1.1", false)
-
-		isInsecure := (p.AnyTLS.CertMode == "self_signed")
-		renderBoolRow("Insecure", isInsecure, "true", "false")
-	}
-
-	// ==========================================
-	// 6. AnyTLS Reality
-	// ==========================================
-	if p.AnyTLSReality.Enabled {
-		sb.WriteString(titleStyle.Render("AnyTLS Reality") + "\n")
-		renderRow("Server", serverIP, false)
-		renderRow("Server Port", fmt.Sprintf("%d", p.AnyTLSReality.Port), false)
-		renderRow("Username", p.AnyTLSReality.Username, false)
-
-		pass := p.AnyTLSReality.Password
-		if pass == "" {
-			pass = cfg.Password
-		}
-		renderRow("Password", pass, true)
-
-		// 補全了你之前缺失的參數
-		renderRow("Server Name", p.AnyTLSReality.SNI, false)
-		renderRow("Fingerprint", "chrome", false)
-
-		// Reality 核心參數
-		renderRow("Public Key", p.AnyTLSReality.PublicKey, true) // 高亮
-		renderRow("Short ID", p.AnyTLSReality.ShortID, true)     // 高亮
 	}
 
-	// ==========================================
-	// 7. ShadowTLS v3
-	// ==========================================
-	if p.ShadowTLS.Enabled {
-		sb.WriteString(titleStyle.Render("ShadowTLS v3") + "\n")
-		renderRow("Server", serverIP, false)
-		renderRow("Server Port", fmt.Sprintf("%d", p.ShadowTLS.Port), false)
-
-		// 握手密碼
-		tlsPass := p.ShadowTLS.Password
-		if tlsPass == "" {
-			tlsPass = cfg.Password
-		}
-		renderRow("Handshake Pwd", tlsPass, true)
-
-		renderRow("Server Name", p.ShadowTLS.SNI, false)
-		renderRow("Version", "3", false)
-		renderBoolRow("Strict Mode", p.ShadowTLS.StrictMode, "On", "Off")
-
-		// 底層 SS 分隔
-		sb.WriteString("\n")
-		renderRow("SS Cipher", p.ShadowTLS.SSMethod, false)
+	return sb.String()
+}
 
-		ssPass := p.ShadowTLS.SSPassword
-		if ssPass == "" {
-			ssPass = cfg.Password
-		}
-		renderRow("SS Password", ssPass, true)
+// instanceTitle 返回實例標題，非主實例附加標籤以便區分
+func instanceTitle(name string, inst *config.ProtocolInstance) string {
+	if inst.IsPrimary() {
+		return name
 	}
-
-	return sb.String()
+	return fmt.Sprintf("%s [%s]", name, inst.Tag)
 }
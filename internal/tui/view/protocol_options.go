@@ -0,0 +1,196 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
+	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
+	"github.com/Yat-Muk/prism-v2/internal/tui/style"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RenderProtocolOptions 配置與協議 > 協議高級參數
+// 主實例以協議編號標識，其餘實例以標籤標識
+func RenderProtocolOptions(cfg *config.Config, ti textinput.Model, statusMsg string) string {
+	header := renderSubpageHeader("協議高級參數")
+
+	desc := lipgloss.NewStyle().
+		Foreground(style.Snow2).
+		Render(" 調整 ALPN、擁塞控制、Shadowsocks 加密與用戶、CDN 傳輸等協議專屬參數")
+
+	divider := lipgloss.NewStyle().
+		Foreground(style.Polar4).
+		Render(strings.Repeat("─", 50))
+
+	labelStyle := lipgloss.NewStyle().Foreground(style.Snow1)
+	valueStyle := lipgloss.NewStyle().Foreground(style.Aurora2)
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+
+	type row struct {
+		label   string
+		summary string
+		enabled bool
+	}
+	var rows []row
+	if cfg != nil {
+		for _, d := range protocol.Descriptors() {
+			for _, inst := range cfg.Protocols.InstancesOf(d.ConfigKey) {
+				label := fmt.Sprintf("%d. %s", d.ID, d.ID)
+				if !inst.IsPrimary() {
+					label = fmt.Sprintf("%d └ %s", d.ID, inst.Tag)
+				}
+				sec, _ := inst.Section()
+				rows = append(rows, row{label, protocolOptionSummary(inst), *sec.Enabled})
+			}
+		}
+	}
+
+	maxWidth := 0
+	for _, r := range rows {
+		if w := runewidth.StringWidth(r.label); w > maxWidth {
+			maxWidth = w
+		}
+	}
+
+	var lines []string
+	for _, r := range rows {
+		line := " " + r.label + strings.Repeat(" ", maxWidth-runewidth.StringWidth(r.label)) + "  "
+		summary := valueStyle.Render(r.summary)
+		if r.summary == "" {
+			summary = mutedStyle.Render("無可調參數")
+		}
+		if !r.enabled {
+			summary += mutedStyle.Render(" (未啟用)")
+		}
+		lines = append(lines, labelStyle.Render(line)+summary)
+	}
+	list := strings.Join(lines, "\n")
+
+	items := []MenuItem{
+		{"", "", "", lipgloss.Color("")},
+		{constants.KeyOption_ALPN, "ALPN", "(Hysteria2 / TUIC / AnyTLS，如 4 h3,spdy/3.1)", style.Snow1},
+		{constants.KeyOption_CongestionControl, "擁塞控制", "(TUIC: " + strings.Join(config.TUICCongestionControls, "/") + ")", style.Snow1},
+		{constants.KeyOption_ZeroRTT, "0-RTT 握手", "(TUIC: on/off)", style.Snow1},
+		{constants.KeyOption_Username, "用戶名", "(AnyTLS / AnyTLS Reality / NaiveProxy)", style.Snow1},
+		{constants.KeyOption_ShortID, "Reality Short ID", "(十六進制，最長 16 位)", style.Snow1},
+		{constants.KeyOption_SSMethod, "SS 加密方式", "(ShadowTLS / Shadowsocks，如 7 2022-blake3-aes-256-gcm)", style.Snow1},
+		{constants.KeyOption_SSPassword, "SS 密碼", "(ShadowTLS 留空沿用全局密碼，Shadowsocks 留空重新生成)", style.Snow1},
+		{constants.KeyOption_DetourPort, "本地轉交端口", "(ShadowTLS，主實例默認 10000)", style.Snow1},
+		{constants.KeyOption_StrictMode, "嚴格模式", "(ShadowTLS: on/off)", style.Snow1},
+		{constants.KeyOption_CDNProxy, "CDN 代理協議", "(" + strings.Join(config.CDNProxies, "/") + ")", style.Snow1},
+		{constants.KeyOption_CDNTransport, "CDN 傳輸方式", "(" + strings.Join(config.CDNTransports, "/") + ")", style.Snow1},
+		{constants.KeyOption_CDNPath, "CDN 路徑", "(WebSocket / HTTPUpgrade，以 / 開頭)", style.Snow1},
+		{constants.KeyOption_CDNHost, "CDN Host 頭", "(留空使用連接地址或 SNI)", style.Snow1},
+		{constants.KeyOption_CDNServiceName, "gRPC 服務名", "(CDN gRPC 傳輸)", style.Snow1},
+		{constants.KeyOption_CDNAddress, "CDN 連接地址", "(客戶端連接的 CDN 域名或優選 IP)", style.Snow1},
+		{constants.KeyOption_CDNPort, "CDN 連接端口", "(留空與監聽端口相同)", style.Snow1},
+		{constants.KeyOption_SSUserAdd, "SS 添加用戶", "(多用戶 EIH，名稱 [密鑰])", style.Snow1},
+		{constants.KeyOption_SSRelayAdd, "SS 添加中轉", "(名稱 地址:端口 目標密鑰)", style.Snow1},
+		{constants.KeyOption_SSRemove, "SS 刪除用戶/中轉", "(名稱)", style.Snow1},
+		{constants.KeyOption_NaiveQUIC, "HTTP/3 (QUIC)", "(NaiveProxy: on/off)", style.Snow1},
+		{constants.KeyOption_Decoy, "偽裝網站", "(Hysteria2: file [目錄]/proxy URL/string [狀態碼] 內容；CDN Trojan: file (內置站點)/proxy http://...)", style.Snow1},
+		{constants.KeyOption_UDPRelayMode, "UDP 轉發模式", "(TUIC: " + strings.Join(config.TUICUDPRelayModes, "/") + "，寫入客戶端配置)", style.Snow1},
+		{constants.KeyOption_AuthTimeout, "認證超時", "(TUIC 服務端，默認 " + config.DefaultTUICAuthTimeout + ")", style.Snow1},
+		{constants.KeyOption_Heartbeat, "心跳間隔", "(TUIC 服務端與客戶端，默認 " + config.DefaultTUICHeartbeat + ")", style.Snow1},
+	}
+
+	menu := renderMenuWithAlignment(items, 0, "", false)
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 選擇參數後輸入「編號或實例標籤 值」，只輸入編號則恢復默認")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+
+	footer := RenderInputFooter(ti)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		desc,
+		divider,
+		list,
+		menu,
+		"",
+		instruction,
+		statusBlock,
+		footer,
+	)
+}
+
+// protocolOptionSummary 返回實例高級參數的當前取值摘要
+func protocolOptionSummary(inst *config.ProtocolInstance) string {
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+
+	var parts []string
+	switch {
+	case inst.RealityVision != nil:
+		parts = append(parts, "Short ID "+inst.RealityVision.ShortID)
+	case inst.RealityGRPC != nil:
+		parts = append(parts, "Short ID "+inst.RealityGRPC.ShortID)
+	case inst.Hysteria2 != nil:
+		parts = append(parts, "ALPN "+strings.Join(inst.Hysteria2.GetALPN(), ","), "偽裝 "+inst.Hysteria2.Decoy.Summary())
+	case inst.TUIC != nil:
+		c := inst.TUIC
+		parts = append(parts,
+			"擁塞 "+c.GetCongestionControl(),
+			"ALPN "+strings.Join(c.GetALPN(), ","),
+			"0-RTT "+onOff(c.ZeroRTTHandshake),
+			"UDP "+c.GetUDPRelayMode(),
+			"認證 "+c.GetAuthTimeout(),
+			"心跳 "+c.GetHeartbeat())
+	case inst.AnyTLS != nil:
+		c := inst.AnyTLS
+		parts = append(parts, "用戶 "+c.GetUsername(), "ALPN "+strings.Join(c.GetALPN(), ","))
+	case inst.AnyTLSReality != nil:
+		c := inst.AnyTLSReality
+		parts = append(parts, "用戶 "+c.GetUsername(), "Short ID "+c.ShortID)
+	case inst.CDN != nil:
+		c := inst.CDN
+		parts = append(parts, c.Proxy+"+"+c.Transport)
+		if c.Transport == config.CDNTransportGRPC {
+			parts = append(parts, "服務名 "+c.ServiceName)
+		} else {
+			parts = append(parts, "路徑 "+c.Path)
+		}
+		addr := c.CDNHost
+		if addr == "" {
+			addr = "服務器地址"
+		}
+		parts = append(parts, fmt.Sprintf("連接 %s:%d", addr, c.GetCDNPort()))
+		if c.Proxy == config.CDNProxyTrojan {
+			parts = append(parts, "回落 "+c.Decoy.Summary())
+		}
+	case inst.Shadowsocks != nil:
+		c := inst.Shadowsocks
+		parts = append(parts, c.Method)
+		switch {
+		case c.MultiUser():
+			parts = append(parts, fmt.Sprintf("用戶 %d", len(c.Users)))
+		case c.IsRelay():
+			parts = append(parts, fmt.Sprintf("中轉 %d", len(c.Relays)))
+		default:
+			parts = append(parts, "單用戶")
+		}
+	case inst.Naive != nil:
+		c := inst.Naive
+		parts = append(parts, "用戶 "+c.GetUsername(), "HTTP/3 "+onOff(c.QUIC))
+	case inst.ShadowTLS != nil:
+		c := inst.ShadowTLS
+		parts = append(parts,
+			c.GetSSMethod(),
+			fmt.Sprintf("轉交 %d", c.GetDetourPort()),
+			"嚴格 "+onOff(c.IsStrictMode()))
+	}
+	return strings.Join(parts, " | ")
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/mattn/go-runewidth"
 
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
 	"github.com/Yat-Muk/prism-v2/internal/domain/protocol"
 	"github.com/Yat-Muk/prism-v2/internal/tui/style"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -13,7 +14,8 @@ import (
 )
 
 // RenderProtocolSwitches 配置與協議 > 協議管理
-func RenderProtocolSwitches(selected []int, ti textinput.Model, statusMsg string) string {
+// 主實例按編號切換，同一協議的其餘實例列在其下方，按標籤切換
+func RenderProtocolSwitches(selected []int, cfg *config.Config, ti textinput.Model, statusMsg string) string {
 	header := renderSubpageHeader("協議開關管理")
 
 	enabled := make(map[int]bool)
@@ -65,7 +67,10 @@ func RenderProtocolSwitches(selected []int, ti textinput.Model, statusMsg string
 		return offText
 	}
 
+	instanceStyle := lipgloss.NewStyle().Foreground(style.Snow3)
+
 	var items []MenuItem
+	var extras []string
 	for _, id := range allIDs {
 
 		nameDisplay := padName(id.String()) + "  " + state(int(id))
@@ -73,16 +78,40 @@ func RenderProtocolSwitches(selected []int, ti textinput.Model, statusMsg string
 		items = append(items, MenuItem{
 			Num:       fmt.Sprintf("%d", id),
 			Text:      nameDisplay,
-			Desc:      id.Tag(),
+			Desc:      id.Badge(),
 			TextColor: style.Snow1,
 		})
+
+		if cfg == nil {
+			continue
+		}
+		d, _ := protocol.Lookup(id)
+		for _, inst := range cfg.Protocols.InstancesOf(d.ConfigKey) {
+			if inst.IsPrimary() {
+				continue
+			}
+			sec, _ := inst.Section()
+			status := offText
+			if *sec.Enabled {
+				status = onText
+			}
+			extras = append(extras, fmt.Sprintf("  %s  %s  %s",
+				instanceStyle.Render(fmt.Sprintf("%d └ %s :%d", id, inst.Tag, *sec.Port)),
+				status,
+				instanceStyle.Render(*sec.SNI)))
+		}
 	}
 
 	menu := renderMenuWithAlignment(items, 0, "", false)
+	if len(extras) > 0 {
+		header := lipgloss.NewStyle().Foreground(style.Snow2).Render(" 其他實例")
+		menu = lipgloss.JoinVertical(lipgloss.Left, menu, "", header, strings.Join(extras, "\n"))
+	}
 
 	instruction := lipgloss.NewStyle().
 		Foreground(style.Snow3).
-		Render(" 💡 輸入編號切換狀態，修改後請記得「應用配置」")
+		Render(" 💡 輸入編號或實例標籤切換狀態，修改後請記得「應用配置」\n" +
+			"    添加實例: +編號 標籤 [端口] [SNI]   刪除實例: -標籤")
 
 	statusBlock := RenderStatusMessage(statusMsg)
 
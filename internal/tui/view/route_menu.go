@@ -27,6 +27,8 @@ func RenderRouteMenu(cursor int, ti textinput.Model, statusMsg string) string {
 		{constants.KeyRoute_IPv6, "IPv6 分流", "(IPv6 流量分流)", style.Snow1},
 		{constants.KeyRoute_DNS, "DNS 分流", "(自定義 DNS 服務器分流)", style.Snow1},
 		{constants.KeyRoute_SNIProxy, "SNI 反向代理", "(SNI 反向代理分流)", style.Snow1},
+		{constants.KeyRoute_Outbound, "自定義出站", "(上游節點 / 鏈式代理)", style.Snow1},
+		{constants.KeyRoute_Rules, "路由規則", "(按優先級匹配的通用規則)", style.Aurora2},
 	}
 
 	menu := renderMenuWithAlignment(items, cursor, "", false)
@@ -0,0 +1,97 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/domain/config"
+	"github.com/Yat-Muk/prism-v2/internal/tui/constants"
+	"github.com/Yat-Muk/prism-v2/internal/tui/style"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RenderRoutingRules 渲染路由規則管理界面
+func RenderRoutingRules(routing *config.RoutingConfig, ti textinput.Model, statusMsg string) string {
+	header := renderSubpageHeader("路由規則")
+
+	desc := lipgloss.NewStyle().
+		Foreground(style.Snow2).
+		Render(" 按優先級從小到大依次匹配，首條命中的規則生效")
+
+	divider := lipgloss.NewStyle().
+		Foreground(style.Polar4).
+		Render(strings.Repeat("─", 50))
+
+	labelStyle := lipgloss.NewStyle().Foreground(style.Snow3)
+	valueStyle := lipgloss.NewStyle().Foreground(style.Aurora2)
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+
+	var lines []string
+	final := config.OutboundDirect
+	if routing != nil {
+		if routing.Final != "" {
+			final = routing.Final
+		}
+		for n, i := range routing.RuleOrder() {
+			rule := routing.Rules[i]
+			line := fmt.Sprintf(" %2d. [%d] %s", n+1, rule.Priority, truncateRule(rule.String()))
+			if rule.Disabled {
+				lines = append(lines, mutedStyle.Render(line+" (已禁用)"))
+				continue
+			}
+			if !routing.IsOutboundAvailable(rule.Outbound) && rule.GetAction() == config.RuleActionRoute {
+				lines = append(lines, mutedStyle.Render(line+" (出站未啓用)"))
+				continue
+			}
+			lines = append(lines, labelStyle.Render(line))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, mutedStyle.Render(" 暫無路由規則"))
+	}
+	lines = append(lines, fmt.Sprintf("%s %s", labelStyle.Render(" 默認出站："), valueStyle.Render(final)))
+	list := strings.Join(lines, "\n")
+
+	items := []MenuItem{
+		{"", "", "", lipgloss.Color("")},
+		{constants.KeyRule_Add, "添加規則", "(條件 => 出站 / reject / hijack-dns)", style.StatusGreen},
+		{constants.KeyRule_Delete, "刪除規則", "(輸入序號)", style.StatusRed},
+		{constants.KeyRule_Priority, "調整優先級", "(序號 優先級)", style.Snow1},
+		{constants.KeyRule_Toggle, "啓用/禁用規則", "(輸入序號)", style.Snow1},
+		{constants.KeyRule_Final, "設置默認出站", "(留空恢復 direct)", style.StatusYellow},
+	}
+
+	menu := renderMenuWithAlignment(items, 0, "", false)
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 例: domain_suffix=openai.com geosite=netflix port=443 priority=50 => warp-out")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+
+	footer := RenderInputFooter(ti)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		desc,
+		divider,
+		list,
+		menu,
+		"",
+		instruction,
+		statusBlock,
+		footer,
+	)
+}
+
+// truncateRule 截斷過長的規則描述
+func truncateRule(s string) string {
+	const maxLen = 60
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen-3]) + "..."
+}
@@ -1,17 +1,34 @@
 package view
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/Yat-Muk/prism-v2/internal/infra/logs"
 	"github.com/Yat-Muk/prism-v2/internal/tui/style"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// ServiceLogViewData 服務日誌查看器的渲染數據
+type ServiceLogViewData struct {
+	Entries      []logs.Entry // 已過濾的可見日誌
+	Total        int          // 緩衝中的日誌總數
+	Source       string
+	Filter       logs.Filter
+	Following    bool
+	Paused       bool
+	NewSinceHold int
+}
+
 // RenderServiceLogViewer 渲染服務日誌實時查看器
-func RenderServiceLogViewer(logs []string, following bool, ti textinput.Model) string {
+func RenderServiceLogViewer(data ServiceLogViewData, ti textinput.Model, statusMsg string) string {
 	header := renderSubpageHeader("服務實時日誌")
 
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+
+	source := mutedStyle.Render(" 來源: " + data.Source)
+
 	divider := lipgloss.NewStyle().
 		Foreground(style.Polar4).
 		Render(strings.Repeat("─", 50))
@@ -19,28 +36,29 @@ func RenderServiceLogViewer(logs []string, following bool, ti textinput.Model) s
 	// 日誌內容
 	logStyle := lipgloss.NewStyle().
 		Foreground(style.Snow1).
-		Width(50).
 		MaxWidth(78)
 
 	var logContent string
-	if len(logs) == 0 {
-		logContent = lipgloss.NewStyle().
-			Foreground(style.Muted).
-			Render("等待日誌輸出...")
+	if len(data.Entries) == 0 {
+		text := "等待日誌輸出..."
+		if data.Total > 0 {
+			text = "沒有符合過濾條件的日誌"
+		}
+		logContent = mutedStyle.Render(text)
 	} else {
 		var lines []string
-		for _, log := range logs {
+		for _, e := range data.Entries {
+			line := e.String()
 			// 根據日誌級別著色
-			if strings.Contains(log, "ERROR") || strings.Contains(log, "FATAL") {
-				lines = append(lines, lipgloss.NewStyle().Foreground(style.StatusRed).Render(log))
-			} else if strings.Contains(log, "WARN") {
-				lines = append(lines, lipgloss.NewStyle().Foreground(style.StatusYellow).Render(log))
-			} else if strings.Contains(log, "DEBUG") {
-				lines = append(lines, lipgloss.NewStyle().Foreground(style.Muted).Render(log))
-			} else if strings.Contains(log, "INFO") {
-				lines = append(lines, lipgloss.NewStyle().Foreground(style.StatusGreen).Render(log))
-			} else {
-				lines = append(lines, logStyle.Render(log))
+			switch {
+			case e.Level >= logs.LevelError:
+				lines = append(lines, logStyle.Foreground(style.StatusRed).Render(line))
+			case e.Level == logs.LevelWarn:
+				lines = append(lines, logStyle.Foreground(style.StatusYellow).Render(line))
+			case e.Level <= logs.LevelDebug:
+				lines = append(lines, logStyle.Foreground(style.Muted).Render(line))
+			default:
+				lines = append(lines, logStyle.Render(line))
 			}
 		}
 		logContent = strings.Join(lines, "\n")
@@ -49,22 +67,52 @@ func RenderServiceLogViewer(logs []string, following bool, ti textinput.Model) s
 	// 狀態欄
 	statusStyle := lipgloss.NewStyle().Foreground(style.Aurora2).Bold(true)
 	var statusText string
-	if following {
+	switch {
+	case data.Paused:
+		text := "⏸ 已暫停"
+		if data.NewSinceHold > 0 {
+			text += fmt.Sprintf(" (%d 條新日誌)", data.NewSinceHold)
+		}
+		statusText = lipgloss.NewStyle().Foreground(style.StatusYellow).Render(text)
+	case data.Following:
 		statusText = statusStyle.Render("● 實時滾動中...")
-	} else {
-		statusText = lipgloss.NewStyle().Foreground(style.StatusYellow).Render("⏸ 已暫停")
+	default:
+		statusText = mutedStyle.Render("○ 已斷開 (輸入 r 重新連接)")
 	}
 
+	if f := data.Filter; !f.IsEmpty() {
+		var parts []string
+		if f.MinLevel > logs.LevelTrace {
+			parts = append(parts, "級別≥"+f.MinLevel.String())
+		}
+		if f.Inbound != "" {
+			parts = append(parts, "入站="+f.Inbound)
+		}
+		if f.Search != "" {
+			parts = append(parts, "搜索="+f.Search)
+		}
+		statusText += mutedStyle.Render("  過濾: " + strings.Join(parts, " "))
+	}
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 p 暫停 | /關鍵字 | l warn | i hysteria2-in (或協議編號) | c 清除過濾")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+
 	// 這裡使用通用的輸入 Footer，確保光標和 Esc 提示一致
 	footer := RenderInputFooter(ti)
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		header,
+		source,
 		divider,
 		logContent,
 		"",
 		statusText,
+		instruction,
+		statusBlock,
 		footer,
 	)
 }
@@ -84,6 +84,7 @@ func RenderServiceMenu(
 			{constants.KeyService_Refresh, "刷新狀態", "(重新獲取服務狀態)", style.Snow1},
 			{constants.KeyService_AutoStart, "設置自啟動", "(開機自動啟動服務)", style.StatusGreen},
 			{constants.KeyService_Health, "健康檢查", "(檢測服務運行狀況)", style.Snow1},
+			{constants.KeyService_Traffic, "流量面板", "(各入站流量與活動連接)", style.Aurora2},
 		}
 	} else {
 		items = []MenuItem{
@@ -11,8 +11,23 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// socks5OutboundStatus 返回 Socks5 出站 (自定義出站 socks5-out) 的狀態描述
+func socks5OutboundStatus(cfg *config.RoutingConfig) string {
+	out := cfg.FindOutbound(config.Socks5OutboundTag)
+	if out == nil {
+		return "✗ 未配置"
+	}
+	if !cfg.Socks5Enabled() {
+		return fmt.Sprintf("✗ 未啓用 (%s:%d)", out.Server, out.Port)
+	}
+	if cfg.Final == config.Socks5OutboundTag {
+		return fmt.Sprintf("✓ 全局轉發 (%s:%d)", out.Server, out.Port)
+	}
+	return fmt.Sprintf("✓ 已啓用 (%s:%d)", out.Server, out.Port)
+}
+
 // RenderSocks5Routing 渲染 Socks5 分流菜單
-func RenderSocks5Routing(cfg *config.Socks5Config, ti textinput.Model, statusMsg string) string {
+func RenderSocks5Routing(cfg *config.RoutingConfig, ti textinput.Model, statusMsg string) string {
 	header := renderSubpageHeader("Socks5 分流")
 
 	desc := lipgloss.NewStyle().
@@ -31,14 +46,11 @@ func RenderSocks5Routing(cfg *config.Socks5Config, ti textinput.Model, statusMsg
 	var statusText string
 	if cfg != nil {
 		inboundStatus := "✗ 未啓用"
-		if cfg.Inbound.Enabled {
-			inboundStatus = fmt.Sprintf("✓ 已啓用 (端口: %d)", cfg.Inbound.Port)
+		if cfg.Socks5.Inbound.Enabled {
+			inboundStatus = fmt.Sprintf("✓ 已啓用 (端口: %d)", cfg.Socks5.Inbound.Port)
 		}
 
-		outboundStatus := "✗ 未啓用"
-		if cfg.Outbound.Enabled {
-			outboundStatus = fmt.Sprintf("✓ 已啓用 (%s:%d)", cfg.Outbound.Server, cfg.Outbound.Port)
-		}
+		outboundStatus := socks5OutboundStatus(cfg)
 
 		statusText = fmt.Sprintf(
 			"%s %s\n%s %s",
@@ -124,7 +136,7 @@ func RenderSocks5Inbound(cfg *config.Socks5InboundConfig, ti textinput.Model, st
 }
 
 // RenderSocks5Outbound 渲染 Socks5 出站配置
-func RenderSocks5Outbound(cfg *config.Socks5OutboundConfig, ti textinput.Model, statusMsg string) string {
+func RenderSocks5Outbound(cfg *config.RoutingConfig, ti textinput.Model, statusMsg string) string {
 	header := renderSubpageHeader("Socks5 出站配置")
 
 	desc := lipgloss.NewStyle().
@@ -135,12 +147,22 @@ func RenderSocks5Outbound(cfg *config.Socks5OutboundConfig, ti textinput.Model,
 		Foreground(style.Polar4).
 		Render(strings.Repeat("─", 50))
 
+	server := "未配置"
+	statusText := ""
+	if cfg != nil {
+		if out := cfg.FindOutbound(config.Socks5OutboundTag); out != nil {
+			server = fmt.Sprintf("%s:%d", out.Server, out.Port)
+		}
+		statusText = lipgloss.NewStyle().Foreground(style.Snow3).Render(" 出站狀態：") +
+			lipgloss.NewStyle().Foreground(style.Aurora2).Render(socks5OutboundStatus(cfg))
+	}
+
 	items := []MenuItem{
-		{constants.KeySocks5Out_Toggle, "啓用/禁用", "(開關 Socks5 出站)", style.Aurora1},
-		{constants.KeySocks5Out_Server, "修改 落地機地址", fmt.Sprintf("(當前: %s:%d)", cfg.Server, cfg.Port), style.Snow1},
+		{constants.KeySocks5Out_Toggle, "啓用/禁用", "(開關 Socks5 分流規則)", style.Aurora1},
+		{constants.KeySocks5Out_Server, "修改 落地機地址", fmt.Sprintf("(當前: %s)", server), style.Snow1},
 		{constants.KeySocks5Out_Auth, "設置認證", "(配置用戶名密碼)", style.Snow1},
-		{constants.KeySocks5Out_Global, "全局轉發", "(所有流量通過 Socks5))", style.StatusYellow},
-		{constants.KeySocks5Out_Rule, "分流規則", "(指定域名分流)", style.Aurora2},
+		{constants.KeySocks5Out_Global, "全局轉發", "(默認出站切換為 Socks5)", style.StatusYellow},
+		{constants.KeySocks5Out_Rule, "分流規則", "(添加分流域名)", style.Aurora2},
 	}
 
 	menu := renderMenuWithAlignment(items, 0, "", false)
@@ -154,6 +176,7 @@ func RenderSocks5Outbound(cfg *config.Socks5OutboundConfig, ti textinput.Model,
 		header,
 		desc,
 		divider,
+		statusText,
 		menu,
 		statusBlock,
 		footer,
@@ -0,0 +1,147 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yat-Muk/prism-v2/internal/tui/style"
+	"github.com/Yat-Muk/prism-v2/internal/tui/types"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TrafficDashboardViewData 流量面板的渲染數據
+type TrafficDashboardViewData struct {
+	Dashboard   *types.TrafficDashboard
+	Connections []types.ConnectionInfo // 當前展示的連接
+	Err         string
+	Paused      bool
+	Frozen      bool // 輸入指令期間暫停刷新
+	ShowAll     bool
+}
+
+// RenderTrafficDashboard 渲染基於 Clash API 的流量與連接面板
+func RenderTrafficDashboard(data TrafficDashboardViewData, ti textinput.Model, statusMsg string) string {
+	header := renderSubpageHeader("流量面板")
+
+	mutedStyle := lipgloss.NewStyle().Foreground(style.Muted)
+	labelStyle := lipgloss.NewStyle().Foreground(style.Snow3)
+	valueStyle := lipgloss.NewStyle().Foreground(style.Snow1)
+	sectionStyle := lipgloss.NewStyle().Foreground(style.Aurora2).Bold(true)
+
+	desc := lipgloss.NewStyle().
+		Foreground(style.Snow2).
+		Render(" 通過 sing-box Clash API 查看各入站流量與活動連接")
+
+	divider := lipgloss.NewStyle().
+		Foreground(style.Polar4).
+		Render(strings.Repeat("─", 50))
+
+	instruction := lipgloss.NewStyle().
+		Foreground(style.Snow3).
+		Render(" 💡 x 序號 關閉連接 | a 顯示全部/折疊 | p 暫停刷新")
+
+	statusBlock := RenderStatusMessage(statusMsg)
+	footer := RenderInputFooter(ti)
+
+	d := data.Dashboard
+	if d == nil {
+		text := "正在連接 Clash API..."
+		if data.Err != "" {
+			text = "✗ " + data.Err
+		}
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			header,
+			desc,
+			divider,
+			"",
+			mutedStyle.Render(" "+text),
+			"",
+			instruction,
+			statusBlock,
+			footer,
+		)
+	}
+
+	// 總覽
+	overview := []string{
+		fmt.Sprintf("%s %s  %s %s",
+			labelStyle.Render(" 實時速率: ↑"), valueStyle.Render(d.UpRate),
+			labelStyle.Render("↓"), valueStyle.Render(d.DownRate)),
+		fmt.Sprintf("%s %s  %s %s",
+			labelStyle.Render(" 累計流量: ↑"), valueStyle.Render(d.UploadTotal),
+			labelStyle.Render("↓"), valueStyle.Render(d.DownloadTotal)),
+		fmt.Sprintf("%s %s  %s %s",
+			labelStyle.Render(" 活動連接:"), valueStyle.Render(fmt.Sprintf("%d", d.TotalConns)),
+			labelStyle.Render("核心內存:"), valueStyle.Render(d.Memory)),
+	}
+
+	var stateLine string
+	switch {
+	case data.Paused:
+		stateLine = lipgloss.NewStyle().Foreground(style.StatusYellow).Render(" ⏸ 已暫停刷新")
+	case data.Frozen:
+		stateLine = lipgloss.NewStyle().Foreground(style.StatusYellow).Render(" ⏸ 輸入指令中，已暫停刷新")
+	case data.Err != "":
+		stateLine = lipgloss.NewStyle().Foreground(style.StatusRed).Render(" ✗ " + data.Err)
+	default:
+		stateLine = mutedStyle.Render(" ● 每秒刷新  更新於 " + d.UpdateTime)
+	}
+
+	// 各入站
+	inboundLines := []string{sectionStyle.Render(" 入站流量")}
+	if len(d.Inbounds) == 0 {
+		inboundLines = append(inboundLines, mutedStyle.Render("  (暫無活動入站)"))
+	}
+	for _, in := range d.Inbounds {
+		inboundLines = append(inboundLines, valueStyle.Render(fmt.Sprintf("  %-20s %3d 連接  ↑ %-12s ↓ %s",
+			in.Tag, in.Connections, in.UpRate, in.DownRate)))
+	}
+
+	// 活動連接
+	title := " 活動連接"
+	if !data.ShowAll && len(data.Connections) < d.TotalConns {
+		title += fmt.Sprintf(" (前 %d 條)", len(data.Connections))
+	}
+	connLines := []string{sectionStyle.Render(title)}
+	if len(data.Connections) == 0 {
+		connLines = append(connLines, mutedStyle.Render("  (暫無活動連接)"))
+	}
+	connStyle := valueStyle.MaxWidth(100)
+	for i, c := range data.Connections {
+		connLines = append(connLines, connStyle.Render(fmt.Sprintf("  %2d. [%s/%s] %s → %s via %s",
+			i+1, c.Inbound, c.Network, c.Source, c.Destination, c.Outbound)))
+		connLines = append(connLines, mutedStyle.Render(fmt.Sprintf("      %s  速率 %s  時長 %s",
+			c.Traffic, c.Rate, c.Duration)))
+	}
+
+	// 熱門目標
+	destLines := []string{sectionStyle.Render(" 熱門目標")}
+	if len(d.TopDestinations) == 0 {
+		destLines = append(destLines, mutedStyle.Render("  (暫無數據)"))
+	}
+	for i, dest := range d.TopDestinations {
+		destLines = append(destLines, valueStyle.Render(fmt.Sprintf("  %2d. %-32s %3d 連接  %s",
+			i+1, dest.Host, dest.Connections, dest.Total)))
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		desc,
+		divider,
+		strings.Join(overview, "\n"),
+		stateLine,
+		"",
+		strings.Join(inboundLines, "\n"),
+		"",
+		strings.Join(connLines, "\n"),
+		"",
+		strings.Join(destLines, "\n"),
+		"",
+		instruction,
+		statusBlock,
+		footer,
+	)
+}
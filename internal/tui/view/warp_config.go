@@ -2,6 +2,8 @@ package view
 
 import (
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
 
 	"github.com/Yat-Muk/prism-v2/internal/domain/config"
@@ -38,12 +40,30 @@ func RenderWARPConfig(cfg *config.WARPConfig, ti textinput.Model, statusMsg stri
 			lipgloss.NewStyle().Foreground(style.Muted).Render("✗ 未啓用"))
 	}
 
+	if cfg != nil {
+		host, port := cfg.GetEndpoint()
+		endpoint := net.JoinHostPort(host, strconv.Itoa(port))
+		if cfg.Endpoint == "" {
+			endpoint += " (默認)"
+		}
+		statusText += "\n" + fmt.Sprintf("%s %s",
+			statusStyle.Render(" 接入點："),
+			valueStyle.Render(endpoint))
+	}
+
+	autoText := "開啓自動優選"
+	if cfg != nil && cfg.AutoOptimize {
+		autoText = "關閉自動優選"
+	}
+
 	items := []MenuItem{
 		{"", "", "", lipgloss.Color("")},
 		{constants.KeyWARPConfig_Enable, "啓用 WARP", "(開啓 Cloudflare WARP 出站)", style.StatusGreen},
 		{constants.KeyWARPConfig_Disable, "禁用 WARP", "(關閉 WARP 出站)", style.StatusRed},
 		{constants.KeyWARPConfig_License, "配置許可證密鑰", "(設置 WARP+ 密鑰)", style.Snow1},
 		{constants.KeyWARPConfig_Test, "測試連接", "(驗證 WARP 是否正常工作)", style.Aurora2},
+		{constants.KeyWARPConfig_Optimize, "優選接入點", "(握手測速並寫入最優 Endpoint)", style.Aurora2},
+		{constants.KeyWARPConfig_Auto, autoText, "(定時任務中自動優選)", style.Snow1},
 	}
 
 	menu := renderMenuWithAlignment(items, 0, "", false)
@@ -78,13 +78,25 @@ func RenderWARPRouting(cfg *config.WARPConfig, ti textinput.Model, statusMsg str
 		{constants.KeyWARP_Disable, "禁用 WARP", "(關閉 WARP 分流)", style.StatusRed},
 		{"", "", "", lipgloss.Color("")},
 		{constants.KeyWARP_SetLicense, "配置許可證", "(WARP+ 密鑰 / 留空免費版)", style.Snow1},
+		{constants.KeyWARP_Register, "註冊 WARP 賬戶", "(自動生成密鑰並獲取地址)", style.Aurora2},
+		{constants.KeyWARP_SetEndpoint, "設置接入點", "(自定義 Endpoint IP:端口)", style.Snow1},
+		{constants.KeyWARP_Outbound, "出站管理", "(測試連接 / 優選接入點)", style.Aurora2},
 	}
 
 	menu := renderMenuWithAlignment(items, 0, "", false)
 
 	warning := lipgloss.NewStyle().
 		Foreground(style.StatusYellow).
-		Render(" ⚠️  需要配置 WARP 密鑰才能使用")
+		Render(" ⚠️  需要先註冊 WARP 賬戶才能使用")
+	if cfg != nil && cfg.IsRegistered() {
+		accountType := cfg.AccountType
+		if accountType == "" {
+			accountType = "free"
+		}
+		warning = lipgloss.NewStyle().
+			Foreground(style.Snow3).
+			Render(fmt.Sprintf(" 🔑 已註冊設備 (賬戶類型: %s)", accountType))
+	}
 
 	instruction := lipgloss.NewStyle().
 		Foreground(style.Snow3).